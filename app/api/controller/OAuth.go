@@ -2,13 +2,18 @@ package controller
 
 import (
 	"fmt"
+	"inis/app/facade"
+	"inis/app/model"
+	"strings"
+
 	"github.com/gin-gonic/gin"
 	"github.com/spf13/cast"
 	"github.com/unti-io/go-utils/utils"
-	"inis/app/facade"
-	"strings"
 )
 
+// oauthBrowserCookie - 绑定授权流程与浏览器的 cookie 名称
+const oauthBrowserCookie = "INIS_OAUTH_STATE"
+
 type OAuth struct {
 	// 继承
 	base
@@ -20,8 +25,9 @@ func (this *OAuth) IGET(ctx *gin.Context) {
 	method := strings.ToLower(ctx.Param("method"))
 
 	allow := map[string]any{
-		"qq":     this.qq,
-		"github": this.github,
+		"providers":  this.providers,
+		"authorize":  this.authorize,
+		"identities": this.identities,
 	}
 	err := this.call(allow, method, ctx)
 
@@ -38,8 +44,7 @@ func (this *OAuth) IPOST(ctx *gin.Context) {
 	method := strings.ToLower(ctx.Param("method"))
 
 	allow := map[string]any{
-		"qq":     this.qq,
-		"github": this.github,
+		"callback": this.callback,
 	}
 	err := this.call(allow, method, ctx)
 
@@ -54,9 +59,7 @@ func (this *OAuth) IPUT(ctx *gin.Context) {
 	// 转小写
 	method := strings.ToLower(ctx.Param("method"))
 
-	allow := map[string]any{
-		"qq": this.qq,
-	}
+	allow := map[string]any{}
 	err := this.call(allow, method, ctx)
 
 	if err != nil {
@@ -71,7 +74,7 @@ func (this *OAuth) IDEL(ctx *gin.Context) {
 	method := strings.ToLower(ctx.Param("method"))
 
 	allow := map[string]any{
-		"qq": this.qq,
+		"unlink": this.unlink,
 	}
 	err := this.call(allow, method, ctx)
 
@@ -86,111 +89,264 @@ func (this *OAuth) INDEX(ctx *gin.Context) {
 	this.json(ctx, nil, facade.Lang(ctx, "没什么用！"), 202)
 }
 
-// qq - QQ登录
-func (this *OAuth) qq(ctx *gin.Context) {
+// providers - 已启用的第三方登录服务商
+func (this *OAuth) providers(ctx *gin.Context) {
 
-	params := this.params(ctx)
+	result := []gin.H{}
+	for _, item := range facade.OAuthProviders() {
+		result = append(result, gin.H{
+			"name":  item.Name,
+			"type":  item.Type,
+			"title": item.Title,
+		})
+	}
+
+	this.json(ctx, result, facade.Lang(ctx, "数据请求成功！"), 200)
+}
+
+// authorize - 发起授权，返回服务商的授权地址（action=link 时为已登录用户绑定第三方账号）
+func (this *OAuth) authorize(ctx *gin.Context) {
+
+	params := this.params(ctx, map[string]any{
+		"action": model.OAuthActionLogin,
+	})
+
+	provider, err := facade.OAuthProviderOf(cast.ToString(params["provider"]))
+	if err != nil {
+		this.json(ctx, nil, facade.Lang(ctx, err.Error()), 400)
+		return
+	}
+
+	action := cast.ToString(params["action"])
+	if !utils.InArray(action, []string{model.OAuthActionLogin, model.OAuthActionLink}) {
+		this.json(ctx, nil, facade.Lang(ctx, "%s 格式不正确！", "action"), 400)
+		return
+	}
 
-	fmt.Println(ctx.Request.Method, params)
-
-	// 登录成功
-	if !utils.Is.Empty(params["code"]) {
-
-		item := utils.Curl(utils.CurlRequest{
-			Method: "GET",
-			Url:    "https://graph.qq.com/oauth2.0/token",
-			Query: map[string]any{
-				"grant_type":    "authorization_code",
-				"client_id":     "102045704",
-				"client_secret": "28BAGLMLGHUdijgY",
-				"state":         cast.ToString(params["state"]),
-				"code":          cast.ToString(params["code"]),
-				"redirect_uri":  "https://go.inis.cn/api/auth/qq",
-				"fmt":           "json",
-			},
-		}).Send()
-
-		if item.Error != nil {
-			this.json(ctx, nil, "获取token失败："+item.Error.Error(), 500)
+	uid := 0
+	if action == model.OAuthActionLink {
+		if uid = this.user(ctx).Id; uid == 0 {
+			this.json(ctx, nil, facade.Lang(ctx, "请先登录！"), 401)
 			return
 		}
+	}
 
-		if !utils.Is.Empty(item.Json["access_token"]) {
-			unionid := utils.Curl(utils.CurlRequest{
-				Url: "https://graph.qq.com/oauth2.0/me",
-				Query: map[string]any{
-					"access_token": cast.ToString(item.Json["access_token"]),
-					"unionid":      "1",
-					"fmt":          "json",
-				},
-			}).Send()
-
-			if !utils.Is.Empty(unionid.Json["openid"]) {
-				user := utils.Curl(utils.CurlRequest{
-					Url: "https://graph.qq.com/user/get_user_info",
-					Query: map[string]any{
-						"access_token":       cast.ToString(item.Json["access_token"]),
-						"oauth_consumer_key": "102045704",
-						"openid":             cast.ToString(unionid.Json["openid"]),
-						"fmt":                "json",
-					},
-				}).Send()
-
-				if !utils.Is.Empty(user.Json["nickname"]) {
-					this.json(ctx, user.Json, "成功", 200)
-					return
-				}
-			}
-		}
+	state, err := model.CreateOAuthState(provider.Name, action, uid)
+	if err != nil {
+		this.json(ctx, nil, err.Error(), 500)
+		return
+	}
 
-		this.json(ctx, item.Json, "成功", 200)
+	url, err := provider.AuthorizeURL(state.State, state.Challenge, state.Nonce)
+	if err != nil {
+		this.json(ctx, nil, facade.Lang(ctx, err.Error()), 500)
+		return
 	}
+
+	// 授权状态与当前浏览器绑定，回调时校验，防止他人诱导当前浏览器登录其第三方账号
+	ctx.SetCookie(oauthBrowserCookie, state.Browser, 600, "/", cookieHost(ctx), false, true)
+
+	this.json(ctx, gin.H{
+		"url":   url,
+		"state": state.State,
+	}, facade.Lang(ctx, "数据请求成功！"), 200)
 }
 
-// github - github登录
-func (this *OAuth) github(ctx *gin.Context) {
+// callback - 回调页面提交 code 与 state，完成登录、自动注册或绑定
+func (this *OAuth) callback(ctx *gin.Context) {
 
 	params := this.params(ctx)
 
-	fmt.Println(ctx.Request.Method, params)
-
-	if !utils.Is.Empty(params["code"]) {
-
-		// access_token
-		item := utils.Curl(utils.CurlRequest{
-			Method: "GET",
-			Url:    "https://github.com/login/oauth/access_token",
-			Query: map[string]any{
-				"client_id":     "Iv1.cc978ca4f5d98345",
-				"client_secret": "cca6c49fdeb724f341bae59ccc1e400de12e6c63",
-				"code":          cast.ToString(params["code"]),
-			},
-			Headers: map[string]any{
-				"accept": "application/json",
-			},
-		}).Send()
-
-		if item.Error != nil {
-			this.json(ctx, nil, "获取token失败："+item.Error.Error(), 400)
+	if utils.Is.Empty(params["code"]) || utils.Is.Empty(params["state"]) {
+		this.json(ctx, nil, facade.Lang(ctx, "%s 不能为空！", "code、state"), 400)
+		return
+	}
+
+	state, err := model.ConsumeOAuthState(cast.ToString(params["state"]))
+	if err != nil {
+		this.json(ctx, nil, facade.Lang(ctx, err.Error()), 400)
+		return
+	}
+
+	browser, _ := ctx.Cookie(oauthBrowserCookie)
+	ctx.SetCookie(oauthBrowserCookie, "", -1, "/", cookieHost(ctx), false, true)
+	if utils.Is.Empty(browser) || browser != state.Browser {
+		this.json(ctx, nil, facade.Lang(ctx, model.ErrOAuthState.Error()), 400)
+		return
+	}
+
+	provider, err := facade.OAuthProviderOf(state.Provider)
+	if err != nil {
+		this.json(ctx, nil, facade.Lang(ctx, err.Error()), 400)
+		return
+	}
+
+	identity, err := provider.Identity(cast.ToString(params["code"]), state.Verifier, state.Nonce)
+	if err != nil {
+		facade.Log.Warn(map[string]any{"provider": provider.Name, "error": err.Error()}, "第三方登录获取用户身份失败")
+		this.json(ctx, nil, facade.Lang(ctx, "第三方登录失败：%v", err.Error()), 400)
+		return
+	}
+
+	if state.Action == model.OAuthActionLink {
+		this.link(ctx, state.Uid, provider, identity)
+		return
+	}
+
+	this.login(ctx, provider, identity)
+}
+
+// link - 绑定第三方账号
+func (this *OAuth) link(ctx *gin.Context, uid int, provider *facade.OAuthProvider, identity *facade.OAuthIdentity) {
+
+	// 发起绑定与完成绑定必须是同一用户
+	if this.user(ctx).Id != uid {
+		this.json(ctx, nil, facade.Lang(ctx, "请先登录！"), 401)
+		return
+	}
+
+	item, err := model.LinkUserIdentity(uid, provider.Name, identity)
+	if err != nil {
+		this.json(ctx, nil, facade.Lang(ctx, err.Error()), 400)
+		return
+	}
+
+	facade.Log.Info(map[string]any{"uid": uid, "provider": provider.Name}, "用户绑定第三方账号")
+
+	this.json(ctx, item, facade.Lang(ctx, "绑定成功！"), 200)
+}
+
+// login - 第三方账号登录，未绑定时按配置自动注册
+func (this *OAuth) login(ctx *gin.Context, provider *facade.OAuthProvider, identity *facade.OAuthIdentity) {
+
+	comm := &Comm{base: this.base}
+	table := model.Users{}
+
+	if link := model.FindUserIdentity(provider.Name, identity.Subject); link != nil {
+
+		item, _ := facade.DB.Model(&table).Where("id", link.Uid).Find()
+		if utils.Is.Empty(item) {
+			this.json(ctx, nil, facade.Lang(ctx, "账户不存在！"), 400)
 			return
 		}
 
-		if !utils.Is.Empty(item.Json["access_token"]) {
-			user := utils.Curl(utils.CurlRequest{
-				Method: "GET",
-				Url:    "https://api.github.com/user",
-				Headers: map[string]any{
-					"Authorization": "token " + cast.ToString(item.Json["access_token"]),
-					"accept":        "application/json",
-				},
-			}).Send()
-
-			if user.Error != nil {
-				this.json(ctx, nil, "获取_token失败："+item.Error.Error(), 400)
-				return
-			}
-
-			this.json(ctx, user.Json, "登录成功！", 200)
+		if !comm.checkLoginStatus(ctx, table) {
+			return
+		}
+
+		link.Touch(identity)
+
+		// 与密码登录一致：开启两步验证的账号需完成第二步
+		if model.TotpEnabled(table.Id) || model.TotpRequired(table.Id) {
+			comm.loginChallenge(ctx, table, table.Account)
+			return
+		}
+
+		comm.loginSuccess(ctx, table, item, table.Account, gin.H{"provider": provider.Name})
+		return
+	}
+
+	// 未绑定：自动注册
+	if !cast.ToBool(facade.OAuthToml.Get("auto_register", true)) || !cast.ToBool(comm.signInConfig(ctx)["value"]) {
+		this.json(ctx, nil, facade.Lang(ctx, "该第三方账号未绑定，请登录后在账号设置中绑定！"), 403)
+		return
+	}
+
+	// 不按邮箱自动关联已有账号，避免通过第三方平台接管他人账号
+	email := ""
+	if identity.EmailVerified && utils.Is.Email(identity.Email) {
+		exist, _ := facade.DB.Model(&model.Users{}).Where("email", identity.Email).Exist()
+		if exist {
+			this.json(ctx, nil, facade.Lang(ctx, "该邮箱已注册，请登录后在账号设置中绑定第三方账号！"), 409)
+			return
 		}
+		email = identity.Email
+	}
+
+	nickname := strings.TrimSpace(identity.Nickname)
+	if utils.Is.Empty(nickname) || facade.Comm.DetectXSS(nickname) {
+		nickname = fmt.Sprintf("用户_%v", utils.Rand.Number(6))
+	}
+	if runes := []rune(facade.Comm.SanitizeHTML(nickname)); len(runes) > 32 {
+		nickname = string(runes[:32])
+	} else {
+		nickname = string(runes)
+	}
+	table.Nickname = nickname
+	table.Email = email
+	if strings.HasPrefix(identity.Avatar, "https://") && !facade.Comm.DetectXSS(identity.Avatar) {
+		table.Avatar = identity.Avatar
+	}
+
+	if err := createUser(&table); err != nil {
+		this.json(ctx, nil, facade.Lang(ctx, err.Error()), 400)
+		return
+	}
+
+	if _, err := model.LinkUserIdentity(table.Id, provider.Name, identity); err != nil {
+		// 并发回调时同一第三方账号已被绑定，回滚刚创建的用户
+		facade.DB.Model(&model.Users{}).Force().Delete(table.Id)
+		this.json(ctx, nil, facade.Lang(ctx, err.Error()), 400)
+		return
+	}
+
+	// 添加默认权限
+	go comm.auth(table.Id)
+
+	model.TriggerWebhook(model.WebhookEventUserRegistered, map[string]any{
+		"id":       table.Id,
+		"account":  table.Account,
+		"nickname": table.Nickname,
+		"source":   table.Source,
+		"provider": provider.Name,
+	})
+
+	item, _ := facade.DB.Model(&table).Where("id", table.Id).Find()
+	comm.loginSuccess(ctx, table, item, table.Account, gin.H{"provider": provider.Name, "register": true})
+}
+
+// identities - 当前用户绑定的第三方账号
+func (this *OAuth) identities(ctx *gin.Context) {
+
+	uid := this.user(ctx).Id
+	if uid == 0 {
+		this.json(ctx, nil, facade.Lang(ctx, "请先登录！"), 401)
+		return
+	}
+
+	var items []model.UserIdentity
+	facade.DB.Drive().Where("uid = ?", uid).Order("id asc").Find(&items)
+
+	if len(items) == 0 {
+		this.json(ctx, gin.H{"data": []any{}, "count": 0}, facade.Lang(ctx, "无数据！"), 204)
+		return
+	}
+
+	this.json(ctx, gin.H{"data": items, "count": len(items)}, facade.Lang(ctx, "数据请求成功！"), 200)
+}
+
+// unlink - 解绑第三方账号
+func (this *OAuth) unlink(ctx *gin.Context) {
+
+	params := this.params(ctx)
+
+	uid := this.user(ctx).Id
+	if uid == 0 {
+		this.json(ctx, nil, facade.Lang(ctx, "请先登录！"), 401)
+		return
 	}
-}
\ No newline at end of file
+
+	if utils.Is.Empty(params["id"]) {
+		this.json(ctx, nil, facade.Lang(ctx, "%s 不能为空！", "id"), 400)
+		return
+	}
+
+	if err := model.UnlinkUserIdentity(uid, cast.ToInt(params["id"])); err != nil {
+		this.json(ctx, nil, facade.Lang(ctx, err.Error()), 400)
+		return
+	}
+
+	facade.Log.Info(map[string]any{"uid": uid, "id": params["id"]}, "用户解绑第三方账号")
+
+	this.json(ctx, gin.H{"id": params["id"]}, facade.Lang(ctx, "解绑成功！"), 200)
+}
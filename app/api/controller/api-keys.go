@@ -5,21 +5,21 @@ import (
 	"inis/app/model"
 	"inis/app/validator"
 	"math"
+	"net"
 	"strings"
 	"time"
 
 	"github.com/gin-gonic/gin"
-	"github.com/google/uuid"
 	"github.com/spf13/cast"
 	"github.com/unti-io/go-utils/utils"
 )
 
 const (
-	apiKeysAllowFields = "value,remark,json,text"
+	apiKeysAllowFields = "scopes,ip_allow,qps,expire_time,remark,json,text"
 	apiKeysAllowQuery  = "id"
 )
 
-var apiKeysAllowFieldsSlice = []any{"value", "remark", "json", "text"}
+var apiKeysAllowFieldsSlice = []any{"scopes", "ip_allow", "qps", "expire_time", "remark", "json", "text"}
 var apiKeysAllowQuerySlice = []any{"id"}
 
 type ApiKeys struct {
@@ -60,9 +60,6 @@ func (this *ApiKeys) setCache(ctx *gin.Context, cacheName string, data any) {
 }
 
 func (this *ApiKeys) processFieldValue(key string, val any) any {
-	if key == "value" {
-		val = strings.ToUpper(cast.ToString(val))
-	}
 	switch utils.Get.Type(val) {
 	case "map":
 		return utils.Json.Encode(val)
@@ -74,10 +71,45 @@ func (this *ApiKeys) processFieldValue(key string, val any) any {
 	return val
 }
 
-func (this *ApiKeys) generateAPIKey() string {
-	UUID := uuid.New().String()
-	UUID = strings.Replace(UUID, "-", "", -1)
-	return strings.ToUpper(UUID)
+// hideSecret - 移除密钥哈希与旧版明文字段
+func (this *ApiKeys) hideSecret(item map[string]any) map[string]any {
+	delete(item, "hash")
+	delete(item, "value")
+	return item
+}
+
+// checkParams - 校验权限范围与IP白名单
+func (this *ApiKeys) checkParams(ctx *gin.Context, params map[string]any) bool {
+
+	if value, ok := params["scopes"]; ok {
+		scopes := model.ApiKeySplit(strings.Join(cast.ToStringSlice(utils.Unity.Keys(value)), ","))
+		for _, scope := range scopes {
+			if strings.HasPrefix(scope, "[") && !strings.Contains(scope, "]") {
+				this.json(ctx, nil, facade.Lang(ctx, "权限范围 %v 格式不正确！", scope), 400)
+				return false
+			}
+		}
+		params["scopes"] = strings.Join(scopes, ",")
+	}
+
+	if value, ok := params["ip_allow"]; ok {
+		list := model.ApiKeySplit(strings.Join(cast.ToStringSlice(utils.Unity.Keys(value)), ","))
+		for _, item := range list {
+			_, _, err := net.ParseCIDR(item)
+			if net.ParseIP(item) == nil && err != nil {
+				this.json(ctx, nil, facade.Lang(ctx, "IP %v 格式不正确！", item), 400)
+				return false
+			}
+		}
+		params["ip_allow"] = strings.Join(list, ",")
+	}
+
+	if value, ok := params["qps"]; ok && cast.ToInt(value) < 0 {
+		this.json(ctx, nil, facade.Lang(ctx, "%s 不能小于 %d！", "qps", 0), 400)
+		return false
+	}
+
+	return true
 }
 
 func (this *ApiKeys) aggregateQuery(ctx *gin.Context, aggFunc func(query *facade.ModelStruct, field string) any) (any, string) {
@@ -130,6 +162,7 @@ func (this *ApiKeys) IGET(ctx *gin.Context) {
 		"rand":   this.rand,
 		"count":  this.count,
 		"column": this.column,
+		"usage":  this.usage,
 	}
 	err := this.call(allow, method, ctx)
 
@@ -162,6 +195,7 @@ func (this *ApiKeys) IPUT(ctx *gin.Context) {
 	allow := map[string]any{
 		"update":  this.update,
 		"restore": this.restore,
+		"reset":   this.reset,
 	}
 	err := this.call(allow, method, ctx)
 
@@ -221,7 +255,7 @@ func (this *ApiKeys) one(ctx *gin.Context) {
 		query := this.withTrashOptions(facade.DB.Model(&table), params)
 		query = this.buildQuery(query, params)
 		item, _ := query.Where(table).Find()
-		data = facade.Comm.WithField(item, params["field"])
+		data = facade.Comm.WithField(this.hideSecret(item), params["field"])
 		this.setCache(ctx, cacheName, data)
 	}
 
@@ -258,6 +292,9 @@ func (this *ApiKeys) all(ctx *gin.Context) {
 		data = cached
 	} else {
 		item, _ := query.Where(table).Limit(limit).Page(page).Order(params["order"]).Select()
+		for _, val := range item {
+			this.hideSecret(val)
+		}
 		data = utils.ArrayMapWithField(item, params["field"])
 		this.setCache(ctx, cacheName, data)
 	}
@@ -293,6 +330,9 @@ func (this *ApiKeys) rand(ctx *gin.Context) {
 	mold = this.buildQuery(mold, params)
 
 	items, _ := mold.Select()
+	for _, item := range items {
+		this.hideSecret(item)
+	}
 	data := utils.Array.MapWithField(utils.Rand.MapSlice(items), params["field"])
 
 	if utils.Is.Empty(data) {
@@ -313,6 +353,7 @@ func (this *ApiKeys) save(ctx *gin.Context) {
 	}
 }
 
+// create - 创建密钥，明文仅在此时返回一次（value 为空时自动生成）
 func (this *ApiKeys) create(ctx *gin.Context) {
 	params := this.params(ctx)
 	err := validator.NewValid("api-keys", params)
@@ -322,6 +363,10 @@ func (this *ApiKeys) create(ctx *gin.Context) {
 		return
 	}
 
+	if !this.checkParams(ctx, params) {
+		return
+	}
+
 	now := time.Now().Unix()
 	table := model.ApiKeys{CreateTime: now, UpdateTime: now}
 
@@ -331,13 +376,16 @@ func (this *ApiKeys) create(ctx *gin.Context) {
 		}
 	}
 
-	if utils.Is.Empty(table.Value) {
-		table.Value = this.generateAPIKey()
+	secret := table.SetValue(cast.ToString(params["value"]))
+	signSecret, err := table.SetSecret()
+	if err != nil {
+		this.json(ctx, nil, err.Error(), 500)
+		return
 	}
 
-	exist, _ := facade.DB.Model(&table).Where("value", table.Value).Exist()
+	exist, _ := facade.DB.Model(&model.ApiKeys{}).WithTrashed().Where("hash", table.Hash).Exist()
 	if exist {
-		this.json(ctx, nil, facade.Lang(ctx, "%s 已经存在！", table.Value), 400)
+		this.json(ctx, nil, facade.Lang(ctx, "%s 已经存在！", "API_KEY"), 400)
 		return
 	}
 
@@ -348,7 +396,13 @@ func (this *ApiKeys) create(ctx *gin.Context) {
 		return
 	}
 
-	this.json(ctx, gin.H{"id": table.Id}, facade.Lang(ctx, "创建成功！"), 200)
+	this.json(ctx, gin.H{
+		"id":      table.Id,
+		"prefix":  table.Prefix,
+		"api_key": secret,
+		"key_id":  table.KeyId,
+		"secret":  signSecret,
+	}, facade.Lang(ctx, "创建成功！"), 200)
 }
 
 func (this *ApiKeys) update(ctx *gin.Context) {
@@ -365,6 +419,10 @@ func (this *ApiKeys) update(ctx *gin.Context) {
 		return
 	}
 
+	if !this.checkParams(ctx, params) {
+		return
+	}
+
 	table := model.ApiKeys{}
 	async := utils.Async[map[string]any]()
 
@@ -374,26 +432,92 @@ func (this *ApiKeys) update(ctx *gin.Context) {
 		}
 	}
 
-	if utils.Is.Empty(async.Get("value")) {
-		async.Set("value", this.generateAPIKey())
+	_, err = facade.DB.Model(&table).WithTrashed().Where("id", params["id"]).Scan(&table).Update(async.Result())
+
+	if err != nil {
+		this.json(ctx, nil, err.Error(), 400)
+		return
 	}
 
-	keyVal := cast.ToString(async.Get("value"))
-	item, _ := facade.DB.Model(&table).Where("value", keyVal).Find()
+	this.json(ctx, gin.H{"id": table.Id}, facade.Lang(ctx, "更新成功！"), 200)
+}
+
+// reset - 重置密钥（旧密钥立即失效），新密钥明文仅在此时返回一次
+func (this *ApiKeys) reset(ctx *gin.Context) {
+
+	params := this.params(ctx)
 
-	if !utils.Is.Empty(item) && item["value"] != keyVal {
-		this.json(ctx, nil, facade.Lang(ctx, "%s 已经存在！", keyVal), 400)
+	if utils.Is.Empty(params["id"]) {
+		this.json(ctx, nil, facade.Lang(ctx, "%s 不能为空！", "id"), 400)
 		return
 	}
 
-	_, err = facade.DB.Model(&table).WithTrashed().Where("id", params["id"]).Scan(&table).Update(async.Result())
+	table := model.ApiKeys{}
+	secret := table.SetValue("")
+	signSecret, err := table.SetSecret()
+	if err != nil {
+		this.json(ctx, nil, err.Error(), 500)
+		return
+	}
 
+	_, err = facade.DB.Model(&model.ApiKeys{}).Where("id", params["id"]).Update(map[string]any{
+		"hash":   table.Hash,
+		"prefix": table.Prefix,
+		"key_id": table.KeyId,
+		"secret": table.Secret,
+	})
 	if err != nil {
 		this.json(ctx, nil, err.Error(), 400)
 		return
 	}
 
-	this.json(ctx, gin.H{"id": table.Id}, facade.Lang(ctx, "更新成功！"), 200)
+	facade.Log.Info(map[string]any{"id": params["id"], "operator_id": this.user(ctx).Id}, "管理员重置接口密钥")
+
+	this.json(ctx, gin.H{
+		"id":      params["id"],
+		"prefix":  table.Prefix,
+		"api_key": secret,
+		"key_id":  table.KeyId,
+		"secret":  signSecret,
+	}, facade.Lang(ctx, "重置成功！"), 200)
+}
+
+// usage - 密钥使用情况（按最后使用时间排序，包含过期状态）
+func (this *ApiKeys) usage(ctx *gin.Context) {
+
+	params := this.params(ctx, map[string]any{
+		"order": "last_time desc",
+	})
+
+	// 先写入内存中尚未落库的使用记录
+	model.FlushApiKeyUsage()
+
+	items, _ := facade.DB.Model(&[]model.ApiKeys{}).IWhere(params["where"]).Order(params["order"]).Select()
+
+	if utils.Is.Empty(items) {
+		this.json(ctx, []any{}, facade.Lang(ctx, "无数据！"), 204)
+		return
+	}
+
+	now := time.Now().Unix()
+	result := make([]map[string]any, 0, len(items))
+	for _, item := range items {
+		expire := cast.ToInt64(item["expire_time"])
+		result = append(result, map[string]any{
+			"id":          item["id"],
+			"prefix":      item["prefix"],
+			"remark":      item["remark"],
+			"scopes":      item["scopes"],
+			"qps":         item["qps"],
+			"use_count":   item["use_count"],
+			"last_time":   item["last_time"],
+			"last_ip":     item["last_ip"],
+			"expire_time": expire,
+			"expired":     expire > 0 && expire <= now,
+		})
+	}
+
+	this.json(ctx, result, facade.Lang(ctx, "数据请求成功！"), 200)
 }
 
 func (this *ApiKeys) count(ctx *gin.Context) {
@@ -460,6 +584,9 @@ func (this *ApiKeys) column(ctx *gin.Context) {
 		data = cached
 	} else {
 		items, _ := query.Select()
+		for _, item := range items {
+			this.hideSecret(item)
+		}
 		data = utils.ArrayMapWithField(items, params["field"])
 		this.setCache(ctx, cacheName, data)
 	}
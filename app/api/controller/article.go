@@ -27,6 +27,11 @@ const (
 var articleAllowFieldsSlice = []any{"title", "abstract", "content", "covers", "tags", "group", "editor", "remark", "json", "text", "publish_time", "status"}
 var articleAllowQuerySlice = []any{"id"}
 
+func init() {
+	// 以下接口会调用 meta.can / meta.owned 判断策略中的数据条件（如 article.uid == user.id）
+	model.RegisterAuthCheck("[PUT]/api/article/update", "[DELETE]/api/article/remove", "[DELETE]/api/article/delete")
+}
+
 func (this *Article) buildQuery(query *facade.ModelStruct, params map[string]any) *facade.ModelStruct {
 	return query.
 		IWhere(params["where"]).
@@ -356,6 +361,17 @@ func (this *Article) create(ctx *gin.Context) {
 		}()
 	}
 
+	// 已发布且无需审核时推送 Webhook
+	if status == 1 && table.Audit == 1 {
+		this.published(map[string]any{
+			"id":           table.Id,
+			"uid":          table.Uid,
+			"title":        table.Title,
+			"abstract":     table.Abstract,
+			"publish_time": table.PublishTime,
+		})
+	}
+
 	if status == 0 {
 		this.json(ctx, gin.H{"id": table.Id}, facade.Lang(ctx, "草稿保存成功！"), 200)
 	} else {
@@ -386,8 +402,19 @@ func (this *Article) update(ctx *gin.Context) {
 		allowFields = append(allowFields, "top", "audit")
 	}
 
-	// 获取状态：0-草稿，1-发布
+	item := facade.DB.Model(&table).WithTrashed().Where("id", params["id"])
+
+	findResult, _ := item.Find()
+	if !this.meta.can(ctx, "article", findResult) {
+		this.json(ctx, nil, facade.Lang(ctx, "无权限！"), 403)
+		return
+	}
+
+	// 获取状态：0-草稿，1-发布（未传时沿用当前状态，如管理员只修改审核状态）
 	status := cast.ToInt(params["status"])
+	if _, ok := params["status"]; !ok {
+		status = cast.ToInt(findResult["status"])
+	}
 
 	if status == 0 {
 		// 草稿：跳过审核，不设置发布时间
@@ -412,14 +439,6 @@ func (this *Article) update(ctx *gin.Context) {
 
 	async.Set("last_update", time.Now().Unix())
 
-	item := facade.DB.Model(&table).WithTrashed().Where("id", params["id"])
-
-	findResult, _ := item.Find()
-	if !root && cast.ToInt(findResult["uid"]) != this.user(ctx).Id {
-		this.json(ctx, nil, facade.Lang(ctx, "无权限！"), 403)
-		return
-	}
-
 	_, err = item.Scan(&table).Update(async.Result())
 
 	if err != nil {
@@ -427,6 +446,13 @@ func (this *Article) update(ctx *gin.Context) {
 		return
 	}
 
+	// 由草稿或待审核变为已发布（包括管理员审核通过）时推送 Webhook
+	if cast.ToInt(findResult["status"]) != 1 || cast.ToInt(findResult["audit"]) != 1 {
+		if after, _ := facade.DB.Model(&model.Article{}).WithTrashed().Where("id", params["id"]).Find(); this.isPublished(after) {
+			this.published(after)
+		}
+	}
+
 	if status == 0 {
 		this.json(ctx, gin.H{"id": table.Id}, facade.Lang(ctx, "草稿保存成功！"), 200)
 	} else {
@@ -434,6 +460,22 @@ func (this *Article) update(ctx *gin.Context) {
 	}
 }
 
+// isPublished - 文章是否处于已发布状态（已发布且已通过审核）
+func (this *Article) isPublished(item map[string]any) bool {
+	return !utils.Is.Empty(item) && cast.ToInt(item["status"]) == 1 && cast.ToInt(item["audit"]) == 1
+}
+
+// published - 推送文章发布 Webhook
+func (this *Article) published(item map[string]any) {
+	model.TriggerWebhook(model.WebhookEventArticlePublished, map[string]any{
+		"id":           item["id"],
+		"uid":          item["uid"],
+		"title":        item["title"],
+		"abstract":     item["abstract"],
+		"publish_time": item["publish_time"],
+	})
+}
+
 func (this *Article) count(ctx *gin.Context) {
 	params := this.params(ctx)
 	query := this.withTrashOptions(facade.DB.Model(&model.Article{}), params)
@@ -563,12 +605,11 @@ func (this *Article) remove(ctx *gin.Context) {
 	}
 
 	item := facade.DB.Model(&model.Article{})
-	if !this.meta.root(ctx) {
-		item.Where("uid", this.user(ctx).Id)
-	}
 
-	columnData, _ := item.WhereIn("id", ids).Column("id")
-	ids = utils.Unity.Ids(columnData)
+	// 筛选可以操作的数据（root、作者本人或策略条件允许）
+	var rows []map[string]any
+	facade.DB.Drive().Model(&model.Article{}).Where("id IN ?", ids).Find(&rows)
+	ids = this.meta.owned(ctx, "article", rows)
 
 	if utils.Is.Empty(ids) {
 		this.json(ctx, nil, facade.Lang(ctx, "无可操作数据！"), 204)
@@ -595,11 +636,11 @@ func (this *Article) delete(ctx *gin.Context) {
 	}
 
 	item := facade.DB.Model(&model.Article{}).WithTrashed()
-	if !this.meta.root(ctx) {
-		item.Where("uid", this.user(ctx).Id)
-	}
 
-	ids = utils.Unity.Ids(item.WhereIn("id", ids).Column("id"))
+	// 筛选可以操作的数据（root、作者本人或策略条件允许）
+	var rows []map[string]any
+	facade.DB.Drive().Model(&model.Article{}).Unscoped().Where("id IN ?", ids).Find(&rows)
+	ids = this.meta.owned(ctx, "article", rows)
 
 	if utils.Is.Empty(ids) {
 		this.json(ctx, nil, facade.Lang(ctx, "无可操作数据！"), 204)
@@ -242,16 +242,17 @@ func (this *Attachment) releaseUploadSlot(count int) {
 func (this *Attachment) IGET(ctx *gin.Context) {
 	method := strings.ToLower(ctx.Param("method"))
 	allow := map[string]any{
-		"one":    this.one,
-		"all":    this.all,
-		"sum":    this.sum,
-		"min":    this.min,
-		"max":    this.max,
-		"rand":   this.rand,
-		"count":  this.count,
-		"column": this.column,
-		"list":   this.list,
-		"emoji":  this.emoji,
+		"one":        this.one,
+		"all":        this.all,
+		"sum":        this.sum,
+		"min":        this.min,
+		"max":        this.max,
+		"rand":       this.rand,
+		"count":      this.count,
+		"column":     this.column,
+		"list":       this.list,
+		"emoji":      this.emoji,
+		"quarantine": this.quarantine,
 	}
 	err := this.call(allow, method, ctx)
 	if err != nil {
@@ -295,9 +296,10 @@ func (this *Attachment) IPUT(ctx *gin.Context) {
 func (this *Attachment) IDEL(ctx *gin.Context) {
 	method := strings.ToLower(ctx.Param("method"))
 	allow := map[string]any{
-		"remove": this.remove,
-		"delete": this.delete,
-		"clear":  this.clear,
+		"remove":     this.remove,
+		"delete":     this.delete,
+		"clear":      this.clear,
+		"quarantine": this.purge,
 	}
 	err := this.call(allow, method, ctx)
 	if err != nil {
@@ -334,7 +336,7 @@ func (this *Attachment) one(ctx *gin.Context) {
 		msg[1] = "（来自缓存）"
 		data = cached
 	} else {
-		query := this.withTrashOptions(facade.DB.Model(&table), params)
+		query := this.withTrashOptions(model.AttachmentModel(&table), params)
 		query = this.buildQuery(query, params)
 
 		item, _ := query.Where(table).Find()
@@ -368,7 +370,7 @@ func (this *Attachment) all(ctx *gin.Context) {
 		limit = 100
 	}
 
-	query := this.withTrashOptions(facade.DB.Model(&[]model.Attachment{}), params)
+	query := this.withTrashOptions(model.AttachmentModel(&[]model.Attachment{}), params)
 	query = this.buildQuery(query, params)
 
 	if !this.meta.root(ctx) {
@@ -426,7 +428,7 @@ func (this *Attachment) rand(ctx *gin.Context) {
 	onlyTrashed := cast.ToBool(params["onlyTrashed"])
 	withTrashed := cast.ToBool(params["withTrashed"])
 
-	query := facade.DB.Model(&[]model.Attachment{}).OnlyTrashed(onlyTrashed).WithTrashed(withTrashed)
+	query := model.AttachmentModel(&[]model.Attachment{}).OnlyTrashed(onlyTrashed).WithTrashed(withTrashed)
 	if !utils.Is.Empty(except) {
 		query = query.Where("id", "NOT IN", except)
 	}
@@ -450,7 +452,7 @@ func (this *Attachment) rand(ctx *gin.Context) {
 
 func (this *Attachment) count(ctx *gin.Context) {
 	params := this.params(ctx)
-	query := this.withTrashOptions(facade.DB.Model(&model.Attachment{}), params)
+	query := this.withTrashOptions(model.AttachmentModel(&model.Attachment{}), params)
 	query = this.buildQuery(query, params)
 
 	if !this.meta.root(ctx) {
@@ -466,7 +468,7 @@ func (this *Attachment) aggregateQuery(ctx *gin.Context, aggFunc func(query *fac
 	var data any
 
 	params := this.params(ctx)
-	query := this.withTrashOptions(facade.DB.Model(&model.Attachment{}), params)
+	query := this.withTrashOptions(model.AttachmentModel(&model.Attachment{}), params)
 	query = this.buildQuery(query, params).Order(params["order"])
 
 	if !this.meta.root(ctx) {
@@ -557,7 +559,7 @@ func (this *Attachment) column(ctx *gin.Context) {
 		return
 	}
 
-	query := this.withTrashOptions(facade.DB.Model(&[]model.Attachment{}), params)
+	query := this.withTrashOptions(model.AttachmentModel(&[]model.Attachment{}), params)
 	query = this.buildQuery(query, params).Order(params["order"])
 
 	if !this.meta.root(ctx) {
@@ -713,6 +715,28 @@ func (this *Attachment) uploadSingleFile(ctx *gin.Context, fileHeader *multipart
 	}
 
 	saveName := fmt.Sprintf("%d_%d%s", time.Now().UnixNano()/1e6, utils.Rand.Int(1000, 9999), suffix)
+
+	// 安全扫描 - 在写入正式存储之前完成
+	scanStatus, scanResult := facade.ScanStatusSkipped, ""
+	if facade.Scanner != nil {
+		scan := this.scanFile(uploadReader)
+		switch {
+		case scan.Status == facade.ScanStatusInfected:
+			this.quarantineFile(uploadReader, fileName, saveName, fileHeader.Size, mimeType, fileExt, userId, scan.Signature)
+			result.Error = fmt.Errorf("文件未通过安全扫描，已被隔离！")
+			return result
+		case scan.Error != nil:
+			facade.Log.Error(map[string]any{"error": scan.Error, "file": fileName}, "上传文件安全扫描失败")
+			if !facade.ScannerConfigInstance.FailOpen {
+				result.Error = fmt.Errorf("文件安全扫描失败，请稍后重试！")
+				return result
+			}
+			scanStatus, scanResult = facade.ScanStatusError, scan.Error.Error()
+		default:
+			scanStatus = facade.ScanStatusClean
+		}
+	}
+
 	hash := sha256.New()
 	hashReader := io.TeeReader(uploadReader, hash)
 	item := facade.Storage.Upload(facade.Storage.Path()+suffix, hashReader)
@@ -742,7 +766,10 @@ func (this *Attachment) uploadSingleFile(ctx *gin.Context, fileHeader *multipart
 		MimeType: mimeType, FileExt: fileExt,
 		StorageDriver: cast.ToString(facade.StorageToml.Get("default")), UploaderId: userId,
 		TargetType: cast.ToString(params["target_type"]), TargetId: cast.ToUint(params["target_id"]),
-		FileHash: fileHash,
+		FileHash: fileHash, ScanStatus: scanStatus, ScanResult: scanResult,
+	}
+	if scanStatus != facade.ScanStatusSkipped {
+		attachment.ScanTime = time.Now().Unix()
 	}
 	_, err = facade.DB.Model(&attachment).Create(&attachment)
 	if err != nil {
@@ -756,6 +783,115 @@ func (this *Attachment) uploadSingleFile(ctx *gin.Context, fileHeader *multipart
 	return result
 }
 
+// scanFile - 扫描上传文件，扫描完成后将文件指针重置到开头
+func (this *Attachment) scanFile(reader io.Reader) *facade.ScanResponse {
+
+	seeker, ok := reader.(io.Seeker)
+	if !ok {
+		return &facade.ScanResponse{Status: facade.ScanStatusError, Error: fmt.Errorf("文件流不支持重置")}
+	}
+
+	result := facade.Scanner.Scan(reader)
+
+	if _, err := seeker.Seek(0, io.SeekStart); err != nil && result.Error == nil {
+		result.Status, result.Error = facade.ScanStatusError, err
+	}
+
+	return result
+}
+
+// quarantineFile - 将未通过扫描的文件写入隔离区并记录
+func (this *Attachment) quarantineFile(reader io.Reader, fileName, saveName string, size int64, mimeType, fileExt string, userId uint, signature string) {
+
+	hash := sha256.New()
+	item := facade.Quarantine.Upload(facade.Quarantine.Path(), io.TeeReader(reader, hash))
+	if item.Error != nil {
+		facade.Log.Error(map[string]any{"error": item.Error, "file": fileName}, "隔离文件写入失败")
+	}
+
+	attachment := model.Attachment{
+		Uuid: (&model.Attachment{}).GenerateUUID(), OriginalName: fileName, SaveName: saveName,
+		SavePath: item.Path, FileSize: size, MimeType: mimeType, FileExt: fileExt,
+		StorageDriver: facade.StorageModeQuarantine, UploaderId: userId,
+		FileHash: fmt.Sprintf("%x", hash.Sum(nil)), ScanStatus: facade.ScanStatusInfected,
+		ScanResult: signature, ScanTime: time.Now().Unix(),
+	}
+	if _, err := facade.DB.Model(&attachment).Create(&attachment); err != nil {
+		facade.Log.Error(map[string]any{"error": err, "file": fileName}, "隔离文件记录保存失败")
+	}
+
+	facade.Log.Info(map[string]any{
+		"file":      fileName,
+		"signature": signature,
+		"uploader":  userId,
+	}, "上传文件未通过安全扫描，已隔离")
+}
+
+// quarantine - 隔离区文件列表（仅管理员）
+func (this *Attachment) quarantine(ctx *gin.Context) {
+
+	if !this.meta.root(ctx) {
+		this.json(ctx, nil, facade.Lang(ctx, "无权限！"), 403)
+		return
+	}
+
+	params := this.params(ctx, map[string]any{"page": 1, "order": "create_time desc"})
+	limit := this.meta.limit(ctx)
+
+	query := facade.DB.Model(&[]model.Attachment{}).Where("scan_status", facade.ScanStatusInfected)
+	count, _ := query.Count()
+	data, _ := query.Limit(limit).Page(cast.ToInt(params["page"])).Order(params["order"]).Select()
+
+	if utils.Is.Empty(data) {
+		this.json(ctx, gin.H{"data": []any{}, "count": 0, "page": 0}, facade.Lang(ctx, "无数据！"), 204)
+		return
+	}
+
+	this.json(ctx, gin.H{"data": data, "count": count, "page": math.Ceil(float64(count) / float64(limit))}, facade.Lang(ctx, "查询成功！"), 200)
+}
+
+// purge - 彻底删除隔离区文件（仅管理员）
+func (this *Attachment) purge(ctx *gin.Context) {
+
+	if !this.meta.root(ctx) {
+		this.json(ctx, nil, facade.Lang(ctx, "无权限！"), 403)
+		return
+	}
+
+	params := this.params(ctx)
+	ids := utils.Unity.Ids(params["ids"])
+	if utils.Is.Empty(ids) {
+		this.json(ctx, nil, facade.Lang(ctx, "%s 不能为空！", "ids"), 400)
+		return
+	}
+
+	query := facade.DB.Model(&model.Attachment{}).WithTrashed().Where("scan_status", facade.ScanStatusInfected).WhereIn("id", ids)
+	items, _ := query.Select()
+	if len(items) == 0 {
+		this.json(ctx, nil, facade.Lang(ctx, "无数据！"), 204)
+		return
+	}
+
+	var paths []string
+	var successIds []any
+	for _, item := range items {
+		successIds = append(successIds, item["id"])
+		if path := cast.ToString(item["save_path"]); path != "" {
+			paths = append(paths, path)
+		}
+	}
+	_ = facade.Quarantine.DeleteMulti(paths)
+
+	if _, err := facade.DB.Model(&model.Attachment{}).WithTrashed().Force().Delete(successIds); err != nil {
+		this.json(ctx, nil, err.Error(), 400)
+		return
+	}
+
+	facade.Log.Info(map[string]any{"user_id": this.meta.user(ctx).Id, "ids": successIds}, "删除隔离区文件")
+
+	this.json(ctx, gin.H{"ids": successIds}, facade.Lang(ctx, "删除成功！"), 200)
+}
+
 func (this *Attachment) checkType(ctx *gin.Context) {
 	params := this.params(ctx)
 	fileNames, ok := params["file_names"].([]any)
@@ -924,9 +1060,9 @@ func (this *Attachment) update(ctx *gin.Context) {
 	var query *facade.ModelStruct
 
 	if !utils.Is.Empty(params["uuid"]) {
-		query = facade.DB.Model(&table).WithTrashed().Where("uuid", params["uuid"])
+		query = model.AttachmentModel(&table).WithTrashed().Where("uuid", params["uuid"])
 	} else {
-		query = facade.DB.Model(&table).WithTrashed().Where("id", params["id"])
+		query = model.AttachmentModel(&table).WithTrashed().Where("id", params["id"])
 	}
 
 	item, _ := query.Find()
@@ -986,7 +1122,7 @@ func (this *Attachment) remove(ctx *gin.Context) {
 		return
 	}
 
-	query := facade.DB.Model(&model.Attachment{}).WhereIn("id", ids)
+	query := model.AttachmentModel(&model.Attachment{}).WhereIn("id", ids)
 	if !this.meta.root(ctx) {
 		query = query.Where("uploader_id", this.meta.user(ctx).Id)
 	}
@@ -1045,7 +1181,7 @@ func (this *Attachment) delete(ctx *gin.Context) {
 		return
 	}
 
-	items, _ := facade.DB.Model(&model.Attachment{}).WithTrashed().WhereIn("id", ids).Select()
+	items, _ := model.AttachmentModel(&model.Attachment{}).WithTrashed().WhereIn("id", ids).Select()
 
 	validIdSet := make(map[any]bool)
 	for _, item := range items {
@@ -1119,7 +1255,7 @@ func (this *Attachment) clear(ctx *gin.Context) {
 		return
 	}
 
-	item := facade.DB.Model(&model.Attachment{}).OnlyTrashed()
+	item := model.AttachmentModel(&model.Attachment{}).OnlyTrashed()
 	columnData, _ := item.Column("id")
 	ids := utils.Unity.Ids(columnData)
 
@@ -1128,7 +1264,7 @@ func (this *Attachment) clear(ctx *gin.Context) {
 		return
 	}
 
-	items, _ := facade.DB.Model(&model.Attachment{}).OnlyTrashed().WhereIn("id", ids).Select()
+	items, _ := model.AttachmentModel(&model.Attachment{}).OnlyTrashed().WhereIn("id", ids).Select()
 
 	filesByDriver := make(map[string][]string)
 	for _, item := range items {
@@ -1177,7 +1313,7 @@ func (this *Attachment) restore(ctx *gin.Context) {
 		return
 	}
 
-	item := facade.DB.Model(&model.Attachment{}).OnlyTrashed().WhereIn("id", ids)
+	item := model.AttachmentModel(&model.Attachment{}).OnlyTrashed().WhereIn("id", ids)
 	if !this.meta.root(ctx) {
 		item = item.Where("uploader_id", this.meta.user(ctx).Id)
 	}
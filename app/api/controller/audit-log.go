@@ -0,0 +1,182 @@
+package controller
+
+import (
+	"bytes"
+	"encoding/csv"
+	"fmt"
+	"inis/app/facade"
+	"inis/app/model"
+	"math"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/spf13/cast"
+	"github.com/unti-io/go-utils/utils"
+)
+
+// auditLogExportFields - 导出的字段（按顺序）
+var auditLogExportFields = []string{
+	"id", "create_time", "uid", "account", "method", "path", "name", "target", "target_ids",
+	"code", "msg", "ip", "ua", "params", "before", "after", "diff",
+}
+
+// AuditLog - 审计日志（只读，由审计中间件写入，按保留天数自动清理）
+type AuditLog struct {
+	base
+}
+
+func (this *AuditLog) IGET(ctx *gin.Context) {
+	method := strings.ToLower(ctx.Param("method"))
+
+	allow := map[string]any{
+		"one":    this.one,
+		"all":    this.all,
+		"count":  this.count,
+		"export": this.export,
+	}
+	err := this.call(allow, method, ctx)
+
+	if err != nil {
+		this.json(ctx, nil, facade.Lang(ctx, "方法调用错误：%v", err.Error()), 405)
+		return
+	}
+}
+
+func (this *AuditLog) IPOST(ctx *gin.Context) {
+	this.json(ctx, nil, facade.Lang(ctx, "不支持POST请求！"), 405)
+}
+
+func (this *AuditLog) IPUT(ctx *gin.Context) {
+	this.json(ctx, nil, facade.Lang(ctx, "不支持PUT请求！"), 405)
+}
+
+func (this *AuditLog) IDEL(ctx *gin.Context) {
+	this.json(ctx, nil, facade.Lang(ctx, "不支持DELETE请求！"), 405)
+}
+
+func (this *AuditLog) INDEX(ctx *gin.Context) {
+	this.json(ctx, nil, facade.Lang(ctx, "没什么用！"), 202)
+}
+
+// query - 查询条件（支持 where/like 以及常用的快捷筛选）
+func (this *AuditLog) query(params map[string]any) *facade.ModelStruct {
+
+	query := facade.DB.Model(&[]model.AuditLog{}).IWhere(params["where"]).ILike(params["like"])
+
+	for _, key := range []string{"uid", "method", "target", "ip", "code"} {
+		if !utils.Is.Empty(params[key]) {
+			query = query.Where(key, params[key])
+		}
+	}
+	if !utils.Is.Empty(params["path"]) {
+		query = query.Where("path", "like", cast.ToString(params["path"])+"%")
+	}
+	if !utils.Is.Empty(params["start_time"]) {
+		query = query.Where("create_time", ">=", cast.ToInt64(params["start_time"]))
+	}
+	if !utils.Is.Empty(params["end_time"]) {
+		query = query.Where("create_time", "<=", cast.ToInt64(params["end_time"]))
+	}
+
+	return query
+}
+
+func (this *AuditLog) one(ctx *gin.Context) {
+
+	params := this.params(ctx)
+
+	if utils.Is.Empty(params["id"]) {
+		this.json(ctx, nil, facade.Lang(ctx, "%s 不能为空！", "id"), 400)
+		return
+	}
+
+	item, _ := facade.DB.Model(&model.AuditLog{}).Where("id", params["id"]).Find()
+	if utils.Is.Empty(item) {
+		this.json(ctx, nil, facade.Lang(ctx, "无数据！"), 204)
+		return
+	}
+
+	this.json(ctx, facade.Comm.WithField(item, params["field"]), facade.Lang(ctx, "数据请求成功！"), 200)
+}
+
+func (this *AuditLog) all(ctx *gin.Context) {
+
+	params := this.params(ctx, map[string]any{
+		"page":  1,
+		"order": "id desc",
+	})
+
+	page := cast.ToInt(params["page"])
+	limit := this.meta.limit(ctx)
+
+	query := this.query(params)
+
+	count, _ := query.Count()
+	items, _ := query.Limit(limit).Page(page).Order(params["order"]).Select()
+
+	if utils.Is.Empty(items) {
+		this.json(ctx, gin.H{"data": []any{}, "count": 0, "page": 0}, facade.Lang(ctx, "无数据！"), 204)
+		return
+	}
+
+	this.json(ctx, gin.H{
+		"data":  utils.ArrayMapWithField(items, params["field"]),
+		"count": count,
+		"page":  math.Ceil(float64(count) / float64(limit)),
+	}, facade.Lang(ctx, "数据请求成功！"), 200)
+}
+
+func (this *AuditLog) count(ctx *gin.Context) {
+	params := this.params(ctx)
+	count, _ := this.query(params).Count()
+	this.json(ctx, count, facade.Lang(ctx, "查询成功！"), 200)
+}
+
+// export - 导出审计日志（format 为 csv 或 json，单次最多导出 audit.export_limit 条）
+func (this *AuditLog) export(ctx *gin.Context) {
+
+	params := this.params(ctx, map[string]any{
+		"format": "csv",
+		"order":  "id desc",
+	})
+
+	format := strings.ToLower(cast.ToString(params["format"]))
+	if format != "csv" && format != "json" {
+		this.json(ctx, nil, facade.Lang(ctx, "不支持的导出格式：%v", format), 400)
+		return
+	}
+
+	limit := cast.ToInt(facade.AppToml.Get("audit.export_limit", 10000))
+	items, _ := this.query(params).Limit(limit).Order(params["order"]).Select()
+
+	name := fmt.Sprintf("audit-log-%s.%s", time.Now().Format("20060102-150405"), format)
+	ctx.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="%s"`, name))
+
+	if format == "json" {
+		ctx.Data(http.StatusOK, "application/json; charset=utf-8", []byte(utils.Json.Encode(items)))
+		return
+	}
+
+	buffer := &bytes.Buffer{}
+	// UTF-8 BOM，避免 Excel 打开时中文乱码
+	buffer.WriteString("\xEF\xBB\xBF")
+
+	writer := csv.NewWriter(buffer)
+	_ = writer.Write(auditLogExportFields)
+	for _, item := range items {
+		row := make([]string, len(auditLogExportFields))
+		for i, field := range auditLogExportFields {
+			if field == "create_time" {
+				row[i] = time.Unix(cast.ToInt64(item[field]), 0).Format("2006-01-02 15:04:05")
+				continue
+			}
+			row[i] = cast.ToString(item[field])
+		}
+		_ = writer.Write(row)
+	}
+	writer.Flush()
+
+	ctx.Data(http.StatusOK, "text/csv; charset=utf-8", buffer.Bytes())
+}
@@ -1,7 +1,7 @@
 package controller
 
 import (
-	"fmt"
+	"encoding/json"
 	"github.com/gin-gonic/gin"
 	"github.com/spf13/cast"
 	"github.com/unti-io/go-utils/utils"
@@ -18,11 +18,11 @@ type AuthGroup struct {
 }
 
 const (
-	authGroupAllowFields = "name,key,rules,uids,root,pages,remark,json,text"
+	authGroupAllowFields = "name,key,rules,policies,uids,root,pages,remark,json,text"
 	authGroupAllowQuery  = "id"
 )
 
-var authGroupAllowFieldsSlice = []any{"name", "key", "rules", "uids", "root", "pages", "remark", "json", "text"}
+var authGroupAllowFieldsSlice = []any{"name", "key", "rules", "policies", "uids", "root", "pages", "remark", "json", "text"}
 var authGroupAllowQuerySlice = []any{"id"}
 
 func (this *AuthGroup) buildQuery(query *facade.ModelStruct, params map[string]any) *facade.ModelStruct {
@@ -70,6 +70,22 @@ func (this *AuthGroup) processFieldValue(val any) any {
 	return val
 }
 
+// fieldValue - 处理字段值，policies 校验后规范化为 JSON，uids 由 AuthGroupUser 维护（返回 false 表示跳过）
+func (this *AuthGroup) fieldValue(key string, val any) (any, bool, error) {
+	switch key {
+	case "uids":
+		return nil, false, nil
+	case "policies":
+		list, err := model.ParseAuthPolicies(val)
+		if err != nil || len(list) == 0 {
+			return "", true, err
+		}
+		text, err := json.Marshal(list)
+		return string(text), true, err
+	}
+	return this.processFieldValue(val), true, nil
+}
+
 func (this *AuthGroup) anyToIntSlice(idsAny []any) []int {
 	ids := make([]int, 0, len(idsAny))
 	for _, id := range idsAny {
@@ -97,14 +113,15 @@ func (this *AuthGroup) IGET(ctx *gin.Context) {
 	method := strings.ToLower(ctx.Param("method"))
 
 	allow := map[string]any{
-		"one":    this.one,
-		"all":    this.all,
-		"sum":    this.sum,
-		"min":    this.min,
-		"max":    this.max,
-		"rand":   this.rand,
-		"count":  this.count,
-		"column": this.column,
+		"one":     this.one,
+		"all":     this.all,
+		"sum":     this.sum,
+		"min":     this.min,
+		"max":     this.max,
+		"rand":    this.rand,
+		"count":   this.count,
+		"column":  this.column,
+		"explain": this.explain,
 	}
 	err := this.call(allow, method, ctx)
 
@@ -118,8 +135,9 @@ func (this *AuthGroup) IPOST(ctx *gin.Context) {
 	method := strings.ToLower(ctx.Param("method"))
 
 	allow := map[string]any{
-		"save":   this.save,
-		"create": this.create,
+		"save":     this.save,
+		"create":   this.create,
+		"simulate": this.simulate,
 	}
 	err := this.call(allow, method, ctx)
 
@@ -173,6 +191,7 @@ func (this *AuthGroup) INDEX(ctx *gin.Context) {
 
 func (this *AuthGroup) delCache() {
 	facade.Cache.DelTags([]any{"[GET]", "auth-group"})
+	model.ResetAuthPolicy()
 }
 
 func (this *AuthGroup) one(ctx *gin.Context) {
@@ -302,7 +321,14 @@ func (this *AuthGroup) create(ctx *gin.Context) {
 
 	for key, val := range params {
 		if utils.In.Array(key, authGroupAllowFieldsSlice) {
-			utils.Struct.Set(&table, key, this.processFieldValue(val))
+			value, ok, err := this.fieldValue(key, val)
+			if err != nil {
+				this.json(ctx, nil, facade.Lang(ctx, err.Error()), 400)
+				return
+			}
+			if ok {
+				utils.Struct.Set(&table, key, value)
+			}
 		}
 	}
 
@@ -313,6 +339,10 @@ func (this *AuthGroup) create(ctx *gin.Context) {
 		return
 	}
 
+	if uids, ok := params["uids"]; ok {
+		model.SetGroupUsers(table.Id, cast.ToIntSlice(utils.Unity.Ids(uids)))
+	}
+
 	this.json(ctx, gin.H{"id": table.Id}, facade.Lang(ctx, "创建成功！"), 200)
 }
 
@@ -336,7 +366,14 @@ func (this *AuthGroup) update(ctx *gin.Context) {
 
 	for key, val := range params {
 		if utils.In.Array(key, authGroupAllowFieldsSlice) {
-			async.Set(key, this.processFieldValue(val))
+			value, ok, err := this.fieldValue(key, val)
+			if err != nil {
+				this.json(ctx, nil, facade.Lang(ctx, err.Error()), 400)
+				return
+			}
+			if ok {
+				async.Set(key, value)
+			}
 		}
 	}
 
@@ -347,6 +384,10 @@ func (this *AuthGroup) update(ctx *gin.Context) {
 		return
 	}
 
+	if uids, ok := params["uids"]; ok {
+		model.SetGroupUsers(cast.ToInt(params["id"]), cast.ToIntSlice(utils.Unity.Ids(uids)))
+	}
+
 	this.json(ctx, gin.H{"id": table.Id}, facade.Lang(ctx, "更新成功！"), 200)
 }
 
@@ -608,52 +649,173 @@ func (this *AuthGroup) uids(ctx *gin.Context) {
 		return
 	}
 
-	go func() {
-		ids := utils.Unity.Ids(params["ids"])
+	model.SetUserGroups(cast.ToInt(params["uid"]), cast.ToIntSlice(utils.Unity.Ids(params["ids"])))
+
+	go facade.Cache.DelTags("user[" + cast.ToString(params["uid"]) + "]")
 
-		cull := facade.DB.Model(&[]model.AuthGroup{}).WithTrashed()
+	this.json(ctx, nil, facade.Lang(ctx, "更新成功！"), 200)
+}
 
-		if !utils.Is.Empty(ids) {
-			cull.Where("id", "not in", ids)
+// authSubject - 权限诊断的对象（用户或分组）
+type authSubject struct {
+	uid    int
+	user   map[string]any
+	groups []model.AuthGroupPolicy
+}
+
+// subject - 解析诊断对象，uid 优先于 gid
+func (this *AuthGroup) subject(ctx *gin.Context, params map[string]any) (*authSubject, string) {
+
+	if utils.Is.Empty(params["route"]) {
+		return nil, facade.Lang(ctx, "%s 不能为空！", "route")
+	}
+
+	if uid := cast.ToInt(params["uid"]); uid > 0 {
+		user := map[string]any{}
+		facade.DB.Drive().Model(&model.Users{}).Where("id = ?", uid).Limit(1).Find(&user)
+		if utils.Is.Empty(user) {
+			return nil, facade.Lang(ctx, "用户不存在！")
 		}
+		return &authSubject{uid: uid, user: user, groups: model.LoadAuthGroupPolicies(model.UserGroupIds(uid))}, ""
+	}
 
-		cullItems, _ := cull.Select()
-		for _, item := range cullItems {
-			uids := cast.ToIntSlice(utils.ArrayUnique(utils.ArrayEmpty(strings.Split(cast.ToString(item["uids"]), "|"))))
-			if utils.InArray[int](cast.ToInt(params["uid"]), uids) {
-				for key, val := range uids {
-					if val == cast.ToInt(params["uid"]) {
-						uids = append(uids[:key], uids[key+1:]...)
-					}
-				}
-			}
-			var result string
-			if len(uids) > 0 {
-				result = fmt.Sprintf("|%v|", strings.Join(cast.ToStringSlice(uids), "|"))
-			}
-			facade.DB.Model(&model.AuthGroup{}).WithTrashed().Where("id", item["id"]).Update(map[string]any{
-				"uids": result,
-			})
+	if gid := cast.ToInt(params["gid"]); gid > 0 {
+		groups := model.LoadAuthGroupPolicies([]int{gid})
+		if len(groups) == 0 {
+			return nil, facade.Lang(ctx, "分组不存在！")
 		}
+		return &authSubject{groups: groups}, ""
+	}
 
-		addItems, _ := facade.DB.Model(&[]model.AuthGroup{}).WithTrashed().Where("id", "in", ids).Select()
+	return nil, facade.Lang(ctx, "%s 不能为空！", "uid 或 gid")
+}
 
-		for _, item := range addItems {
-			uids := strings.Split(cast.ToString(item["uids"]), "|")
-			uids = append(uids, cast.ToString(params["uid"]))
-			var result string
-			if len(uids) > 0 {
-				result = fmt.Sprintf("|%v|", strings.Join(cast.ToStringSlice(utils.ArrayUnique(utils.ArrayEmpty(uids))), "|"))
-			}
-			facade.DB.Model(&model.AuthGroup{}).WithTrashed().Where("id", item["id"]).Update(map[string]any{
-				"uids": result,
-			})
+// explain - 权限诊断：给定用户（uid）或分组（gid）及请求类型（method）、路由（route），返回判定结果与过程
+func (this *AuthGroup) explain(ctx *gin.Context) {
+
+	params := this.params(ctx, map[string]any{
+		"method": "GET",
+	})
+
+	subject, msg := this.subject(ctx, params)
+	if subject == nil {
+		this.json(ctx, nil, msg, 400)
+		return
+	}
+
+	method, route := strings.ToUpper(cast.ToString(params["method"])), cast.ToString(params["route"])
+	rule := model.FindAuthRule(method, route)
+
+	this.json(ctx, model.ExplainAuth(rule, subject.uid, subject.user, subject.groups, method, route), facade.Lang(ctx, "数据请求成功！"), 200)
+}
+
+// proposed - 在现有分组上应用待保存的修改（name、root、rules、policies）
+func (this *AuthGroup) proposed(ctx *gin.Context, value map[string]any) (*model.AuthGroupPolicy, string) {
+
+	if utils.Is.Empty(value["id"]) {
+		return nil, facade.Lang(ctx, "%s 不能为空！", "group.id")
+	}
+
+	table := model.AuthGroup{}
+	facade.DB.Model(&model.AuthGroup{}).WithTrashed().Where("id", value["id"]).Scan(&table)
+	if table.Id == 0 {
+		return nil, facade.Lang(ctx, "分组不存在！")
+	}
+
+	if val, ok := value["name"]; ok {
+		table.Name = cast.ToString(val)
+	}
+	if val, ok := value["root"]; ok {
+		table.Root = cast.ToInt(val)
+	}
+	if val, ok := value["rules"]; ok {
+		table.Rules = cast.ToString(this.processFieldValue(val))
+	}
+	if val, ok := value["policies"]; ok {
+		policies, _, err := this.fieldValue("policies", val)
+		if err != nil {
+			return nil, facade.Lang(ctx, err.Error())
 		}
-	}()
+		table.Policies = cast.ToString(policies)
+	}
 
-	go func() {
-		facade.Cache.DelTags(fmt.Sprintf("user[%v]", params["uid"]))
-	}()
+	item := model.NewAuthGroupPolicy(table)
+	return &item, ""
+}
 
-	this.json(ctx, nil, facade.Lang(ctx, "更新成功！"), 200)
+// simulate - 模拟分组变更：groups 为用户调整后的分组ID，group 为待保存的分组修改，返回变更前后的判定结果
+func (this *AuthGroup) simulate(ctx *gin.Context) {
+
+	params := this.params(ctx, map[string]any{
+		"method": "GET",
+	})
+
+	subject, msg := this.subject(ctx, params)
+	if subject == nil {
+		this.json(ctx, nil, msg, 400)
+		return
+	}
+
+	method, route := strings.ToUpper(cast.ToString(params["method"])), cast.ToString(params["route"])
+	rule := model.FindAuthRule(method, route)
+
+	groups := subject.groups
+	if val, ok := params["groups"]; ok && subject.uid > 0 {
+		groups = model.LoadAuthGroupPolicies(cast.ToIntSlice(utils.Unity.Ids(val)))
+	}
+
+	var change *model.AuthGroupPolicy
+	if val, ok := params["group"]; ok {
+		if change, msg = this.proposed(ctx, cast.ToStringMap(val)); change == nil {
+			this.json(ctx, nil, msg, 400)
+			return
+		}
+		groups = model.ReplaceAuthGroup(groups, *change)
+	}
+
+	before := model.ExplainAuth(rule, subject.uid, subject.user, subject.groups, method, route)
+	after := model.ExplainAuth(rule, subject.uid, subject.user, groups, method, route)
+
+	result := gin.H{
+		"before":  before,
+		"after":   after,
+		"changed": before.Allow != after.Allow || before.Root() != after.Root(),
+	}
+
+	// 修改分组时，列出该分组中权限会发生变化的成员
+	if change != nil {
+		result["affected"], result["members"] = this.affected(rule, *change, method, route)
+	}
+
+	this.json(ctx, result, facade.Lang(ctx, "数据请求成功！"), 200)
+}
+
+// affected - 分组修改后对该接口的权限发生变化的成员（最多检查 200 人），返回变化的成员与成员总数
+func (this *AuthGroup) affected(rule map[string]any, item model.AuthGroupPolicy, method, route string) ([]gin.H, int) {
+
+	result := []gin.H{}
+	uids := model.GroupUserIds(item.Id)
+
+	for key, uid := range uids {
+		if key >= 200 {
+			break
+		}
+		user := map[string]any{}
+		facade.DB.Drive().Model(&model.Users{}).Where("id = ?", uid).Limit(1).Find(&user)
+		groups := model.LoadAuthGroupPolicies(model.UserGroupIds(uid))
+
+		before := model.ExplainAuth(rule, uid, user, groups, method, route)
+		after := model.ExplainAuth(rule, uid, user, model.ReplaceAuthGroup(groups, item), method, route)
+		if before.Allow == after.Allow && before.Root() == after.Root() {
+			continue
+		}
+		result = append(result, gin.H{
+			"uid":      uid,
+			"nickname": user["nickname"],
+			"before":   gin.H{"allow": before.Allow, "root": before.Root()},
+			"after":    gin.H{"allow": after.Allow, "root": after.Root()},
+		})
+	}
+
+	return result, len(uids)
 }
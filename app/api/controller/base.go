@@ -37,6 +37,65 @@ func (this base) json(ctx *gin.Context, data, msg, code any) {
 	})
 }
 
+// codeError - 验证码服务与防暴力破解拦截的错误信息（本地化）
+func (this base) codeError(ctx *gin.Context, err error) string {
+	var item *model.VerifyCodeError
+	if errors.As(err, &item) {
+		return facade.Lang(ctx, item.Message, item.Args...)
+	}
+	var guard *model.LoginGuardError
+	if errors.As(err, &guard) {
+		return facade.Lang(ctx, guard.Message, guard.Args...)
+	}
+	return err.Error()
+}
+
+// guardError - 输出防暴力破解拦截响应（需要人机验证时携带 captcha 标记）
+func (this base) guardError(ctx *gin.Context, err error) {
+	var item *model.LoginGuardError
+	if errors.As(err, &item) {
+		this.json(ctx, gin.H{"wait": item.Wait, "captcha": item.Captcha}, this.codeError(ctx, err), 429)
+		return
+	}
+	this.json(ctx, nil, err.Error(), 400)
+}
+
+// audit - 提供审计日志中变更前后的快照（用于无法按数据表自动记录的目标，如配置文件），由审计中间件脱敏后写入
+func (this base) audit(ctx *gin.Context, before, after any) {
+	ctx.Set("audit", map[string]any{"before": before, "after": after})
+}
+
+// checkPassword - 敏感操作前校验当前用户的密码，失败时直接输出响应
+func (this base) checkPassword(ctx *gin.Context, uid int, password any) bool {
+
+	if utils.Is.Empty(password) {
+		this.json(ctx, nil, facade.Lang(ctx, "请输入当前密码！"), 400)
+		return false
+	}
+
+	// 与登录共用防暴力破解统计，避免持有会话者借敏感操作接口穷举密码
+	guard := model.NewLoginGuard(model.LoginGuardLogin, cast.ToString(uid), ctx.ClientIP())
+	if err := guard.Check(); err != nil {
+		this.guardError(ctx, err)
+		return false
+	}
+
+	item, _ := facade.DB.Model(&model.Users{}).Where("id", uid).Find()
+	if utils.Is.Empty(item) {
+		this.json(ctx, nil, facade.Lang(ctx, "用户不存在！"), 404)
+		return false
+	}
+
+	if utils.Password.Verify(cast.ToString(item["password"]), password) == false {
+		guard.Fail()
+		this.json(ctx, nil, facade.Lang(ctx, "密码错误！"), 400)
+		return false
+	}
+
+	guard.Success()
+	return true
+}
+
 // Call 方法调用 - 资源路由本体
 func (this base) call(allow map[string]any, name string, params ...any) (err error) {
 
@@ -200,6 +259,11 @@ func (this meta) user(ctx *gin.Context) (result model.Users) {
 	return table
 }
 
+// 从上下文中解析当前登录会话ID
+func (this meta) sid(ctx *gin.Context) string {
+	return ctx.GetString("sid")
+}
+
 // 从上下文中解析路由信息
 func (this meta) route(ctx *gin.Context) (result model.AuthRules) {
 
@@ -223,32 +287,52 @@ func (this meta) rules(ctx *gin.Context) (slice []any) {
 	return (&model.Users{}).Rules(this.user(ctx).Id)
 }
 
-// 从上下文中解析权限信息
-func (this meta) root(ctx *gin.Context) (ok bool) {
+// 从上下文中解析鉴权结果（由 Rule 中间件写入，公共接口则在此计算）
+func (this meta) auth(ctx *gin.Context) *model.AuthDecision {
 
-	var hash  []string
-	var table []model.AuthGroup
+	if value, ok := ctx.Get("auth"); ok {
+		if decision, ok := value.(*model.AuthDecision); ok {
+			return decision
+		}
+	}
 
-	user := this.user(ctx)
-	// 查询自己拥有的权限
-	facade.DB.Model(&table).Where("root", 1).Like("uids", "%|" + cast.ToString(user.Id) + "|%").Select()
+	decision := model.AuthorizeUser(this.user(ctx).Id, this.info(ctx), ctx.Request.Method, ctx.Request.URL.Path, this.route(ctx).Hash)
+	ctx.Set("auth", decision)
 
-	for _, item := range table {
-		// 逗号分隔的权限
-		hash = append(hash, strings.Split(cast.ToString(item.Rules), ",")...)
-	}
+	return decision
+}
 
-	// 拥有全部权限 - 直接返回
-	if utils.InArray("all", hash) {
-		return true
+// 从上下文中解析用户信息（用于策略条件中的 user.xxx）
+func (this meta) info(ctx *gin.Context) map[string]any {
+	if user, ok := ctx.Get("user"); ok {
+		return cast.ToStringMap(user)
 	}
+	return map[string]any{}
+}
 
-	// 拥有部分权限 - 判断当前路由是否在权限列表中
-	if utils.InArray(this.route(ctx).Hash, hash) {
-		return true
+// 从上下文中解析权限信息（root 分组无条件允许当前接口，可越权操作他人数据）
+func (this meta) root(ctx *gin.Context) (ok bool) {
+	return this.auth(ctx).Root
+}
+
+// 能否操作某条数据（拒绝条件、root、数据所有者、允许条件，见 model.AuthDecision.Check）
+func (this meta) can(ctx *gin.Context, name string, item map[string]any) (ok bool) {
+	return this.auth(ctx).Check(name, item, this.info(ctx))
+}
+
+// 筛选出可以操作的数据ID
+func (this meta) owned(ctx *gin.Context, name string, items []map[string]any) (ids []any) {
+
+	decision := this.auth(ctx)
+	info     := this.info(ctx)
+
+	for _, item := range items {
+		if decision.Check(name, item, info) {
+			ids = append(ids, item["id"])
+		}
 	}
 
-	return false
+	return ids
 }
 
 // 分页限制
@@ -0,0 +1,79 @@
+package controller
+
+import (
+	"errors"
+	"inis/app/facade"
+	"inis/app/model"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/spf13/cast"
+)
+
+// Captcha - 人机验证（自建验证码服务，题目保存在缓存中）
+type Captcha struct {
+	base
+}
+
+func (this *Captcha) IGET(ctx *gin.Context) {
+	method := strings.ToLower(ctx.Param("method"))
+
+	allow := map[string]any{
+		"create": this.create,
+		"config": this.config,
+	}
+	err := this.call(allow, method, ctx)
+
+	if err != nil {
+		this.json(ctx, nil, facade.Lang(ctx, "方法调用错误：%v", err.Error()), 405)
+		return
+	}
+}
+
+func (this *Captcha) IPOST(ctx *gin.Context) {
+	this.json(ctx, nil, facade.Lang(ctx, "不支持POST请求！"), 405)
+}
+
+func (this *Captcha) IPUT(ctx *gin.Context) {
+	this.json(ctx, nil, facade.Lang(ctx, "不支持PUT请求！"), 405)
+}
+
+func (this *Captcha) IDEL(ctx *gin.Context) {
+	this.json(ctx, nil, facade.Lang(ctx, "不支持DELETE请求！"), 405)
+}
+
+func (this *Captcha) INDEX(ctx *gin.Context) {
+	this.json(ctx, nil, facade.Lang(ctx, "没什么用！"), 202)
+}
+
+// create - 获取题目（type 为 image、math 或 pow，为空时使用配置的类型）
+func (this *Captcha) create(ctx *gin.Context) {
+
+	params := this.params(ctx)
+
+	item, err := model.CreateCaptcha(cast.ToString(params["type"]), ctx.ClientIP())
+	if err != nil {
+		if errors.Is(err, model.ErrCaptchaType) {
+			this.json(ctx, nil, facade.Lang(ctx, err.Error()), 400)
+			return
+		}
+		this.json(ctx, nil, err.Error(), 500)
+		return
+	}
+
+	this.json(ctx, item, facade.Lang(ctx, "数据请求成功！"), 200)
+}
+
+// config - 人机验证配置（供前端判断哪些接口需要先完成人机验证）
+func (this *Captcha) config(ctx *gin.Context) {
+
+	setting := model.CaptchaSetting()
+
+	this.json(ctx, gin.H{
+		"enable":     setting["enable"],
+		"type":       setting["type"],
+		"guest_only": cast.ToBool(setting["guest_only"]),
+		"routes":     setting["routes"],
+		"types":      model.CaptchaTypes,
+	}, facade.Lang(ctx, "数据请求成功！"), 200)
+}
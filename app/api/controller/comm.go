@@ -1,6 +1,7 @@
 package controller
 
 import (
+	"errors"
 	"fmt"
 	"inis/app/facade"
 	"inis/app/model"
@@ -40,11 +41,15 @@ func (this *Comm) IPOST(ctx *gin.Context) {
 	method := strings.ToLower(ctx.Param("method"))
 
 	allow := map[string]any{
-		"login":          this.login,
-		"register":       this.register,
-		"check-token":    this.checkToken,
-		"reset-password": this.resetPassword,
-		"logout":         this.logout,
+		"login":           this.login,
+		"login-2fa":       this.login2fa,
+		"passkey-options": this.passkeyOptions,
+		"passkey-login":   this.passkeyLogin,
+		"register":        this.register,
+		"check-token":     this.checkToken,
+		"refresh-token":   this.refreshToken,
+		"reset-password":  this.resetPassword,
+		"logout":          this.logout,
 	}
 	err := this.call(allow, method, ctx)
 
@@ -170,15 +175,73 @@ func (this *Comm) login(ctx *gin.Context) {
 		[]any{"account", "=", params["account"]},
 	}).Where("source", params["source"]).Find()
 
+	// 防暴力破解：账号维度按用户ID统计，避免切换账号/邮箱/手机号绕过
+	guard := model.NewLoginGuard(model.LoginGuardLogin, utils.Ternary(utils.Is.Empty(item), "", cast.ToString(table.Id)), ctx.ClientIP())
+	if err := guard.Check(); err != nil {
+		this.guardError(ctx, err)
+		return
+	}
+	// 已通过路由级人机验证时凭证已被使用，不再重复校验
+	guard.Passed = ctx.GetBool("captcha")
+	if err := guard.CheckCaptcha(params["captcha"]); err != nil {
+		this.guardError(ctx, err)
+		return
+	}
+
 	if utils.Is.Empty(item) {
+		guard.Fail()
 		this.json(ctx, nil, facade.Lang(ctx, "账户不存在！"), 400)
 		return
 	}
 
+	// 检查账号是否被冻结或封禁
+	if !this.checkLoginStatus(ctx, table) {
+		return
+	}
+
+	if utils.Is.Empty(table.Password) {
+		this.json(ctx, nil, facade.Lang(ctx, "该帐号未设置密码，请切换登录方式！"), 400)
+		return
+	}
+
+	// 密码校验
+	if utils.Password.Verify(table.Password, params["password"]) == false {
+		this.guardFail(ctx, guard, table.Id, cast.ToString(params["account"]))
+		this.json(ctx, gin.H{"captcha": guard.Captcha()}, facade.Lang(ctx, "密码错误！"), 400)
+		return
+	}
+
+	// 两步验证：已开启或被要求开启时，先签发挑战令牌，完成第二步验证后再创建会话
+	if model.TotpEnabled(table.Id) || model.TotpRequired(table.Id) {
+		this.loginChallenge(ctx, table, cast.ToString(params["account"]))
+		return
+	}
+
+	this.loginSuccess(ctx, table, item, cast.ToString(params["account"]), nil)
+}
+
+// guardFail - 记录一次验证失败，账号因本次失败被锁定时异步通知账号所有者
+func (this *Comm) guardFail(ctx *gin.Context, guard *model.LoginGuard, uid int, account string) {
+
+	if !guard.Fail() {
+		return
+	}
+
+	go func(uid int, account, ip, ua string, lock time.Duration) {
+		notification := new(model.Notification)
+		if _, e := notification.CreateLockNotification(uid, account, ip, ua, lock); e != nil {
+			facade.Log.Error(map[string]any{"error": e.Error(), "uid": uid}, "发送账号锁定通知失败")
+		}
+	}(uid, account, ctx.ClientIP(), ctx.Request.UserAgent(), guard.Lock)
+}
+
+// checkLoginStatus - 检查账号是否被冻结或封禁（限制登录），不允许登录时直接输出响应
+func (this *Comm) checkLoginStatus(ctx *gin.Context, table model.Users) bool {
+
 	// 检查账号是否被冻结（使用 status 字段，0为正常，1为冻结）
 	if table.Status == 1 {
 		this.json(ctx, nil, facade.Lang(ctx, "当前账号已被冻结，请联系管理员！"), 403)
-		return
+		return false
 	}
 
 	// 检查账号是否处于封禁状态（限制登录）
@@ -203,26 +266,220 @@ func (this *Comm) login(ctx *gin.Context) {
 					msg = fmt.Sprintf("您的账号已被永久封禁！原因：%s", reason)
 				}
 				this.json(ctx, nil, facade.Lang(ctx, msg), 403)
+				return false
+			}
+		}
+	}
+
+	return true
+}
+
+// loginChallenge - 签发两步登录挑战令牌（可用 TOTP、恢复码或已绑定的通行密钥；均未绑定但被要求开启时，返回绑定 TOTP 所需的密钥）
+func (this *Comm) loginChallenge(ctx *gin.Context, table model.Users, account string) {
+
+	totp := model.TotpEnabled(table.Id)
+	passkey := model.HasUserPasskey(table.Id)
+	enroll := !totp && !passkey
+
+	challenge, err := model.CreateLoginChallenge(table.Id, account, ctx.ClientIP(), enroll)
+	if err != nil {
+		this.json(ctx, nil, err.Error(), 500)
+		return
+	}
+
+	result := gin.H{
+		"challenge": challenge.Token,
+		"expire":    challenge.Expire,
+		"enroll":    enroll,
+	}
+
+	if enroll {
+		secret, uri, err := model.TotpEnroll(table.Id, utils.Ternary(utils.Is.Empty(table.Email), table.Account, table.Email))
+		if err != nil {
+			challenge.Revoke()
+			this.json(ctx, nil, err.Error(), 500)
+			return
+		}
+		result["methods"] = []string{"totp"}
+		result["secret"] = secret
+		result["uri"] = uri
+		this.json(ctx, result, facade.Lang(ctx, "管理员要求开启两步验证，请使用身份验证器App扫码绑定后输入验证码！"), 202)
+		return
+	}
+
+	var methods []string
+	if totp {
+		methods = append(methods, "totp", "recovery")
+	}
+	if passkey {
+		rp, err := model.NewWebAuthnRP()
+		// 通行密钥服务未配置时仍可使用 TOTP 完成验证
+		if err != nil && !totp {
+			challenge.Revoke()
+			this.json(ctx, nil, facade.Lang(ctx, err.Error()), 500)
+			return
+		}
+		if err == nil {
+			options, err := rp.LoginOptions(table.Id)
+			if err != nil {
+				challenge.Revoke()
+				this.json(ctx, nil, err.Error(), 500)
 				return
 			}
+			methods = append(methods, "webauthn")
+			result["webauthn"] = options
 		}
 	}
+	result["methods"] = methods
 
-	if utils.Is.Empty(table.Password) {
-		this.json(ctx, nil, facade.Lang(ctx, "该帐号未设置密码，请切换登录方式！"), 400)
+	this.json(ctx, result, facade.Lang(ctx, "请完成两步验证！"), 202)
+}
+
+// login2fa - 两步登录第二步：校验挑战令牌与两步验证码、恢复码或通行密钥，通过后创建登录会话
+func (this *Comm) login2fa(ctx *gin.Context) {
+
+	params := this.params(ctx)
+
+	if utils.Is.Empty(params["challenge"]) {
+		this.json(ctx, nil, facade.Lang(ctx, "%s 不能为空！", "challenge"), 400)
 		return
 	}
 
-	// 密码校验
-	if utils.Password.Verify(table.Password, params["password"]) == false {
-		this.json(ctx, nil, facade.Lang(ctx, "密码错误！"), 400)
+	credential := cast.ToStringMap(params["credential"])
+	if utils.Is.Empty(params["code"]) && utils.Is.Empty(credential) {
+		this.json(ctx, nil, facade.Lang(ctx, "请提交两步验证码！"), 400)
 		return
 	}
 
-	jwt := facade.Jwt().Create(facade.H{
-		"uid":  table.Id,
-		"hash": utils.Hash.Sum32(table.Password),
-	})
+	challenge := model.FindLoginChallenge(cast.ToString(params["challenge"]), ctx.ClientIP())
+	if challenge == nil {
+		this.json(ctx, nil, facade.Lang(ctx, "登录验证已过期，请重新登录！"), 401)
+		return
+	}
+
+	// 第二步的失败次数与密码错误合并统计，避免反复重新登录绕过挑战令牌的次数限制
+	guard := model.NewLoginGuard(model.LoginGuardLogin, cast.ToString(challenge.Uid), ctx.ClientIP())
+	if err := guard.Check(); err != nil {
+		challenge.Revoke()
+		this.guardError(ctx, err)
+		return
+	}
+
+	var codes []string
+	var err error
+	switch {
+	case challenge.Enroll:
+		codes, err = model.TotpConfirm(challenge.Uid, cast.ToString(params["code"]))
+	case !utils.Is.Empty(credential):
+		var rp *model.WebAuthnRP
+		if rp, err = model.NewWebAuthnRP(); err == nil {
+			_, err = rp.Login(credential, challenge.Uid)
+		}
+	default:
+		err = model.TotpVerify(challenge.Uid, cast.ToString(params["code"]))
+	}
+
+	if err != nil {
+		this.guardFail(ctx, guard, challenge.Uid, challenge.Account)
+		if !challenge.Fail() {
+			this.json(ctx, nil, facade.Lang(ctx, "验证失败次数过多，请重新登录！"), 401)
+			return
+		}
+		this.json(ctx, nil, facade.Lang(ctx, err.Error()), 400)
+		return
+	}
+
+	// 挑战令牌一次性使用
+	challenge.Revoke()
+
+	table := model.Users{}
+	item, _ := facade.DB.Model(&table).Where("id", challenge.Uid).Find()
+	if utils.Is.Empty(item) {
+		this.json(ctx, nil, facade.Lang(ctx, "账户不存在！"), 400)
+		return
+	}
+
+	// 挑战期间账号被冻结或封禁
+	if !this.checkLoginStatus(ctx, table) {
+		return
+	}
+
+	var extra map[string]any
+	if len(codes) > 0 {
+		// 首次绑定时返回恢复码，仅此一次
+		extra = map[string]any{"recovery_codes": codes}
+	}
+
+	this.loginSuccess(ctx, table, item, challenge.Account, extra)
+}
+
+// passkeyOptions - 通行密钥无密码登录：获取验证仪式参数
+func (this *Comm) passkeyOptions(ctx *gin.Context) {
+
+	rp, err := model.NewWebAuthnRP()
+	if err != nil {
+		this.json(ctx, nil, facade.Lang(ctx, err.Error()), 500)
+		return
+	}
+
+	options, err := rp.LoginOptions(0)
+	if err != nil {
+		this.json(ctx, nil, err.Error(), 500)
+		return
+	}
+
+	this.json(ctx, options, facade.Lang(ctx, "数据请求成功！"), 200)
+}
+
+// passkeyLogin - 通行密钥无密码登录（认证器已验证用户身份，视为已完成两步验证）
+func (this *Comm) passkeyLogin(ctx *gin.Context) {
+
+	params := this.params(ctx)
+
+	credential := cast.ToStringMap(params["credential"])
+	if utils.Is.Empty(credential) {
+		this.json(ctx, nil, facade.Lang(ctx, "%s 不能为空！", "credential"), 400)
+		return
+	}
+
+	rp, err := model.NewWebAuthnRP()
+	if err != nil {
+		this.json(ctx, nil, facade.Lang(ctx, err.Error()), 500)
+		return
+	}
+
+	passkey, err := rp.Login(credential, 0)
+	if err != nil {
+		this.json(ctx, nil, facade.Lang(ctx, err.Error()), 400)
+		return
+	}
+
+	table := model.Users{}
+	item, _ := facade.DB.Model(&table).Where("id", passkey.Uid).Find()
+	if utils.Is.Empty(item) {
+		this.json(ctx, nil, facade.Lang(ctx, "账户不存在！"), 400)
+		return
+	}
+
+	if !this.checkLoginStatus(ctx, table) {
+		return
+	}
+
+	this.loginSuccess(ctx, table, item, utils.Ternary(utils.Is.Empty(table.Account), table.Email, table.Account), nil)
+}
+
+// loginSuccess - 登录校验全部通过：创建会话、更新登录时间、发放经验与登录通知
+func (this *Comm) loginSuccess(ctx *gin.Context, table model.Users, item map[string]any, account string, extra map[string]any) {
+
+	// 创建登录会话，签发访问令牌与刷新令牌
+	result, err := createSession(ctx, table.Id, table.Password)
+	if err != nil {
+		this.json(ctx, nil, err.Error(), 400)
+		return
+	}
+
+	// 登录成功，清除账号的失败次数
+	model.NewLoginGuard(model.LoginGuardLogin, cast.ToString(table.Id), ctx.ClientIP()).Success()
 
 	// 删除 item 中的密码
 	delete(item, "password")
@@ -232,14 +489,11 @@ func (this *Comm) login(ctx *gin.Context) {
 		"login_time": item["login_time"],
 	})
 
-	result := map[string]any{
-		"user":       item,
-		"token":      jwt.Text,
-		"valid_time": jwt.Valid, // 登录会话有效期（秒）
+	result["user"] = item
+	for key, value := range extra {
+		result[key] = value
 	}
 
-	// 往客户端写入cookie - 存储登录token
-	setToken(ctx, jwt.Text)
 	// 登录增加经验
 	go this.loginExp(item["id"])
 
@@ -249,7 +503,7 @@ func (this *Comm) login(ctx *gin.Context) {
 		if _, e := notification.CreateLoginNotification(uid, account, ip, ua); e != nil {
 			facade.Log.Error(map[string]any{"error": e.Error(), "uid": uid}, "发送账号登录通知失败")
 		}
-	}(cast.ToInt(item["id"]), cast.ToString(params["account"]), ctx.ClientIP(), ctx.Request.UserAgent())
+	}(cast.ToInt(item["id"]), account, ctx.ClientIP(), ctx.Request.UserAgent())
 
 	this.json(ctx, result, facade.Lang(ctx, "登录成功！"), 200)
 }
@@ -321,55 +575,17 @@ func (this *Comm) register(ctx *gin.Context) {
 		}
 	}
 
-	cacheName := fmt.Sprintf("[register][%v=%v]", social, params["social"])
+	code := model.NewVerifyCode(model.VerifyCodeRegister, utils.Ternary(social == "email", "email", "sms"), cast.ToString(params["social"]))
+	code.Lang = facade.RequestLang(ctx)
 
 	// 验证码为空 - 发送验证码
 	if utils.Is.Empty(params["code"]) {
 
-		drives := cast.ToStringMap(facade.SMSToml.Get("drive"))
-		drive := utils.Ternary(social == "email", "email", "sms")
-
-		if utils.Is.Empty(drives[drive]) {
-			this.json(ctx, nil, facade.Lang(ctx, "发送验证码失败！管理员未开启短信服务！"), 400)
+		if err := code.Send(ctx.ClientIP()); err != nil {
+			this.json(ctx, nil, this.codeError(ctx, err), 400)
 			return
 		}
 
-		// 本地频控检查
-		frequencyCacheName := fmt.Sprintf("frequency-%v-%v", drive, params["social"])
-		dailyLimitCacheName := fmt.Sprintf("daily-limit-%v-%v", drive, params["social"])
-
-		// 检查发送间隔（60秒）
-		lastSendTime := facade.Cache.Get(frequencyCacheName)
-		if !utils.Is.Empty(lastSendTime) {
-			if time.Now().Unix()-cast.ToInt64(lastSendTime) < 60 {
-				this.json(ctx, nil, facade.Lang(ctx, "发送过于频繁，请60秒后再试！"), 400)
-				return
-			}
-		}
-
-		// 检查每日发送限制（10次）
-		dailyCount := cast.ToInt(facade.Cache.Get(dailyLimitCacheName))
-		if dailyCount >= 10 {
-			this.json(ctx, nil, facade.Lang(ctx, "今日发送验证码次数已达上限，请明日再试！"), 400)
-			return
-		}
-
-		sms := facade.NewSMS(drives[drive]).VerifyCode(params["social"])
-		if sms.Error != nil {
-			// 处理阿里云频控错误
-			if drive == "sms" && (strings.Contains(sms.Error.Error(), "check frequency failed") || strings.Contains(sms.Error.Error(), "FREQUENCY_FAIL")) {
-				this.json(ctx, nil, facade.Lang(ctx, "发送过于频繁，请稍后再试！"), 400)
-				return
-			}
-			this.json(ctx, nil, sms.Error.Error(), 400)
-			return
-		}
-		// 缓存验证码 - 5分钟
-		facade.Cache.Set(cacheName, sms.VerifyCode, 5*time.Minute)
-		// 缓存发送时间 - 60秒
-		go facade.Cache.Set(frequencyCacheName, time.Now().Unix(), time.Second*60)
-		// 缓存每日发送次数 - 24小时
-		go facade.Cache.Set(dailyLimitCacheName, dailyCount+1, time.Hour*24)
 		this.json(ctx, nil, facade.Lang(ctx, "验证码发送成功！"), 201)
 		return
 	}
@@ -379,14 +595,6 @@ func (this *Comm) register(ctx *gin.Context) {
 		return
 	}
 
-	// 获取缓存里面的验证码
-	cacheCode := facade.Cache.Get(cacheName)
-
-	if cast.ToString(params["code"]) != cacheCode {
-		this.json(ctx, nil, facade.Lang(ctx, "验证码错误！"), 400)
-		return
-	}
-
 	// 允许存储的字段
 	allow := []any{"account", "password", "email", "phone", "nickname", "avatar", "description", "source"}
 	// 动态给结构体赋值
@@ -416,74 +624,42 @@ func (this *Comm) register(ctx *gin.Context) {
 		utils.Struct.Set(&table, "nickname", fmt.Sprintf("用户_%v", utils.Rand.Number(6)))
 	}
 
-	// 未传入账号时，生成从 10000001 开始递增的纯数字账号（8 位以内）
-	// 借助 account 唯一索引，创建冲突时自动重试取下一个可用账号
-	if utils.Is.Empty(table.Account) {
-		const maxRetry = 10
-		for i := 0; i < maxRetry; i++ {
-			maxAccount, _ := facade.DB.Model(&model.Users{}).Max("cast(account as unsigned)")
-			next := cast.ToInt64(maxAccount) + 1
-			// 起点 10000001，保证首个账号为 10000001 并依次递增
-			if next < 10000001 {
-				next = 10000001
-			}
-			// 限制 8 位以内（不超过 99999999）
-			if next > 99999999 {
-				next = 99999999
-			}
-			utils.Struct.Set(&table, "account", cast.ToString(next))
-			// 设置登录时间
-			utils.Struct.Set(&table, "login_time", time.Now().Unix())
-			// 创建用户
-			_, err = facade.DB.Model(&table).Create(&table)
-			if err == nil {
-				break
-			}
-			// 非唯一索引冲突（Error 1062）则重试，其他错误直接返回
-			if !strings.Contains(cast.ToString(err.Error()), "1062") {
-				this.json(ctx, nil, err.Error(), 400)
-				return
-			}
-		}
-		if err != nil {
-			this.json(ctx, nil, facade.Lang(ctx, "注册失败，请稍后重试！"), 400)
-			return
-		}
-	} else {
-		// 设置登录时间
-		utils.Struct.Set(&table, "login_time", time.Now().Unix())
-		// 创建用户
-		_, err = facade.DB.Model(&table).Create(&table)
-		if err != nil {
-			this.json(ctx, nil, err.Error(), 400)
-			return
-		}
+	// 输入校验全部通过后再消费验证码，避免因输入错误浪费一次性验证码
+	if err := code.Check(params["code"], ctx.ClientIP()); err != nil {
+		this.json(ctx, nil, this.codeError(ctx, err), 400)
+		return
 	}
 
-	// 删除验证码
-	go facade.Cache.Del(cacheName)
+	// 创建用户
+	if err = createUser(&table); err != nil {
+		this.json(ctx, nil, facade.Lang(ctx, err.Error()), 400)
+		return
+	}
 
-	jwt := facade.Jwt().Create(facade.H{
-		"uid":  table.Id,
-		"hash": utils.Hash.Sum32(table.Password),
-	})
+	// 创建登录会话，签发访问令牌与刷新令牌
+	result, err := createSession(ctx, table.Id, table.Password)
+	if err != nil {
+		this.json(ctx, nil, err.Error(), 400)
+		return
+	}
 
 	// 删除密码
 	table.Password = ""
 
-	result := map[string]any{
-		"user":       table,
-		"token":      jwt.Text,
-		"valid_time": jwt.Valid, // 登录会话有效期（秒）
-	}
+	result["user"] = table
 
-	// 往客户端写入cookie - 存储登录token
-	setToken(ctx, jwt.Text)
 	// 登录增加经验
 	go this.loginExp(table.Id)
 	// 添加默认权限
 	go this.auth(table.Id)
 
+	model.TriggerWebhook(model.WebhookEventUserRegistered, map[string]any{
+		"id":       table.Id,
+		"account":  table.Account,
+		"nickname": table.Nickname,
+		"source":   table.Source,
+	})
+
 	this.json(ctx, result, facade.Lang(ctx, "注册成功！"), 200)
 }
 
@@ -531,25 +707,15 @@ func (this *Comm) password(ctx *gin.Context, socialType string) {
 	// 请求参数
 	params := this.params(ctx)
 
-	drives := cast.ToStringMap(facade.SMSToml.Get("drive"))
-
 	// 获取用户提交的联系方式
 	social := cast.ToString(params["social"])
+	mode := utils.Ternary(socialType == "email", "email", "sms")
 
-	// 确定模式和驱动
-	var mode string
-	var drive string
-
-	if socialType == "email" {
-		mode = "email"
-		drive = cast.ToString(drives["email"])
-	} else if socialType == "phone" {
-		mode = "sms"
-		drive = cast.ToString(drives["sms"])
-	}
+	code := model.NewVerifyCode(model.VerifyCodeResetPassword, mode, social)
+	code.Lang = facade.RequestLang(ctx)
 
 	// 驱动不可用
-	if utils.Is.Empty(drive) {
+	if utils.Is.Empty(code.Drive()) {
 		if mode == "email" {
 			this.json(ctx, nil, facade.Lang(ctx, "管理员未开启邮箱服务，无法发送验证码！"), 400)
 		} else {
@@ -572,63 +738,21 @@ func (this *Comm) password(ctx *gin.Context, socialType string) {
 		return
 	}
 
-	// 缓存名称 - 使用 mode 而非具体驱动名，确保发送和验证时 key 一致
-	cacheName := fmt.Sprintf("[reset-password][%v=%v]", mode, social)
+	// 防暴力破解：账号或IP被锁定时，发送与校验验证码均被拒绝
+	guard := model.NewLoginGuard(model.LoginGuardResetPassword, cast.ToString(user["id"]), ctx.ClientIP())
+	if err := guard.Check(); err != nil {
+		this.guardError(ctx, err)
+		return
+	}
 
 	// 验证码为空 - 发送验证码
 	if utils.Is.Empty(params["code"]) {
 
-		// 本地频控检查（仅短信模式）
-		if drive == "sms" {
-			frequencyCacheName := fmt.Sprintf("frequency-%v-%v", drive, social)
-			dailyLimitCacheName := fmt.Sprintf("daily-limit-%v-%v", drive, social)
-
-			// 检查发送间隔（60秒）
-			lastSendTime := facade.Cache.Get(frequencyCacheName)
-			if !utils.Is.Empty(lastSendTime) {
-				if time.Now().Unix()-cast.ToInt64(lastSendTime) < 60 {
-					this.json(ctx, nil, facade.Lang(ctx, "发送过于频繁，请60秒后再试！"), 400)
-					return
-				}
-			}
-
-			// 检查每日发送限制（10次）
-			dailyCount := cast.ToInt(facade.Cache.Get(dailyLimitCacheName))
-			if dailyCount >= 10 {
-				this.json(ctx, nil, facade.Lang(ctx, "今日发送验证码次数已达上限，请明日再试！"), 400)
-				return
-			}
-
-			sms := facade.NewSMS(drive).VerifyCode(social)
-			if sms.Error != nil {
-				// 处理阿里云频控错误
-				if strings.Contains(sms.Error.Error(), "check frequency failed") || strings.Contains(sms.Error.Error(), "FREQUENCY_FAIL") {
-					this.json(ctx, nil, facade.Lang(ctx, "发送过于频繁，请稍后再试！"), 400)
-					return
-				}
-				this.json(ctx, nil, sms.Error.Error(), 400)
-				return
-			}
-			// 缓存验证码 - 5分钟
-			facade.Cache.Set(cacheName, sms.VerifyCode, 5*time.Minute)
-			// 缓存发送时间 - 60秒
-			go facade.Cache.Set(frequencyCacheName, time.Now().Unix(), time.Second*60)
-			// 缓存每日发送次数 - 24小时
-			go facade.Cache.Set(dailyLimitCacheName, dailyCount+1, time.Hour*24)
-
-			msg := fmt.Sprintf("验证码发送至您的%v：%s，请注意查收！", utils.Ternary(mode == "email", "邮箱", "手机"), social)
-			this.json(ctx, nil, facade.Lang(ctx, msg), 201)
+		if err := code.Send(ctx.ClientIP()); err != nil {
+			this.json(ctx, nil, this.codeError(ctx, err), 400)
 			return
 		}
 
-		sms := facade.NewSMS(drive).VerifyCode(social)
-		if sms.Error != nil {
-			this.json(ctx, nil, sms.Error.Error(), 400)
-			return
-		}
-		// 缓存验证码 - 5分钟
-		facade.Cache.Set(cacheName, sms.VerifyCode, 5*time.Minute)
-
 		msg := fmt.Sprintf("验证码发送至您的%v：%s，请注意查收！", utils.Ternary(mode == "email", "邮箱", "手机"), social)
 		this.json(ctx, nil, facade.Lang(ctx, msg), 201)
 		return
@@ -639,14 +763,21 @@ func (this *Comm) password(ctx *gin.Context, socialType string) {
 		return
 	}
 
-	// 获取缓存里面的验证码
-	cacheCode := facade.Cache.Get(cacheName)
+	// 已通过路由级人机验证时凭证已被使用，不再重复校验
+	guard.Passed = ctx.GetBool("captcha")
+	if err := guard.CheckCaptcha(params["captcha"]); err != nil {
+		this.guardError(ctx, err)
+		return
+	}
 
-	if cast.ToString(params["code"]) != cast.ToString(cacheCode) {
-		this.json(ctx, nil, facade.Lang(ctx, "验证码错误！"), 400)
+	if err := code.Check(params["code"], ctx.ClientIP()); err != nil {
+		this.guardFail(ctx, guard, cast.ToInt(user["id"]), social)
+		this.json(ctx, gin.H{"captcha": guard.Captcha()}, this.codeError(ctx, err), 400)
 		return
 	}
 
+	guard.Success()
+
 	// 加密密码
 	password := utils.Password.Create(params["password"])
 
@@ -657,8 +788,9 @@ func (this *Comm) password(ctx *gin.Context, socialType string) {
 		return
 	}
 
-	// 删除验证码
-	go facade.Cache.Del(cacheName)
+	// 重置密码后下线全部会话
+	go model.RevokeUserSessions(cast.ToInt(user["id"]), "", model.SessionRevokePassword)
+	facade.Cache.Del(fmt.Sprintf("user[%v]", user["id"]))
 
 	this.json(ctx, nil, facade.Lang(ctx, "密码重置成功！"), 200)
 }
@@ -689,6 +821,13 @@ func (this *Comm) checkToken(ctx *gin.Context) {
 		return
 	}
 
+	// 会话已退出或被下线
+	sid := cast.ToString(jwt.Data["sid"])
+	if !model.UserSessionActive(sid, cast.ToInt(jwt.Data["uid"])) {
+		this.json(ctx, nil, facade.Lang(ctx, "%s 无效！", "Authorization"), 400)
+		return
+	}
+
 	// 表数据结构体
 	table := model.Users{}
 	// 查询用户
@@ -701,13 +840,12 @@ func (this *Comm) checkToken(ctx *gin.Context) {
 	// token 有效时长
 	valid := jwt.Valid
 
+	// 续期：为当前会话签发新的访问令牌（刷新令牌不变）
 	if cast.ToBool(params["renew"]) {
-		jwt = facade.Jwt().Create(facade.H{
-			"uid":  table.Id,
-			"hash": utils.Hash.Sum32(table.Password),
-		})
+		session := &model.UserSession{Uid: table.Id, Sid: sid}
+		jwt = session.AccessToken(table.Password)
 		token = jwt.Text
-		valid = cast.ToInt64(utils.Calc(facade.AppToml.Get("jwt.expire", facade.DefaultJwtExpire)))
+		valid = jwt.Valid
 		// 往客户端写入cookie - 存储登录token
 		setToken(ctx, token)
 	}
@@ -721,36 +859,206 @@ func (this *Comm) checkToken(ctx *gin.Context) {
 	}, facade.Lang(ctx, facade.Lang(ctx, "合法的token！")), 200)
 }
 
-// 退出登录
-func (this *Comm) logout(ctx *gin.Context) {
+// 刷新令牌 - 使用刷新令牌换取新的访问令牌，刷新令牌同时轮换
+func (this *Comm) refreshToken(ctx *gin.Context) {
 
-	host := ctx.Request.Host
-	if strings.Contains(host, ":") {
-		host = strings.Split(host, ":")[0]
+	params := this.params(ctx)
+
+	refresh := cast.ToString(params["refresh_token"])
+	if utils.Is.Empty(refresh) {
+		refresh, _ = ctx.Cookie(refreshTokenName())
 	}
 
+	if utils.Is.Empty(refresh) {
+		this.json(ctx, nil, facade.Lang(ctx, "%s 不能为空！", "refresh_token"), 412)
+		return
+	}
+
+	session, next, err := model.RotateUserSession(refresh, ctx.ClientIP(), ctx.Request.UserAgent())
+	if err != nil {
+		clearToken(ctx)
+		this.json(ctx, nil, facade.Lang(ctx, err.Error()), 401)
+		return
+	}
+
+	// 表数据结构体
+	table := model.Users{}
+	item, _ := facade.DB.Model(&table).Where("id", session.Uid).Find()
+	if utils.Is.Empty(item) {
+		go model.RevokeUserSession(session.Sid, model.SessionRevokeDestroy)
+		clearToken(ctx)
+		this.json(ctx, nil, facade.Lang(ctx, "用户不存在！"), 401)
+		return
+	}
+
+	// 冻结或封禁登录的账号不再续期
+	if table.Status == model.UserStatusFrozen || (table.Restrictions&model.BanTypeLogin != 0 && table.CurrentBanId > 0) {
+		go model.RevokeUserSession(session.Sid, model.SessionRevokeBan)
+		clearToken(ctx)
+		this.json(ctx, nil, facade.Lang(ctx, "登录已过期，请重新登录！"), 401)
+		return
+	}
+
+	jwt := session.AccessToken(table.Password)
+	if jwt.Error != nil {
+		this.json(ctx, nil, jwt.Error.Error(), 400)
+		return
+	}
+
+	setToken(ctx, jwt.Text)
+	setRefreshToken(ctx, next)
+
+	delete(item, "password")
+
+	this.json(ctx, gin.H{
+		"user":               item,
+		"token":              jwt.Text,
+		"valid_time":         jwt.Valid,
+		"refresh_token":      next,
+		"refresh_valid_time": session.ExpireTime - time.Now().Unix(),
+	}, facade.Lang(ctx, "刷新成功！"), 200)
+}
+
+// 退出登录 - 下线当前会话
+func (this *Comm) logout(ctx *gin.Context) {
+
+	params := this.params(ctx)
 	tokenName := cast.ToString(facade.AppToml.Get("app.token_name", "INIS_LOGIN_TOKEN"))
 
-	// 清除 cookie：domain 必须与登录时 setToken 的 domain 一致，否则无法清除
-	// 同时兼容带域名和空域名两种写法
-	ctx.SetCookie(tokenName, "", -1, "/", host, false, false)
-	ctx.SetCookie(tokenName, "", -1, "/", "", false, false)
+	token := ctx.Request.Header.Get("Authorization")
+	if utils.Is.Empty(token) {
+		token, _ = ctx.Cookie(tokenName)
+	}
+
+	// 优先使用访问令牌中的会话ID，访问令牌已过期时通过刷新令牌定位会话
+	var sid string
+	if !utils.Is.Empty(token) {
+		if jwt := facade.Jwt().Parse(token); jwt.Error == nil {
+			sid = cast.ToString(jwt.Data["sid"])
+		}
+	}
+	if utils.Is.Empty(sid) {
+		refresh := cast.ToString(params["refresh_token"])
+		if utils.Is.Empty(refresh) {
+			refresh, _ = ctx.Cookie(refreshTokenName())
+		}
+		if session := model.FindUserSession(refresh); session != nil {
+			sid = session.Sid
+		}
+	}
+
+	if err := model.RevokeUserSession(sid, model.SessionRevokeLogout); err != nil {
+		facade.Log.Error(map[string]any{"error": err, "sid": sid}, "退出登录时下线会话失败")
+	}
+
+	clearToken(ctx)
 
 	this.json(ctx, nil, facade.Lang(ctx, "退出成功！"), 200)
 }
 
-// 设置登录token到客户的cookie中
-func setToken(ctx *gin.Context, token any) {
+// createUser 创建用户，未指定账号时生成从 10000001 开始递增的纯数字账号（8 位以内）
+func createUser(table *model.Users) (err error) {
+
+	// 设置登录时间
+	table.LoginTime = time.Now().Unix()
+
+	if !utils.Is.Empty(table.Account) {
+		_, err = facade.DB.Model(table).Create(table)
+		return err
+	}
+
+	// 借助 account 唯一索引，创建冲突时自动重试取下一个可用账号
+	const maxRetry = 10
+	for i := 0; i < maxRetry; i++ {
+		maxAccount, _ := facade.DB.Model(&model.Users{}).Max("cast(account as unsigned)")
+		next := cast.ToInt64(maxAccount) + 1
+		// 起点 10000001，保证首个账号为 10000001 并依次递增
+		if next < 10000001 {
+			next = 10000001
+		}
+		// 限制 8 位以内（不超过 99999999）
+		if next > 99999999 {
+			next = 99999999
+		}
+		table.Account = cast.ToString(next)
+		_, err = facade.DB.Model(table).Create(table)
+		if err == nil {
+			return nil
+		}
+		// 非唯一索引冲突（Error 1062）则重试，其他错误直接返回
+		if !strings.Contains(cast.ToString(err.Error()), "1062") {
+			return err
+		}
+	}
+
+	return errors.New("注册失败，请稍后重试！")
+}
+
+// createSession 创建登录会话，写入访问令牌与刷新令牌 cookie，返回登录结果
+func createSession(ctx *gin.Context, uid int, password string) (map[string]any, error) {
+
+	session, refresh, err := model.CreateUserSession(uid, ctx.ClientIP(), ctx.Request.UserAgent())
+	if err != nil {
+		return nil, err
+	}
 
+	jwt := session.AccessToken(password)
+	if jwt.Error != nil {
+		return nil, jwt.Error
+	}
+
+	// 往客户端写入cookie - 存储登录token
+	setToken(ctx, jwt.Text)
+	setRefreshToken(ctx, refresh)
+
+	return map[string]any{
+		"token":              jwt.Text,
+		"valid_time":         jwt.Valid, // 访问令牌有效期（秒）
+		"refresh_token":      refresh,
+		"refresh_valid_time": facade.JwtSessionExpire(), // 登录会话有效期（秒）
+	}, nil
+}
+
+// cookieHost 写入 cookie 的域名
+func cookieHost(ctx *gin.Context) string {
 	host := ctx.Request.Host
 	if strings.Contains(host, ":") {
 		host = strings.Split(host, ":")[0]
 	}
+	return host
+}
+
+// refreshTokenName 刷新令牌的 cookie 名称
+func refreshTokenName() string {
+	return cast.ToString(facade.AppToml.Get("app.token_name", "INIS_LOGIN_TOKEN")) + "_REFRESH"
+}
 
-	expire := cast.ToInt(utils.Calc(facade.AppToml.Get("jwt.expire", facade.DefaultJwtExpire)))
+// 设置登录token到客户的cookie中
+func setToken(ctx *gin.Context, token any) {
+
+	expire := cast.ToInt(facade.JwtAccessExpire())
 	tokenName := cast.ToString(facade.AppToml.Get("app.token_name", "INIS_LOGIN_TOKEN"))
 
-	ctx.SetCookie(tokenName, cast.ToString(token), expire, "/", host, false, false)
+	ctx.SetCookie(tokenName, cast.ToString(token), expire, "/", cookieHost(ctx), false, false)
+}
+
+// setRefreshToken 设置刷新令牌到 cookie 中（HttpOnly，前端脚本无法读取）
+func setRefreshToken(ctx *gin.Context, token string) {
+	ctx.SetCookie(refreshTokenName(), token, cast.ToInt(facade.JwtSessionExpire()), "/", cookieHost(ctx), false, true)
+}
+
+// clearToken 清除登录相关 cookie
+func clearToken(ctx *gin.Context) {
+
+	host := cookieHost(ctx)
+	tokenName := cast.ToString(facade.AppToml.Get("app.token_name", "INIS_LOGIN_TOKEN"))
+
+	// 清除 cookie：domain 必须与登录时 setToken 的 domain 一致，否则无法清除
+	// 同时兼容带域名和空域名两种写法
+	for _, name := range []string{tokenName, refreshTokenName()} {
+		ctx.SetCookie(name, "", -1, "/", host, false, false)
+		ctx.SetCookie(name, "", -1, "/", "", false, false)
+	}
 }
 
 // 获取注册配置
@@ -794,20 +1102,11 @@ func (this *Comm) auth(uid any) {
 	// 默认权限
 	ids := utils.Unity.Ids(config["text"])
 
-	for _, id := range ids {
-		// 查找权限分组数据
-		item, _ := facade.DB.Model(&model.AuthGroup{}).WithTrashed().Where("id", id).Find()
-		// 分组不存在 - 跳过
-		if utils.Is.Empty(item) {
-			return
-		}
-		uids := utils.Unity.Ids(item["uids"])
-		// 如果分组中没有该用户
-		if !utils.In.Array(uid, uids) {
-			uids = append(uids, uid)
-			go facade.DB.Model(&model.AuthGroup{}).Where("id", id).Update(map[string]any{
-				"uids": fmt.Sprintf("|%v|", strings.Join(cast.ToStringSlice(utils.ArrayUnique(utils.ArrayEmpty(uids))), "|")),
-			})
-		}
+	if utils.Is.Empty(ids) {
+		return
 	}
+
+	// 只加入存在的分组
+	exist, _ := facade.DB.Model(&model.AuthGroup{}).WithTrashed().WhereIn("id", ids).Column("id")
+	go model.AddUserGroups(cast.ToInt(uid), cast.ToIntSlice(utils.Unity.Ids(exist))...)
 }
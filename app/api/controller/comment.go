@@ -28,6 +28,11 @@ const (
 var commentAllowFieldsSlice = []any{"pid", "content", "bind_id", "bind_type", "editor", "json", "text"}
 var commentAllowQuerySlice = []any{"id", "pid", "bind_id", "bind_type", "editor"}
 
+func init() {
+	// 以下接口会调用 meta.can / meta.owned 判断策略中的数据条件（如 comment.uid == user.id）
+	model.RegisterAuthCheck("[PUT]/api/comment/update", "[DELETE]/api/comment/remove", "[DELETE]/api/comment/delete")
+}
+
 func (this *Comment) buildQuery(query *facade.ModelStruct, params map[string]any) *facade.ModelStruct {
 	return query.
 		IWhere(params["where"]).
@@ -530,6 +535,15 @@ func (this *Comment) create(ctx *gin.Context) {
 		return
 	}
 
+	model.TriggerWebhook(model.WebhookEventCommentCreated, map[string]any{
+		"id":        table.Id,
+		"pid":       table.Pid,
+		"uid":       table.Uid,
+		"bind_id":   table.BindId,
+		"bind_type": table.BindType,
+		"content":   table.Content,
+	})
+
 	go func() {
 		defer func() {
 			if r := recover(); r != nil {
@@ -563,10 +577,6 @@ func (this *Comment) create(ctx *gin.Context) {
 		} else if cast.ToInt(emailNotify["enabled"]) == 1 {
 			facade.Log.Info(nil, "邮件通知功能已开启")
 
-			retryCount := cast.ToInt(emailNotify["retry_count"])
-			retryInterval := cast.ToInt(emailNotify["retry_interval"])
-			facade.Log.Info(map[string]any{"retry_count": retryCount, "retry_interval": retryInterval}, "邮件发送重试配置")
-
 			userInfo, _ := facade.DB.Model(&model.Users{}).Where("id", user.Id).Find()
 			userEmail := cast.ToString(cast.ToStringMap(userInfo)["email"])
 			facade.Log.Info(map[string]any{"user_id": user.Id, "user_email": facade.Comm.MaskEmail(userEmail), "user_name": cast.ToString(cast.ToStringMap(userInfo)["name"])}, "获取评论用户信息")
@@ -616,14 +626,17 @@ func (this *Comment) create(ctx *gin.Context) {
 			}
 			facade.Log.Info(map[string]any{"comment_id": table.Id, "bind_type": table.BindType, "bind_id": table.BindId}, "评论通知处理")
 
-			var authorEmail string
+			var authorUid int
+			var authorEmail, authorLang string
 			switch table.BindType {
 			case "article":
 				article, _ := facade.DB.Model(&model.Article{}).Where("id", table.BindId).Find()
 				if !utils.Is.Empty(article) {
 					authorId := cast.ToInt(cast.ToStringMap(article)["uid"])
 					author, _ := facade.DB.Model(&model.Users{}).Where("id", authorId).Find()
+					authorUid = authorId
 					authorEmail = cast.ToString(cast.ToStringMap(author)["email"])
+					authorLang = cast.ToString(cast.ToStringMap(author)["lang"])
 					facade.Log.Info(map[string]any{"article_id": table.BindId, "author_id": authorId, "author_email": facade.Comm.MaskEmail(authorEmail)}, "获取文章作者信息")
 				} else {
 					facade.Log.Warn(map[string]any{"article_id": table.BindId}, "文章不存在，跳过发送给作者")
@@ -633,7 +646,9 @@ func (this *Comment) create(ctx *gin.Context) {
 				if !utils.Is.Empty(page) {
 					authorId := cast.ToInt(cast.ToStringMap(page)["uid"])
 					author, _ := facade.DB.Model(&model.Users{}).Where("id", authorId).Find()
+					authorUid = authorId
 					authorEmail = cast.ToString(cast.ToStringMap(author)["email"])
+					authorLang = cast.ToString(cast.ToStringMap(author)["lang"])
 					facade.Log.Info(map[string]any{"page_id": table.BindId, "author_id": authorId, "author_email": facade.Comm.MaskEmail(authorEmail)}, "获取页面作者信息")
 				} else {
 					facade.Log.Warn(map[string]any{"page_id": table.BindId}, "页面不存在，跳过发送给作者")
@@ -643,7 +658,9 @@ func (this *Comment) create(ctx *gin.Context) {
 				if !utils.Is.Empty(moment) {
 					authorId := cast.ToInt(cast.ToStringMap(moment)["uid"])
 					author, _ := facade.DB.Model(&model.Users{}).Where("id", authorId).Find()
+					authorUid = authorId
 					authorEmail = cast.ToString(cast.ToStringMap(author)["email"])
+					authorLang = cast.ToString(cast.ToStringMap(author)["lang"])
 					facade.Log.Info(map[string]any{"moment_id": table.BindId, "author_id": authorId, "author_email": facade.Comm.MaskEmail(authorEmail)}, "获取动态作者信息")
 				} else {
 					facade.Log.Warn(map[string]any{"moment_id": table.BindId}, "动态不存在，跳过发送给作者")
@@ -654,19 +671,14 @@ func (this *Comment) create(ctx *gin.Context) {
 
 			if utils.Is.Email(authorEmail) && authorEmail != userEmail {
 				facade.Log.Info(map[string]any{"recipient": facade.Comm.MaskEmail(authorEmail)}, "开始发送邮件给作者")
-				for i := 0; i <= retryCount; i++ {
-					sms := facade.NewSMS("email")
-					response := sms.SendCommentNotify(authorEmail, commentInfo)
-					if response.Error == nil {
-						facade.Log.Info(map[string]any{"recipient": facade.Comm.MaskEmail(authorEmail)}, "邮件发送给作者成功")
-						break
-					}
-					if i < retryCount {
-						facade.Log.Warn(map[string]any{"error": response.Error, "retry": i + 1}, "邮件发送给作者失败，准备重试")
-						time.Sleep(time.Duration(retryInterval) * time.Minute)
-					} else {
-						facade.Log.Error(map[string]any{"error": response.Error, "recipient": facade.Comm.MaskEmail(authorEmail)}, "发送文章作者邮件通知失败")
-					}
+				// 按收件人的语言与通知偏好发送
+				commentInfo["lang"] = authorLang
+				commentInfo["uid"] = authorUid
+				commentInfo["notify_type"] = model.NotifyTypeComment
+				// 写入发件箱，失败后由定时任务重试
+				response := model.NewOutbox("email").SendCommentNotify(authorEmail, commentInfo)
+				if response.Error != nil {
+					facade.Log.Error(map[string]any{"error": response.Error, "recipient": facade.Comm.MaskEmail(authorEmail)}, "发送文章作者邮件通知失败")
 				}
 			} else {
 				facade.Log.Warn(map[string]any{"author_email": facade.Comm.MaskEmail(authorEmail), "user_email": facade.Comm.MaskEmail(userEmail)}, "作者邮箱无效或重复，跳过发送")
@@ -683,19 +695,12 @@ func (this *Comment) create(ctx *gin.Context) {
 
 					if utils.Is.Email(parentEmail) && parentUid != user.Id {
 						facade.Log.Info(map[string]any{"recipient": facade.Comm.MaskEmail(parentEmail)}, "开始发送邮件给被回复用户")
-						for i := 0; i <= retryCount; i++ {
-							sms := facade.NewSMS("email")
-							response := sms.SendReplyNotify(parentEmail, commentInfo)
-							if response.Error == nil {
-								facade.Log.Info(map[string]any{"recipient": facade.Comm.MaskEmail(parentEmail)}, "邮件发送给被回复用户成功")
-								break
-							}
-							if i < retryCount {
-								facade.Log.Warn(map[string]any{"error": response.Error, "retry": i + 1}, "邮件发送给被回复用户失败，准备重试")
-								time.Sleep(time.Duration(retryInterval) * time.Minute)
-							} else {
-								facade.Log.Error(map[string]any{"error": response.Error, "recipient": facade.Comm.MaskEmail(parentEmail)}, "发送评论回复邮件通知失败")
-							}
+						commentInfo["lang"] = cast.ToString(cast.ToStringMap(parentUser)["lang"])
+						commentInfo["uid"] = parentUid
+						commentInfo["notify_type"] = model.NotifyTypeReply
+						response := model.NewOutbox("email").SendReplyNotify(parentEmail, commentInfo)
+						if response.Error != nil {
+							facade.Log.Error(map[string]any{"error": response.Error, "recipient": facade.Comm.MaskEmail(parentEmail)}, "发送评论回复邮件通知失败")
 						}
 					} else {
 						facade.Log.Warn(map[string]any{"parent_email": facade.Comm.MaskEmail(parentEmail), "parent_uid": parentUid, "user_id": user.Id}, "被回复用户邮箱无效或为评论者本人，跳过发送")
@@ -757,9 +762,26 @@ func (this *Comment) create(ctx *gin.Context) {
 			}
 
 			_, _ = (&model.Notification{}).CreateNotification(
-				authorId, user.Id, "comment", title, notifContent, table.BindType, table.BindId,
+				authorId, user.Id, model.NotifyTypeComment, title, notifContent, table.BindType, table.BindId,
 			)
 		}
+
+		// 回复评论时通知被回复用户（作者本人已收到上面的通知）
+		if table.Pid > 0 {
+			parentComment, _ := facade.DB.Model(&model.Comment{}).Where("id", table.Pid).Find()
+			parentUid := cast.ToInt(cast.ToStringMap(parentComment)["uid"])
+			if parentUid > 0 && parentUid != user.Id && parentUid != authorId {
+				notifUserInfo, _ := facade.DB.Model(&model.Users{}).Where("id", user.Id).Find()
+				notifContent := cast.ToString(cast.ToStringMap(notifUserInfo)["nickname"]) + " 回复了你的评论"
+				if bindTitle != "" {
+					notifContent += "（" + bindTitle + "）"
+				}
+
+				_, _ = (&model.Notification{}).CreateNotification(
+					parentUid, user.Id, model.NotifyTypeReply, "收到新回复", notifContent, table.BindType, table.BindId,
+				)
+			}
+		}
 	}()
 
 	this.json(ctx, gin.H{"id": table.Id}, facade.Lang(ctx, "创建成功！"), 200)
@@ -807,7 +829,7 @@ func (this *Comment) update(ctx *gin.Context) {
 	item := facade.DB.Model(&table).WithTrashed().Where("id", params["id"])
 
 	itemData, _ := item.Find()
-	if !root && cast.ToInt(itemData["uid"]) != this.user(ctx).Id {
+	if !this.meta.can(ctx, "comment", itemData) {
 		this.json(ctx, nil, facade.Lang(ctx, "无权限！"), 403)
 		return
 	}
@@ -911,12 +933,10 @@ func (this *Comment) remove(ctx *gin.Context) {
 
 	item := facade.DB.Model(&model.Comment{})
 
-	if !this.meta.root(ctx) {
-		item.Where("uid", this.user(ctx).Id)
-	}
-
-	columnData, _ := item.WhereIn("id", ids).Column("id")
-	ids = utils.Unity.Ids(columnData)
+	// 筛选可以操作的数据（root、作者本人或策略条件允许）
+	var rows []map[string]any
+	facade.DB.Drive().Model(&model.Comment{}).Where("id IN ?", ids).Find(&rows)
+	ids = this.meta.owned(ctx, "comment", rows)
 
 	if utils.Is.Empty(ids) {
 		this.json(ctx, nil, facade.Lang(ctx, "无可操作数据！"), 204)
@@ -944,11 +964,10 @@ func (this *Comment) delete(ctx *gin.Context) {
 
 	item := facade.DB.Model(&model.Comment{}).WithTrashed()
 
-	if !this.meta.root(ctx) {
-		item.Where("uid", this.user(ctx).Id)
-	}
-
-	ids = utils.Unity.Ids(item.WhereIn("id", ids).Column("id"))
+	// 筛选可以操作的数据（root、作者本人或策略条件允许）
+	var rows []map[string]any
+	facade.DB.Drive().Model(&model.Comment{}).Unscoped().Where("id IN ?", ids).Find(&rows)
+	ids = this.meta.owned(ctx, "comment", rows)
 
 	if utils.Is.Empty(ids) {
 		this.json(ctx, nil, facade.Lang(ctx, "无可操作数据！"), 204)
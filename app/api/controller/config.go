@@ -9,7 +9,6 @@ import (
 	"time"
 
 	"github.com/gin-gonic/gin"
-	"github.com/google/uuid"
 	"github.com/spf13/cast"
 	"github.com/unti-io/go-utils/utils"
 )
@@ -156,6 +155,8 @@ func (this *Config) delCache() {
 	facade.Cache.DelTags([]any{"[GET]", "[?]"})
 	// 清除经验值配置缓存
 	facade.Cache.Del(model.ExpCacheKey)
+	// 清除人机验证配置缓存
+	facade.Cache.Del(model.CaptchaCacheKey)
 }
 
 func (this *Config) one(ctx *gin.Context) {
@@ -311,7 +312,15 @@ func (this *Config) update(ctx *gin.Context) {
 		return
 	}
 
-	go this.watch()
+	// 自动创建的密钥只在此时返回一次明文
+	if secret := this.watch(); !utils.Is.Empty(secret) {
+		this.json(ctx, gin.H{
+			"id":      table.Id,
+			"key":     table.Key,
+			"api_key": secret,
+		}, facade.Lang(ctx, "更新成功！已为您自动创建API_KEY，请妥善保存，该密钥仅显示一次！"), 200)
+		return
+	}
 
 	this.json(ctx, gin.H{
 		"id":  table.Id,
@@ -470,19 +479,26 @@ func (this *Config) restore(ctx *gin.Context) {
 	this.json(ctx, gin.H{"keys": keys}, facade.Lang(ctx, "恢复成功！"), 200)
 }
 
-func (this *Config) watch() {
+// watch - 开启 API_KEY 功能但无可用密钥时自动创建一个，返回新密钥的明文（未创建时为空）
+func (this *Config) watch() string {
 	item, _ := facade.DB.Model(&model.Config{}).Where("key", "SYSTEM_API_KEY").Find()
-	if cast.ToInt(item["value"]) == 1 {
-
-		ApiKeys := facade.DB.Model(&model.ApiKeys{})
-		count, _ := ApiKeys.Count()
-		if count == 0 {
-			UUID := uuid.New().String()
-			UUID = strings.Replace(UUID, "-", "", -1)
-			ApiKeys.Create(&model.ApiKeys{
-				Value:  strings.ToUpper(UUID),
-				Remark: "检测到您开启了API_KEY功能，但无可用密钥，系统贴心的为您创建了一个密钥！",
-			})
-		}
+	if cast.ToInt(item["value"]) != 1 {
+		return ""
+	}
+
+	count, _ := facade.DB.Model(&model.ApiKeys{}).Count()
+	if count > 0 {
+		return ""
 	}
+
+	table := model.ApiKeys{Remark: "检测到您开启了API_KEY功能，但无可用密钥，系统贴心的为您创建了一个密钥！"}
+	secret := table.SetValue("")
+	if _, err := facade.DB.Model(&model.ApiKeys{}).Create(&table); err != nil {
+		facade.Log.Error(map[string]any{"error": err}, "自动创建接口密钥失败")
+		return ""
+	}
+
+	go facade.Cache.DelTags([]any{"[GET]", "api-keys"})
+
+	return secret
 }
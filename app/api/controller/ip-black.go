@@ -218,6 +218,7 @@ func (this *IpBlack) INDEX(ctx *gin.Context) {
 
 func (this *IpBlack) delCache() {
 	facade.Cache.DelTags([]any{"[GET]", "ip-black"})
+	model.ResetIpMatchers()
 }
 
 func (this *IpBlack) one(ctx *gin.Context) {
@@ -343,6 +344,14 @@ func (this *IpBlack) create(ctx *gin.Context) {
 		return
 	}
 
+	// ip 支持单个IP、CIDR 网段、country:CN、asn:13335
+	if !utils.Is.Empty(params["ip"]) {
+		if _, err := facade.ParseIPRule(cast.ToString(params["ip"])); err != nil {
+			this.json(ctx, nil, facade.Lang(ctx, err.Error()), 400)
+			return
+		}
+	}
+
 	table := model.IpBlack{CreateTime: time.Now().Unix(), UpdateTime: time.Now().Unix()}
 
 	// 设置默认封禁参数（如果用户未指定）
@@ -426,6 +435,14 @@ func (this *IpBlack) update(ctx *gin.Context) {
 		return
 	}
 
+	// ip 支持单个IP、CIDR 网段、country:CN、asn:13335
+	if !utils.Is.Empty(params["ip"]) {
+		if _, err := facade.ParseIPRule(cast.ToString(params["ip"])); err != nil {
+			this.json(ctx, nil, facade.Lang(ctx, err.Error()), 400)
+			return
+		}
+	}
+
 	table := model.IpBlack{}
 	async := utils.Async[map[string]any]()
 
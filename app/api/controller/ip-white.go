@@ -218,6 +218,7 @@ func (this *IpWhite) INDEX(ctx *gin.Context) {
 
 func (this *IpWhite) delCache() {
 	facade.Cache.DelTags([]any{"[GET]", "ip-white"})
+	model.ResetIpMatchers()
 }
 
 func (this *IpWhite) one(ctx *gin.Context) {
@@ -343,6 +344,14 @@ func (this *IpWhite) create(ctx *gin.Context) {
 		return
 	}
 
+	// ip 支持单个IP、CIDR 网段、country:CN、asn:13335
+	if !utils.Is.Empty(params["ip"]) {
+		if _, err := facade.ParseIPRule(cast.ToString(params["ip"])); err != nil {
+			this.json(ctx, nil, facade.Lang(ctx, err.Error()), 400)
+			return
+		}
+	}
+
 	table := model.IpWhite{CreateTime: time.Now().Unix(), UpdateTime: time.Now().Unix()}
 
 	for key, val := range params {
@@ -379,6 +388,14 @@ func (this *IpWhite) update(ctx *gin.Context) {
 		return
 	}
 
+	// ip 支持单个IP、CIDR 网段、country:CN、asn:13335
+	if !utils.Is.Empty(params["ip"]) {
+		if _, err := facade.ParseIPRule(cast.ToString(params["ip"])); err != nil {
+			this.json(ctx, nil, facade.Lang(ctx, err.Error()), 400)
+			return
+		}
+	}
+
 	table := model.IpWhite{}
 	async := utils.Async[map[string]any]()
 
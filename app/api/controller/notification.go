@@ -1,9 +1,12 @@
 package controller
 
 import (
+	"bytes"
+	"html/template"
 	"inis/app/facade"
 	"inis/app/model"
 	"math"
+	"net/http"
 	"strings"
 
 	"github.com/gin-gonic/gin"
@@ -60,16 +63,20 @@ func (this *Notification) IGET(ctx *gin.Context) {
 	method := strings.ToLower(ctx.Param("method"))
 
 	allow := map[string]any{
-		"one":          this.one,
-		"all":          this.all,
-		"sum":          this.sum,
-		"min":          this.min,
-		"max":          this.max,
-		"rand":         this.rand,
-		"count":        this.count,
-		"column":       this.column,
-		"unread-count": this.unreadCount,
-		"list":         this.list,
+		"one":                this.one,
+		"all":                this.all,
+		"sum":                this.sum,
+		"min":                this.min,
+		"max":                this.max,
+		"rand":               this.rand,
+		"count":              this.count,
+		"column":             this.column,
+		"unread-count":       this.unreadCount,
+		"list":               this.list,
+		"preferences":        this.preferences,
+		"unsubscribe":        this.unsubscribePage,
+		"push-key":           this.pushKey,
+		"push-subscriptions": this.pushSubscriptions,
 	}
 	err := this.call(allow, method, ctx)
 
@@ -83,9 +90,11 @@ func (this *Notification) IPOST(ctx *gin.Context) {
 	method := strings.ToLower(ctx.Param("method"))
 
 	allow := map[string]any{
-		"save":        this.save,
-		"create":      this.create,
-		"send-system": this.sendSystem,
+		"save":           this.save,
+		"create":         this.create,
+		"send-system":    this.sendSystem,
+		"unsubscribe":    this.unsubscribe,
+		"push-subscribe": this.pushSubscribe,
 	}
 	err := this.call(allow, method, ctx)
 
@@ -101,11 +110,12 @@ func (this *Notification) IPUT(ctx *gin.Context) {
 	method := strings.ToLower(ctx.Param("method"))
 
 	allow := map[string]any{
-		"update":     this.update,
-		"restore":    this.restore,
-		"read":       this.read,
-		"read-all":   this.readAll,
-		"read-batch": this.readBatch,
+		"update":      this.update,
+		"restore":     this.restore,
+		"read":        this.read,
+		"read-all":    this.readAll,
+		"read-batch":  this.readBatch,
+		"preferences": this.putPreferences,
 	}
 	err := this.call(allow, method, ctx)
 
@@ -121,10 +131,11 @@ func (this *Notification) IDEL(ctx *gin.Context) {
 	method := strings.ToLower(ctx.Param("method"))
 
 	allow := map[string]any{
-		"remove":     this.remove,
-		"delete":     this.delete,
-		"clear":      this.clear,
-		"remove-all": this.removeAll,
+		"remove":           this.remove,
+		"delete":           this.delete,
+		"clear":            this.clear,
+		"remove-all":       this.removeAll,
+		"push-unsubscribe": this.pushUnsubscribe,
 	}
 	err := this.call(allow, method, ctx)
 
@@ -370,6 +381,8 @@ func (this *Notification) readBatch(ctx *gin.Context) {
 		}
 	}
 
+	model.PublishNotifyUnread(uid)
+
 	this.json(ctx, gin.H{"ids": ids}, facade.Lang(ctx, "批量标记已读成功！"), 200)
 }
 
@@ -651,6 +664,8 @@ func (this *Notification) remove(ctx *gin.Context) {
 		}
 	}
 
+	model.PublishNotifyUnread(uid)
+
 	this.json(ctx, gin.H{"ids": ids}, facade.Lang(ctx, "删除成功！"), 200)
 }
 
@@ -689,6 +704,8 @@ func (this *Notification) delete(ctx *gin.Context) {
 		return
 	}
 
+	model.PublishNotifyUnread(uid)
+
 	this.json(ctx, gin.H{"ids": ids}, facade.Lang(ctx, "删除成功！"), 200)
 }
 
@@ -735,6 +752,8 @@ func (this *Notification) removeAll(ctx *gin.Context) {
 		return
 	}
 
+	model.PublishNotifyUnread(uid)
+
 	this.json(ctx, gin.H{"ids": ids}, facade.Lang(ctx, "清空成功！"), 200)
 }
 
@@ -859,6 +878,7 @@ func (this *Notification) sendSystem(ctx *gin.Context) {
 		}
 		// 发送人（管理员）自己标记为已读，避免后台角标出现未读
 		_ = (&model.Notification{}).MarkBroadcastRead(broadcast.Id, uid)
+		model.PublishNotifyUnread(uid)
 
 		this.json(ctx, gin.H{
 			"broadcast": true,
@@ -922,11 +942,13 @@ func (this *Notification) sendSystem(ctx *gin.Context) {
 
 				// 使用现有邮件发送机制
 				commentInfo := map[string]any{
-					"email":   email,
-					"subject": t,
-					"content": c,
+					"email":       email,
+					"subject":     t,
+					"content":     c,
+					"uid":         uid,
+					"notify_type": model.NotifyTypeSystem,
 				}
-				facade.SMS.SendCommentNotify(email, commentInfo)
+				model.NewOutbox(facade.SMSModeEmail).SendCommentNotify(email, commentInfo)
 			}(targetId, notifTitle, notifContent)
 		}
 	}
@@ -936,3 +958,271 @@ func (this *Notification) sendSystem(ctx *gin.Context) {
 		"success": successCount,
 	}, facade.Lang(ctx, "推送完成！"), 200)
 }
+
+// preferences - 获取当前用户的通知偏好
+func (this *Notification) preferences(ctx *gin.Context) {
+	uid := this.meta.user(ctx).Id
+	if uid == 0 {
+		this.json(ctx, nil, facade.Lang(ctx, "请先登录！"), 401)
+		return
+	}
+
+	this.json(ctx, gin.H{
+		"types":       model.NotifyTypes,
+		"channels":    model.NotifyChannels,
+		"preferences": model.NotifyPreferences(uid),
+		"required":    map[string][]string{model.NotifyTypeSystem: {model.NotifyChannelSite}},
+		"digest":      model.GetNotifyDigest(uid),
+	}, facade.Lang(ctx, "查询成功！"), 200)
+}
+
+// putPreferences - 修改当前用户的通知偏好
+// 支持批量 preferences: {"comment": {"email": false}} 或单项 type、channel、enabled
+// 摘要邮件：digest: {"frequency": "off|daily|weekly", "timezone": "Asia/Shanghai"}
+func (this *Notification) putPreferences(ctx *gin.Context) {
+	params := this.params(ctx)
+	uid := this.meta.user(ctx).Id
+	if uid == 0 {
+		this.json(ctx, nil, facade.Lang(ctx, "请先登录！"), 401)
+		return
+	}
+
+	if !utils.Is.Empty(params["digest"]) {
+		digest := model.GetNotifyDigest(uid)
+		for key, val := range cast.ToStringMap(params["digest"]) {
+			switch key {
+			case "frequency":
+				digest.Frequency = cast.ToString(val)
+			case "timezone":
+				digest.Timezone = cast.ToString(val)
+			}
+		}
+		if err := model.SetNotifyDigest(uid, digest.Frequency, digest.Timezone); err != nil {
+			this.json(ctx, nil, facade.Lang(ctx, err.Error()), 400)
+			return
+		}
+	}
+
+	items := cast.ToStringMap(params["preferences"])
+	if utils.Is.Empty(items) {
+		switch {
+		case !utils.Is.Empty(params["type"]) && !utils.Is.Empty(params["channel"]):
+			items = map[string]any{
+				cast.ToString(params["type"]): map[string]any{cast.ToString(params["channel"]): params["enabled"]},
+			}
+		case !utils.Is.Empty(params["digest"]):
+			// 仅修改摘要设置
+		default:
+			this.json(ctx, nil, facade.Lang(ctx, "%s 不能为空！", "preferences"), 400)
+			return
+		}
+	}
+
+	// 先校验再写入，避免部分生效
+	for typ, value := range items {
+		if _, ok := model.NotifyTypes[typ]; !ok {
+			this.json(ctx, nil, facade.Lang(ctx, "不支持的通知类型：%v", typ), 400)
+			return
+		}
+		for channel := range cast.ToStringMap(value) {
+			if _, ok := model.NotifyChannels[channel]; !ok {
+				this.json(ctx, nil, facade.Lang(ctx, "不支持的通知渠道：%v", channel), 400)
+				return
+			}
+			if model.NotifyRequired(typ, channel) {
+				this.json(ctx, nil, facade.Lang(ctx, "该通知不可关闭！"), 400)
+				return
+			}
+		}
+	}
+
+	for typ, value := range items {
+		for channel, enabled := range cast.ToStringMap(value) {
+			if err := model.SetNotifyPreference(uid, typ, channel, cast.ToBool(enabled)); err != nil {
+				this.json(ctx, nil, facade.Lang(ctx, "修改失败！"), 400)
+				return
+			}
+		}
+	}
+
+	this.json(ctx, model.NotifyPreferences(uid), facade.Lang(ctx, "修改成功！"), 200)
+}
+
+// unsubscribeCheck - 校验退订链接参数与签名
+func (this *Notification) unsubscribeCheck(params map[string]any) (uid int, typ string, ok bool) {
+
+	uid = cast.ToInt(params["uid"])
+	typ = cast.ToString(params["type"])
+
+	if uid <= 0 || utils.Is.Empty(typ) || utils.Is.Empty(params["sign"]) {
+		return 0, "", false
+	}
+
+	if _, exist := model.NotifyTypes[typ]; !exist && typ != model.NotifyDigestType {
+		return 0, "", false
+	}
+
+	return uid, typ, facade.MailUnsubscribeVerify(uid, typ, cast.ToString(params["sign"]))
+}
+
+// unsubscribeTemplate - 退订确认页
+var unsubscribeTemplate = template.Must(template.New("unsubscribe").Parse(`<!DOCTYPE html>
+<html><head><meta charset="utf-8"><meta name="viewport" content="width=device-width, initial-scale=1"><meta name="robots" content="noindex">
+<title>{{.title}}</title>
+<style>body{font-family:sans-serif;background:#f5f5f5;margin:0;padding:48px 16px}.box{max-width:420px;margin:0 auto;background:#fff;border-radius:8px;padding:32px;text-align:center}button{margin-top:16px;padding:10px 24px;border:0;border-radius:4px;background:#4a7cf7;color:#fff;font-size:15px;cursor:pointer}</style>
+</head><body><div class="box"><h2>{{.title}}</h2><p>{{.message}}</p>
+{{if .action}}<form method="post" action="{{.action}}"><button type="submit">{{.button}}</button></form>{{end}}
+</div></body></html>`))
+
+// unsubscribePage - 退订确认页（GET 不修改数据，避免邮件安全扫描或链接预取误退订；确认后以 POST 提交）
+func (this *Notification) unsubscribePage(ctx *gin.Context) {
+
+	if _, _, ok := this.unsubscribeCheck(this.params(ctx)); !ok {
+		this.unsubscribeHTML(ctx, http.StatusBadRequest, facade.Lang(ctx, "退订链接无效！"), false)
+		return
+	}
+
+	this.unsubscribeHTML(ctx, http.StatusOK, facade.Lang(ctx, "确认后你将不再收到此类邮件。"), true)
+}
+
+// unsubscribeHTML - 输出退订页面，confirm 为 true 时附带确认按钮
+func (this *Notification) unsubscribeHTML(ctx *gin.Context, code int, message string, confirm bool) {
+
+	data := map[string]any{"title": facade.Lang(ctx, "邮件退订"), "message": message}
+	if confirm {
+		data["button"] = facade.Lang(ctx, "确认退订")
+		data["action"] = ctx.Request.URL.RequestURI()
+	}
+
+	buffer := new(bytes.Buffer)
+	if err := unsubscribeTemplate.Execute(buffer, data); err != nil {
+		this.json(ctx, nil, err.Error(), 500)
+		return
+	}
+
+	ctx.Header("Cache-Control", "no-store")
+	ctx.Data(code, "text/html; charset=utf-8", buffer.Bytes())
+}
+
+// unsubscribeReply - 退订结果（浏览器提交确认页时返回页面，其余返回 JSON）
+func (this *Notification) unsubscribeReply(ctx *gin.Context, data any, msg string, code int) {
+	if strings.Contains(ctx.GetHeader("Accept"), "text/html") {
+		this.unsubscribeHTML(ctx, utils.Ternary(code == 200, http.StatusOK, http.StatusBadRequest), msg, false)
+		return
+	}
+	this.json(ctx, data, msg, code)
+}
+
+// unsubscribe - 邮件一键退订（无需登录，通过签名校验；支持 RFC 8058 List-Unsubscribe-Post）
+func (this *Notification) unsubscribe(ctx *gin.Context) {
+
+	uid, typ, ok := this.unsubscribeCheck(this.params(ctx))
+	if !ok {
+		this.unsubscribeReply(ctx, nil, facade.Lang(ctx, "退订链接无效！"), 400)
+		return
+	}
+
+	// 摘要邮件退订即关闭摘要
+	if typ == model.NotifyDigestType {
+		digest := model.GetNotifyDigest(uid)
+		if err := model.SetNotifyDigest(uid, model.NotifyDigestOff, digest.Timezone); err != nil {
+			this.unsubscribeReply(ctx, nil, facade.Lang(ctx, "退订失败！"), 400)
+			return
+		}
+		this.unsubscribeReply(ctx, gin.H{"type": typ}, facade.Lang(ctx, "退订成功，你将不再收到摘要邮件！"), 200)
+		return
+	}
+
+	if err := model.SetNotifyPreference(uid, typ, model.NotifyChannelEmail, false); err != nil {
+		this.unsubscribeReply(ctx, nil, facade.Lang(ctx, "退订失败！"), 400)
+		return
+	}
+
+	this.unsubscribeReply(ctx, gin.H{"type": typ}, facade.Lang(ctx, "退订成功，你将不再收到此类邮件！"), 200)
+}
+
+// pushKey - 获取 Web Push 的 VAPID 公钥（前端订阅时作为 applicationServerKey）
+func (this *Notification) pushKey(ctx *gin.Context) {
+
+	key := facade.WebPushPublicKey()
+	if utils.Is.Empty(key) {
+		this.json(ctx, nil, facade.Lang(ctx, "Web Push 未配置！"), 400)
+		return
+	}
+
+	this.json(ctx, gin.H{"public_key": key}, facade.Lang(ctx, "数据请求成功！"), 200)
+}
+
+// pushSubscriptions - 获取当前用户已订阅 Web Push 的设备
+func (this *Notification) pushSubscriptions(ctx *gin.Context) {
+	uid := this.meta.user(ctx).Id
+	if uid == 0 {
+		this.json(ctx, nil, facade.Lang(ctx, "请先登录！"), 401)
+		return
+	}
+
+	var list []model.PushSubscription
+	facade.DB.Drive().Where("uid = ?", uid).Order("update_time desc").Find(&list)
+
+	this.json(ctx, list, facade.Lang(ctx, "数据请求成功！"), 200)
+}
+
+// pushSubscribe - 订阅 Web Push
+// 参数为浏览器 PushSubscription.toJSON() 的结果：{"endpoint": "...", "keys": {"p256dh": "...", "auth": "..."}}
+func (this *Notification) pushSubscribe(ctx *gin.Context) {
+	params := this.params(ctx)
+	uid := this.meta.user(ctx).Id
+	if uid == 0 {
+		this.json(ctx, nil, facade.Lang(ctx, "请先登录！"), 401)
+		return
+	}
+
+	if utils.Is.Empty(facade.WebPushPublicKey()) {
+		this.json(ctx, nil, facade.Lang(ctx, "Web Push 未配置！"), 400)
+		return
+	}
+
+	keys := cast.ToStringMap(params["keys"])
+	subscription := facade.WebPushSubscription{
+		Endpoint: cast.ToString(params["endpoint"]),
+		P256dh:   cast.ToString(utils.Ternary(utils.Is.Empty(keys["p256dh"]), params["p256dh"], keys["p256dh"])),
+		Auth:     cast.ToString(utils.Ternary(utils.Is.Empty(keys["auth"]), params["auth"], keys["auth"])),
+	}
+
+	for key, val := range map[string]string{"endpoint": subscription.Endpoint, "p256dh": subscription.P256dh, "auth": subscription.Auth} {
+		if utils.Is.Empty(val) {
+			this.json(ctx, nil, facade.Lang(ctx, "%s 不能为空！", key), 400)
+			return
+		}
+	}
+
+	if err := model.SavePushSubscription(uid, subscription, ctx.Request.UserAgent()); err != nil {
+		this.json(ctx, nil, facade.Lang(ctx, err.Error()), 400)
+		return
+	}
+
+	this.json(ctx, nil, facade.Lang(ctx, "订阅成功！"), 200)
+}
+
+// pushUnsubscribe - 取消 Web Push 订阅（endpoint 为空时取消全部设备）
+func (this *Notification) pushUnsubscribe(ctx *gin.Context) {
+	params := this.params(ctx)
+	uid := this.meta.user(ctx).Id
+	if uid == 0 {
+		this.json(ctx, nil, facade.Lang(ctx, "请先登录！"), 401)
+		return
+	}
+
+	count, err := model.DeletePushSubscription(uid, cast.ToString(params["endpoint"]))
+	if err != nil {
+		this.json(ctx, nil, facade.Lang(ctx, "取消订阅失败！"), 400)
+		return
+	}
+
+	if count == 0 {
+		this.json(ctx, nil, facade.Lang(ctx, "无可操作数据！"), 204)
+		return
+	}
+
+	this.json(ctx, gin.H{"count": count}, facade.Lang(ctx, "取消订阅成功！"), 200)
+}
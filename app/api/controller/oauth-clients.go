@@ -0,0 +1,398 @@
+package controller
+
+import (
+	"inis/app/facade"
+	"inis/app/model"
+	"inis/app/validator"
+	"math"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/spf13/cast"
+	"github.com/unti-io/go-utils/utils"
+)
+
+var oauthClientsAllowFieldsSlice = []any{
+	"name", "logo", "homepage", "description", "redirect_uris", "scopes",
+	"public", "trusted", "status", "remark", "json", "text",
+}
+
+// OAuthClients - 接入 inis 账号登录的第三方应用管理
+type OAuthClients struct {
+	base
+}
+
+func (this *OAuthClients) IGET(ctx *gin.Context) {
+	method := strings.ToLower(ctx.Param("method"))
+
+	allow := map[string]any{
+		"one":    this.one,
+		"all":    this.all,
+		"count":  this.count,
+		"scopes": this.scopes,
+	}
+	err := this.call(allow, method, ctx)
+
+	if err != nil {
+		this.json(ctx, nil, facade.Lang(ctx, "方法调用错误：%v", err.Error()), 405)
+		return
+	}
+}
+
+func (this *OAuthClients) IPOST(ctx *gin.Context) {
+	method := strings.ToLower(ctx.Param("method"))
+
+	allow := map[string]any{
+		"save":   this.save,
+		"create": this.create,
+	}
+	err := this.call(allow, method, ctx)
+
+	if err != nil {
+		this.json(ctx, nil, facade.Lang(ctx, "方法调用错误：%v", err.Error()), 405)
+		return
+	}
+}
+
+func (this *OAuthClients) IPUT(ctx *gin.Context) {
+	method := strings.ToLower(ctx.Param("method"))
+
+	allow := map[string]any{
+		"update":  this.update,
+		"restore": this.restore,
+		"secret":  this.secret,
+	}
+	err := this.call(allow, method, ctx)
+
+	if err != nil {
+		this.json(ctx, nil, facade.Lang(ctx, "方法调用错误：%v", err.Error()), 405)
+		return
+	}
+}
+
+func (this *OAuthClients) IDEL(ctx *gin.Context) {
+	method := strings.ToLower(ctx.Param("method"))
+
+	allow := map[string]any{
+		"remove": this.remove,
+		"delete": this.delete,
+	}
+	err := this.call(allow, method, ctx)
+
+	if err != nil {
+		this.json(ctx, nil, facade.Lang(ctx, "方法调用错误：%v", err.Error()), 405)
+		return
+	}
+}
+
+func (this *OAuthClients) INDEX(ctx *gin.Context) {
+	this.json(ctx, nil, facade.Lang(ctx, "没什么用！"), 202)
+}
+
+// hideSecret - 移除客户端密钥哈希
+func (this *OAuthClients) hideSecret(item map[string]any) map[string]any {
+	delete(item, "secret_hash")
+	return item
+}
+
+// checkParams - 校验回调地址与权限范围
+func (this *OAuthClients) checkParams(ctx *gin.Context, params map[string]any) bool {
+
+	if value, ok := params["redirect_uris"]; ok {
+		uris := model.OAuthSplit(strings.Join(cast.ToStringSlice(utils.Unity.Keys(value)), ","))
+		if len(uris) == 0 {
+			this.json(ctx, nil, facade.Lang(ctx, "%s 不能为空！", "redirect_uris"), 400)
+			return false
+		}
+		for _, uri := range uris {
+			if err := model.OAuthCheckRedirect(uri); err != nil {
+				this.json(ctx, nil, facade.Lang(ctx, "回调地址 %v 不可用：%v", uri, facade.Lang(ctx, err.Error())), 400)
+				return false
+			}
+		}
+		params["redirect_uris"] = strings.Join(uris, ",")
+	}
+
+	if value, ok := params["scopes"]; ok {
+		scopes := model.OAuthSplit(strings.Join(cast.ToStringSlice(utils.Unity.Keys(value)), ","))
+		for _, scope := range scopes {
+			if _, exist := model.OAuthScopes[scope]; !exist {
+				this.json(ctx, nil, facade.Lang(ctx, "不支持的权限范围：%v", scope), 400)
+				return false
+			}
+		}
+		if !utils.InArray(model.OAuthScopeOpenid, scopes) {
+			this.json(ctx, nil, facade.Lang(ctx, "权限范围必须包含 %s！", model.OAuthScopeOpenid), 400)
+			return false
+		}
+		params["scopes"] = strings.Join(scopes, ",")
+	}
+
+	return true
+}
+
+// scopes - 可开放的权限范围
+func (this *OAuthClients) scopes(ctx *gin.Context) {
+	this.json(ctx, model.OAuthScopes, facade.Lang(ctx, "数据请求成功！"), 200)
+}
+
+func (this *OAuthClients) one(ctx *gin.Context) {
+
+	params := this.params(ctx)
+
+	if utils.Is.Empty(params["id"]) {
+		this.json(ctx, nil, facade.Lang(ctx, "%s 不能为空！", "id"), 400)
+		return
+	}
+
+	item, _ := facade.DB.Model(&model.OAuthClient{}).Where("id", params["id"]).Find()
+	if utils.Is.Empty(item) {
+		this.json(ctx, nil, facade.Lang(ctx, "无数据！"), 204)
+		return
+	}
+
+	this.json(ctx, facade.Comm.WithField(this.hideSecret(item), params["field"]), facade.Lang(ctx, "数据请求成功！"), 200)
+}
+
+func (this *OAuthClients) all(ctx *gin.Context) {
+
+	params := this.params(ctx, map[string]any{
+		"page":  1,
+		"order": "create_time desc",
+	})
+
+	page := cast.ToInt(params["page"])
+	limit := this.meta.limit(ctx)
+
+	query := facade.DB.Model(&[]model.OAuthClient{})
+	if cast.ToBool(params["onlyTrashed"]) {
+		query = query.OnlyTrashed()
+	}
+	if cast.ToBool(params["withTrashed"]) {
+		query = query.WithTrashed()
+	}
+	query = query.IWhere(params["where"]).ILike(params["like"])
+
+	count, _ := query.Count()
+	items, _ := query.Limit(limit).Page(page).Order(params["order"]).Select()
+
+	if utils.Is.Empty(items) {
+		this.json(ctx, gin.H{"data": []any{}, "count": 0, "page": 0}, facade.Lang(ctx, "无数据！"), 204)
+		return
+	}
+
+	for _, item := range items {
+		this.hideSecret(item)
+	}
+
+	this.json(ctx, gin.H{
+		"data":  utils.ArrayMapWithField(items, params["field"]),
+		"count": count,
+		"page":  math.Ceil(float64(count) / float64(limit)),
+	}, facade.Lang(ctx, "数据请求成功！"), 200)
+}
+
+func (this *OAuthClients) count(ctx *gin.Context) {
+	params := this.params(ctx)
+	count, _ := facade.DB.Model(&model.OAuthClient{}).IWhere(params["where"]).Count()
+	this.json(ctx, count, facade.Lang(ctx, "查询成功！"), 200)
+}
+
+func (this *OAuthClients) save(ctx *gin.Context) {
+	params := this.params(ctx)
+
+	if utils.Is.Empty(params["id"]) {
+		this.create(ctx)
+	} else {
+		this.update(ctx)
+	}
+}
+
+// create - 登记应用，客户端密钥仅在此时返回一次
+func (this *OAuthClients) create(ctx *gin.Context) {
+
+	params := this.params(ctx, map[string]any{
+		"scopes": "openid,profile,email",
+	})
+
+	if err := validator.NewValid("oauth-clients", params); err != nil {
+		this.json(ctx, nil, err.Error(), 400)
+		return
+	}
+
+	if !this.checkParams(ctx, params) {
+		return
+	}
+
+	table := model.OAuthClient{Status: 1}
+	for key, val := range params {
+		if utils.In.Array(key, oauthClientsAllowFieldsSlice) {
+			utils.Struct.Set(&table, key, val)
+		}
+	}
+	table.GenerateClientId()
+
+	secret, err := table.GenerateSecret()
+	if err != nil {
+		this.json(ctx, nil, err.Error(), 500)
+		return
+	}
+
+	if _, err := facade.DB.Model(&table).Create(&table); err != nil {
+		this.json(ctx, nil, err.Error(), 400)
+		return
+	}
+
+	this.json(ctx, gin.H{
+		"id":            table.Id,
+		"client_id":     table.ClientId,
+		"client_secret": secret,
+	}, facade.Lang(ctx, "创建成功！"), 200)
+}
+
+func (this *OAuthClients) update(ctx *gin.Context) {
+
+	params := this.params(ctx)
+
+	if utils.Is.Empty(params["id"]) {
+		this.json(ctx, nil, facade.Lang(ctx, "%s 不能为空！", "id"), 400)
+		return
+	}
+
+	if !this.checkParams(ctx, params) {
+		return
+	}
+
+	async := utils.Async[map[string]any]()
+	for key, val := range params {
+		if utils.In.Array(key, oauthClientsAllowFieldsSlice) {
+			async.Set(key, val)
+		}
+	}
+
+	table := model.OAuthClient{}
+	_, err := facade.DB.Model(&table).WithTrashed().Where("id", params["id"]).Scan(&table).Update(async.Result())
+	if err != nil {
+		this.json(ctx, nil, err.Error(), 400)
+		return
+	}
+
+	this.json(ctx, gin.H{"id": table.Id}, facade.Lang(ctx, "更新成功！"), 200)
+}
+
+// secret - 重置客户端密钥（旧密钥立即失效）
+func (this *OAuthClients) secret(ctx *gin.Context) {
+
+	params := this.params(ctx)
+
+	if utils.Is.Empty(params["id"]) {
+		this.json(ctx, nil, facade.Lang(ctx, "%s 不能为空！", "id"), 400)
+		return
+	}
+
+	table := model.OAuthClient{}
+	secret, err := table.GenerateSecret()
+	if err != nil {
+		this.json(ctx, nil, err.Error(), 500)
+		return
+	}
+
+	_, err = facade.DB.Model(&table).Where("id", params["id"]).Update(map[string]any{"secret_hash": table.SecretHash})
+	if err != nil {
+		this.json(ctx, nil, err.Error(), 400)
+		return
+	}
+
+	facade.Log.Info(map[string]any{"id": params["id"], "operator_id": this.user(ctx).Id}, "管理员重置第三方应用密钥")
+
+	this.json(ctx, gin.H{"id": params["id"], "client_secret": secret}, facade.Lang(ctx, "重置成功！"), 200)
+}
+
+// remove - 软删除（回收站），应用的令牌随之失效
+func (this *OAuthClients) remove(ctx *gin.Context) {
+
+	params := this.params(ctx)
+	ids := utils.Unity.Ids(params["ids"])
+
+	if utils.Is.Empty(ids) {
+		this.json(ctx, nil, facade.Lang(ctx, "%s 不能为空！", "ids"), 400)
+		return
+	}
+
+	query := facade.DB.Model(&model.OAuthClient{})
+	ids = utils.Unity.Ids(query.WhereIn("id", ids).Column("id"))
+
+	if utils.Is.Empty(ids) {
+		this.json(ctx, nil, facade.Lang(ctx, "无可操作数据！"), 204)
+		return
+	}
+
+	if _, err := query.Delete(ids); err != nil {
+		this.json(ctx, nil, facade.Lang(ctx, "删除失败！"), 400)
+		return
+	}
+
+	this.json(ctx, gin.H{"ids": ids}, facade.Lang(ctx, "删除成功！"), 200)
+}
+
+// delete - 彻底删除，同时清理用户授权记录与令牌
+func (this *OAuthClients) delete(ctx *gin.Context) {
+
+	params := this.params(ctx)
+	ids := utils.Unity.Ids(params["ids"])
+
+	if utils.Is.Empty(ids) {
+		this.json(ctx, nil, facade.Lang(ctx, "%s 不能为空！", "ids"), 400)
+		return
+	}
+
+	var clientIds []string
+	facade.DB.Drive().Unscoped().Model(&model.OAuthClient{}).Where("id IN ?", ids).Pluck("client_id", &clientIds)
+
+	query := facade.DB.Model(&model.OAuthClient{}).WithTrashed()
+	ids = utils.Unity.Ids(query.WhereIn("id", ids).Column("id"))
+
+	if utils.Is.Empty(ids) {
+		this.json(ctx, nil, facade.Lang(ctx, "无可操作数据！"), 204)
+		return
+	}
+
+	if _, err := query.Force().Delete(ids); err != nil {
+		this.json(ctx, nil, facade.Lang(ctx, "删除失败！"), 400)
+		return
+	}
+
+	if len(clientIds) > 0 {
+		go func() {
+			facade.DB.Drive().Where("client_id IN ?", clientIds).Delete(&model.OAuthConsent{})
+			facade.DB.Drive().Where("client_id IN ?", clientIds).Delete(&model.OAuthToken{})
+		}()
+	}
+
+	this.json(ctx, gin.H{"ids": ids}, facade.Lang(ctx, "删除成功！"), 200)
+}
+
+func (this *OAuthClients) restore(ctx *gin.Context) {
+
+	params := this.params(ctx)
+	ids := utils.Unity.Ids(params["ids"])
+
+	if utils.Is.Empty(ids) {
+		this.json(ctx, nil, facade.Lang(ctx, "%s 不能为空！", "ids"), 400)
+		return
+	}
+
+	ids = utils.Unity.Ids(facade.DB.Model(&model.OAuthClient{}).OnlyTrashed().WhereIn("id", ids).Column("id"))
+
+	if utils.Is.Empty(ids) {
+		this.json(ctx, nil, facade.Lang(ctx, "无可操作数据！"), 204)
+		return
+	}
+
+	if _, err := facade.DB.Model(&model.OAuthClient{}).OnlyTrashed().Restore(ids); err != nil {
+		this.json(ctx, nil, facade.Lang(ctx, "恢复失败！"), 400)
+		return
+	}
+
+	this.json(ctx, gin.H{"ids": ids}, facade.Lang(ctx, "恢复成功！"), 200)
+}
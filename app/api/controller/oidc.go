@@ -0,0 +1,616 @@
+package controller
+
+import (
+	"encoding/base64"
+	"inis/app/facade"
+	"inis/app/model"
+	"net/url"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/spf13/cast"
+	"github.com/unti-io/go-utils/utils"
+)
+
+// oidcChallengeRegexp - PKCE code_challenge 格式（S256 的 base64url 编码结果）
+var oidcChallengeRegexp = regexp.MustCompile(`^[A-Za-z0-9_-]{43}$`)
+
+// OIDC - inis 作为 OAuth2 / OpenID Connect 授权服务，供其他站点使用 inis 账号登录
+//
+// /api/oidc/* 供前端授权确认页使用；令牌、用户信息、吊销与发现端点为标准协议端点，见 route 包
+type OIDC struct {
+	base
+}
+
+// IGET - GET请求本体
+func (this *OIDC) IGET(ctx *gin.Context) {
+	// 转小写
+	method := strings.ToLower(ctx.Param("method"))
+
+	allow := map[string]any{
+		"authorize": this.authorize,
+		"grants":    this.grants,
+	}
+	err := this.call(allow, method, ctx)
+
+	if err != nil {
+		this.json(ctx, nil, facade.Lang(ctx, "方法调用错误：%v", err.Error()), 405)
+		return
+	}
+}
+
+// IPOST - POST请求本体
+func (this *OIDC) IPOST(ctx *gin.Context) {
+	// 转小写
+	method := strings.ToLower(ctx.Param("method"))
+
+	allow := map[string]any{
+		"authorize": this.approve,
+	}
+	err := this.call(allow, method, ctx)
+
+	if err != nil {
+		this.json(ctx, nil, facade.Lang(ctx, "方法调用错误：%v", err.Error()), 405)
+		return
+	}
+}
+
+// IPUT - PUT请求本体
+func (this *OIDC) IPUT(ctx *gin.Context) {
+	// 转小写
+	method := strings.ToLower(ctx.Param("method"))
+
+	allow := map[string]any{}
+	err := this.call(allow, method, ctx)
+
+	if err != nil {
+		this.json(ctx, nil, facade.Lang(ctx, "方法调用错误：%v", err.Error()), 405)
+		return
+	}
+}
+
+// IDEL - DELETE请求本体
+func (this *OIDC) IDEL(ctx *gin.Context) {
+	// 转小写
+	method := strings.ToLower(ctx.Param("method"))
+
+	allow := map[string]any{
+		"grant": this.revokeGrant,
+	}
+	err := this.call(allow, method, ctx)
+
+	if err != nil {
+		this.json(ctx, nil, facade.Lang(ctx, "方法调用错误：%v", err.Error()), 405)
+		return
+	}
+}
+
+// INDEX - GET请求本体
+func (this *OIDC) INDEX(ctx *gin.Context) {
+	this.json(ctx, nil, facade.Lang(ctx, "没什么用！"), 202)
+}
+
+// oidcIssuer - 签发者（配置为空时使用请求的协议与域名）
+func oidcIssuer(ctx *gin.Context) string {
+
+	if issuer := strings.TrimRight(cast.ToString(facade.AppToml.Get("oauth_server.issuer", "")), "/"); !utils.Is.Empty(issuer) {
+		return issuer
+	}
+
+	scheme := "http"
+	if ctx.Request.TLS != nil || strings.EqualFold(ctx.GetHeader("X-Forwarded-Proto"), "https") {
+		scheme = "https"
+	}
+
+	return scheme + "://" + ctx.Request.Host
+}
+
+// oidcRedirect - 拼接回调地址的查询参数（空值不拼接）
+func oidcRedirect(uri string, values map[string]string) string {
+
+	item, err := url.Parse(uri)
+	if err != nil {
+		return uri
+	}
+
+	query := item.Query()
+	for key, value := range values {
+		if !utils.Is.Empty(value) {
+			query.Set(key, value)
+		}
+	}
+	item.RawQuery = query.Encode()
+
+	return item.String()
+}
+
+// oidcRequest - 已校验的授权请求
+type oidcRequest struct {
+	client      *model.OAuthClient
+	redirectURI string
+	state       string
+	nonce       string
+	challenge   string
+	prompt      []string
+	scopes      []string
+	// 用户未登录（或登录时间超过 max_age）、需要用户确认授权
+	loginRequired   bool
+	consentRequired bool
+}
+
+// parseRequest - 校验授权请求；客户端或回调地址不合法时直接报错，其余错误带上可跳回应用的地址
+func (this *OIDC) parseRequest(ctx *gin.Context) (*oidcRequest, bool) {
+
+	params := this.params(ctx)
+
+	client := model.FindOAuthClient(cast.ToString(params["client_id"]))
+	if client == nil {
+		this.json(ctx, nil, facade.Lang(ctx, "应用不存在或已停用！"), 400)
+		return nil, false
+	}
+
+	request := &oidcRequest{
+		client:      client,
+		redirectURI: cast.ToString(params["redirect_uri"]),
+		state:       cast.ToString(params["state"]),
+		nonce:       cast.ToString(params["nonce"]),
+		challenge:   cast.ToString(params["code_challenge"]),
+		prompt:      strings.Fields(cast.ToString(params["prompt"])),
+	}
+
+	// 只登记了一个回调地址时允许省略
+	if utils.Is.Empty(request.redirectURI) {
+		if list := client.RedirectList(); len(list) == 1 {
+			request.redirectURI = list[0]
+		}
+	}
+	if !client.AllowRedirect(request.redirectURI) {
+		this.json(ctx, nil, facade.Lang(ctx, "回调地址未登记！"), 400)
+		return nil, false
+	}
+
+	fail := func(code, msg string) (*oidcRequest, bool) {
+		this.json(ctx, gin.H{
+			"redirect": oidcRedirect(request.redirectURI, map[string]string{
+				"error":             code,
+				"error_description": msg,
+				"state":             request.state,
+				"iss":               oidcIssuer(ctx),
+			}),
+		}, facade.Lang(ctx, msg), 400)
+		return nil, false
+	}
+
+	if cast.ToString(params["response_type"]) != "code" {
+		return fail("unsupported_response_type", "仅支持授权码模式（response_type=code）！")
+	}
+
+	scopes, err := client.GrantScopes(cast.ToString(params["scope"]))
+	if err != nil || len(scopes) == 0 {
+		return fail("invalid_scope", "申请的权限范围不被允许！")
+	}
+	request.scopes = scopes
+
+	if !utils.Is.Empty(request.challenge) {
+		if cast.ToString(params["code_challenge_method"]) != "S256" || !oidcChallengeRegexp.MatchString(request.challenge) {
+			return fail("invalid_request", "code_challenge 仅支持 S256！")
+		}
+	} else if client.Public == 1 {
+		return fail("invalid_request", "公开客户端必须使用 PKCE！")
+	}
+
+	user := this.user(ctx)
+	if user.Id == 0 {
+		request.loginRequired = true
+	} else if maxAge, ok := params["max_age"]; ok && time.Now().Unix()-user.LoginTime > cast.ToInt64(maxAge) {
+		request.loginRequired = true
+	}
+
+	request.consentRequired = utils.InArray("consent", request.prompt) ||
+		(client.Trusted != 1 && (user.Id == 0 || !model.OAuthConsented(user.Id, client.ClientId, scopes)))
+
+	// prompt=none 要求静默完成，不能展示任何页面
+	if utils.InArray("none", request.prompt) {
+		if request.loginRequired {
+			return fail("login_required", "请先登录！")
+		}
+		if request.consentRequired {
+			return fail("consent_required", "需要用户确认授权！")
+		}
+	}
+
+	return request, true
+}
+
+// authorize - 授权确认页数据：应用信息、申请的权限与是否需要用户确认
+func (this *OIDC) authorize(ctx *gin.Context) {
+
+	request, ok := this.parseRequest(ctx)
+	if !ok {
+		return
+	}
+
+	if request.loginRequired {
+		this.json(ctx, nil, facade.Lang(ctx, "请先登录！"), 401)
+		return
+	}
+
+	scopes := []gin.H{}
+	for _, scope := range request.scopes {
+		scopes = append(scopes, gin.H{"scope": scope, "description": model.OAuthScopes[scope]})
+	}
+
+	user := this.user(ctx)
+
+	this.json(ctx, gin.H{
+		"client": gin.H{
+			"client_id":   request.client.ClientId,
+			"name":        request.client.Name,
+			"logo":        request.client.Logo,
+			"homepage":    request.client.Homepage,
+			"description": request.client.Description,
+		},
+		"user": gin.H{
+			"id":       user.Id,
+			"account":  user.Account,
+			"nickname": user.Nickname,
+			"avatar":   user.Avatar,
+		},
+		"scopes":           scopes,
+		"redirect_uri":     request.redirectURI,
+		"consent_required": request.consentRequired,
+	}, facade.Lang(ctx, "数据请求成功！"), 200)
+}
+
+// approve - 用户同意或拒绝授权，返回带授权码（或错误）的回调地址
+func (this *OIDC) approve(ctx *gin.Context) {
+
+	request, ok := this.parseRequest(ctx)
+	if !ok {
+		return
+	}
+
+	if request.loginRequired {
+		this.json(ctx, nil, facade.Lang(ctx, "请先登录！"), 401)
+		return
+	}
+
+	issuer := oidcIssuer(ctx)
+	user := this.user(ctx)
+
+	if !cast.ToBool(this.params(ctx)["approve"]) {
+		this.json(ctx, gin.H{
+			"redirect": oidcRedirect(request.redirectURI, map[string]string{
+				"error":             "access_denied",
+				"error_description": "用户拒绝授权",
+				"state":             request.state,
+				"iss":               issuer,
+			}),
+		}, facade.Lang(ctx, "已拒绝授权！"), 200)
+		return
+	}
+
+	if request.client.Trusted != 1 {
+		if err := model.SaveOAuthConsent(user.Id, request.client.ClientId, request.scopes); err != nil {
+			this.json(ctx, nil, err.Error(), 500)
+			return
+		}
+	}
+
+	code, err := model.CreateOAuthCode(&model.OAuthGrant{
+		ClientId:    request.client.ClientId,
+		RedirectURI: request.redirectURI,
+		Uid:         user.Id,
+		Scopes:      request.scopes,
+		Challenge:   request.challenge,
+		Nonce:       request.nonce,
+		AuthTime:    user.LoginTime,
+	})
+	if err != nil {
+		this.json(ctx, nil, err.Error(), 500)
+		return
+	}
+
+	this.json(ctx, gin.H{
+		"redirect": oidcRedirect(request.redirectURI, map[string]string{
+			"code":  code,
+			"state": request.state,
+			"iss":   issuer,
+		}),
+	}, facade.Lang(ctx, "授权成功！"), 200)
+}
+
+// grants - 当前用户已授权的应用
+func (this *OIDC) grants(ctx *gin.Context) {
+
+	uid := this.user(ctx).Id
+	if uid == 0 {
+		this.json(ctx, nil, facade.Lang(ctx, "请先登录！"), 401)
+		return
+	}
+
+	var consents []model.OAuthConsent
+	facade.DB.Drive().Where("uid = ?", uid).Order("update_time desc").Find(&consents)
+
+	if len(consents) == 0 {
+		this.json(ctx, gin.H{"data": []any{}, "count": 0}, facade.Lang(ctx, "无数据！"), 204)
+		return
+	}
+
+	ids := make([]string, 0, len(consents))
+	for _, item := range consents {
+		ids = append(ids, item.ClientId)
+	}
+
+	var clients []model.OAuthClient
+	facade.DB.Drive().Where("client_id IN ?", ids).Find(&clients)
+	named := map[string]model.OAuthClient{}
+	for _, item := range clients {
+		named[item.ClientId] = item
+	}
+
+	result := []gin.H{}
+	for _, item := range consents {
+		client, ok := named[item.ClientId]
+		if !ok {
+			continue
+		}
+		result = append(result, gin.H{
+			"client_id":   item.ClientId,
+			"name":        client.Name,
+			"logo":        client.Logo,
+			"homepage":    client.Homepage,
+			"scopes":      strings.Fields(item.Scope),
+			"create_time": item.CreateTime,
+			"update_time": item.UpdateTime,
+		})
+	}
+
+	this.json(ctx, gin.H{"data": result, "count": len(result)}, facade.Lang(ctx, "数据请求成功！"), 200)
+}
+
+// revokeGrant - 取消对应用的授权并吊销其全部令牌
+func (this *OIDC) revokeGrant(ctx *gin.Context) {
+
+	params := this.params(ctx)
+
+	uid := this.user(ctx).Id
+	if uid == 0 {
+		this.json(ctx, nil, facade.Lang(ctx, "请先登录！"), 401)
+		return
+	}
+
+	clientId := cast.ToString(params["client_id"])
+	if utils.Is.Empty(clientId) {
+		this.json(ctx, nil, facade.Lang(ctx, "%s 不能为空！", "client_id"), 400)
+		return
+	}
+
+	if _, err := model.DeleteOAuthConsent(uid, clientId); err != nil {
+		this.json(ctx, nil, err.Error(), 400)
+		return
+	}
+
+	facade.Log.Info(map[string]any{"uid": uid, "client_id": clientId}, "用户取消第三方应用授权")
+
+	this.json(ctx, gin.H{"client_id": clientId}, facade.Lang(ctx, "已取消授权！"), 200)
+}
+
+// oauthError - 协议端点的标准错误响应（RFC 6749 5.2）
+func (this *OIDC) oauthError(ctx *gin.Context, status int, code, msg string) {
+	ctx.Header("Cache-Control", "no-store")
+	ctx.JSON(status, gin.H{
+		"error":             code,
+		"error_description": facade.Lang(ctx, msg),
+	})
+}
+
+// client - 客户端认证（client_secret_basic、client_secret_post，公开客户端为 none）
+func (this *OIDC) client(ctx *gin.Context) (*model.OAuthClient, bool) {
+
+	params := this.params(ctx)
+	clientId, secret := cast.ToString(params["client_id"]), cast.ToString(params["client_secret"])
+
+	if header := ctx.GetHeader("Authorization"); strings.HasPrefix(header, "Basic ") {
+		raw, err := base64.StdEncoding.DecodeString(strings.TrimPrefix(header, "Basic "))
+		if id, pass, ok := strings.Cut(string(raw), ":"); err == nil && ok {
+			// RFC 6749 2.3.1：凭证在放入 Basic 认证前经过 form 编码
+			clientId, _ = url.QueryUnescape(id)
+			secret, _ = url.QueryUnescape(pass)
+		}
+	}
+
+	client := model.FindOAuthClient(clientId)
+	if client == nil || (client.Public != 1 && !client.CheckSecret(secret)) {
+		ctx.Header("WWW-Authenticate", `Basic realm="inis"`)
+		this.oauthError(ctx, 401, "invalid_client", "客户端认证失败！")
+		return nil, false
+	}
+
+	return client, true
+}
+
+// Discovery - OpenID Connect 发现文档（/.well-known/openid-configuration）
+func (this *OIDC) Discovery(ctx *gin.Context) {
+
+	issuer := oidcIssuer(ctx)
+
+	authorize := strings.TrimSpace(cast.ToString(facade.AppToml.Get("oauth_server.authorize_page", "")))
+	if utils.Is.Empty(authorize) {
+		authorize = issuer + "/oauth/authorize"
+	}
+
+	scopes := make([]string, 0, len(model.OAuthScopes))
+	for scope := range model.OAuthScopes {
+		scopes = append(scopes, scope)
+	}
+
+	auth := []string{"client_secret_basic", "client_secret_post", "none"}
+
+	ctx.JSON(200, gin.H{
+		"issuer":                                         issuer,
+		"authorization_endpoint":                         authorize,
+		"token_endpoint":                                 issuer + "/oauth2/token",
+		"userinfo_endpoint":                              issuer + "/oauth2/userinfo",
+		"revocation_endpoint":                            issuer + "/oauth2/revoke",
+		"jwks_uri":                                       issuer + "/.well-known/jwks.json",
+		"response_types_supported":                       []string{"code"},
+		"response_modes_supported":                       []string{"query"},
+		"grant_types_supported":                          []string{"authorization_code", "refresh_token"},
+		"subject_types_supported":                        []string{"public"},
+		"id_token_signing_alg_values_supported":          []string{facade.OIDCSigningAlg},
+		"scopes_supported":                               scopes,
+		"token_endpoint_auth_methods_supported":          auth,
+		"revocation_endpoint_auth_methods_supported":     auth,
+		"code_challenge_methods_supported":               []string{"S256"},
+		"prompt_values_supported":                        []string{"none", "consent"},
+		"authorization_response_iss_parameter_supported": true,
+		"claims_supported": []string{
+			"sub", "iss", "aud", "exp", "iat", "auth_time", "nonce", "azp",
+			"name", "nickname", "preferred_username", "picture", "gender", "locale", "updated_at",
+			"email", "email_verified", "phone_number", "phone_number_verified",
+		},
+	})
+}
+
+// JWKS - 身份令牌与 JWT 的签名公钥（/.well-known/jwks.json）
+func (this *OIDC) JWKS(ctx *gin.Context) {
+	// 缓存时间不宜过长，轮换后第三方需尽快获取新公钥
+	ctx.Header("Cache-Control", "public, max-age=600")
+	ctx.JSON(200, facade.JWKS())
+}
+
+// Token - 令牌端点：授权码或刷新令牌换取访问令牌（/oauth2/token）
+func (this *OIDC) Token(ctx *gin.Context) {
+
+	client, ok := this.client(ctx)
+	if !ok {
+		return
+	}
+
+	params := this.params(ctx)
+
+	var (
+		token   *model.OAuthToken
+		access  string
+		refresh string
+		nonce   string
+		err     error
+	)
+
+	switch cast.ToString(params["grant_type"]) {
+	case "authorization_code":
+
+		grant, fail := model.ConsumeOAuthCode(cast.ToString(params["code"]))
+		if fail != nil || grant.ClientId != client.ClientId || grant.RedirectURI != cast.ToString(params["redirect_uri"]) {
+			this.oauthError(ctx, 400, "invalid_grant", model.ErrOAuthCode.Error())
+			return
+		}
+		if err := grant.CheckVerifier(cast.ToString(params["code_verifier"])); err != nil {
+			this.oauthError(ctx, 400, "invalid_grant", err.Error())
+			return
+		}
+		if _, err := model.OAuthUser(grant.Uid); err != nil {
+			this.oauthError(ctx, 400, "invalid_grant", err.Error())
+			return
+		}
+
+		nonce = grant.Nonce
+		token, access, refresh, err = model.IssueOAuthToken(client.ClientId, grant.Uid, grant.Scopes, grant.AuthTime)
+
+	case "refresh_token":
+
+		token, access, refresh, err = model.RefreshOAuthToken(client.ClientId, cast.ToString(params["refresh_token"]), strings.Fields(cast.ToString(params["scope"])))
+		if err != nil {
+			this.oauthError(ctx, 400, utils.Ternary(err == model.ErrOAuthScope, "invalid_scope", "invalid_grant"), err.Error())
+			return
+		}
+
+	default:
+		this.oauthError(ctx, 400, "unsupported_grant_type", "仅支持 authorization_code 与 refresh_token！")
+		return
+	}
+
+	if err != nil {
+		this.oauthError(ctx, 500, "server_error", err.Error())
+		return
+	}
+
+	result := gin.H{
+		"access_token": access,
+		"token_type":   "Bearer",
+		"expires_in":   token.AccessExpire - time.Now().Unix(),
+		"scope":        token.Scope,
+	}
+	if !utils.Is.Empty(refresh) {
+		result["refresh_token"] = refresh
+	}
+
+	if utils.InArray(model.OAuthScopeOpenid, token.Scopes()) {
+		user, err := model.OAuthUser(token.Uid)
+		if err != nil {
+			this.oauthError(ctx, 400, "invalid_grant", err.Error())
+			return
+		}
+		idToken, err := model.OAuthIDToken(oidcIssuer(ctx), client.ClientId, user, token.Scopes(), nonce, token.AuthTime)
+		if err != nil {
+			this.oauthError(ctx, 500, "server_error", err.Error())
+			return
+		}
+		result["id_token"] = idToken
+	}
+
+	ctx.Header("Cache-Control", "no-store")
+	ctx.Header("Pragma", "no-cache")
+	ctx.JSON(200, result)
+}
+
+// UserInfo - 用户信息端点，按访问令牌的权限范围返回声明（/oauth2/userinfo）
+func (this *OIDC) UserInfo(ctx *gin.Context) {
+
+	access := cast.ToString(this.params(ctx)["access_token"])
+	if header := ctx.GetHeader("Authorization"); len(header) > 7 && strings.EqualFold(header[:7], "Bearer ") {
+		access = strings.TrimSpace(header[7:])
+	}
+
+	token, err := model.FindOAuthAccessToken(access)
+	if err != nil {
+		ctx.Header("WWW-Authenticate", `Bearer error="invalid_token"`)
+		this.oauthError(ctx, 401, "invalid_token", err.Error())
+		return
+	}
+
+	if !utils.InArray(model.OAuthScopeOpenid, token.Scopes()) {
+		ctx.Header("WWW-Authenticate", `Bearer error="insufficient_scope", scope="openid"`)
+		this.oauthError(ctx, 403, "insufficient_scope", "访问令牌未包含 openid 权限！")
+		return
+	}
+
+	user, err := model.OAuthUser(token.Uid)
+	if err != nil {
+		ctx.Header("WWW-Authenticate", `Bearer error="invalid_token"`)
+		this.oauthError(ctx, 401, "invalid_token", err.Error())
+		return
+	}
+
+	ctx.Header("Cache-Control", "no-store")
+	ctx.JSON(200, model.OAuthClaims(user, token.Scopes()))
+}
+
+// Revoke - 令牌吊销端点（RFC 7009，令牌无效时同样返回成功）（/oauth2/revoke）
+func (this *OIDC) Revoke(ctx *gin.Context) {
+
+	client, ok := this.client(ctx)
+	if !ok {
+		return
+	}
+
+	if err := model.RevokeOAuthToken(client.ClientId, cast.ToString(this.params(ctx)["token"])); err != nil {
+		this.oauthError(ctx, 503, "temporarily_unavailable", err.Error())
+		return
+	}
+
+	ctx.Status(200)
+}
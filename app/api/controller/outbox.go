@@ -0,0 +1,254 @@
+package controller
+
+import (
+	"inis/app/facade"
+	"inis/app/model"
+	"math"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/spf13/cast"
+	"github.com/unti-io/go-utils/utils"
+)
+
+// Outbox - 邮件/短信发件箱（查看待发送与死信消息）
+type Outbox struct {
+	base
+}
+
+func (this *Outbox) IGET(ctx *gin.Context) {
+	method := strings.ToLower(ctx.Param("method"))
+
+	allow := map[string]any{
+		"one":   this.one,
+		"all":   this.all,
+		"count": this.count,
+		"stats": this.stats,
+		"kinds": this.kinds,
+	}
+	err := this.call(allow, method, ctx)
+
+	if err != nil {
+		this.json(ctx, nil, facade.Lang(ctx, "方法调用错误：%v", err.Error()), 405)
+		return
+	}
+}
+
+func (this *Outbox) IPOST(ctx *gin.Context) {
+	method := strings.ToLower(ctx.Param("method"))
+
+	allow := map[string]any{}
+	err := this.call(allow, method, ctx)
+
+	if err != nil {
+		this.json(ctx, nil, facade.Lang(ctx, "方法调用错误：%v", err.Error()), 405)
+		return
+	}
+}
+
+func (this *Outbox) IPUT(ctx *gin.Context) {
+	method := strings.ToLower(ctx.Param("method"))
+
+	allow := map[string]any{
+		"retry": this.retry,
+	}
+	err := this.call(allow, method, ctx)
+
+	if err != nil {
+		this.json(ctx, nil, facade.Lang(ctx, "方法调用错误：%v", err.Error()), 405)
+		return
+	}
+}
+
+func (this *Outbox) IDEL(ctx *gin.Context) {
+	method := strings.ToLower(ctx.Param("method"))
+
+	allow := map[string]any{
+		"delete": this.delete,
+		"clear":  this.clear,
+	}
+	err := this.call(allow, method, ctx)
+
+	if err != nil {
+		this.json(ctx, nil, facade.Lang(ctx, "方法调用错误：%v", err.Error()), 405)
+		return
+	}
+}
+
+func (this *Outbox) INDEX(ctx *gin.Context) {
+	this.json(ctx, nil, facade.Lang(ctx, "没什么用！"), 202)
+}
+
+// maskPayload - 隐藏验证码与收件人，避免在后台泄露
+func (this *Outbox) maskPayload(item map[string]any) map[string]any {
+
+	if cast.ToString(item["kind"]) == model.OutboxKindVerifyCode {
+		item["payload"] = ""
+	}
+
+	recipient := cast.ToString(item["recipient"])
+	if utils.Is.Email(recipient) {
+		item["recipient"] = facade.Comm.MaskEmail(recipient)
+	} else if len(recipient) > 7 {
+		item["recipient"] = recipient[:3] + strings.Repeat("*", len(recipient)-7) + recipient[len(recipient)-4:]
+	}
+
+	return item
+}
+
+// kinds - 消息类型列表
+func (this *Outbox) kinds(ctx *gin.Context) {
+	this.json(ctx, model.OutboxKinds, facade.Lang(ctx, "数据请求成功！"), 200)
+}
+
+func (this *Outbox) one(ctx *gin.Context) {
+
+	params := this.params(ctx)
+
+	if utils.Is.Empty(params["id"]) {
+		this.json(ctx, nil, facade.Lang(ctx, "%s 不能为空！", "id"), 400)
+		return
+	}
+
+	item, _ := facade.DB.Model(&model.Outbox{}).Where("id", params["id"]).Find()
+	if utils.Is.Empty(item) {
+		this.json(ctx, nil, facade.Lang(ctx, "无数据！"), 204)
+		return
+	}
+
+	this.json(ctx, facade.Comm.WithField(this.maskPayload(item), params["field"]), facade.Lang(ctx, "数据请求成功！"), 200)
+}
+
+func (this *Outbox) all(ctx *gin.Context) {
+
+	params := this.params(ctx, map[string]any{
+		"page":  1,
+		"order": "id desc",
+	})
+
+	page := cast.ToInt(params["page"])
+	limit := this.meta.limit(ctx)
+
+	query := facade.DB.Model(&[]model.Outbox{})
+	for _, key := range []string{"status", "kind", "drive"} {
+		if !utils.Is.Empty(params[key]) {
+			query = query.Where(key, params[key])
+		}
+	}
+	query = query.IWhere(params["where"])
+
+	count, _ := query.Count()
+	items, _ := query.Limit(limit).Page(page).Order(params["order"]).Select()
+
+	if utils.Is.Empty(items) {
+		this.json(ctx, gin.H{"data": []any{}, "count": 0, "page": 0}, facade.Lang(ctx, "无数据！"), 204)
+		return
+	}
+
+	for _, item := range items {
+		this.maskPayload(item)
+	}
+
+	this.json(ctx, gin.H{
+		"data":  utils.ArrayMapWithField(items, params["field"]),
+		"count": count,
+		"page":  math.Ceil(float64(count) / float64(limit)),
+	}, facade.Lang(ctx, "数据请求成功！"), 200)
+}
+
+func (this *Outbox) count(ctx *gin.Context) {
+	params := this.params(ctx)
+	count, _ := facade.DB.Model(&model.Outbox{}).IWhere(params["where"]).Count()
+	this.json(ctx, count, facade.Lang(ctx, "查询成功！"), 200)
+}
+
+// stats - 各状态的消息数量
+func (this *Outbox) stats(ctx *gin.Context) {
+
+	result := make(map[string]int64)
+	for _, status := range []string{model.OutboxStatusPending, model.OutboxStatusSuccess, model.OutboxStatusDead} {
+		count, _ := facade.DB.Model(&model.Outbox{}).Where("status", status).Count()
+		result[status] = count
+	}
+
+	this.json(ctx, result, facade.Lang(ctx, "数据请求成功！"), 200)
+}
+
+// retry - 将死信重新加入队列
+func (this *Outbox) retry(ctx *gin.Context) {
+
+	params := this.params(ctx)
+	ids := utils.Unity.Ids(params["ids"])
+
+	if utils.Is.Empty(ids) {
+		this.json(ctx, nil, facade.Lang(ctx, "%s 不能为空！", "ids"), 400)
+		return
+	}
+
+	var list []model.Outbox
+	facade.DB.Drive().Where("id IN ?", ids).Find(&list)
+
+	success := make([]int, 0)
+	failed := make(map[int]string)
+	for _, item := range list {
+		if err := item.Requeue(); err != nil {
+			failed[item.Id] = err.Error()
+			continue
+		}
+		success = append(success, item.Id)
+	}
+
+	if utils.Is.Empty(success) {
+		this.json(ctx, gin.H{"failed": failed}, facade.Lang(ctx, "无可操作数据！"), 400)
+		return
+	}
+
+	this.json(ctx, gin.H{"ids": success, "failed": failed}, facade.Lang(ctx, "已重新加入发送队列！"), 200)
+}
+
+func (this *Outbox) delete(ctx *gin.Context) {
+
+	params := this.params(ctx)
+	ids := utils.Unity.Ids(params["ids"])
+
+	if utils.Is.Empty(ids) {
+		this.json(ctx, nil, facade.Lang(ctx, "%s 不能为空！", "ids"), 400)
+		return
+	}
+
+	query := facade.DB.Model(&model.Outbox{})
+	ids = utils.Unity.Ids(query.WhereIn("id", ids).Column("id"))
+
+	if utils.Is.Empty(ids) {
+		this.json(ctx, nil, facade.Lang(ctx, "无可操作数据！"), 204)
+		return
+	}
+
+	if _, err := query.Force().Delete(ids); err != nil {
+		this.json(ctx, nil, facade.Lang(ctx, "删除失败！"), 400)
+		return
+	}
+
+	this.json(ctx, gin.H{"ids": ids}, facade.Lang(ctx, "删除成功！"), 200)
+}
+
+// clear - 清空指定状态的消息（默认清空已发送成功的消息）
+func (this *Outbox) clear(ctx *gin.Context) {
+
+	params := this.params(ctx, map[string]any{
+		"status": model.OutboxStatusSuccess,
+	})
+
+	status := cast.ToString(params["status"])
+	if status != model.OutboxStatusSuccess && status != model.OutboxStatusDead {
+		this.json(ctx, nil, facade.Lang(ctx, "只能清空已发送或死信消息！"), 400)
+		return
+	}
+
+	if _, err := facade.DB.Model(&model.Outbox{}).Where("status", status).Force().Delete(); err != nil {
+		this.json(ctx, nil, facade.Lang(ctx, "清空失败！"), 400)
+		return
+	}
+
+	this.json(ctx, nil, facade.Lang(ctx, "清空成功！"), 200)
+}
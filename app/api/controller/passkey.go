@@ -0,0 +1,270 @@
+package controller
+
+import (
+	"inis/app/facade"
+	"inis/app/model"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/spf13/cast"
+	"github.com/unti-io/go-utils/utils"
+)
+
+// Passkey - 通行密钥（WebAuthn）管理
+type Passkey struct {
+	base
+}
+
+func (this *Passkey) IGET(ctx *gin.Context) {
+	method := strings.ToLower(ctx.Param("method"))
+
+	allow := map[string]any{
+		"all": this.all,
+	}
+	err := this.call(allow, method, ctx)
+
+	if err != nil {
+		this.json(ctx, nil, facade.Lang(ctx, "方法调用错误：%v", err.Error()), 405)
+		return
+	}
+}
+
+func (this *Passkey) IPOST(ctx *gin.Context) {
+	method := strings.ToLower(ctx.Param("method"))
+
+	allow := map[string]any{
+		"register-options": this.registerOptions,
+		"register":         this.register,
+	}
+	err := this.call(allow, method, ctx)
+
+	if err != nil {
+		this.json(ctx, nil, facade.Lang(ctx, "方法调用错误：%v", err.Error()), 405)
+		return
+	}
+}
+
+func (this *Passkey) IPUT(ctx *gin.Context) {
+	method := strings.ToLower(ctx.Param("method"))
+
+	allow := map[string]any{
+		"rename": this.rename,
+	}
+	err := this.call(allow, method, ctx)
+
+	if err != nil {
+		this.json(ctx, nil, facade.Lang(ctx, "方法调用错误：%v", err.Error()), 405)
+		return
+	}
+}
+
+func (this *Passkey) IDEL(ctx *gin.Context) {
+	method := strings.ToLower(ctx.Param("method"))
+
+	allow := map[string]any{
+		"remove": this.remove,
+		"reset":  this.reset,
+	}
+	err := this.call(allow, method, ctx)
+
+	if err != nil {
+		this.json(ctx, nil, facade.Lang(ctx, "方法调用错误：%v", err.Error()), 405)
+		return
+	}
+}
+
+func (this *Passkey) INDEX(ctx *gin.Context) {
+	this.json(ctx, nil, facade.Lang(ctx, "没什么用！"), 202)
+}
+
+// all - 当前用户已绑定的通行密钥
+func (this *Passkey) all(ctx *gin.Context) {
+
+	uid := this.user(ctx).Id
+	if uid == 0 {
+		this.json(ctx, nil, facade.Lang(ctx, "请先登录！"), 401)
+		return
+	}
+
+	var items []model.UserPasskey
+	facade.DB.Drive().Where("uid = ?", uid).Order("id desc").Find(&items)
+
+	if len(items) == 0 {
+		this.json(ctx, gin.H{"data": []any{}, "count": 0}, facade.Lang(ctx, "无数据！"), 204)
+		return
+	}
+
+	this.json(ctx, gin.H{"data": items, "count": len(items)}, facade.Lang(ctx, "数据请求成功！"), 200)
+}
+
+// registerOptions - 获取绑定通行密钥的仪式参数（需验证密码）
+func (this *Passkey) registerOptions(ctx *gin.Context) {
+
+	params := this.params(ctx)
+	user := this.user(ctx)
+
+	if user.Id == 0 {
+		this.json(ctx, nil, facade.Lang(ctx, "请先登录！"), 401)
+		return
+	}
+
+	if !this.checkPassword(ctx, user.Id, params["password"]) {
+		return
+	}
+
+	rp, err := model.NewWebAuthnRP()
+	if err != nil {
+		this.json(ctx, nil, facade.Lang(ctx, err.Error()), 500)
+		return
+	}
+
+	options, err := rp.RegisterOptions(user)
+	if err != nil {
+		this.json(ctx, nil, err.Error(), 500)
+		return
+	}
+
+	this.json(ctx, options, facade.Lang(ctx, "数据请求成功！"), 200)
+}
+
+// register - 校验认证器返回的凭证并绑定
+func (this *Passkey) register(ctx *gin.Context) {
+
+	params := this.params(ctx)
+
+	uid := this.user(ctx).Id
+	if uid == 0 {
+		this.json(ctx, nil, facade.Lang(ctx, "请先登录！"), 401)
+		return
+	}
+
+	credential := cast.ToStringMap(params["credential"])
+	if utils.Is.Empty(credential) {
+		this.json(ctx, nil, facade.Lang(ctx, "%s 不能为空！", "credential"), 400)
+		return
+	}
+
+	rp, err := model.NewWebAuthnRP()
+	if err != nil {
+		this.json(ctx, nil, facade.Lang(ctx, err.Error()), 500)
+		return
+	}
+
+	item, err := rp.Register(uid, cast.ToString(params["name"]), credential)
+	if err != nil {
+		this.json(ctx, nil, facade.Lang(ctx, err.Error()), 400)
+		return
+	}
+
+	facade.Log.Info(map[string]any{"uid": uid, "id": item.Id, "aaguid": item.Aaguid}, "用户绑定通行密钥")
+
+	this.json(ctx, item, facade.Lang(ctx, "绑定成功！"), 200)
+}
+
+// rename - 重命名通行密钥
+func (this *Passkey) rename(ctx *gin.Context) {
+
+	params := this.params(ctx)
+
+	uid := this.user(ctx).Id
+	if uid == 0 {
+		this.json(ctx, nil, facade.Lang(ctx, "请先登录！"), 401)
+		return
+	}
+
+	if utils.Is.Empty(params["id"]) {
+		this.json(ctx, nil, facade.Lang(ctx, "%s 不能为空！", "id"), 400)
+		return
+	}
+
+	name := strings.TrimSpace(cast.ToString(params["name"]))
+	if utils.Is.Empty(name) || len([]rune(name)) > 32 {
+		this.json(ctx, nil, facade.Lang(ctx, "名称不能为空且不能超过32个字符！"), 400)
+		return
+	}
+
+	result := facade.DB.Drive().Model(&model.UserPasskey{}).
+		Where("id = ? AND uid = ?", params["id"], uid).Update("name", name)
+	if result.Error != nil {
+		this.json(ctx, nil, result.Error.Error(), 400)
+		return
+	}
+	if result.RowsAffected == 0 {
+		this.json(ctx, nil, facade.Lang(ctx, "无数据！"), 204)
+		return
+	}
+
+	this.json(ctx, gin.H{"id": params["id"], "name": name}, facade.Lang(ctx, "修改成功！"), 200)
+}
+
+// remove - 删除通行密钥（需验证密码）
+func (this *Passkey) remove(ctx *gin.Context) {
+
+	params := this.params(ctx)
+
+	uid := this.user(ctx).Id
+	if uid == 0 {
+		this.json(ctx, nil, facade.Lang(ctx, "请先登录！"), 401)
+		return
+	}
+
+	if utils.Is.Empty(params["id"]) {
+		this.json(ctx, nil, facade.Lang(ctx, "%s 不能为空！", "id"), 400)
+		return
+	}
+
+	if !this.checkPassword(ctx, uid, params["password"]) {
+		return
+	}
+
+	// 被要求开启两步验证且未开启 TOTP 时，不能删除最后一个通行密钥
+	if model.TotpRequired(uid) && !model.TotpEnabled(uid) {
+		var count int64
+		facade.DB.Drive().Model(&model.UserPasskey{}).Where("uid = ?", uid).Count(&count)
+		if count <= 1 {
+			this.json(ctx, nil, facade.Lang(ctx, "管理员要求开启两步验证，请先开启身份验证器App验证！"), 403)
+			return
+		}
+	}
+
+	result := facade.DB.Drive().Where("id = ? AND uid = ?", params["id"], uid).Delete(&model.UserPasskey{})
+	if result.Error != nil {
+		this.json(ctx, nil, result.Error.Error(), 400)
+		return
+	}
+	if result.RowsAffected == 0 {
+		this.json(ctx, nil, facade.Lang(ctx, "无数据！"), 204)
+		return
+	}
+
+	facade.Log.Info(map[string]any{"uid": uid, "id": params["id"]}, "用户删除通行密钥")
+
+	this.json(ctx, gin.H{"id": params["id"]}, facade.Lang(ctx, "删除成功！"), 200)
+}
+
+// reset - 管理员清空指定用户的通行密钥（用户丢失认证器时）
+func (this *Passkey) reset(ctx *gin.Context) {
+
+	params := this.params(ctx)
+
+	if !this.meta.root(ctx) {
+		this.json(ctx, nil, facade.Lang(ctx, "无权限！"), 403)
+		return
+	}
+
+	uid := cast.ToInt(params["uid"])
+	if uid <= 0 {
+		this.json(ctx, nil, facade.Lang(ctx, "%s 不能为空！", "uid"), 400)
+		return
+	}
+
+	count, err := model.DeleteUserPasskeys(uid)
+	if err != nil {
+		this.json(ctx, nil, err.Error(), 400)
+		return
+	}
+
+	facade.Log.Info(map[string]any{"uid": uid, "operator_id": this.user(ctx).Id, "count": count}, "管理员清空用户通行密钥")
+
+	this.json(ctx, gin.H{"uid": uid, "count": count}, facade.Lang(ctx, "重置成功！"), 200)
+}
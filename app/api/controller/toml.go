@@ -2,10 +2,14 @@ package controller
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"inis/app/facade"
+	"inis/app/model"
 	"net/http"
 	"net/url"
+	"path/filepath"
 	"regexp"
 	"strings"
 	"time"
@@ -54,17 +58,6 @@ func (this *Toml) replaceTomlVars(temp string, tomlData map[string]any) string {
 	return temp
 }
 
-// 敏感字段名集合（密钥、密码等，返回给前端时需脱敏）
-var sensitiveTomlFields = map[string]bool{
-	"password":          true, // mysql/redis/email 密码
-	"access_key_secret": true, // 阿里云 AccessKey Secret
-	"secret_key":        true, // 腾讯云/七牛云 SecretKey
-	"access_key":        true, // 七牛云 AccessKey
-	"access_key_id":     true, // AccessKey ID
-	"secret_id":         true, // 腾讯云 SecretId
-	"key":               true, // JWT 签名密钥（仅出现在 crypt 配置的 jwt 段）
-}
-
 // restoreSecretParams 还原脱敏占位值：若前端回传的是脱敏后的密钥（含 **** 或全 *），
 // 则用当前配置中的原值替换，避免把脱敏值写回配置导致密钥损坏
 func (this *Toml) restoreSecretParams(params map[string]any, keys []string, current map[string]any) {
@@ -83,24 +76,7 @@ func (this *Toml) restoreSecretParams(params map[string]any, keys []string, curr
 
 // maskSensitiveFields 递归脱敏 map/slice 中的敏感字段
 func (this *Toml) maskSensitiveFields(data any) any {
-	switch val := data.(type) {
-	case map[string]any:
-		for key, value := range val {
-			if sensitiveTomlFields[strings.ToLower(key)] {
-				val[key] = facade.Comm.MaskSecret(cast.ToString(value))
-			} else {
-				val[key] = this.maskSensitiveFields(value)
-			}
-		}
-		return val
-	case []any:
-		for i, value := range val {
-			val[i] = this.maskSensitiveFields(value)
-		}
-		return val
-	default:
-		return data
-	}
+	return facade.Comm.MaskSensitive(data)
 }
 
 // getNestedValue 从嵌套 map 中获取值，支持 "file.path" 这样的路径
@@ -231,17 +207,44 @@ func (this *Toml) storageConfigToReplaceMap() map[string]any {
 		}
 	}
 
+	// 扫描配置 - 旧配置文件中可能不存在该段，使用默认值兜底，避免写回未替换的占位符
+	result["${scanner.enabled}"] = cast.ToBool(facade.StorageToml.Get("scanner.enabled", false))
+	result["${scanner.network}"] = cast.ToString(facade.StorageToml.Get("scanner.network", "tcp"))
+	result["${scanner.address}"] = cast.ToString(facade.StorageToml.Get("scanner.address", "127.0.0.1:3310"))
+	result["${scanner.timeout}"] = cast.ToInt(facade.StorageToml.Get("scanner.timeout", 30))
+	result["${scanner.fail_open}"] = cast.ToBool(facade.StorageToml.Get("scanner.fail_open", false))
+	result["${scanner.quarantine}"] = cast.ToString(facade.StorageToml.Get("scanner.quarantine", "runtime/quarantine"))
+
 	return result
 }
 
+// readTomlFile 从磁盘读取配置文件（不经过已加载的配置，用于记录修改前后的内容）
+func (this *Toml) readTomlFile(path string) map[string]any {
+	dir, file := filepath.Split(path)
+	item := utils.Viper(utils.ViperModel{
+		Path: strings.TrimSuffix(dir, "/"),
+		Mode: "toml",
+		Name: strings.TrimSuffix(file, filepath.Ext(file)),
+	}).Read()
+	if item.Error != nil {
+		return nil
+	}
+	return item.Result
+}
+
 // saveTomlConfig 保存配置文件
 func (this *Toml) saveTomlConfig(ctx *gin.Context, content string, path string, successMsg string) {
+	before := this.readTomlFile(path)
+
 	item := utils.File().Save(strings.NewReader(content), path)
 	if item.Error != nil {
 		this.json(ctx, nil, facade.Lang(ctx, "修改失败！"), 400)
 		return
 	}
 
+	// 审计日志记录配置文件修改前后的内容
+	this.audit(ctx, before, this.readTomlFile(path))
+
 	if path == "config/storage.toml" {
 		go facade.ReloadStorageToml()
 	}
@@ -257,12 +260,13 @@ func (this *Toml) IGET(ctx *gin.Context) {
 	method := strings.ToLower(ctx.Param("method"))
 
 	allow := map[string]any{
-		"log":          this.getLog,
-		"sms":          this.getSMS,
-		"cache":        this.getCache,
-		"crypt":        this.getCrypt,
-		"storage":      this.getStorage,
-		"notification": this.getNotification,
+		"log":            this.getLog,
+		"sms":            this.getSMS,
+		"cache":          this.getCache,
+		"crypt":          this.getCrypt,
+		"storage":        this.getStorage,
+		"notification":   this.getNotification,
+		"email-template": this.getEmailTemplate,
 	}
 	err := this.call(allow, method, ctx)
 
@@ -285,10 +289,14 @@ func (this *Toml) IPOST(ctx *gin.Context) {
 		"test-sms-aliyun":               this.testSMSAliyun,
 		"test-sms-aliyun-number-verify": this.testSMSAliYunNumberVerify,
 		"test-sms-tencent":              this.testSMSTencent,
+		"test-sms-http":                 this.testSMSHttp,
 		"test-redis":                    this.testRedis,
 		"test-oss":                      this.testOSS,
 		"test-cos":                      this.testCOS,
 		"test-kodo":                     this.testKODO,
+		"test-scanner":                  this.testScanner,
+		"preview-email-template":        this.previewEmailTemplate,
+		"test-email-template":           this.testEmailTemplate,
 	}
 	err := this.call(allow, method, ctx)
 
@@ -311,6 +319,7 @@ func (this *Toml) IPUT(ctx *gin.Context) {
 		"sms-aliyun":               this.putSMSAliyun,
 		"sms-aliyun-number-verify": this.putSMSAliYunNumberVerify,
 		"sms-tencent":              this.putSMSTencent,
+		"sms-http":                 this.putSMSHttp,
 		"sms-drive":                this.putSMSDrive,
 		"crypt-jwt":                this.putCryptJWT,
 		"cache-default":            this.putCacheDefault,
@@ -325,6 +334,7 @@ func (this *Toml) IPUT(ctx *gin.Context) {
 		"storage-kodo":             this.putStorageKODO,
 		"storage-attachment":       this.putStorageAttachment,
 		"notification":             this.putNotification,
+		"email-template":           this.putEmailTemplate,
 	}
 	err := this.call(allow, method, ctx)
 
@@ -336,12 +346,14 @@ func (this *Toml) IPUT(ctx *gin.Context) {
 
 // IDEL - 删除配置信息
 // @Summary 删除配置信息
-// @Description 删除系统配置相关数据（当前暂不支持）
+// @Description 删除系统配置相关数据（自定义邮件模板）
 func (this *Toml) IDEL(ctx *gin.Context) {
 	// 转小写
 	method := strings.ToLower(ctx.Param("method"))
 
-	allow := map[string]any{}
+	allow := map[string]any{
+		"email-template": this.delEmailTemplate,
+	}
 	err := this.call(allow, method, ctx)
 
 	if err != nil {
@@ -364,7 +376,7 @@ func (this *Toml) getSMS(ctx *gin.Context) {
 	params := this.params(ctx)
 
 	// 允许的查询范围 - 新增 aliyun_number_verify
-	field := []any{"email", "aliyun", "aliyun_number_verify", "tencent"}
+	field := []any{"email", "aliyun", "aliyun_number_verify", "tencent", "http"}
 
 	item := facade.SMSToml
 	if item.Error != nil {
@@ -402,7 +414,7 @@ func (this *Toml) putSMS(ctx *gin.Context) {
 	}
 
 	// 允许的修改范围 - 新增 aliyun_number_verify
-	field := []any{"default", "email", "aliyun", "aliyun_number_verify", "tencent"}
+	field := []any{"default", "email", "aliyun", "aliyun_number_verify", "tencent", "http"}
 
 	if !utils.In.Array(params["name"], field) {
 		this.json(ctx, nil, facade.Lang(ctx, "不允许的修改范围！"), 400)
@@ -420,6 +432,8 @@ func (this *Toml) putSMS(ctx *gin.Context) {
 		this.putSMSAliYunNumberVerify(ctx)
 	case "tencent":
 		this.putSMSTencent(ctx)
+	case "http":
+		this.putSMSHttp(ctx)
 	default:
 		this.putSMSDrive(ctx)
 	}
@@ -466,7 +480,7 @@ func (this *Toml) getCrypt(ctx *gin.Context) {
 	params := this.params(ctx)
 
 	// 允许的查询范围
-	field := []any{"jwt"}
+	field := []any{"jwt", "jwt_keys"}
 
 	item := facade.CryptToml
 	if item.Error != nil {
@@ -496,7 +510,7 @@ func (this *Toml) getStorage(ctx *gin.Context) {
 	params := this.params(ctx)
 
 	// 允许的查询范围
-	field := []any{"local", "oss", "cos", "kodo", "attachment"}
+	field := []any{"local", "oss", "cos", "kodo", "attachment", "scanner"}
 
 	item := facade.StorageToml
 	if item.Error != nil {
@@ -578,7 +592,12 @@ func (this *Toml) putSMSDrive(ctx *gin.Context) {
 		if !utils.In.Array(key, allow) {
 			continue
 		}
-		opts[fmt.Sprintf("${drive.%s}", key)] = value
+		// 允许为空（关闭该类服务），否则必须是已支持的驱动
+		if !utils.Is.Empty(value) && !utils.In.Array(strings.ToLower(cast.ToString(value)), facade.SMSDrives) {
+			this.json(ctx, nil, facade.Lang(ctx, "不支持的驱动：%v", value), 400)
+			return
+		}
+		opts[fmt.Sprintf("${drive.%s}", key)] = strings.ToLower(cast.ToString(value))
 	}
 
 	temp := facade.TempSMS
@@ -797,6 +816,126 @@ func (this *Toml) putSMSTencent(ctx *gin.Context) {
 	this.saveTomlConfig(ctx, temp, "config/sms.toml", "修改成功！")
 }
 
+// smsHttpParams - 校验并整理 HTTP 短信网关配置参数
+func (this *Toml) smsHttpParams(params map[string]any) (*facade.HttpSMS, error) {
+
+	method := strings.ToUpper(cast.ToString(params["method"]))
+	if utils.Is.Empty(method) {
+		method = http.MethodPost
+	}
+	if !utils.In.Array(method, []any{http.MethodGet, http.MethodPost, http.MethodPut}) {
+		return nil, errors.New("method 只能是 GET、POST 或 PUT！")
+	}
+
+	target := strings.TrimSpace(cast.ToString(params["url"]))
+	if utils.Is.Empty(target) {
+		return nil, fmt.Errorf("%s 不能为空！", "url")
+	}
+	if !strings.HasPrefix(target, "http://") && !strings.HasPrefix(target, "https://") {
+		return nil, errors.New("url 仅允许 http/https 协议！")
+	}
+
+	// 请求头与请求体写入 TOML 字面量字符串，不能包含对应的引号
+	headers := make(map[string]string)
+	if raw := strings.TrimSpace(cast.ToString(params["headers"])); !utils.Is.Empty(raw) {
+		if err := json.Unmarshal([]byte(raw), &headers); err != nil {
+			return nil, errors.New("headers 必须是 JSON 对象！")
+		}
+	}
+	body := cast.ToString(params["body"])
+	if strings.Contains(body, "'''") {
+		return nil, errors.New("body 中不能包含连续三个单引号！")
+	}
+
+	timeout := cast.ToInt(params["timeout"])
+	if timeout <= 0 {
+		timeout = 10
+	}
+
+	return &facade.HttpSMS{
+		Method:       method,
+		Url:          target,
+		Headers:      headers,
+		Body:         body,
+		ContentType:  cast.ToString(params["content_type"]),
+		SuccessPath:  cast.ToString(params["success_path"]),
+		SuccessValue: cast.ToString(params["success_value"]),
+		MessagePath:  cast.ToString(params["message_path"]),
+		SignName:     cast.ToString(params["sign_name"]),
+		Template:     cast.ToString(params["verify_code"]),
+		Timeout:      time.Duration(timeout) * time.Second,
+	}, nil
+}
+
+// putSMSHttp - 修改通用 HTTP 短信网关配置
+func (this *Toml) putSMSHttp(ctx *gin.Context) {
+
+	// 请求参数
+	params := this.params(ctx)
+
+	// 还原脱敏占位值（避免把脱敏请求头写回配置）
+	this.restoreSecretParams(params, []string{"headers"}, cast.ToStringMap(facade.SMSToml.Get("http")))
+
+	item, err := this.smsHttpParams(params)
+	if err != nil {
+		this.json(ctx, nil, facade.Lang(ctx, err.Error()), 400)
+		return
+	}
+
+	headers, _ := json.Marshal(item.Headers)
+	if strings.Contains(string(headers), "'") {
+		this.json(ctx, nil, facade.Lang(ctx, "headers 中不能包含单引号！"), 400)
+		return
+	}
+
+	temp := facade.TempSMS
+	temp = utils.Replace(temp, map[string]any{
+		"${http.method}":        item.Method,
+		"${http.url}":           item.Url,
+		"${http.headers}":       string(headers),
+		"${http.body}":          item.Body,
+		"${http.content_type}":  item.ContentType,
+		"${http.success_path}":  item.SuccessPath,
+		"${http.success_value}": item.SuccessValue,
+		"${http.message_path}":  item.MessagePath,
+		"${http.sign_name}":     item.SignName,
+		"${http.verify_code}":   item.Template,
+		"${http.timeout}":       cast.ToString(int(item.Timeout / time.Second)),
+	})
+	temp = this.replaceTomlVars(temp, facade.SMSToml.Result)
+
+	this.saveTomlConfig(ctx, temp, "config/sms.toml", "修改成功！")
+}
+
+// testSMSHttp - 测试通用 HTTP 短信网关
+func (this *Toml) testSMSHttp(ctx *gin.Context) {
+
+	// 请求参数
+	params := this.params(ctx)
+
+	this.restoreSecretParams(params, []string{"headers"}, cast.ToStringMap(facade.SMSToml.Get("http")))
+
+	if !utils.Is.Phone(params["phone"]) {
+		this.json(ctx, nil, facade.Lang(ctx, "格式错误，请给一个正确的手机号码"), 400)
+		return
+	}
+
+	item, err := this.smsHttpParams(params)
+	if err != nil {
+		this.json(ctx, nil, facade.Lang(ctx, err.Error()), 400)
+		return
+	}
+
+	text, err := item.Send(cast.ToString(params["phone"]), map[string]any{"code": "6666"})
+	if err != nil {
+		// 仅管理员可调用，附带网关原始响应便于排查配置
+		this.json(ctx, map[string]any{"error": err.Error(), "response": facade.Comm.Truncate(text, 2048)}, facade.Lang(ctx, "测试短信发送失败！"), 400)
+		return
+	}
+
+	this.json(ctx, utils.Json.Decode(text), facade.Lang(ctx, "测试短信发送成功！"), 200)
+}
+
 // testEmail - 测试邮件服务
 func (this *Toml) testSMSEmail(ctx *gin.Context) {
 
@@ -1120,7 +1259,7 @@ func (this *Toml) testSMSTencent(ctx *gin.Context) {
 	this.json(ctx, nil, facade.Lang(ctx, "测试短信发送成功！"), 200)
 }
 
-// putCryptJWT - 修改JWT配置
+// putCryptJWT - 修改JWT配置（rotate=true 时轮换签名密钥，alg 可选 HS256、RS256、EdDSA）
 func (this *Toml) putCryptJWT(ctx *gin.Context) {
 
 	// 请求参数
@@ -1129,9 +1268,9 @@ func (this *Toml) putCryptJWT(ctx *gin.Context) {
 	// 还原脱敏占位值（避免把脱敏密钥写回配置）
 	this.restoreSecretParams(params, []string{"key"}, cast.ToStringMap(facade.CryptToml.Get("jwt")))
 
+	current := cast.ToString(facade.CryptToml.Get("jwt.key", ""))
 	if utils.Is.Empty(params["key"]) {
-		this.json(ctx, nil, facade.Lang(ctx, "%s 不能为空！", "key"), 400)
-		return
+		params["key"] = current
 	}
 
 	if utils.Is.Empty(params["expire"]) {
@@ -1139,6 +1278,11 @@ func (this *Toml) putCryptJWT(ctx *gin.Context) {
 		return
 	}
 
+	// 旧版本前端未提交访问令牌有效期时沿用当前配置
+	if utils.Is.Empty(params["access_expire"]) {
+		params["access_expire"] = facade.CryptToml.Get("jwt.access_expire", facade.DefaultJwtAccessExpire)
+	}
+
 	if utils.Is.Empty(params["issuer"]) {
 		this.json(ctx, nil, facade.Lang(ctx, "%s 不能为空！", "issuer"), 400)
 		return
@@ -1149,16 +1293,62 @@ func (this *Toml) putCryptJWT(ctx *gin.Context) {
 		return
 	}
 
+	keys := facade.JwtKeys()
+	message := "修改成功！"
+
+	// 轮换签名密钥；直接修改 HS256 密钥（key）同样按轮换处理，旧密钥在重叠期内继续有效，已登录用户不会被迫下线
+	rotate := cast.ToBool(params["rotate"])
+	if !rotate && cast.ToString(params["key"]) != current {
+		rotate, params["alg"] = true, facade.JwtAlgHS256
+	}
+
+	if rotate {
+
+		alg := cast.ToString(params["alg"])
+		if utils.Is.Empty(alg) {
+			alg = facade.JwtAlgHS256
+			if key, err := facade.JwtSigningKey(); err == nil {
+				alg = key.Alg
+			}
+		}
+		if !utils.In.Array(alg, facade.JwtAlgs) {
+			this.json(ctx, nil, facade.Lang(ctx, "不支持的签名算法：%v", alg), 400)
+			return
+		}
+
+		// 仅直接修改 HS256 密钥时沿用提交的密钥，其余情况随机生成
+		secret := ""
+		if alg == facade.JwtAlgHS256 && cast.ToString(params["key"]) != current {
+			secret = cast.ToString(params["key"])
+		}
+
+		var (
+			next *facade.JwtKey
+			err  error
+		)
+		if keys, next, err = facade.JwtRotate(alg, secret); err != nil {
+			this.json(ctx, nil, facade.Lang(ctx, "密钥生成失败：%v", err.Error()), 500)
+			return
+		}
+
+		message = "签名密钥已轮换！"
+		facade.Log.Info(map[string]any{"kid": next.Kid, "alg": next.Alg, "operator_id": this.user(ctx).Id}, "管理员轮换JWT签名密钥")
+	}
+
+	// 新密钥只写入密钥环，jwt.key 保持不变（退订链接等仍以其为根密钥派生，轮换不能使其失效）
 	temp := facade.TempCrypt
 	temp = utils.Replace(temp, map[string]any{
-		"${jwt.key}":     params["key"],
-		"${jwt.expire}":  params["expire"],
-		"${jwt.issuer}":  params["issuer"],
-		"${jwt.subject}": params["subject"],
+		"${jwt.key}":           current,
+		"${jwt.expire}":        params["expire"],
+		"${jwt.access_expire}": params["access_expire"],
+		"${jwt.issuer}":        params["issuer"],
+		"${jwt.subject}":       params["subject"],
+		"${jwt_keys}":          facade.JwtKeysToml(keys),
+		"${seal.key}":          facade.CryptToml.Get("seal.key", ""),
 	})
 	temp = this.replaceTomlVars(temp, facade.CryptToml.Result)
 
-	this.saveTomlConfig(ctx, temp, "config/crypt.toml", "修改成功！")
+	this.saveTomlConfig(ctx, temp, "config/crypt.toml", message)
 }
 
 // putCacheRedis - 修改Redis缓存配置
@@ -1656,6 +1846,46 @@ func (this *Toml) putStorageKODO(ctx *gin.Context) {
 	this.saveTomlConfig(ctx, temp, "config/storage.toml", "修改成功！")
 }
 
+// testScanner - 测试上传文件扫描服务
+func (this *Toml) testScanner(ctx *gin.Context) {
+
+	// 请求参数
+	params := this.params(ctx, map[string]any{
+		"network": facade.StorageToml.Get("scanner.network", "tcp"),
+		"address": facade.StorageToml.Get("scanner.address", "127.0.0.1:3310"),
+		"timeout": facade.StorageToml.Get("scanner.timeout", 30),
+	})
+
+	if utils.Is.Empty(params["address"]) {
+		this.json(ctx, nil, facade.Lang(ctx, "%s 不能为空！", "address"), 400)
+		return
+	}
+
+	scanner := &facade.ClamdScanner{
+		Network: cast.ToString(params["network"]),
+		Address: cast.ToString(params["address"]),
+		Timeout: time.Duration(cast.ToInt(params["timeout"])) * time.Second,
+	}
+
+	if err := scanner.Ping(); err != nil {
+		this.json(ctx, nil, facade.Lang(ctx, "扫描服务连接失败：%v", err.Error()), 400)
+		return
+	}
+
+	// 使用 EICAR 标准测试串验证扫描服务能否识别威胁
+	eicar := `X5O!P%@AP[4\PZX54(P^)7CC)7}$EICAR-STANDARD-ANTIVIRUS-TEST-FILE!$H+H*`
+	result := scanner.Scan(strings.NewReader(eicar))
+	if result.Error != nil {
+		this.json(ctx, nil, facade.Lang(ctx, "扫描服务调用失败：%v", result.Error.Error()), 400)
+		return
+	}
+
+	this.json(ctx, gin.H{
+		"status":    result.Status,
+		"signature": result.Signature,
+	}, facade.Lang(ctx, "扫描服务连接成功！"), 200)
+}
+
 // putStorageAttachment - 修改附件配置
 func (this *Toml) putStorageAttachment(ctx *gin.Context) {
 
@@ -1810,6 +2040,31 @@ func (this *Toml) putStorage(ctx *gin.Context) {
 		}
 	}
 
+	if scanner, ok := params["scanner"].(map[string]any); ok {
+		if v, ok := scanner["enabled"]; ok {
+			replaceMap["${scanner.enabled}"] = cast.ToBool(v)
+		}
+		if v, ok := scanner["network"]; ok {
+			if !utils.In.Array(v, []any{"tcp", "unix"}) {
+				this.json(ctx, nil, facade.Lang(ctx, "scanner.network 只允许是 tcp、unix 其中一个！"), 400)
+				return
+			}
+			replaceMap["${scanner.network}"] = v
+		}
+		if v, ok := scanner["address"]; ok {
+			replaceMap["${scanner.address}"] = v
+		}
+		if v, ok := scanner["timeout"]; ok {
+			replaceMap["${scanner.timeout}"] = cast.ToInt(v)
+		}
+		if v, ok := scanner["fail_open"]; ok {
+			replaceMap["${scanner.fail_open}"] = cast.ToBool(v)
+		}
+		if v, ok := scanner["quarantine"]; ok {
+			replaceMap["${scanner.quarantine}"] = v
+		}
+	}
+
 	temp := facade.TempStorage
 	temp = utils.Replace(temp, replaceMap)
 
@@ -1861,3 +2116,192 @@ func (this *Toml) putNotification(ctx *gin.Context) {
 
 	this.saveTomlConfig(ctx, content, appTomlPath, "修改成功！")
 }
+
+// getEmailTemplate - 获取邮件模板（不传 name 时返回模板列表及可用变量说明）
+func (this *Toml) getEmailTemplate(ctx *gin.Context) {
+
+	params := this.params(ctx)
+	name := cast.ToString(params["name"])
+
+	if utils.Is.Empty(name) {
+		list, _ := facade.DB.Model(&model.EmailTemplate{}).Order("name asc, lang asc").Select()
+		this.json(ctx, map[string]any{
+			"events":  facade.MailTemplateEvents,
+			"custom":  list,
+			"default": facade.MailTemplateDefaultLang,
+		}, facade.Lang(ctx, "数据请求成功！"), 200)
+		return
+	}
+
+	event, ok := facade.MailTemplateEventByName(name)
+	if !ok {
+		this.json(ctx, nil, facade.Lang(ctx, "未知的邮件模板！"), 400)
+		return
+	}
+
+	lang := facade.MailTemplateLang(params["lang"])
+	source, err := facade.MailTemplateSource(name, lang)
+	if err != nil {
+		this.json(ctx, nil, err.Error(), 400)
+		return
+	}
+
+	item, _ := facade.DB.Model(&model.EmailTemplate{}).Where("name", name).Where("lang", lang).Find()
+
+	subject, body := facade.MailTemplateSplit(source)
+	this.json(ctx, map[string]any{
+		"name":      name,
+		"lang":      lang,
+		"subject":   subject,
+		"body":      body,
+		"custom":    !utils.Is.Empty(item),
+		"variables": event.Variables,
+		"sample":    event.Sample,
+	}, facade.Lang(ctx, "数据请求成功！"), 200)
+}
+
+// putEmailTemplate - 保存自定义邮件模板（同名同语言已存在时覆盖）
+func (this *Toml) putEmailTemplate(ctx *gin.Context) {
+
+	params := this.params(ctx)
+	name := cast.ToString(params["name"])
+
+	event, ok := facade.MailTemplateEventByName(name)
+	if !ok {
+		this.json(ctx, nil, facade.Lang(ctx, "未知的邮件模板！"), 400)
+		return
+	}
+
+	for _, key := range []string{"lang", "subject", "body"} {
+		if utils.Is.Empty(params[key]) {
+			this.json(ctx, nil, facade.Lang(ctx, "%s 不能为空！", key), 400)
+			return
+		}
+	}
+
+	lang := facade.MailTemplateLang(params["lang"])
+	if lang != strings.ReplaceAll(strings.ToLower(strings.TrimSpace(cast.ToString(params["lang"]))), "_", "-") {
+		this.json(ctx, nil, facade.Lang(ctx, "语言标识格式不正确！"), 400)
+		return
+	}
+
+	subject := cast.ToString(params["subject"])
+	body := cast.ToString(params["body"])
+
+	// 保存前用示例数据试渲染，避免错误模板导致邮件无法发送
+	if _, _, err := facade.RenderMailSource(name, facade.MailTemplateCompose(subject, body), event.Sample); err != nil {
+		this.json(ctx, nil, err.Error(), 400)
+		return
+	}
+
+	var table model.EmailTemplate
+	facade.DB.Drive().Where("name = ? AND lang = ?", name, lang).Limit(1).Find(&table)
+
+	table.Name = name
+	table.Lang = lang
+	table.Subject = subject
+	table.Body = body
+	table.Remark = cast.ToString(params["remark"])
+
+	if err := facade.DB.Drive().Save(&table).Error; err != nil {
+		this.json(ctx, nil, err.Error(), 400)
+		return
+	}
+
+	this.json(ctx, map[string]any{"id": table.Id}, facade.Lang(ctx, "保存成功！"), 200)
+}
+
+// delEmailTemplate - 删除自定义邮件模板（恢复为默认模板文件）
+func (this *Toml) delEmailTemplate(ctx *gin.Context) {
+
+	params := this.params(ctx)
+
+	if utils.Is.Empty(params["name"]) {
+		this.json(ctx, nil, facade.Lang(ctx, "%s 不能为空！", "name"), 400)
+		return
+	}
+
+	if utils.Is.Empty(params["lang"]) {
+		this.json(ctx, nil, facade.Lang(ctx, "%s 不能为空！", "lang"), 400)
+		return
+	}
+
+	// 物理删除，避免软删除记录占用唯一索引
+	err := facade.DB.Drive().Unscoped().Where("name = ? AND lang = ?", params["name"], facade.MailTemplateLang(params["lang"])).Delete(&model.EmailTemplate{}).Error
+	if err != nil {
+		this.json(ctx, nil, err.Error(), 400)
+		return
+	}
+
+	this.json(ctx, nil, facade.Lang(ctx, "删除成功！"), 200)
+}
+
+// emailTemplateRender - 渲染邮件模板，传入 subject 与 body 时渲染未保存的草稿
+func (this *Toml) emailTemplateRender(params map[string]any) (subject, body string, err error) {
+
+	name := cast.ToString(params["name"])
+	event, ok := facade.MailTemplateEventByName(name)
+	if !ok {
+		return "", "", fmt.Errorf("未知的邮件模板：%s", name)
+	}
+
+	// 示例数据，可通过 data 覆盖
+	data := make(map[string]any)
+	for key, val := range event.Sample {
+		data[key] = val
+	}
+	for key, val := range cast.ToStringMap(params["data"]) {
+		data[key] = val
+	}
+
+	if !utils.Is.Empty(params["subject"]) || !utils.Is.Empty(params["body"]) {
+		source := facade.MailTemplateCompose(cast.ToString(params["subject"]), cast.ToString(params["body"]))
+		return facade.RenderMailSource(name, source, data)
+	}
+
+	return facade.RenderMailTemplate(name, facade.MailTemplateLang(params["lang"]), data)
+}
+
+// previewEmailTemplate - 预览邮件模板
+func (this *Toml) previewEmailTemplate(ctx *gin.Context) {
+
+	subject, body, err := this.emailTemplateRender(this.params(ctx))
+	if err != nil {
+		this.json(ctx, nil, err.Error(), 400)
+		return
+	}
+
+	this.json(ctx, map[string]any{
+		"subject": subject,
+		"html":    body,
+	}, facade.Lang(ctx, "数据请求成功！"), 200)
+}
+
+// testEmailTemplate - 使用当前邮件服务发送模板测试邮件
+func (this *Toml) testEmailTemplate(ctx *gin.Context) {
+
+	params := this.params(ctx)
+
+	if utils.Is.Empty(params["email"]) {
+		this.json(ctx, nil, facade.Lang(ctx, "%s 不能为空！", "email"), 400)
+		return
+	}
+
+	if !utils.Is.Email(params["email"]) {
+		this.json(ctx, nil, facade.Lang(ctx, "邮箱格式不正确！"), 400)
+		return
+	}
+
+	subject, body, err := this.emailTemplateRender(params)
+	if err != nil {
+		this.json(ctx, nil, err.Error(), 400)
+		return
+	}
+
+	if err = facade.GoMail.Send(cast.ToString(params["email"]), subject, body); err != nil {
+		this.json(ctx, err.Error(), facade.Lang(ctx, "测试邮件发送失败！"), 400)
+		return
+	}
+
+	this.json(ctx, nil, facade.Lang(ctx, "测试邮件发送成功！"), 200)
+}
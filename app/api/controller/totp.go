@@ -0,0 +1,252 @@
+package controller
+
+import (
+	"inis/app/facade"
+	"inis/app/model"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/spf13/cast"
+	"github.com/unti-io/go-utils/utils"
+)
+
+// Totp - 两步验证（身份验证器App）
+type Totp struct {
+	base
+}
+
+func (this *Totp) IGET(ctx *gin.Context) {
+	method := strings.ToLower(ctx.Param("method"))
+
+	allow := map[string]any{
+		"status": this.status,
+	}
+	err := this.call(allow, method, ctx)
+
+	if err != nil {
+		this.json(ctx, nil, facade.Lang(ctx, "方法调用错误：%v", err.Error()), 405)
+		return
+	}
+}
+
+func (this *Totp) IPOST(ctx *gin.Context) {
+	method := strings.ToLower(ctx.Param("method"))
+
+	allow := map[string]any{
+		"enroll":         this.enroll,
+		"confirm":        this.confirm,
+		"recovery-codes": this.recoveryCodes,
+	}
+	err := this.call(allow, method, ctx)
+
+	if err != nil {
+		this.json(ctx, nil, facade.Lang(ctx, "方法调用错误：%v", err.Error()), 405)
+		return
+	}
+}
+
+func (this *Totp) IPUT(ctx *gin.Context) {
+	method := strings.ToLower(ctx.Param("method"))
+
+	allow := map[string]any{}
+	err := this.call(allow, method, ctx)
+
+	if err != nil {
+		this.json(ctx, nil, facade.Lang(ctx, "方法调用错误：%v", err.Error()), 405)
+		return
+	}
+}
+
+func (this *Totp) IDEL(ctx *gin.Context) {
+	method := strings.ToLower(ctx.Param("method"))
+
+	allow := map[string]any{
+		"disable": this.disable,
+		"reset":   this.reset,
+	}
+	err := this.call(allow, method, ctx)
+
+	if err != nil {
+		this.json(ctx, nil, facade.Lang(ctx, "方法调用错误：%v", err.Error()), 405)
+		return
+	}
+}
+
+func (this *Totp) INDEX(ctx *gin.Context) {
+	this.json(ctx, nil, facade.Lang(ctx, "没什么用！"), 202)
+}
+
+// status - 当前用户的两步验证状态
+func (this *Totp) status(ctx *gin.Context) {
+
+	uid := this.user(ctx).Id
+	if uid == 0 {
+		this.json(ctx, nil, facade.Lang(ctx, "请先登录！"), 401)
+		return
+	}
+
+	result := gin.H{
+		"enabled":         false,
+		"required":        model.TotpRequired(uid),
+		"enable_time":     0,
+		"recovery_remain": 0,
+	}
+
+	if item := model.GetUserTotp(uid); item != nil && item.Enabled == 1 {
+		result["enabled"] = true
+		result["enable_time"] = item.EnableTime
+		result["recovery_remain"] = item.RecoveryRemain()
+	}
+
+	this.json(ctx, result, facade.Lang(ctx, "数据请求成功！"), 200)
+}
+
+// enroll - 生成待绑定的密钥（需验证密码），前端使用 uri 生成二维码
+func (this *Totp) enroll(ctx *gin.Context) {
+
+	params := this.params(ctx)
+	user := this.user(ctx)
+
+	if user.Id == 0 {
+		this.json(ctx, nil, facade.Lang(ctx, "请先登录！"), 401)
+		return
+	}
+
+	if !this.checkPassword(ctx, user.Id, params["password"]) {
+		return
+	}
+
+	if model.TotpEnabled(user.Id) {
+		this.json(ctx, nil, facade.Lang(ctx, "已开启两步验证，如需更换设备请先关闭！"), 400)
+		return
+	}
+
+	secret, uri, err := model.TotpEnroll(user.Id, utils.Ternary(utils.Is.Empty(user.Email), user.Account, user.Email))
+	if err != nil {
+		this.json(ctx, nil, err.Error(), 500)
+		return
+	}
+
+	this.json(ctx, gin.H{
+		"secret": secret,
+		"uri":    uri,
+	}, facade.Lang(ctx, "请使用身份验证器App扫码绑定后输入验证码！"), 200)
+}
+
+// confirm - 校验身份验证器App的验证码并开启两步验证，返回恢复码（仅此一次）
+func (this *Totp) confirm(ctx *gin.Context) {
+
+	params := this.params(ctx)
+
+	uid := this.user(ctx).Id
+	if uid == 0 {
+		this.json(ctx, nil, facade.Lang(ctx, "请先登录！"), 401)
+		return
+	}
+
+	if utils.Is.Empty(params["code"]) {
+		this.json(ctx, nil, facade.Lang(ctx, "请提交两步验证码！"), 400)
+		return
+	}
+
+	codes, err := model.TotpConfirm(uid, cast.ToString(params["code"]))
+	if err != nil {
+		this.json(ctx, nil, facade.Lang(ctx, err.Error()), 400)
+		return
+	}
+
+	facade.Log.Info(map[string]any{"uid": uid}, "用户开启两步验证")
+
+	this.json(ctx, gin.H{"recovery_codes": codes}, facade.Lang(ctx, "两步验证已开启，请妥善保存恢复码！"), 200)
+}
+
+// recoveryCodes - 重新生成恢复码（需验证密码与两步验证码），旧恢复码全部失效
+func (this *Totp) recoveryCodes(ctx *gin.Context) {
+
+	params := this.params(ctx)
+
+	uid := this.user(ctx).Id
+	if uid == 0 {
+		this.json(ctx, nil, facade.Lang(ctx, "请先登录！"), 401)
+		return
+	}
+
+	if !this.checkPassword(ctx, uid, params["password"]) {
+		return
+	}
+
+	if err := model.TotpVerify(uid, cast.ToString(params["code"])); err != nil {
+		this.json(ctx, nil, facade.Lang(ctx, err.Error()), 400)
+		return
+	}
+
+	codes, err := model.TotpRegenerateRecovery(uid)
+	if err != nil {
+		this.json(ctx, nil, facade.Lang(ctx, err.Error()), 400)
+		return
+	}
+
+	this.json(ctx, gin.H{"recovery_codes": codes}, facade.Lang(ctx, "恢复码已重新生成，请妥善保存！"), 200)
+}
+
+// disable - 关闭两步验证（需验证密码与两步验证码）
+func (this *Totp) disable(ctx *gin.Context) {
+
+	params := this.params(ctx)
+
+	uid := this.user(ctx).Id
+	if uid == 0 {
+		this.json(ctx, nil, facade.Lang(ctx, "请先登录！"), 401)
+		return
+	}
+
+	// 被要求开启两步验证时，需保留至少一种方式（TOTP 或通行密钥）
+	if model.TotpRequired(uid) && !model.HasUserPasskey(uid) {
+		this.json(ctx, nil, facade.Lang(ctx, "管理员要求开启两步验证，无法关闭！"), 403)
+		return
+	}
+
+	if !this.checkPassword(ctx, uid, params["password"]) {
+		return
+	}
+
+	if err := model.TotpVerify(uid, cast.ToString(params["code"])); err != nil {
+		this.json(ctx, nil, facade.Lang(ctx, err.Error()), 400)
+		return
+	}
+
+	if err := model.TotpDisable(uid); err != nil {
+		this.json(ctx, nil, err.Error(), 400)
+		return
+	}
+
+	facade.Log.Info(map[string]any{"uid": uid}, "用户关闭两步验证")
+
+	this.json(ctx, nil, facade.Lang(ctx, "两步验证已关闭！"), 200)
+}
+
+// reset - 管理员重置指定用户的两步验证（用户丢失设备且恢复码用尽时）
+func (this *Totp) reset(ctx *gin.Context) {
+
+	params := this.params(ctx)
+
+	if !this.meta.root(ctx) {
+		this.json(ctx, nil, facade.Lang(ctx, "无权限！"), 403)
+		return
+	}
+
+	uid := cast.ToInt(params["uid"])
+	if uid <= 0 {
+		this.json(ctx, nil, facade.Lang(ctx, "%s 不能为空！", "uid"), 400)
+		return
+	}
+
+	if err := model.TotpDisable(uid); err != nil {
+		this.json(ctx, nil, err.Error(), 400)
+		return
+	}
+
+	facade.Log.Info(map[string]any{"uid": uid, "operator_id": this.user(ctx).Id}, "管理员重置用户两步验证")
+
+	this.json(ctx, gin.H{"uid": uid}, facade.Lang(ctx, "重置成功！"), 200)
+}
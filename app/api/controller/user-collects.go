@@ -674,42 +674,40 @@ func (this *UserCollects) collect(ctx *gin.Context) {
 			return
 		}
 
-		userInfo, _ := facade.DB.Model(&model.Users{}).Find(uid)
-		fromNickname := cast.ToString(cast.ToStringMap(userInfo)["nickname"])
-
+		// 动作描述不含昵称，由聚合通知拼接触发用户
 		var notifTitle, notifContent string
 		switch targetType {
 		case "article":
 			if !utils.Is.Empty(title) {
 				title = truncateSafe(title, 20)
 				notifTitle = "文章被收藏"
-				notifContent = fromNickname + " 收藏了你的文章「" + title + "」"
+				notifContent = "收藏了你的文章「" + title + "」"
 			} else {
 				notifTitle = "获得新收藏"
-				notifContent = fromNickname + " 收藏了你的文章"
+				notifContent = "收藏了你的文章"
 			}
 		case "page":
 			if !utils.Is.Empty(title) {
 				title = truncateSafe(title, 20)
 				notifTitle = "页面被收藏"
-				notifContent = fromNickname + " 收藏了你的页面「" + title + "」"
+				notifContent = "收藏了你的页面「" + title + "」"
 			} else {
 				notifTitle = "获得新收藏"
-				notifContent = fromNickname + " 收藏了你的页面"
+				notifContent = "收藏了你的页面"
 			}
 		case "moments":
 			if !utils.Is.Empty(content) {
 				content = truncateSafe(content, 30)
 				notifTitle = "动态被收藏"
-				notifContent = fromNickname + " 收藏了你的动态「" + content + "」"
+				notifContent = "收藏了你的动态「" + content + "」"
 			} else {
 				notifTitle = "获得新收藏"
-				notifContent = fromNickname + " 收藏了你的动态"
+				notifContent = "收藏了你的动态"
 			}
 		}
 
-		_, _ = (&model.Notification{}).CreateNotification(
-			authorId, uid, "collect", notifTitle, notifContent, targetType, targetId,
+		_, _ = (&model.Notification{}).CreateAggregatedNotification(
+			authorId, uid, model.NotifyTypeCollect, notifTitle, notifContent, targetType, targetId,
 		)
 	}()
 
@@ -635,13 +635,11 @@ func (this *UserFollows) follow(ctx *gin.Context) {
 			}
 		}()
 
-		userInfo, _ := facade.DB.Model(&model.Users{}).Find(uid)
-		fromNickname := cast.ToString(cast.ToStringMap(userInfo)["nickname"])
-
-		_, _ = (&model.Notification{}).CreateNotification(
-			followUid, uid, "follow", "有新关注",
-			fromNickname+" 关注了你",
-			"user", uid,
+		// 关联到被关注者本人，使同一时段的新关注聚合为一条通知（最新关注者见 from_uid）
+		_, _ = (&model.Notification{}).CreateAggregatedNotification(
+			followUid, uid, model.NotifyTypeFollow, "有新关注",
+			"关注了你",
+			"user", followUid,
 		)
 	}()
 
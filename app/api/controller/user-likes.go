@@ -689,51 +689,49 @@ func (this *UserLikes) like(ctx *gin.Context) {
 			return
 		}
 
-		userInfo, _ := facade.DB.Model(&model.Users{}).Find(uid)
-		fromNickname := cast.ToString(cast.ToStringMap(userInfo)["nickname"])
-
+		// 动作描述不含昵称，由聚合通知拼接触发用户
 		var notifTitle, notifContent string
 		switch targetType {
 		case "article":
 			if !utils.Is.Empty(title) {
 				title = truncateSafe(title, 20)
 				notifTitle = "文章被点赞"
-				notifContent = fromNickname + " 赞了你的文章「" + title + "」"
+				notifContent = "赞了你的文章「" + title + "」"
 			} else {
 				notifTitle = "获得新点赞"
-				notifContent = fromNickname + " 赞了你的文章"
+				notifContent = "赞了你的文章"
 			}
 		case "page":
 			if !utils.Is.Empty(title) {
 				title = truncateSafe(title, 20)
 				notifTitle = "页面被点赞"
-				notifContent = fromNickname + " 赞了你的页面「" + title + "」"
+				notifContent = "赞了你的页面「" + title + "」"
 			} else {
 				notifTitle = "获得新点赞"
-				notifContent = fromNickname + " 赞了你的页面"
+				notifContent = "赞了你的页面"
 			}
 		case "moments":
 			if !utils.Is.Empty(content) {
 				content = truncateSafe(content, 30)
 				notifTitle = "动态被点赞"
-				notifContent = fromNickname + " 赞了你的动态「" + content + "」"
+				notifContent = "赞了你的动态「" + content + "」"
 			} else {
 				notifTitle = "获得新点赞"
-				notifContent = fromNickname + " 赞了你的动态"
+				notifContent = "赞了你的动态"
 			}
 		case "comment":
 			if !utils.Is.Empty(content) {
 				content = truncateSafe(content, 30)
 				notifTitle = "评论被点赞"
-				notifContent = fromNickname + " 赞了你的评论「" + content + "」"
+				notifContent = "赞了你的评论「" + content + "」"
 			} else {
 				notifTitle = "获得新点赞"
-				notifContent = fromNickname + " 赞了你的评论"
+				notifContent = "赞了你的评论"
 			}
 		}
 
-		_, _ = (&model.Notification{}).CreateNotification(
-			authorId, uid, "like", notifTitle, notifContent, bindType, bindId,
+		_, _ = (&model.Notification{}).CreateAggregatedNotification(
+			authorId, uid, model.NotifyTypeLike, notifTitle, notifContent, bindType, bindId,
 		)
 	}()
 
@@ -0,0 +1,220 @@
+package controller
+
+import (
+	"inis/app/facade"
+	"inis/app/model"
+	"math"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/spf13/cast"
+	"github.com/unti-io/go-utils/utils"
+)
+
+// UserSessions - 登录会话（登录设备管理）
+type UserSessions struct {
+	base
+}
+
+func (this *UserSessions) IGET(ctx *gin.Context) {
+	method := strings.ToLower(ctx.Param("method"))
+
+	allow := map[string]any{
+		"all": this.all,
+	}
+	err := this.call(allow, method, ctx)
+
+	if err != nil {
+		this.json(ctx, nil, facade.Lang(ctx, "方法调用错误：%v", err.Error()), 405)
+		return
+	}
+}
+
+func (this *UserSessions) IPOST(ctx *gin.Context) {
+	method := strings.ToLower(ctx.Param("method"))
+
+	allow := map[string]any{}
+	err := this.call(allow, method, ctx)
+
+	if err != nil {
+		this.json(ctx, nil, facade.Lang(ctx, "方法调用错误：%v", err.Error()), 405)
+		return
+	}
+}
+
+func (this *UserSessions) IPUT(ctx *gin.Context) {
+	method := strings.ToLower(ctx.Param("method"))
+
+	allow := map[string]any{}
+	err := this.call(allow, method, ctx)
+
+	if err != nil {
+		this.json(ctx, nil, facade.Lang(ctx, "方法调用错误：%v", err.Error()), 405)
+		return
+	}
+}
+
+func (this *UserSessions) IDEL(ctx *gin.Context) {
+	method := strings.ToLower(ctx.Param("method"))
+
+	allow := map[string]any{
+		"revoke": this.revoke,
+		"others": this.others,
+		"user":   this.kill,
+	}
+	err := this.call(allow, method, ctx)
+
+	if err != nil {
+		this.json(ctx, nil, facade.Lang(ctx, "方法调用错误：%v", err.Error()), 405)
+		return
+	}
+}
+
+func (this *UserSessions) INDEX(ctx *gin.Context) {
+	this.json(ctx, nil, facade.Lang(ctx, "没什么用！"), 202)
+}
+
+// all - 登录设备列表（默认仅有效会话，管理员可通过 uid 查看指定用户）
+func (this *UserSessions) all(ctx *gin.Context) {
+
+	params := this.params(ctx, map[string]any{
+		"page":   1,
+		"order":  "last_time desc",
+		"revoke": 0,
+	})
+
+	uid := this.user(ctx).Id
+	if uid == 0 {
+		this.json(ctx, nil, facade.Lang(ctx, "请先登录！"), 401)
+		return
+	}
+	if !utils.Is.Empty(params["uid"]) && cast.ToInt(params["uid"]) != uid {
+		if !this.meta.root(ctx) {
+			this.json(ctx, nil, facade.Lang(ctx, "无权限！"), 403)
+			return
+		}
+		uid = cast.ToInt(params["uid"])
+	}
+
+	page := cast.ToInt(params["page"])
+	limit := this.meta.limit(ctx)
+
+	query := facade.DB.Model(&[]model.UserSession{}).Where("uid", uid)
+	// revoke=1 时包含已下线与已过期的会话
+	if !cast.ToBool(params["revoke"]) {
+		query = query.Where("revoke_time", 0).Where("expire_time", ">", time.Now().Unix())
+	}
+
+	count, _ := query.Count()
+	items, _ := query.Limit(limit).Page(page).Order(params["order"]).Select()
+
+	if utils.Is.Empty(items) {
+		this.json(ctx, gin.H{"data": []any{}, "count": 0, "page": 0}, facade.Lang(ctx, "无数据！"), 204)
+		return
+	}
+
+	sid := this.meta.sid(ctx)
+	for _, item := range items {
+		delete(item, "refresh_hash")
+		delete(item, "prev_hash")
+		delete(item, "rotate_time")
+		item["current"] = !utils.Is.Empty(sid) && cast.ToString(item["sid"]) == sid
+	}
+
+	this.json(ctx, gin.H{
+		"data":  utils.ArrayMapWithField(items, params["field"]),
+		"count": count,
+		"page":  math.Ceil(float64(count) / float64(limit)),
+	}, facade.Lang(ctx, "数据请求成功！"), 200)
+}
+
+// revoke - 下线指定会话（本人或管理员）
+func (this *UserSessions) revoke(ctx *gin.Context) {
+
+	params := this.params(ctx)
+
+	if utils.Is.Empty(params["id"]) {
+		this.json(ctx, nil, facade.Lang(ctx, "%s 不能为空！", "id"), 400)
+		return
+	}
+
+	uid := this.user(ctx).Id
+	if uid == 0 {
+		this.json(ctx, nil, facade.Lang(ctx, "请先登录！"), 401)
+		return
+	}
+
+	item := model.UserSession{}
+	facade.DB.Drive().Where("id = ?", params["id"]).Take(&item)
+	if item.Id == 0 {
+		this.json(ctx, nil, facade.Lang(ctx, "无数据！"), 204)
+		return
+	}
+
+	reason := model.SessionRevokeUser
+	if item.Uid != uid {
+		if !this.meta.root(ctx) {
+			this.json(ctx, nil, facade.Lang(ctx, "无权限！"), 403)
+			return
+		}
+		reason = model.SessionRevokeAdmin
+	}
+
+	if err := model.RevokeUserSession(item.Sid, reason); err != nil {
+		this.json(ctx, nil, err.Error(), 400)
+		return
+	}
+
+	// 下线的是当前会话时同时清除 cookie
+	if item.Sid == this.meta.sid(ctx) {
+		clearToken(ctx)
+	}
+
+	this.json(ctx, gin.H{"id": item.Id}, facade.Lang(ctx, "下线成功！"), 200)
+}
+
+// others - 退出其它设备（保留当前会话）
+func (this *UserSessions) others(ctx *gin.Context) {
+
+	uid := this.user(ctx).Id
+	if uid == 0 {
+		this.json(ctx, nil, facade.Lang(ctx, "请先登录！"), 401)
+		return
+	}
+
+	count, err := model.RevokeUserSessions(uid, this.meta.sid(ctx), model.SessionRevokeOthers)
+	if err != nil {
+		this.json(ctx, nil, err.Error(), 400)
+		return
+	}
+
+	this.json(ctx, gin.H{"count": count}, facade.Lang(ctx, "下线成功！"), 200)
+}
+
+// kill - 管理员强制下线指定用户的全部会话
+func (this *UserSessions) kill(ctx *gin.Context) {
+
+	params := this.params(ctx)
+
+	if !this.meta.root(ctx) {
+		this.json(ctx, nil, facade.Lang(ctx, "无权限！"), 403)
+		return
+	}
+
+	uid := cast.ToInt(params["uid"])
+	if uid <= 0 {
+		this.json(ctx, nil, facade.Lang(ctx, "%s 不能为空！", "uid"), 400)
+		return
+	}
+
+	count, err := model.RevokeUserSessions(uid, "", model.SessionRevokeAdmin)
+	if err != nil {
+		this.json(ctx, nil, err.Error(), 400)
+		return
+	}
+
+	facade.Log.Info(map[string]any{"uid": uid, "operator_id": this.user(ctx).Id, "count": count}, "管理员强制下线用户")
+
+	this.json(ctx, gin.H{"uid": uid, "count": count}, facade.Lang(ctx, "下线成功！"), 200)
+}
@@ -80,6 +80,7 @@ func (this *Users) IPUT(ctx *gin.Context) {
 		"status":        this.status,
 		"ban":           this.ban,
 		"unban":         this.unban,
+		"unlock":        this.unlock,
 		"appeal-handle": this.appealHandle,
 	}
 	err := this.call(allow, method, ctx)
@@ -377,7 +378,7 @@ func (this *Users) create(ctx *gin.Context) {
 
 	// 表数据结构体
 	table := model.Users{CreateTime: time.Now().Unix(), UpdateTime: time.Now().Unix()}
-	allow := []any{"account", "password", "nickname", "email", "phone", "avatar", "description", "source", "remark", "title", "gender", "json", "text", "status"}
+	allow := []any{"account", "password", "nickname", "email", "phone", "avatar", "description", "source", "remark", "title", "gender", "lang", "json", "text", "status"}
 
 	if utils.Is.Empty(params["email"]) {
 		this.json(ctx, nil, facade.Lang(ctx, "邮箱不能为空！"), 400)
@@ -447,7 +448,7 @@ func (this *Users) update(ctx *gin.Context) {
 
 	// 表数据结构体
 	table := model.Users{}
-	allow := []any{"id", "account", "password", "nickname", "avatar", "title", "description", "gender", "json", "text", "status"}
+	allow := []any{"id", "account", "password", "nickname", "avatar", "title", "description", "gender", "lang", "json", "text", "status"}
 	async := utils.Async[map[string]any]()
 
 	root := this.meta.root(ctx)
@@ -503,6 +504,12 @@ func (this *Users) update(ctx *gin.Context) {
 		return
 	}
 
+	// 修改密码后下线其它会话（修改自己的密码时保留当前会话）
+	if !utils.Is.Empty(params["password"]) {
+		except := utils.Ternary(cast.ToInt(params["id"]) == this.user(ctx).Id, this.meta.sid(ctx), "")
+		go model.RevokeUserSessions(cast.ToInt(params["id"]), except, model.SessionRevokePassword)
+	}
+
 	// 删除缓存
 	facade.Cache.Del(fmt.Sprintf("user[%v]", params["id"]))
 
@@ -558,6 +565,11 @@ func (this *Users) status(ctx *gin.Context) {
 		return
 	}
 
+	// 冻结后强制下线
+	if status == model.UserStatusFrozen {
+		go model.RevokeUserSessions(userId, "", model.SessionRevokeBan)
+	}
+
 	// 删除缓存
 	facade.Cache.Del(fmt.Sprintf("user[%v]", userId))
 
@@ -934,14 +946,6 @@ func (this *Users) email(ctx *gin.Context) {
 		return
 	}
 
-	// 驱动
-	drive := cast.ToString(facade.SMSToml.Get("drive.email"))
-
-	if utils.Is.Empty(drive) {
-		this.json(ctx, nil, facade.Lang(ctx, "管理员未开启邮箱服务，无法发送验证码！"), 400)
-		return
-	}
-
 	// 从数据库里面找一下这个邮箱是否已经存在
 	exist, _ := facade.DB.Model(&model.Users{}).Where("email", params["email"]).Where("id", "!=", user.Id).Exist()
 	if exist {
@@ -949,54 +953,24 @@ func (this *Users) email(ctx *gin.Context) {
 		return
 	}
 
-	// 缓存名称
-	cacheName := fmt.Sprintf("%v-%v", drive, params["email"])
+	code := model.NewVerifyCode(model.VerifyCodeBindEmail, "email", cast.ToString(params["email"]))
+	code.Lang = utils.Ternary(utils.Is.Empty(user.Lang), facade.RequestLang(ctx), user.Lang)
 
 	// 验证码为空，发送验证码
 	if utils.Is.Empty(params["code"]) {
-		// 本地频控检查
-		frequencyCacheName := fmt.Sprintf("frequency-%v-%v", drive, params["email"])
-		dailyLimitCacheName := fmt.Sprintf("daily-limit-%v-%v", drive, params["email"])
-
-		// 检查发送间隔（60秒）
-		lastSendTime := facade.Cache.Get(frequencyCacheName)
-		if !utils.Is.Empty(lastSendTime) {
-			if time.Now().Unix()-cast.ToInt64(lastSendTime) < 60 {
-				this.json(ctx, nil, facade.Lang(ctx, "发送过于频繁，请60秒后再试！"), 400)
-				return
-			}
-		}
-
-		// 检查每日发送限制（10次）
-		dailyCount := cast.ToInt(facade.Cache.Get(dailyLimitCacheName))
-		if dailyCount >= 10 {
-			this.json(ctx, nil, facade.Lang(ctx, "今日发送验证码次数已达上限，请明日再试！"), 400)
-			return
-		}
 
-		sms := facade.NewSMS(drive).VerifyCode(params["email"])
-		if sms.Error != nil {
-			this.json(ctx, nil, sms.Error.Error(), 400)
+		if err := code.Send(ctx.ClientIP()); err != nil {
+			this.json(ctx, nil, this.codeError(ctx, err), 400)
 			return
 		}
 
-		// 缓存验证码 - 5分钟
-		go facade.Cache.Set(cacheName, sms.VerifyCode, 5*time.Minute)
-		// 缓存发送时间 - 60秒
-		go facade.Cache.Set(frequencyCacheName, time.Now().Unix(), time.Second*60)
-		// 缓存每日发送次数 - 24小时
-		go facade.Cache.Set(dailyLimitCacheName, dailyCount+1, time.Hour*24)
-
 		msg := fmt.Sprintf("验证码发送至您的邮箱：%s，请注意查收！", facade.Comm.MaskEmail(cast.ToString(params["email"])))
 		this.json(ctx, nil, facade.Lang(ctx, msg), 201)
 		return
 	}
 
-	// 获取缓存里面的验证码
-	cacheCode := facade.Cache.Get(cacheName)
-
-	if cast.ToString(params["code"]) != cast.ToString(cacheCode) {
-		this.json(ctx, nil, facade.Lang(ctx, "验证码错误！"), 400)
+	if err := code.Check(params["code"], ctx.ClientIP()); err != nil {
+		this.json(ctx, nil, this.codeError(ctx, err), 400)
 		return
 	}
 
@@ -1007,9 +981,6 @@ func (this *Users) email(ctx *gin.Context) {
 		return
 	}
 
-	// 删除验证码
-	go facade.Cache.Del(cacheName)
-
 	this.json(ctx, gin.H{"id": user.Id}, facade.Lang(ctx, "修改成功！"), 200)
 }
 
@@ -1032,14 +1003,6 @@ func (this *Users) phone(ctx *gin.Context) {
 		return
 	}
 
-	// 驱动
-	drive := cast.ToString(facade.SMSToml.Get("drive.sms"))
-
-	if utils.Is.Empty(drive) {
-		this.json(ctx, nil, facade.Lang(ctx, "管理员未开启短信服务，无法发送验证码！"), 400)
-		return
-	}
-
 	// 从数据库里面找一下这个手机号是否已经存在
 	exist, _ := facade.DB.Model(&model.Users{}).Where("phone", params["phone"]).Where("id", "!=", user.Id).Exist()
 	if exist {
@@ -1047,59 +1010,23 @@ func (this *Users) phone(ctx *gin.Context) {
 		return
 	}
 
-	// 缓存名称
-	cacheName := fmt.Sprintf("%v-%v", drive, params["phone"])
+	code := model.NewVerifyCode(model.VerifyCodeBindPhone, "sms", cast.ToString(params["phone"]))
 
 	// 验证码为空，发送验证码
 	if utils.Is.Empty(params["code"]) {
-		// 本地频控检查
-		frequencyCacheName := fmt.Sprintf("frequency-%v-%v", drive, params["phone"])
-		dailyLimitCacheName := fmt.Sprintf("daily-limit-%v-%v", drive, params["phone"])
-
-		// 检查发送间隔（60秒）
-		lastSendTime := facade.Cache.Get(frequencyCacheName)
-		if !utils.Is.Empty(lastSendTime) {
-			if time.Now().Unix()-cast.ToInt64(lastSendTime) < 60 {
-				this.json(ctx, nil, facade.Lang(ctx, "发送过于频繁，请60秒后再试！"), 400)
-				return
-			}
-		}
 
-		// 检查每日发送限制（10次）
-		dailyCount := cast.ToInt(facade.Cache.Get(dailyLimitCacheName))
-		if dailyCount >= 10 {
-			this.json(ctx, nil, facade.Lang(ctx, "今日发送验证码次数已达上限，请明日再试！"), 400)
+		if err := code.Send(ctx.ClientIP()); err != nil {
+			this.json(ctx, nil, this.codeError(ctx, err), 400)
 			return
 		}
 
-		sms := facade.NewSMS(drive).VerifyCode(params["phone"])
-		if sms.Error != nil {
-			// 处理阿里云频控错误
-			if strings.Contains(sms.Error.Error(), "check frequency failed") || strings.Contains(sms.Error.Error(), "FREQUENCY_FAIL") {
-				this.json(ctx, nil, facade.Lang(ctx, "发送过于频繁，请稍后再试！"), 400)
-				return
-			}
-			this.json(ctx, nil, sms.Error.Error(), 400)
-			return
-		}
-
-		// 缓存验证码 - 5分钟
-		go facade.Cache.Set(cacheName, sms.VerifyCode, 5*time.Minute)
-		// 缓存发送时间 - 60秒
-		go facade.Cache.Set(frequencyCacheName, time.Now().Unix(), time.Second*60)
-		// 缓存每日发送次数 - 24小时
-		go facade.Cache.Set(dailyLimitCacheName, dailyCount+1, time.Hour*24)
-
 		msg := fmt.Sprintf("验证码发送至您的手机：%s，请注意查收！", facade.Comm.MaskPhone(cast.ToString(params["phone"])))
 		this.json(ctx, nil, facade.Lang(ctx, msg), 201)
 		return
 	}
 
-	// 获取缓存里面的验证码
-	cacheCode := facade.Cache.Get(cacheName)
-
-	if cast.ToString(params["code"]) != cast.ToString(cacheCode) {
-		this.json(ctx, nil, facade.Lang(ctx, "验证码错误！"), 400)
+	if err := code.Check(params["code"], ctx.ClientIP()); err != nil {
+		this.json(ctx, nil, this.codeError(ctx, err), 400)
 		return
 	}
 
@@ -1110,9 +1037,6 @@ func (this *Users) phone(ctx *gin.Context) {
 		return
 	}
 
-	// 删除验证码
-	go facade.Cache.Del(cacheName)
-
 	this.json(ctx, gin.H{"id": user.Id}, facade.Lang(ctx, "修改成功！"), 200)
 }
 
@@ -1152,51 +1076,27 @@ func (this *Users) destroy(ctx *gin.Context) {
 		contact = user.Phone
 	}
 
-	cacheName := fmt.Sprintf("[login][%v=%v]", social, contact)
+	code := model.NewVerifyCode(model.VerifyCodeDestroy, utils.Ternary(social == "email", "email", "sms"), contact)
+	code.Lang = facade.RequestLang(ctx)
 
 	if utils.Is.Empty(params["code"]) {
-		drive := utils.Ternary(social == "email", "email", "sms")
-
-		frequencyCacheName := fmt.Sprintf("destroy-frequency-%v-%v", drive, contact)
-		dailyLimitCacheName := fmt.Sprintf("destroy-daily-limit-%v-%v", drive, contact)
-
-		lastSendTime := facade.Cache.Get(frequencyCacheName)
-		if !utils.Is.Empty(lastSendTime) {
-			if time.Now().Unix()-cast.ToInt64(lastSendTime) < 60 {
-				this.json(ctx, nil, facade.Lang(ctx, "发送过于频繁，请60秒后再试！"), 400)
-				return
-			}
-		}
-
-		dailyCount := cast.ToInt(facade.Cache.Get(dailyLimitCacheName))
-		if dailyCount >= 10 {
-			this.json(ctx, nil, facade.Lang(ctx, "今日发送验证码次数已达上限，请明日再试！"), 400)
-			return
-		}
 
-		sms := facade.NewSMS(drive).VerifyCode(contact)
-		if sms.Error != nil {
-			this.json(ctx, nil, sms.Error.Error(), 400)
+		if err := code.Send(ctx.ClientIP()); err != nil {
+			this.json(ctx, nil, this.codeError(ctx, err), 400)
 			return
 		}
 
-		go facade.Cache.Set(cacheName, sms.VerifyCode, 5*time.Minute)
-		go facade.Cache.Set(frequencyCacheName, time.Now().Unix(), time.Second*60)
-		go facade.Cache.Set(dailyLimitCacheName, dailyCount+1, time.Hour*24)
 		this.json(ctx, nil, facade.Lang(ctx, "验证码发送成功！"), 201)
 		return
 	}
 
-	cacheCode := facade.Cache.Get(cacheName)
-	if cast.ToString(params["code"]) != cast.ToString(cacheCode) {
-		this.json(ctx, nil, facade.Lang(ctx, "验证码错误！"), 400)
+	if utils.Is.Empty(params["password"]) {
+		this.json(ctx, nil, facade.Lang(ctx, "请输入当前密码以确认注销！"), 400)
 		return
 	}
 
-	go facade.Cache.Del(cacheName)
-
-	if utils.Is.Empty(params["password"]) {
-		this.json(ctx, nil, facade.Lang(ctx, "请输入当前密码以确认注销！"), 400)
+	if err := code.Check(params["code"], ctx.ClientIP()); err != nil {
+		this.json(ctx, nil, this.codeError(ctx, err), 400)
 		return
 	}
 
@@ -1223,6 +1123,11 @@ func (this *Users) destroy(ctx *gin.Context) {
 	}
 
 	facade.Cache.Del(fmt.Sprintf("user[%v]", user.Id))
+	model.RevokeUserSessions(user.Id, "", model.SessionRevokeDestroy)
+	_ = model.TotpDisable(user.Id)
+	_, _ = model.DeleteUserPasskeys(user.Id)
+	_, _ = model.DeleteUserIdentities(user.Id)
+	_ = model.DeleteOAuthGrants(user.Id)
 
 	_, err = facade.DB.Model(&table).Force().Delete(user.Id)
 	if err != nil {
@@ -1230,7 +1135,7 @@ func (this *Users) destroy(ctx *gin.Context) {
 		return
 	}
 
-	ctx.SetCookie(cast.ToString(facade.AppToml.Get("app.token_name", "INIS_LOGIN_TOKEN")), "", -1, "/", "", false, false)
+	clearToken(ctx)
 
 	facade.Log.Info(map[string]any{"user_id": user.Id, "email": user.Email, "phone": user.Phone}, "用户注销账户")
 
@@ -1403,6 +1308,11 @@ func (this *Users) ban(ctx *gin.Context) {
 	// 清除目标用户的缓存，强制下线
 	facade.Cache.Del(fmt.Sprintf("user[%v]", uid))
 	facade.Cache.DelPrefix(fmt.Sprintf("[token][%v]", tokenName))
+	if banType&model.BanTypeLogin != 0 || freezeUser == 1 {
+		if _, err := model.RevokeUserSessions(uid, "", model.SessionRevokeBan); err != nil {
+			facade.Log.Error(map[string]any{"error": err, "uid": uid}, "封禁用户时下线会话失败")
+		}
+	}
 
 	// 删除列表缓存
 	go this.delCache()
@@ -1423,6 +1333,16 @@ func (this *Users) ban(ctx *gin.Context) {
 		"operator_ua":    operatorUa,
 	}, "管理员封禁用户")
 
+	model.TriggerWebhook(model.WebhookEventUserBanned, map[string]any{
+		"uid":         uid,
+		"record_id":   record.Id,
+		"operator_id": operator.Id,
+		"ban_type":    banType,
+		"reason":      reason,
+		"duration":    duration,
+		"expires_at":  expiresAt,
+	})
+
 	this.json(ctx, gin.H{"id": record.Id}, facade.Lang(ctx, "封禁成功！"), 200)
 }
 
@@ -1468,6 +1388,49 @@ func (this *Users) unban(ctx *gin.Context) {
 	this.unbanByRecordId(ctx, currentBanId)
 }
 
+// unlock 管理员解除账号或IP因多次验证失败产生的临时锁定
+func (this *Users) unlock(ctx *gin.Context) {
+
+	params := this.params(ctx)
+
+	// 权限检查 - 仅管理员
+	if !this.meta.root(ctx) {
+		this.json(ctx, nil, facade.Lang(ctx, "无权限！"), 403)
+		return
+	}
+
+	uid := cast.ToInt(params["uid"])
+	ip := strings.TrimSpace(cast.ToString(params["ip"]))
+
+	if uid == 0 && utils.Is.Empty(ip) {
+		this.json(ctx, nil, facade.Lang(ctx, "%s 不能为空！", "uid 或 ip"), 400)
+		return
+	}
+
+	// 登录与找回密码按用户ID统计，验证码按收件人（邮箱、手机号）统计
+	var subjects []string
+	if uid > 0 {
+		table := model.Users{}
+		item, _ := facade.DB.Model(&table).Where("id", uid).Find()
+		if utils.Is.Empty(item) {
+			this.json(ctx, nil, facade.Lang(ctx, "用户不存在！"), 404)
+			return
+		}
+		subjects = append(subjects, cast.ToString(table.Id), table.Email, table.Phone)
+	}
+
+	// 解除前的锁定记录交由审计中间件写入审计日志
+	this.audit(ctx, model.LoginGuardUnlock(subjects, ip), map[string]any{})
+
+	facade.Log.Info(map[string]any{
+		"uid":      uid,
+		"ip":       ip,
+		"operator": this.user(ctx).Id,
+	}, "管理员解除验证失败锁定")
+
+	this.json(ctx, nil, facade.Lang(ctx, "解除锁定成功！"), 200)
+}
+
 // unbanByRecordId 根据封禁记录ID解封
 func (this *Users) unbanByRecordId(ctx *gin.Context, recordId int) {
 
@@ -1876,77 +1839,26 @@ func (this *Users) appealPublic(ctx *gin.Context) {
 		return
 	}
 
-	drive := utils.Ternary(social == "email", "email", "sms")
-	drives := cast.ToStringMap(facade.SMSToml.Get("drive"))
-	if utils.Is.Empty(drives[drive]) {
-		this.json(ctx, nil, facade.Lang(ctx, "管理员未开启%v服务，无法发送验证码！", utils.Ternary(social == "email", "邮箱", "短信")), 400)
-		return
-	}
-
 	clientIP := ctx.ClientIP()
 
+	// 申诉验证码发送限制更严格：账号每日5次、IP每日10次
+	code := model.NewVerifyCode(model.VerifyCodeAppeal, utils.Ternary(social == "email", "email", "sms"), contact)
+	code.Lang = facade.RequestLang(ctx)
+	code.RecipientPerDay = 5
+	code.IPPerDay = 10
+
 	// 缓存键名
-	cacheName := fmt.Sprintf("[appeal][%v=%v]", social, contact)
-	freqAccount := fmt.Sprintf("appeal-freq-account-%v", contact)
-	freqIP := fmt.Sprintf("appeal-freq-ip-%v", clientIP)
-	dailyAccount := fmt.Sprintf("appeal-daily-account-%v", contact)
-	dailyIP := fmt.Sprintf("appeal-daily-ip-%v", clientIP)
 	submitAccount := fmt.Sprintf("appeal-submit-account-%v", contact)
 	submitIP := fmt.Sprintf("appeal-submit-ip-%v", clientIP)
-	codeErrKey := fmt.Sprintf("appeal-code-err-%v", contact)
 
 	// ========== 阶段一：发送验证码 ==========
 	if utils.Is.Empty(params["code"]) {
 
-		// 账号维度发送间隔（60秒）
-		if lastSend := facade.Cache.Get(freqAccount); !utils.Is.Empty(lastSend) {
-			if time.Now().Unix()-cast.ToInt64(lastSend) < 60 {
-				this.json(ctx, nil, facade.Lang(ctx, "发送过于频繁，请60秒后再试！"), 400)
-				return
-			}
-		}
-
-		// IP 维度发送间隔（60秒）
-		if lastSend := facade.Cache.Get(freqIP); !utils.Is.Empty(lastSend) {
-			if time.Now().Unix()-cast.ToInt64(lastSend) < 60 {
-				this.json(ctx, nil, facade.Lang(ctx, "发送过于频繁，请60秒后再试！"), 400)
-				return
-			}
-		}
-
-		// 账号每日发送上限（5次）
-		if cast.ToInt(facade.Cache.Get(dailyAccount)) >= 5 {
-			this.json(ctx, nil, facade.Lang(ctx, "今日发送验证码次数已达上限，请明日再试！"), 400)
-			return
-		}
-
-		// IP 每日发送上限（10次）
-		if cast.ToInt(facade.Cache.Get(dailyIP)) >= 10 {
-			this.json(ctx, nil, facade.Lang(ctx, "今日发送验证码次数已达上限，请明日再试！"), 400)
-			return
-		}
-
-		sms := facade.NewSMS(drives[drive]).VerifyCode(contact)
-		if sms.Error != nil {
-			if drive == "sms" && (strings.Contains(sms.Error.Error(), "check frequency failed") || strings.Contains(sms.Error.Error(), "FREQUENCY_FAIL")) {
-				this.json(ctx, nil, facade.Lang(ctx, "发送过于频繁，请稍后再试！"), 400)
-				return
-			}
-			this.json(ctx, nil, sms.Error.Error(), 400)
+		if err := code.Send(clientIP); err != nil {
+			this.json(ctx, nil, this.codeError(ctx, err), 400)
 			return
 		}
 
-		// 缓存验证码 - 5分钟
-		facade.Cache.Set(cacheName, sms.VerifyCode, 5*time.Minute)
-		// 清除验证码错误计数
-		facade.Cache.Del(codeErrKey)
-		// 发送间隔 - 60秒
-		go facade.Cache.Set(freqAccount, time.Now().Unix(), time.Second*60)
-		go facade.Cache.Set(freqIP, time.Now().Unix(), time.Second*60)
-		// 每日计数 - 24小时
-		go facade.Cache.Set(dailyAccount, cast.ToInt(facade.Cache.Get(dailyAccount))+1, time.Hour*24)
-		go facade.Cache.Set(dailyIP, cast.ToInt(facade.Cache.Get(dailyIP))+1, time.Hour*24)
-
 		var contactMasked string
 		if social == "email" {
 			contactMasked = facade.Comm.MaskEmail(contact)
@@ -1969,20 +1881,6 @@ func (this *Users) appealPublic(ctx *gin.Context) {
 		return
 	}
 
-	// 验证码错误次数上限（5次后需重新发送）
-	if cast.ToInt(facade.Cache.Get(codeErrKey)) >= 5 {
-		this.json(ctx, nil, facade.Lang(ctx, "验证码错误次数过多，请重新发送验证码！"), 400)
-		return
-	}
-
-	// 验证验证码
-	cacheCode := facade.Cache.Get(cacheName)
-	if cast.ToString(params["code"]) != cast.ToString(cacheCode) {
-		go facade.Cache.Set(codeErrKey, cast.ToInt(facade.Cache.Get(codeErrKey))+1, 5*time.Minute)
-		this.json(ctx, nil, facade.Lang(ctx, "验证码错误！"), 400)
-		return
-	}
-
 	// 提交频率限制（账号维度，每小时3次）
 	if cast.ToInt(facade.Cache.Get(submitAccount)) >= 3 {
 		this.json(ctx, nil, facade.Lang(ctx, "申诉提交过于频繁，请稍后再试！"), 400)
@@ -1995,6 +1893,12 @@ func (this *Users) appealPublic(ctx *gin.Context) {
 		return
 	}
 
+	// 验证验证码（错误次数达到上限后需重新发送）
+	if err := code.Check(params["code"], ctx.ClientIP()); err != nil {
+		this.json(ctx, nil, this.codeError(ctx, err), 400)
+		return
+	}
+
 	// 通过 status 条件更新限制每条封禁记录仅可申诉一次
 	now := time.Now().Unix()
 	tx, err := facade.DB.Model(&model.UserBanRecords{}).
@@ -2014,10 +1918,6 @@ func (this *Users) appealPublic(ctx *gin.Context) {
 		return
 	}
 
-	// 清理验证码相关缓存
-	go facade.Cache.Del(cacheName)
-	go facade.Cache.Del(codeErrKey)
-
 	// 更新提交频率
 	go facade.Cache.Set(submitAccount, cast.ToInt(facade.Cache.Get(submitAccount))+1, time.Hour)
 	go facade.Cache.Set(submitIP, cast.ToInt(facade.Cache.Get(submitIP))+1, time.Hour)
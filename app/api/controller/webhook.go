@@ -0,0 +1,478 @@
+package controller
+
+import (
+	"inis/app/facade"
+	"inis/app/model"
+	"inis/app/validator"
+	"math"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/spf13/cast"
+	"github.com/unti-io/go-utils/utils"
+)
+
+var webhookAllowFieldsSlice = []any{"name", "url", "events", "status", "remark", "json", "text"}
+
+type Webhook struct {
+	base
+}
+
+func (this *Webhook) IGET(ctx *gin.Context) {
+	method := strings.ToLower(ctx.Param("method"))
+
+	allow := map[string]any{
+		"one":        this.one,
+		"all":        this.all,
+		"count":      this.count,
+		"events":     this.events,
+		"deliveries": this.deliveries,
+	}
+	err := this.call(allow, method, ctx)
+
+	if err != nil {
+		this.json(ctx, nil, facade.Lang(ctx, "方法调用错误：%v", err.Error()), 405)
+		return
+	}
+}
+
+func (this *Webhook) IPOST(ctx *gin.Context) {
+	method := strings.ToLower(ctx.Param("method"))
+
+	allow := map[string]any{
+		"save":      this.save,
+		"create":    this.create,
+		"ping":      this.ping,
+		"redeliver": this.redeliver,
+	}
+	err := this.call(allow, method, ctx)
+
+	if err != nil {
+		this.json(ctx, nil, facade.Lang(ctx, "方法调用错误：%v", err.Error()), 405)
+		return
+	}
+}
+
+func (this *Webhook) IPUT(ctx *gin.Context) {
+	method := strings.ToLower(ctx.Param("method"))
+
+	allow := map[string]any{
+		"update":  this.update,
+		"restore": this.restore,
+		"secret":  this.secret,
+	}
+	err := this.call(allow, method, ctx)
+
+	if err != nil {
+		this.json(ctx, nil, facade.Lang(ctx, "方法调用错误：%v", err.Error()), 405)
+		return
+	}
+}
+
+func (this *Webhook) IDEL(ctx *gin.Context) {
+	method := strings.ToLower(ctx.Param("method"))
+
+	allow := map[string]any{
+		"remove": this.remove,
+		"delete": this.delete,
+		"clear":  this.clear,
+	}
+	err := this.call(allow, method, ctx)
+
+	if err != nil {
+		this.json(ctx, nil, facade.Lang(ctx, "方法调用错误：%v", err.Error()), 405)
+		return
+	}
+}
+
+func (this *Webhook) INDEX(ctx *gin.Context) {
+	this.json(ctx, nil, facade.Lang(ctx, "没什么用！"), 202)
+}
+
+// maskSecret - 隐藏签名密钥，仅创建和重置时返回明文
+func (this *Webhook) maskSecret(item map[string]any) map[string]any {
+	if secret, ok := item["secret"]; ok {
+		item["secret"] = facade.Comm.MaskSecret(cast.ToString(secret))
+	}
+	return item
+}
+
+// checkParams - 校验推送地址与订阅事件
+func (this *Webhook) checkParams(ctx *gin.Context, params map[string]any) bool {
+
+	if value, ok := params["url"]; ok {
+		if err := facade.Comm.IsSafeOutboundURL(cast.ToString(value)); err != nil {
+			this.json(ctx, nil, facade.Lang(ctx, "推送地址不可用：%v", err.Error()), 400)
+			return false
+		}
+	}
+
+	if value, ok := params["events"]; ok {
+		events := utils.Unity.Keys(value)
+		if utils.Is.Empty(events) {
+			this.json(ctx, nil, facade.Lang(ctx, "%s 不能为空！", "events"), 400)
+			return false
+		}
+		for _, event := range events {
+			if _, exist := model.WebhookEvents[cast.ToString(event)]; !exist && cast.ToString(event) != "*" {
+				this.json(ctx, nil, facade.Lang(ctx, "不支持的事件类型：%v", event), 400)
+				return false
+			}
+		}
+		params["events"] = strings.Join(cast.ToStringSlice(events), ",")
+	}
+
+	return true
+}
+
+// events - 可订阅的事件列表
+func (this *Webhook) events(ctx *gin.Context) {
+	this.json(ctx, model.WebhookEvents, facade.Lang(ctx, "数据请求成功！"), 200)
+}
+
+func (this *Webhook) one(ctx *gin.Context) {
+
+	params := this.params(ctx)
+
+	if utils.Is.Empty(params["id"]) {
+		this.json(ctx, nil, facade.Lang(ctx, "%s 不能为空！", "id"), 400)
+		return
+	}
+
+	item, _ := facade.DB.Model(&model.Webhook{}).Where("id", params["id"]).Find()
+	if utils.Is.Empty(item) {
+		this.json(ctx, nil, facade.Lang(ctx, "无数据！"), 204)
+		return
+	}
+
+	this.json(ctx, facade.Comm.WithField(this.maskSecret(item), params["field"]), facade.Lang(ctx, "数据请求成功！"), 200)
+}
+
+func (this *Webhook) all(ctx *gin.Context) {
+
+	params := this.params(ctx, map[string]any{
+		"page":  1,
+		"order": "create_time desc",
+	})
+
+	page := cast.ToInt(params["page"])
+	limit := this.meta.limit(ctx)
+
+	query := facade.DB.Model(&[]model.Webhook{})
+	if cast.ToBool(params["onlyTrashed"]) {
+		query = query.OnlyTrashed()
+	}
+	if cast.ToBool(params["withTrashed"]) {
+		query = query.WithTrashed()
+	}
+	query = query.IWhere(params["where"]).ILike(params["like"])
+
+	count, _ := query.Count()
+	items, _ := query.Limit(limit).Page(page).Order(params["order"]).Select()
+
+	if utils.Is.Empty(items) {
+		this.json(ctx, gin.H{"data": []any{}, "count": 0, "page": 0}, facade.Lang(ctx, "无数据！"), 204)
+		return
+	}
+
+	for _, item := range items {
+		this.maskSecret(item)
+	}
+
+	this.json(ctx, gin.H{
+		"data":  utils.ArrayMapWithField(items, params["field"]),
+		"count": count,
+		"page":  math.Ceil(float64(count) / float64(limit)),
+	}, facade.Lang(ctx, "数据请求成功！"), 200)
+}
+
+func (this *Webhook) count(ctx *gin.Context) {
+	params := this.params(ctx)
+	count, _ := facade.DB.Model(&model.Webhook{}).IWhere(params["where"]).Count()
+	this.json(ctx, count, facade.Lang(ctx, "查询成功！"), 200)
+}
+
+// deliveries - 投递记录
+func (this *Webhook) deliveries(ctx *gin.Context) {
+
+	params := this.params(ctx, map[string]any{
+		"page":  1,
+		"order": "id desc",
+	})
+
+	page := cast.ToInt(params["page"])
+	limit := this.meta.limit(ctx)
+
+	query := facade.DB.Model(&[]model.WebhookDelivery{})
+	if !utils.Is.Empty(params["webhook_id"]) {
+		query = query.Where("webhook_id", params["webhook_id"])
+	}
+	if !utils.Is.Empty(params["status"]) {
+		query = query.Where("status", params["status"])
+	}
+	if !utils.Is.Empty(params["event"]) {
+		query = query.Where("event", params["event"])
+	}
+
+	count, _ := query.Count()
+	items, _ := query.Limit(limit).Page(page).Order(params["order"]).Select()
+
+	if utils.Is.Empty(items) {
+		this.json(ctx, gin.H{"data": []any{}, "count": 0, "page": 0}, facade.Lang(ctx, "无数据！"), 204)
+		return
+	}
+
+	this.json(ctx, gin.H{
+		"data":  utils.ArrayMapWithField(items, params["field"]),
+		"count": count,
+		"page":  math.Ceil(float64(count) / float64(limit)),
+	}, facade.Lang(ctx, "数据请求成功！"), 200)
+}
+
+func (this *Webhook) save(ctx *gin.Context) {
+	params := this.params(ctx)
+
+	if utils.Is.Empty(params["id"]) {
+		this.create(ctx)
+	} else {
+		this.update(ctx)
+	}
+}
+
+func (this *Webhook) create(ctx *gin.Context) {
+
+	params := this.params(ctx, map[string]any{
+		"events": "*",
+	})
+
+	if err := validator.NewValid("webhook", params); err != nil {
+		this.json(ctx, nil, err.Error(), 400)
+		return
+	}
+
+	if !this.checkParams(ctx, params) {
+		return
+	}
+
+	table := model.Webhook{Status: 1}
+	for key, val := range params {
+		if utils.In.Array(key, webhookAllowFieldsSlice) {
+			utils.Struct.Set(&table, key, val)
+		}
+	}
+	table.Secret = table.GenerateSecret()
+
+	if _, err := facade.DB.Model(&table).Create(&table); err != nil {
+		this.json(ctx, nil, err.Error(), 400)
+		return
+	}
+
+	this.json(ctx, gin.H{"id": table.Id, "secret": table.Secret}, facade.Lang(ctx, "创建成功！"), 200)
+}
+
+func (this *Webhook) update(ctx *gin.Context) {
+
+	params := this.params(ctx)
+
+	if utils.Is.Empty(params["id"]) {
+		this.json(ctx, nil, facade.Lang(ctx, "%s 不能为空！", "id"), 400)
+		return
+	}
+
+	if !this.checkParams(ctx, params) {
+		return
+	}
+
+	async := utils.Async[map[string]any]()
+	for key, val := range params {
+		if utils.In.Array(key, webhookAllowFieldsSlice) {
+			async.Set(key, val)
+		}
+	}
+
+	table := model.Webhook{}
+	_, err := facade.DB.Model(&table).WithTrashed().Where("id", params["id"]).Scan(&table).Update(async.Result())
+	if err != nil {
+		this.json(ctx, nil, err.Error(), 400)
+		return
+	}
+
+	this.json(ctx, gin.H{"id": table.Id}, facade.Lang(ctx, "更新成功！"), 200)
+}
+
+// secret - 重置签名密钥
+func (this *Webhook) secret(ctx *gin.Context) {
+
+	params := this.params(ctx)
+
+	if utils.Is.Empty(params["id"]) {
+		this.json(ctx, nil, facade.Lang(ctx, "%s 不能为空！", "id"), 400)
+		return
+	}
+
+	table := model.Webhook{}
+	secret := table.GenerateSecret()
+
+	_, err := facade.DB.Model(&table).Where("id", params["id"]).Update(map[string]any{"secret": secret})
+	if err != nil {
+		this.json(ctx, nil, err.Error(), 400)
+		return
+	}
+
+	this.json(ctx, gin.H{"id": params["id"], "secret": secret}, facade.Lang(ctx, "重置成功！"), 200)
+}
+
+// ping - 发送测试事件
+func (this *Webhook) ping(ctx *gin.Context) {
+
+	params := this.params(ctx)
+
+	if utils.Is.Empty(params["id"]) {
+		this.json(ctx, nil, facade.Lang(ctx, "%s 不能为空！", "id"), 400)
+		return
+	}
+
+	item, _ := facade.DB.Model(&model.Webhook{}).Where("id", params["id"]).Find()
+	if utils.Is.Empty(item) {
+		this.json(ctx, nil, facade.Lang(ctx, "无数据！"), 204)
+		return
+	}
+
+	delivery, err := model.NewWebhookDelivery(cast.ToInt(item["id"]), cast.ToString(item["url"]), model.WebhookEventPing, gin.H{
+		"webhook_id": item["id"],
+		"message":    "pong",
+	})
+	if err != nil {
+		this.json(ctx, nil, err.Error(), 400)
+		return
+	}
+
+	delivery.Send()
+
+	this.json(ctx, delivery, facade.Lang(ctx, "测试事件已发送！"), 200)
+}
+
+// redeliver - 手动重新投递
+func (this *Webhook) redeliver(ctx *gin.Context) {
+
+	params := this.params(ctx)
+
+	if utils.Is.Empty(params["id"]) {
+		this.json(ctx, nil, facade.Lang(ctx, "%s 不能为空！", "id"), 400)
+		return
+	}
+
+	var delivery model.WebhookDelivery
+	if err := facade.DB.Drive().Where("id = ?", params["id"]).First(&delivery).Error; err != nil {
+		this.json(ctx, nil, facade.Lang(ctx, "投递记录不存在！"), 400)
+		return
+	}
+
+	result, err := delivery.Redeliver()
+	if err != nil {
+		this.json(ctx, nil, facade.Lang(ctx, "重新投递失败：%v", err.Error()), 400)
+		return
+	}
+
+	this.json(ctx, result, facade.Lang(ctx, "已重新投递！"), 200)
+}
+
+func (this *Webhook) remove(ctx *gin.Context) {
+
+	params := this.params(ctx)
+	ids := utils.Unity.Ids(params["ids"])
+
+	if utils.Is.Empty(ids) {
+		this.json(ctx, nil, facade.Lang(ctx, "%s 不能为空！", "ids"), 400)
+		return
+	}
+
+	query := facade.DB.Model(&model.Webhook{})
+	ids = utils.Unity.Ids(query.WhereIn("id", ids).Column("id"))
+
+	if utils.Is.Empty(ids) {
+		this.json(ctx, nil, facade.Lang(ctx, "无可操作数据！"), 204)
+		return
+	}
+
+	if _, err := query.Delete(ids); err != nil {
+		this.json(ctx, nil, facade.Lang(ctx, "删除失败！"), 400)
+		return
+	}
+
+	this.json(ctx, gin.H{"ids": ids}, facade.Lang(ctx, "删除成功！"), 200)
+}
+
+func (this *Webhook) delete(ctx *gin.Context) {
+
+	params := this.params(ctx)
+	ids := utils.Unity.Ids(params["ids"])
+
+	if utils.Is.Empty(ids) {
+		this.json(ctx, nil, facade.Lang(ctx, "%s 不能为空！", "ids"), 400)
+		return
+	}
+
+	query := facade.DB.Model(&model.Webhook{}).WithTrashed()
+	ids = utils.Unity.Ids(query.WhereIn("id", ids).Column("id"))
+
+	if utils.Is.Empty(ids) {
+		this.json(ctx, nil, facade.Lang(ctx, "无可操作数据！"), 204)
+		return
+	}
+
+	if _, err := query.Force().Delete(ids); err != nil {
+		this.json(ctx, nil, facade.Lang(ctx, "删除失败！"), 400)
+		return
+	}
+
+	// 同时清理投递记录
+	go facade.DB.Model(&model.WebhookDelivery{}).WhereIn("webhook_id", ids).Force().Delete()
+
+	this.json(ctx, gin.H{"ids": ids}, facade.Lang(ctx, "删除成功！"), 200)
+}
+
+func (this *Webhook) clear(ctx *gin.Context) {
+
+	query := facade.DB.Model(&model.Webhook{}).OnlyTrashed()
+	ids := utils.Unity.Ids(query.Column("id"))
+
+	if utils.Is.Empty(ids) {
+		this.json(ctx, nil, facade.Lang(ctx, "无可操作数据！"), 204)
+		return
+	}
+
+	if _, err := query.Force().Delete(); err != nil {
+		this.json(ctx, nil, facade.Lang(ctx, "清空失败！"), 400)
+		return
+	}
+
+	go facade.DB.Model(&model.WebhookDelivery{}).WhereIn("webhook_id", ids).Force().Delete()
+
+	this.json(ctx, gin.H{"ids": ids}, facade.Lang(ctx, "清空成功！"), 200)
+}
+
+func (this *Webhook) restore(ctx *gin.Context) {
+
+	params := this.params(ctx)
+	ids := utils.Unity.Ids(params["ids"])
+
+	if utils.Is.Empty(ids) {
+		this.json(ctx, nil, facade.Lang(ctx, "%s 不能为空！", "ids"), 400)
+		return
+	}
+
+	ids = utils.Unity.Ids(facade.DB.Model(&model.Webhook{}).OnlyTrashed().WhereIn("id", ids).Column("id"))
+
+	if utils.Is.Empty(ids) {
+		this.json(ctx, nil, facade.Lang(ctx, "无可操作数据！"), 204)
+		return
+	}
+
+	if _, err := facade.DB.Model(&model.Webhook{}).OnlyTrashed().Restore(ids); err != nil {
+		this.json(ctx, nil, facade.Lang(ctx, "恢复失败！"), 400)
+		return
+	}
+
+	this.json(ctx, gin.H{"ids": ids}, facade.Lang(ctx, "恢复成功！"), 200)
+}
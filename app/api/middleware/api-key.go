@@ -1,18 +1,24 @@
 package middleware
 
 import (
+	"inis/app/client"
 	"inis/app/facade"
 	"inis/app/model"
+	"sync"
 
 	"github.com/gin-gonic/gin"
 	"github.com/spf13/cast"
 	"github.com/unti-io/go-utils/utils"
+	"golang.org/x/time/rate"
 )
 
-var (
-	cacheConfigPrefix = "config"
-	cacheApiKeyPrefix = "[GET]/api/api-keys/column"
-)
+var cacheConfigPrefix = "config"
+
+// apiKeyLimiter - 按密钥ID的限流器
+var apiKeyLimiter = make(map[int]*rate.Limiter)
+
+// apiKeyMutex - 互斥锁
+var apiKeyMutex = &sync.Mutex{}
 
 // getConfigValue 获取配置值（带缓存）
 func getConfigValue(key string) any {
@@ -33,31 +39,13 @@ func getConfigValue(key string) any {
 	return value
 }
 
-func getApiKeys() []string {
-	cacheName := cacheApiKeyPrefix + "[value]"
-	cacheState := cast.ToBool(facade.CacheToml.Get("open"))
-
-	if cacheState && facade.Cache.Has(cacheName) {
-		return cast.ToStringSlice(facade.Cache.Get(cacheName))
-	}
-
-	columnData, _ := facade.DB.Model(&model.ApiKeys{}).Column("value")
-	keys := cast.ToStringSlice(columnData)
-
-	if cacheState {
-		go facade.Cache.Set(cacheName, keys)
-	}
-
-	return keys
-}
-
 // isPublicPath 判断是否为公开路径
 func isPublicPath(path string) bool {
 	publicPaths := []any{"/api/file/rand"}
 	return utils.In.Array(path, publicPaths)
 }
 
-// ApiKey - 安全校验中间件
+// ApiKey - 安全校验中间件（校验密钥的有效期、IP白名单、权限范围与每秒请求上限）
 func ApiKey() gin.HandlerFunc {
 	return func(ctx *gin.Context) {
 		open := cast.ToBool(getConfigValue("SYSTEM_API_KEY"))
@@ -74,20 +62,76 @@ func ApiKey() gin.HandlerFunc {
 			key, _ = ctx.GetQuery("i-api-key")
 		}
 
-		if utils.Is.Empty(key) && !isPublicPath(ctx.Request.URL.Path) {
+		if isPublicPath(ctx.Request.URL.Path) {
+			ctx.Next()
+			return
+		}
+
+		item := model.FindApiKey(key)
+		// 签名请求只携带签名密钥ID，签名通过即视为持有该密钥
+		if item == nil && utils.Is.Empty(key) && !utils.Is.Empty(ctx.GetHeader(client.HeaderKeyId)) {
+			var msg string
+			if item, msg = signatureVerify(ctx); item == nil {
+				signatureAbort(ctx, msg)
+				return
+			}
+		}
+		if item == nil {
 			ctx.JSON(200, gin.H{"code": 403, "msg": facade.Lang(ctx, "禁止非法操作！"), "data": nil})
 			ctx.Abort()
 			return
 		}
 
-		keys := getApiKeys()
+		if item.Expired() {
+			ctx.JSON(200, gin.H{"code": 403, "msg": facade.Lang(ctx, "API_KEY 已过期！"), "data": nil})
+			ctx.Abort()
+			return
+		}
+
+		ip := ctx.ClientIP()
+		if !item.AllowIp(ip) {
+			ctx.JSON(200, gin.H{"code": 403, "msg": facade.Lang(ctx, "当前IP不允许使用该 API_KEY！"), "data": nil})
+			ctx.Abort()
+			return
+		}
 
-		if !utils.InArray(key, keys) && !isPublicPath(ctx.Request.URL.Path) {
-			ctx.JSON(200, gin.H{"code": 403, "msg": facade.Lang(ctx, "禁止非法操作！"), "data": nil})
+		var hash string
+		if rule, ok := ctx.Get("route"); ok {
+			hash = cast.ToString(cast.ToStringMap(rule)["hash"])
+		}
+		if !item.AllowScope(ctx.Request.Method, ctx.Request.URL.Path, hash) {
+			ctx.JSON(200, gin.H{"code": 403, "msg": facade.Lang(ctx, "该 API_KEY 无权访问此接口！"), "data": nil})
+			ctx.Abort()
+			return
+		}
+
+		if !apiKeyAllow(item) {
+			ctx.JSON(200, gin.H{"code": 429, "msg": facade.Lang(ctx, "请求过于频繁！"), "data": nil})
 			ctx.Abort()
 			return
 		}
 
+		item.Touch(ip)
+		ctx.Set("api-key", item.Id)
+
 		ctx.Next()
 	}
 }
+
+// apiKeyAllow - 密钥的每秒请求上限（0 不限制，修改上限后重建限流器）
+func apiKeyAllow(item *model.ApiKeys) bool {
+
+	if item.Qps <= 0 {
+		return true
+	}
+
+	apiKeyMutex.Lock()
+	limit := apiKeyLimiter[item.Id]
+	if limit == nil || limit.Burst() != item.Qps {
+		limit = rate.NewLimiter(rate.Limit(item.Qps), item.Qps)
+		apiKeyLimiter[item.Id] = limit
+	}
+	apiKeyMutex.Unlock()
+
+	return limit.Allow()
+}
@@ -0,0 +1,114 @@
+package middleware
+
+import (
+	"bytes"
+	"inis/app/model"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/spf13/cast"
+	"github.com/unti-io/go-utils/utils"
+)
+
+// auditBodyLimit - 审计时最多缓存的响应内容长度（只用于解析 code、msg 与新建数据的ID）
+const auditBodyLimit = 64 * 1024
+
+// auditWriter - 在写出响应的同时缓存响应内容
+type auditWriter struct {
+	gin.ResponseWriter
+	body *bytes.Buffer
+}
+
+func (this *auditWriter) Write(data []byte) (int, error) {
+	if this.body.Len() < auditBodyLimit {
+		this.body.Write(data)
+	}
+	return this.ResponseWriter.Write(data)
+}
+
+func (this *auditWriter) WriteString(data string) (int, error) {
+	if this.body.Len() < auditBodyLimit {
+		this.body.WriteString(data)
+	}
+	return this.ResponseWriter.WriteString(data)
+}
+
+// auditTarget - 路由中的控制器名（/api/:name/:method）
+func auditTarget(path string) string {
+	parts := strings.Split(strings.Trim(strings.TrimPrefix(path, "/api/"), "/"), "/")
+	return parts[0]
+}
+
+// Audit - 审计日志中间件（记录操作者、接口、目标数据变更前后的快照与响应结果）
+func Audit() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+
+		if !model.AuditEnabled() || !model.AuditRoute(ctx.Request.Method, ctx.Request.URL.Path) {
+			ctx.Next()
+			return
+		}
+
+		var params map[string]any
+		if value, ok := ctx.Get("params"); ok {
+			params = cast.ToStringMap(value)
+		}
+
+		target := auditTarget(ctx.Request.URL.Path)
+		ids := model.AuditIds(params)
+
+		var before map[string]any
+		if ctx.Request.Method != "GET" {
+			before = model.AuditSnapshot(target, ids)
+		}
+
+		writer := &auditWriter{ResponseWriter: ctx.Writer, body: &bytes.Buffer{}}
+		ctx.Writer = writer
+
+		ctx.Next()
+
+		user := getUserFromContext(ctx)
+		item := &model.AuditLog{
+			Uid:     user.Id,
+			Account: utils.Ternary(utils.Is.Empty(user.Account), user.Email, user.Account),
+			Method:  strings.ToUpper(ctx.Request.Method),
+			Path:    ctx.Request.URL.Path,
+			Target:  target,
+			Ip:      ctx.ClientIP(),
+			Ua:      ctx.Request.UserAgent(),
+		}
+
+		if rule, ok := ctx.Get("route"); ok {
+			item.Name = cast.ToString(cast.ToStringMap(rule)["name"])
+		}
+
+		response := cast.ToStringMap(utils.Json.Decode(writer.body.String()))
+		item.Code = cast.ToInt(response["code"])
+		item.Msg = cast.ToString(response["msg"])
+
+		// 新建数据时从响应中取得ID
+		if len(ids) == 0 && item.Code == 200 {
+			data := cast.ToStringMap(response["data"])
+			if !utils.Is.Empty(data["id"]) {
+				ids = []any{data["id"]}
+			} else {
+				ids = utils.Unity.Ids(data["ids"])
+			}
+		}
+		item.TargetIds = model.AuditJoinIds(ids)
+
+		// 控制器提供的快照（如配置文件）优先于按数据表查询的快照
+		custom, hasCustom := ctx.Get("audit")
+
+		go func() {
+			var after any
+			var old any = before
+			if hasCustom {
+				snapshot := cast.ToStringMap(custom)
+				old, after = snapshot["before"], snapshot["after"]
+			} else if item.Method != "GET" {
+				after = model.AuditSnapshot(target, ids)
+			}
+			model.CreateAuditLog(item, params, old, after)
+		}()
+	}
+}
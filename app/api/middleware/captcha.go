@@ -0,0 +1,40 @@
+package middleware
+
+import (
+	"inis/app/facade"
+	"inis/app/model"
+
+	"github.com/gin-gonic/gin"
+	"github.com/spf13/cast"
+	"github.com/unti-io/go-utils/utils"
+)
+
+// Captcha - 人机验证中间件（需要验证的路由由 SYSTEM_CAPTCHA 配置，凭证通过 captcha 参数提交）
+func Captcha() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+
+		user := getUserFromContext(ctx)
+		if !model.CaptchaRequired(ctx.Request.Method, ctx.Request.URL.Path, ctx.ClientIP(), user.Id != 0) {
+			ctx.Next()
+			return
+		}
+
+		var params map[string]any
+		if value, ok := ctx.Get("params"); ok {
+			params = cast.ToStringMap(value)
+		}
+
+		if utils.Is.Empty(params["captcha"]) {
+			ctx.AbortWithStatusJSON(200, gin.H{"code": 400, "msg": facade.Lang(ctx, "请完成人机验证！"), "data": gin.H{"captcha": true}})
+			return
+		}
+
+		if !model.VerifyCaptcha(cast.ToString(params["captcha"]), ctx.ClientIP()) {
+			ctx.AbortWithStatusJSON(200, gin.H{"code": 400, "msg": facade.Lang(ctx, "人机验证失败，请重试！"), "data": gin.H{"captcha": true}})
+			return
+		}
+
+		ctx.Set("captcha", true)
+		ctx.Next()
+	}
+}
@@ -38,15 +38,8 @@ func IsIpBanned(ip string) bool {
 		return false
 	}
 
-	// 查询黑名单记录
-	var ipBlack model.IpBlack
-	facade.DB.Model(&model.IpBlack{}).Where("ip", ip).Scan(&ipBlack)
-
-	if ipBlack.Id == 0 {
-		return false
-	}
-
-	return ipBlack.IsBanned()
+	// 匹配黑名单规则（单个IP、网段、国家或 ASN）
+	return model.MatchIpBlack(ip) != nil
 }
 
 // IpBlack - IP黑名单
@@ -60,11 +53,8 @@ func IpBlack() gin.HandlerFunc {
 			return
 		}
 
-		// 检查是否在封禁期内
-		var ipBlack model.IpBlack
-		facade.DB.Model(&model.IpBlack{}).Where("ip", ip).Scan(&ipBlack)
-
-		if ipBlack.Id > 0 && ipBlack.IsBanned() {
+		// 检查是否命中处于封禁期内的规则
+		if ipBlack := model.MatchIpBlack(ip); ipBlack != nil {
 			// 计算剩余时间
 			remaining := ""
 			if !ipBlack.IsPermanent && ipBlack.ExpireTime > 0 {
@@ -104,6 +104,17 @@ func validatePasswordHash(jwtHash, userPassword any) error {
 	return nil
 }
 
+// validateSession 验证访问令牌所属的登录会话（退出登录、下线设备、封禁后会话立即失效）
+func validateSession(ctx *gin.Context, data map[string]any) error {
+	sid := cast.ToString(data["sid"])
+	if !model.UserSessionActive(sid, cast.ToInt(data["uid"])) {
+		return model.ErrSessionInvalid
+	}
+	ctx.Set("sid", sid)
+	go model.TouchUserSession(sid, ctx.ClientIP())
+	return nil
+}
+
 // abortWithError 统一错误响应处理
 func abortWithError(ctx *gin.Context, tokenName string, code int, msg string) {
 	ctx.SetCookie(tokenName, "", -1, "/", "", false, false)
@@ -132,6 +143,11 @@ func Jwt() gin.HandlerFunc {
 			return
 		}
 
+		if err := validateSession(ctx, jwtResult.Data); err != nil {
+			abortWithError(ctx, tokenName, 401, facade.Lang(ctx, err.Error()))
+			return
+		}
+
 		user, err := getUserInfoWithCache(jwtResult.Data["uid"], jwtResult.Valid)
 		if err != nil {
 			abortWithError(ctx, tokenName, 401, err.Error())
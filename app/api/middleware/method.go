@@ -44,6 +44,11 @@ func Method() gin.HandlerFunc {
 			return
 		}
 
+		if err := validateSession(ctx, jwt.Data); err != nil {
+			abortWithError(ctx, tokenName, 401, facade.Lang(ctx, err.Error()))
+			return
+		}
+
 		uid := jwt.Data["uid"]
 		user, err := getUserInfoWithCache(uid, jwt.Valid)
 		if err != nil {
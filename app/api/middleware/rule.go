@@ -87,20 +87,31 @@ func Rule() gin.HandlerFunc {
 			return
 		}
 
+		info := map[string]any{}
+		if value, ok := ctx.Get("user"); ok {
+			info = cast.ToStringMap(value)
+		}
+		decision := model.AuthorizeUser(user.Id, info, ctx.Request.Method, ctx.Request.URL.Path, cast.ToString(rule["hash"]))
+		ctx.Set("auth", decision)
+
 		if isLoginRoute(cast.ToString(rule["type"])) {
 			if user.Id == 0 {
 				ctx.JSON(200, gin.H{"code": 401, "msg": facade.Lang(ctx, "请先登录！"), "data": nil})
 				ctx.Abort()
 				return
 			}
+			// 登录即可访问的接口仍受拒绝策略约束，带数据条件的拒绝只有控制器判断时才能放行
+			if decision.Deny || (decision.PendingDeny() && !model.AuthCheckRoute(ctx.Request.Method, ctx.Request.URL.Path)) {
+				ctx.JSON(200, gin.H{"code": 403, "msg": facade.Lang(ctx, "无权限！"), "data": nil})
+				ctx.Abort()
+				return
+			}
 			ctx.Next()
 			return
 		}
 
-		rules := (&model.Users{}).Rules(user.Id)
-		name := fmt.Sprintf("[%v][%v]", strings.ToUpper(ctx.Request.Method), ctx.Request.URL.Path)
-
-		if !utils.InArray[any](name, rules) {
+		// 拒绝优先，其次由分组的规则哈希（或 all）及策略允许；取决于数据条件时，控制器不判断条件的接口一律拒绝
+		if !decision.Allow || (decision.Conditional() && !model.AuthCheckRoute(ctx.Request.Method, ctx.Request.URL.Path)) {
 			ctx.JSON(200, gin.H{"code": 403, "msg": facade.Lang(ctx, "无权限！"), "data": nil})
 			ctx.Abort()
 			return
@@ -0,0 +1,138 @@
+package middleware
+
+import (
+	"fmt"
+	"inis/app/client"
+	"inis/app/facade"
+	"inis/app/model"
+	"math"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/spf13/cast"
+	"github.com/unti-io/go-utils/utils"
+)
+
+// signatureNonceMutex - 检查并记录 nonce 时的互斥锁
+var signatureNonceMutex = &sync.Mutex{}
+
+// signatureRoute - 接口是否需要签名（routes 为空时全部接口都需要）
+func signatureRoute(routes []string, method, path string) bool {
+	if len(routes) == 0 {
+		return true
+	}
+	for _, item := range routes {
+		if model.ApiKeyScopeMatch(item, method, path, "") {
+			return true
+		}
+	}
+	return false
+}
+
+// signatureAbort - 输出签名校验失败的响应
+func signatureAbort(ctx *gin.Context, msg string) {
+	ctx.AbortWithStatusJSON(200, gin.H{"code": 401, "msg": facade.Lang(ctx, msg), "data": nil})
+}
+
+// signatureVerify - 校验请求签名，通过后记录 nonce 并返回签名使用的接口密钥；失败时返回错误提示
+func signatureVerify(ctx *gin.Context) (*model.ApiKeys, string) {
+
+	keyId := ctx.GetHeader(client.HeaderKeyId)
+	timestamp := ctx.GetHeader(client.HeaderTimestamp)
+	nonce := ctx.GetHeader(client.HeaderNonce)
+	signature := ctx.GetHeader(client.HeaderSignature)
+
+	if utils.Is.Empty(keyId) || utils.Is.Empty(timestamp) || utils.Is.Empty(nonce) || utils.Is.Empty(signature) {
+		return nil, "缺少签名参数！"
+	}
+
+	if len(nonce) < 8 || len(nonce) > 64 {
+		return nil, "nonce 长度须为 8 到 64 位！"
+	}
+
+	window := cast.ToInt64(facade.AppToml.Get("signature.window", 300))
+	if window <= 0 {
+		window = 300
+	}
+	if math.Abs(float64(time.Now().Unix()-cast.ToInt64(timestamp))) > float64(window) {
+		return nil, "签名已过期，请校准时间后重试！"
+	}
+
+	// 签名密钥独立于 API_KEY 生成，数据库中只保存其密文
+	item := model.FindApiKeyByKeyId(keyId)
+	if item == nil {
+		return nil, "签名错误！"
+	}
+	secret, err := item.SignSecret()
+	if err != nil {
+		facade.Log.Error(map[string]any{"error": err, "id": item.Id}, "接口签名密钥解密失败")
+		return nil, "签名错误！"
+	}
+
+	var params map[string]any
+	if value, ok := ctx.Get("params"); ok {
+		params = cast.ToStringMap(value)
+	}
+
+	if !client.Verify(secret, signature, ctx.Request.Method, ctx.Request.URL.Path, params, timestamp, nonce) {
+		return nil, "签名错误！"
+	}
+
+	// 签名通过后再记录 nonce，避免伪造的请求占用 nonce
+	cacheName := fmt.Sprintf("signature[nonce][%d][%s]", item.Id, nonce)
+	signatureNonceMutex.Lock()
+	if facade.Cache.Has(cacheName) {
+		signatureNonceMutex.Unlock()
+		return nil, "请求重复，请勿重放！"
+	}
+	facade.Cache.Set(cacheName, timestamp, time.Duration(window*2)*time.Second)
+	signatureNonceMutex.Unlock()
+
+	ctx.Set("signed", item.Id)
+	return item, ""
+}
+
+// Signature - 接口签名校验中间件（HMAC-SHA256，拒绝过期的时间戳与重复的 nonce）
+//
+// 不传 routes 时由 signature 配置决定是否开启及需要签名的接口；
+// 传入 routes 时（如在路由组上单独使用）始终对匹配的接口校验签名。
+func Signature(routes ...string) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+
+		list := routes
+		if len(list) == 0 {
+			if !cast.ToBool(facade.AppToml.Get("signature.enable", false)) {
+				ctx.Next()
+				return
+			}
+			list = cast.ToStringSlice(facade.AppToml.Get("signature.routes", []string{}))
+		}
+
+		method, path := ctx.Request.Method, ctx.Request.URL.Path
+		if method == "OPTIONS" || !signatureRoute(list, method, path) {
+			ctx.Next()
+			return
+		}
+
+		// 已在接口密钥中间件中完成校验（请求只携带了签名密钥ID）
+		if _, ok := ctx.Get("signed"); ok {
+			ctx.Next()
+			return
+		}
+
+		item, msg := signatureVerify(ctx)
+		if item == nil {
+			signatureAbort(ctx, msg)
+			return
+		}
+
+		// 同时携带 API_KEY 时，签名须由同一密钥签发
+		if id, ok := ctx.Get("api-key"); ok && cast.ToInt(id) != item.Id {
+			signatureAbort(ctx, "签名错误！")
+			return
+		}
+
+		ctx.Next()
+	}
+}
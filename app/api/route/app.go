@@ -16,8 +16,11 @@ var defaultMiddleware = []gin.HandlerFunc{
 	global.QpsGlobal(),
 	global.Params(),
 	middle.Jwt(),
+	middle.Audit(),
 	middle.Rule(),
 	middle.ApiKey(),
+	middle.Signature(),
+	middle.Captcha(),
 	middle.Restriction(),
 }
 
@@ -56,7 +59,24 @@ var controllers = map[string]controller.ApiInterface{
 	"user-likes":    &controller.UserLikes{},
 	"user-collects": &controller.UserCollects{},
 	"user-follows":  &controller.UserFollows{},
+	"user-sessions": &controller.UserSessions{},
+	"totp":          &controller.Totp{},
+	"passkey":       &controller.Passkey{},
 	"notification":  &controller.Notification{},
+	"webhook":       &controller.Webhook{},
+	"outbox":        &controller.Outbox{},
+	"oidc":          &controller.OIDC{},
+	"oauth-clients": &controller.OAuthClients{},
+	"audit-log":     &controller.AuditLog{},
+	"captcha":       &controller.Captcha{},
+}
+
+// 授权服务的协议端点（OAuth2 / OpenID Connect）由第三方应用以客户端凭证或 Bearer 令牌访问，不经过登录与权限中间件
+var oidcMiddleware = []gin.HandlerFunc{
+	middle.IpBlack(),
+	global.QpsPoint(),
+	global.QpsGlobal(),
+	global.Params(),
 }
 
 // registerRoutes 注册路由
@@ -77,4 +97,13 @@ func registerRoutes(group gin.IRoutes, controllers map[string]controller.ApiInte
 func Route(Gin *gin.Engine) {
 	group := Gin.Group("/api/", defaultMiddleware...)
 	registerRoutes(group, controllers)
+
+	oidc := &controller.OIDC{}
+	provider := Gin.Group("/", oidcMiddleware...)
+	provider.GET(".well-known/openid-configuration", oidc.Discovery)
+	provider.GET(".well-known/jwks.json", oidc.JWKS)
+	provider.POST("oauth2/token", oidc.Token)
+	provider.GET("oauth2/userinfo", oidc.UserInfo)
+	provider.POST("oauth2/userinfo", oidc.UserInfo)
+	provider.POST("oauth2/revoke", oidc.Revoke)
 }
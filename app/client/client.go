@@ -0,0 +1,137 @@
+package client
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Response - 接口的统一响应
+type Response struct {
+	Code int             `json:"code"`
+	Msg  string          `json:"msg"`
+	Data json.RawMessage `json:"data"`
+}
+
+// Client - 带签名的 inis 接口客户端
+//
+//	item := client.New("https://example.com", "YOUR-KEY-ID", "YOUR-SECRET")
+//	result, err := item.Get("/api/article/one", map[string]any{"id": 1})
+type Client struct {
+	// 站点地址，如 https://example.com
+	Endpoint string
+	// 签名密钥ID与签名密钥（创建或重置接口密钥时返回）
+	KeyId  string
+	Secret string
+	HTTP   *http.Client
+}
+
+// New - 创建客户端
+func New(endpoint, keyId, secret string) *Client {
+	return &Client{
+		Endpoint: strings.TrimRight(endpoint, "/"),
+		KeyId:    keyId,
+		Secret:   secret,
+		HTTP:     &http.Client{Timeout: 30 * time.Second},
+	}
+}
+
+// Sign - 为请求生成签名相关的请求头
+func (this *Client) Sign(method, path string, params map[string]any) map[string]string {
+
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+	nonce := Nonce()
+
+	// 只发送签名密钥ID，签名密钥不离开客户端
+	return map[string]string{
+		HeaderKeyId:     this.KeyId,
+		HeaderTimestamp: timestamp,
+		HeaderNonce:     nonce,
+		HeaderSignature: "sha256=" + Sign(this.Secret, method, path, params, timestamp, nonce),
+	}
+}
+
+// Do - 发送请求（GET 的参数放在查询串，其它请求以 JSON 提交）
+func (this *Client) Do(method, path string, params map[string]any) (*Response, error) {
+
+	method = strings.ToUpper(method)
+	if params == nil {
+		params = map[string]any{}
+	}
+
+	target := this.Endpoint + path
+	var body io.Reader
+	if method == http.MethodGet {
+		// 服务端从查询串解析出的值均为字符串，签名前先做同样的转换
+		values := url.Values{}
+		query := make(map[string]any, len(params))
+		for key, value := range params {
+			values.Set(key, Value(value))
+			query[key] = Value(value)
+		}
+		if len(values) > 0 {
+			target += "?" + values.Encode()
+		}
+		params = query
+	} else {
+		data, err := json.Marshal(params)
+		if err != nil {
+			return nil, err
+		}
+		body = bytes.NewReader(data)
+	}
+
+	request, err := http.NewRequest(method, target, body)
+	if err != nil {
+		return nil, err
+	}
+	if body != nil {
+		request.Header.Set("Content-Type", "application/json")
+	}
+	for key, value := range this.Sign(method, path, params) {
+		request.Header.Set(key, value)
+	}
+
+	response, err := this.HTTP.Do(request)
+	if err != nil {
+		return nil, err
+	}
+	defer response.Body.Close()
+
+	data, err := io.ReadAll(response.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &Response{}
+	if err := json.Unmarshal(data, result); err != nil {
+		return nil, fmt.Errorf("HTTP %d: %s", response.StatusCode, string(data))
+	}
+	return result, nil
+}
+
+// Get - 发送 GET 请求
+func (this *Client) Get(path string, params map[string]any) (*Response, error) {
+	return this.Do(http.MethodGet, path, params)
+}
+
+// Post - 发送 POST 请求
+func (this *Client) Post(path string, params map[string]any) (*Response, error) {
+	return this.Do(http.MethodPost, path, params)
+}
+
+// Put - 发送 PUT 请求
+func (this *Client) Put(path string, params map[string]any) (*Response, error) {
+	return this.Do(http.MethodPut, path, params)
+}
+
+// Delete - 发送 DELETE 请求
+func (this *Client) Delete(path string, params map[string]any) (*Response, error) {
+	return this.Do(http.MethodDelete, path, params)
+}
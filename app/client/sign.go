@@ -0,0 +1,104 @@
+// Package client - inis 接口的 Go 客户端，负责按签名密钥对请求签名
+//
+// 创建或重置接口密钥时，服务端会额外返回签名密钥ID（key_id）与签名密钥（secret），
+// 签名密钥与 API_KEY 相互独立且只返回一次，请求中只携带签名密钥ID，不发送 API_KEY 与签名密钥。
+//
+// 签名算法（服务端的签名中间件使用同一实现）：
+//
+//	canonical = METHOD + "\n" + PATH + "\n" + 排序后的参数 + "\n" + timestamp + "\n" + nonce
+//	signature = hex(HMAC-SHA256(secret, canonical))
+//
+// 参数按键名排序后以 key=value 用 & 连接（键和值均经过 url.QueryEscape），
+// 字符串原样使用，数字与布尔值取字面量，对象和数组取 JSON 编码，i-api-key 参数不参与签名。
+package client
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// 签名相关的请求头
+const (
+	HeaderApiKey    = "i-api-key"
+	HeaderKeyId     = "X-Inis-Key-Id"
+	HeaderTimestamp = "X-Inis-Timestamp"
+	HeaderNonce     = "X-Inis-Nonce"
+	HeaderSignature = "X-Inis-Signature"
+)
+
+// Nonce - 生成随机串（32位十六进制）
+func Nonce() string {
+	buf := make([]byte, 16)
+	_, _ = rand.Read(buf)
+	return hex.EncodeToString(buf)
+}
+
+// Value - 参数值的规范化字符串
+func Value(value any) string {
+	switch val := value.(type) {
+	case nil:
+		return ""
+	case string:
+		return val
+	case bool:
+		return strconv.FormatBool(val)
+	case float64:
+		return strconv.FormatFloat(val, 'f', -1, 64)
+	case float32:
+		return strconv.FormatFloat(float64(val), 'f', -1, 32)
+	case int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64:
+		return fmt.Sprintf("%d", val)
+	case json.Number:
+		return val.String()
+	}
+	data, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Sprint(value)
+	}
+	return string(data)
+}
+
+// Params - 排序后的参数串
+func Params(params map[string]any) string {
+
+	keys := make([]string, 0, len(params))
+	for key := range params {
+		if key == HeaderApiKey {
+			continue
+		}
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	items := make([]string, 0, len(keys))
+	for _, key := range keys {
+		items = append(items, url.QueryEscape(key)+"="+url.QueryEscape(Value(params[key])))
+	}
+	return strings.Join(items, "&")
+}
+
+// Canonical - 待签名的字符串
+func Canonical(method, path string, params map[string]any, timestamp, nonce string) string {
+	return strings.Join([]string{strings.ToUpper(method), path, Params(params), timestamp, nonce}, "\n")
+}
+
+// Sign - 计算签名
+func Sign(secret, method, path string, params map[string]any, timestamp, nonce string) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(Canonical(method, path, params, timestamp, nonce)))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// Verify - 校验签名（签名可带 sha256= 前缀）
+func Verify(secret, signature, method, path string, params map[string]any, timestamp, nonce string) bool {
+	expect := Sign(secret, method, path, params, timestamp, nonce)
+	return hmac.Equal([]byte(strings.TrimPrefix(strings.ToLower(signature), "sha256=")), []byte(expect))
+}
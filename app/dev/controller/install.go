@@ -219,35 +219,28 @@ func (this *Install) createAdmin(ctx *gin.Context) {
 	// 创建用户
 	facade.DB.Model(&table).Create(&table)
 
-	jwt := facade.Jwt().Create(facade.H{
-		"uid": table.Id,
-	})
+	// 创建登录会话并签发访问令牌
+	session, refresh, err := model.CreateUserSession(table.Id, ctx.ClientIP(), ctx.Request.UserAgent())
+	if err != nil {
+		this.error(ctx, err.Error())
+		return
+	}
+	jwt := session.AccessToken(table.Password)
 
 	// 删除密码
 	table.Password = ""
 
 	result := map[string]any{
-		"user":  table,
-		"token": jwt.Text,
+		"user":          table,
+		"token":         jwt.Text,
+		"refresh_token": refresh,
 	}
 
 	// 往客户端写入 cookie
 	this.setToken(ctx, jwt.Text)
 
 	// 异步添加到管理员组
-	go func(uid string) {
-		uids := []string{uid}
-		group, _ := facade.DB.Model(&model.AuthGroup{}).Find(1)
-		if !utils.Is.Empty(group) {
-			uids = append(uids, strings.Split(cast.ToString(group["uids"]), "|")...)
-		}
-
-		// 去重去空
-		uids = cast.ToStringSlice(utils.ArrayUnique(utils.ArrayEmpty(uids)))
-		facade.DB.Model(&model.AuthGroup{}).Where("id", 1).Update(&model.AuthGroup{
-			Uids: fmt.Sprintf("|%s|", strings.Join(uids, "|")),
-		})
-	}(cast.ToString(table.Id))
+	go model.AddUserGroups(table.Id, 1)
 
 	this.json(ctx, result, facade.Lang(ctx, "注册成功！"), DefaultSuccessCode)
 }
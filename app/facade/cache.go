@@ -2,6 +2,7 @@ package facade
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"path/filepath"
 	"reflect"
@@ -50,15 +51,35 @@ func NewCache(mode any) CacheInterface {
 
 // Cache - Cache实例
 var Cache CacheInterface
+
 var Redis *RedisCacheStruct
 var FileCache *FileCacheStruct
 var BigCache *BigCacheStruct
 
+// cacheAtomic - 文件与内存缓存仅在本进程内共享，原子操作以进程内互斥锁串行化
+var cacheAtomic sync.Mutex
+
+// cacheTake - 基于 Update 实现的读取并删除
+func cacheTake(cache CacheInterface, key any) any {
+	var item any
+	if !cache.Update(key, func(value any) (any, time.Duration) {
+		item = value
+		return nil, 0
+	}) {
+		return nil
+	}
+	return item
+}
+
 type CacheInterface interface {
 	Has(key any) bool
 	Get(key any) any
 	Set(key any, value any, expire ...any) bool
 	Del(key any) bool
+	// Take - 读取并删除（原子操作，并发时只有一个调用方能取到值）
+	Take(key any) any
+	// Update - 读取、修改并写回（原子操作）；fn 返回 nil 时删除该键
+	Update(key any, fn func(value any) (next any, expire time.Duration)) bool
 	DelPrefix(prefix ...any) bool
 	DelTags(tag ...any) bool
 	Clear() bool
@@ -244,6 +265,43 @@ func (this *RedisCacheStruct) Del(key any) bool {
 	return utils.Ternary[bool](err != nil, false, true)
 }
 
+func (this *RedisCacheStruct) Take(key any) any {
+	return cacheTake(this, key)
+}
+
+// Update - 基于 WATCH/MULTI 的乐观事务，键在读取后被其他客户端修改时重试
+func (this *RedisCacheStruct) Update(key any, fn func(value any) (next any, expire time.Duration)) bool {
+	ctx := context.Background()
+	name := this.Prefix + cast.ToString(key)
+
+	for i := 0; i < 10; i++ {
+		err := this.Client.Watch(ctx, func(tx *redis.Tx) error {
+			var value any
+			result, err := tx.Get(ctx, name).Result()
+			if err != nil && !errors.Is(err, redis.Nil) {
+				return err
+			}
+			if err == nil {
+				value = utils.Json.Decode(result)
+			}
+			next, expire := fn(value)
+			_, err = tx.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+				if next == nil {
+					pipe.Del(ctx, name)
+				} else {
+					pipe.Set(ctx, name, utils.Json.Encode(next), utils.Ternary(expire > 0, expire, this.Expire))
+				}
+				return nil
+			})
+			return err
+		}, name)
+		if !errors.Is(err, redis.TxFailedErr) {
+			return err == nil
+		}
+	}
+	return false
+}
+
 func (this *RedisCacheStruct) DelPrefix(prefix ...any) bool {
 	ctx := context.Background()
 	var keys []string
@@ -412,6 +470,27 @@ func (this *FileCacheStruct) Del(key any) bool {
 	return this.Client.Del(key)
 }
 
+func (this *FileCacheStruct) Take(key any) any {
+	return cacheTake(this, key)
+}
+
+func (this *FileCacheStruct) Update(key any, fn func(value any) (next any, expire time.Duration)) bool {
+	if this.Client == nil {
+		return false
+	}
+	cacheAtomic.Lock()
+	defer cacheAtomic.Unlock()
+
+	next, expire := fn(this.Get(key))
+	if next == nil {
+		return this.Del(key)
+	}
+	if expire > 0 {
+		return this.Set(key, next, expire)
+	}
+	return this.Set(key, next)
+}
+
 func (this *FileCacheStruct) DelPrefix(prefix ...any) bool {
 	if this.Client == nil {
 		return false
@@ -509,6 +588,27 @@ func (this *BigCacheStruct) Del(key any) bool {
 	return this.Client.Del(key)
 }
 
+func (this *BigCacheStruct) Take(key any) any {
+	return cacheTake(this, key)
+}
+
+func (this *BigCacheStruct) Update(key any, fn func(value any) (next any, expire time.Duration)) bool {
+	if this.Client == nil {
+		return false
+	}
+	cacheAtomic.Lock()
+	defer cacheAtomic.Unlock()
+
+	next, expire := fn(this.Get(key))
+	if next == nil {
+		return this.Del(key)
+	}
+	if expire > 0 {
+		return this.Set(key, next, expire)
+	}
+	return this.Set(key, next)
+}
+
 func (this *BigCacheStruct) DelPrefix(prefix ...any) bool {
 	if this.Client == nil {
 		return false
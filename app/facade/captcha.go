@@ -0,0 +1,207 @@
+package facade
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"image"
+	"image/color"
+	"image/png"
+	"math"
+	"math/bits"
+	"math/rand"
+)
+
+// CaptchaChars - 图形验证码使用的字符（去掉了 0/O、1/I 等易混淆的字符）
+const CaptchaChars = "23456789ABCDEFGHJKLMNPQRSTUVWXYZ"
+
+// captchaFont - 5x7 点阵字体
+var captchaFont = map[rune][7]string{
+	'0': {"01110", "10001", "10011", "10101", "11001", "10001", "01110"},
+	'1': {"00100", "01100", "00100", "00100", "00100", "00100", "01110"},
+	'2': {"01110", "10001", "00001", "00010", "00100", "01000", "11111"},
+	'3': {"11110", "00001", "00001", "01110", "00001", "00001", "11110"},
+	'4': {"00010", "00110", "01010", "10010", "11111", "00010", "00010"},
+	'5': {"11111", "10000", "11110", "00001", "00001", "10001", "01110"},
+	'6': {"00110", "01000", "10000", "11110", "10001", "10001", "01110"},
+	'7': {"11111", "00001", "00010", "00100", "01000", "01000", "01000"},
+	'8': {"01110", "10001", "10001", "01110", "10001", "10001", "01110"},
+	'9': {"01110", "10001", "10001", "01111", "00001", "00010", "01100"},
+	'A': {"01110", "10001", "10001", "11111", "10001", "10001", "10001"},
+	'B': {"11110", "10001", "10001", "11110", "10001", "10001", "11110"},
+	'C': {"01110", "10001", "10000", "10000", "10000", "10001", "01110"},
+	'D': {"11100", "10010", "10001", "10001", "10001", "10010", "11100"},
+	'E': {"11111", "10000", "10000", "11110", "10000", "10000", "11111"},
+	'F': {"11111", "10000", "10000", "11110", "10000", "10000", "10000"},
+	'G': {"01110", "10001", "10000", "10111", "10001", "10001", "01111"},
+	'H': {"10001", "10001", "10001", "11111", "10001", "10001", "10001"},
+	'J': {"00111", "00010", "00010", "00010", "00010", "10010", "01100"},
+	'K': {"10001", "10010", "10100", "11000", "10100", "10010", "10001"},
+	'L': {"10000", "10000", "10000", "10000", "10000", "10000", "11111"},
+	'M': {"10001", "11011", "10101", "10101", "10001", "10001", "10001"},
+	'N': {"10001", "10001", "11001", "10101", "10011", "10001", "10001"},
+	'P': {"11110", "10001", "10001", "11110", "10000", "10000", "10000"},
+	'Q': {"01110", "10001", "10001", "10001", "10101", "10010", "01101"},
+	'R': {"11110", "10001", "10001", "11110", "10100", "10010", "10001"},
+	'S': {"01111", "10000", "10000", "01110", "00001", "00001", "11110"},
+	'T': {"11111", "00100", "00100", "00100", "00100", "00100", "00100"},
+	'U': {"10001", "10001", "10001", "10001", "10001", "10001", "01110"},
+	'V': {"10001", "10001", "10001", "10001", "10001", "01010", "00100"},
+	'W': {"10001", "10001", "10001", "10101", "10101", "10101", "01010"},
+	'X': {"10001", "10001", "01010", "00100", "01010", "10001", "10001"},
+	'Y': {"10001", "10001", "01010", "00100", "00100", "00100", "00100"},
+	'Z': {"11111", "00001", "00010", "00100", "01000", "10000", "11111"},
+	'+': {"00000", "00100", "00100", "11111", "00100", "00100", "00000"},
+	'-': {"00000", "00000", "00000", "11111", "00000", "00000", "00000"},
+	'x': {"00000", "10001", "01010", "00100", "01010", "10001", "00000"},
+	'=': {"00000", "00000", "11111", "00000", "11111", "00000", "00000"},
+	'?': {"01110", "10001", "00001", "00010", "00100", "00000", "00100"},
+	' ': {"00000", "00000", "00000", "00000", "00000", "00000", "00000"},
+}
+
+// CaptchaText - 生成 length 位随机字符
+func CaptchaText(length int) string {
+	result := make([]byte, length)
+	for i := range result {
+		result[i] = CaptchaChars[rand.Intn(len(CaptchaChars))]
+	}
+	return string(result)
+}
+
+// CaptchaMath - 生成算术题，返回题目（如 "7 + 5 = ?"）与答案
+func CaptchaMath() (question string, answer int) {
+	a, b := rand.Intn(9)+1, rand.Intn(9)+1
+	switch rand.Intn(3) {
+	case 0:
+		return string(rune('0'+a)) + " + " + string(rune('0'+b)) + " = ?", a + b
+	case 1:
+		if a < b {
+			a, b = b, a
+		}
+		return string(rune('0'+a)) + " - " + string(rune('0'+b)) + " = ?", a - b
+	}
+	return string(rune('0'+a)) + " x " + string(rune('0'+b)) + " = ?", a * b
+}
+
+// CaptchaImage - 将文字绘制为带干扰的 PNG 图片
+/**
+ * @param text 文字（仅支持 captchaFont 中的字符）
+ * @param width 图片宽度
+ * @param height 图片高度
+ */
+func CaptchaImage(text string, width, height int) ([]byte, error) {
+
+	canvas := image.NewRGBA(image.Rect(0, 0, width, height))
+	background := color.RGBA{R: uint8(235 + rand.Intn(20)), G: uint8(235 + rand.Intn(20)), B: uint8(235 + rand.Intn(20)), A: 255}
+	for x := 0; x < width; x++ {
+		for y := 0; y < height; y++ {
+			canvas.Set(x, y, background)
+		}
+	}
+
+	runes := []rune(text)
+	if len(runes) > 0 {
+		// 每个字符占 5 列点阵加 1 列间距，按图片大小计算点阵的缩放
+		cell := width / (len(runes) * 6)
+		if limit := height * 7 / 10 / 7; cell > limit {
+			cell = limit
+		}
+		if cell < 1 {
+			cell = 1
+		}
+		left := (width - len(runes)*6*cell) / 2
+		for i, char := range runes {
+			ink := color.RGBA{R: uint8(rand.Intn(120)), G: uint8(rand.Intn(120)), B: uint8(rand.Intn(120)), A: 255}
+			offsetX := left + i*6*cell + rand.Intn(cell+1) - cell/2
+			offsetY := (height-7*cell)/2 + rand.Intn(cell*2+1) - cell
+			captchaGlyph(canvas, captchaFont[char], offsetX, offsetY, cell, ink)
+		}
+	}
+
+	// 干扰线
+	for i := 0; i < 4; i++ {
+		ink := color.RGBA{R: uint8(rand.Intn(200)), G: uint8(rand.Intn(200)), B: uint8(rand.Intn(200)), A: 255}
+		captchaLine(canvas, rand.Intn(width), rand.Intn(height), rand.Intn(width), rand.Intn(height), ink)
+	}
+	// 干扰点
+	for i := 0; i < width*height/30; i++ {
+		canvas.Set(rand.Intn(width), rand.Intn(height), color.RGBA{R: uint8(rand.Intn(255)), G: uint8(rand.Intn(255)), B: uint8(rand.Intn(255)), A: 255})
+	}
+
+	buffer := &bytes.Buffer{}
+	if err := png.Encode(buffer, captchaWave(canvas)); err != nil {
+		return nil, err
+	}
+	return buffer.Bytes(), nil
+}
+
+// captchaGlyph - 绘制单个字符
+func captchaGlyph(canvas *image.RGBA, glyph [7]string, left, top, cell int, ink color.RGBA) {
+	for row, line := range glyph {
+		for col, dot := range line {
+			if dot != '1' {
+				continue
+			}
+			for x := 0; x < cell; x++ {
+				for y := 0; y < cell; y++ {
+					canvas.Set(left+col*cell+x, top+row*cell+y, ink)
+				}
+			}
+		}
+	}
+}
+
+// captchaLine - 绘制直线
+func captchaLine(canvas *image.RGBA, x0, y0, x1, y1 int, ink color.RGBA) {
+	steps := int(math.Max(math.Abs(float64(x1-x0)), math.Abs(float64(y1-y0))))
+	if steps == 0 {
+		return
+	}
+	for i := 0; i <= steps; i++ {
+		x := x0 + (x1-x0)*i/steps
+		y := y0 + (y1-y0)*i/steps
+		canvas.Set(x, y, ink)
+		canvas.Set(x, y+1, ink)
+	}
+}
+
+// captchaWave - 正弦波扭曲，增加识别难度
+func captchaWave(canvas *image.RGBA) *image.RGBA {
+	bounds := canvas.Bounds()
+	result := image.NewRGBA(bounds)
+	amplitude := 1 + rand.Float64()*1.5
+	period := 60 + rand.Float64()*40
+	phase := rand.Float64() * math.Pi * 2
+	for x := 0; x < bounds.Dx(); x++ {
+		shift := int(amplitude * math.Sin(float64(x)/period*math.Pi*2+phase))
+		for y := 0; y < bounds.Dy(); y++ {
+			source := y + shift
+			if source < 0 || source >= bounds.Dy() {
+				result.Set(x, y, canvas.At(x, y))
+				continue
+			}
+			result.Set(x, y, canvas.At(x, source))
+		}
+	}
+	return result
+}
+
+// CaptchaPowValid - 工作量证明是否有效：SHA-256(salt + nonce) 的前导零位数不少于 difficulty
+func CaptchaPowValid(salt, nonce string, difficulty int) bool {
+
+	if nonce == "" || len(nonce) > 64 {
+		return false
+	}
+
+	sum := sha256.Sum256([]byte(salt + nonce))
+	zeros := 0
+	for _, item := range sum {
+		if item == 0 {
+			zeros += 8
+			continue
+		}
+		zeros += bits.LeadingZeros8(item)
+		break
+	}
+
+	return zeros >= difficulty
+}
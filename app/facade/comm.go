@@ -1,8 +1,10 @@
 package facade
 
 import (
+	"context"
 	"errors"
 	"net"
+	"net/http"
 	"net/url"
 	"regexp"
 	"runtime"
@@ -189,6 +191,56 @@ func (c *CommStruct) MaskSecret(secret string) string {
 	return string(runes[:4]) + "****" + string(runes[length-4:])
 }
 
+// SensitiveFields - 敏感字段名集合（密钥、密码等，返回给前端或写入审计日志时需脱敏）
+var SensitiveFields = map[string]bool{
+	"password":          true, // mysql/redis/email 密码、用户密码
+	"access_key_secret": true, // 阿里云 AccessKey Secret
+	"secret_key":        true, // 腾讯云/七牛云 SecretKey
+	"access_key":        true, // 七牛云 AccessKey
+	"access_key_id":     true, // AccessKey ID
+	"secret_id":         true, // 腾讯云 SecretId
+	"key":               true, // JWT 签名密钥（crypt 配置的 jwt 段与 jwt_keys 密钥环）
+	"private_key":       true, // VAPID 与 OIDC 私钥
+	"headers":           true, // HTTP 短信网关请求头（通常包含鉴权令牌）
+	"secret":            true, // Webhook 签名密钥、两步验证密钥
+	"client_secret":     true, // 第三方应用密钥
+	"token":             true, // 访问令牌
+	"access_token":      true, // 访问令牌
+	"refresh_token":     true, // 刷新令牌
+	"recovery_codes":    true, // 两步验证恢复码
+	"api_key":           true, // 接口密钥明文（创建与重置时返回）
+}
+
+// MaskSensitive 递归脱敏 map/slice 中的敏感字段（返回副本，不修改原数据）
+func (c *CommStruct) MaskSensitive(data any) any {
+	switch val := data.(type) {
+	case map[string]any:
+		result := make(map[string]any, len(val))
+		for key, value := range val {
+			if SensitiveFields[strings.ToLower(key)] {
+				result[key] = c.MaskSecret(cast.ToString(value))
+			} else {
+				result[key] = c.MaskSensitive(value)
+			}
+		}
+		return result
+	case []map[string]any:
+		result := make([]any, len(val))
+		for i, value := range val {
+			result[i] = c.MaskSensitive(value)
+		}
+		return result
+	case []any:
+		result := make([]any, len(val))
+		for i, value := range val {
+			result[i] = c.MaskSensitive(value)
+		}
+		return result
+	default:
+		return data
+	}
+}
+
 // MaskUA User-Agent脱敏处理
 func (c *CommStruct) MaskUA(ua string) string {
 	if ua == "" {
@@ -328,6 +380,15 @@ func (c *CommStruct) generateSecureHeaders(body map[string]any, unix int64) map[
 	}
 }
 
+// Truncate 按字符（而非字节）截断字符串，避免超出数据库字段长度
+func (c *CommStruct) Truncate(value string, size int) string {
+	runes := []rune(value)
+	if len(runes) > size {
+		return string(runes[:size])
+	}
+	return value
+}
+
 // IsPrivateIP 判断 IP 是否为内网/回环/链路本地/保留地址，用于 SSRF 防护
 func (c *CommStruct) IsPrivateIP(ipStr string) bool {
 	ip := net.ParseIP(strings.TrimSpace(ipStr))
@@ -394,3 +455,49 @@ func (c *CommStruct) IsSafeOutboundURL(rawURL string) error {
 
 	return nil
 }
+
+// SafeOutboundClient SSRF 防护：返回只会连接公网地址的 HTTP 客户端
+// 在建立连接时校验实际解析出的 IP 并直接连接该 IP，防止校验与连接之间 DNS 变更（DNS rebinding）；不跟随重定向，不使用环境变量中的代理
+func (c *CommStruct) SafeOutboundClient(timeout time.Duration) *http.Client {
+
+	dialer := &net.Dialer{Timeout: timeout, KeepAlive: 30 * time.Second}
+
+	transport := &http.Transport{
+		DialContext: func(ctx context.Context, network, address string) (net.Conn, error) {
+
+			host, port, err := net.SplitHostPort(address)
+			if err != nil {
+				return nil, err
+			}
+
+			ips, err := net.DefaultResolver.LookupIPAddr(ctx, host)
+			if err != nil || len(ips) == 0 {
+				return nil, errors.New("目标主机无法解析！")
+			}
+			for _, ip := range ips {
+				if c.IsPrivateIP(ip.IP.String()) {
+					return nil, errors.New("禁止访问内网地址！")
+				}
+			}
+
+			for _, ip := range ips {
+				var conn net.Conn
+				if conn, err = dialer.DialContext(ctx, network, net.JoinHostPort(ip.IP.String(), port)); err == nil {
+					return conn, nil
+				}
+			}
+			return nil, err
+		},
+		ForceAttemptHTTP2:   true,
+		DisableKeepAlives:   true,
+		TLSHandshakeTimeout: 10 * time.Second,
+	}
+
+	return &http.Client{
+		Timeout:   timeout,
+		Transport: transport,
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+}
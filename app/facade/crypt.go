@@ -1,7 +1,14 @@
 package facade
 
 import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hkdf"
 	"crypto/md5"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
 	"fmt"
 	"os"
 	"strings"
@@ -14,10 +21,11 @@ import (
 )
 
 const (
-	ConfigNameCrypt   = "crypt"
-	DefaultJwtExpire  = "15 * 24 * 60 * 60" // 默认登录会话有效期：15 天
-	DefaultJwtIssuer  = "inis"
-	DefaultJwtSubject = "inis"
+	ConfigNameCrypt        = "crypt"
+	DefaultJwtExpire       = "15 * 24 * 60 * 60" // 默认登录会话（刷新令牌）有效期：15 天
+	DefaultJwtAccessExpire = "30 * 60"           // 默认访问令牌有效期：30 分钟
+	DefaultJwtIssuer       = "inis"
+	DefaultJwtSubject      = "inis"
 )
 
 // CryptToml - Crypt配置文件
@@ -47,11 +55,26 @@ func initCryptToml() {
 		secret = fmt.Sprintf("INIS-%x", md5.Sum([]byte(key)))
 
 		// 确保文件存在（父目录已存在，这里直接写入）
+		publicKey, privateKey, _ := WebPushGenerateKeys()
+		signingKey, _ := OIDCGenerateKey()
+		sealKey, _ := CryptSealGenerateKey()
+		jwtKey, _ := JwtGenerateKey(JwtAlgHS256, secret)
+		keyRing := ""
+		if jwtKey != nil {
+			keyRing = JwtKeysToml([]*JwtKey{jwtKey})
+		}
 		content := utils.Replace(TempCrypt, map[string]any{
-			"${jwt.key}":     secret,
-			"${jwt.expire}":  DefaultJwtExpire,
-			"${jwt.issuer}":  DefaultJwtIssuer,
-			"${jwt.subject}": DefaultJwtSubject,
+			"${jwt.key}":           secret,
+			"${jwt.expire}":        DefaultJwtExpire,
+			"${jwt.access_expire}": DefaultJwtAccessExpire,
+			"${jwt.issuer}":        DefaultJwtIssuer,
+			"${jwt.subject}":       DefaultJwtSubject,
+			"${vapid.public_key}":  publicKey,
+			"${vapid.private_key}": privateKey,
+			"${vapid.subject}":     "",
+			"${oidc.private_key}":  signingKey,
+			"${seal.key}":          sealKey,
+			"${jwt_keys}":          keyRing,
 		})
 		if err := os.WriteFile(filePath, []byte(content), 0755); err != nil {
 			Log.Error(map[string]any{
@@ -107,7 +130,135 @@ func readCryptKey(filePath string) (key string) {
 }
 
 func initCrypt() {
+	initVapid()
+	initOidcKey()
+	initSeal()
+	initJwtKeys()
+}
+
+// CryptSealGenerateKey - 生成加密根密钥（32 字节随机数的 base64url 编码）
+func CryptSealGenerateKey() (string, error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(buf), nil
+}
+
+// initSeal - 补全加密根密钥（旧版本生成的 crypt.toml 中没有 [seal] 配置）
+func initSeal() {
+
+	if CryptToml == nil || CryptToml.Viper == nil {
+		return
+	}
+	if !utils.Is.Empty(CryptToml.Get("seal.key", "")) {
+		return
+	}
+
+	key, err := CryptSealGenerateKey()
+	if err != nil {
+		Log.Error(map[string]any{"error": err}, "加密根密钥生成失败")
+		return
+	}
+
+	// 先写入内存，避免追加文件触发的配置重载前出现空密钥
+	CryptToml.Viper.Set("seal.key", key)
+
+	filePath := fmt.Sprintf("%s/%s.%s", ConfigPath, ConfigNameCrypt, ModeToml)
+	file, err := os.OpenFile(filePath, os.O_APPEND|os.O_WRONLY, 0755)
+	if err != nil {
+		Log.Error(map[string]any{"error": err}, "加密根密钥写入失败")
+		return
+	}
+	defer file.Close()
 
+	content := utils.Replace(TempSeal, map[string]any{
+		"${seal.key}": key,
+	})
+	if _, err := file.WriteString(content); err != nil {
+		Log.Error(map[string]any{"error": err}, "加密根密钥写入失败")
+	}
+}
+
+// cryptKey - 由 seal.key 经 HKDF 派生指定用途的密钥，各用途的密钥互不相同（seal.key 不随 JWT 密钥轮换）
+func cryptKey(purpose string) ([]byte, error) {
+	secret := ""
+	if CryptToml != nil {
+		secret = cast.ToString(CryptToml.Get("seal.key", ""))
+	}
+	if utils.Is.Empty(secret) {
+		return nil, errors.New("加密根密钥未配置")
+	}
+	return hkdf.Key(sha256.New, []byte(secret), nil, "inis "+purpose, 32)
+}
+
+// CryptSeal - 加密需要保存在数据库中的密钥（AES-256-GCM，密钥派生自 crypt.toml 的 seal.key，仅泄露数据库无法还原明文）
+/**
+ * @param purpose 用途，解密时必须一致
+ * @return base64url(nonce || 密文)
+ */
+func CryptSeal(purpose string, plaintext []byte) (string, error) {
+
+	key, err := cryptKey(purpose)
+	if err != nil {
+		return "", err
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return "", err
+	}
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return "", err
+	}
+
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+
+	return base64.RawURLEncoding.EncodeToString(gcm.Seal(nonce, nonce, plaintext, []byte(purpose))), nil
+}
+
+// CryptOpen - 解密 CryptSeal 的结果
+func CryptOpen(purpose, sealed string) ([]byte, error) {
+
+	raw, err := base64.RawURLEncoding.DecodeString(sealed)
+	if err != nil {
+		return nil, err
+	}
+
+	key, err := cryptKey(purpose)
+	if err != nil {
+		return nil, err
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+	if len(raw) < gcm.NonceSize() {
+		return nil, errors.New("密文长度错误")
+	}
+
+	return gcm.Open(nil, raw[:gcm.NonceSize()], raw[gcm.NonceSize():], []byte(purpose))
+}
+
+// JwtSessionExpire - 登录会话（刷新令牌）有效期（秒）
+func JwtSessionExpire() int64 {
+	return cast.ToInt64(utils.Calc(CryptToml.Get("jwt.expire", DefaultJwtExpire)))
+}
+
+// JwtAccessExpire - 访问令牌有效期（秒），不超过登录会话有效期
+func JwtAccessExpire() int64 {
+	expire := cast.ToInt64(utils.Calc(CryptToml.Get("jwt.access_expire", DefaultJwtAccessExpire)))
+	if expire <= 0 {
+		expire = cast.ToInt64(utils.Calc(DefaultJwtAccessExpire))
+	}
+	return min(expire, JwtSessionExpire())
 }
 
 // JWT相关结构体和接口
@@ -153,9 +304,7 @@ func Jwt(request ...JwtRequest) *JwtStruct {
 	if utils.Is.Empty(req.Subject) {
 		req.Subject = cast.ToString(CryptToml.Get("jwt.subject", DefaultJwtSubject))
 	}
-	if utils.Is.Empty(req.Key) {
-		req.Key = cast.ToString(CryptToml.Get("jwt.key", ""))
-	}
+	// 未指定密钥时使用签名密钥环
 
 	return &JwtStruct{
 		request: req,
@@ -168,15 +317,21 @@ func Jwt(request ...JwtRequest) *JwtStruct {
 // Create - 创建JWT
 func (this *JwtStruct) Create(data map[string]any) JwtResponse {
 
-	// 密钥为空时禁止签发，避免使用空密钥导致的安全问题
+	// 指定了密钥时使用 HS256 签发，否则使用密钥环的当前签名密钥（header 中携带 kid）
+	method, kid, signer := JWT.SigningMethod(JWT.SigningMethodHS256), "", any([]byte(this.request.Key))
 	if utils.Is.Empty(this.request.Key) {
-		this.response.Error = fmt.Errorf("JWT密钥未配置，无法签发令牌！")
-		Log.Error(map[string]any{
-			"func_name": utils.Caller().FuncName,
-			"file_name": utils.Caller().FileName,
-			"file_line": utils.Caller().Line,
-		}, "JWT密钥为空")
-		return this.response
+		key, err := JwtSigningKey()
+		// 密钥为空时禁止签发，避免使用空密钥导致的安全问题
+		if err != nil {
+			this.response.Error = err
+			Log.Error(map[string]any{
+				"func_name": utils.Caller().FuncName,
+				"file_name": utils.Caller().FileName,
+				"file_line": utils.Caller().Line,
+			}, "JWT密钥为空")
+			return this.response
+		}
+		method, kid, signer = key.Method(), key.Kid, key.signer
 	}
 
 	now := time.Now()
@@ -192,7 +347,12 @@ func (this *JwtStruct) Create(data map[string]any) JwtResponse {
 		},
 	}
 
-	token, err := JWT.NewWithClaims(JWT.SigningMethodHS256, claims).SignedString([]byte(this.request.Key))
+	jwtToken := JWT.NewWithClaims(method, claims)
+	if !utils.Is.Empty(kid) {
+		jwtToken.Header["kid"] = kid
+	}
+
+	token, err := jwtToken.SignedString(signer)
 	if err != nil {
 		this.response.Error = err
 		return this.response
@@ -203,17 +363,47 @@ func (this *JwtStruct) Create(data map[string]any) JwtResponse {
 	return this.response
 }
 
-// Parse - 解析JWT
-func (this *JwtStruct) Parse(token any) JwtResponse {
+// verifyKey - 按 kid 选择校验密钥，算法必须与密钥一致，防止算法混淆攻击（alg=none、用公钥冒充 HMAC 密钥）
+func (this *JwtStruct) verifyKey(token *JWT.Token) (any, error) {
 
-	claims := &JwtClaims{}
-	jwtToken, err := JWT.ParseWithClaims(cast.ToString(token), claims, func(token *JWT.Token) (any, error) {
-		// 校验签名算法，防止算法混淆攻击（alg=none）
+	if !utils.Is.Empty(this.request.Key) {
 		if _, ok := token.Method.(*JWT.SigningMethodHMAC); !ok {
 			return nil, fmt.Errorf("非法的签名算法：%v", token.Header["alg"])
 		}
 		return []byte(this.request.Key), nil
-	},
+	}
+
+	// 轮换前签发的令牌没有 kid，使用仍在重叠期内的 HS256 密钥逐个校验
+	kid, _ := token.Header["kid"].(string)
+	if utils.Is.Empty(kid) {
+		if token.Method != JWT.SigningMethodHS256 {
+			return nil, fmt.Errorf("非法的签名算法：%v", token.Header["alg"])
+		}
+		set := JWT.VerificationKeySet{}
+		for _, item := range JwtKeys() {
+			if item.Alg == JwtAlgHS256 && item.Usable() {
+				set.Keys = append(set.Keys, item.verifier)
+			}
+		}
+		return set, nil
+	}
+
+	key := JwtVerifyKey(kid)
+	if key == nil {
+		return nil, fmt.Errorf("未知的签名密钥：%v", kid)
+	}
+	if token.Method.Alg() != key.Alg {
+		return nil, fmt.Errorf("非法的签名算法：%v", token.Header["alg"])
+	}
+
+	return key.verifier, nil
+}
+
+// Parse - 解析JWT
+func (this *JwtStruct) Parse(token any) JwtResponse {
+
+	claims := &JwtClaims{}
+	jwtToken, err := JWT.ParseWithClaims(cast.ToString(token), claims, this.verifyKey,
 		// 严格校验签发者与主题
 		JWT.WithIssuer(this.request.Issuer),
 		JWT.WithSubject(this.request.Subject),
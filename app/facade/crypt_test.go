@@ -0,0 +1,62 @@
+package facade
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/unti-io/go-utils/utils"
+)
+
+// cryptTestConfig - 写入临时的 crypt 配置
+func cryptTestConfig(t *testing.T, content string) {
+
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "crypt.toml"), []byte(content), 0644); err != nil {
+		t.Fatalf("写入配置失败：%v", err)
+	}
+
+	item := utils.Viper(utils.ViperModel{Path: dir, Mode: ModeToml, Name: "crypt"}).Read()
+	if item.Error != nil {
+		t.Fatalf("读取配置失败：%v", item.Error)
+	}
+
+	origin := CryptToml
+	CryptToml = &item
+	t.Cleanup(func() { CryptToml = origin })
+}
+
+// TestCryptSealIgnoresJwtKey - 更换 jwt.key（如轮换 JWT 密钥）后仍能解密
+func TestCryptSealIgnoresJwtKey(t *testing.T) {
+
+	cryptTestConfig(t, "[jwt]\nkey = \"INIS-old\"\n[seal]\nkey = \"seal-secret\"\n")
+
+	sealed, err := CryptSeal("test", []byte("hello"))
+	if err != nil {
+		t.Fatalf("加密失败：%v", err)
+	}
+
+	CryptToml.Viper.Set("jwt.key", "INIS-new")
+
+	plain, err := CryptOpen("test", sealed)
+	if err != nil {
+		t.Fatalf("更换 jwt.key 后解密失败：%v", err)
+	}
+	if string(plain) != "hello" {
+		t.Fatalf("解密结果错误：%q", plain)
+	}
+
+	if _, err := CryptOpen("other", sealed); err == nil {
+		t.Fatal("不同用途的密钥不应能解密")
+	}
+}
+
+// TestCryptSealRequiresKey - 未配置 seal.key 时拒绝加解密，而不是退化为空密钥
+func TestCryptSealRequiresKey(t *testing.T) {
+
+	cryptTestConfig(t, "[jwt]\nkey = \"INIS-old\"\n")
+
+	if _, err := CryptSeal("test", []byte("hello")); err == nil {
+		t.Fatal("未配置 seal.key 时应加密失败")
+	}
+}
@@ -0,0 +1,370 @@
+package facade
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+	"net/netip"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/spf13/cast"
+)
+
+// geoipMetaMarker - MaxMind DB 元数据的起始标记
+var geoipMetaMarker = []byte("\xAB\xCD\xEFMaxMind.com")
+
+// ErrGeoIPFormat - 不是有效的 MaxMind DB 文件
+var ErrGeoIPFormat = errors.New("无效的 MaxMind DB 文件")
+
+// GeoIPReader - MaxMind DB（.mmdb）格式的只读解析器
+type GeoIPReader struct {
+	// Metadata - 数据库元数据（database_type、build_epoch 等）
+	Metadata   map[string]any
+	buffer     []byte
+	nodeCount  uint
+	recordSize uint
+	ipVersion  uint
+	treeSize   uint
+	ipv4Start  uint
+}
+
+// OpenGeoIP - 读取 MaxMind DB 文件
+func OpenGeoIP(path string) (*GeoIPReader, error) {
+
+	buffer, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	index := bytes.LastIndex(buffer, geoipMetaMarker)
+	if index == -1 {
+		return nil, ErrGeoIPFormat
+	}
+
+	start := index + len(geoipMetaMarker)
+	decoder := &geoipDecoder{buffer: buffer[start:]}
+	value, _, err := decoder.decode(0)
+	if err != nil {
+		return nil, err
+	}
+	meta, ok := value.(map[string]any)
+	if !ok {
+		return nil, ErrGeoIPFormat
+	}
+
+	reader := &GeoIPReader{
+		Metadata:   meta,
+		nodeCount:  cast.ToUint(meta["node_count"]),
+		recordSize: cast.ToUint(meta["record_size"]),
+		ipVersion:  cast.ToUint(meta["ip_version"]),
+	}
+	if reader.recordSize != 24 && reader.recordSize != 28 && reader.recordSize != 32 {
+		return nil, fmt.Errorf("不支持的记录长度：%d", reader.recordSize)
+	}
+
+	reader.treeSize = reader.nodeCount * reader.recordSize / 4
+	if reader.treeSize+16 > uint(index) {
+		return nil, ErrGeoIPFormat
+	}
+	reader.buffer = buffer[:index]
+
+	// IPv6 数据库中 IPv4 地址位于 ::/96 之下，预先走完前 96 位
+	if reader.ipVersion == 6 {
+		node := uint(0)
+		for i := 0; i < 96 && node < reader.nodeCount; i++ {
+			node = reader.record(node, 0)
+		}
+		reader.ipv4Start = node
+	}
+
+	return reader, nil
+}
+
+// record - 读取节点的左（0）或右（1）记录
+func (this *GeoIPReader) record(node, bit uint) uint {
+	offset := node * this.recordSize / 4
+	item := this.buffer[offset:]
+	switch this.recordSize {
+	case 24:
+		item = item[bit*3:]
+		return uint(item[0])<<16 | uint(item[1])<<8 | uint(item[2])
+	case 28:
+		if bit == 0 {
+			return uint(item[3]&0xF0)<<20 | uint(item[0])<<16 | uint(item[1])<<8 | uint(item[2])
+		}
+		return uint(item[3]&0x0F)<<24 | uint(item[4])<<16 | uint(item[5])<<8 | uint(item[6])
+	}
+	return uint(binary.BigEndian.Uint32(item[bit*4:]))
+}
+
+// Lookup - 查询IP对应的数据，未收录时返回 nil
+func (this *GeoIPReader) Lookup(ip string) (map[string]any, error) {
+
+	addr, err := netip.ParseAddr(strings.TrimSpace(ip))
+	if err != nil {
+		return nil, err
+	}
+	addr = addr.Unmap()
+
+	node := uint(0)
+	if addr.Is4() && this.ipVersion == 6 {
+		node = this.ipv4Start
+	} else if !addr.Is4() && this.ipVersion == 4 {
+		return nil, nil
+	}
+
+	bytes := addr.AsSlice()
+	for i := 0; i < len(bytes)*8 && node < this.nodeCount; i++ {
+		node = this.record(node, uint(bytes[i/8]>>(7-i%8)&1))
+	}
+
+	if node <= this.nodeCount {
+		return nil, nil
+	}
+
+	offset := node - this.nodeCount - 16
+	decoder := &geoipDecoder{buffer: this.buffer[this.treeSize+16:]}
+	value, _, err := decoder.decode(offset)
+	if err != nil {
+		return nil, err
+	}
+	result, _ := value.(map[string]any)
+	return result, nil
+}
+
+// geoipDecoder - MaxMind DB 数据段解码器
+type geoipDecoder struct {
+	buffer []byte
+}
+
+// bytes - 读取 offset 开始的 size 个字节
+func (this *geoipDecoder) bytes(offset, size uint) ([]byte, error) {
+	if offset+size > uint(len(this.buffer)) {
+		return nil, ErrGeoIPFormat
+	}
+	return this.buffer[offset : offset+size], nil
+}
+
+// uint - 读取大端无符号整数
+func (this *geoipDecoder) uint(offset, size uint) (uint64, error) {
+	item, err := this.bytes(offset, size)
+	if err != nil {
+		return 0, err
+	}
+	var result uint64
+	for _, value := range item {
+		result = result<<8 | uint64(value)
+	}
+	return result, nil
+}
+
+// decode - 解码 offset 处的数据，返回数据与下一个数据的位置
+func (this *geoipDecoder) decode(offset uint) (any, uint, error) {
+
+	ctrl, err := this.bytes(offset, 1)
+	if err != nil {
+		return nil, 0, err
+	}
+	offset++
+
+	kind := uint(ctrl[0] >> 5)
+
+	// 指针：读取所指位置的数据，但继续从指针之后解码
+	if kind == 1 {
+		size := uint(ctrl[0]>>3) & 0x3
+		value := uint64(ctrl[0] & 0x7)
+		next, err := this.uint(offset, size+1)
+		if err != nil {
+			return nil, 0, err
+		}
+		switch size {
+		case 0:
+			value = value<<8 | next
+		case 1:
+			value = (value<<16 | next) + 2048
+		case 2:
+			value = (value<<24 | next) + 526336
+		default:
+			value = next
+		}
+		result, _, err := this.decode(uint(value))
+		return result, offset + size + 1, err
+	}
+
+	// 扩展类型
+	if kind == 0 {
+		next, err := this.bytes(offset, 1)
+		if err != nil {
+			return nil, 0, err
+		}
+		kind = 7 + uint(next[0])
+		offset++
+	}
+
+	size := uint(ctrl[0] & 0x1F)
+	if size >= 29 {
+		count := size - 28
+		next, err := this.uint(offset, count)
+		if err != nil {
+			return nil, 0, err
+		}
+		offset += count
+		switch count {
+		case 1:
+			size = 29 + uint(next)
+		case 2:
+			size = 285 + uint(next)
+		default:
+			size = 65821 + uint(next)
+		}
+	}
+
+	switch kind {
+	case 2:
+		item, err := this.bytes(offset, size)
+		return string(item), offset + size, err
+	case 3:
+		value, err := this.uint(offset, 8)
+		return math.Float64frombits(value), offset + 8, err
+	case 4:
+		item, err := this.bytes(offset, size)
+		return append([]byte{}, item...), offset + size, err
+	case 5, 6, 9:
+		value, err := this.uint(offset, size)
+		return value, offset + size, err
+	case 8:
+		value, err := this.uint(offset, size)
+		return int32(uint32(value)), offset + size, err
+	case 10:
+		item, err := this.bytes(offset, size)
+		return fmt.Sprintf("%x", item), offset + size, err
+	case 7:
+		result := make(map[string]any, size)
+		for i := uint(0); i < size; i++ {
+			key, next, err := this.decode(offset)
+			if err != nil {
+				return nil, 0, err
+			}
+			value, last, err := this.decode(next)
+			if err != nil {
+				return nil, 0, err
+			}
+			result[cast.ToString(key)] = value
+			offset = last
+		}
+		return result, offset, nil
+	case 11:
+		result := make([]any, 0, size)
+		for i := uint(0); i < size; i++ {
+			value, next, err := this.decode(offset)
+			if err != nil {
+				return nil, 0, err
+			}
+			result = append(result, value)
+			offset = next
+		}
+		return result, offset, nil
+	case 14:
+		return size != 0, offset, nil
+	case 15:
+		value, err := this.uint(offset, 4)
+		return math.Float32frombits(uint32(value)), offset + 4, err
+	}
+
+	return nil, 0, fmt.Errorf("不支持的数据类型：%d", kind)
+}
+
+// geoipCache - 已加载的数据库（配置的路径或文件修改时间变化时重新加载）
+type geoipCache struct {
+	mutex  sync.RWMutex
+	path   string
+	mtime  time.Time
+	check  time.Time
+	reader *GeoIPReader
+}
+
+var geoipCountry = &geoipCache{}
+var geoipASN = &geoipCache{}
+
+// get - 获取数据库，未配置或加载失败时返回 nil
+func (this *geoipCache) get(path string) *GeoIPReader {
+
+	if path == "" {
+		return nil
+	}
+
+	this.mutex.RLock()
+	reader, fresh := this.reader, this.path == path && time.Since(this.check) < time.Minute
+	this.mutex.RUnlock()
+	if fresh {
+		return reader
+	}
+
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+
+	this.check = time.Now()
+	info, err := os.Stat(path)
+	if err != nil {
+		if this.path != path {
+			Log.Error(map[string]any{"error": err, "path": path}, "GeoIP数据库不存在")
+		}
+		this.path, this.reader = path, nil
+		return nil
+	}
+	if this.path == path && info.ModTime().Equal(this.mtime) {
+		return this.reader
+	}
+
+	this.path, this.mtime = path, info.ModTime()
+	this.reader, err = OpenGeoIP(path)
+	if err != nil {
+		Log.Error(map[string]any{"error": err, "path": path}, "GeoIP数据库加载失败")
+	}
+	return this.reader
+}
+
+// GeoIPCountry - 查询IP所属国家/地区的 ISO 代码（大写），未配置数据库或未收录时返回空
+func GeoIPCountry(ip string) string {
+
+	reader := geoipCountry.get(cast.ToString(AppToml.Get("geoip.country_db", "")))
+	if reader == nil {
+		return ""
+	}
+
+	item, err := reader.Lookup(ip)
+	if err != nil || item == nil {
+		return ""
+	}
+
+	// 优先使用实际所在国家，缺失时使用注册国家
+	for _, key := range []string{"country", "registered_country"} {
+		country := cast.ToStringMap(item[key])
+		if code := cast.ToString(country["iso_code"]); code != "" {
+			return strings.ToUpper(code)
+		}
+	}
+
+	return ""
+}
+
+// GeoIPASN - 查询IP所属的自治系统号，未配置数据库或未收录时返回 0
+func GeoIPASN(ip string) uint {
+
+	reader := geoipASN.get(cast.ToString(AppToml.Get("geoip.asn_db", "")))
+	if reader == nil {
+		return 0
+	}
+
+	item, err := reader.Lookup(ip)
+	if err != nil || item == nil {
+		return 0
+	}
+
+	return cast.ToUint(item["autonomous_system_number"])
+}
@@ -0,0 +1,197 @@
+package facade
+
+import (
+	"errors"
+	"net/netip"
+	"strconv"
+	"strings"
+)
+
+// IP 规则的类型
+const (
+	// IPRuleAddr - 单个IP
+	IPRuleAddr = "ip"
+	// IPRuleCIDR - 网段（IPv4 CIDR 或 IPv6 前缀）
+	IPRuleCIDR = "cidr"
+	// IPRuleCountry - 国家/地区（ISO 3166 代码，需要 GeoIP 国家数据库）
+	IPRuleCountry = "country"
+	// IPRuleASN - 自治系统号（需要 GeoIP ASN 数据库）
+	IPRuleASN = "asn"
+)
+
+// ErrIPRule - 无法识别的IP规则
+var ErrIPRule = errors.New("IP规则格式错误，支持 IP、CIDR（如 10.0.0.0/8、2001:db8::/32）、country:CN、asn:13335")
+
+// IPRule - 解析后的IP规则
+type IPRule struct {
+	Kind    string
+	Prefix  netip.Prefix
+	Country string
+	ASN     uint
+}
+
+// ParseIPRule - 解析IP规则（大小写不敏感）
+/**
+ * @example：
+ * 1. rule, err := facade.ParseIPRule("192.168.0.0/16")
+ * 2. rule, err := facade.ParseIPRule("country:CN")
+ * 3. rule, err := facade.ParseIPRule("ASN:13335")
+ */
+func ParseIPRule(value string) (*IPRule, error) {
+
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return nil, ErrIPRule
+	}
+
+	if kind, item, ok := strings.Cut(value, ":"); ok && !strings.Contains(item, ":") {
+		switch strings.ToLower(kind) {
+		case IPRuleCountry:
+			item = strings.ToUpper(strings.TrimSpace(item))
+			if len(item) != 2 {
+				return nil, ErrIPRule
+			}
+			return &IPRule{Kind: IPRuleCountry, Country: item}, nil
+		case IPRuleASN:
+			item = strings.TrimPrefix(strings.ToUpper(strings.TrimSpace(item)), "AS")
+			number, err := strconv.ParseUint(item, 10, 32)
+			if err != nil || number == 0 {
+				return nil, ErrIPRule
+			}
+			return &IPRule{Kind: IPRuleASN, ASN: uint(number)}, nil
+		}
+	}
+
+	// IPv6 的 zone 与大写均不影响解析，统一转小写
+	value = strings.ToLower(value)
+	if strings.Contains(value, "/") {
+		prefix, err := netip.ParsePrefix(value)
+		if err != nil {
+			return nil, ErrIPRule
+		}
+		if prefix.Addr().Is4In6() && prefix.Bits() >= 96 {
+			prefix = netip.PrefixFrom(prefix.Addr().Unmap(), prefix.Bits()-96)
+		}
+		return &IPRule{Kind: IPRuleCIDR, Prefix: prefix.Masked()}, nil
+	}
+
+	addr, err := netip.ParseAddr(value)
+	if err != nil {
+		return nil, ErrIPRule
+	}
+	addr = addr.Unmap().WithZone("")
+	return &IPRule{Kind: IPRuleAddr, Prefix: netip.PrefixFrom(addr, addr.BitLen())}, nil
+}
+
+// Geo - 是否为 GeoIP 规则
+func (this *IPRule) Geo() bool {
+	return this.Kind == IPRuleCountry || this.Kind == IPRuleASN
+}
+
+// ipNode - 前缀树节点（按位分叉的 radix 树）
+type ipNode struct {
+	child [2]*ipNode
+	items []any
+}
+
+// IPMatcher - IP 规则匹配器
+//
+// IPv4 与 IPv6 分别使用一棵按位分叉的前缀树，查询耗时只与地址位数有关，与规则数量无关；
+// 国家与 ASN 规则先用 GeoIP 数据库查出 IP 的归属，再按哈希表匹配。
+// 构建完成后只读，可在多个 goroutine 中并发查询。
+type IPMatcher struct {
+	v4      *ipNode
+	v6      *ipNode
+	country map[string][]any
+	asn     map[uint][]any
+	size    int
+}
+
+// NewIPMatcher - 创建IP规则匹配器
+func NewIPMatcher() *IPMatcher {
+	return &IPMatcher{
+		v4:      &ipNode{},
+		v6:      &ipNode{},
+		country: make(map[string][]any),
+		asn:     make(map[uint][]any),
+	}
+}
+
+// Add - 添加规则，item 为命中时返回的数据
+func (this *IPMatcher) Add(value string, item any) error {
+
+	rule, err := ParseIPRule(value)
+	if err != nil {
+		return err
+	}
+
+	switch rule.Kind {
+	case IPRuleCountry:
+		this.country[rule.Country] = append(this.country[rule.Country], item)
+	case IPRuleASN:
+		this.asn[rule.ASN] = append(this.asn[rule.ASN], item)
+	default:
+		node := this.v6
+		if rule.Prefix.Addr().Is4() {
+			node = this.v4
+		}
+		bytes := rule.Prefix.Addr().AsSlice()
+		for i := 0; i < rule.Prefix.Bits(); i++ {
+			bit := bytes[i/8] >> (7 - i%8) & 1
+			if node.child[bit] == nil {
+				node.child[bit] = &ipNode{}
+			}
+			node = node.child[bit]
+		}
+		node.items = append(node.items, item)
+	}
+
+	this.size++
+	return nil
+}
+
+// Len - 规则数量
+func (this *IPMatcher) Len() int {
+	return this.size
+}
+
+// Match - 返回全部命中的规则数据，网段越精确越靠前，GeoIP 规则排在最后
+func (this *IPMatcher) Match(ip string) []any {
+
+	addr, err := netip.ParseAddr(strings.TrimSpace(ip))
+	if err != nil {
+		return nil
+	}
+	addr = addr.Unmap()
+
+	node := this.v6
+	if addr.Is4() {
+		node = this.v4
+	}
+
+	var result []any
+	bytes := addr.AsSlice()
+	for i := 0; node != nil; i++ {
+		// 越深的节点前缀越长，插到前面
+		if len(node.items) > 0 {
+			result = append(append([]any{}, node.items...), result...)
+		}
+		if i >= addr.BitLen() {
+			break
+		}
+		node = node.child[bytes[i/8]>>(7-i%8)&1]
+	}
+
+	if len(this.country) > 0 {
+		if code := GeoIPCountry(addr.String()); code != "" {
+			result = append(result, this.country[code]...)
+		}
+	}
+	if len(this.asn) > 0 {
+		if number := GeoIPASN(addr.String()); number != 0 {
+			result = append(result, this.asn[number]...)
+		}
+	}
+
+	return result
+}
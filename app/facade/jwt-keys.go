@@ -0,0 +1,317 @@
+package facade
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	JWT "github.com/golang-jwt/jwt/v5"
+	"github.com/spf13/cast"
+	"github.com/unti-io/go-utils/utils"
+)
+
+// JWT 签名算法
+const (
+	JwtAlgHS256 = "HS256"
+	JwtAlgRS256 = "RS256"
+	JwtAlgEdDSA = "EdDSA"
+)
+
+// JwtAlgs - 支持的签名算法
+var JwtAlgs = []string{JwtAlgHS256, JwtAlgRS256, JwtAlgEdDSA}
+
+// JwtKey - 签名密钥环中的一个密钥
+type JwtKey struct {
+	Kid string `json:"kid"`
+	Alg string `json:"alg"`
+	// HS256 为共享密钥，RS256 与 EdDSA 为 base64 编码的 PKCS#8 私钥
+	Key        string `json:"key"`
+	CreateTime int64  `json:"create_time"`
+	// 停用时间，0 表示当前签名密钥；停用后在登录会话有效期内继续用于校验旧令牌
+	RetireTime int64 `json:"retire_time"`
+	// 解析后的签名与校验密钥
+	signer   any
+	verifier any
+}
+
+// jwtKeys - 已解析的密钥环（配置变化时按原文重新解析）
+var jwtKeys struct {
+	sync.Mutex
+	raw  string
+	keys []*JwtKey
+}
+
+// Method - 签名方法
+func (this *JwtKey) Method() JWT.SigningMethod {
+	switch this.Alg {
+	case JwtAlgRS256:
+		return JWT.SigningMethodRS256
+	case JwtAlgEdDSA:
+		return JWT.SigningMethodEdDSA
+	default:
+		return JWT.SigningMethodHS256
+	}
+}
+
+// Usable - 是否仍可用于校验（当前密钥，或停用未超过登录会话有效期的旧密钥）
+func (this *JwtKey) Usable() bool {
+	return this.RetireTime == 0 || this.RetireTime+JwtSessionExpire() > time.Now().Unix()
+}
+
+// parse - 解析密钥原文
+func (this *JwtKey) parse() error {
+
+	if this.Alg == JwtAlgHS256 {
+		if utils.Is.Empty(this.Key) {
+			return errors.New("JWT密钥为空")
+		}
+		this.signer, this.verifier = []byte(this.Key), []byte(this.Key)
+		return nil
+	}
+
+	der, err := base64.StdEncoding.DecodeString(this.Key)
+	if err != nil {
+		return err
+	}
+	parsed, err := x509.ParsePKCS8PrivateKey(der)
+	if err != nil {
+		return err
+	}
+
+	switch key := parsed.(type) {
+	case *rsa.PrivateKey:
+		if this.Alg != JwtAlgRS256 {
+			return fmt.Errorf("密钥类型与算法 %s 不匹配", this.Alg)
+		}
+		this.signer, this.verifier = key, &key.PublicKey
+	case ed25519.PrivateKey:
+		if this.Alg != JwtAlgEdDSA {
+			return fmt.Errorf("密钥类型与算法 %s 不匹配", this.Alg)
+		}
+		this.signer, this.verifier = key, key.Public()
+	default:
+		return fmt.Errorf("不支持的密钥类型：%T", parsed)
+	}
+
+	return nil
+}
+
+// JWK - 公钥的 JWK 表示（HS256 共享密钥不公开，返回 nil）
+func (this *JwtKey) JWK() map[string]any {
+
+	var item map[string]any
+	switch key := this.verifier.(type) {
+	case *rsa.PublicKey:
+		item = oidcJWK(key)
+	case ed25519.PublicKey:
+		item = map[string]any{
+			"kty": "OKP",
+			"crv": "Ed25519",
+			"x":   base64.RawURLEncoding.EncodeToString(key),
+		}
+	default:
+		return nil
+	}
+
+	item["kid"] = this.Kid
+	item["alg"] = this.Alg
+	item["use"] = "sig"
+	return item
+}
+
+// JwtGenerateKey - 生成指定算法的新密钥（secret 仅对 HS256 有效，为空时随机生成）
+func JwtGenerateKey(alg, secret string) (*JwtKey, error) {
+
+	buf := make([]byte, 8)
+	if _, err := rand.Read(buf); err != nil {
+		return nil, err
+	}
+
+	item := &JwtKey{
+		Kid:        hex.EncodeToString(buf),
+		Alg:        alg,
+		CreateTime: time.Now().Unix(),
+	}
+
+	var private crypto.PrivateKey
+	switch alg {
+	case JwtAlgHS256:
+		if utils.Is.Empty(secret) {
+			raw := make([]byte, 32)
+			if _, err := rand.Read(raw); err != nil {
+				return nil, err
+			}
+			secret = "INIS-" + hex.EncodeToString(raw)
+		}
+		item.Key = secret
+	case JwtAlgRS256:
+		key, err := rsa.GenerateKey(rand.Reader, 2048)
+		if err != nil {
+			return nil, err
+		}
+		private = key
+	case JwtAlgEdDSA:
+		_, key, err := ed25519.GenerateKey(rand.Reader)
+		if err != nil {
+			return nil, err
+		}
+		private = key
+	default:
+		return nil, fmt.Errorf("不支持的签名算法：%s", alg)
+	}
+
+	if private != nil {
+		der, err := x509.MarshalPKCS8PrivateKey(private)
+		if err != nil {
+			return nil, err
+		}
+		item.Key = base64.StdEncoding.EncodeToString(der)
+	}
+
+	if err := item.parse(); err != nil {
+		return nil, err
+	}
+
+	return item, nil
+}
+
+// JwtKeys - 签名密钥环（第一个为当前签名密钥）
+func JwtKeys() []*JwtKey {
+
+	if CryptToml == nil {
+		return nil
+	}
+
+	raw := cast.ToSlice(CryptToml.Get("jwt_keys", nil))
+	signature := fmt.Sprint(raw)
+
+	jwtKeys.Lock()
+	defer jwtKeys.Unlock()
+
+	if jwtKeys.keys != nil && jwtKeys.raw == signature {
+		return jwtKeys.keys
+	}
+
+	keys := []*JwtKey{}
+	for _, value := range raw {
+		data := cast.ToStringMap(value)
+		item := &JwtKey{
+			Kid:        cast.ToString(data["kid"]),
+			Alg:        cast.ToString(data["alg"]),
+			Key:        cast.ToString(data["key"]),
+			CreateTime: cast.ToInt64(data["create_time"]),
+			RetireTime: cast.ToInt64(data["retire_time"]),
+		}
+		if err := item.parse(); err != nil || utils.Is.Empty(item.Kid) {
+			Log.Error(map[string]any{"kid": item.Kid, "error": err}, "JWT签名密钥解析失败")
+			continue
+		}
+		keys = append(keys, item)
+	}
+
+	jwtKeys.raw, jwtKeys.keys = signature, keys
+	return keys
+}
+
+// JwtSigningKey - 当前签名密钥
+func JwtSigningKey() (*JwtKey, error) {
+	keys := JwtKeys()
+	if len(keys) == 0 || keys[0].RetireTime != 0 {
+		return nil, errors.New("JWT密钥未配置，无法签发令牌！")
+	}
+	return keys[0], nil
+}
+
+// JwtVerifyKey - 按 kid 查找可用于校验的密钥
+func JwtVerifyKey(kid string) *JwtKey {
+	for _, item := range JwtKeys() {
+		if item.Kid == kid && item.Usable() {
+			return item
+		}
+	}
+	return nil
+}
+
+// JwtRotate - 轮换签名密钥：生成新密钥作为当前密钥，原密钥停用但在重叠期内继续校验，清理已过重叠期的旧密钥
+func JwtRotate(alg, secret string) ([]*JwtKey, *JwtKey, error) {
+
+	next, err := JwtGenerateKey(alg, secret)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	now := time.Now().Unix()
+	keys := []*JwtKey{next}
+	for _, item := range JwtKeys() {
+		old := *item
+		if old.RetireTime == 0 {
+			old.RetireTime = now
+		}
+		if old.Usable() {
+			keys = append(keys, &old)
+		}
+	}
+
+	return keys, next, nil
+}
+
+// JwtKeysToml - 将密钥环渲染为 crypt.toml 中的 [[jwt_keys]] 段落
+func JwtKeysToml(keys []*JwtKey) string {
+	var builder strings.Builder
+	for _, item := range keys {
+		builder.WriteString(fmt.Sprintf("[[jwt_keys]]\nkid         = \"%s\"\nalg         = \"%s\"\nkey         = \"%s\"\ncreate_time = %d\nretire_time = %d\n\n",
+			item.Kid, item.Alg, item.Key, item.CreateTime, item.RetireTime))
+	}
+	return builder.String()
+}
+
+// initJwtKeys - 补全签名密钥环（旧版本的 crypt.toml 只有 jwt.key，将其作为 HS256 密钥迁入，已签发的令牌不受影响）
+func initJwtKeys() {
+
+	if CryptToml == nil || CryptToml.Viper == nil {
+		return
+	}
+	if len(cast.ToSlice(CryptToml.Get("jwt_keys", nil))) > 0 {
+		return
+	}
+
+	item, err := JwtGenerateKey(JwtAlgHS256, cast.ToString(CryptToml.Get("jwt.key", "")))
+	if err != nil {
+		Log.Error(map[string]any{"error": err}, "JWT签名密钥环初始化失败")
+		return
+	}
+
+	// 先写入内存，避免追加文件触发的配置重载前没有可用密钥
+	CryptToml.Viper.Set("jwt_keys", []any{map[string]any{
+		"kid":         item.Kid,
+		"alg":         item.Alg,
+		"key":         item.Key,
+		"create_time": item.CreateTime,
+		"retire_time": item.RetireTime,
+	}})
+
+	filePath := fmt.Sprintf("%s/%s.%s", ConfigPath, ConfigNameCrypt, ModeToml)
+	file, err := os.OpenFile(filePath, os.O_APPEND|os.O_WRONLY, 0755)
+	if err != nil {
+		Log.Error(map[string]any{"error": err}, "JWT签名密钥环写入失败")
+		return
+	}
+	defer file.Close()
+
+	content := utils.Replace(TempJwtKeys, map[string]any{
+		"${jwt_keys}": JwtKeysToml([]*JwtKey{item}),
+	})
+	if _, err := file.WriteString(content); err != nil {
+		Log.Error(map[string]any{"error": err}, "JWT签名密钥环写入失败")
+	}
+}
@@ -7,13 +7,16 @@ import (
 	"strings"
 )
 
+// RequestLang - 请求的语言（inis_lang 参数或 Cookie），未设置时返回空
+func RequestLang(ctx *gin.Context) string {
+	lang, _ := ctx.Cookie("inis_lang")
+	return strings.ToLower(ctx.DefaultQuery("inis_lang", lang))
+}
+
 func Lang(ctx *gin.Context, key string, args ...any) (result string) {
 
-	var lang string
 	// 获取语言
-	lang, _ = ctx.Cookie("inis_lang")
-	lang = ctx.DefaultQuery("inis_lang", lang)
-	lang = strings.ToLower(lang)
+	lang := RequestLang(ctx)
 
 	model := utils.LangModel{
 		Directory: "config/i18n/",
@@ -0,0 +1,416 @@
+package facade
+
+import (
+	"bytes"
+	"crypto/hkdf"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"html"
+	"html/template"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/spf13/cast"
+	"github.com/unti-io/go-utils/utils"
+)
+
+// ========== 邮件模板名称 ==========
+const (
+	// MailTemplateVerifyCode - 验证码
+	MailTemplateVerifyCode = "verify-code"
+	// MailTemplateCommentNotify - 评论通知
+	MailTemplateCommentNotify = "comment-notify"
+	// MailTemplateReplyNotify - 评论回复通知
+	MailTemplateReplyNotify = "reply-notify"
+	// MailTemplateDigest - 未读通知摘要
+	MailTemplateDigest = "notification-digest"
+	// MailTemplateDefaultLang - 默认语言（收件人未设置语言或该语言无模板时使用）
+	MailTemplateDefaultLang = "zh-cn"
+)
+
+// mailTemplateDir - 默认模板文件目录，结构为 config/mail/{lang}/{name}.html
+const mailTemplateDir = "config/mail"
+
+// mailLangRegexp - 语言标识校验，防止拼接路径时越权访问
+var mailLangRegexp = regexp.MustCompile(`^[a-z]{2}(-[a-z]{2})?$`)
+
+// MailTemplateEvent - 邮件模板说明
+type MailTemplateEvent struct {
+	// 模板名称
+	Name string `json:"name"`
+	// 模板用途
+	Label string `json:"label"`
+	// 可用变量及说明，模板中以 {{.变量名}} 引用
+	Variables map[string]string `json:"variables"`
+	// 预览时使用的示例数据
+	Sample map[string]any `json:"sample"`
+}
+
+// MailTemplateEvents - 所有邮件模板及其可用变量
+var MailTemplateEvents = []MailTemplateEvent{
+	{
+		Name:  MailTemplateVerifyCode,
+		Label: "验证码",
+		Variables: map[string]string{
+			"site":   "站点名称（email.sign_name）",
+			"code":   "验证码",
+			"expire": "有效期（分钟）",
+		},
+		Sample: map[string]any{"code": "123456", "expire": 5},
+	},
+	{
+		Name:  MailTemplateCommentNotify,
+		Label: "评论通知",
+		Variables: map[string]string{
+			"site":         "站点名称（email.sign_name）",
+			"bind_label":   "评论对象类型名称，如 文章/页面/动态",
+			"bind_type":    "评论对象类型",
+			"bind_id":      "评论对象ID",
+			"title":        "评论对象标题",
+			"content":      "评论内容",
+			"author_name":  "评论者昵称",
+			"author_email": "评论者邮箱",
+			"created_at":   "评论时间",
+			"ip":           "评论者IP",
+			"unsubscribe":  "一键退订链接（站点域名未知时为空）",
+		},
+		Sample: map[string]any{
+			"bind_label": "文章", "bind_type": "article", "bind_id": 1, "title": "Hello World",
+			"content": "写得真好！", "author_name": "inis", "author_email": "inis@example.com",
+			"created_at": "2024-01-01 12:00:00", "ip": "127.0.0.1",
+		},
+	},
+	{
+		Name:  MailTemplateReplyNotify,
+		Label: "评论回复通知",
+		Variables: map[string]string{
+			"site":         "站点名称（email.sign_name）",
+			"bind_label":   "评论对象类型名称，如 文章/页面/动态",
+			"bind_type":    "评论对象类型",
+			"bind_id":      "评论对象ID",
+			"title":        "评论对象标题",
+			"content":      "回复内容",
+			"author_name":  "回复者昵称",
+			"author_email": "回复者邮箱",
+			"created_at":   "回复时间",
+			"ip":           "回复者IP",
+			"unsubscribe":  "一键退订链接（站点域名未知时为空）",
+		},
+		Sample: map[string]any{
+			"bind_label": "文章", "bind_type": "article", "bind_id": 1, "title": "Hello World",
+			"content": "谢谢你的评论！", "author_name": "inis", "author_email": "inis@example.com",
+			"created_at": "2024-01-01 12:00:00", "ip": "127.0.0.1",
+		},
+	},
+	{
+		Name:  MailTemplateDigest,
+		Label: "未读通知摘要",
+		Variables: map[string]string{
+			"site":        "站点名称（email.sign_name）",
+			"period":      "摘要周期：daily 每日 / weekly 每周",
+			"count":       "未读通知总数",
+			"items":       "通知列表（最多 20 条），每项包含 title、content、time，使用 {{range .items}} 遍历",
+			"more":        "未列出的通知数量",
+			"unsubscribe": "一键退订链接（站点域名未知时为空）",
+		},
+		Sample: mailDigestSample,
+	},
+}
+
+// mailDigestSample - 通知摘要的示例数据
+var mailDigestSample = map[string]any{
+	"period": "daily", "count": 3, "more": 1,
+	"items": []map[string]any{
+		{"title": "文章被点赞", "content": "A、B 等 3 人赞了你的文章「Hello World」", "time": "2024-01-01 12:00"},
+		{"title": "有新关注", "content": "inis 关注了你", "time": "2024-01-01 09:30"},
+	},
+}
+
+// MailTemplateLoader - 读取数据库中的自定义模板（由 model 包注入），未命中时使用默认模板文件
+var MailTemplateLoader func(name, lang string) (subject, body string, ok bool)
+
+// mailUnsubscribeKey - 退订链接的签名密钥（由 jwt.key 经 HKDF 派生，泄露退订签名不会影响登录令牌）
+func mailUnsubscribeKey() []byte {
+	secret := ""
+	if CryptToml != nil {
+		secret = cast.ToString(CryptToml.Get("jwt.key"))
+	}
+	key, _ := hkdf.Key(sha256.New, []byte(secret), nil, "inis mail unsubscribe", sha256.Size)
+	return key
+}
+
+// MailUnsubscribeSign - 计算退订链接签名 HMAC-SHA256(退订密钥, "unsubscribe:uid:type")
+func MailUnsubscribeSign(uid int, typ string) string {
+	mac := hmac.New(sha256.New, mailUnsubscribeKey())
+	mac.Write([]byte(fmt.Sprintf("unsubscribe:%d:%s", uid, typ)))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// MailUnsubscribeVerify - 校验退订链接签名
+func MailUnsubscribeVerify(uid int, typ, sign string) bool {
+	return hmac.Equal([]byte(MailUnsubscribeSign(uid, typ)), []byte(sign))
+}
+
+// MailUnsubscribeURL - 生成一键退订链接，站点域名未知时返回空
+func MailUnsubscribeURL(uid int, typ string) string {
+
+	domain := cast.ToString(Var.Get("domain"))
+	if uid <= 0 || utils.Is.Empty(typ) || utils.Is.Empty(domain) {
+		return ""
+	}
+
+	query := url.Values{}
+	query.Set("uid", cast.ToString(uid))
+	query.Set("type", typ)
+	query.Set("sign", MailUnsubscribeSign(uid, typ))
+
+	return strings.TrimRight(domain, "/") + "/api/notification/unsubscribe?" + query.Encode()
+}
+
+// MailTemplateEventByName - 获取模板说明
+func MailTemplateEventByName(name string) (*MailTemplateEvent, bool) {
+	for i := range MailTemplateEvents {
+		if MailTemplateEvents[i].Name == name {
+			return &MailTemplateEvents[i], true
+		}
+	}
+	return nil, false
+}
+
+// MailTemplateLang - 规范化语言标识，非法时返回默认语言
+func MailTemplateLang(lang any) string {
+	value := strings.ReplaceAll(strings.ToLower(strings.TrimSpace(cast.ToString(lang))), "_", "-")
+	if !mailLangRegexp.MatchString(value) {
+		return MailTemplateDefaultLang
+	}
+	return value
+}
+
+// MailTemplateCompose - 将主题与正文合并为完整模板
+func MailTemplateCompose(subject, body string) string {
+	return `{{define "subject"}}` + subject + `{{end}}` + body
+}
+
+// mailSubjectRegexp - 匹配模板中的主题块
+var mailSubjectRegexp = regexp.MustCompile(`(?s)\{\{\s*define\s+"subject"\s*\}\}(.*?)\{\{\s*end\s*\}\}`)
+
+// MailTemplateSplit - 将完整模板拆分为主题与正文（MailTemplateCompose 的逆操作）
+func MailTemplateSplit(source string) (subject, body string) {
+	match := mailSubjectRegexp.FindStringSubmatchIndex(source)
+	if match == nil {
+		return "", strings.TrimSpace(source)
+	}
+	return strings.TrimSpace(source[match[2]:match[3]]), strings.TrimSpace(source[:match[0]] + source[match[1]:])
+}
+
+// MailTemplateSource - 获取模板源码，优先级：数据库(语言) > 文件(语言) > 数据库(默认语言) > 文件(默认语言)
+func MailTemplateSource(name, lang string) (source string, err error) {
+
+	if _, ok := MailTemplateEventByName(name); !ok {
+		return "", fmt.Errorf("未知的邮件模板：%s", name)
+	}
+
+	langs := []string{MailTemplateLang(lang)}
+	if langs[0] != MailTemplateDefaultLang {
+		langs = append(langs, MailTemplateDefaultLang)
+	}
+
+	for _, item := range langs {
+		if MailTemplateLoader != nil {
+			if subject, body, ok := MailTemplateLoader(name, item); ok {
+				return MailTemplateCompose(subject, body), nil
+			}
+		}
+		if content, err := os.ReadFile(filepath.Join(mailTemplateDir, item, name+".html")); err == nil {
+			return string(content), nil
+		}
+		if content, ok := mailDefaults[item][name]; ok {
+			return content, nil
+		}
+	}
+
+	return "", fmt.Errorf("邮件模板不存在：%s", name)
+}
+
+// RenderMailTemplate - 按名称与语言渲染邮件模板
+func RenderMailTemplate(name, lang string, data map[string]any) (subject, body string, err error) {
+
+	source, err := MailTemplateSource(name, lang)
+	if err != nil {
+		return "", "", err
+	}
+
+	return RenderMailSource(name, source, data)
+}
+
+// RenderMailSource - 渲染邮件模板源码，模板需包含 {{define "subject"}}...{{end}} 主题块
+func RenderMailSource(name, source string, data map[string]any) (subject, body string, err error) {
+
+	vars := map[string]any{"site": ""}
+	if SMSToml != nil {
+		vars["site"] = cast.ToString(SMSToml.Get("email.sign_name"))
+	}
+	// 未传入的变量填充为空字符串，避免渲染出 <no value>
+	if event, ok := MailTemplateEventByName(name); ok {
+		for key := range event.Variables {
+			if _, exist := vars[key]; !exist {
+				vars[key] = ""
+			}
+		}
+	}
+	for key, val := range data {
+		vars[key] = val
+	}
+
+	tmpl, err := template.New(name).Parse(source)
+	if err != nil {
+		return "", "", fmt.Errorf("模板解析失败：%v", err)
+	}
+
+	if tmpl.Lookup("subject") == nil {
+		return "", "", errors.New("模板缺少主题（subject）定义")
+	}
+
+	buffer := new(bytes.Buffer)
+	if err = tmpl.ExecuteTemplate(buffer, "subject", vars); err != nil {
+		return "", "", fmt.Errorf("主题渲染失败：%v", err)
+	}
+	// 主题为纯文本，还原 html/template 的转义
+	subject = strings.TrimSpace(html.UnescapeString(buffer.String()))
+
+	buffer.Reset()
+	if err = tmpl.Execute(buffer, vars); err != nil {
+		return "", "", fmt.Errorf("正文渲染失败：%v", err)
+	}
+
+	return subject, strings.TrimSpace(buffer.String()), nil
+}
+
+// initMailTemplate - 写出默认模板文件（已存在则跳过，便于直接修改文件定制模板）
+func initMailTemplate() {
+	for lang, list := range mailDefaults {
+		dir := filepath.Join(mailTemplateDir, lang)
+		for name, content := range list {
+			path := filepath.Join(dir, name+".html")
+			if utils.File().Exist(path) {
+				continue
+			}
+			if err := os.MkdirAll(dir, 0755); err != nil {
+				smsLog("email", false, map[string]any{"error": err, "type": "初始化邮件模板"})
+				return
+			}
+			if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+				smsLog("email", false, map[string]any{"error": err, "type": "初始化邮件模板", "path": path})
+			}
+		}
+	}
+}
+
+// ========== 默认模板 ==========
+
+// mailLayout - 默认模板的公共布局
+func mailLayout(lang, subject, heading, content, footer string) string {
+	return `{{define "subject"}}` + subject + `{{end}}<!DOCTYPE html>
+<html lang="` + lang + `">
+<head>
+<meta charset="UTF-8">
+<title>` + heading + `</title>
+<style>
+* { margin: 0; padding: 0; box-sizing: border-box; }
+body { line-height: 1.7; color: #444; background-color: #f8f9fa; padding: 20px 0; }
+.container { max-width: 720px; margin: 0 auto; background: #fff; border-radius: 12px; box-shadow: 0 4px 20px rgba(0,0,0,0.05); overflow: hidden; }
+.mail-header { background: #165DFF; padding: 24px 30px; color: #fff; }
+.brand-name { font-size: 18px; font-weight: 600; }
+.mail-content { padding: 30px; }
+.subtitle { color: #666; margin-bottom: 24px; font-size: 15px; }
+.comment-card { background: #f9fafb; border-radius: 8px; padding: 20px; margin: 20px 0 30px; border-left: 4px solid #165DFF; font-size: 15px; }
+.comment-content { line-height: 1.8; color: #333; }
+.code { font-size: 28px; font-weight: 600; letter-spacing: 6px; color: #165DFF; margin: 10px 0 20px; }
+.mail-footer { padding: 20px 30px; background: #f9fafb; border-top: 1px solid #f0f0f0; font-size: 14px; color: #888; }
+@media (max-width: 600px) {
+	.container { width: 95%; margin: 0 auto; }
+	.mail-header, .mail-content, .mail-footer { padding: 20px 15px; }
+}
+</style>
+</head>
+<body>
+<div class="container">
+<div class="mail-header"><div class="brand-name">` + heading + `</div></div>
+<div class="mail-content">
+` + content + `
+</div>
+<div class="mail-footer"><p>` + footer + `</p></div>
+</div>
+</body>
+</html>
+`
+}
+
+// mailBindLabelEn - 英文模板中评论对象类型的名称
+const mailBindLabelEn = `{{if eq .bind_type "article"}}article{{else if eq .bind_type "page"}}page{{else if eq .bind_type "moments"}}moment{{else}}post{{end}}`
+
+// mailDefaults - 内置默认模板 [语言][模板名称]
+var mailDefaults = map[string]map[string]string{
+	"zh-cn": {
+		MailTemplateVerifyCode: mailLayout("zh-cn", "{{.site}}", "验证码",
+			`<p class="subtitle">您的验证码是：</p>
+<p class="code">{{.code}}</p>
+<p>有效期{{.expire}}分钟。（打死也不要把验证码告诉别人）</p>`,
+			"这是自动发送的邮件，请勿直接回复"),
+		MailTemplateCommentNotify: mailLayout("zh-cn", "新评论通知 - {{.site}}", "新评论通知",
+			`<p class="subtitle">您的{{.bind_label}}《{{.title}}》收到了一条新评论</p>
+<div class="comment-card"><div class="comment-content">{{.content}}</div></div>
+<p><strong>评论者：</strong>{{.author_name}}</p>
+<p><strong>评论时间：</strong>{{.created_at}}</p>
+<p><strong>评论者邮箱：</strong>{{.author_email}}</p>
+<p><strong>评论IP：</strong>{{.ip}}</p>`,
+			"这是自动发送的通知邮件，如有疑问可通过站点内的联系方式找到我{{if .unsubscribe}}｜<a href=\"{{.unsubscribe}}\">不再接收此类邮件</a>{{end}}"),
+		MailTemplateReplyNotify: mailLayout("zh-cn", "评论回复通知 - {{.site}}", "评论回复通知",
+			`<p class="subtitle">您在{{.bind_label}}《{{.title}}》中的评论收到了一条回复</p>
+<div class="comment-card"><div class="comment-content">{{.content}}</div></div>
+<p><strong>回复者：</strong>{{.author_name}}</p>
+<p><strong>回复时间：</strong>{{.created_at}}</p>
+<p><strong>回复者邮箱：</strong>{{.author_email}}</p>
+<p><strong>回复IP：</strong>{{.ip}}</p>`,
+			"这是自动发送的通知邮件，如有疑问可通过站点内的联系方式找到我{{if .unsubscribe}}｜<a href=\"{{.unsubscribe}}\">不再接收此类邮件</a>{{end}}"),
+		MailTemplateDigest: mailLayout("zh-cn", `{{if eq .period "weekly"}}每周{{else}}每日{{end}}通知摘要 - {{.site}}`, "通知摘要",
+			`<p class="subtitle">您有 {{.count}} 条未读通知</p>
+{{range .items}}<div class="comment-card"><p><strong>{{.title}}</strong> <span style="color:#999">{{.time}}</span></p><div class="comment-content">{{.content}}</div></div>
+{{end}}{{if .more}}<p>另有 {{.more}} 条通知未列出，请登录站点查看。</p>{{end}}`,
+			"这是自动发送的通知邮件{{if .unsubscribe}}｜<a href=\"{{.unsubscribe}}\">不再接收摘要邮件</a>{{end}}"),
+	},
+	"en-us": {
+		MailTemplateVerifyCode: mailLayout("en-us", "{{.site}}", "Verification code",
+			`<p class="subtitle">Your verification code is:</p>
+<p class="code">{{.code}}</p>
+<p>It expires in {{.expire}} minutes. Never share this code with anyone.</p>`,
+			"This is an automated message, please do not reply"),
+		MailTemplateCommentNotify: mailLayout("en-us", "New comment - {{.site}}", "New comment",
+			`<p class="subtitle">Your `+mailBindLabelEn+` "{{.title}}" received a new comment</p>
+<div class="comment-card"><div class="comment-content">{{.content}}</div></div>
+<p><strong>Author: </strong>{{.author_name}}</p>
+<p><strong>Time: </strong>{{.created_at}}</p>
+<p><strong>Email: </strong>{{.author_email}}</p>
+<p><strong>IP: </strong>{{.ip}}</p>`,
+			"This is an automated notification, please do not reply{{if .unsubscribe}} | <a href=\"{{.unsubscribe}}\">Unsubscribe</a>{{end}}"),
+		MailTemplateReplyNotify: mailLayout("en-us", "New reply - {{.site}}", "New reply",
+			`<p class="subtitle">Your comment on the `+mailBindLabelEn+` "{{.title}}" received a reply</p>
+<div class="comment-card"><div class="comment-content">{{.content}}</div></div>
+<p><strong>Author: </strong>{{.author_name}}</p>
+<p><strong>Time: </strong>{{.created_at}}</p>
+<p><strong>Email: </strong>{{.author_email}}</p>
+<p><strong>IP: </strong>{{.ip}}</p>`,
+			"This is an automated notification, please do not reply{{if .unsubscribe}} | <a href=\"{{.unsubscribe}}\">Unsubscribe</a>{{end}}"),
+		MailTemplateDigest: mailLayout("en-us", `{{if eq .period "weekly"}}Weekly{{else}}Daily{{end}} digest - {{.site}}`, "Notification digest",
+			`<p class="subtitle">You have {{.count}} unread notifications</p>
+{{range .items}}<div class="comment-card"><p><strong>{{.title}}</strong> <span style="color:#999">{{.time}}</span></p><div class="comment-content">{{.content}}</div></div>
+{{end}}{{if .more}}<p>And {{.more}} more, sign in to view them all.</p>{{end}}`,
+			"This is an automated notification, please do not reply{{if .unsubscribe}} | <a href=\"{{.unsubscribe}}\">Stop digest emails</a>{{end}}"),
+	},
+}
@@ -0,0 +1,521 @@
+package facade
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/spf13/cast"
+	"github.com/unti-io/go-utils/utils"
+)
+
+const (
+	// ConfigNameOAuth - 第三方登录配置文件名
+	ConfigNameOAuth = "oauth"
+	// oauthTimeout - 请求服务商的超时时间
+	oauthTimeout = 10 * time.Second
+)
+
+// 服务商类型
+const (
+	OAuthTypeGithub = "github"
+	OAuthTypeQQ     = "qq"
+	OAuthTypeOIDC   = "oidc"
+)
+
+// OAuthToml - 第三方登录配置文件
+var OAuthToml *utils.ViperResponse
+
+// oauthName - 服务商名称格式
+var oauthName = regexp.MustCompile(`^[a-z0-9_-]{1,32}$`)
+
+// oauthDiscovery - OIDC 发现文档缓存（配置变更时清空）
+var oauthDiscovery sync.Map
+
+// OAuthProvider - 第三方登录服务商
+type OAuthProvider struct {
+	// 服务商名称（配置段名）
+	Name  string
+	Type  string
+	Title string
+	// OIDC 签发者地址
+	Issuer       string
+	ClientId     string
+	ClientSecret string
+	Scopes       []string
+	RedirectURI  string
+}
+
+// OAuthIdentity - 服务商返回的用户身份
+type OAuthIdentity struct {
+	// 服务商内的唯一标识（GitHub 为用户ID，QQ 为 openid，OIDC 为 sub）
+	Subject string
+	Email   string
+	// 邮箱是否经服务商验证
+	EmailVerified bool
+	Nickname      string
+	Avatar        string
+}
+
+func init() {
+	initOAuthToml()
+	WatchConfigChange(OAuthToml, initOAuth)
+}
+
+// initOAuthToml - 初始化第三方登录配置文件
+func initOAuthToml() {
+	item := utils.Viper(utils.ViperModel{
+		Path: ConfigPath,
+		Mode: ModeToml,
+		Name: ConfigNameOAuth,
+		Content: utils.Replace(TempOAuth, map[string]any{
+			"${redirect_uri}":  "",
+			"${auto_register}": "true",
+		}),
+	}).Read()
+
+	if item.Error != nil {
+		Log.Error(map[string]any{
+			"error":     item.Error,
+			"func_name": utils.Caller().FuncName,
+			"file_name": utils.Caller().FileName,
+			"file_line": utils.Caller().Line,
+		}, "OAuth配置初始化错误")
+	}
+
+	OAuthToml = &item
+}
+
+// initOAuth - 配置变更后重新读取并清空发现文档缓存
+func initOAuth() {
+	if OAuthToml != nil && OAuthToml.Viper != nil {
+		if err := OAuthToml.Viper.ReadInConfig(); err == nil {
+			OAuthToml.Result = OAuthToml.Viper.AllSettings()
+		}
+	}
+	oauthDiscovery.Clear()
+}
+
+// OAuthProviders - 已启用的服务商（按名称排序）
+func OAuthProviders() (result []*OAuthProvider) {
+
+	if OAuthToml == nil {
+		return nil
+	}
+
+	var names []string
+	for name, value := range cast.ToStringMap(OAuthToml.Result) {
+		if _, ok := value.(map[string]any); ok {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		if item, err := OAuthProviderOf(name); err == nil {
+			result = append(result, item)
+		}
+	}
+
+	return result
+}
+
+// OAuthProviderOf - 获取已启用的服务商
+func OAuthProviderOf(name string) (*OAuthProvider, error) {
+
+	name = strings.ToLower(strings.TrimSpace(name))
+	if OAuthToml == nil || !oauthName.MatchString(name) || !cast.ToBool(OAuthToml.Get(name+".enable")) {
+		return nil, fmt.Errorf("第三方登录服务 %s 未开启", name)
+	}
+
+	item := &OAuthProvider{
+		Name:         name,
+		Type:         cast.ToString(OAuthToml.Get(name + ".type")),
+		Title:        cast.ToString(OAuthToml.Get(name+".title", name)),
+		Issuer:       strings.TrimRight(cast.ToString(OAuthToml.Get(name+".issuer")), "/"),
+		ClientId:     cast.ToString(OAuthToml.Get(name + ".client_id")),
+		ClientSecret: cast.ToString(OAuthToml.Get(name + ".client_secret")),
+		RedirectURI:  cast.ToString(OAuthToml.Get(name + ".redirect_uri")),
+	}
+
+	for _, scope := range strings.Split(cast.ToString(OAuthToml.Get(name+".scopes")), ",") {
+		if scope = strings.TrimSpace(scope); scope != "" {
+			item.Scopes = append(item.Scopes, scope)
+		}
+	}
+
+	if utils.Is.Empty(item.RedirectURI) {
+		item.RedirectURI = strings.ReplaceAll(cast.ToString(OAuthToml.Get("redirect_uri")), "{provider}", name)
+	}
+
+	if !utils.InArray(item.Type, []string{OAuthTypeGithub, OAuthTypeQQ, OAuthTypeOIDC}) {
+		return nil, fmt.Errorf("第三方登录服务 %s 的类型 %s 不受支持", name, item.Type)
+	}
+	if utils.Is.Empty(item.ClientId) || utils.Is.Empty(item.ClientSecret) || utils.Is.Empty(item.RedirectURI) {
+		return nil, fmt.Errorf("第三方登录服务 %s 配置不完整", name)
+	}
+	if item.Type == OAuthTypeOIDC && utils.Is.Empty(item.Issuer) {
+		return nil, fmt.Errorf("第三方登录服务 %s 未配置 issuer", name)
+	}
+
+	return item, nil
+}
+
+// OAuthPKCE - 生成 PKCE 校验码与 S256 挑战（RFC 7636）
+func OAuthPKCE() (verifier, challenge string, err error) {
+	buf := make([]byte, 32)
+	if _, err = rand.Read(buf); err != nil {
+		return "", "", err
+	}
+	verifier = base64.RawURLEncoding.EncodeToString(buf)
+	sum := sha256.Sum256([]byte(verifier))
+	return verifier, base64.RawURLEncoding.EncodeToString(sum[:]), nil
+}
+
+// AuthorizeURL - 授权地址
+/**
+ * @param state 防 CSRF 的随机值
+ * @param challenge PKCE 挑战
+ * @param nonce OIDC 的 ID Token 防重放随机值
+ */
+func (this *OAuthProvider) AuthorizeURL(state, challenge, nonce string) (string, error) {
+
+	endpoint := ""
+	query := url.Values{}
+	query.Set("response_type", "code")
+	query.Set("client_id", this.ClientId)
+	query.Set("redirect_uri", this.RedirectURI)
+	query.Set("state", state)
+	query.Set("code_challenge", challenge)
+	query.Set("code_challenge_method", "S256")
+
+	switch this.Type {
+	case OAuthTypeGithub:
+		endpoint = "https://github.com/login/oauth/authorize"
+		query.Set("scope", strings.Join(this.Scopes, " "))
+	case OAuthTypeQQ:
+		endpoint = "https://graph.qq.com/oauth2.0/authorize"
+		query.Set("scope", strings.Join(this.Scopes, ","))
+	case OAuthTypeOIDC:
+		discovery, err := this.discovery()
+		if err != nil {
+			return "", err
+		}
+		endpoint = cast.ToString(discovery["authorization_endpoint"])
+		query.Set("scope", strings.Join(this.Scopes, " "))
+		query.Set("nonce", nonce)
+	}
+
+	if strings.Contains(endpoint, "?") {
+		return endpoint + "&" + query.Encode(), nil
+	}
+	return endpoint + "?" + query.Encode(), nil
+}
+
+// Identity - 使用授权码换取令牌并获取用户身份
+func (this *OAuthProvider) Identity(code, verifier, nonce string) (*OAuthIdentity, error) {
+	switch this.Type {
+	case OAuthTypeGithub:
+		return this.github(code, verifier)
+	case OAuthTypeQQ:
+		return this.qq(code)
+	case OAuthTypeOIDC:
+		return this.oidc(code, verifier, nonce)
+	}
+	return nil, fmt.Errorf("不支持的服务商类型：%s", this.Type)
+}
+
+// github - GitHub 授权码换取用户信息
+func (this *OAuthProvider) github(code, verifier string) (*OAuthIdentity, error) {
+
+	token, err := oauthRequest(http.MethodPost, "https://github.com/login/oauth/access_token", url.Values{
+		"client_id":     {this.ClientId},
+		"client_secret": {this.ClientSecret},
+		"code":          {code},
+		"redirect_uri":  {this.RedirectURI},
+		"code_verifier": {verifier},
+	}, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	access := cast.ToString(token["access_token"])
+	if utils.Is.Empty(access) {
+		return nil, fmt.Errorf("获取令牌失败：%v", token["error_description"])
+	}
+	headers := map[string]string{"Authorization": "Bearer " + access}
+
+	user, err := oauthRequest(http.MethodGet, "https://api.github.com/user", nil, headers)
+	if err != nil {
+		return nil, err
+	}
+	if utils.Is.Empty(user["id"]) {
+		return nil, errors.New("获取用户信息失败")
+	}
+
+	item := &OAuthIdentity{
+		Subject:  cast.ToString(user["id"]),
+		Nickname: cast.ToString(utils.Ternary(utils.Is.Empty(user["name"]), user["login"], user["name"])),
+		Avatar:   cast.ToString(user["avatar_url"]),
+	}
+
+	// 公开资料中的邮箱未必已验证，以邮箱接口返回的已验证主邮箱为准
+	if emails, err := oauthRequestList(http.MethodGet, "https://api.github.com/user/emails", headers); err == nil {
+		for _, value := range emails {
+			email := cast.ToStringMap(value)
+			if cast.ToBool(email["primary"]) && cast.ToBool(email["verified"]) {
+				item.Email, item.EmailVerified = cast.ToString(email["email"]), true
+				break
+			}
+		}
+	}
+
+	return item, nil
+}
+
+// qq - QQ 授权码换取用户信息（QQ 不支持 PKCE 与邮箱）
+func (this *OAuthProvider) qq(code string) (*OAuthIdentity, error) {
+
+	token, err := oauthRequest(http.MethodGet, "https://graph.qq.com/oauth2.0/token?"+url.Values{
+		"grant_type":    {"authorization_code"},
+		"client_id":     {this.ClientId},
+		"client_secret": {this.ClientSecret},
+		"code":          {code},
+		"redirect_uri":  {this.RedirectURI},
+		"fmt":           {"json"},
+	}.Encode(), nil, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	access := cast.ToString(token["access_token"])
+	if utils.Is.Empty(access) {
+		return nil, fmt.Errorf("获取令牌失败：%v", token["error_description"])
+	}
+
+	me, err := oauthRequest(http.MethodGet, "https://graph.qq.com/oauth2.0/me?"+url.Values{
+		"access_token": {access},
+		"fmt":          {"json"},
+	}.Encode(), nil, nil)
+	if err != nil {
+		return nil, err
+	}
+	openid := cast.ToString(me["openid"])
+	if utils.Is.Empty(openid) || cast.ToString(me["client_id"]) != this.ClientId {
+		return nil, errors.New("获取用户信息失败")
+	}
+
+	user, err := oauthRequest(http.MethodGet, "https://graph.qq.com/user/get_user_info?"+url.Values{
+		"access_token":       {access},
+		"oauth_consumer_key": {this.ClientId},
+		"openid":             {openid},
+		"fmt":                {"json"},
+	}.Encode(), nil, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	return &OAuthIdentity{
+		Subject:  openid,
+		Nickname: cast.ToString(user["nickname"]),
+		Avatar:   cast.ToString(utils.Ternary(utils.Is.Empty(user["figureurl_qq_2"]), user["figureurl_qq_1"], user["figureurl_qq_2"])),
+	}, nil
+}
+
+// oidc - OpenID Connect 授权码换取用户信息
+/**
+ * ID Token 直接从令牌端点经 TLS 获取，按 OIDC Core 3.1.3.7 可不校验签名，但仍校验 iss、aud、exp 与 nonce
+ */
+func (this *OAuthProvider) oidc(code, verifier, nonce string) (*OAuthIdentity, error) {
+
+	discovery, err := this.discovery()
+	if err != nil {
+		return nil, err
+	}
+
+	form := url.Values{
+		"grant_type":    {"authorization_code"},
+		"code":          {code},
+		"redirect_uri":  {this.RedirectURI},
+		"code_verifier": {verifier},
+	}
+	headers := map[string]string{}
+
+	// 服务商仅声明支持 client_secret_post 时，密钥放在表单中，否则使用 HTTP Basic
+	methods := cast.ToStringSlice(discovery["token_endpoint_auth_methods_supported"])
+	if len(methods) > 0 && !utils.InArray("client_secret_basic", methods) && utils.InArray("client_secret_post", methods) {
+		form.Set("client_id", this.ClientId)
+		form.Set("client_secret", this.ClientSecret)
+	} else {
+		basic := url.QueryEscape(this.ClientId) + ":" + url.QueryEscape(this.ClientSecret)
+		headers["Authorization"] = "Basic " + base64.StdEncoding.EncodeToString([]byte(basic))
+	}
+
+	token, err := oauthRequest(http.MethodPost, cast.ToString(discovery["token_endpoint"]), form, headers)
+	if err != nil {
+		return nil, err
+	}
+	if utils.Is.Empty(token["id_token"]) {
+		return nil, fmt.Errorf("获取令牌失败：%v", utils.Ternary(utils.Is.Empty(token["error_description"]), token["error"], token["error_description"]))
+	}
+
+	claims, err := oauthClaims(cast.ToString(token["id_token"]))
+	if err != nil {
+		return nil, err
+	}
+	if cast.ToString(claims["iss"]) != cast.ToString(discovery["issuer"]) {
+		return nil, errors.New("ID Token 签发者不匹配")
+	}
+	audience := cast.ToStringSlice(claims["aud"])
+	if len(audience) == 0 {
+		audience = []string{cast.ToString(claims["aud"])}
+	}
+	if !utils.InArray(this.ClientId, audience) {
+		return nil, errors.New("ID Token 受众不匹配")
+	}
+	if cast.ToInt64(claims["exp"]) < time.Now().Unix() {
+		return nil, errors.New("ID Token 已过期")
+	}
+	if cast.ToString(claims["nonce"]) != nonce {
+		return nil, errors.New("ID Token nonce 不匹配")
+	}
+	if utils.Is.Empty(claims["sub"]) {
+		return nil, errors.New("ID Token 缺少 sub")
+	}
+
+	// 补充用户信息接口的资料（sub 必须一致）
+	if endpoint := cast.ToString(discovery["userinfo_endpoint"]); !utils.Is.Empty(endpoint) && !utils.Is.Empty(token["access_token"]) {
+		info, err := oauthRequest(http.MethodGet, endpoint, nil, map[string]string{
+			"Authorization": "Bearer " + cast.ToString(token["access_token"]),
+		})
+		if err == nil && cast.ToString(info["sub"]) == cast.ToString(claims["sub"]) {
+			for key, value := range info {
+				if _, ok := claims[key]; !ok {
+					claims[key] = value
+				}
+			}
+		}
+	}
+
+	return &OAuthIdentity{
+		Subject:       cast.ToString(claims["sub"]),
+		Email:         cast.ToString(claims["email"]),
+		EmailVerified: cast.ToBool(claims["email_verified"]),
+		Nickname:      cast.ToString(utils.Ternary(utils.Is.Empty(claims["name"]), claims["preferred_username"], claims["name"])),
+		Avatar:        cast.ToString(claims["picture"]),
+	}, nil
+}
+
+// discovery - 读取 OIDC 发现文档（内存缓存）
+func (this *OAuthProvider) discovery() (map[string]any, error) {
+
+	if value, ok := oauthDiscovery.Load(this.Issuer); ok {
+		return value.(map[string]any), nil
+	}
+
+	item, err := oauthRequest(http.MethodGet, this.Issuer+"/.well-known/openid-configuration", nil, nil)
+	if err != nil {
+		return nil, fmt.Errorf("读取 OIDC 发现文档失败：%v", err)
+	}
+	if strings.TrimRight(cast.ToString(item["issuer"]), "/") != this.Issuer {
+		return nil, errors.New("OIDC 发现文档的 issuer 与配置不一致")
+	}
+	if utils.Is.Empty(item["authorization_endpoint"]) || utils.Is.Empty(item["token_endpoint"]) {
+		return nil, errors.New("OIDC 发现文档缺少授权或令牌端点")
+	}
+
+	oauthDiscovery.Store(this.Issuer, item)
+	return item, nil
+}
+
+// oauthClaims - 解析 JWT 的载荷（不校验签名）
+func oauthClaims(token string) (map[string]any, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, errors.New("ID Token 格式错误")
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(strings.TrimRight(parts[1], "="))
+	if err != nil {
+		return nil, errors.New("ID Token 格式错误")
+	}
+	claims := map[string]any{}
+	if err := json.Unmarshal(payload, &claims); err != nil {
+		return nil, errors.New("ID Token 格式错误")
+	}
+	return claims, nil
+}
+
+// oauthSend - 请求服务商接口，返回响应体
+func oauthSend(method, endpoint string, form url.Values, headers map[string]string) ([]byte, error) {
+
+	var body io.Reader
+	if form != nil {
+		body = strings.NewReader(form.Encode())
+	}
+
+	request, err := http.NewRequest(method, endpoint, body)
+	if err != nil {
+		return nil, err
+	}
+	request.Header.Set("Accept", "application/json")
+	request.Header.Set("User-Agent", "inis")
+	if form != nil {
+		request.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	}
+	for key, value := range headers {
+		request.Header.Set(key, value)
+	}
+
+	response, err := (&http.Client{Timeout: oauthTimeout}).Do(request)
+	if err != nil {
+		return nil, err
+	}
+	defer response.Body.Close()
+
+	text, err := io.ReadAll(io.LimitReader(response.Body, 1<<20))
+	if err != nil {
+		return nil, err
+	}
+	if response.StatusCode >= 500 {
+		return nil, fmt.Errorf("服务商响应异常：%d", response.StatusCode)
+	}
+
+	return text, nil
+}
+
+// oauthRequest - 请求服务商接口，响应为 JSON 对象
+func oauthRequest(method, endpoint string, form url.Values, headers map[string]string) (map[string]any, error) {
+	text, err := oauthSend(method, endpoint, form, headers)
+	if err != nil {
+		return nil, err
+	}
+	item := map[string]any{}
+	if err := json.Unmarshal(text, &item); err != nil {
+		return nil, fmt.Errorf("服务商响应解析失败：%v", err)
+	}
+	return item, nil
+}
+
+// oauthRequestList - 请求服务商接口，响应为 JSON 数组
+func oauthRequestList(method, endpoint string, headers map[string]string) ([]any, error) {
+	text, err := oauthSend(method, endpoint, nil, headers)
+	if err != nil {
+		return nil, err
+	}
+	var items []any
+	if err := json.Unmarshal(text, &items); err != nil {
+		return nil, fmt.Errorf("服务商响应解析失败：%v", err)
+	}
+	return items, nil
+}
@@ -0,0 +1,166 @@
+package facade
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+	"sync"
+
+	JWT "github.com/golang-jwt/jwt/v5"
+	"github.com/spf13/cast"
+	"github.com/unti-io/go-utils/utils"
+)
+
+// OIDCSigningAlg - 身份令牌（ID Token）签名算法
+const OIDCSigningAlg = "RS256"
+
+// oidcKey - 已解析的签名密钥（配置变化时按原文重新解析）
+var oidcKey struct {
+	sync.Mutex
+	raw string
+	key *rsa.PrivateKey
+	kid string
+}
+
+// OIDCGenerateKey - 生成 RSA-2048 签名密钥（base64 编码的 PKCS#8）
+func OIDCGenerateKey() (string, error) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		return "", err
+	}
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		return "", err
+	}
+	return base64.StdEncoding.EncodeToString(der), nil
+}
+
+// initOidcKey - 补全身份令牌签名密钥（旧版本生成的 crypt.toml 中没有 [oidc] 配置）
+func initOidcKey() {
+
+	if CryptToml == nil || CryptToml.Viper == nil {
+		return
+	}
+	if !utils.Is.Empty(CryptToml.Get("oidc.private_key", "")) {
+		return
+	}
+
+	privateKey, err := OIDCGenerateKey()
+	if err != nil {
+		Log.Error(map[string]any{"error": err}, "OIDC签名密钥生成失败")
+		return
+	}
+
+	// 先写入内存，避免追加文件触发的配置重载前出现空密钥
+	CryptToml.Viper.Set("oidc.private_key", privateKey)
+
+	filePath := fmt.Sprintf("%s/%s.%s", ConfigPath, ConfigNameCrypt, ModeToml)
+	file, err := os.OpenFile(filePath, os.O_APPEND|os.O_WRONLY, 0755)
+	if err != nil {
+		Log.Error(map[string]any{"error": err}, "OIDC签名密钥写入失败")
+		return
+	}
+	defer file.Close()
+
+	content := utils.Replace(TempOidcKey, map[string]any{
+		"${oidc.private_key}": privateKey,
+	})
+	if _, err := file.WriteString(content); err != nil {
+		Log.Error(map[string]any{"error": err}, "OIDC签名密钥写入失败")
+	}
+}
+
+// OIDCSigningKey - 身份令牌签名私钥与密钥ID（kid 为公钥的 RFC 7638 指纹）
+func OIDCSigningKey() (*rsa.PrivateKey, string, error) {
+
+	if CryptToml == nil {
+		return nil, "", errors.New("OIDC签名密钥未配置！")
+	}
+
+	raw := cast.ToString(CryptToml.Get("oidc.private_key", ""))
+	if utils.Is.Empty(raw) {
+		return nil, "", errors.New("OIDC签名密钥未配置！")
+	}
+
+	oidcKey.Lock()
+	defer oidcKey.Unlock()
+
+	if oidcKey.key != nil && oidcKey.raw == raw {
+		return oidcKey.key, oidcKey.kid, nil
+	}
+
+	der, err := base64.StdEncoding.DecodeString(raw)
+	if err != nil {
+		return nil, "", fmt.Errorf("OIDC签名密钥格式错误：%v", err)
+	}
+	parsed, err := x509.ParsePKCS8PrivateKey(der)
+	if err != nil {
+		return nil, "", fmt.Errorf("OIDC签名密钥格式错误：%v", err)
+	}
+	key, ok := parsed.(*rsa.PrivateKey)
+	if !ok {
+		return nil, "", errors.New("OIDC签名密钥必须为RSA私钥！")
+	}
+
+	oidcKey.raw, oidcKey.key, oidcKey.kid = raw, key, oidcThumbprint(&key.PublicKey)
+	return oidcKey.key, oidcKey.kid, nil
+}
+
+// oidcJWK - RSA 公钥的 JWK 表示
+func oidcJWK(key *rsa.PublicKey) map[string]any {
+	return map[string]any{
+		"kty": "RSA",
+		"n":   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+		"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+	}
+}
+
+// oidcThumbprint - RFC 7638 JWK 指纹（成员按字典序排列，json.Marshal 对 map 的键排序恰好满足）
+func oidcThumbprint(key *rsa.PublicKey) string {
+	data, _ := json.Marshal(oidcJWK(key))
+	sum := sha256.Sum256(data)
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
+
+// OIDCSign - 使用签名密钥签发 JWT（header 中携带 kid）
+func OIDCSign(claims map[string]any) (string, error) {
+
+	key, kid, err := OIDCSigningKey()
+	if err != nil {
+		return "", err
+	}
+
+	token := JWT.NewWithClaims(JWT.SigningMethodRS256, JWT.MapClaims(claims))
+	token.Header["kid"] = kid
+
+	return token.SignedString(key)
+}
+
+// JWKS - 公开的签名公钥集合，包含身份令牌签名密钥与 JWT 密钥环中仍可用的非对称密钥
+func JWKS() map[string]any {
+
+	keys := []map[string]any{}
+
+	if key, kid, err := OIDCSigningKey(); err == nil {
+		item := oidcJWK(&key.PublicKey)
+		item["kid"] = kid
+		item["use"] = "sig"
+		item["alg"] = OIDCSigningAlg
+		keys = append(keys, item)
+	}
+
+	for _, item := range JwtKeys() {
+		if jwk := item.JWK(); jwk != nil && item.Usable() {
+			keys = append(keys, jwk)
+		}
+	}
+
+	return map[string]any{"keys": keys}
+}
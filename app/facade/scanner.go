@@ -0,0 +1,293 @@
+package facade
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/spf13/cast"
+	"github.com/unti-io/go-utils/utils"
+)
+
+const (
+	// ScanStatusClean - 扫描通过
+	ScanStatusClean = "clean"
+	// ScanStatusInfected - 发现威胁
+	ScanStatusInfected = "infected"
+	// ScanStatusError - 扫描失败
+	ScanStatusError = "error"
+	// ScanStatusSkipped - 未扫描（扫描未开启）
+	ScanStatusSkipped = "skipped"
+	// StorageModeQuarantine - 隔离区（仅用于存放被拦截的文件）
+	StorageModeQuarantine = "quarantine"
+)
+
+// clamd INSTREAM 单个分块大小
+const clamdChunkSize = 64 * 1024
+
+// ScanResponse - 扫描结果
+type ScanResponse struct {
+	// 扫描状态 clean/infected/error
+	Status string
+	// 病毒特征名（仅 infected 时有值）
+	Signature string
+	// 错误信息
+	Error error
+}
+
+// ScannerInterface - 文件扫描接口
+type ScannerInterface interface {
+	// Scan - 扫描文件流
+	Scan(reader io.Reader) *ScanResponse
+	// Ping - 检查扫描服务是否可用
+	Ping() error
+}
+
+// ClamdScanner - clamd 协议扫描器（兼容 ClamAV 及其他实现了 INSTREAM 指令的服务）
+type ClamdScanner struct {
+	// 连接方式 tcp/unix
+	Network string
+	// 服务地址，如 127.0.0.1:3310 或 /var/run/clamav/clamd.ctl
+	Address string
+	// 超时时间
+	Timeout time.Duration
+}
+
+// ScannerConfig - 扫描配置
+type ScannerConfig struct {
+	Enabled    bool   // 是否启用
+	FailOpen   bool   // 扫描服务异常时是否放行
+	Quarantine string // 隔离目录
+}
+
+// Scanner - 扫描器实例（未启用时为 nil）
+var Scanner ScannerInterface
+
+// ScannerConfigInstance - 扫描配置实例
+var ScannerConfigInstance *ScannerConfig
+
+// Quarantine - 隔离区存储
+var Quarantine *QuarantineStruct
+
+// InitScanner - 初始化扫描器
+func InitScanner() {
+
+	ScannerConfigInstance = &ScannerConfig{
+		Enabled:    cast.ToBool(StorageToml.Get("scanner.enabled", false)),
+		FailOpen:   cast.ToBool(StorageToml.Get("scanner.fail_open", false)),
+		Quarantine: cast.ToString(StorageToml.Get("scanner.quarantine", "runtime/quarantine")),
+	}
+
+	Quarantine = &QuarantineStruct{Dir: ScannerConfigInstance.Quarantine}
+
+	if !ScannerConfigInstance.Enabled {
+		Scanner = nil
+		return
+	}
+
+	Scanner = &ClamdScanner{
+		Network: cast.ToString(StorageToml.Get("scanner.network", "tcp")),
+		Address: cast.ToString(StorageToml.Get("scanner.address", "127.0.0.1:3310")),
+		Timeout: time.Duration(cast.ToInt(StorageToml.Get("scanner.timeout", 30))) * time.Second,
+	}
+}
+
+// dial - 建立 clamd 连接
+func (this *ClamdScanner) dial() (net.Conn, error) {
+
+	network := strings.ToLower(this.Network)
+	if network != "tcp" && network != "unix" {
+		return nil, fmt.Errorf("不支持的扫描服务连接方式：%s", this.Network)
+	}
+
+	if utils.Is.Empty(this.Address) {
+		return nil, errors.New("扫描服务地址未配置")
+	}
+
+	timeout := this.Timeout
+	if timeout <= 0 {
+		timeout = 30 * time.Second
+	}
+
+	conn, err := net.DialTimeout(network, this.Address, timeout)
+	if err != nil {
+		return nil, err
+	}
+
+	_ = conn.SetDeadline(time.Now().Add(timeout))
+
+	return conn, nil
+}
+
+// Ping - 检查扫描服务是否可用
+func (this *ClamdScanner) Ping() error {
+
+	conn, err := this.dial()
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	if _, err = conn.Write([]byte("zPING\x00")); err != nil {
+		return err
+	}
+
+	reply, err := bufio.NewReader(conn).ReadString('\x00')
+	if err != nil && err != io.EOF {
+		return err
+	}
+
+	if strings.TrimRight(reply, "\x00\n") != "PONG" {
+		return fmt.Errorf("扫描服务响应异常：%s", reply)
+	}
+
+	return nil
+}
+
+// Scan - 以 INSTREAM 指令将文件流分块发送给 clamd 扫描
+func (this *ClamdScanner) Scan(reader io.Reader) (response *ScanResponse) {
+
+	response = &ScanResponse{Status: ScanStatusError}
+
+	conn, err := this.dial()
+	if err != nil {
+		response.Error = err
+		return
+	}
+	defer conn.Close()
+
+	if _, err = conn.Write([]byte("zINSTREAM\x00")); err != nil {
+		response.Error = err
+		return
+	}
+
+	// 分块格式：4 字节大端长度 + 数据，以长度为 0 的分块结束
+	size := make([]byte, 4)
+	buffer := make([]byte, clamdChunkSize)
+	for {
+		n, err := reader.Read(buffer)
+		if n > 0 {
+			binary.BigEndian.PutUint32(size, uint32(n))
+			if _, err := conn.Write(size); err != nil {
+				response.Error = err
+				return
+			}
+			if _, err := conn.Write(buffer[:n]); err != nil {
+				response.Error = err
+				return
+			}
+		}
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			response.Error = err
+			return
+		}
+	}
+
+	binary.BigEndian.PutUint32(size, 0)
+	if _, err = conn.Write(size); err != nil {
+		response.Error = err
+		return
+	}
+
+	reply, err := bufio.NewReader(conn).ReadString('\x00')
+	if err != nil && err != io.EOF {
+		response.Error = err
+		return
+	}
+
+	return parseClamdReply(reply)
+}
+
+// parseClamdReply - 解析 clamd 响应
+// 示例：stream: OK / stream: Eicar-Signature FOUND / INSTREAM size limit exceeded. ERROR
+func parseClamdReply(reply string) (response *ScanResponse) {
+
+	response = &ScanResponse{Status: ScanStatusError}
+	reply = strings.TrimSpace(strings.TrimRight(reply, "\x00"))
+
+	// 去掉 "stream: " 前缀
+	if index := strings.Index(reply, ": "); index >= 0 {
+		reply = reply[index+2:]
+	}
+
+	switch {
+	case reply == "OK":
+		response.Status = ScanStatusClean
+	case strings.HasSuffix(reply, " FOUND"):
+		response.Status = ScanStatusInfected
+		response.Signature = strings.TrimSpace(strings.TrimSuffix(reply, " FOUND"))
+	case strings.HasSuffix(reply, " ERROR"):
+		response.Error = errors.New(strings.TrimSpace(strings.TrimSuffix(reply, " ERROR")))
+	default:
+		response.Error = fmt.Errorf("扫描服务响应异常：%s", reply)
+	}
+
+	return
+}
+
+// =================================== 隔离区存储 - 开始 ===================================
+
+// QuarantineStruct - 隔离区存储（被拦截的文件只落在本地隔离目录，不对外提供访问）
+type QuarantineStruct struct {
+	Dir string
+}
+
+// Upload - 写入隔离区
+func (this *QuarantineStruct) Upload(key string, reader io.Reader) (result *StorageResponse) {
+
+	result = &StorageResponse{}
+
+	path := filepath.Join(this.Dir, filepath.Base(key))
+	if err := os.MkdirAll(this.Dir, 0700); err != nil {
+		result.Error = err
+		return
+	}
+
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0600)
+	if err != nil {
+		result.Error = err
+		return
+	}
+	defer file.Close()
+
+	if _, err = io.Copy(file, reader); err != nil {
+		result.Error = err
+		return
+	}
+
+	result.Path = path
+	return
+}
+
+// Path - 生成隔离文件名
+func (this *QuarantineStruct) Path() string {
+	return fmt.Sprintf("%d_%s", time.Now().UnixNano()/1e6, utils.Rand.String(8, "0123456789abcdef"))
+}
+
+// Delete - 删除隔离文件
+func (this *QuarantineStruct) Delete(key string) error {
+	return os.Remove(filepath.Join(this.Dir, filepath.Base(key)))
+}
+
+// DeleteMulti - 批量删除隔离文件
+func (this *QuarantineStruct) DeleteMulti(keys []string) error {
+	for _, key := range keys {
+		if err := this.Delete(key); err != nil {
+			Log.Error(map[string]any{
+				"error": err,
+				"key":   key,
+			}, "隔离区批量删除文件失败")
+		}
+	}
+	return nil
+}
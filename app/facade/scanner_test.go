@@ -0,0 +1,177 @@
+package facade
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"io"
+	"net"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// clamdStub - 模拟 clamd 的 PING 与 INSTREAM 指令，内容包含 EICAR 时报告感染
+func clamdStub(t *testing.T, network, address string) (string, func()) {
+
+	listener, err := net.Listen(network, address)
+	if err != nil {
+		t.Fatalf("启动 clamd 模拟服务失败：%v", err)
+	}
+
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go func(conn net.Conn) {
+				defer conn.Close()
+				reader := bufio.NewReader(conn)
+				command, err := reader.ReadString('\x00')
+				if err != nil {
+					return
+				}
+				switch strings.TrimRight(command, "\x00") {
+				case "zPING":
+					_, _ = conn.Write([]byte("PONG\x00"))
+				case "zINSTREAM":
+					var body bytes.Buffer
+					size := make([]byte, 4)
+					for {
+						if _, err := io.ReadFull(reader, size); err != nil {
+							return
+						}
+						length := binary.BigEndian.Uint32(size)
+						if length == 0 {
+							break
+						}
+						if _, err := io.CopyN(&body, reader, int64(length)); err != nil {
+							return
+						}
+					}
+					switch {
+					case strings.Contains(body.String(), "EICAR"):
+						_, _ = conn.Write([]byte("stream: Eicar-Signature FOUND\x00"))
+					case body.Len() > clamdChunkSize*2:
+						_, _ = conn.Write([]byte("INSTREAM size limit exceeded. ERROR\x00"))
+					default:
+						_, _ = conn.Write([]byte("stream: OK\x00"))
+					}
+				default:
+					_, _ = conn.Write([]byte("UNKNOWN COMMAND\x00"))
+				}
+			}(conn)
+		}
+	}()
+
+	return listener.Addr().String(), func() { _ = listener.Close() }
+}
+
+func TestClamdScannerTCP(t *testing.T) {
+
+	address, stop := clamdStub(t, "tcp", "127.0.0.1:0")
+	defer stop()
+
+	scanner := &ClamdScanner{Network: "tcp", Address: address, Timeout: 5 * time.Second}
+
+	if err := scanner.Ping(); err != nil {
+		t.Fatalf("Ping：%v", err)
+	}
+
+	tests := []struct {
+		name      string
+		body      []byte
+		status    string
+		signature string
+	}{
+		{"clean", []byte("hello world"), ScanStatusClean, ""},
+		{"empty", []byte{}, ScanStatusClean, ""},
+		{"infected", []byte("X5O!P%@AP[4\\PZX54(P^)7CC)7}$EICAR-STANDARD-ANTIVIRUS-TEST-FILE!$H+H*"), ScanStatusInfected, "Eicar-Signature"},
+		{"multi chunk", bytes.Repeat([]byte("a"), clamdChunkSize+123), ScanStatusClean, ""},
+		{"too large", bytes.Repeat([]byte("a"), clamdChunkSize*3), ScanStatusError, ""},
+	}
+
+	for _, item := range tests {
+		t.Run(item.name, func(t *testing.T) {
+			result := scanner.Scan(bytes.NewReader(item.body))
+			if result.Status != item.status {
+				t.Fatalf("状态 = %q，期望 %q（%v）", result.Status, item.status, result.Error)
+			}
+			if result.Signature != item.signature {
+				t.Fatalf("特征 = %q，期望 %q", result.Signature, item.signature)
+			}
+			if item.status == ScanStatusError && result.Error == nil {
+				t.Fatal("扫描失败时应返回错误")
+			}
+		})
+	}
+}
+
+func TestClamdScannerUnix(t *testing.T) {
+
+	address, stop := clamdStub(t, "unix", filepath.Join(t.TempDir(), "clamd.sock"))
+	defer stop()
+
+	scanner := &ClamdScanner{Network: "unix", Address: address, Timeout: 5 * time.Second}
+	if result := scanner.Scan(strings.NewReader("EICAR")); result.Status != ScanStatusInfected {
+		t.Fatalf("状态 = %q，期望 %q（%v）", result.Status, ScanStatusInfected, result.Error)
+	}
+}
+
+func TestClamdScannerUnavailable(t *testing.T) {
+
+	address, stop := clamdStub(t, "tcp", "127.0.0.1:0")
+	stop()
+
+	scanner := &ClamdScanner{Network: "tcp", Address: address, Timeout: time.Second}
+	if err := scanner.Ping(); err == nil {
+		t.Fatal("服务不可用时 Ping 应返回错误")
+	}
+	if result := scanner.Scan(strings.NewReader("hello")); result.Status != ScanStatusError || result.Error == nil {
+		t.Fatalf("服务不可用时应返回 error 状态，实际 %q", result.Status)
+	}
+
+	if err := (&ClamdScanner{Network: "udp", Address: address}).Ping(); err == nil {
+		t.Fatal("不支持的连接方式应返回错误")
+	}
+}
+
+func TestParseClamdReply(t *testing.T) {
+
+	tests := []struct {
+		reply     string
+		status    string
+		signature string
+	}{
+		{"stream: OK\x00", ScanStatusClean, ""},
+		{"stream: Win.Test.EICAR_HDB-1 FOUND\x00", ScanStatusInfected, "Win.Test.EICAR_HDB-1"},
+		{"INSTREAM size limit exceeded. ERROR", ScanStatusError, ""},
+		{"garbage", ScanStatusError, ""},
+	}
+
+	for _, item := range tests {
+		result := parseClamdReply(item.reply)
+		if result.Status != item.status || result.Signature != item.signature {
+			t.Errorf("parseClamdReply(%q) = %q/%q，期望 %q/%q", item.reply, result.Status, result.Signature, item.status, item.signature)
+		}
+	}
+}
+
+func TestQuarantineStore(t *testing.T) {
+
+	store := &QuarantineStruct{Dir: t.TempDir()}
+	key := store.Path()
+
+	result := store.Upload("../../"+key, strings.NewReader("EICAR"))
+	if result.Error != nil {
+		t.Fatalf("写入隔离区失败：%v", result.Error)
+	}
+	if filepath.Dir(result.Path) != store.Dir {
+		t.Fatalf("隔离文件应位于隔离目录内，实际 %s", result.Path)
+	}
+	if err := store.Delete(key); err != nil {
+		t.Fatalf("删除隔离文件失败：%v", err)
+	}
+}
@@ -1,11 +1,17 @@
 package facade
 
 import (
+	"bytes"
+	"encoding/json"
 	"errors"
 	"fmt"
+	"io"
+	"net/http"
 	"os"
 	"path/filepath"
+	"strconv"
 	"strings"
+	"text/template"
 	"time"
 
 	AliYunClient "github.com/alibabacloud-go/darabonba-openapi/v2/client"
@@ -31,8 +37,15 @@ const (
 	SMSModeAliYunNumberVerify = "aliyun_number_verify"
 	// SMSModeTencent - 腾讯云
 	SMSModeTencent = "tencent"
+	// SMSModeHttp - 通用 HTTP 短信网关
+	SMSModeHttp = "http"
+	// SMSModeLog - 仅写入日志（开发调试用，不实际发送）
+	SMSModeLog = "log"
 )
 
+// SMSDrives - 可选的驱动
+var SMSDrives = []any{SMSModeEmail, SMSModeAliYun, SMSModeAliYunNumberVerify, SMSModeTencent, SMSModeHttp, SMSModeLog}
+
 // ========== SMS 日志记录 ==========
 // smsLog - 记录短信/邮件发送日志到独立文件
 // logType: "email" 或 "sms"
@@ -139,6 +152,24 @@ type TencentSMS struct {
 	Client *TencentCloud.Client
 }
 
+// HttpSMS - 通用 HTTP 短信网关（请求方式、地址、请求头、请求体模板与成功判断均由配置决定）
+type HttpSMS struct {
+	Method       string
+	Url          string
+	Headers      map[string]string
+	Body         string
+	ContentType  string
+	SuccessPath  string
+	SuccessValue string
+	MessagePath  string
+	SignName     string
+	Template     string
+	Timeout      time.Duration
+}
+
+// LogSMS - 日志驱动，验证码与通知只写入日志（开发调试用）
+type LogSMS struct{}
+
 // ========== 全局变量声明 ==========
 // SMSToml - SMS配置文件
 var SMSToml *utils.ViperResponse
@@ -150,6 +181,8 @@ var (
 	SMSAliYun             *AliYunSMS
 	SMSAliYunNumberVerify *AliYunNumberVerify // 阿里云号码验证实例
 	SMSTencent            *TencentSMS
+	SMSHttp               *HttpSMS
+	SMSLog                *LogSMS
 )
 
 // ========== 初始化函数 ==========
@@ -187,6 +220,10 @@ func NewSMS(mode any) SMSInterface {
 		SMS = SMSAliYunNumberVerify
 	case SMSModeTencent:
 		SMS = SMSTencent
+	case SMSModeHttp:
+		SMS = SMSHttp
+	case SMSModeLog:
+		SMS = SMSLog
 	default:
 		SMS = GoMail
 	}
@@ -226,6 +263,17 @@ func initSMSToml() {
 			"${tencent.sign_name}":                      "",
 			"${tencent.verify_code}":                    "",
 			"${tencent.region}":                         "ap-guangzhou",
+			"${http.method}":                            "POST",
+			"${http.url}":                               "",
+			"${http.headers}":                           "{}",
+			"${http.body}":                              `{"phone": {{json .phone}}, "code": {{json .code}}}`,
+			"${http.content_type}":                      "application/json",
+			"${http.success_path}":                      "",
+			"${http.success_value}":                     "",
+			"${http.message_path}":                      "",
+			"${http.sign_name}":                         "",
+			"${http.verify_code}":                       "",
+			"${http.timeout}":                           "10",
 		}),
 	}).Read()
 
@@ -257,6 +305,13 @@ func initSMS() {
 	SMSTencent = &TencentSMS{}
 	SMSTencent.init()
 
+	// 通用 HTTP 短信网关
+	SMSHttp = &HttpSMS{}
+	SMSHttp.init()
+
+	// 日志驱动
+	SMSLog = &LogSMS{}
+
 	// 设置默认驱动
 	if SMSToml != nil {
 		switch cast.ToString(SMSToml.Get("drive.default")) {
@@ -268,6 +323,10 @@ func initSMS() {
 			SMS = SMSAliYunNumberVerify
 		case "tencent":
 			SMS = SMSTencent
+		case "http":
+			SMS = SMSHttp
+		case "log":
+			SMS = SMSLog
 		default:
 			SMS = GoMail
 		}
@@ -298,252 +357,167 @@ func (this *GoMailRequest) init() {
 
 // VerifyCode - 发送验证码
 func (this *GoMailRequest) VerifyCode(phone any, code ...any) (response *SMSResponse) {
+
+	if len(code) == 0 {
+		code = append(code, utils.Rand.String(6, "0123456789"))
+	}
+
+	return this.SendVerifyCode(cast.ToString(phone), code[0], MailTemplateDefaultLang, VerifyCodeExpireMinutes())
+}
+
+// VerifyCodeExpireMinutes - 验证码有效期（分钟，向上取整），读取自 app.toml 的 verify_code.expire
+func VerifyCodeExpireMinutes() int {
+	return (cast.ToInt(AppToml.Get("verify_code.expire", 300)) + 59) / 60
+}
+
+// SendVerifyCode - 按收件人语言发送验证码邮件
+/**
+ * @param recipient 收件人
+ * @param code 验证码
+ * @param lang 收件人语言，无对应模板时使用默认语言
+ * @param expire 有效期（分钟）
+ */
+func (this *GoMailRequest) SendVerifyCode(recipient string, code, lang any, expire int) (response *SMSResponse) {
+
 	response = &SMSResponse{}
 
-	if !utils.Is.Email(phone) {
+	if !utils.Is.Email(recipient) {
 		response.Error = errors.New("格式错误，请给一个正确的邮箱地址")
-		smsLog("email", false, map[string]any{"recipient": phone, "error": response.Error, "type": "验证码"})
+		smsLog("email", false, map[string]any{"recipient": recipient, "error": response.Error, "type": "验证码"})
 		return
 	}
 
 	if this.Client == nil {
 		response.Error = errors.New("邮件服务未初始化，请检查config/sms.toml配置")
-		smsLog("email", false, map[string]any{"recipient": phone, "error": response.Error, "type": "验证码"})
+		smsLog("email", false, map[string]any{"recipient": recipient, "error": response.Error, "type": "验证码"})
 		return
 	}
 
-	if len(code) == 0 {
-		code = append(code, utils.Rand.String(6, "0123456789"))
-	}
-
-	if utils.Is.Empty(this.Template) {
-		this.Template = "您的验证码是：${code}，有效期5分钟。（打死也不要把验证码告诉别人）"
+	if expire <= 0 {
+		expire = VerifyCodeExpireMinutes()
 	}
 
-	item := gomail.NewMessage()
-	nickname := cast.ToString(SMSToml.Get("email.nickname"))
-	account := cast.ToString(SMSToml.Get("email.account"))
-	item.SetHeader("From", nickname+"<"+account+">")
-	// 发送给多个用户
-	item.SetHeader("To", cast.ToString(phone))
-	// 设置邮件主题
-	item.SetHeader("Subject", cast.ToString(SMSToml.Get("email.sign_name")))
-	// 替换验证码
-	temp := utils.Replace(this.Template, map[string]any{
-		"${code}": code[0],
-	})
-	// 设置邮件正文
-	item.SetBody("text/html", temp)
+	// 自定义了模板内容时沿用旧的 ${code} 替换方式，否则使用邮件模板
+	if !utils.Is.Empty(this.Template) {
+		item := gomail.NewMessage()
+		nickname := cast.ToString(SMSToml.Get("email.nickname"))
+		account := cast.ToString(SMSToml.Get("email.account"))
+		item.SetHeader("From", nickname+"<"+account+">")
+		item.SetHeader("To", recipient)
+		item.SetHeader("Subject", cast.ToString(SMSToml.Get("email.sign_name")))
+		item.SetBody("text/html", utils.Replace(this.Template, map[string]any{
+			"${code}":   code,
+			"${expire}": expire,
+		}))
 
-	// 发送邮件
-	err := this.Client.DialAndSend(item)
-	if err != nil {
-		response.Error = err
-		smsLog("email", false, map[string]any{"recipient": phone, "error": err, "type": "验证码"})
-		return response
+		if err := this.Client.DialAndSend(item); err != nil {
+			response.Error = err
+			smsLog("email", false, map[string]any{"recipient": recipient, "error": err, "type": "验证码"})
+			return response
+		}
+	} else {
+		result := this.SendTemplate(recipient, MailTemplateVerifyCode, lang, map[string]any{
+			"code":   code,
+			"expire": expire,
+		}, "验证码")
+		if result.Error != nil {
+			response.Error = result.Error
+			return response
+		}
 	}
 
-	response.VerifyCode = cast.ToString(code[0])
-	smsLog("email", true, map[string]any{"recipient": phone, "type": "验证码"})
+	response.VerifyCode = cast.ToString(code)
 	return response
 }
 
 // SendCommentNotify - 发送评论通知邮件
 func (this *GoMailRequest) SendCommentNotify(recipient string, commentInfo map[string]any) (response *SMSResponse) {
+	return this.SendTemplate(recipient, MailTemplateCommentNotify, commentInfo["lang"], commentInfo, "评论通知")
+}
+
+// SendReplyNotify - 发送评论回复通知邮件
+func (this *GoMailRequest) SendReplyNotify(recipient string, commentInfo map[string]any) (response *SMSResponse) {
+	return this.SendTemplate(recipient, MailTemplateReplyNotify, commentInfo["lang"], commentInfo, "回复通知")
+}
+
+// SendTemplate - 按模板发送邮件
+/**
+ * @name 按模板发送邮件
+ * @param recipient 收件人邮箱
+ * @param name 模板名称
+ * @param lang 收件人语言（为空时使用默认语言）
+ * @param data 模板变量
+ * @param label 日志中的邮件类型
+ * @return *SMSResponse
+ */
+func (this *GoMailRequest) SendTemplate(recipient, name string, lang any, data map[string]any, label string) (response *SMSResponse) {
 	response = &SMSResponse{}
 
 	if !utils.Is.Email(recipient) {
 		response.Error = errors.New("格式错误，请给一个正确的邮箱地址")
-		smsLog("email", false, map[string]any{"recipient": recipient, "error": response.Error, "type": "评论通知"})
+		smsLog("email", false, map[string]any{"recipient": recipient, "error": response.Error, "type": label})
 		return
 	}
 
 	if this.Client == nil {
 		response.Error = errors.New("邮件服务未初始化，请检查config/sms.toml配置")
-		smsLog("email", false, map[string]any{"recipient": recipient, "error": response.Error, "type": "评论通知"})
+		smsLog("email", false, map[string]any{"recipient": recipient, "error": response.Error, "type": label})
 		return
 	}
 
-	template := `
-	<!DOCTYPE html>
-	<html>
-	<head>
-	<meta charset="UTF-8">
-	<title>新评论通知</title>
-	<style>
-	* { margin: 0; padding: 0; box-sizing: border-box; }
-	body { line-height: 1.7; color: #444; background-color: #f8f9fa; padding: 20px 0; }
-	.container { max-width: 720px; margin: 0 auto; background: #fff; border-radius: 12px; box-shadow: 0 4px 20px rgba(0,0,0,0.05); overflow: hidden; }
-	.mail-header { background: #165DFF; padding: 24px 30px; color: #fff; }
-	.brand { display: flex; align-items: center; gap: 12px; }
-	.brand-name { font-size: 18px; font-weight: 600; }
-	.mail-content { padding: 30px; }
-	.mail-title { font-size: 22px; color: #222; margin-bottom: 20px; padding-bottom: 15px; border-bottom: 1px solid #f0f0f0; }
-	.subtitle { color: #666; margin-bottom: 24px; font-size: 15px; }
-	.comment-card { background: #f9fafb; border-radius: 8px; padding: 20px; margin: 20px 0 30px; border-left: 4px solid #165DFF; font-size: 15px; }
-	.comment-content { line-height: 1.8; color: #333; }
-	.action-btn { display: inline-block; background: #165DFF; color: #fff; padding: 12px 24px; border-radius: 6px; text-decoration: none; font-weight: 500; margin: 10px 0 25px; transition: background 0.3s; }
-	.action-btn:hover { background: #0E42D2; }
-	.mail-footer { padding: 20px 30px; background: #f9fafb; border-top: 1px solid #f0f0f0; font-size: 14px; color: #888; }
-	.footer-note { margin-bottom: 12px; }
-	.unsubscribe { color: #165DFF; text-decoration: none; }
-	.unsubscribe:hover { text-decoration: underline; }
-	@media (max-width: 600px) {
-		.container { width: 95%; margin: 0 auto; }
-		.mail-header, .mail-content, .mail-footer { padding: 20px 15px; }
-		.mail-title { font-size: 18px; }
-		.action-btn { width: 100%; text-align: center; }
-	}
-	</style>
-	</head>
-	<body>
-	<div class="container">
-	<div class="mail-header">
-		<div class="brand"><div class="brand-name">新评论通知</div></div>
-	</div>
-	<div class="mail-content">
-		<p class="subtitle">您的${bind_label}《${title}》收到了一条新评论</p>
-		<div class="comment-card"><div class="comment-content">${content}</div></div>
-		<p><strong>评论者：</strong>${author_name}</p>
-		<p><strong>评论时间：</strong>${created_at}</p>
-		<p><strong>评论者邮箱：</strong>${author_email}</p>
-		<p><strong>评论IP：</strong>${ip}</p>
-	</div>
-	<div class="mail-footer">
-		<p class="footer-note">这是自动发送的通知邮件，如有疑问可通过站点内的联系方式找到我</p>
-	</div>
-	</div>
-	</body>
-	</html>
-	`
-
-	item := gomail.NewMessage()
-	nickname := cast.ToString(SMSToml.Get("email.nickname"))
-	account := cast.ToString(SMSToml.Get("email.account"))
-	item.SetHeader("From", nickname+"<"+account+">")
-	item.SetHeader("To", recipient)
-	item.SetHeader("Subject", "新评论通知 - "+cast.ToString(SMSToml.Get("email.sign_name")))
-
-	// 转换评论信息为正确的格式
-	replaceMap := make(map[string]any)
-	for key, val := range commentInfo {
-		replaceMap["${"+key+"}"] = val
+	// 通知类邮件附带一键退订链接（验证码等事务性邮件不可退订）
+	headers := make(map[string]string)
+	vars := make(map[string]any, len(data)+1)
+	for key, val := range data {
+		vars[key] = val
+	}
+	if unsubscribe := MailUnsubscribeURL(cast.ToInt(data["uid"]), cast.ToString(data["notify_type"])); !utils.Is.Empty(unsubscribe) {
+		vars["unsubscribe"] = unsubscribe
+		headers["List-Unsubscribe"] = "<" + unsubscribe + ">"
+		headers["List-Unsubscribe-Post"] = "List-Unsubscribe=One-Click"
 	}
 
-	// 替换模板变量
-	temp := utils.Replace(template, replaceMap)
-	item.SetBody("text/html", temp)
+	subject, body, err := RenderMailTemplate(name, cast.ToString(lang), vars)
+	if err != nil {
+		response.Error = err
+		smsLog("email", false, map[string]any{"recipient": recipient, "error": err, "type": label, "template": name})
+		return
+	}
 
 	// 发送邮件
-	err := this.Client.DialAndSend(item)
+	err = this.Send(recipient, subject, body, headers)
 	if err != nil {
 		response.Error = err
-		smsLog("email", false, map[string]any{"recipient": recipient, "error": err, "type": "评论通知", "bind_type": commentInfo["bind_type"], "bind_id": commentInfo["bind_id"]})
+		smsLog("email", false, map[string]any{"recipient": recipient, "error": err, "type": label, "bind_type": data["bind_type"], "bind_id": data["bind_id"]})
 		return response
 	}
 
 	response.Result = "邮件发送成功"
-	smsLog("email", true, map[string]any{"recipient": recipient, "type": "评论通知", "bind_type": commentInfo["bind_type"], "bind_id": commentInfo["bind_id"]})
+	smsLog("email", true, map[string]any{"recipient": recipient, "type": label, "bind_type": data["bind_type"], "bind_id": data["bind_id"]})
 	return response
 }
 
-// SendReplyNotify - 发送评论回复通知邮件
-func (this *GoMailRequest) SendReplyNotify(recipient string, commentInfo map[string]any) (response *SMSResponse) {
-	response = &SMSResponse{}
-
-	if !utils.Is.Email(recipient) {
-		response.Error = errors.New("格式错误，请给一个正确的邮箱地址")
-		smsLog("email", false, map[string]any{"recipient": recipient, "error": response.Error, "type": "回复通知"})
-		return
-	}
+// Send - 发送 HTML 邮件，可附加自定义邮件头
+func (this *GoMailRequest) Send(recipient, subject, body string, headers ...map[string]string) error {
 
 	if this.Client == nil {
-		response.Error = errors.New("邮件服务未初始化，请检查config/sms.toml配置")
-		smsLog("email", false, map[string]any{"recipient": recipient, "error": response.Error, "type": "回复通知"})
-		return
+		return errors.New("邮件服务未初始化，请检查config/sms.toml配置")
 	}
 
-	template := `
-	<!DOCTYPE html>
-	<html>
-	<head>
-	<meta charset="UTF-8">
-	<title>评论回复通知</title>
-	<style>
-	* { margin: 0; padding: 0; box-sizing: border-box; }
-	body { line-height: 1.7; color: #444; background-color: #f8f9fa; padding: 20px 0; }
-	.container { max-width: 720px; margin: 0 auto; background: #fff; border-radius: 12px; box-shadow: 0 4px 20px rgba(0,0,0,0.05); overflow: hidden; }
-	.mail-header { background: #165DFF; padding: 24px 30px; color: #fff; }
-	.brand { display: flex; align-items: center; gap: 12px; }
-	.brand-name { font-size: 18px; font-weight: 600; }
-	.mail-content { padding: 30px; }
-	.mail-title { font-size: 22px; color: #222; margin-bottom: 20px; padding-bottom: 15px; border-bottom: 1px solid #f0f0f0; }
-	.subtitle { color: #666; margin-bottom: 24px; font-size: 15px; }
-	.comment-card { background: #f9fafb; border-radius: 8px; padding: 20px; margin: 20px 0 30px; border-left: 4px solid #165DFF; font-size: 15px; }
-	.comment-content { line-height: 1.8; color: #333; }
-	.action-btn { display: inline-block; background: #165DFF; color: #fff; padding: 12px 24px; border-radius: 6px; text-decoration: none; font-weight: 500; margin: 10px 0 25px; transition: background 0.3s; }
-	.action-btn:hover { background: #0E42D2; }
-	.mail-footer { padding: 20px 30px; background: #f9fafb; border-top: 1px solid #f0f0f0; font-size: 14px; color: #888; }
-	.footer-note { margin-bottom: 12px; }
-	.unsubscribe { color: #165DFF; text-decoration: none; }
-	.unsubscribe:hover { text-decoration: underline; }
-	@media (max-width: 600px) {
-		.container { width: 95%; margin: 0 auto; }
-		.mail-header, .mail-content, .mail-footer { padding: 20px 15px; }
-		.mail-title { font-size: 18px; }
-		.action-btn { width: 100%; text-align: center; }
-	}
-	</style>
-	</head>
-	<body>
-	<div class="container">
-	<div class="mail-header">
-		<div class="brand"><div class="brand-name">评论回复通知</div></div>
-	</div>
-	<div class="mail-content">
-		<p class="subtitle">您在${bind_label}《${title}》中的评论收到了一条回复</p>
-		<div class="comment-card"><div class="comment-content">${content}</div></div>
-		<p><strong>回复者：</strong>${author_name}</p>
-		<p><strong>回复时间：</strong>${created_at}</p>
-		<p><strong>回复者邮箱：</strong>${author_email}</p>
-		<p><strong>回复IP：</strong>${ip}</p>
-	</div>
-	<div class="mail-footer">
-		<p class="footer-note">这是自动发送的通知邮件，如有疑问可通过站点内的联系方式找到我</p>
-	</div>
-	</div>
-	</body>
-	</html>
-	`
-
 	item := gomail.NewMessage()
 	nickname := cast.ToString(SMSToml.Get("email.nickname"))
 	account := cast.ToString(SMSToml.Get("email.account"))
 	item.SetHeader("From", nickname+"<"+account+">")
 	item.SetHeader("To", recipient)
-	item.SetHeader("Subject", "评论回复通知 - "+cast.ToString(SMSToml.Get("email.sign_name")))
-
-	// 转换评论信息为正确的格式
-	replaceMap := make(map[string]any)
-	for key, val := range commentInfo {
-		replaceMap["${"+key+"}"] = val
-	}
-
-	// 替换模板变量
-	temp := utils.Replace(template, replaceMap)
-	item.SetBody("text/html", temp)
-
-	// 发送邮件
-	err := this.Client.DialAndSend(item)
-	if err != nil {
-		response.Error = err
-		smsLog("email", false, map[string]any{"recipient": recipient, "error": err, "type": "回复通知", "bind_type": commentInfo["bind_type"], "bind_id": commentInfo["bind_id"]})
-		return response
+	item.SetHeader("Subject", subject)
+	for _, list := range headers {
+		for key, val := range list {
+			item.SetHeader(key, val)
+		}
 	}
+	item.SetBody("text/html", body)
 
-	response.Result = "邮件发送成功"
-	smsLog("email", true, map[string]any{"recipient": recipient, "type": "回复通知", "bind_type": commentInfo["bind_type"], "bind_id": commentInfo["bind_id"]})
-	return response
+	return this.Client.DialAndSend(item)
 }
 
 // ================================== 阿里云短信 - 实现 ==================================
@@ -1096,3 +1070,263 @@ func (this *TencentSMS) SendReplyNotify(recipient string, commentInfo map[string
 	response.Error = errors.New("腾讯云短信服务暂不支持评论回复通知")
 	return response
 }
+
+// ================================== 通用HTTP短信 - 实现 ==================================
+// init 初始化 通用HTTP短信
+func (this *HttpSMS) init() {
+	if SMSToml == nil {
+		return
+	}
+
+	this.Method = strings.ToUpper(cast.ToString(SMSToml.Get("http.method", "POST")))
+	if utils.Is.Empty(this.Method) {
+		this.Method = http.MethodPost
+	}
+	this.Url = strings.TrimSpace(cast.ToString(SMSToml.Get("http.url", "")))
+	this.Body = cast.ToString(SMSToml.Get("http.body", ""))
+	this.ContentType = cast.ToString(SMSToml.Get("http.content_type", "application/json"))
+	this.SuccessPath = cast.ToString(SMSToml.Get("http.success_path", ""))
+	this.SuccessValue = cast.ToString(SMSToml.Get("http.success_value", ""))
+	this.MessagePath = cast.ToString(SMSToml.Get("http.message_path", ""))
+	this.SignName = cast.ToString(SMSToml.Get("http.sign_name", ""))
+	this.Template = cast.ToString(SMSToml.Get("http.verify_code", ""))
+
+	this.Timeout = time.Duration(cast.ToInt(SMSToml.Get("http.timeout", 10))) * time.Second
+	if this.Timeout <= 0 {
+		this.Timeout = 10 * time.Second
+	}
+
+	this.Headers = make(map[string]string)
+	if headers := cast.ToString(SMSToml.Get("http.headers", "")); !utils.Is.Empty(headers) {
+		if err := json.Unmarshal([]byte(headers), &this.Headers); err != nil {
+			smsLog("sms", false, map[string]any{"provider": "http", "error": "请求头不是合法的 JSON 对象：" + err.Error()})
+		}
+	}
+}
+
+// smsTemplateFuncs - 地址与请求体模板可用的函数
+var smsTemplateFuncs = template.FuncMap{
+	// json - 输出 JSON 编码后的值（字符串带引号并转义），用于拼接 JSON 请求体
+	"json": func(value any) string {
+		bytes, _ := json.Marshal(value)
+		return string(bytes)
+	},
+}
+
+// smsRender - 渲染地址或请求体模板
+func smsRender(text string, data map[string]any) (string, error) {
+	item, err := template.New("sms").Funcs(smsTemplateFuncs).Option("missingkey=zero").Parse(text)
+	if err != nil {
+		return "", fmt.Errorf("模板格式错误：%v", err)
+	}
+	var buffer bytes.Buffer
+	if err := item.Execute(&buffer, data); err != nil {
+		return "", fmt.Errorf("模板渲染错误：%v", err)
+	}
+	return buffer.String(), nil
+}
+
+// SMSJsonPath - 按路径读取 JSON 数据，支持 $.a.b、$.list[0].code 与 a.b 的写法
+func SMSJsonPath(data any, path string) (any, bool) {
+
+	path = strings.TrimPrefix(strings.TrimSpace(path), "$")
+	path = strings.TrimPrefix(path, ".")
+	if path == "" {
+		return data, true
+	}
+
+	current := data
+	for _, part := range strings.Split(strings.ReplaceAll(path, "[", ".["), ".") {
+		if part == "" {
+			continue
+		}
+
+		if strings.HasPrefix(part, "[") && strings.HasSuffix(part, "]") {
+			list, ok := current.([]any)
+			index, err := strconv.Atoi(strings.Trim(part, "[]"))
+			if !ok || err != nil || index < 0 || index >= len(list) {
+				return nil, false
+			}
+			current = list[index]
+			continue
+		}
+
+		item, ok := current.(map[string]any)
+		if !ok {
+			return nil, false
+		}
+		if current, ok = item[part]; !ok {
+			return nil, false
+		}
+	}
+
+	return current, true
+}
+
+// Send - 按配置发起请求并判断是否发送成功
+func (this *HttpSMS) Send(phone string, data map[string]any) (text string, err error) {
+
+	if utils.Is.Empty(this.Url) {
+		return "", errors.New("HTTP短信网关地址未配置")
+	}
+
+	data["phone"] = phone
+	data["sign_name"] = this.SignName
+	data["template"] = this.Template
+	data["timestamp"] = time.Now().Unix()
+
+	target, err := smsRender(this.Url, data)
+	if err != nil {
+		return "", err
+	}
+
+	var body io.Reader
+	if this.Method != http.MethodGet && this.Method != http.MethodHead {
+		content, err := smsRender(this.Body, data)
+		if err != nil {
+			return "", err
+		}
+		body = strings.NewReader(content)
+	}
+
+	request, err := http.NewRequest(this.Method, target, body)
+	if err != nil {
+		return "", err
+	}
+	if body != nil && !utils.Is.Empty(this.ContentType) {
+		request.Header.Set("Content-Type", this.ContentType)
+	}
+	request.Header.Set("User-Agent", "inis-sms/"+Version)
+	for key, value := range this.Headers {
+		request.Header.Set(key, value)
+	}
+
+	client := &http.Client{Timeout: this.Timeout}
+	response, err := client.Do(request)
+	if err != nil {
+		// 错误信息中可能带有含密钥的网关地址，仅记录在服务端
+		Log.Error(map[string]any{"error": err, "phone": phone}, "HTTP短信网关请求失败")
+		return "", errors.New("短信网关请求失败")
+	}
+	defer response.Body.Close()
+
+	content, _ := io.ReadAll(io.LimitReader(response.Body, 64*1024))
+	text = string(content)
+
+	var result any
+	json.Unmarshal(content, &result)
+
+	// 网关原始响应只写入服务端日志，返回给调用方的错误不包含网关内容
+	failed := func(reason string) error {
+		detail := map[string]any{"phone": phone, "status": response.StatusCode, "response": Comm.Truncate(text, 2048)}
+		if !utils.Is.Empty(this.MessagePath) {
+			if message, ok := SMSJsonPath(result, this.MessagePath); ok && !utils.Is.Empty(message) {
+				detail["message"] = message
+			}
+		}
+		Log.Error(detail, "HTTP短信网关"+reason)
+		return errors.New("短信网关" + reason)
+	}
+
+	if response.StatusCode < 200 || response.StatusCode >= 300 {
+		return text, failed(fmt.Sprintf("返回 HTTP %d", response.StatusCode))
+	}
+
+	if utils.Is.Empty(this.SuccessPath) {
+		return text, nil
+	}
+
+	// 配置了成功值时按字符串比较（如 code 为 0），否则只要求该路径存在且不为空
+	value, ok := SMSJsonPath(result, this.SuccessPath)
+	if !ok {
+		return text, failed("响应不符合成功条件")
+	}
+	if utils.Is.Empty(this.SuccessValue) && utils.Is.Empty(value) {
+		return text, failed("响应不符合成功条件")
+	}
+	if !utils.Is.Empty(this.SuccessValue) && cast.ToString(value) != this.SuccessValue {
+		return text, failed("响应不符合成功条件")
+	}
+
+	return text, nil
+}
+
+// VerifyCode - 发送验证码
+func (this *HttpSMS) VerifyCode(phone any, code ...any) (response *SMSResponse) {
+	response = &SMSResponse{}
+
+	// 手机号格式校验
+	if !utils.Is.Phone(phone) {
+		response.Error = errors.New("格式错误，请给一个正确的手机号码")
+		smsLog("sms", false, map[string]any{"provider": "http", "phone": phone, "error": response.Error, "type": "验证码"})
+		return
+	}
+
+	// 生成验证码
+	if len(code) == 0 || utils.Is.Empty(code[0]) {
+		code = []any{utils.Rand.String(6, "0123456789")}
+	}
+
+	text, err := this.Send(cast.ToString(phone), map[string]any{"code": cast.ToString(code[0])})
+	if err != nil {
+		response.Error = err
+		smsLog("sms", false, map[string]any{"provider": "http", "phone": phone, "error": err, "type": "验证码"})
+		return response
+	}
+
+	// 响应赋值
+	response.VerifyCode = cast.ToString(code[0])
+	response.Text = text
+	response.Result = utils.Json.Decode(text)
+	smsLog("sms", true, map[string]any{"provider": "http", "phone": phone, "type": "验证码"})
+
+	return response
+}
+
+// SendCommentNotify - 发送评论通知
+func (this *HttpSMS) SendCommentNotify(recipient string, commentInfo map[string]any) (response *SMSResponse) {
+	response = &SMSResponse{}
+	response.Error = errors.New("HTTP短信网关暂不支持评论通知")
+	return response
+}
+
+// SendReplyNotify - 发送评论回复通知
+func (this *HttpSMS) SendReplyNotify(recipient string, commentInfo map[string]any) (response *SMSResponse) {
+	response = &SMSResponse{}
+	response.Error = errors.New("HTTP短信网关暂不支持评论回复通知")
+	return response
+}
+
+// ================================== 日志驱动 - 实现 ==================================
+// VerifyCode - 将验证码写入日志（不实际发送）
+func (this *LogSMS) VerifyCode(phone any, code ...any) (response *SMSResponse) {
+	response = &SMSResponse{}
+
+	if !utils.Is.Phone(phone) && !utils.Is.Email(phone) {
+		response.Error = errors.New("格式错误，请给一个正确的手机号码或邮箱地址")
+		smsLog("sms", false, map[string]any{"provider": "log", "phone": phone, "error": response.Error, "type": "验证码"})
+		return
+	}
+
+	if len(code) == 0 || utils.Is.Empty(code[0]) {
+		code = []any{utils.Rand.String(6, "0123456789")}
+	}
+
+	response.VerifyCode = cast.ToString(code[0])
+	smsLog("sms", true, map[string]any{"provider": "log", "phone": phone, "code": response.VerifyCode, "type": "验证码"})
+	Log.Info(map[string]any{"phone": phone, "code": response.VerifyCode}, "日志驱动：验证码未实际发送")
+
+	return response
+}
+
+// SendCommentNotify - 将评论通知写入日志（不实际发送）
+func (this *LogSMS) SendCommentNotify(recipient string, commentInfo map[string]any) (response *SMSResponse) {
+	smsLog("sms", true, map[string]any{"provider": "log", "recipient": recipient, "type": "评论通知", "content": commentInfo["content"]})
+	return &SMSResponse{}
+}
+
+// SendReplyNotify - 将评论回复通知写入日志（不实际发送）
+func (this *LogSMS) SendReplyNotify(recipient string, commentInfo map[string]any) (response *SMSResponse) {
+	smsLog("sms", true, map[string]any{"provider": "log", "recipient": recipient, "type": "评论回复通知", "content": commentInfo["content"]})
+	return &SMSResponse{}
+}
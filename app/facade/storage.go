@@ -110,6 +110,12 @@ func initStorageToml() {
 			"${attachment.limit_per_day}":    1000,
 			"${attachment.limit_per_week}":   5000,
 			"${attachment.limit_per_month}":  20000,
+			"${scanner.enabled}":             "false",
+			"${scanner.network}":             "tcp",
+			"${scanner.address}":             "127.0.0.1:3310",
+			"${scanner.timeout}":             30,
+			"${scanner.fail_open}":           "false",
+			"${scanner.quarantine}":          "runtime/quarantine",
 		}),
 	}).Read()
 
@@ -183,6 +189,8 @@ func initStorage() {
 
 	// 初始化附件配置
 	InitAttachmentConfig()
+	// 初始化上传文件扫描
+	InitScanner()
 }
 
 // Storage - Storage实例
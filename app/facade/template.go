@@ -33,10 +33,151 @@ enable_ip_ban = false           # 是否启用IP临时封禁
 ip_ban_threshold = 3            # IP连续超限几次后临时封禁
 ip_ban_duration = 300           # IP临时封禁时长(秒)
 
+# 图片处理配置（public 目录下图片的 ?size=宽x高 缩放）
+[image]
+# 允许的缩放尺寸（宽x高，多个用,分隔；填 * 表示不限制，但仍受最大宽高约束）
+sizes = "64x64,128x128,256x256,512x512,320x180,640x360,1280x720"
+# 最大宽度(像素)
+max_width = 2048
+# 最大高度(像素)
+max_height = 2048
+# 缩放结果缓存目录
+cache_path = "runtime/cache/image"
+# 按 Accept 请求头协商的输出格式（按优先级由高到低，可选 avif、webp；留空则保持原格式）
+formats = "avif,webp"
+# webp/avif 编码质量(1-100)
+quality = 75
+# 浏览器缓存时间(秒)
+max_age = 604800
+# 同时进行的缩放任务数量
+concurrency = 4
+
 # 通知配置
 [notification]
 # 通知保留天数（已读通知与广播通知超过该天数后自动清理）
 retention_days = 30
+# 聚合窗口(秒)，窗口内同一对象的点赞/收藏/关注合并为一条通知，0 表示不聚合
+aggregate_window = 86400
+# 摘要邮件发送时刻（用户所在时区的小时，0-23）
+digest_hour = 8
+
+# 邮件/短信发件箱配置（发送失败后自动重试）
+[outbox]
+# 最大发送次数（含首次），超过后进入死信
+max_attempts = 6
+# 每个收件人每分钟最多成功发送的条数（0 表示不限制）
+recipient_per_minute = 3
+# 每个收件人每小时最多成功发送的条数（0 表示不限制）
+recipient_per_hour = 20
+
+# 验证码配置（注册、找回密码、绑定邮箱/手机号、注销、申诉等）
+[verify_code]
+# 验证码长度
+length = 6
+# 有效期(秒)
+expire = 300
+# 最大校验次数，达到后验证码失效需重新发送
+max_attempts = 5
+# 同一用途与收件人的重发间隔(秒)
+cooldown = 60
+# 每个收件人每天最多发送次数（0 表示不限制）
+recipient_per_day = 10
+# 每个IP每小时最多发送次数（0 表示不限制）
+ip_per_hour = 10
+# 每个IP每天最多发送次数（0 表示不限制）
+ip_per_day = 20
+
+# 防暴力破解配置（登录、找回密码、验证码校验）
+[login_guard]
+# 是否开启
+enable = true
+# 同一账号在统计窗口内失败N次后临时锁定（0 表示不锁定）
+account_limit = 5
+# 同一IP在统计窗口内失败N次后临时锁定（0 表示不锁定）
+ip_limit = 20
+# 失败次数统计窗口(秒)
+window = 900
+# 锁定时长(秒)
+lock = 900
+# 渐进延迟基数(秒)，第N次失败后需等待 delay * 2^(N-1) 秒才能再次尝试
+delay = 1
+# 渐进延迟上限(秒)
+max_delay = 30
+# 账号或IP失败N次后要求人机验证（0 表示不要求）
+captcha_after = 3
+
+# 审计日志配置（记录 /api/ 下的全部非 GET 请求与以下敏感 GET 请求）
+[audit]
+# 是否开启
+enable = true
+# 保留天数（0 表示永久保留）
+retention_days = 180
+# 单次最多导出条数
+export_limit = 10000
+# 需要审计的 GET 请求路径，以 * 结尾表示前缀匹配
+sensitive_get = ["/api/toml/*", "/api/audit-log/*", "/api/oauth-clients/*", "/api/user-sessions/*"]
+
+# 接口签名配置（使用 API_KEY 的客户端按 HMAC-SHA256 对请求签名，防篡改与重放，签名方式见 app/client）
+[signature]
+# 是否开启
+enable = false
+# 时间戳允许的误差（秒），nonce 在两倍误差时间内不可重复使用
+window = 300
+# 需要签名的接口，支持 /api/article/*、[POST]/api/* 写法，为空表示全部接口
+routes = []
+
+# 人机验证（验证码）配置，需要验证的接口在系统配置 SYSTEM_CAPTCHA 中设置
+[captcha]
+# 题目有效期（秒）
+expire = 300
+# 图形验证码的字符数
+length = 4
+# 图片宽度
+width = 120
+# 图片高度
+height = 40
+# 工作量证明的难度（SHA-256 前导零位数，每加 1 计算量翻倍）
+pow_difficulty = 18
+
+# GeoIP 配置（IP黑白名单的 country:CN、asn:13335 规则使用，数据库为 MaxMind 格式的 .mmdb 文件，如 GeoLite2）
+[geoip]
+# 国家数据库路径（GeoLite2-Country 或 GeoLite2-City），为空表示不启用国家规则
+country_db = ""
+# ASN 数据库路径（GeoLite2-ASN），为空表示不启用 ASN 规则
+asn_db = ""
+
+# 两步验证（TOTP）配置
+[totp]
+# 身份验证器App中显示的发行方名称
+issuer = "inis"
+# 是否要求 root 权限组的成员必须开启两步验证（未开启时登录会要求先完成绑定）
+require_root = false
+# 允许的时间步偏移（每步30秒，用于容忍设备时间误差）
+skew = 1
+
+# 通行密钥（WebAuthn）配置
+[webauthn]
+# 依赖方ID，一般为站点主域名（不含协议与端口），必填，未配置时无法使用通行密钥；修改后已绑定的通行密钥将失效
+rp_id = ""
+# 依赖方名称，显示在系统的通行密钥弹窗中
+rp_name = "inis"
+# 允许的来源（多个用,分隔，如 https://inis.cn），必填，须为 rp_id 或其子域名下的 https 地址
+origins = ""
+# 浏览器弹窗超时(毫秒)
+timeout = 60000
+
+# OAuth2 / OpenID Connect 授权服务（其他站点使用 inis 账号登录）
+[oauth_server]
+# 签发者，即站点对外的根地址（如 https://inis.cn），为空时使用请求的协议与域名
+issuer = ""
+# 前端授权确认页地址，第三方应用将用户重定向到此页面，为空时为 {issuer}/oauth/authorize
+authorize_page = ""
+# 授权码有效期(秒)
+code_expire = 60
+# 访问令牌与身份令牌有效期(秒)
+access_expire = 3600
+# 刷新令牌有效期(秒)，申请 offline_access 权限时签发
+refresh_expire = 2592000
 
 # CORS配置
 [system.cors]
@@ -47,7 +188,7 @@ allowed_origins = "http://localhost:3000,http://127.0.0.1:3000"
 # 允许的HTTP方法
 allowed_methods = "GET, POST, PATCH, PUT, DELETE, OPTIONS"
 # 允许的HTTP头
-allowed_headers = "X-Khronos, X-Gorgon, X-Argus, X-Ss-Stub, Token, Authorization, i-api-key, Content-Type, If-Match, If-Modified-Since, If-None-Match, If-Unmodified-Since, X-CSRF-TOKEN, X-Requested-With"
+allowed_headers = "X-Khronos, X-Gorgon, X-Argus, X-Ss-Stub, Token, Authorization, i-api-key, Content-Type, If-Match, If-Modified-Since, If-None-Match, If-Unmodified-Since, X-CSRF-TOKEN, X-Requested-With, X-Inis-Key-Id, X-Inis-Timestamp, X-Inis-Nonce, X-Inis-Signature"
 # 暴露的HTTP头
 exposed_headers = "Content-Type, Content-Length, Access-Control-Allow-Origin, Access-Control-Allow-Headers"
 # 是否允许携带凭证
@@ -209,6 +350,38 @@ sign_name         = "${tencent.sign_name}"
 verify_code       = "${tencent.verify_code}"
 # 区域
 region            = "${tencent.region}"
+
+
+# 通用 HTTP 短信网关配置（对接其它服务商或自建网关）
+# 地址与请求体为 Go text/template 模板，可用变量：
+# {{.phone}} 手机号、{{.code}} 验证码、{{.sign_name}} 短信签名、{{.template}} 验证码模板、{{.timestamp}} 时间戳
+# 在 JSON 请求体中使用 {{json .phone}} 输出带引号的转义字符串，在地址中使用 {{urlquery .phone}}
+[http]
+# 请求方式 GET/POST/PUT
+method        = "${http.method}"
+# 请求地址
+url           = "${http.url}"
+# 请求头（JSON 对象），如 {"Authorization": "Bearer xxx"}
+headers       = '${http.headers}'
+# 请求体模板（GET 请求忽略）
+body          = '''${http.body}'''
+# 请求体类型
+content_type  = "${http.content_type}"
+# 成功判断：响应 JSON 中的路径，如 $.code 或 $.data[0].status，为空时仅判断 HTTP 状态码
+success_path  = "${http.success_path}"
+# 成功判断：success_path 对应的值，为空时只要求该路径存在且不为空
+success_value = "${http.success_value}"
+# 失败时读取错误信息的路径，如 $.message
+message_path  = "${http.message_path}"
+# 短信签名
+sign_name     = "${http.sign_name}"
+# 验证码模板
+verify_code   = "${http.verify_code}"
+# 请求超时(秒)
+timeout       = "${http.timeout}"
+
+
+# 日志驱动无需配置：验证码与通知仅写入 runtime/sms/sms.log，不实际发送（仅用于开发调试）
 `
 
 // TempStorage - 存储配置模板
@@ -283,18 +456,112 @@ allow_extensions = "${attachment.allow_extensions}"
 max_file_size = ${attachment.max_file_size}
 # 并发上传限制（同时上传的最大文件数）
 concurrent_limit = ${attachment.concurrent_limit}
+
+
+# ======== 上传文件安全扫描配置（clamd 协议） ========
+[scanner]
+# 是否启用扫描
+enabled    = ${scanner.enabled}
+# 连接方式：tcp 或 unix
+network    = "${scanner.network}"
+# clamd 地址，如 127.0.0.1:3310 或 /var/run/clamav/clamd.ctl
+address    = "${scanner.address}"
+# 超时时间（秒）
+timeout    = ${scanner.timeout}
+# 扫描服务不可用时是否放行上传（false 为拒绝上传）
+fail_open  = ${scanner.fail_open}
+# 隔离目录 - 被拦截的文件存放于此，不对外提供访问
+quarantine = "${scanner.quarantine}"
 `
 
 const TempCrypt = `# ======== 加密配置 ========
 
 # JWT配置
 [jwt]
-# 密钥
-key      = "${jwt.key}"
-# 过期时间(秒)
-expire   = "${jwt.expire}"
+# HS256 共享密钥（早期版本的唯一签名密钥，现已迁入下方的密钥环；轮换密钥时不再修改）
+key           = "${jwt.key}"
+# 登录会话（刷新令牌）有效期(秒)
+expire        = "${jwt.expire}"
+# 访问令牌有效期(秒)，过期后使用刷新令牌换取新的访问令牌
+access_expire = "${jwt.access_expire}"
 # 签发者
-issuer   = "${jwt.issuer}"
+issuer        = "${jwt.issuer}"
 # 主题
-subject  = "${jwt.subject}"
+subject       = "${jwt.subject}"
+` + TempVapid + TempOidcKey + TempSeal + TempJwtKeys
+
+// TempVapid - Web Push 的 VAPID 密钥配置（旧版本的 crypt.toml 缺少该配置时自动追加）
+const TempVapid = `
+# Web Push VAPID 密钥（base64url 编码的 P-256 密钥对，更换后浏览器需重新订阅）
+[vapid]
+# 公钥 - 前端订阅时作为 applicationServerKey
+public_key  = "${vapid.public_key}"
+# 私钥
+private_key = "${vapid.private_key}"
+# 联系方式（mailto: 或 https: 地址），为空时使用站点域名
+subject     = "${vapid.subject}"
+`
+
+// TempOidcKey - OpenID Connect 身份令牌签名密钥配置（旧版本的 crypt.toml 缺少该配置时自动追加）
+const TempOidcKey = `
+# OpenID Connect 身份令牌签名密钥（base64 编码的 PKCS#8 RSA 私钥，公钥通过 /.well-known/jwks.json 公开）
+[oidc]
+private_key = "${oidc.private_key}"
+`
+
+// TempSeal - 数据库中敏感数据的加密密钥（旧版本的 crypt.toml 缺少该配置时自动追加）
+const TempSeal = `
+# 数据库中敏感数据（如接口签名密钥）的加密根密钥，只生成一次，与 JWT 签名密钥相互独立
+# 请勿修改，否则已加密的数据将无法解密
+[seal]
+key = "${seal.key}"
+`
+
+// TempJwtKeys - JWT 签名密钥环（旧版本的 crypt.toml 缺少该配置时由 jwt.key 迁入并追加）
+const TempJwtKeys = `
+# JWT 签名密钥环（由后台“轮换密钥”维护，请勿手动修改）
+# 第一个为当前签名密钥；其余为已停用的旧密钥，停用后在登录会话有效期内继续校验旧令牌，之后轮换时自动移除
+# RS256 与 EdDSA 的公钥通过 /.well-known/jwks.json 公开，第三方可据此校验 inis 签发的令牌
+${jwt_keys}`
+
+// TempOAuth - 第三方登录配置模板
+const TempOAuth = `# ======== 第三方登录配置（OAuth2 / OpenID Connect） ========
+
+# 回调地址，{provider} 会被替换为服务商名称（需与服务商后台登记的地址一致）
+# 回调页面拿到 code 与 state 后，提交到 POST /api/oauth/callback 完成登录或绑定
+redirect_uri  = "${redirect_uri}"
+# 首次登录且未绑定账号时是否自动注册（同时受后台“允许注册”开关控制）
+auto_register = ${auto_register}
+
+# 每个段落为一个服务商，段名即服务商名称（仅限小写字母、数字、-、_）
+# type 可选：github、qq、oidc（通用 OpenID Connect，通过 issuer 自动发现端点，可配置多个）
+
+[github]
+# 是否启用
+enable        = false
+type          = "github"
+# 显示名称
+title         = "GitHub"
+client_id     = ""
+client_secret = ""
+# 授权范围（多个用,分隔）
+scopes        = "read:user,user:email"
+
+[qq]
+enable        = false
+type          = "qq"
+title         = "QQ"
+client_id     = ""
+client_secret = ""
+scopes        = "get_user_info"
+
+[oidc]
+enable        = false
+type          = "oidc"
+title         = "OpenID Connect"
+# 签发者地址，将从 {issuer}/.well-known/openid-configuration 读取端点
+issuer        = ""
+client_id     = ""
+client_secret = ""
+scopes        = "openid,email,profile"
 `
@@ -0,0 +1,384 @@
+package facade
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"math/big"
+	"net/url"
+	"strings"
+
+	"github.com/unti-io/go-utils/utils"
+)
+
+// WebAuthn 认证器数据标志位
+const (
+	WebAuthnFlagUP = 0x01 // 用户在场（User Present）
+	WebAuthnFlagUV = 0x04 // 用户已验证（User Verified，指纹/面容/PIN）
+	WebAuthnFlagAT = 0x40 // 包含凭证数据（Attested credential data）
+	WebAuthnFlagED = 0x80 // 包含扩展数据（Extension data）
+)
+
+// COSE 签名算法（pubKeyCredParams 中的 alg）
+const (
+	WebAuthnAlgES256 = -7   // ECDSA P-256 + SHA-256
+	WebAuthnAlgEdDSA = -8   // Ed25519
+	WebAuthnAlgRS256 = -257 // RSASSA-PKCS1-v1_5 + SHA-256
+)
+
+// WebAuthnAlgs - 支持的签名算法（按优先级）
+var WebAuthnAlgs = []int{WebAuthnAlgES256, WebAuthnAlgEdDSA, WebAuthnAlgRS256}
+
+// WebAuthnClientData - 浏览器生成的 clientDataJSON
+type WebAuthnClientData struct {
+	// webauthn.create 或 webauthn.get
+	Type        string `json:"type"`
+	Challenge   string `json:"challenge"`
+	Origin      string `json:"origin"`
+	CrossOrigin bool   `json:"crossOrigin"`
+}
+
+// WebAuthnAuthData - 认证器数据（authenticatorData）
+type WebAuthnAuthData struct {
+	RPIDHash  []byte
+	Flags     byte
+	SignCount uint32
+	// 以下字段仅在注册（AT 标志）时存在
+	AAGUID       []byte
+	CredentialID []byte
+	// COSE 编码的公钥原文
+	PublicKey []byte
+}
+
+// UserPresent - 用户是否在场
+func (this *WebAuthnAuthData) UserPresent() bool {
+	return this.Flags&WebAuthnFlagUP != 0
+}
+
+// UserVerified - 认证器是否已验证用户身份
+func (this *WebAuthnAuthData) UserVerified() bool {
+	return this.Flags&WebAuthnFlagUV != 0
+}
+
+// WebAuthnChallenge - 生成 32 字节随机挑战（base64url）
+func WebAuthnChallenge() (string, error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(buf), nil
+}
+
+// WebAuthnDecode - 解码前端提交的二进制字段（兼容 base64url 与标准 base64，有无填充均可）
+func WebAuthnDecode(value string) ([]byte, error) {
+	value = strings.TrimRight(strings.TrimSpace(value), "=")
+	value = strings.NewReplacer("+", "-", "/", "_").Replace(value)
+	return base64.RawURLEncoding.DecodeString(value)
+}
+
+// WebAuthnOrigin - 规范化允许的来源（scheme://host[:port]），来源必须属于依赖方ID或其子域名，且为浏览器提供 WebAuthn 的安全上下文
+func WebAuthnOrigin(rpID, origin string) (string, error) {
+
+	item, err := url.Parse(strings.TrimSpace(origin))
+	if err != nil || utils.Is.Empty(item.Host) {
+		return "", fmt.Errorf("来源格式错误：%s", origin)
+	}
+	if (item.Path != "" && item.Path != "/") || item.RawQuery != "" || item.Fragment != "" || item.User != nil {
+		return "", fmt.Errorf("来源只能包含协议、域名与端口：%s", origin)
+	}
+
+	host := strings.ToLower(item.Hostname())
+	if host != rpID && !strings.HasSuffix(host, "."+rpID) {
+		return "", fmt.Errorf("来源 %s 不属于依赖方 %s", origin, rpID)
+	}
+	// 浏览器仅在安全上下文（https 或 localhost）中提供 WebAuthn
+	if item.Scheme != "https" && (item.Scheme != "http" || host != "localhost") {
+		return "", fmt.Errorf("来源必须使用 https：%s", origin)
+	}
+
+	// 与浏览器序列化的来源一致：默认端口省略
+	if port := item.Port(); !utils.Is.Empty(port) && !(item.Scheme == "https" && port == "443") && !(item.Scheme == "http" && port == "80") {
+		host += ":" + port
+	}
+
+	return item.Scheme + "://" + host, nil
+}
+
+// WebAuthnParseClientData - 解析 clientDataJSON
+func WebAuthnParseClientData(raw []byte) (*WebAuthnClientData, error) {
+	item := &WebAuthnClientData{}
+	if err := json.Unmarshal(raw, item); err != nil {
+		return nil, fmt.Errorf("clientDataJSON 解析失败：%v", err)
+	}
+	return item, nil
+}
+
+// WebAuthnParseAuthData - 解析认证器数据
+func WebAuthnParseAuthData(raw []byte) (*WebAuthnAuthData, error) {
+
+	if len(raw) < 37 {
+		return nil, errors.New("authenticatorData 长度错误")
+	}
+
+	item := &WebAuthnAuthData{
+		RPIDHash:  raw[:32],
+		Flags:     raw[32],
+		SignCount: binary.BigEndian.Uint32(raw[33:37]),
+	}
+
+	if item.Flags&WebAuthnFlagAT == 0 {
+		return item, nil
+	}
+
+	rest := raw[37:]
+	if len(rest) < 18 {
+		return nil, errors.New("凭证数据长度错误")
+	}
+	item.AAGUID = rest[:16]
+	size := int(binary.BigEndian.Uint16(rest[16:18]))
+	rest = rest[18:]
+	if size == 0 || size > 1023 || len(rest) < size {
+		return nil, errors.New("凭证ID长度错误")
+	}
+	item.CredentialID = rest[:size]
+	rest = rest[size:]
+
+	// 公钥为一个 CBOR 对象，其后可能紧跟扩展数据
+	_, next, err := cborDecode(rest, 0)
+	if err != nil {
+		return nil, fmt.Errorf("凭证公钥解析失败：%v", err)
+	}
+	item.PublicKey = rest[:len(rest)-len(next)]
+
+	return item, nil
+}
+
+// WebAuthnParseAttestation - 解析 attestationObject，返回证明格式与认证器数据
+/**
+ * 仅用于内部账号的无密码登录，注册时请求 attestation: "none"，不校验证明语句（attStmt）
+ */
+func WebAuthnParseAttestation(raw []byte) (format string, authData []byte, err error) {
+
+	value, _, err := cborDecode(raw, 0)
+	if err != nil {
+		return "", nil, fmt.Errorf("attestationObject 解析失败：%v", err)
+	}
+
+	item, ok := value.(map[any]any)
+	if !ok {
+		return "", nil, errors.New("attestationObject 格式错误")
+	}
+
+	format, _ = item["fmt"].(string)
+	authData, ok = item["authData"].([]byte)
+	if !ok || len(authData) == 0 {
+		return "", nil, errors.New("attestationObject 缺少 authData")
+	}
+
+	return format, authData, nil
+}
+
+// WebAuthnPublicKey - 解析 COSE 公钥，返回签名算法与公钥
+func WebAuthnPublicKey(cose []byte) (alg int, key crypto.PublicKey, err error) {
+
+	value, _, err := cborDecode(cose, 0)
+	if err != nil {
+		return 0, nil, err
+	}
+	item, ok := value.(map[any]any)
+	if !ok {
+		return 0, nil, errors.New("COSE 公钥格式错误")
+	}
+
+	integer := func(key int64) int64 {
+		number, _ := item[key].(int64)
+		return number
+	}
+	bytes := func(key int64) []byte {
+		buf, _ := item[key].([]byte)
+		return buf
+	}
+
+	alg = int(integer(3))
+	switch {
+	// EC2 + P-256
+	case integer(1) == 2 && alg == WebAuthnAlgES256 && integer(-1) == 1:
+		x, y := bytes(-2), bytes(-3)
+		if len(x) != 32 || len(y) != 32 {
+			return 0, nil, errors.New("EC2 公钥长度错误")
+		}
+		point := append(append([]byte{0x04}, x...), y...)
+		pub, err := ecdsa.ParseUncompressedPublicKey(elliptic.P256(), point)
+		if err != nil {
+			return 0, nil, err
+		}
+		return alg, pub, nil
+	// OKP + Ed25519
+	case integer(1) == 1 && alg == WebAuthnAlgEdDSA && integer(-1) == 6:
+		x := bytes(-2)
+		if len(x) != ed25519.PublicKeySize {
+			return 0, nil, errors.New("Ed25519 公钥长度错误")
+		}
+		return alg, ed25519.PublicKey(x), nil
+	// RSA
+	case integer(1) == 3 && alg == WebAuthnAlgRS256:
+		n, e := bytes(-1), bytes(-2)
+		if len(n) < 256 || len(e) == 0 || len(e) > 4 {
+			return 0, nil, errors.New("RSA 公钥长度错误")
+		}
+		return alg, &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(new(big.Int).SetBytes(e).Int64())}, nil
+	}
+
+	return 0, nil, fmt.Errorf("不支持的公钥算法：%d", alg)
+}
+
+// WebAuthnVerify - 使用 COSE 公钥校验签名（data 为 authenticatorData 与 clientDataJSON 哈希的拼接）
+func WebAuthnVerify(cose, data, sig []byte) error {
+
+	_, key, err := WebAuthnPublicKey(cose)
+	if err != nil {
+		return err
+	}
+
+	hash := sha256.Sum256(data)
+
+	switch pub := key.(type) {
+	case *ecdsa.PublicKey:
+		if ecdsa.VerifyASN1(pub, hash[:], sig) {
+			return nil
+		}
+	case ed25519.PublicKey:
+		if ed25519.Verify(pub, data, sig) {
+			return nil
+		}
+	case *rsa.PublicKey:
+		if rsa.VerifyPKCS1v15(pub, crypto.SHA256, hash[:], sig) == nil {
+			return nil
+		}
+	}
+
+	return errors.New("签名校验失败")
+}
+
+// cborDecode - 最小 CBOR 解码（RFC 8949），仅覆盖 WebAuthn 用到的类型，不支持不定长编码
+/**
+ * 整数解码为 int64，字节串为 []byte，文本为 string，数组为 []any，映射为 map[any]any
+ * @return value 解码结果，rest 剩余未解码的数据
+ */
+func cborDecode(data []byte, depth int) (value any, rest []byte, err error) {
+
+	if depth > 16 {
+		return nil, nil, errors.New("CBOR 嵌套过深")
+	}
+	if len(data) == 0 {
+		return nil, nil, errors.New("CBOR 数据不完整")
+	}
+
+	major, info := data[0]>>5, data[0]&0x1f
+	data = data[1:]
+
+	var arg uint64
+	switch {
+	case info < 24:
+		arg = uint64(info)
+	case info <= 27:
+		size := 1 << (info - 24)
+		if len(data) < size {
+			return nil, nil, errors.New("CBOR 数据不完整")
+		}
+		for _, b := range data[:size] {
+			arg = arg<<8 | uint64(b)
+		}
+		data = data[size:]
+	default:
+		return nil, nil, errors.New("不支持的 CBOR 编码")
+	}
+
+	switch major {
+	// 无符号整数
+	case 0:
+		if arg > math.MaxInt64 {
+			return nil, nil, errors.New("CBOR 整数溢出")
+		}
+		return int64(arg), data, nil
+	// 负整数
+	case 1:
+		if arg > math.MaxInt64 {
+			return nil, nil, errors.New("CBOR 整数溢出")
+		}
+		return -1 - int64(arg), data, nil
+	// 字节串、文本
+	case 2, 3:
+		if arg > uint64(len(data)) {
+			return nil, nil, errors.New("CBOR 数据不完整")
+		}
+		if major == 2 {
+			return append([]byte{}, data[:arg]...), data[arg:], nil
+		}
+		return string(data[:arg]), data[arg:], nil
+	// 数组
+	case 4:
+		if arg > uint64(len(data)) {
+			return nil, nil, errors.New("CBOR 数据不完整")
+		}
+		items := make([]any, 0, arg)
+		for range arg {
+			var item any
+			if item, data, err = cborDecode(data, depth+1); err != nil {
+				return nil, nil, err
+			}
+			items = append(items, item)
+		}
+		return items, data, nil
+	// 映射（键仅支持整数与文本）
+	case 5:
+		if arg > uint64(len(data)) {
+			return nil, nil, errors.New("CBOR 数据不完整")
+		}
+		items := make(map[any]any, arg)
+		for range arg {
+			var key, item any
+			if key, data, err = cborDecode(data, depth+1); err != nil {
+				return nil, nil, err
+			}
+			switch key.(type) {
+			case int64, string:
+			default:
+				return nil, nil, errors.New("不支持的 CBOR 映射键")
+			}
+			if item, data, err = cborDecode(data, depth+1); err != nil {
+				return nil, nil, err
+			}
+			items[key] = item
+		}
+		return items, data, nil
+	// 标签：忽略标签，返回被标记的值
+	case 6:
+		return cborDecode(data, depth+1)
+	// 简单值与浮点数
+	default:
+		switch info {
+		case 20:
+			return false, data, nil
+		case 21:
+			return true, data, nil
+		case 22, 23:
+			return nil, data, nil
+		case 26:
+			return float64(math.Float32frombits(uint32(arg))), data, nil
+		case 27:
+			return math.Float64frombits(arg), data, nil
+		}
+		return nil, nil, errors.New("不支持的 CBOR 简单值")
+	}
+}
@@ -0,0 +1,294 @@
+package facade
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"sort"
+	"testing"
+)
+
+// cborEncode - 测试用的最小 CBOR 编码（整数、字节串、文本、映射）
+func cborEncode(value any) []byte {
+
+	head := func(major byte, arg uint64) []byte {
+		switch {
+		case arg < 24:
+			return []byte{major<<5 | byte(arg)}
+		case arg <= 0xff:
+			return []byte{major<<5 | 24, byte(arg)}
+		case arg <= 0xffff:
+			return binary.BigEndian.AppendUint16([]byte{major<<5 | 25}, uint16(arg))
+		}
+		return binary.BigEndian.AppendUint32([]byte{major<<5 | 26}, uint32(arg))
+	}
+
+	switch item := value.(type) {
+	case int:
+		if item < 0 {
+			return head(1, uint64(-1-item))
+		}
+		return head(0, uint64(item))
+	case []byte:
+		return append(head(2, uint64(len(item))), item...)
+	case string:
+		return append(head(3, uint64(len(item))), item...)
+	case map[any]any:
+		// 按编码后的键排序，保证输出稳定
+		var keys [][]byte
+		values := map[string][]byte{}
+		for key, val := range item {
+			encoded := cborEncode(key)
+			keys = append(keys, encoded)
+			values[string(encoded)] = cborEncode(val)
+		}
+		sort.Slice(keys, func(i, j int) bool { return bytes.Compare(keys[i], keys[j]) < 0 })
+		buf := head(5, uint64(len(item)))
+		for _, key := range keys {
+			buf = append(append(buf, key...), values[string(key)]...)
+		}
+		return buf
+	}
+	panic("unsupported type")
+}
+
+// softAuthenticator - 软件认证器，模拟浏览器与平台认证器完成注册与验证仪式
+type softAuthenticator struct {
+	rpID   string
+	origin string
+	id     []byte
+	count  uint32
+	ec     *ecdsa.PrivateKey
+	ed     ed25519.PrivateKey
+}
+
+func newSoftAuthenticator(t *testing.T, rpID, origin string, alg int) *softAuthenticator {
+
+	item := &softAuthenticator{rpID: rpID, origin: origin, id: make([]byte, 32)}
+	if _, err := rand.Read(item.id); err != nil {
+		t.Fatal(err)
+	}
+
+	var err error
+	switch alg {
+	case WebAuthnAlgES256:
+		item.ec, err = ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	case WebAuthnAlgEdDSA:
+		_, item.ed, err = ed25519.GenerateKey(rand.Reader)
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	return item
+}
+
+// cose - 凭证公钥的 COSE 编码
+func (this *softAuthenticator) cose() []byte {
+	if this.ed != nil {
+		return cborEncode(map[any]any{1: 1, 3: WebAuthnAlgEdDSA, -1: 6, -2: []byte(this.ed.Public().(ed25519.PublicKey))})
+	}
+	point, _ := this.ec.PublicKey.Bytes()
+	return cborEncode(map[any]any{1: 2, 3: WebAuthnAlgES256, -1: 1, -2: point[1:33], -3: point[33:]})
+}
+
+// clientData - 浏览器生成的 clientDataJSON
+func (this *softAuthenticator) clientData(kind, challenge string) []byte {
+	raw, _ := json.Marshal(map[string]any{"type": kind, "challenge": challenge, "origin": this.origin, "crossOrigin": false})
+	return raw
+}
+
+// authData - 认证器数据，注册时附带凭证数据
+func (this *softAuthenticator) authData(flags byte, attested bool) []byte {
+	hash := sha256.Sum256([]byte(this.rpID))
+	buf := append(hash[:], flags)
+	buf = binary.BigEndian.AppendUint32(buf, this.count)
+	if attested {
+		buf = append(buf, make([]byte, 16)...)
+		buf = binary.BigEndian.AppendUint16(buf, uint16(len(this.id)))
+		buf = append(append(buf, this.id...), this.cose()...)
+	}
+	return buf
+}
+
+// create - navigator.credentials.create（attestation: none）
+func (this *softAuthenticator) create(challenge string) (clientData, attestation []byte) {
+	auth := this.authData(WebAuthnFlagUP|WebAuthnFlagUV|WebAuthnFlagAT, true)
+	return this.clientData("webauthn.create", challenge), cborEncode(map[any]any{"fmt": "none", "attStmt": map[any]any{}, "authData": auth})
+}
+
+// get - navigator.credentials.get，每次签名计数递增
+func (this *softAuthenticator) get(t *testing.T, challenge string) (clientData, authData, sig []byte) {
+
+	this.count++
+	clientData = this.clientData("webauthn.get", challenge)
+	authData = this.authData(WebAuthnFlagUP|WebAuthnFlagUV, false)
+
+	hash := sha256.Sum256(clientData)
+	data := append(append([]byte{}, authData...), hash[:]...)
+
+	var err error
+	if this.ed != nil {
+		sig = ed25519.Sign(this.ed, data)
+	} else {
+		digest := sha256.Sum256(data)
+		sig, err = ecdsa.SignASN1(rand.Reader, this.ec, digest[:])
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	return clientData, authData, sig
+}
+
+func TestWebAuthnSoftAuthenticator(t *testing.T) {
+
+	for _, alg := range []int{WebAuthnAlgES256, WebAuthnAlgEdDSA} {
+
+		authenticator := newSoftAuthenticator(t, "inis.cn", "https://inis.cn", alg)
+		rpHash := sha256.Sum256([]byte("inis.cn"))
+
+		// 注册仪式
+		challenge, err := WebAuthnChallenge()
+		if err != nil {
+			t.Fatal(err)
+		}
+		clientData, attestation := authenticator.create(challenge)
+
+		data, err := WebAuthnParseClientData(clientData)
+		if err != nil || data.Type != "webauthn.create" || data.Challenge != challenge || data.Origin != "https://inis.cn" {
+			t.Fatalf("alg %d：clientDataJSON 解析结果不符：%+v %v", alg, data, err)
+		}
+
+		format, raw, err := WebAuthnParseAttestation(attestation)
+		if err != nil || format != "none" {
+			t.Fatalf("alg %d：attestationObject 解析失败：%q %v", alg, format, err)
+		}
+		auth, err := WebAuthnParseAuthData(raw)
+		if err != nil {
+			t.Fatalf("alg %d：authenticatorData 解析失败：%v", alg, err)
+		}
+		if !bytes.Equal(auth.RPIDHash, rpHash[:]) || !auth.UserPresent() || !auth.UserVerified() {
+			t.Fatalf("alg %d：认证器数据标志或依赖方哈希不符", alg)
+		}
+		if !bytes.Equal(auth.CredentialID, authenticator.id) || !bytes.Equal(auth.PublicKey, authenticator.cose()) {
+			t.Fatalf("alg %d：凭证ID或公钥不符", alg)
+		}
+		if got, _, err := WebAuthnPublicKey(auth.PublicKey); err != nil || got != alg {
+			t.Fatalf("alg %d：公钥算法 = %d（%v）", alg, got, err)
+		}
+		cose := auth.PublicKey
+
+		// 验证仪式：连续两次，签名计数递增
+		var last uint32
+		for range 2 {
+			clientData, authData, sig := authenticator.get(t, challenge)
+			item, err := WebAuthnParseAuthData(authData)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if item.SignCount <= last {
+				t.Fatalf("alg %d：签名计数未递增：%d <= %d", alg, item.SignCount, last)
+			}
+			last = item.SignCount
+
+			hash := sha256.Sum256(clientData)
+			signed := append(append([]byte{}, authData...), hash[:]...)
+			if err := WebAuthnVerify(cose, signed, sig); err != nil {
+				t.Fatalf("alg %d：签名校验失败：%v", alg, err)
+			}
+
+			// 篡改 clientDataJSON（如替换来源）后签名失效
+			forged := sha256.Sum256(bytes.Replace(clientData, []byte("inis.cn"), []byte("evil.cn"), 1))
+			if WebAuthnVerify(cose, append(append([]byte{}, authData...), forged[:]...), sig) == nil {
+				t.Fatalf("alg %d：篡改后的数据不应通过校验", alg)
+			}
+		}
+
+		// 其他认证器的签名不能通过该凭证的校验
+		other := newSoftAuthenticator(t, "inis.cn", "https://inis.cn", alg)
+		clientData, authData, sig := other.get(t, challenge)
+		hash := sha256.Sum256(clientData)
+		if WebAuthnVerify(cose, append(append([]byte{}, authData...), hash[:]...), sig) == nil {
+			t.Fatalf("alg %d：其他认证器的签名不应通过校验", alg)
+		}
+	}
+}
+
+func TestWebAuthnParseMalformed(t *testing.T) {
+
+	authenticator := newSoftAuthenticator(t, "inis.cn", "https://inis.cn", WebAuthnAlgES256)
+	raw := authenticator.authData(WebAuthnFlagUP|WebAuthnFlagAT, true)
+
+	tests := map[string][]byte{
+		"too short":      raw[:36],
+		"no credential":  raw[:37+10],
+		"truncated id":   raw[:37+18+8],
+		"truncated cose": raw[:len(raw)-5],
+	}
+	for name, item := range tests {
+		if _, err := WebAuthnParseAuthData(item); err == nil {
+			t.Errorf("%s：应返回错误", name)
+		}
+	}
+
+	// 嵌套过深的 CBOR
+	if _, _, err := cborDecode(append(bytes.Repeat([]byte{0x81}, 32), 0x00), 0); err == nil {
+		t.Error("嵌套过深的 CBOR 应返回错误")
+	}
+	if _, _, err := WebAuthnParseAttestation(cborEncode(map[any]any{"fmt": "none"})); err == nil {
+		t.Error("缺少 authData 时应返回错误")
+	}
+	if _, _, err := WebAuthnPublicKey(cborEncode(map[any]any{1: 2, 3: -36, -1: 1})); err == nil {
+		t.Error("不支持的算法应返回错误")
+	}
+}
+
+func TestWebAuthnOrigin(t *testing.T) {
+
+	tests := []struct {
+		origin string
+		want   string
+		ok     bool
+	}{
+		{"https://inis.cn", "https://inis.cn", true},
+		{"https://inis.cn/", "https://inis.cn", true},
+		{"https://INIS.cn:443", "https://inis.cn", true},
+		{"https://admin.inis.cn:8443", "https://admin.inis.cn:8443", true},
+		{"http://localhost:3000", "", false},
+		{"http://inis.cn", "", false},
+		{"https://evilinis.cn", "", false},
+		{"https://inis.cn.evil.com", "", false},
+		{"https://inis.cn/login", "", false},
+		{"https://user@inis.cn", "", false},
+		{"inis.cn", "", false},
+	}
+
+	for _, item := range tests {
+		got, err := WebAuthnOrigin("inis.cn", item.origin)
+		if (err == nil) != item.ok || got != item.want {
+			t.Errorf("WebAuthnOrigin(%q) = %q, %v，期望 %q", item.origin, got, err, item.want)
+		}
+	}
+
+	if got, err := WebAuthnOrigin("localhost", "http://localhost:3000"); err != nil || got != "http://localhost:3000" {
+		t.Errorf("本地开发来源 = %q, %v", got, err)
+	}
+}
+
+func TestWebAuthnDecode(t *testing.T) {
+	raw := []byte{0xfb, 0xff, 0x01}
+	for _, value := range []string{
+		base64.RawURLEncoding.EncodeToString(raw),
+		base64.URLEncoding.EncodeToString(raw),
+		base64.StdEncoding.EncodeToString(raw),
+	} {
+		if got, err := WebAuthnDecode(value); err != nil || !bytes.Equal(got, raw) {
+			t.Errorf("WebAuthnDecode(%q) = %x, %v", value, got, err)
+		}
+	}
+}
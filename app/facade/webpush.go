@@ -0,0 +1,300 @@
+package facade
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/ecdh"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/hkdf"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	JWT "github.com/golang-jwt/jwt/v5"
+	"github.com/spf13/cast"
+	"github.com/unti-io/go-utils/utils"
+)
+
+const (
+	// webPushRecordSize - aes128gcm 记录大小（RFC 8188），负载须在单条记录内
+	webPushRecordSize = 4096
+	// WebPushMaxPayload - 单条推送的最大明文长度（记录大小 - 16 字节认证标签 - 1 字节分隔符）
+	WebPushMaxPayload = webPushRecordSize - 16 - 1
+	// webPushTimeout - 推送服务请求超时时间
+	webPushTimeout = 10 * time.Second
+)
+
+// ErrWebPushGone - 订阅已失效（推送服务返回 404/410），调用方应删除该订阅
+var ErrWebPushGone = errors.New("推送订阅已失效")
+
+// webPushClient - 投递推送使用的客户端：连接时校验实际解析出的地址，且不跟随重定向，避免被引导至内网
+var webPushClient = func() *http.Client {
+	return Comm.SafeOutboundClient(webPushTimeout)
+}
+
+// WebPushSubscription - 浏览器 PushSubscription 的关键信息
+type WebPushSubscription struct {
+	// 推送服务地址
+	Endpoint string `json:"endpoint"`
+	// 浏览器公钥（base64url 编码的 P-256 非压缩点）
+	P256dh string `json:"p256dh"`
+	// 认证密钥（base64url 编码的 16 字节随机数）
+	Auth string `json:"auth"`
+}
+
+// WebPushOptions - 推送选项
+type WebPushOptions struct {
+	// 消息在推送服务中的保留时间（秒）
+	TTL int
+	// 紧急程度 very-low / low / normal / high
+	Urgency string
+	// 相同 Topic 的未送达消息会被替换
+	Topic string
+}
+
+// webPushEncoding - 密钥的 base64url 编码（无填充）
+var webPushEncoding = base64.RawURLEncoding
+
+// webPushDecode - 兼容带填充与标准 base64 的密钥
+func webPushDecode(value string) ([]byte, error) {
+	value = strings.TrimRight(strings.TrimSpace(value), "=")
+	value = strings.NewReplacer("+", "-", "/", "_").Replace(value)
+	return webPushEncoding.DecodeString(value)
+}
+
+// WebPushGenerateKeys - 生成 VAPID 密钥对（base64url 编码）
+func WebPushGenerateKeys() (publicKey, privateKey string, err error) {
+	key, err := ecdh.P256().GenerateKey(rand.Reader)
+	if err != nil {
+		return "", "", err
+	}
+	return webPushEncoding.EncodeToString(key.PublicKey().Bytes()), webPushEncoding.EncodeToString(key.Bytes()), nil
+}
+
+// initVapid - 补全 VAPID 密钥（旧版本生成的 crypt.toml 中没有 [vapid] 配置）
+func initVapid() {
+
+	if CryptToml == nil || CryptToml.Viper == nil {
+		return
+	}
+	if !utils.Is.Empty(CryptToml.Get("vapid.private_key", "")) {
+		return
+	}
+
+	publicKey, privateKey, err := WebPushGenerateKeys()
+	if err != nil {
+		Log.Error(map[string]any{"error": err}, "VAPID密钥生成失败")
+		return
+	}
+
+	// 先写入内存，避免追加文件触发的配置重载前出现空密钥
+	CryptToml.Viper.Set("vapid.public_key", publicKey)
+	CryptToml.Viper.Set("vapid.private_key", privateKey)
+
+	filePath := fmt.Sprintf("%s/%s.%s", ConfigPath, ConfigNameCrypt, ModeToml)
+	file, err := os.OpenFile(filePath, os.O_APPEND|os.O_WRONLY, 0755)
+	if err != nil {
+		Log.Error(map[string]any{"error": err}, "VAPID密钥写入失败")
+		return
+	}
+	defer file.Close()
+
+	content := utils.Replace(TempVapid, map[string]any{
+		"${vapid.public_key}":  publicKey,
+		"${vapid.private_key}": privateKey,
+		"${vapid.subject}":     "",
+	})
+	if _, err := file.WriteString(content); err != nil {
+		Log.Error(map[string]any{"error": err}, "VAPID密钥写入失败")
+	}
+}
+
+// WebPushPublicKey - VAPID 公钥（前端订阅时使用）
+func WebPushPublicKey() string {
+	if CryptToml == nil {
+		return ""
+	}
+	return cast.ToString(CryptToml.Get("vapid.public_key", ""))
+}
+
+// webPushVapid - 生成 VAPID 认证头 vapid t=<jwt>, k=<公钥>（RFC 8292）
+func webPushVapid(endpoint string) (string, error) {
+
+	publicKey := WebPushPublicKey()
+	raw, err := webPushDecode(cast.ToString(CryptToml.Get("vapid.private_key", "")))
+	if err != nil || utils.Is.Empty(publicKey) {
+		return "", errors.New("VAPID密钥未配置")
+	}
+
+	key, err := ecdsa.ParseRawPrivateKey(elliptic.P256(), raw)
+	if err != nil {
+		return "", fmt.Errorf("VAPID私钥格式错误：%v", err)
+	}
+
+	target, err := url.Parse(endpoint)
+	if err != nil {
+		return "", err
+	}
+
+	subject := cast.ToString(CryptToml.Get("vapid.subject", ""))
+	if utils.Is.Empty(subject) {
+		subject = strings.TrimRight(cast.ToString(Var.Get("domain")), "/")
+	}
+	if utils.Is.Empty(subject) {
+		subject = "mailto:admin@inis.cn"
+	}
+
+	token, err := JWT.NewWithClaims(JWT.SigningMethodES256, JWT.MapClaims{
+		"aud": target.Scheme + "://" + target.Host,
+		"exp": time.Now().Add(12 * time.Hour).Unix(),
+		"sub": subject,
+	}).SignedString(key)
+	if err != nil {
+		return "", err
+	}
+
+	return fmt.Sprintf("vapid t=%s, k=%s", token, publicKey), nil
+}
+
+// WebPushEncrypt - 按 RFC 8291 加密推送负载（aes128gcm 内容编码）
+func WebPushEncrypt(subscription WebPushSubscription, payload []byte) ([]byte, error) {
+
+	if len(payload) > WebPushMaxPayload {
+		return nil, fmt.Errorf("推送内容超过 %d 字节", WebPushMaxPayload)
+	}
+
+	uaRaw, err := webPushDecode(subscription.P256dh)
+	if err != nil {
+		return nil, errors.New("订阅公钥格式错误")
+	}
+	uaPublic, err := ecdh.P256().NewPublicKey(uaRaw)
+	if err != nil {
+		return nil, errors.New("订阅公钥格式错误")
+	}
+	authSecret, err := webPushDecode(subscription.Auth)
+	if err != nil || len(authSecret) == 0 {
+		return nil, errors.New("订阅认证密钥格式错误")
+	}
+
+	// 每条消息使用一次性的服务端密钥对
+	asPrivate, err := ecdh.P256().GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+	asPublic := asPrivate.PublicKey().Bytes()
+
+	secret, err := asPrivate.ECDH(uaPublic)
+	if err != nil {
+		return nil, err
+	}
+
+	// IKM = HKDF(auth_secret, ecdh_secret, "WebPush: info" || 0x00 || ua_public || as_public, 32)
+	prkKey, err := hkdf.Extract(sha256.New, secret, authSecret)
+	if err != nil {
+		return nil, err
+	}
+	ikm, err := hkdf.Expand(sha256.New, prkKey, "WebPush: info\x00"+string(uaRaw)+string(asPublic), 32)
+	if err != nil {
+		return nil, err
+	}
+
+	salt := make([]byte, 16)
+	if _, err := rand.Read(salt); err != nil {
+		return nil, err
+	}
+
+	prk, err := hkdf.Extract(sha256.New, ikm, salt)
+	if err != nil {
+		return nil, err
+	}
+	cek, err := hkdf.Expand(sha256.New, prk, "Content-Encoding: aes128gcm\x00", 16)
+	if err != nil {
+		return nil, err
+	}
+	nonce, err := hkdf.Expand(sha256.New, prk, "Content-Encoding: nonce\x00", 12)
+	if err != nil {
+		return nil, err
+	}
+
+	block, err := aes.NewCipher(cek)
+	if err != nil {
+		return nil, err
+	}
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+
+	// 单条记录，0x02 表示最后一条记录
+	plaintext := append(append([]byte{}, payload...), 0x02)
+
+	// 头部：salt(16) || rs(4) || idlen(1) || keyid(as_public)
+	header := bytes.NewBuffer(salt)
+	binary.Write(header, binary.BigEndian, uint32(webPushRecordSize))
+	header.WriteByte(byte(len(asPublic)))
+	header.Write(asPublic)
+
+	return gcm.Seal(header.Bytes(), nonce, plaintext, nil), nil
+}
+
+// WebPushSend - 向推送服务投递一条加密消息，订阅失效时返回 ErrWebPushGone
+func WebPushSend(subscription WebPushSubscription, payload []byte, opts ...WebPushOptions) error {
+
+	opt := WebPushOptions{TTL: 86400, Urgency: "normal"}
+	if len(opts) > 0 {
+		opt = opts[0]
+	}
+
+	body, err := WebPushEncrypt(subscription, payload)
+	if err != nil {
+		return err
+	}
+
+	authorization, err := webPushVapid(subscription.Endpoint)
+	if err != nil {
+		return err
+	}
+
+	request, err := http.NewRequest(http.MethodPost, subscription.Endpoint, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+
+	request.Header.Set("Authorization", authorization)
+	request.Header.Set("Content-Encoding", "aes128gcm")
+	request.Header.Set("Content-Type", "application/octet-stream")
+	request.Header.Set("TTL", cast.ToString(opt.TTL))
+	if !utils.Is.Empty(opt.Urgency) {
+		request.Header.Set("Urgency", opt.Urgency)
+	}
+	if !utils.Is.Empty(opt.Topic) {
+		request.Header.Set("Topic", opt.Topic)
+	}
+
+	response, err := webPushClient().Do(request)
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+
+	switch {
+	case response.StatusCode >= 200 && response.StatusCode < 300:
+		return nil
+	case response.StatusCode == http.StatusNotFound || response.StatusCode == http.StatusGone:
+		return ErrWebPushGone
+	}
+
+	message, _ := io.ReadAll(io.LimitReader(response.Body, 512))
+	return fmt.Errorf("HTTP %d %s", response.StatusCode, strings.TrimSpace(string(message)))
+}
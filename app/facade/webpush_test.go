@@ -0,0 +1,284 @@
+package facade
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/ecdh"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/hkdf"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	JWT "github.com/golang-jwt/jwt/v5"
+	"github.com/unti-io/go-utils/utils"
+)
+
+// webPushTestKeys - 写入临时的 VAPID 配置，返回公钥
+func webPushTestKeys(t *testing.T) string {
+
+	publicKey, privateKey, err := WebPushGenerateKeys()
+	if err != nil {
+		t.Fatalf("生成 VAPID 密钥失败：%v", err)
+	}
+
+	dir := t.TempDir()
+	content := fmt.Sprintf("[vapid]\npublic_key = \"%s\"\nprivate_key = \"%s\"\nsubject = \"mailto:test@inis.cn\"\n", publicKey, privateKey)
+	if err := os.WriteFile(filepath.Join(dir, "crypt.toml"), []byte(content), 0644); err != nil {
+		t.Fatalf("写入配置失败：%v", err)
+	}
+
+	item := utils.Viper(utils.ViperModel{Path: dir, Mode: ModeToml, Name: "crypt"}).Read()
+	if item.Error != nil {
+		t.Fatalf("读取配置失败：%v", item.Error)
+	}
+
+	origin := CryptToml
+	CryptToml = &item
+	t.Cleanup(func() { CryptToml = origin })
+
+	return publicKey
+}
+
+// webPushTestSubscription - 模拟浏览器生成订阅密钥
+func webPushTestSubscription(t *testing.T, endpoint string) (WebPushSubscription, *ecdh.PrivateKey, []byte) {
+
+	key, err := ecdh.P256().GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("生成订阅密钥失败：%v", err)
+	}
+	auth := make([]byte, 16)
+	if _, err := rand.Read(auth); err != nil {
+		t.Fatalf("生成认证密钥失败：%v", err)
+	}
+
+	return WebPushSubscription{
+		Endpoint: endpoint,
+		P256dh:   webPushEncoding.EncodeToString(key.PublicKey().Bytes()),
+		Auth:     webPushEncoding.EncodeToString(auth),
+	}, key, auth
+}
+
+// webPushTestDecrypt - 以浏览器身份按 RFC 8291 解密 aes128gcm 负载
+func webPushTestDecrypt(body []byte, key *ecdh.PrivateKey, auth []byte) ([]byte, error) {
+
+	if len(body) < 21 {
+		return nil, errors.New("负载过短")
+	}
+	salt, recordSize, idlen := body[:16], binary.BigEndian.Uint32(body[16:20]), int(body[20])
+	if recordSize != webPushRecordSize {
+		return nil, fmt.Errorf("记录大小 = %d", recordSize)
+	}
+	if len(body) < 21+idlen {
+		return nil, errors.New("keyid 越界")
+	}
+	asRaw, ciphertext := body[21:21+idlen], body[21+idlen:]
+
+	asPublic, err := ecdh.P256().NewPublicKey(asRaw)
+	if err != nil {
+		return nil, err
+	}
+	secret, err := key.ECDH(asPublic)
+	if err != nil {
+		return nil, err
+	}
+
+	prkKey, err := hkdf.Extract(sha256.New, secret, auth)
+	if err != nil {
+		return nil, err
+	}
+	ikm, err := hkdf.Expand(sha256.New, prkKey, "WebPush: info\x00"+string(key.PublicKey().Bytes())+string(asRaw), 32)
+	if err != nil {
+		return nil, err
+	}
+	prk, err := hkdf.Extract(sha256.New, ikm, salt)
+	if err != nil {
+		return nil, err
+	}
+	cek, err := hkdf.Expand(sha256.New, prk, "Content-Encoding: aes128gcm\x00", 16)
+	if err != nil {
+		return nil, err
+	}
+	nonce, err := hkdf.Expand(sha256.New, prk, "Content-Encoding: nonce\x00", 12)
+	if err != nil {
+		return nil, err
+	}
+
+	block, err := aes.NewCipher(cek)
+	if err != nil {
+		return nil, err
+	}
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+	plaintext, err := gcm.Open(nil, nonce, ciphertext, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(plaintext) == 0 || plaintext[len(plaintext)-1] != 0x02 {
+		return nil, errors.New("缺少最后一条记录的分隔符")
+	}
+	return plaintext[:len(plaintext)-1], nil
+}
+
+// webPushTestVapid - 校验 vapid t=<jwt>, k=<公钥> 认证头
+func webPushTestVapid(authorization, publicKey, audience string) error {
+
+	params, ok := strings.CutPrefix(authorization, "vapid ")
+	if !ok {
+		return fmt.Errorf("认证头格式错误：%q", authorization)
+	}
+	var token, key string
+	for _, item := range strings.Split(params, ",") {
+		name, value, _ := strings.Cut(strings.TrimSpace(item), "=")
+		switch name {
+		case "t":
+			token = value
+		case "k":
+			key = value
+		}
+	}
+	if key != publicKey {
+		return fmt.Errorf("k = %q，期望 %q", key, publicKey)
+	}
+
+	raw, err := webPushDecode(key)
+	if err != nil {
+		return err
+	}
+	verify, err := ecdsa.ParseUncompressedPublicKey(elliptic.P256(), raw)
+	if err != nil {
+		return err
+	}
+
+	claims := JWT.MapClaims{}
+	_, err = JWT.ParseWithClaims(token, claims, func(*JWT.Token) (any, error) { return verify, nil },
+		JWT.WithValidMethods([]string{JWT.SigningMethodES256.Alg()}),
+		JWT.WithAudience(audience),
+		JWT.WithExpirationRequired(),
+	)
+	if err != nil {
+		return err
+	}
+	if claims["sub"] != "mailto:test@inis.cn" {
+		return fmt.Errorf("sub = %v", claims["sub"])
+	}
+	return nil
+}
+
+func TestWebPushSend(t *testing.T) {
+
+	publicKey := webPushTestKeys(t)
+	payload := []byte(`{"title":"inis","body":"你有一条新通知"}`)
+
+	var subscription WebPushSubscription
+	var key *ecdh.PrivateKey
+	var auth []byte
+	var failure error
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+
+		failure = func() error {
+			if err := webPushTestVapid(r.Header.Get("Authorization"), publicKey, "http://"+r.Host); err != nil {
+				return fmt.Errorf("VAPID 校验失败：%v", err)
+			}
+			if value := r.Header.Get("Content-Encoding"); value != "aes128gcm" {
+				return fmt.Errorf("Content-Encoding = %q", value)
+			}
+			if value := r.Header.Get("TTL"); value != "60" {
+				return fmt.Errorf("TTL = %q", value)
+			}
+			if value := r.Header.Get("Urgency"); value != "high" {
+				return fmt.Errorf("Urgency = %q", value)
+			}
+			if value := r.Header.Get("Topic"); value != "comment" {
+				return fmt.Errorf("Topic = %q", value)
+			}
+
+			body, err := io.ReadAll(r.Body)
+			if err != nil {
+				return err
+			}
+			plaintext, err := webPushTestDecrypt(body, key, auth)
+			if err != nil {
+				return fmt.Errorf("解密失败：%v", err)
+			}
+			if string(plaintext) != string(payload) {
+				return fmt.Errorf("明文 = %q，期望 %q", plaintext, payload)
+			}
+			return nil
+		}()
+
+		switch r.URL.Path {
+		case "/gone":
+			w.WriteHeader(http.StatusGone)
+		case "/error":
+			w.WriteHeader(http.StatusBadRequest)
+			_, _ = w.Write([]byte("bad request"))
+		default:
+			w.WriteHeader(http.StatusCreated)
+		}
+	}))
+	defer server.Close()
+
+	// 本地服务位于回环地址，测试中不经过出站地址校验
+	origin := webPushClient
+	webPushClient = server.Client
+	defer func() { webPushClient = origin }()
+
+	tests := []struct {
+		path  string
+		check func(error) bool
+	}{
+		{"/push", func(err error) bool { return err == nil }},
+		{"/gone", func(err error) bool { return errors.Is(err, ErrWebPushGone) }},
+		{"/error", func(err error) bool { return err != nil && !errors.Is(err, ErrWebPushGone) }},
+	}
+
+	for _, item := range tests {
+		t.Run(item.path, func(t *testing.T) {
+			subscription, key, auth = webPushTestSubscription(t, server.URL+item.path)
+			err := WebPushSend(subscription, payload, WebPushOptions{TTL: 60, Urgency: "high", Topic: "comment"})
+			if failure != nil {
+				t.Fatal(failure)
+			}
+			if !item.check(err) {
+				t.Fatalf("返回值不符合预期：%v", err)
+			}
+		})
+	}
+}
+
+func TestWebPushEncryptLimit(t *testing.T) {
+
+	subscription, key, auth := webPushTestSubscription(t, "https://push.example.com/x")
+
+	if _, err := WebPushEncrypt(subscription, make([]byte, WebPushMaxPayload+1)); err == nil {
+		t.Fatal("超过单条记录长度的负载应返回错误")
+	}
+
+	body, err := WebPushEncrypt(subscription, make([]byte, WebPushMaxPayload))
+	if err != nil {
+		t.Fatalf("加密失败：%v", err)
+	}
+	if _, err := webPushTestDecrypt(body, key, auth); err != nil {
+		t.Fatalf("解密失败：%v", err)
+	}
+
+	subscription.P256dh = "invalid"
+	if _, err := WebPushEncrypt(subscription, []byte("x")); err == nil {
+		t.Fatal("非法公钥应返回错误")
+	}
+}
@@ -17,7 +17,7 @@ const (
 	defaultCorsMaxAge                = 1800
 	defaultCorsAllowCredentials      = true
 	defaultCorsAllowedMethods        = "GET, POST, PATCH, PUT, DELETE, OPTIONS"
-	defaultCorsAllowedHeaders        = "X-Khronos, X-Gorgon, X-Argus, X-Ss-Stub, Token, Authorization, i-api-key, Content-Type, If-Match, If-Modified-Since, If-None-Match, If-Unmodified-Since, X-CSRF-TOKEN, X-Requested-With"
+	defaultCorsAllowedHeaders        = "X-Khronos, X-Gorgon, X-Argus, X-Ss-Stub, Token, Authorization, i-api-key, Content-Type, If-Match, If-Modified-Since, If-None-Match, If-Unmodified-Since, X-CSRF-TOKEN, X-Requested-With, X-Inis-Key-Id, X-Inis-Timestamp, X-Inis-Nonce, X-Inis-Signature"
 	defaultCorsExposedHeaders        = "Content-Type, Content-Length, Access-Control-Allow-Origin, Access-Control-Allow-Headers"
 	defaultCorsDefaultOrigin         = "http://localhost:8642"
 	defaultCorsAllowedOriginsDefault = "http://localhost:3000,http://127.0.0.1:3000"
@@ -192,6 +192,10 @@ func qpsAutoUnban() {
 			}, "IP自动解封")
 		}
 
+		if len(expiredIPs) > 0 {
+			model.ResetIpMatchers()
+		}
+
 		cacheState := cast.ToBool(facade.CacheToml.Get("open"))
 		if cacheState {
 			facade.Cache.Del("[GET][ip-black][column]")
@@ -208,6 +212,11 @@ func QpsWarn(ctx *gin.Context) {
 		return
 	}
 
+	// 已被网段、国家或 ASN 规则封禁的IP无需再单独拉黑
+	if ban := model.MatchIpBlack(ip); ban != nil && ban.Ip != ip {
+		return
+	}
+
 	var QpsBlock map[string]any
 
 	cacheState := cast.ToBool(facade.CacheToml.Get("open"))
@@ -282,6 +291,7 @@ func QpsWarn(ctx *gin.Context) {
 		if cacheState {
 			facade.Cache.Del("[GET][ip-black][column]")
 		}
+		model.ResetIpMatchers()
 
 		// 发送封禁通知
 		go sendBanNotification(ctx, ip, newLevel, ipBlack.Duration, ipBlack.IsPermanent)
@@ -308,6 +318,16 @@ func QpsWarn(ctx *gin.Context) {
 
 // sendBanNotification - 发送封禁通知
 func sendBanNotification(ctx *gin.Context, ip string, level int, duration int64, isPermanent bool) {
+	webhookData := map[string]any{
+		"ip":           ip,
+		"level":        level,
+		"duration":     duration,
+		"is_permanent": isPermanent,
+	}
+
+	// 推送给订阅了该事件的 Webhook 端点（不受通知开关影响）
+	model.TriggerWebhook(model.WebhookEventQpsBlocked, webhookData)
+
 	// 获取通知配置
 	var notifyConfig map[string]any
 	notifyCacheName := "config[SYSTEM_QPS_NOTIFY]"
@@ -352,12 +372,8 @@ func sendBanNotification(ctx *gin.Context, ip string, level int, duration int64,
 		}, "QPS封禁邮件通知")
 	}
 
-	// 发送Webhook通知
+	// 发送Webhook通知（兼容旧配置中单独填写的地址，已注册的端点由 TriggerWebhook 统一投递）
 	if !utils.Is.Empty(config["webhook"]) {
-		// TODO: 实现Webhook发送
-		facade.Log.Info(map[string]any{
-			"webhook": config["webhook"],
-			"message": message,
-		}, "QPS封禁Webhook通知")
+		model.TriggerWebhookURL(cast.ToString(config["webhook"]), model.WebhookEventQpsBlocked, webhookData)
 	}
 }
@@ -1,17 +1,53 @@
 package model
 
 import (
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"inis/app/facade"
+	"net"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
 	"github.com/spf13/cast"
 	"github.com/unti-io/go-utils/utils"
 	"gorm.io/gorm"
 	"gorm.io/plugin/soft_delete"
-	"inis/app/facade"
 )
 
+// apiKeyUsageInterval - 使用记录（最后使用时间、IP、次数）批量写入数据库的间隔
+const apiKeyUsageInterval = 30 * time.Second
+
+// apiKeySignPurpose - 签名密钥的加密用途
+const apiKeySignPurpose = "api-key-sign"
+
+// ApiKeys - 接口密钥（只保存哈希，明文仅在创建与重置时返回一次）
 type ApiKeys struct {
-	Id      int    `gorm:"type:int(32); comment:主键;" json:"id"`
-	Value 	string `gorm:"comment:值; default:Null;" json:"value"`
-	Remark  string `gorm:"comment:备注; default:Null;" json:"remark"`
+	Id int `gorm:"type:int(32); comment:主键;" json:"id"`
+	// 旧版本保存的明文密钥，初始化时迁移为哈希后清空
+	Value string `gorm:"comment:值（已废弃）; default:Null;" json:"-"`
+	// 密钥的 SHA-256 与前缀（前缀用于在后台辨识密钥）
+	Hash   string `gorm:"size:64; index; comment:密钥哈希;" json:"-"`
+	Prefix string `gorm:"size:16; comment:密钥前缀;" json:"prefix"`
+	// 签名密钥ID（公开，随签名请求发送）与加密保存的签名密钥，明文仅在创建与重置时返回一次
+	KeyId  string `gorm:"size:32; index; comment:签名密钥ID; default:Null;" json:"key_id"`
+	Secret string `gorm:"type:text; comment:签名密钥（已加密）; default:Null;" json:"-"`
+	// 可访问的接口（逗号或换行分隔）：* 全部、/api/article/* 路由、[GET]/api/article/* 指定请求类型的路由、AuthRules 的 hash；为空时不限制
+	Scopes string `gorm:"type:text; comment:权限范围;" json:"scopes"`
+	// 允许使用的IP（逗号或换行分隔，支持 CIDR）；为空时不限制
+	IpAllow    string `gorm:"type:text; comment:IP白名单;" json:"ip_allow"`
+	Qps        int    `gorm:"type:int(32); default:0; comment:每秒请求上限 0不限;" json:"qps"`
+	ExpireTime int64  `gorm:"default:0; comment:过期时间 0永不过期;" json:"expire_time"`
+	// 使用记录
+	LastTime int64  `gorm:"default:0; comment:最后使用时间;" json:"last_time"`
+	LastIp   string `gorm:"size:64; comment:最后使用IP; default:Null;" json:"last_ip"`
+	UseCount int64  `gorm:"default:0; comment:累计使用次数;" json:"use_count"`
+	Remark   string `gorm:"comment:备注; default:Null;" json:"remark"`
 	// 以下为公共字段
 	Json       any                   `gorm:"type:longtext; comment:用于存储JSON数据;" json:"json"`
 	Text       any                   `gorm:"type:longtext; comment:用于存储文本数据;" json:"text"`
@@ -29,6 +65,20 @@ func InitApiKeys() {
 		facade.Log.Error(map[string]any{"error": err}, "ApiKeys表迁移失败")
 		return
 	}
+
+	// 旧版本的明文密钥转为哈希存储
+	var items []ApiKeys
+	facade.DB.Drive().Unscoped().Where("(hash IS NULL OR hash = '') AND value IS NOT NULL AND value <> ''").Find(&items)
+	for _, item := range items {
+		facade.DB.Drive().Unscoped().Model(&ApiKeys{}).Where("id = ?", item.Id).UpdateColumns(map[string]any{
+			"hash":   ApiKeyHash(item.Value),
+			"prefix": apiKeyPrefix(item.Value),
+			"value":  nil,
+		})
+	}
+	if len(items) > 0 {
+		facade.Log.Info(map[string]any{"count": len(items)}, "已将明文接口密钥迁移为哈希存储")
+	}
 }
 
 // AfterFind - 查询Hook
@@ -38,4 +88,267 @@ func (this *ApiKeys) AfterFind(tx *gorm.DB) (err error) {
 	this.Json = utils.Json.Decode(this.Json)
 
 	return
-}
\ No newline at end of file
+}
+
+// ApiKeyHash - 接口密钥的哈希（密钥不区分大小写）
+func ApiKeyHash(value string) string {
+	sum := sha256.Sum256([]byte(strings.ToUpper(strings.TrimSpace(value))))
+	return hex.EncodeToString(sum[:])
+}
+
+// apiKeyPrefix - 密钥前缀
+func apiKeyPrefix(value string) string {
+	value = strings.ToUpper(strings.TrimSpace(value))
+	if len(value) > 8 {
+		return value[:8]
+	}
+	return value
+}
+
+// SetValue - 设置密钥（只保存哈希与前缀），value 为空时自动生成，返回明文
+func (this *ApiKeys) SetValue(value string) string {
+	if value = strings.ToUpper(strings.TrimSpace(value)); utils.Is.Empty(value) {
+		value = strings.ToUpper(strings.ReplaceAll(uuid.New().String(), "-", ""))
+	}
+	this.Hash = ApiKeyHash(value)
+	this.Prefix = apiKeyPrefix(value)
+	return value
+}
+
+// SetSecret - 生成签名密钥与签名密钥ID（只保存密文），返回签名密钥明文
+func (this *ApiKeys) SetSecret() (string, error) {
+
+	buf := make([]byte, 40)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+
+	secret := hex.EncodeToString(buf[:32])
+	sealed, err := facade.CryptSeal(apiKeySignPurpose, []byte(secret))
+	if err != nil {
+		return "", err
+	}
+
+	this.KeyId = "ak_" + hex.EncodeToString(buf[32:])
+	this.Secret = sealed
+	return secret, nil
+}
+
+// SignSecret - 解密签名密钥（未生成或无法解密时返回错误）
+func (this *ApiKeys) SignSecret() (string, error) {
+	if utils.Is.Empty(this.Secret) {
+		return "", errors.New("签名密钥未生成")
+	}
+	secret, err := facade.CryptOpen(apiKeySignPurpose, this.Secret)
+	if err != nil {
+		return "", err
+	}
+	return string(secret), nil
+}
+
+// CheckValue - 校验密钥明文
+func (this *ApiKeys) CheckValue(value string) bool {
+	if utils.Is.Empty(value) || utils.Is.Empty(this.Hash) {
+		return false
+	}
+	return subtle.ConstantTimeCompare([]byte(ApiKeyHash(value)), []byte(this.Hash)) == 1
+}
+
+// FindApiKey - 按明文查询密钥（带缓存，不存在时返回 nil）
+func FindApiKey(value string) *ApiKeys {
+
+	if utils.Is.Empty(value) {
+		return nil
+	}
+
+	hash := ApiKeyHash(value)
+	cacheName := "[GET]/api/api-keys/hash[" + hash + "]"
+	cacheState := cast.ToBool(facade.CacheToml.Get("open"))
+
+	item := &ApiKeys{}
+	if cacheState && facade.Cache.Has(cacheName) {
+		if json.Unmarshal([]byte(cast.ToString(facade.Cache.Get(cacheName))), item) == nil && item.Id != 0 {
+			item.Hash = hash
+			return item
+		}
+		item = &ApiKeys{}
+	}
+
+	if err := facade.DB.Drive().Where("hash = ?", hash).Take(item).Error; err != nil {
+		return nil
+	}
+
+	if cacheState {
+		go facade.Cache.Set(cacheName, utils.Json.Encode(item))
+	}
+
+	return item
+}
+
+// FindApiKeyByKeyId - 按签名密钥ID查询（不存在时返回 nil）
+func FindApiKeyByKeyId(keyId string) *ApiKeys {
+
+	if utils.Is.Empty(keyId) {
+		return nil
+	}
+
+	item := &ApiKeys{}
+	if err := facade.DB.Drive().Where("key_id = ?", keyId).Take(item).Error; err != nil {
+		return nil
+	}
+
+	return item
+}
+
+// ApiKeySplit - 拆分逗号或换行分隔的列表
+func ApiKeySplit(value any) []string {
+	var result []string
+	for _, item := range strings.FieldsFunc(cast.ToString(value), func(r rune) bool {
+		return r == ',' || r == '\n' || r == '\r'
+	}) {
+		if item = strings.TrimSpace(item); !utils.Is.Empty(item) {
+			result = append(result, item)
+		}
+	}
+	return result
+}
+
+// Expired - 密钥是否已过期
+func (this *ApiKeys) Expired() bool {
+	return this.ExpireTime > 0 && this.ExpireTime <= time.Now().Unix()
+}
+
+// AllowIp - IP是否在白名单内（白名单为空时不限制）
+func (this *ApiKeys) AllowIp(ip string) bool {
+
+	list := ApiKeySplit(this.IpAllow)
+	if len(list) == 0 {
+		return true
+	}
+
+	addr := net.ParseIP(ip)
+	for _, item := range list {
+		if strings.Contains(item, "/") {
+			if _, network, err := net.ParseCIDR(item); err == nil && addr != nil && network.Contains(addr) {
+				return true
+			}
+			continue
+		}
+		if item == ip || (addr != nil && addr.Equal(net.ParseIP(item))) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// AllowScope - 是否允许访问该接口（权限范围为空时不限制）
+func (this *ApiKeys) AllowScope(method, path, hash string) bool {
+
+	list := ApiKeySplit(this.Scopes)
+	if len(list) == 0 {
+		return true
+	}
+
+	method = strings.ToUpper(method)
+	for _, item := range list {
+		if ApiKeyScopeMatch(item, method, path, hash) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// ApiKeyScopeMatch - 单条权限范围是否匹配接口
+func ApiKeyScopeMatch(scope, method, path, hash string) bool {
+
+	if scope == "*" {
+		return true
+	}
+
+	// AuthRules 的 hash
+	if !strings.HasPrefix(scope, "[") && !strings.HasPrefix(scope, "/") {
+		return !utils.Is.Empty(hash) && scope == hash
+	}
+
+	// [GET]/api/article/* - 指定请求类型
+	if strings.HasPrefix(scope, "[") {
+		end := strings.Index(scope, "]")
+		if end < 0 {
+			return false
+		}
+		if item := strings.ToUpper(scope[1:end]); item != "*" && item != strings.ToUpper(method) {
+			return false
+		}
+		scope = scope[end+1:]
+	}
+
+	if strings.HasSuffix(scope, "*") {
+		return strings.HasPrefix(path, strings.TrimSuffix(scope, "*"))
+	}
+
+	return path == scope
+}
+
+// apiKeyUsage - 待写入的使用记录
+type apiKeyUsage struct {
+	count int64
+	time  int64
+	ip    string
+}
+
+var (
+	apiKeyUsageMap   = map[int]*apiKeyUsage{}
+	apiKeyUsageMutex = &sync.Mutex{}
+	apiKeyUsageOnce  = &sync.Once{}
+)
+
+// Touch - 记录一次使用（先在内存中累计，定时批量写入数据库）
+func (this *ApiKeys) Touch(ip string) {
+
+	apiKeyUsageOnce.Do(func() {
+		go func() {
+			for {
+				time.Sleep(apiKeyUsageInterval)
+				FlushApiKeyUsage()
+			}
+		}()
+	})
+
+	apiKeyUsageMutex.Lock()
+	defer apiKeyUsageMutex.Unlock()
+
+	item := apiKeyUsageMap[this.Id]
+	if item == nil {
+		item = &apiKeyUsage{}
+		apiKeyUsageMap[this.Id] = item
+	}
+	item.count++
+	item.time = time.Now().Unix()
+	item.ip = ip
+}
+
+// FlushApiKeyUsage - 将内存中累计的使用记录写入数据库
+func FlushApiKeyUsage() {
+
+	apiKeyUsageMutex.Lock()
+	items := apiKeyUsageMap
+	apiKeyUsageMap = map[int]*apiKeyUsage{}
+	apiKeyUsageMutex.Unlock()
+
+	if facade.DB == nil {
+		return
+	}
+
+	for id, item := range items {
+		err := facade.DB.Drive().Model(&ApiKeys{}).Where("id = ?", id).UpdateColumns(map[string]any{
+			"last_time": item.time,
+			"last_ip":   item.ip,
+			"use_count": gorm.Expr("use_count + ?", item.count),
+		}).Error
+		if err != nil {
+			facade.Log.Error(map[string]any{"error": err, "id": id}, "写入接口密钥使用记录失败")
+		}
+	}
+}
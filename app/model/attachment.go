@@ -25,6 +25,9 @@ type Attachment struct {
 	TargetType    string                `gorm:"size:32; index; comment:关联业务类型;" json:"target_type"`
 	TargetId      uint                  `gorm:"type:int(32); index; comment:关联业务ID;" json:"target_id"`
 	FileHash      string                `gorm:"size:64; index; comment:文件SHA256值;" json:"file_hash"`
+	ScanStatus    string                `gorm:"size:16; index; default:'skipped'; comment:安全扫描状态;" json:"scan_status"`
+	ScanResult    string                `gorm:"size:256; comment:安全扫描结果;" json:"scan_result"`
+	ScanTime      int64                 `gorm:"type:int(64); default:0; comment:安全扫描时间;" json:"scan_time"`
 	CreateTime    int64                 `gorm:"autoCreateTime; comment:创建时间;" json:"create_time"`
 	UpdateTime    int64                 `gorm:"autoUpdateTime; comment:更新时间;" json:"update_time"`
 	DeleteTime    soft_delete.DeletedAt `gorm:"comment:删除时间; default:0;" json:"delete_time"`
@@ -38,6 +41,11 @@ func InitAttachment() {
 	}
 }
 
+// AttachmentModel - 附件查询（排除未通过安全扫描的隔离文件，隔离文件只能通过隔离区接口管理）
+func AttachmentModel(table any) *facade.ModelStruct {
+	return facade.DB.Model(table).Where("scan_status", "<>", facade.ScanStatusInfected)
+}
+
 func (this *Attachment) AfterFind(tx *gorm.DB) (err error) {
 	this.FullUrl = utils.Replace(this.FullUrl, DomainTemp1())
 	return
@@ -56,29 +64,29 @@ func (this *Attachment) GenerateUUID() string {
 }
 
 func (this *Attachment) GetByUUID(uuid string) map[string]any {
-	result, _ := facade.DB.Model(&Attachment{}).Where("uuid", uuid).Find()
+	result, _ := AttachmentModel(&Attachment{}).Where("uuid", uuid).Find()
 	return result
 }
 
 func (this *Attachment) GetByHash(fileHash string) map[string]any {
-	result, _ := facade.DB.Model(&Attachment{}).Where("file_hash", fileHash).Where("status", 1).Find()
+	result, _ := AttachmentModel(&Attachment{}).Where("file_hash", fileHash).Where("status", 1).Find()
 	return result
 }
 
 func (this *Attachment) GetByTarget(targetType string, targetId uint) []map[string]any {
-	result, _ := facade.DB.Model(&[]Attachment{}).Where("target_type", targetType).Where("target_id", targetId).Where("status", 1).Select()
+	result, _ := AttachmentModel(&[]Attachment{}).Where("target_type", targetType).Where("target_id", targetId).Where("status", 1).Select()
 	return result
 }
 
 func (this *Attachment) GetByUploader(uploaderId uint, page, limit int) ([]map[string]any, int64) {
-	query := facade.DB.Model(&[]Attachment{}).Where("uploader_id", uploaderId)
+	query := AttachmentModel(&[]Attachment{}).Where("uploader_id", uploaderId)
 	count, _ := query.Count()
 	data, _ := query.Limit(limit).Page(page).Order("create_time desc").Select()
 	return data, count
 }
 
 func (this *Attachment) DeleteByUUID(uuid string, uploaderId uint, isAdmin bool) bool {
-	query := facade.DB.Model(&Attachment{}).Where("uuid", uuid)
+	query := AttachmentModel(&Attachment{}).Where("uuid", uuid)
 	if !isAdmin {
 		query = query.Where("uploader_id", uploaderId)
 	}
@@ -87,7 +95,7 @@ func (this *Attachment) DeleteByUUID(uuid string, uploaderId uint, isAdmin bool)
 }
 
 func (this *Attachment) ForceDeleteByUUID(uuid string, isAdmin bool) bool {
-	query := facade.DB.Model(&Attachment{}).WithTrashed().Where("uuid", uuid)
+	query := AttachmentModel(&Attachment{}).WithTrashed().Where("uuid", uuid)
 	if !isAdmin {
 		result, _ := query.Find()
 		query = query.Where("uploader_id", cast.ToUint(result["uploader_id"]))
@@ -97,7 +105,7 @@ func (this *Attachment) ForceDeleteByUUID(uuid string, isAdmin bool) bool {
 }
 
 func (this *Attachment) UpdateStatus(uuid string, status int8, isAdmin bool) bool {
-	query := facade.DB.Model(&Attachment{}).Where("uuid", uuid)
+	query := AttachmentModel(&Attachment{}).Where("uuid", uuid)
 	if !isAdmin {
 		result, _ := query.Find()
 		query = query.Where("uploader_id", cast.ToUint(result["uploader_id"]))
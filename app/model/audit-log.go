@@ -0,0 +1,282 @@
+package model
+
+import (
+	"inis/app/facade"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/spf13/cast"
+	"github.com/unti-io/go-utils/utils"
+)
+
+// auditSnapshotLimit - 单次请求最多记录的目标数据条数（批量操作超出部分只记录ID）
+const auditSnapshotLimit = 100
+
+// auditAuthParams - 认证流程中的一次性凭证（两步验证码、邮件/短信验证码、待完成的两步验证挑战、通行密钥断言、PKCE 校验码、人机验证答案等）
+// 只在审计日志的请求参数中整体隐藏；这些键名可能与配置项重名（如短信模板的 verify_code），因此不加入 facade.SensitiveFields
+var auditAuthParams = map[string]bool{
+	"code":          true,
+	"verify_code":   true,
+	"challenge":     true,
+	"credential":    true,
+	"code_verifier": true,
+	"captcha":       true,
+	"state":         true,
+}
+
+// AuditLog - 审计日志（管理操作与敏感查询的持久化记录，写入后不可修改）
+type AuditLog struct {
+	Id int `gorm:"type:int(32); comment:主键;" json:"id"`
+	// 操作者
+	Uid     int    `gorm:"type:int(32); index; comment:操作者ID;" json:"uid"`
+	Account string `gorm:"size:128; comment:操作者账号;" json:"account"`
+	// 请求
+	Method string `gorm:"size:8; comment:请求方法;" json:"method"`
+	Path   string `gorm:"size:256; index; comment:请求路径;" json:"path"`
+	Name   string `gorm:"size:128; comment:接口名称;" json:"name"`
+	Params string `gorm:"type:longtext; comment:请求参数（已脱敏）;" json:"params"`
+	// 目标实体
+	Target    string `gorm:"size:64; index; comment:目标实体;" json:"target"`
+	TargetIds string `gorm:"size:1024; comment:目标ID，多个用逗号分隔;" json:"target_ids"`
+	// 变更前后的数据与差异（均已脱敏）
+	Before string `gorm:"type:longtext; comment:变更前;" json:"before"`
+	After  string `gorm:"type:longtext; comment:变更后;" json:"after"`
+	Diff   string `gorm:"type:longtext; comment:差异;" json:"diff"`
+	// 响应
+	Code int    `gorm:"type:int(32); comment:响应状态码;" json:"code"`
+	Msg  string `gorm:"size:512; comment:响应消息;" json:"msg"`
+	// 客户端
+	Ip         string `gorm:"size:64; index; comment:IP;" json:"ip"`
+	Ua         string `gorm:"size:512; comment:User-Agent;" json:"ua"`
+	CreateTime int64  `gorm:"autoCreateTime; index; comment:创建时间;" json:"create_time"`
+	UpdateTime int64  `gorm:"autoUpdateTime; comment:更新时间;" json:"update_time"`
+}
+
+// InitAuditLog - 初始化AuditLog表
+func InitAuditLog() {
+	err := facade.DB.Drive().AutoMigrate(&AuditLog{})
+	if err != nil {
+		facade.Log.Error(map[string]any{"error": err}, "AuditLog表迁移失败")
+		return
+	}
+}
+
+// AuditTargets - 可记录变更前后数据的目标实体（路由中的控制器名 => 数据表）
+var AuditTargets = map[string]func() any{
+	"users":         func() any { return &[]Users{} },
+	"auth-group":    func() any { return &[]AuthGroup{} },
+	"auth-rules":    func() any { return &[]AuthRules{} },
+	"auth-pages":    func() any { return &[]AuthPages{} },
+	"api-keys":      func() any { return &[]ApiKeys{} },
+	"ip-black":      func() any { return &[]IpBlack{} },
+	"ip-white":      func() any { return &[]IpWhite{} },
+	"config":        func() any { return &[]Config{} },
+	"article":       func() any { return &[]Article{} },
+	"article-group": func() any { return &[]ArticleGroup{} },
+	"comment":       func() any { return &[]Comment{} },
+	"pages":         func() any { return &[]Pages{} },
+	"links":         func() any { return &[]Links{} },
+	"links-group":   func() any { return &[]LinksGroup{} },
+	"tags":          func() any { return &[]Tags{} },
+	"banner":        func() any { return &[]Banner{} },
+	"placard":       func() any { return &[]Placard{} },
+	"level":         func() any { return &[]Level{} },
+	"moments":       func() any { return &[]Moments{} },
+	"attachment":    func() any { return &[]Attachment{} },
+	"webhook":       func() any { return &[]Webhook{} },
+	"oauth-clients": func() any { return &[]OAuthClient{} },
+}
+
+// AuditEnabled - 是否开启审计日志
+func AuditEnabled() bool {
+	return facade.DB != nil && cast.ToBool(facade.AppToml.Get("audit.enable", true))
+}
+
+// AuditRoute - 请求是否需要审计（/api/ 下的全部非 GET 请求，以及配置的敏感 GET 请求）
+func AuditRoute(method, path string) bool {
+
+	if !strings.HasPrefix(path, "/api/") {
+		return false
+	}
+
+	switch strings.ToUpper(method) {
+	case "OPTIONS", "HEAD":
+		return false
+	case "GET":
+		for _, item := range cast.ToStringSlice(facade.AppToml.Get("audit.sensitive_get", []string{})) {
+			if item = strings.TrimSpace(item); utils.Is.Empty(item) {
+				continue
+			}
+			if strings.HasSuffix(item, "*") && strings.HasPrefix(path, strings.TrimSuffix(item, "*")) || path == item {
+				return true
+			}
+		}
+		return false
+	}
+
+	return true
+}
+
+// AuditIds - 请求参数中的目标ID（id 或 ids）
+func AuditIds(params map[string]any) []any {
+	if !utils.Is.Empty(params["id"]) {
+		return []any{params["id"]}
+	}
+	return utils.Unity.Ids(params["ids"])
+}
+
+// AuditSnapshot - 目标数据快照（按ID索引，包含已软删除的数据；不是可记录的目标实体时返回 nil）
+func AuditSnapshot(target string, ids []any) map[string]any {
+
+	factory, ok := AuditTargets[target]
+	if !ok || len(ids) == 0 || facade.DB == nil {
+		return nil
+	}
+	if len(ids) > auditSnapshotLimit {
+		ids = ids[:auditSnapshotLimit]
+	}
+
+	items, _ := facade.DB.Model(factory()).WithTrashed().WhereIn("id", ids).Select()
+
+	result := map[string]any{}
+	for _, item := range items {
+		result[cast.ToString(item["id"])] = item
+	}
+	return result
+}
+
+// auditFlatten - 将嵌套数据展开为 路径 => 值
+func auditFlatten(prefix string, data any, result map[string]any) {
+	switch val := data.(type) {
+	case map[string]any:
+		for key, value := range val {
+			auditFlatten(strings.TrimPrefix(prefix+"."+key, "."), value, result)
+		}
+	default:
+		if !utils.Is.Empty(prefix) {
+			result[prefix] = data
+		}
+	}
+}
+
+// AuditDiff - 变更前后的差异（路径 => {before, after}，敏感字段脱敏；update_time 等自动维护的字段不计入）
+func AuditDiff(before, after any) map[string]any {
+
+	old, now := map[string]any{}, map[string]any{}
+	auditFlatten("", before, old)
+	auditFlatten("", after, now)
+
+	keys := map[string]bool{}
+	for key := range old {
+		keys[key] = true
+	}
+	for key := range now {
+		keys[key] = true
+	}
+
+	result := map[string]any{}
+	for key := range keys {
+		field := strings.ToLower(key[strings.LastIndex(key, ".")+1:])
+		if field == "update_time" {
+			continue
+		}
+		if utils.Json.Encode(old[key]) == utils.Json.Encode(now[key]) {
+			continue
+		}
+		item := map[string]any{"before": old[key], "after": now[key]}
+		if facade.SensitiveFields[field] {
+			item["before"] = facade.Comm.MaskSecret(cast.ToString(old[key]))
+			item["after"] = facade.Comm.MaskSecret(cast.ToString(now[key]))
+		}
+		result[key] = item
+	}
+
+	return result
+}
+
+// CreateAuditLog - 写入审计日志（before/after/params 在此统一脱敏）
+func CreateAuditLog(item *AuditLog, params, before, after any) {
+
+	if facade.DB == nil {
+		return
+	}
+
+	item.Params = auditEncode(auditParams(params))
+	item.Before = auditEncode(before)
+	item.After = auditEncode(after)
+	if diff := AuditDiff(before, after); len(diff) > 0 {
+		item.Diff = utils.Json.Encode(diff)
+	}
+
+	if len(item.Ua) > 512 {
+		item.Ua = item.Ua[:512]
+	}
+	if len([]rune(item.Msg)) > 512 {
+		item.Msg = string([]rune(item.Msg)[:512])
+	}
+	if len(item.TargetIds) > 1024 {
+		item.TargetIds = item.TargetIds[:1024]
+	}
+
+	if _, err := facade.DB.Model(&AuditLog{}).Create(item); err != nil {
+		facade.Log.Error(map[string]any{"error": err, "path": item.Path}, "写入审计日志失败")
+	}
+}
+
+// auditEncode - 脱敏后编码为 JSON
+func auditEncode(data any) string {
+	if utils.Is.Empty(data) {
+		return ""
+	}
+	return utils.Json.Encode(facade.Comm.MaskSensitive(data))
+}
+
+// auditParams - 隐藏请求参数中认证流程的一次性凭证（其余敏感字段由 MaskSensitive 处理）
+func auditParams(params any) any {
+
+	item, ok := params.(map[string]any)
+	if !ok {
+		return params
+	}
+
+	result := make(map[string]any, len(item))
+	for key, value := range item {
+		if auditAuthParams[strings.ToLower(key)] && !utils.Is.Empty(value) {
+			value = "******"
+		}
+		result[key] = value
+	}
+
+	return result
+}
+
+// AuditJoinIds - 目标ID列表转为逗号分隔的字符串（去重并排序）
+func AuditJoinIds(ids ...[]any) string {
+	unique := map[string]bool{}
+	for _, list := range ids {
+		for _, id := range list {
+			if value := cast.ToString(id); !utils.Is.Empty(value) {
+				unique[value] = true
+			}
+		}
+	}
+	result := make([]string, 0, len(unique))
+	for id := range unique {
+		result = append(result, id)
+	}
+	sort.Strings(result)
+	return strings.Join(result, ",")
+}
+
+// CleanAuditLogs - 清理超过保留天数的审计日志（days 小于等于 0 时不清理）
+func CleanAuditLogs(days int) (int64, error) {
+
+	if days <= 0 {
+		return 0, nil
+	}
+
+	before := time.Now().AddDate(0, 0, -days).Unix()
+	result := facade.DB.Drive().Where("create_time < ?", before).Delete(&AuditLog{})
+	return result.RowsAffected, result.Error
+}
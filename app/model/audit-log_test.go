@@ -0,0 +1,36 @@
+package model
+
+import (
+	"strings"
+	"testing"
+)
+
+// TestAuditParamsLogin2fa - comm/login2fa 的审计记录不包含两步验证挑战与验证码明文
+func TestAuditParamsLogin2fa(t *testing.T) {
+
+	challenge := "c0ffee2fa9challenge7b3d1e5a"
+	code := "482913"
+
+	record := auditEncode(auditParams(map[string]any{
+		"challenge": challenge,
+		"code":      code,
+		"remember":  true,
+	}))
+
+	if strings.Contains(record, challenge) || strings.Contains(record, code) {
+		t.Fatalf("审计记录包含明文凭证：%s", record)
+	}
+	if !strings.Contains(record, "remember") {
+		t.Fatalf("其余参数应原样记录：%s", record)
+	}
+}
+
+// TestAuditParamsPassword - 密码等配置类敏感字段仍由 MaskSensitive 脱敏
+func TestAuditParamsPassword(t *testing.T) {
+
+	record := auditEncode(auditParams(map[string]any{"account": "admin", "password": "correct-horse-battery"}))
+
+	if strings.Contains(record, "correct-horse-battery") {
+		t.Fatalf("审计记录包含密码明文：%s", record)
+	}
+}
@@ -0,0 +1,197 @@
+package model
+
+import (
+	"fmt"
+	"inis/app/facade"
+	"strings"
+
+	"github.com/spf13/cast"
+	"github.com/unti-io/go-utils/utils"
+)
+
+// AuthExplain - 权限诊断结果（与 Rule 中间件的判定一致）
+type AuthExplain struct {
+	// Method - 请求类型
+	Method string `json:"method"`
+	// Route - 请求路径
+	Route string `json:"route"`
+	// Rule - 对应的权限规则，未登记时为 nil
+	Rule map[string]any `json:"rule"`
+	// Type - 接口类型：common、login 或 permission
+	Type string `json:"type"`
+	// Uid - 诊断的用户，按分组诊断时为 0
+	Uid int `json:"uid"`
+	// Groups - 参与判定的分组
+	Groups []AuthGroupPolicy `json:"groups"`
+	// Allow - 是否可以访问
+	Allow bool `json:"allow"`
+	// Code - Rule 中间件返回的状态码：200、401 或 403
+	Code int `json:"code"`
+	// Msg - Rule 中间件返回的提示
+	Msg string `json:"msg"`
+	// Chain - 判定过程
+	Chain []string `json:"chain"`
+	// Decision - 分组与策略的判定结果
+	Decision *AuthDecision `json:"decision"`
+}
+
+// FindAuthRule - 查询接口对应的权限规则
+func FindAuthRule(method, path string) map[string]any {
+	result, _ := facade.DB.Model(&AuthRules{}).Where([]any{
+		[]any{"route", "=", path},
+		[]any{"method", "=", strings.ToUpper(method)},
+	}).Find()
+	return result
+}
+
+// ReplaceAuthGroup - 用修改后的分组替换列表中的同 ID 分组（用于模拟变更），不在列表中时原样返回
+func ReplaceAuthGroup(groups []AuthGroupPolicy, item AuthGroupPolicy) (result []AuthGroupPolicy) {
+	for _, group := range groups {
+		if group.Id == item.Id {
+			group = item
+		}
+		result = append(result, group)
+	}
+	return result
+}
+
+// authGroupLabel - 分组的描述
+func authGroupLabel(item AuthMatch) string {
+	label := fmt.Sprintf("分组「%s」(#%d", item.Name, item.Group)
+	if item.Root {
+		label += "，root"
+	}
+	return label + ")"
+}
+
+// ExplainAuth - 诊断能否访问接口，返回判定结果与过程
+/**
+ * @param rule 接口对应的权限规则（FindAuthRule）
+ * @param uid 用户ID，按分组诊断时为 0
+ * @param user 用户信息，用于判断策略条件中的 user.xxx，为 nil 时此类条件视为待定
+ * @param groups 参与判定的分组
+ * @param method 请求类型
+ * @param path 请求路径
+ */
+func ExplainAuth(rule map[string]any, uid int, user map[string]any, groups []AuthGroupPolicy, method, path string) *AuthExplain {
+
+	method = strings.ToUpper(method)
+	result := &AuthExplain{
+		Method: method,
+		Route:  path,
+		Uid:    uid,
+		Groups: groups,
+		Type:   cast.ToString(rule["type"]),
+		Chain:  []string{},
+	}
+	if result.Groups == nil {
+		result.Groups = []AuthGroupPolicy{}
+	}
+
+	name := fmt.Sprintf("[%s]%s", method, path)
+	if utils.Is.Empty(rule) {
+		result.Type = "permission"
+		result.Chain = append(result.Chain, fmt.Sprintf("接口 %s 未登记在权限规则中，按需要权限处理，只有策略中的路由能够命中", name))
+	} else {
+		result.Rule = map[string]any{"id": rule["id"], "name": rule["name"], "type": rule["type"], "hash": rule["hash"]}
+		if result.Type != "common" && result.Type != "login" {
+			result.Type = "permission"
+		}
+		result.Chain = append(result.Chain, fmt.Sprintf("接口 %s 对应规则「%v」（hash：%v，类型：%v）", name, rule["name"], rule["hash"], rule["type"]))
+	}
+
+	if result.Type == "common" {
+		result.Allow, result.Code, result.Msg = true, 200, "允许访问"
+		result.Chain = append(result.Chain, "公共接口，无需登录即可访问")
+		return result
+	}
+
+	decision := Authorize(groups, user, method, path, cast.ToString(rule["hash"]))
+	result.Decision = decision
+
+	if len(groups) == 0 {
+		result.Chain = append(result.Chain, "不在任何分组中")
+	}
+	for _, group := range groups {
+		flags := []string{}
+		if group.Root {
+			flags = append(flags, "root")
+		}
+		if group.All {
+			flags = append(flags, "all")
+		}
+		result.Chain = append(result.Chain, fmt.Sprintf("所在分组「%s」(#%d) 标记：%v，规则 %d 条，策略 %d 条", group.Name, group.Id, flags, len(group.Rules), len(group.Policies)))
+	}
+
+	for _, item := range decision.Matches {
+		var text string
+		switch {
+		case item.Source == "rules" && item.Route == "all":
+			text = authGroupLabel(item) + " 拥有全部权限（all），允许"
+		case item.Source == "rules":
+			text = authGroupLabel(item) + " 的规则包含该接口的哈希，允许"
+		default:
+			effect := map[string]string{PolicyAllow: "允许", PolicyDeny: "拒绝"}[item.Effect]
+			text = fmt.Sprintf("%s 的策略 %s %s 命中", authGroupLabel(item), item.Effect, item.Route)
+			if item.Condition != "" {
+				text += fmt.Sprintf("，条件 %s", item.Condition)
+				if item.Pending {
+					text += " 需由控制器结合数据判断"
+				} else {
+					text += " 成立"
+				}
+			}
+			text += "，" + effect
+		}
+		result.Chain = append(result.Chain, text)
+	}
+
+	checked := AuthCheckRoute(method, path)
+
+	if result.Type == "login" {
+		switch {
+		case uid == 0 && user != nil:
+			result.Code, result.Msg = 401, "请先登录！"
+			result.Chain = append(result.Chain, "登录即可访问的接口，当前未登录")
+		case decision.Deny:
+			result.Code, result.Msg = 403, "无权限！"
+			result.Chain = append(result.Chain, "登录即可访问的接口，但被拒绝策略明确拒绝（拒绝优先）")
+		case decision.PendingDeny() && !checked:
+			result.Code, result.Msg = 403, "无权限！"
+			result.Chain = append(result.Chain, "登录即可访问的接口，命中带数据条件的拒绝策略，但该接口不由控制器判断数据条件，拒绝")
+		default:
+			result.Allow, result.Code, result.Msg = true, 200, "允许访问"
+			result.Chain = append(result.Chain, "登录即可访问的接口，未被拒绝策略拒绝")
+		}
+	} else {
+		switch {
+		case decision.Deny:
+			result.Code, result.Msg = 403, "无权限！"
+			result.Chain = append(result.Chain, "命中无条件的拒绝策略，拒绝优先于允许")
+		case decision.Conditional() && !checked:
+			result.Code, result.Msg = 403, "无权限！"
+			result.Chain = append(result.Chain, "判定取决于数据条件，但该接口不由控制器判断数据条件，拒绝")
+		case decision.Allow:
+			result.Allow, result.Code, result.Msg = true, 200, "允许访问"
+			result.Chain = append(result.Chain, "至少一个分组允许，且未被拒绝")
+		default:
+			result.Code, result.Msg = 403, "无权限！"
+			result.Chain = append(result.Chain, "没有分组允许该接口（规则哈希、all 或策略均未命中）")
+		}
+	}
+
+	if result.Allow {
+		if decision.Root {
+			result.Chain = append(result.Chain, "root 分组无条件允许，可操作他人的数据")
+		} else {
+			result.Chain = append(result.Chain, "不具备越权能力，只能操作自己的数据或满足策略条件的数据")
+		}
+	}
+
+	return result
+}
+
+// Root - 是否可以操作他人的数据
+func (this *AuthExplain) Root() bool {
+	return this.Allow && this.Decision != nil && this.Decision.Root
+}
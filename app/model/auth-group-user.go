@@ -0,0 +1,183 @@
+package model
+
+import (
+	"fmt"
+	"inis/app/facade"
+	"strings"
+
+	"github.com/spf13/cast"
+	"github.com/unti-io/go-utils/utils"
+	"gorm.io/gorm/clause"
+)
+
+// AuthGroupUser - 用户与权限分组的关联
+//
+// 取代 AuthGroup.uids 的 LIKE '%|uid|%' 查询，AuthGroup.uids 仅作为兼容旧版接口的镜像字段保留。
+type AuthGroupUser struct {
+	Id         int   `gorm:"type:int(32); comment:主键;" json:"id"`
+	Gid        int   `gorm:"type:int(32); uniqueIndex:uk_auth_group_user,priority:1; comment:权限分组ID;" json:"gid"`
+	Uid        int   `gorm:"type:int(32); uniqueIndex:uk_auth_group_user,priority:2; index:idx_auth_group_user_uid; comment:用户ID;" json:"uid"`
+	CreateTime int64 `gorm:"autoCreateTime; comment:创建时间;" json:"create_time"`
+}
+
+// InitAuthGroupUser - 初始化AuthGroupUser表（首次创建时从 AuthGroup.uids 迁移数据）
+func InitAuthGroupUser() {
+	err := facade.DB.Drive().AutoMigrate(&AuthGroupUser{})
+	if err != nil {
+		facade.Log.Error(map[string]any{"error": err}, "AuthGroupUser表迁移失败")
+		return
+	}
+
+	var count int64
+	facade.DB.Drive().Model(&AuthGroupUser{}).Count(&count)
+	if count != 0 {
+		return
+	}
+
+	var groups []map[string]any
+	facade.DB.Drive().Model(&AuthGroup{}).Unscoped().Select("id", "uids").Find(&groups)
+
+	var list []AuthGroupUser
+	for _, group := range groups {
+		for _, uid := range authGroupSplitUids(cast.ToString(group["uids"])) {
+			list = append(list, AuthGroupUser{Gid: cast.ToInt(group["id"]), Uid: uid})
+		}
+	}
+	if len(list) == 0 {
+		return
+	}
+
+	err = facade.DB.Drive().Clauses(clause.OnConflict{DoNothing: true}).CreateInBatches(&list, 200).Error
+	if err != nil {
+		facade.Log.Error(map[string]any{"error": err}, "AuthGroup.uids迁移到AuthGroupUser失败")
+		return
+	}
+	facade.Log.Info(map[string]any{"count": len(list)}, "AuthGroup.uids已迁移到AuthGroupUser")
+}
+
+// authGroupSplitUids - 解析旧版 |1|2|3| 格式的用户ID
+func authGroupSplitUids(value string) (result []int) {
+	for _, item := range strings.Split(value, "|") {
+		if uid := cast.ToInt(strings.TrimSpace(item)); uid > 0 && !utils.InArray(uid, result) {
+			result = append(result, uid)
+		}
+	}
+	return result
+}
+
+// UserGroupIds - 用户所在的权限分组ID
+func UserGroupIds(uid any) (result []int) {
+	if cast.ToInt(uid) <= 0 {
+		return nil
+	}
+	facade.DB.Drive().Model(&AuthGroupUser{}).Where("uid = ?", cast.ToInt(uid)).Order("gid").Pluck("gid", &result)
+	return result
+}
+
+// GroupUserIds - 权限分组中的用户ID
+func GroupUserIds(gid any) (result []int) {
+	facade.DB.Drive().Model(&AuthGroupUser{}).Where("gid = ?", cast.ToInt(gid)).Order("uid").Pluck("uid", &result)
+	return result
+}
+
+// AddUserGroups - 将用户加入权限分组
+func AddUserGroups(uid int, gids ...int) {
+
+	if uid <= 0 || len(gids) == 0 {
+		return
+	}
+
+	var list []AuthGroupUser
+	for _, gid := range gids {
+		list = append(list, AuthGroupUser{Gid: gid, Uid: uid})
+	}
+	facade.DB.Drive().Clauses(clause.OnConflict{DoNothing: true}).Create(&list)
+
+	authGroupUserChanged(gids...)
+}
+
+// RemoveUserGroups - 将用户移出权限分组，不传 gids 时移出全部分组
+func RemoveUserGroups(uid int, gids ...int) {
+
+	if uid <= 0 {
+		return
+	}
+
+	query := facade.DB.Drive().Where("uid = ?", uid)
+	if len(gids) > 0 {
+		query = query.Where("gid IN ?", gids)
+	} else {
+		gids = UserGroupIds(uid)
+	}
+	query.Delete(&AuthGroupUser{})
+
+	authGroupUserChanged(gids...)
+}
+
+// SetUserGroups - 设置用户所在的权限分组（不在 gids 中的分组将被移出）
+func SetUserGroups(uid int, gids []int) {
+
+	if uid <= 0 {
+		return
+	}
+
+	current := UserGroupIds(uid)
+
+	var remove []int
+	for _, gid := range current {
+		if !utils.InArray(gid, gids) {
+			remove = append(remove, gid)
+		}
+	}
+	if len(remove) > 0 {
+		RemoveUserGroups(uid, remove...)
+	}
+
+	var add []int
+	for _, gid := range gids {
+		if gid > 0 && !utils.InArray(gid, current) && !utils.InArray(gid, add) {
+			add = append(add, gid)
+		}
+	}
+	AddUserGroups(uid, add...)
+}
+
+// SetGroupUsers - 设置权限分组的成员（不在 uids 中的用户将被移出）
+func SetGroupUsers(gid int, uids []int) {
+
+	if gid <= 0 {
+		return
+	}
+
+	query := facade.DB.Drive().Where("gid = ?", gid)
+	if len(uids) > 0 {
+		query = query.Where("uid NOT IN ?", uids)
+	}
+	query.Delete(&AuthGroupUser{})
+
+	var list []AuthGroupUser
+	for _, uid := range uids {
+		if uid > 0 {
+			list = append(list, AuthGroupUser{Gid: gid, Uid: uid})
+		}
+	}
+	if len(list) > 0 {
+		facade.DB.Drive().Clauses(clause.OnConflict{DoNothing: true}).Create(&list)
+	}
+
+	authGroupUserChanged(gid)
+}
+
+// authGroupUserChanged - 成员变更后同步 AuthGroup.uids 镜像字段并使权限缓存失效
+func authGroupUserChanged(gids ...int) {
+
+	for _, gid := range utils.ArrayUnique(gids) {
+		var uids string
+		if list := GroupUserIds(gid); len(list) > 0 {
+			uids = fmt.Sprintf("|%v|", strings.Join(cast.ToStringSlice(list), "|"))
+		}
+		facade.DB.Drive().Model(&AuthGroup{}).Unscoped().Where("id = ?", gid).UpdateColumn("uids", uids)
+	}
+
+	ResetAuthPolicy()
+}
@@ -2,9 +2,7 @@ package model
 
 import (
 	"errors"
-	"fmt"
 	"inis/app/facade"
-	"strings"
 	"sync"
 
 	"github.com/spf13/cast"
@@ -14,15 +12,16 @@ import (
 )
 
 type AuthGroup struct {
-	Id      int    `gorm:"type:int(32); comment:主键;" json:"id"`
-	Name    string `gorm:"comment:权限名称;" json:"name"`
-	Key     string `gorm:"size:256; comment:唯一键; default:Null;" json:"key"`
-	Uids    string `gorm:"type:text; comment:用户ID;" json:"uids"`
-	Root    int    `gorm:"type:int(32); comment:'是否拥有越权限操作数据的能力'; default:0;" json:"root"`
-	Rules   string `gorm:"type:text; comment:权限规则;" json:"rules"`
-	Default int    `gorm:"type:int(32); comment:默认权限; default:0;" json:"default"`
-	Pages   string `gorm:"type:text; comment:页面权限; default:Null;" json:"pages"`
-	Remark  string `gorm:"comment:备注; default:Null;" json:"remark"`
+	Id       int    `gorm:"type:int(32); comment:主键;" json:"id"`
+	Name     string `gorm:"comment:权限名称;" json:"name"`
+	Key      string `gorm:"size:256; comment:唯一键; default:Null;" json:"key"`
+	Uids     string `gorm:"type:text; comment:用户ID（AuthGroupUser的镜像，仅用于兼容）;" json:"uids"`
+	Root     int    `gorm:"type:int(32); comment:'是否拥有越权限操作数据的能力'; default:0;" json:"root"`
+	Rules    string `gorm:"type:text; comment:权限规则;" json:"rules"`
+	Policies string `gorm:"type:text; comment:策略（JSON数组，支持路由通配、allow/deny与条件）; default:Null;" json:"policies"`
+	Default  int    `gorm:"type:int(32); comment:默认权限; default:0;" json:"default"`
+	Pages    string `gorm:"type:text; comment:页面权限; default:Null;" json:"pages"`
+	Remark   string `gorm:"comment:备注; default:Null;" json:"remark"`
 	// 以下为公共字段
 	Json       any                   `gorm:"type:longtext; comment:用于存储JSON数据;" json:"json"`
 	Text       any                   `gorm:"type:longtext; comment:用于存储文本数据;" json:"text"`
@@ -98,50 +97,31 @@ func (this *AuthGroup) users(wg *sync.WaitGroup, result *any) {
 
 	defer wg.Done()
 
-	tags := utils.ArrayUnique(utils.ArrayEmpty(strings.Split(this.Uids, "|")))
-	columns, _ := facade.DB.Model(&[]Users{}).WhereIn("id", tags).Column("id", "nickname", "avatar", "account")
+	ids := GroupUserIds(this.Id)
+	if len(ids) == 0 {
+		*result = []any{}
+		return
+	}
+	columns, _ := facade.DB.Model(&[]Users{}).WhereIn("id", ids).Column("id", "nickname", "avatar", "account")
 	*result = columns
 }
 
-// Auth 应用权限
+// Auth 应用权限（将用户加入或移出分组）
 func (this *AuthGroup) Auth(uid any, group any, isRemove bool) {
 
+	var gids []int
 	for _, id := range utils.Unity.Ids(group) {
+		gids = append(gids, cast.ToInt(id))
+	}
+	if len(gids) == 0 {
+		return
+	}
 
-		item, _ := facade.DB.Model(&AuthGroup{}).WithTrashed().Where("id", id).Find()
-		if utils.Is.Empty(item) {
-			continue
-		}
-
-		uids := utils.Unity.Ids(item["uids"])
-
-		// 移除权限
-		if isRemove {
-			// 判断 uid 是否在数组中
-			if utils.InArray(cast.ToInt(uid), cast.ToIntSlice(uids)) {
-				// 原生 Go 语言删除数组元素
-				for key, val := range uids {
-					if cast.ToInt(val) == cast.ToInt(uid) {
-						uids = append(uids[:key], uids[key+1:]...)
-					}
-				}
-			}
-		} else {
-			uids = append(uids, uid)
-		}
-
-		// 去重 - 去空
-		uids = utils.Unity.Ids(uids)
-
-		var result string
-		if len(uids) > 0 {
-			result = fmt.Sprintf("|%v|", strings.Join(cast.ToStringSlice(uids), "|"))
-		}
-		// 更新数据
-		facade.DB.Model(&AuthGroup{}).WithTrashed().Where("id", id).Update(map[string]any{
-			"uids": result,
-		})
+	if isRemove {
+		RemoveUserGroups(cast.ToInt(uid), gids...)
+		return
 	}
+	AddUserGroups(cast.ToInt(uid), gids...)
 }
 
 // BeforeDelete - 删除前Hook（软删除/物理删除都会触发）
@@ -160,11 +140,8 @@ func IsRootAdmin(uid int) bool {
 		return false
 	}
 
-	var table []AuthGroup
-	facade.DB.Model(&table).Where("root", 1).Like("uids", "%|"+cast.ToString(uid)+"|%").Select()
-
-	for _, item := range table {
-		if utils.InArray("all", strings.Split(cast.ToString(item.Rules), ",")) {
+	for _, item := range UserAuthGroups(uid) {
+		if item.Root && item.All {
 			return true
 		}
 	}
@@ -0,0 +1,536 @@
+package model
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"inis/app/facade"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/spf13/cast"
+	"github.com/unti-io/go-utils/utils"
+)
+
+// 策略效果
+const (
+	// PolicyAllow - 允许
+	PolicyAllow = "allow"
+	// PolicyDeny - 拒绝（优先于允许）
+	PolicyDeny = "deny"
+)
+
+// ErrAuthPolicy - 策略格式错误
+var ErrAuthPolicy = errors.New("策略格式错误！")
+
+// authPolicyVersionKey - 权限版本号的缓存键，分组或成员变更时更新，使所有用户的权限缓存失效
+const authPolicyVersionKey = "auth-policy[version]"
+
+// authPolicyExpire - 用户权限缓存的有效期
+const authPolicyExpire = time.Hour
+
+// AuthPolicy - 权限分组的策略
+/**
+ * @example：
+ * 1. {"effect": "deny", "route": "[DELETE]/api/article/*"}
+ * 2. {"effect": "allow", "route": "[PUT]/api/article/update", "condition": "article.uid == user.id"}
+ * 3. {"effect": "allow", "route": "/api/links/*", "condition": "user.level == 'vip'"}
+ */
+type AuthPolicy struct {
+	// Effect - allow 或 deny
+	Effect string `json:"effect"`
+	// Route - 路由规则，写法同 API_KEY 的 scopes：*、/api/article/*、[GET]/api/article/*、规则哈希
+	Route string `json:"route"`
+	// Condition - 条件，多个比较用 && 连接，只引用 user 的条件在鉴权中间件中判断，引用数据的条件（如 article.uid）由控制器判断
+	Condition string `json:"condition,omitempty"`
+}
+
+// authCompare - 条件中的单个比较
+type authCompare struct {
+	left  string
+	op    string
+	right string
+}
+
+// ParseAuthPolicies - 解析策略（JSON 字符串或数组）
+func ParseAuthPolicies(value any) ([]AuthPolicy, error) {
+
+	if utils.Is.Empty(value) {
+		return nil, nil
+	}
+
+	var text []byte
+	if item, ok := value.(string); ok {
+		if strings.TrimSpace(item) == "" {
+			return nil, nil
+		}
+		text = []byte(item)
+	} else {
+		var err error
+		if text, err = json.Marshal(value); err != nil {
+			return nil, ErrAuthPolicy
+		}
+	}
+
+	var result []AuthPolicy
+	if err := json.Unmarshal(text, &result); err != nil {
+		return nil, ErrAuthPolicy
+	}
+
+	for i := range result {
+		item := &result[i]
+		item.Effect = strings.ToLower(strings.TrimSpace(item.Effect))
+		item.Route = strings.TrimSpace(item.Route)
+		item.Condition = strings.TrimSpace(item.Condition)
+		if item.Effect == "" {
+			item.Effect = PolicyAllow
+		}
+		if item.Effect != PolicyAllow && item.Effect != PolicyDeny {
+			return nil, fmt.Errorf("策略 effect 只能为 allow 或 deny：%s", item.Effect)
+		}
+		if item.Route == "" {
+			return nil, fmt.Errorf("策略 route 不能为空！")
+		}
+		if _, err := parseAuthCondition(item.Condition); err != nil {
+			return nil, err
+		}
+	}
+
+	return result, nil
+}
+
+// parseAuthCondition - 解析条件（支持 == 与 != 比较，多个比较用 && 连接）
+func parseAuthCondition(text string) (result []authCompare, err error) {
+
+	if strings.TrimSpace(text) == "" {
+		return nil, nil
+	}
+
+	for _, item := range strings.Split(text, "&&") {
+		op := "=="
+		index := strings.Index(item, "==")
+		if other := strings.Index(item, "!="); other != -1 && (index == -1 || other < index) {
+			op, index = "!=", other
+		}
+		if index == -1 {
+			return nil, fmt.Errorf("策略条件格式错误：%s", strings.TrimSpace(item))
+		}
+		left, right := strings.TrimSpace(item[:index]), strings.TrimSpace(item[index+2:])
+		if left == "" || right == "" {
+			return nil, fmt.Errorf("策略条件格式错误：%s", strings.TrimSpace(item))
+		}
+		result = append(result, authCompare{left: left, op: op, right: right})
+	}
+
+	return result, nil
+}
+
+// authOperand - 解析比较的操作数，name 为空表示字面量，否则为 name.field 形式的引用
+func authOperand(text string) (name, field, literal string) {
+
+	if len(text) >= 2 && (text[0] == '\'' || text[0] == '"') && text[len(text)-1] == text[0] {
+		return "", "", text[1 : len(text)-1]
+	}
+	if before, after, ok := strings.Cut(text, "."); ok {
+		if _, err := strconv.ParseFloat(text, 64); err != nil {
+			return strings.ToLower(before), after, ""
+		}
+	}
+	return "", "", text
+}
+
+// authResolve - 取操作数的值，引用的数据不存在时 ok 为 false
+func authResolve(text string, values map[string]map[string]any) (result string, ok bool) {
+	name, field, literal := authOperand(text)
+	if name == "" {
+		return literal, true
+	}
+	item, exist := values[name]
+	if !exist {
+		return "", false
+	}
+	return cast.ToString(item[field]), true
+}
+
+// evalAuthCondition - 判断条件，条件引用了 values 之外的数据时 ok 为 false
+func evalAuthCondition(text string, values map[string]map[string]any) (result, ok bool) {
+
+	list, err := parseAuthCondition(text)
+	if err != nil {
+		return false, false
+	}
+
+	for _, item := range list {
+		left, ok1 := authResolve(item.left, values)
+		right, ok2 := authResolve(item.right, values)
+		if !ok1 || !ok2 {
+			return false, false
+		}
+		equal := left == right
+		if a, err := strconv.ParseFloat(left, 64); err == nil {
+			if b, err := strconv.ParseFloat(right, 64); err == nil {
+				equal = a == b
+			}
+		}
+		if equal != (item.op == "==") {
+			return false, true
+		}
+	}
+
+	return true, true
+}
+
+// authConditionResolved - 条件引用的数据及其字段是否都已提供
+func authConditionResolved(text string, values map[string]map[string]any) bool {
+	list, err := parseAuthCondition(text)
+	if err != nil {
+		return false
+	}
+	for _, item := range list {
+		for _, operand := range []string{item.left, item.right} {
+			name, field, _ := authOperand(operand)
+			if name == "" {
+				continue
+			}
+			if _, ok := values[name][field]; !ok {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// authConditionNames - 条件中引用的数据名称（如 user、article）
+func authConditionNames(text string) (result []string) {
+	list, _ := parseAuthCondition(text)
+	for _, item := range list {
+		for _, operand := range []string{item.left, item.right} {
+			if name, _, _ := authOperand(operand); name != "" && !utils.InArray(name, result) {
+				result = append(result, name)
+			}
+		}
+	}
+	return result
+}
+
+// AuthGroupPolicy - 参与鉴权的权限分组
+type AuthGroupPolicy struct {
+	Id       int          `json:"id"`
+	Name     string       `json:"name"`
+	Key      string       `json:"key"`
+	Root     bool         `json:"root"`
+	All      bool         `json:"all"`
+	Rules    []string     `json:"rules"`
+	Policies []AuthPolicy `json:"policies"`
+}
+
+// NewAuthGroupPolicy - 由权限分组生成鉴权数据（无效的策略会被忽略）
+func NewAuthGroupPolicy(item AuthGroup) AuthGroupPolicy {
+
+	rules := cast.ToStringSlice(utils.ArrayEmpty(utils.ArrayUnique(strings.Split(item.Rules, ","))))
+	policies, err := ParseAuthPolicies(item.Policies)
+	if err != nil {
+		facade.Log.Warn(map[string]any{"error": err, "group": item.Id}, "权限分组策略无效，已忽略")
+	}
+
+	return AuthGroupPolicy{
+		Id:       item.Id,
+		Name:     item.Name,
+		Key:      item.Key,
+		Root:     item.Root == 1,
+		All:      utils.InArray("all", rules),
+		Rules:    rules,
+		Policies: policies,
+	}
+}
+
+// LoadAuthGroupPolicies - 查询权限分组的鉴权数据（已删除的分组不生效）
+func LoadAuthGroupPolicies(gids []int) (result []AuthGroupPolicy) {
+
+	if len(gids) == 0 {
+		return nil
+	}
+
+	// 查询到 map 中，避免触发 AuthGroup 的 AfterFind（会额外查询分组成员）
+	var list []map[string]any
+	facade.DB.Drive().Model(&AuthGroup{}).Select("id", "name", "key", "root", "rules", "policies").Where("id IN ?", gids).Order("id").Find(&list)
+
+	for _, item := range list {
+		result = append(result, NewAuthGroupPolicy(AuthGroup{
+			Id:       cast.ToInt(item["id"]),
+			Name:     cast.ToString(item["name"]),
+			Key:      cast.ToString(item["key"]),
+			Root:     cast.ToInt(item["root"]),
+			Rules:    cast.ToString(item["rules"]),
+			Policies: cast.ToString(item["policies"]),
+		}))
+	}
+	return result
+}
+
+// authPolicyVersion - 当前权限版本号
+func authPolicyVersion() string {
+	return cast.ToString(facade.Cache.Get(authPolicyVersionKey))
+}
+
+// ResetAuthPolicy - 权限分组、规则或成员变更后调用，使所有用户的权限缓存失效
+func ResetAuthPolicy() {
+	facade.Cache.Set(authPolicyVersionKey, time.Now().UnixNano(), 0)
+}
+
+// UserAuthGroups - 用户所在权限分组的鉴权数据（带缓存）
+func UserAuthGroups(uid any) (result []AuthGroupPolicy) {
+
+	if cast.ToInt(uid) <= 0 {
+		return nil
+	}
+
+	cacheName := fmt.Sprintf("user[%v][auth-group][%v]", uid, authPolicyVersion())
+	cacheState := cast.ToBool(facade.CacheToml.Get("open"))
+
+	if cacheState && facade.Cache.Has(cacheName) {
+		if json.Unmarshal([]byte(cast.ToString(facade.Cache.Get(cacheName))), &result) == nil {
+			return result
+		}
+	}
+
+	result = LoadAuthGroupPolicies(UserGroupIds(uid))
+
+	if cacheState {
+		if text, err := json.Marshal(result); err == nil {
+			go facade.Cache.Set(cacheName, string(text), authPolicyExpire)
+		}
+	}
+
+	return result
+}
+
+// AuthMatch - 鉴权过程中命中的规则
+type AuthMatch struct {
+	// Group - 分组ID
+	Group int `json:"group"`
+	// Name - 分组名称
+	Name string `json:"name"`
+	// Root - 分组是否拥有越权操作数据的能力
+	Root bool `json:"root"`
+	// Source - 来源：rules（分组的规则哈希或 all）或 policy（分组的策略）
+	Source string `json:"source"`
+	// Effect - allow 或 deny
+	Effect string `json:"effect"`
+	// Route - 命中的规则哈希、all 或策略路由
+	Route string `json:"route"`
+	// Condition - 策略条件
+	Condition string `json:"condition,omitempty"`
+	// Pending - 条件需由控制器结合数据判断
+	Pending bool `json:"pending,omitempty"`
+}
+
+// AuthDecision - 鉴权结果
+type AuthDecision struct {
+	// Allow - 是否允许访问接口
+	Allow bool `json:"allow"`
+	// Deny - 是否被拒绝策略明确拒绝
+	Deny bool `json:"deny"`
+	// Root - 是否可以越权操作他人的数据（由 root 分组无条件允许）
+	Root bool `json:"root"`
+	// Matches - 命中的规则
+	Matches []AuthMatch `json:"matches"`
+}
+
+// Authorize - 根据权限分组判断能否访问接口
+/**
+ * 拒绝优先：命中任意无条件的 deny 即拒绝；否则命中任意 allow 即允许。
+ * 只引用 user 的条件立即判断，不成立的策略视为未命中；引用数据的条件标记为 Pending，由控制器调用 Check 判断，
+ * 结果取决于此类条件时（Conditional）只有登记过的接口（RegisterAuthCheck）才能放行。
+ * @param groups 用户所在的权限分组
+ * @param user 用户信息（条件中的 user.xxx），未登录时传空 map 而不是 nil
+ * @param method 请求类型
+ * @param path 请求路径
+ * @param hash 路由规则的哈希（未登记的路由为空）
+ */
+func Authorize(groups []AuthGroupPolicy, user map[string]any, method, path, hash string) *AuthDecision {
+
+	result := &AuthDecision{Matches: []AuthMatch{}}
+	// user 为 nil 时（如按分组诊断）引用 user 的条件同样标记为 Pending
+	values := map[string]map[string]any{}
+	if user != nil {
+		values["user"] = user
+	}
+
+	for _, group := range groups {
+
+		if !utils.Is.Empty(hash) && (group.All || utils.InArray(hash, group.Rules)) {
+			route := hash
+			if group.All {
+				route = "all"
+			}
+			result.Matches = append(result.Matches, AuthMatch{
+				Group: group.Id, Name: group.Name, Root: group.Root,
+				Source: "rules", Effect: PolicyAllow, Route: route,
+			})
+		}
+
+		for _, policy := range group.Policies {
+			if !ApiKeyScopeMatch(policy.Route, method, path, hash) {
+				continue
+			}
+			match := AuthMatch{
+				Group: group.Id, Name: group.Name, Root: group.Root,
+				Source: "policy", Effect: policy.Effect, Route: policy.Route, Condition: policy.Condition,
+			}
+			if policy.Condition != "" {
+				if ok, known := evalAuthCondition(policy.Condition, values); known && !ok {
+					continue
+				} else if !known {
+					match.Pending = true
+				}
+			}
+			result.Matches = append(result.Matches, match)
+		}
+	}
+
+	for _, item := range result.Matches {
+		if item.Effect == PolicyDeny && !item.Pending {
+			result.Deny = true
+		}
+	}
+	if result.Deny {
+		return result
+	}
+
+	for _, item := range result.Matches {
+		if item.Effect != PolicyAllow {
+			continue
+		}
+		result.Allow = true
+		if item.Root && !item.Pending {
+			result.Root = true
+		}
+	}
+
+	return result
+}
+
+// authCheckRoutes - 控制器会调用 Check 判断数据条件的接口（写法同策略的 route）
+var authCheckRoutes []string
+
+// RegisterAuthCheck - 登记会调用 Check 判断数据条件的接口（由控制器在 init 中登记）
+/**
+ * 未登记的接口无法判断引用数据的条件，Rule 中间件会拒绝取决于此类条件的访问。
+ * @example：
+ * 1. model.RegisterAuthCheck("[PUT]/api/article/update", "[DELETE]/api/article/remove")
+ */
+func RegisterAuthCheck(routes ...string) {
+	authCheckRoutes = append(authCheckRoutes, routes...)
+}
+
+// AuthCheckRoute - 接口的控制器是否会调用 Check 判断数据条件
+func AuthCheckRoute(method, path string) bool {
+	for _, item := range authCheckRoutes {
+		if ApiKeyScopeMatch(item, method, path, "") {
+			return true
+		}
+	}
+	return false
+}
+
+// authDataPending - 命中的策略是否带有待判断的数据条件（只引用 user 的条件不算）
+func authDataPending(item AuthMatch) bool {
+	if !item.Pending {
+		return false
+	}
+	for _, name := range authConditionNames(item.Condition) {
+		if name != "user" {
+			return true
+		}
+	}
+	return false
+}
+
+// PendingDeny - 是否命中了带数据条件的 deny（需由控制器调用 Check 判断）
+func (this *AuthDecision) PendingDeny() bool {
+	if this == nil {
+		return false
+	}
+	for _, item := range this.Matches {
+		if item.Effect == PolicyDeny && authDataPending(item) {
+			return true
+		}
+	}
+	return false
+}
+
+// Conditional - 判定是否取决于数据条件：命中带数据条件的 deny，或只由带数据条件的 allow 允许
+func (this *AuthDecision) Conditional() bool {
+
+	if this == nil || this.Deny {
+		return false
+	}
+	if this.PendingDeny() {
+		return true
+	}
+
+	for _, item := range this.Matches {
+		if item.Effect == PolicyAllow && !authDataPending(item) {
+			return false
+		}
+	}
+
+	return this.Allow
+}
+
+// AuthorizeUser - 根据用户所在的权限分组判断能否访问接口
+func AuthorizeUser(uid int, user map[string]any, method, path, hash string) *AuthDecision {
+	return Authorize(UserAuthGroups(uid), user, method, path, hash)
+}
+
+// Check - 判断能否操作某条数据（控制器中调用）
+/**
+ * 依次判断：命中的 deny 条件成立或无法判断则拒绝；root 可操作任意数据；数据属于自己（uid 字段）可操作；命中的 allow 条件成立可操作。
+ * @param name 数据名称，对应条件中的前缀，如 article
+ * @param item 数据
+ * @param user 当前用户
+ * @example：
+ * 1. if !decision.Check("article", item, user) { ... }
+ */
+func (this *AuthDecision) Check(name string, item, user map[string]any) bool {
+
+	if this == nil || this.Deny {
+		return false
+	}
+
+	values := map[string]map[string]any{"user": user, strings.ToLower(name): item}
+
+	// 无法判断的拒绝条件（引用了未提供的数据或字段）按拒绝处理
+	for _, match := range this.Matches {
+		if match.Effect != PolicyDeny || !match.Pending {
+			continue
+		}
+		if !authConditionResolved(match.Condition, values) {
+			return false
+		}
+		if ok, known := evalAuthCondition(match.Condition, values); !known || ok {
+			return false
+		}
+	}
+
+	if this.Root {
+		return true
+	}
+
+	if uid := cast.ToInt(user["id"]); uid > 0 && cast.ToInt(item["uid"]) == uid {
+		return true
+	}
+
+	for _, match := range this.Matches {
+		if match.Effect != PolicyAllow || !match.Pending || !utils.InArray(strings.ToLower(name), authConditionNames(match.Condition)) {
+			continue
+		}
+		if ok, known := evalAuthCondition(match.Condition, values); known && ok {
+			return true
+		}
+	}
+
+	return false
+}
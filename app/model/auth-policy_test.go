@@ -0,0 +1,97 @@
+package model
+
+import "testing"
+
+// authPolicyTestGroups - 只由带数据条件的策略允许或拒绝的分组
+func authPolicyTestGroups(effect string) []AuthGroupPolicy {
+	return []AuthGroupPolicy{{
+		Id: 1, Name: "author",
+		Policies: []AuthPolicy{{Effect: effect, Route: "/api/links/*", Condition: "links.uid == user.id"}},
+	}}
+}
+
+// TestAuthorizeConditionalAllow - 只由数据条件允许的接口，控制器未登记 Check 时不能放行
+func TestAuthorizeConditionalAllow(t *testing.T) {
+
+	decision := Authorize(authPolicyTestGroups(PolicyAllow), map[string]any{"id": 1}, "PUT", "/api/links/update", "")
+
+	if !decision.Allow || !decision.Conditional() {
+		t.Fatalf("应为取决于数据条件的允许：%+v", decision)
+	}
+	if AuthCheckRoute("PUT", "/api/links/update") {
+		t.Fatal("links 控制器未登记 Check，不应视为会判断数据条件")
+	}
+
+	// 同时被无条件允许时不再取决于数据条件
+	groups := append(authPolicyTestGroups(PolicyAllow), AuthGroupPolicy{Id: 2, Name: "admin", Rules: []string{"hash"}})
+	if decision := Authorize(groups, map[string]any{"id": 1}, "PUT", "/api/links/update", "hash"); decision.Conditional() {
+		t.Fatalf("已被规则哈希无条件允许：%+v", decision)
+	}
+}
+
+// TestAuthorizeConditionalDeny - 带数据条件的拒绝同样需要控制器判断
+func TestAuthorizeConditionalDeny(t *testing.T) {
+
+	groups := append(authPolicyTestGroups(PolicyDeny), AuthGroupPolicy{Id: 2, Name: "admin", Rules: []string{"hash"}})
+	decision := Authorize(groups, map[string]any{"id": 1}, "PUT", "/api/links/update", "hash")
+
+	if decision.Deny || !decision.PendingDeny() || !decision.Conditional() {
+		t.Fatalf("应为待判断的拒绝：%+v", decision)
+	}
+}
+
+// TestAuthorizeUserCondition - 只引用 user 的条件立即判断，不算数据条件
+func TestAuthorizeUserCondition(t *testing.T) {
+
+	groups := []AuthGroupPolicy{{
+		Id: 1, Name: "vip",
+		Policies: []AuthPolicy{{Effect: PolicyAllow, Route: "/api/links/*", Condition: "user.level == 'vip'"}},
+	}}
+
+	if decision := Authorize(groups, map[string]any{"level": "vip"}, "GET", "/api/links/one", ""); !decision.Allow || decision.Conditional() {
+		t.Fatalf("条件成立时应无条件允许：%+v", decision)
+	}
+	if decision := Authorize(groups, map[string]any{"level": "user"}, "GET", "/api/links/one", ""); decision.Allow {
+		t.Fatalf("条件不成立时不应允许：%+v", decision)
+	}
+}
+
+// TestAuthCheckRoute - 登记的接口按请求类型与路径匹配
+func TestAuthCheckRoute(t *testing.T) {
+
+	origin := authCheckRoutes
+	t.Cleanup(func() { authCheckRoutes = origin })
+
+	RegisterAuthCheck("[PUT]/api/test/update")
+
+	if !AuthCheckRoute("PUT", "/api/test/update") {
+		t.Fatal("已登记的接口应匹配")
+	}
+	if AuthCheckRoute("GET", "/api/test/update") {
+		t.Fatal("请求类型不同时不应匹配")
+	}
+}
+
+// TestCheckUnresolvedDeny - 拒绝条件引用的字段未提供时按拒绝处理
+func TestCheckUnresolvedDeny(t *testing.T) {
+
+	groups := []AuthGroupPolicy{{
+		Id: 1, Name: "author", Rules: []string{"hash"},
+		Policies: []AuthPolicy{{Effect: PolicyDeny, Route: "/api/article/*", Condition: "article.status == 0"}},
+	}}
+	user := map[string]any{"id": 1}
+	decision := Authorize(groups, user, "PUT", "/api/article/update", "hash")
+
+	// 只提供了 uid（字段 B），没有 status（字段 A）
+	if decision.Check("article", map[string]any{"uid": 1}, user) {
+		t.Fatal("拒绝条件无法判断时不应放行")
+	}
+	// 引用的数据名称未提供
+	if decision.Check("comment", map[string]any{"uid": 1, "status": 1}, user) {
+		t.Fatal("拒绝条件引用的数据未提供时不应放行")
+	}
+	// 条件可以判断且不成立时，数据属于自己可以操作
+	if !decision.Check("article", map[string]any{"uid": 1, "status": 1}, user) {
+		t.Fatal("拒绝条件不成立时应允许操作自己的数据")
+	}
+}
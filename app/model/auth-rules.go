@@ -81,8 +81,12 @@ func createAuthRules() (result []AuthRules) {
 		"comm": {
 			"POST": {
 				"path=login&name=传统和加密登录&type=common",
+				"path=login-2fa&name=两步验证登录&type=common",
+				"path=passkey-options&name=获取通行密钥登录参数&type=common",
+				"path=passkey-login&name=通行密钥登录&type=common",
 				"path=register&name=注册账户&type=common",
 				"path=check-token&name=校验登录&type=common",
+				"path=refresh-token&name=刷新登录令牌&type=common",
 				"path=reset-password&name=重置密码&type=common",
 				"path=logout&name=退出登录&type=common",
 			},
@@ -96,6 +100,7 @@ func createAuthRules() (result []AuthRules) {
 				"path=log&name=获取日志服务配置",
 				"path=storage&name=获取存储服务配置",
 				"path=notification&name=获取通知配置",
+				"path=email-template&name=获取邮件模板",
 			},
 			"PUT": {
 				"path=sms&name=修改SMS服务配置",
@@ -103,7 +108,8 @@ func createAuthRules() (result []AuthRules) {
 				"path=sms-aliyun&name=修改阿里云短信服务配置",
 				"path=sms-aliyun-number-verify&name=修改阿里云号码验证配置",
 				"path=sms-tencent&name=修改腾讯云短信服务配置",
-				"path=crypt-jwt&name=修改JWT配置",
+				"path=sms-http&name=修改HTTP短信网关配置",
+				"path=crypt-jwt&name=修改JWT配置或轮换签名密钥",
 				"path=cache-redis&name=修改Redis缓存配置",
 				"path=cache-file&name=修改文件缓存配置",
 				"path=cache-ram&name=修改内存缓存配置",
@@ -116,16 +122,24 @@ func createAuthRules() (result []AuthRules) {
 				"path=storage-kodo&name=修改KODO存储配置",
 				"path=storage-attachment&name=修改附件配置",
 				"path=notification&name=修改通知配置",
+				"path=email-template&name=修改邮件模板",
 			},
 			"POST": {
 				"path=test-sms-email&name=发送测试邮件",
 				"path=test-sms-aliyun&name=发送阿里云测试短信",
 				"path=test-sms-aliyun-number-verify&name=发送阿里云号码验证服务测试短信",
 				"path=test-sms-tencent&name=发送腾讯云测试短信",
+				"path=test-sms-http&name=发送HTTP短信网关测试短信",
 				"path=test-redis&name=测试Redis连接",
 				"path=test-oss&name=测试OSS连接",
 				"path=test-cos&name=测试COS连接",
 				"path=test-kodo&name=测试KODO连接",
+				"path=test-scanner&name=测试文件扫描服务",
+				"path=preview-email-template&name=预览邮件模板",
+				"path=test-email-template&name=发送测试模板邮件",
+			},
+			"DELETE": {
+				"path=email-template&name=删除自定义邮件模板",
 			},
 		},
 		"tags": {
@@ -163,6 +177,7 @@ func createAuthRules() (result []AuthRules) {
 				"path=status&type=login&name=修改用户状态",
 				"path=ban&name=封禁用户",
 				"path=unban&name=解封用户",
+				"path=unlock&name=解除账号或IP的验证失败锁定",
 				"path=appeal-handle&name=处理申诉",
 			},
 			"POST": {
@@ -297,15 +312,140 @@ func createAuthRules() (result []AuthRules) {
 			"DELETE": {"path=remove&type=login", "path=delete&type=login", "path=clear&type=login"},
 		},
 		"api-keys": {
-			"GET":    {"one", "all", "sum", "min", "max", "count", "column", "rand"},
-			"PUT":    {"update", "restore"},
+			"GET":    {"one", "all", "sum", "min", "max", "count", "column", "rand", "path=usage&name=查看密钥使用情况"},
+			"PUT":    {"update", "restore", "path=reset&name=重置密钥"},
 			"POST":   {"save", "create"},
 			"DELETE": {"remove", "delete", "clear"},
 		},
+		"webhook": {
+			"GET": {
+				"path=one&name=获取指定Webhook",
+				"path=all&name=获取Webhook列表",
+				"path=count&name=查询Webhook数量",
+				"path=events&name=获取Webhook事件列表",
+				"path=deliveries&name=获取Webhook投递记录",
+			},
+			"POST": {
+				"path=save&name=保存Webhook",
+				"path=create&name=创建Webhook",
+				"path=ping&name=发送Webhook测试事件",
+				"path=redeliver&name=重新投递Webhook",
+			},
+			"PUT": {
+				"path=update&name=更新Webhook",
+				"path=restore&name=恢复Webhook",
+				"path=secret&name=重置Webhook签名密钥",
+			},
+			"DELETE": {
+				"path=remove&name=删除Webhook",
+				"path=delete&name=彻底删除Webhook",
+				"path=clear&name=清空Webhook回收站",
+			},
+		},
+		"user-sessions": {
+			"GET": {"path=all&type=login&name=获取登录设备"},
+			"DELETE": {
+				"path=revoke&type=login&name=下线登录设备",
+				"path=others&type=login&name=退出其它设备",
+				"path=user&name=强制下线用户",
+			},
+		},
+		"oauth": {
+			"GET": {
+				"path=providers&name=获取第三方登录服务&type=common",
+				"path=authorize&name=发起第三方登录或绑定&type=common",
+				"path=identities&type=login&name=获取绑定的第三方账号",
+			},
+			"POST":   {"path=callback&name=第三方登录回调&type=common"},
+			"DELETE": {"path=unlink&type=login&name=解绑第三方账号"},
+		},
+		"oidc": {
+			"GET": {
+				"path=authorize&type=common&name=获取第三方应用授权信息",
+				"path=grants&type=login&name=获取已授权的第三方应用",
+			},
+			"POST":   {"path=authorize&type=login&name=确认第三方应用授权"},
+			"DELETE": {"path=grant&type=login&name=取消第三方应用授权"},
+		},
+		"oauth-clients": {
+			"GET": {
+				"path=one&name=获取指定第三方应用",
+				"path=all&name=获取第三方应用列表",
+				"path=count&name=查询第三方应用数量",
+				"path=scopes&name=获取可开放的权限范围",
+			},
+			"POST": {
+				"path=save&name=保存第三方应用",
+				"path=create&name=登记第三方应用",
+			},
+			"PUT": {
+				"path=update&name=更新第三方应用",
+				"path=restore&name=恢复第三方应用",
+				"path=secret&name=重置第三方应用密钥",
+			},
+			"DELETE": {
+				"path=remove&name=删除第三方应用",
+				"path=delete&name=彻底删除第三方应用",
+			},
+		},
+		"audit-log": {
+			"GET": {
+				"path=one&name=获取指定审计日志",
+				"path=all&name=获取审计日志列表",
+				"path=count&name=查询审计日志数量",
+				"path=export&name=导出审计日志",
+			},
+		},
+		"captcha": {
+			"GET": {
+				"path=create&type=common&name=获取人机验证题目",
+				"path=config&type=common&name=获取人机验证配置",
+			},
+		},
+		"totp": {
+			"GET": {"path=status&type=login&name=获取两步验证状态"},
+			"POST": {
+				"path=enroll&type=login&name=获取两步验证密钥",
+				"path=confirm&type=login&name=开启两步验证",
+				"path=recovery-codes&type=login&name=重新生成恢复码",
+			},
+			"DELETE": {
+				"path=disable&type=login&name=关闭两步验证",
+				"path=reset&name=重置用户两步验证",
+			},
+		},
+		"passkey": {
+			"GET": {"path=all&type=login&name=获取通行密钥"},
+			"POST": {
+				"path=register-options&type=login&name=获取通行密钥绑定参数",
+				"path=register&type=login&name=绑定通行密钥",
+			},
+			"PUT": {"path=rename&type=login&name=重命名通行密钥"},
+			"DELETE": {
+				"path=remove&type=login&name=删除通行密钥",
+				"path=reset&name=清空用户通行密钥",
+			},
+		},
+		"outbox": {
+			"GET": {
+				"path=one&name=获取指定消息",
+				"path=all&name=获取消息列表",
+				"path=count&name=查询消息数量",
+				"path=stats&name=统计各状态消息数量",
+				"path=kinds&name=获取消息类型列表",
+			},
+			"PUT": {
+				"path=retry&name=重新发送消息",
+			},
+			"DELETE": {
+				"path=delete&name=删除消息",
+				"path=clear&name=清空已发送或死信消息",
+			},
+		},
 		"auth-group": {
-			"GET":    {"one", "all", "sum", "min", "max", "count", "column", "rand"},
+			"GET":    {"one", "all", "sum", "min", "max", "count", "column", "rand", "path=explain&name=权限诊断"},
 			"PUT":    {"update", "restore", "path=uids&name=更改用户权限"},
-			"POST":   {"save", "create"},
+			"POST":   {"save", "create", "path=simulate&name=模拟分组变更"},
 			"DELETE": {"remove", "delete", "clear"},
 		},
 		"auth-rules": {
@@ -447,6 +587,7 @@ func createAuthRules() (result []AuthRules) {
 				"path=column&type=common&name=列查询",
 				"path=list&type=login&name=获取我的附件",
 				"path=emoji&type=common&name=获取表情列表",
+				"path=quarantine&name=获取隔离区文件",
 			},
 			"POST": {
 				"path=save&type=login&name=保存数据",
@@ -464,6 +605,7 @@ func createAuthRules() (result []AuthRules) {
 				"path=remove&type=login&name=软删除",
 				"path=delete&type=login&name=彻底删除",
 				"path=clear&type=login&name=清空回收站",
+				"path=quarantine&name=删除隔离区文件",
 			},
 		},
 		"user-collects": {
@@ -569,11 +711,17 @@ func createAuthRules() (result []AuthRules) {
 				"path=column&type=login&name=列查询通知",
 				"path=list&type=login&name=获取通知列表",
 				"path=unread-count&type=login&name=获取未读通知数",
+				"path=preferences&type=login&name=获取通知偏好",
+				"path=unsubscribe&type=common&name=邮件退订确认页",
+				"path=push-key&type=common&name=获取Web Push公钥",
+				"path=push-subscriptions&type=login&name=获取Web Push订阅设备",
 			},
 			"POST": {
 				"path=save&type=login",
 				"path=create&type=login",
 				"path=send-system&type=default&name=发送系统消息",
+				"path=unsubscribe&type=common&name=邮件一键退订（List-Unsubscribe-Post）",
+				"path=push-subscribe&type=login&name=订阅Web Push",
 			},
 			"PUT": {
 				"path=update&type=login",
@@ -581,12 +729,14 @@ func createAuthRules() (result []AuthRules) {
 				"path=read&type=login&name=标记已读",
 				"path=read-all&type=login&name=全部标记已读",
 				"path=read-batch&type=login&name=批量标记已读",
+				"path=preferences&type=login&name=修改通知偏好",
 			},
 			"DELETE": {
 				"path=remove&type=login&name=删除通知",
 				"path=delete&type=login",
 				"path=clear&type=login",
 				"path=remove-all&type=login&name=清空通知",
+				"path=push-unsubscribe&type=login&name=取消Web Push订阅",
 			},
 		},
 	}
@@ -625,6 +775,12 @@ func createAuthRules() (result []AuthRules) {
 		"moments":       "【动态 API】",
 		"attachment":    "【附件 API】",
 		"notification":  "【消息通知 API】",
+		"webhook":       "【Webhook API】",
+		"outbox":        "【发件箱 API】",
+		"oidc":          "【授权服务 API】",
+		"oauth-clients": "【第三方应用 API】",
+		"audit-log":     "【审计日志 API】",
+		"captcha":       "【人机验证 API】",
 	}
 
 	// 基础方法
@@ -48,6 +48,7 @@ func InitTable() {
 		{"Tags", InitTags},
 		{"Users", InitUsers},
 		{"AuthGroup", InitAuthGroup},
+		{"AuthGroupUser", InitAuthGroupUser},
 		{"Pages", InitPages},
 		{"Level", InitLevel},
 		{"EXP", InitEXP},
@@ -62,6 +63,21 @@ func InitTable() {
 		{"UserBanRecords", InitUserBanRecords},
 		{"Notification", InitNotification},
 		{"NotificationRead", InitNotificationRead},
+		{"Webhook", InitWebhook},
+		{"WebhookDelivery", InitWebhookDelivery},
+		{"Outbox", InitOutbox},
+		{"NotificationPreference", InitNotificationPreference},
+		{"NotificationDigest", InitNotificationDigest},
+		{"EmailTemplate", InitEmailTemplate},
+		{"PushSubscription", InitPushSubscription},
+		{"UserSession", InitUserSession},
+		{"UserTotp", InitUserTotp},
+		{"UserPasskey", InitUserPasskey},
+		{"UserIdentity", InitUserIdentity},
+		{"OAuthClient", InitOAuthClient},
+		{"OAuthToken", InitOAuthToken},
+		{"OAuthConsent", InitOAuthConsent},
+		{"AuditLog", InitAuditLog},
 	}
 
 	for _, item := range allow {
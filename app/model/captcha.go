@@ -0,0 +1,216 @@
+package model
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"inis/app/facade"
+	"strings"
+	"time"
+
+	"github.com/spf13/cast"
+	"github.com/unti-io/go-utils/utils"
+)
+
+// 人机验证的题目类型
+const (
+	// CaptchaImage - 图形字符
+	CaptchaImage = "image"
+	// CaptchaMath - 图形算术题
+	CaptchaMath = "math"
+	// CaptchaPow - 工作量证明（客户端计算，无需用户操作）
+	CaptchaPow = "pow"
+)
+
+// 路由的人机验证模式
+const (
+	// CaptchaAlways - 每次请求都需要
+	CaptchaAlways = "always"
+	// CaptchaRisk - 当前IP有验证失败记录时才需要
+	CaptchaRisk = "risk"
+)
+
+// CaptchaTypes - 支持的题目类型
+var CaptchaTypes = []string{CaptchaImage, CaptchaMath, CaptchaPow}
+
+// CaptchaCacheKey - 人机验证配置（SYSTEM_CAPTCHA）的缓存键
+const CaptchaCacheKey = "config[SYSTEM_CAPTCHA]"
+
+// ErrCaptchaType - 不支持的题目类型
+var ErrCaptchaType = errors.New("不支持的人机验证类型！")
+
+func init() {
+	// 为防暴力破解注册人机验证
+	LoginGuardCaptcha = VerifyCaptcha
+}
+
+// captchaKey - 题目的缓存键
+func captchaKey(id string) string {
+	return fmt.Sprintf("captcha[%v]", id)
+}
+
+// captchaExpire - 题目有效期
+func captchaExpire() time.Duration {
+	return time.Duration(cast.ToInt(facade.AppToml.Get("captcha.expire", 300))) * time.Second
+}
+
+// CreateCaptcha - 生成题目，答案保存在缓存中，返回给客户端的题目信息
+/**
+ * @param kind 题目类型，为空时使用 SYSTEM_CAPTCHA 配置的类型
+ * @param ip 客户端IP（校验时必须来自同一IP）
+ * @example：
+ * 1. item, err := model.CreateCaptcha(model.CaptchaImage, ctx.ClientIP())
+ */
+func CreateCaptcha(kind, ip string) (map[string]any, error) {
+
+	if utils.Is.Empty(kind) {
+		kind = cast.ToString(CaptchaSetting()["type"])
+	}
+	if !utils.InArray(kind, CaptchaTypes) {
+		return nil, ErrCaptchaType
+	}
+
+	buf := make([]byte, 16)
+	if _, err := rand.Read(buf); err != nil {
+		return nil, err
+	}
+	id := hex.EncodeToString(buf)
+	expire := captchaExpire()
+
+	item := map[string]any{"type": kind, "ip": ip}
+	result := map[string]any{
+		"id":     id,
+		"type":   kind,
+		"expire": time.Now().Add(expire).Unix(),
+	}
+
+	width := cast.ToInt(facade.AppToml.Get("captcha.width", 120))
+	height := cast.ToInt(facade.AppToml.Get("captcha.height", 40))
+
+	var text string
+	switch kind {
+	case CaptchaImage:
+		text = facade.CaptchaText(cast.ToInt(facade.AppToml.Get("captcha.length", 4)))
+		item["answer"] = text
+	case CaptchaMath:
+		question, answer := facade.CaptchaMath()
+		text = question
+		item["answer"] = cast.ToString(answer)
+		// 算术题字符较多，按字数加宽图片
+		if size := len(question)*24 + 16; width < size {
+			width = size
+		}
+	case CaptchaPow:
+		salt := make([]byte, 16)
+		if _, err := rand.Read(salt); err != nil {
+			return nil, err
+		}
+		difficulty := cast.ToInt(facade.AppToml.Get("captcha.pow_difficulty", 18))
+		item["salt"] = hex.EncodeToString(salt)
+		item["difficulty"] = difficulty
+		result["salt"] = item["salt"]
+		result["difficulty"] = difficulty
+		result["algorithm"] = "sha256"
+	}
+
+	if !utils.Is.Empty(text) {
+		image, err := facade.CaptchaImage(text, width, height)
+		if err != nil {
+			return nil, err
+		}
+		result["image"] = "data:image/png;base64," + base64.StdEncoding.EncodeToString(image)
+	}
+
+	facade.Cache.Set(captchaKey(id), item, expire)
+
+	return result, nil
+}
+
+// VerifyCaptcha - 校验人机验证凭证（格式为 题目ID:答案，工作量证明的答案为 nonce），题目无论成功与否只能校验一次
+func VerifyCaptcha(token, ip string) bool {
+
+	id, answer, ok := strings.Cut(strings.TrimSpace(token), ":")
+	if !ok || utils.Is.Empty(id) || utils.Is.Empty(answer) || len(id) > 64 {
+		return false
+	}
+
+	// 原子地取出并删除，并发提交同一答案时只有一个能通过
+	item := cast.ToStringMap(facade.Cache.Take(captchaKey(id)))
+	if utils.Is.Empty(item) {
+		return false
+	}
+
+	if !utils.Is.Empty(item["ip"]) && cast.ToString(item["ip"]) != ip {
+		return false
+	}
+
+	switch cast.ToString(item["type"]) {
+	case CaptchaImage:
+		return strings.EqualFold(answer, cast.ToString(item["answer"]))
+	case CaptchaMath:
+		return strings.TrimSpace(answer) == cast.ToString(item["answer"])
+	case CaptchaPow:
+		return facade.CaptchaPowValid(cast.ToString(item["salt"]), answer, cast.ToInt(item["difficulty"]))
+	}
+
+	return false
+}
+
+// CaptchaSetting - 人机验证配置（SYSTEM_CAPTCHA：value 是否开启，json 包含 type 默认题目类型、guest_only 仅游客、routes 路由 => 模式）
+func CaptchaSetting() map[string]any {
+
+	cacheState := cast.ToBool(facade.CacheToml.Get("open"))
+
+	var config map[string]any
+	if cacheState && facade.Cache.Has(CaptchaCacheKey) {
+		config = cast.ToStringMap(facade.Cache.Get(CaptchaCacheKey))
+	} else {
+		config, _ = facade.DB.Model(&Config{}).Where("key", "SYSTEM_CAPTCHA").Find()
+		if cacheState {
+			go facade.Cache.Set(CaptchaCacheKey, config)
+		}
+	}
+
+	setting := cast.ToStringMap(config["json"])
+	if !utils.InArray(cast.ToString(setting["type"]), CaptchaTypes) {
+		setting["type"] = CaptchaImage
+	}
+	setting["enable"] = cast.ToBool(config["value"])
+	setting["routes"] = cast.ToStringMapString(setting["routes"])
+
+	return setting
+}
+
+// CaptchaRequired - 请求是否需要人机验证
+/**
+ * @param method 请求类型
+ * @param path 请求路径
+ * @param ip 客户端IP
+ * @param login 是否已登录
+ */
+func CaptchaRequired(method, path, ip string, login bool) bool {
+
+	setting := CaptchaSetting()
+	if !cast.ToBool(setting["enable"]) {
+		return false
+	}
+	if login && cast.ToBool(setting["guest_only"]) {
+		return false
+	}
+
+	for route, mode := range cast.ToStringMapString(setting["routes"]) {
+		if !ApiKeyScopeMatch(route, method, path, "") {
+			continue
+		}
+		switch mode {
+		case CaptchaAlways:
+			return true
+		case CaptchaRisk:
+			return LoginGuardRisk(ip)
+		}
+	}
+
+	return false
+}
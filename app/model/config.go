@@ -44,6 +44,15 @@ func InitConfig() {
 			"email":   "",
 			"webhook": "",
 		}), Remark: "QPS自动封禁通知（邮件/Webhook）"},
+		{Key: "SYSTEM_CAPTCHA", Value: "0", Json: utils.Json.Encode(facade.H{
+			"type":       "image",
+			"guest_only": 1,
+			"routes": facade.H{
+				"[POST]/api/comm/register":       "always",
+				"[POST]/api/comment/create":      "always",
+				"[POST]/api/comm/reset-password": "risk",
+			},
+		}), Remark: "人机验证（路由 => always 每次验证、risk 有失败记录时验证）"},
 		{Key: "SYSTEM_PAGE_LIMIT", Value: "1", Text: "50", Remark: "限制分页查询单次最大数据量"},
 		{Key: "ALLOW_REGISTER", Value: "1", Remark: "是否允许用户自行注册"},
 		{Key: "PAGE", Json: utils.Json.Encode(facade.H{
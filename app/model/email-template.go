@@ -0,0 +1,51 @@
+package model
+
+import (
+	"inis/app/facade"
+
+	"gorm.io/plugin/soft_delete"
+)
+
+// EmailTemplate - 自定义邮件模板（未配置时使用 config/mail 下的默认模板文件）
+type EmailTemplate struct {
+	Id      int    `gorm:"type:int(32); comment:主键;" json:"id"`
+	Name    string `gorm:"size:64; uniqueIndex:uk_email_template_name_lang,priority:1; comment:模板名称;" json:"name"`
+	Lang    string `gorm:"size:16; uniqueIndex:uk_email_template_name_lang,priority:2; comment:语言;" json:"lang"`
+	Subject string `gorm:"size:256; comment:邮件主题模板;" json:"subject"`
+	Body    string `gorm:"type:longtext; comment:邮件正文模板;" json:"body"`
+	Remark  string `gorm:"comment:备注; default:Null;" json:"remark"`
+	// 以下为公共字段
+	CreateTime int64                 `gorm:"autoCreateTime; comment:创建时间;" json:"create_time"`
+	UpdateTime int64                 `gorm:"autoUpdateTime; comment:更新时间;" json:"update_time"`
+	DeleteTime soft_delete.DeletedAt `gorm:"comment:删除时间; default:0;" json:"delete_time"`
+}
+
+func init() {
+	facade.MailTemplateLoader = loadEmailTemplate
+}
+
+// InitEmailTemplate - 初始化EmailTemplate表
+func InitEmailTemplate() {
+	err := facade.DB.Drive().AutoMigrate(&EmailTemplate{})
+	if err != nil {
+		facade.Log.Error(map[string]any{"error": err}, "EmailTemplate表迁移失败")
+		return
+	}
+}
+
+// loadEmailTemplate - 读取数据库中的自定义模板
+func loadEmailTemplate(name, lang string) (subject, body string, ok bool) {
+
+	// 数据库未初始化（未安装）时跳过
+	if facade.DB == nil {
+		return "", "", false
+	}
+
+	var item EmailTemplate
+	err := facade.DB.Drive().Where("name = ? AND lang = ?", name, lang).Limit(1).Find(&item).Error
+	if err != nil || item.Id == 0 {
+		return "", "", false
+	}
+
+	return item.Subject, item.Body, true
+}
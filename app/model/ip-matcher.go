@@ -0,0 +1,84 @@
+package model
+
+import (
+	"inis/app/facade"
+	"sync"
+	"time"
+)
+
+// ipMatcherTTL - 匹配器的最长缓存时间（多实例部署时其他实例修改名单后最迟在此时间后生效）
+const ipMatcherTTL = time.Minute
+
+// ipMatcherCache - 由名单表构建的IP规则匹配器
+type ipMatcherCache struct {
+	mutex   sync.Mutex
+	matcher *facade.IPMatcher
+	time    time.Time
+	load    func() *facade.IPMatcher
+}
+
+// get - 获取匹配器，过期时重新构建
+func (this *ipMatcherCache) get() *facade.IPMatcher {
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+	if this.matcher == nil || time.Since(this.time) > ipMatcherTTL {
+		this.matcher, this.time = this.load(), time.Now()
+	}
+	return this.matcher
+}
+
+// reset - 丢弃匹配器，下次使用时重新构建
+func (this *ipMatcherCache) reset() {
+	this.mutex.Lock()
+	this.matcher = nil
+	this.mutex.Unlock()
+}
+
+var ipBlackMatcher = &ipMatcherCache{load: func() *facade.IPMatcher {
+	var list []IpBlack
+	facade.DB.Drive().Find(&list)
+	matcher := facade.NewIPMatcher()
+	for i := range list {
+		if err := matcher.Add(list[i].Ip, &list[i]); err != nil {
+			facade.Log.Warn(map[string]any{"id": list[i].Id, "ip": list[i].Ip}, "IP黑名单规则无效，已忽略")
+		}
+	}
+	return matcher
+}}
+
+var ipWhiteMatcher = &ipMatcherCache{load: func() *facade.IPMatcher {
+	var list []IpWhite
+	facade.DB.Drive().Find(&list)
+	matcher := facade.NewIPMatcher()
+	for i := range list {
+		if err := matcher.Add(list[i].Ip, &list[i]); err != nil {
+			facade.Log.Warn(map[string]any{"id": list[i].Id, "ip": list[i].Ip}, "IP白名单规则无效，已忽略")
+		}
+	}
+	return matcher
+}}
+
+// ResetIpMatchers - 黑白名单变更后调用，使新规则立即生效
+func ResetIpMatchers() {
+	ipBlackMatcher.reset()
+	ipWhiteMatcher.reset()
+}
+
+// MatchIpBlack - 返回命中且仍在封禁期内的黑名单记录（单个IP、网段、国家或 ASN），未命中时返回 nil
+/**
+ * @example：
+ * 1. if item := model.MatchIpBlack(ctx.ClientIP()); item != nil { ... }
+ */
+func MatchIpBlack(ip string) *IpBlack {
+	for _, item := range ipBlackMatcher.get().Match(ip) {
+		if record := item.(*IpBlack); record.IsBanned() {
+			return record
+		}
+	}
+	return nil
+}
+
+// MatchIpWhite - IP是否命中白名单（单个IP、网段、国家或 ASN）
+func MatchIpWhite(ip string) bool {
+	return len(ipWhiteMatcher.get().Match(ip)) > 0
+}
@@ -0,0 +1,110 @@
+package model
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"fmt"
+	"inis/app/facade"
+	"time"
+
+	"github.com/spf13/cast"
+	"github.com/unti-io/go-utils/utils"
+)
+
+const (
+	// LoginChallengeExpire - 两步登录挑战令牌有效期
+	LoginChallengeExpire = 5 * time.Minute
+	// loginChallengeAttempts - 挑战令牌允许的最大失败次数
+	loginChallengeAttempts = 5
+	// cacheLoginChallenge - 挑战令牌缓存键
+	cacheLoginChallenge = "login-challenge[%v]"
+)
+
+// LoginChallenge - 两步登录挑战（密码校验通过后签发，完成第二步验证后换取登录会话）
+type LoginChallenge struct {
+	Token   string
+	Uid     int
+	Account string
+	Ip      string
+	// 是否为强制开启两步验证时的首次绑定
+	Enroll   bool
+	Attempts int
+	Expire   int64
+}
+
+// CreateLoginChallenge - 签发挑战令牌（仅保存在缓存中，不可用于访问接口）
+func CreateLoginChallenge(uid int, account, ip string, enroll bool) (*LoginChallenge, error) {
+
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return nil, err
+	}
+
+	item := &LoginChallenge{
+		Token:   base64.RawURLEncoding.EncodeToString(buf),
+		Uid:     uid,
+		Account: account,
+		Ip:      ip,
+		Enroll:  enroll,
+		Expire:  time.Now().Add(LoginChallengeExpire).Unix(),
+	}
+	item.save()
+
+	return item, nil
+}
+
+// FindLoginChallenge - 查询有效的挑战令牌（不存在、已过期或IP不一致时返回 nil）
+func FindLoginChallenge(token, ip string) *LoginChallenge {
+
+	if utils.Is.Empty(token) {
+		return nil
+	}
+
+	cached := cast.ToStringMap(facade.Cache.Get(fmt.Sprintf(cacheLoginChallenge, token)))
+	if utils.Is.Empty(cached) {
+		return nil
+	}
+
+	item := &LoginChallenge{
+		Token:    token,
+		Uid:      cast.ToInt(cached["uid"]),
+		Account:  cast.ToString(cached["account"]),
+		Ip:       cast.ToString(cached["ip"]),
+		Enroll:   cast.ToBool(cached["enroll"]),
+		Attempts: cast.ToInt(cached["attempts"]),
+		Expire:   cast.ToInt64(cached["expire"]),
+	}
+	if item.Uid <= 0 || item.Expire <= time.Now().Unix() || item.Ip != ip {
+		return nil
+	}
+
+	return item
+}
+
+// Fail - 记录一次验证失败，达到上限后挑战令牌失效，返回是否仍可重试
+func (this *LoginChallenge) Fail() bool {
+	this.Attempts++
+	if this.Attempts >= loginChallengeAttempts {
+		this.Revoke()
+		return false
+	}
+	this.save()
+	return true
+}
+
+// Revoke - 使挑战令牌失效（一次性，验证通过后立即调用）
+func (this *LoginChallenge) Revoke() {
+	facade.Cache.Del(fmt.Sprintf(cacheLoginChallenge, this.Token))
+}
+
+// save - 写入缓存（续写时不延长有效期）
+func (this *LoginChallenge) save() {
+	facade.Cache.Set(fmt.Sprintf(cacheLoginChallenge, this.Token), map[string]any{
+		"uid":      this.Uid,
+		"account":  this.Account,
+		"ip":       this.Ip,
+		"enroll":   this.Enroll,
+		"attempts": this.Attempts,
+		"expire":   this.Expire,
+	}, time.Until(time.Unix(this.Expire, 0)))
+}
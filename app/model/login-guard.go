@@ -0,0 +1,357 @@
+package model
+
+import (
+	"fmt"
+	"inis/app/facade"
+	"math"
+	"time"
+
+	"github.com/spf13/cast"
+	"github.com/unti-io/go-utils/utils"
+)
+
+// 防暴力破解的校验场景 - 不同场景的失败次数分开统计
+const (
+	LoginGuardLogin         = "login"
+	LoginGuardResetPassword = "reset-password"
+	LoginGuardVerifyCode    = "verify-code"
+)
+
+// LoginGuardScopes - 全部校验场景（管理员解除锁定时逐一清理）
+var LoginGuardScopes = []string{LoginGuardLogin, LoginGuardResetPassword, LoginGuardVerifyCode}
+
+// LoginGuardCaptcha - 人机验证校验函数（由验证码服务注册，未注册时不强制人机验证）
+var LoginGuardCaptcha func(token, ip string) bool
+
+// LoginGuardError - 防暴力破解拦截错误（Message 为语言包键，Args 为格式化参数）
+type LoginGuardError struct {
+	Message string
+	Args    []any
+	// 需要等待的秒数
+	Wait int64
+	// 是否需要人机验证
+	Captcha bool
+}
+
+func (this *LoginGuardError) Error() string {
+	return fmt.Sprintf(this.Message, this.Args...)
+}
+
+// LoginGuard - 登录、找回密码与验证码校验的失败次数统计（按 账号 与 IP 两个维度）
+type LoginGuard struct {
+	// 校验场景，如 LoginGuardLogin
+	Scope string
+	// 账号标识（登录与找回密码为用户ID，验证码为收件人），为空时只统计IP
+	Subject string
+	// 客户端IP
+	IP string
+	// 是否开启
+	Enable bool
+	// 同一账号在统计窗口内失败N次后锁定（0 表示不锁定）
+	AccountLimit int
+	// 同一IP在统计窗口内失败N次后锁定（0 表示不锁定）
+	IPLimit int
+	// 失败次数统计窗口
+	Window time.Duration
+	// 锁定时长
+	Lock time.Duration
+	// 渐进延迟基数，第N次失败后需等待 Delay * 2^(N-1) 才能再次尝试
+	Delay time.Duration
+	// 渐进延迟上限
+	MaxDelay time.Duration
+	// 账号或IP失败N次后要求人机验证（0 表示不要求）
+	CaptchaAfter int
+	// 本次请求已通过人机验证（如路由级的人机验证中间件），无需重复校验
+	Passed bool
+}
+
+// NewLoginGuard - 创建防暴力破解统计，参数读取自 app.toml 的 [login_guard] 配置
+/**
+ * @param scope 校验场景
+ * @param subject 账号标识
+ * @param ip 客户端IP
+ * @example：
+ * 1. guard := model.NewLoginGuard(model.LoginGuardLogin, cast.ToString(user.Id), ctx.ClientIP())
+ */
+func NewLoginGuard(scope, subject, ip string) *LoginGuard {
+	second := func(key string, def int) time.Duration {
+		return time.Duration(cast.ToInt(facade.AppToml.Get("login_guard."+key, def))) * time.Second
+	}
+	return &LoginGuard{
+		Scope:        scope,
+		Subject:      subject,
+		IP:           ip,
+		Enable:       cast.ToBool(facade.AppToml.Get("login_guard.enable", true)),
+		AccountLimit: cast.ToInt(facade.AppToml.Get("login_guard.account_limit", 5)),
+		IPLimit:      cast.ToInt(facade.AppToml.Get("login_guard.ip_limit", 20)),
+		Window:       second("window", 900),
+		Lock:         second("lock", 900),
+		Delay:        second("delay", 1),
+		MaxDelay:     second("max_delay", 30),
+		CaptchaAfter: cast.ToInt(facade.AppToml.Get("login_guard.captcha_after", 3)),
+	}
+}
+
+// loginGuardKey - 失败次数缓存键
+func loginGuardKey(scope, kind, value string) string {
+	return fmt.Sprintf("login-guard[%v][%v][%v]", scope, kind, value)
+}
+
+// loginGuardGet - 读取失败记录（count 失败次数，last 最后失败时间，reset 窗口结束时间，lock 锁定结束时间）
+func loginGuardGet(key string) map[string]any {
+	item := cast.ToStringMap(facade.Cache.Get(key))
+	if cast.ToInt64(item["reset"]) <= time.Now().Unix() {
+		item["count"] = 0
+	}
+	return item
+}
+
+// keys - 当前需要统计的缓存键
+func (this *LoginGuard) keys() (account, ip string) {
+	if !utils.Is.Empty(this.Subject) {
+		account = loginGuardKey(this.Scope, "account", this.Subject)
+	}
+	if !utils.Is.Empty(this.IP) {
+		ip = loginGuardKey(this.Scope, "ip", this.IP)
+	}
+	return account, ip
+}
+
+// Check - 尝试前检查：账号或IP是否被锁定、是否仍在渐进延迟中
+func (this *LoginGuard) Check() error {
+
+	if !this.Enable {
+		return nil
+	}
+
+	now := time.Now().Unix()
+	accountKey, ipKey := this.keys()
+
+	if !utils.Is.Empty(ipKey) {
+		if lock := cast.ToInt64(loginGuardGet(ipKey)["lock"]); lock > now {
+			return &LoginGuardError{Message: "当前IP尝试次数过多，请%d分钟后再试！", Args: []any{loginGuardMinutes(lock - now)}, Wait: lock - now}
+		}
+	}
+
+	if utils.Is.Empty(accountKey) {
+		return nil
+	}
+
+	item := loginGuardGet(accountKey)
+	if lock := cast.ToInt64(item["lock"]); lock > now {
+		return &LoginGuardError{Message: "该账号因多次验证失败已被临时锁定，请%d分钟后再试！", Args: []any{loginGuardMinutes(lock - now)}, Wait: lock - now}
+	}
+
+	if wait := cast.ToInt64(item["last"]) + int64(this.delay(cast.ToInt(item["count"])).Seconds()) - now; wait > 0 {
+		return &LoginGuardError{Message: "尝试过于频繁，请%d秒后再试！", Args: []any{wait}, Wait: wait}
+	}
+
+	return nil
+}
+
+// delay - 第 count 次失败后的渐进延迟
+func (this *LoginGuard) delay(count int) time.Duration {
+	if count <= 0 || this.Delay <= 0 {
+		return 0
+	}
+	delay := time.Duration(float64(this.Delay) * math.Pow(2, float64(count-1)))
+	if this.MaxDelay > 0 && (delay > this.MaxDelay || delay <= 0) {
+		return this.MaxDelay
+	}
+	return delay
+}
+
+// Captcha - 是否需要人机验证（已注册验证码服务，且账号或IP的失败次数达到阈值）
+func (this *LoginGuard) Captcha() bool {
+
+	if !this.Enable || this.CaptchaAfter <= 0 || LoginGuardCaptcha == nil {
+		return false
+	}
+
+	accountKey, ipKey := this.keys()
+	for _, key := range []string{accountKey, ipKey} {
+		if !utils.Is.Empty(key) && cast.ToInt(loginGuardGet(key)["count"]) >= this.CaptchaAfter {
+			return true
+		}
+	}
+
+	return false
+}
+
+// CheckCaptcha - 需要人机验证时校验提交的人机验证凭证
+func (this *LoginGuard) CheckCaptcha(token any) error {
+
+	if this.Passed || !this.Captcha() {
+		return nil
+	}
+
+	if utils.Is.Empty(token) {
+		return &LoginGuardError{Message: "请完成人机验证！", Captcha: true}
+	}
+	if !LoginGuardCaptcha(cast.ToString(token), this.IP) {
+		return &LoginGuardError{Message: "人机验证失败，请重试！", Captcha: true}
+	}
+
+	return nil
+}
+
+// Fail - 记录一次失败，返回账号是否因本次失败被锁定
+func (this *LoginGuard) Fail() (locked bool) {
+
+	if !this.Enable {
+		return false
+	}
+
+	accountKey, ipKey := this.keys()
+
+	if !utils.Is.Empty(accountKey) {
+		if locked = this.incr(accountKey, this.AccountLimit); locked {
+			this.audit("account", this.Subject, "账号因多次验证失败被临时锁定")
+		}
+	}
+	if !utils.Is.Empty(ipKey) && this.incr(ipKey, this.IPLimit) {
+		facade.Log.Warn(map[string]any{"scope": this.Scope, "ip": this.IP}, "IP因多次验证失败被临时锁定")
+		this.audit("ip", this.IP, "IP因多次验证失败被临时锁定")
+	}
+
+	facade.Log.Warn(map[string]any{
+		"scope":   this.Scope,
+		"subject": this.Subject,
+		"ip":      this.IP,
+		"locked":  locked,
+	}, utils.Ternary(locked, "账号因多次验证失败被临时锁定", "验证失败"))
+
+	return locked
+}
+
+// incr - 失败次数加一（固定窗口，续写时不延长窗口），达到上限时锁定并重新计数
+// 读取、计数与锁定在 Cache.Update 中完成，并发的失败请求逐一计数，不会互相覆盖
+func (this *LoginGuard) incr(key string, limit int) (locked bool) {
+
+	ok := facade.Cache.Update(key, func(value any) (any, time.Duration) {
+
+		now := time.Now().Unix()
+		item := cast.ToStringMap(value)
+
+		reset := cast.ToInt64(item["reset"])
+		count := cast.ToInt(item["count"]) + 1
+		if reset <= now {
+			reset, count = time.Now().Add(this.Window).Unix(), 1
+		}
+
+		lock := cast.ToInt64(item["lock"])
+		// 回调在 Redis 事务冲突时会重试，locked 以最后一次执行为准
+		locked = false
+		if limit > 0 && count >= limit {
+			lock, count, locked = time.Now().Add(this.Lock).Unix(), 0, true
+		}
+
+		expire := reset
+		if lock > expire {
+			expire = lock
+		}
+
+		return map[string]any{
+			"count": count,
+			"last":  now,
+			"reset": reset,
+			"lock":  lock,
+		}, time.Duration(expire-now) * time.Second
+	})
+	if !ok {
+		facade.Log.Error(map[string]any{"key": key}, "登录失败次数写入失败")
+		return false
+	}
+
+	return locked
+}
+
+// audit - 账号或IP被锁定时写入审计日志（由失败请求触发，没有操作者）
+func (this *LoginGuard) audit(kind, value, name string) {
+
+	if !AuditEnabled() {
+		return
+	}
+
+	item := &AuditLog{
+		Name:      name,
+		Target:    "login-guard",
+		TargetIds: value,
+		Ip:        this.IP,
+	}
+	go CreateAuditLog(item, nil, nil, map[string]any{
+		"scope":   this.Scope,
+		"kind":    kind,
+		"subject": value,
+		"lock":    time.Now().Add(this.Lock).Unix(),
+	})
+}
+
+// Success - 验证成功，清除账号的失败次数（IP维度继续统计，避免轮换账号试探）
+func (this *LoginGuard) Success() {
+
+	if !this.Enable {
+		return
+	}
+
+	if accountKey, _ := this.keys(); !utils.Is.Empty(accountKey) {
+		facade.Cache.Del(accountKey)
+	}
+
+	facade.Log.Info(map[string]any{"scope": this.Scope, "subject": this.Subject, "ip": this.IP}, "验证成功")
+}
+
+// LoginGuardUnlock - 解除账号标识与IP在全部场景下的锁定与失败次数，返回解除前的记录（写入审计日志）
+/**
+ * @param subjects 账号标识（用户ID、邮箱、手机号等）
+ * @param ip 客户端IP，为空时不处理
+ * @return 以 场景.类型.标识 为键的失败记录（count 失败次数，lock 锁定结束时间）
+ */
+func LoginGuardUnlock(subjects []string, ip string) map[string]any {
+
+	result := map[string]any{}
+	take := func(scope, kind, value string) {
+		key := loginGuardKey(scope, kind, value)
+		if item := cast.ToStringMap(facade.Cache.Take(key)); !utils.Is.Empty(item) {
+			result[fmt.Sprintf("%s.%s.%s", scope, kind, value)] = map[string]any{
+				"count": item["count"],
+				"lock":  item["lock"],
+			}
+		}
+	}
+
+	for _, scope := range LoginGuardScopes {
+		for _, subject := range subjects {
+			if !utils.Is.Empty(subject) {
+				take(scope, "account", subject)
+			}
+		}
+		if !utils.Is.Empty(ip) {
+			take(scope, "ip", ip)
+		}
+	}
+
+	return result
+}
+
+// LoginGuardRisk - IP在任一场景下的失败次数是否达到人机验证阈值
+func LoginGuardRisk(ip string) bool {
+
+	guard := NewLoginGuard("", "", ip)
+	if !guard.Enable || guard.CaptchaAfter <= 0 || utils.Is.Empty(ip) {
+		return false
+	}
+
+	for _, scope := range LoginGuardScopes {
+		if cast.ToInt(loginGuardGet(loginGuardKey(scope, "ip", ip))["count"]) >= guard.CaptchaAfter {
+			return true
+		}
+	}
+
+	return false
+}
+
+// loginGuardMinutes - 秒数向上取整为分钟
+func loginGuardMinutes(seconds int64) int64 {
+	return (seconds + 59) / 60
+}
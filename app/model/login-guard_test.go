@@ -0,0 +1,51 @@
+package model
+
+import (
+	"inis/app/facade"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// loginGuardTestCache - 使用独立的内存缓存
+func loginGuardTestCache(t *testing.T) {
+	origin := facade.Cache
+	facade.Cache = &facade.BigCacheStruct{Client: facade.NewBigCache(600, "login-guard-test")}
+	t.Cleanup(func() { facade.Cache = origin })
+}
+
+// TestLoginGuardConcurrentFail - 并发的失败请求逐一计数，达到上限时锁定
+func TestLoginGuardConcurrentFail(t *testing.T) {
+
+	loginGuardTestCache(t)
+
+	const limit = 20
+	guard := &LoginGuard{
+		Scope: LoginGuardLogin, Subject: "1", Enable: true,
+		AccountLimit: limit, Window: time.Minute, Lock: time.Minute,
+	}
+	key, _ := guard.keys()
+
+	var (
+		wait   sync.WaitGroup
+		locked atomic.Int32
+	)
+	for i := 0; i < limit; i++ {
+		wait.Add(1)
+		go func() {
+			defer wait.Done()
+			if guard.incr(key, limit) {
+				locked.Add(1)
+			}
+		}()
+	}
+	wait.Wait()
+
+	if locked.Load() != 1 {
+		t.Fatalf("%d 次并发失败应恰好锁定一次，实际 %d 次", limit, locked.Load())
+	}
+	if err := guard.Check(); err == nil {
+		t.Fatal("达到失败上限后账号应被锁定")
+	}
+}
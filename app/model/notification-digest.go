@@ -0,0 +1,180 @@
+package model
+
+import (
+	"errors"
+	"inis/app/facade"
+	"time"
+
+	"github.com/spf13/cast"
+	"github.com/unti-io/go-utils/utils"
+	"gorm.io/gorm/clause"
+)
+
+// 通知摘要周期
+const (
+	NotifyDigestOff    = "off"
+	NotifyDigestDaily  = "daily"
+	NotifyDigestWeekly = "weekly"
+)
+
+// NotifyDigestType - 摘要邮件的退订类型
+const NotifyDigestType = "digest"
+
+// notifyDigestItems - 摘要邮件中最多列出的通知数量
+const notifyDigestItems = 20
+
+// NotificationDigest - 用户的未读通知摘要设置
+type NotificationDigest struct {
+	Id        int    `gorm:"type:int(32); comment:主键;" json:"id"`
+	Uid       int    `gorm:"type:int(32); uniqueIndex; comment:用户ID;" json:"uid"`
+	Frequency string `gorm:"size:16; index; default:'off'; comment:摘要周期 off/daily/weekly;" json:"frequency"`
+	Timezone  string `gorm:"size:64; comment:时区，如 Asia/Shanghai，为空时使用服务器时区;" json:"timezone"`
+	// 上次发送时间，用于避免重复发送并确定摘要的起始时间
+	LastTime   int64 `gorm:"default:0; comment:上次发送时间;" json:"last_time"`
+	CreateTime int64 `gorm:"autoCreateTime; comment:创建时间;" json:"create_time"`
+	UpdateTime int64 `gorm:"autoUpdateTime; comment:更新时间;" json:"update_time"`
+}
+
+// InitNotificationDigest - 初始化NotificationDigest表
+func InitNotificationDigest() {
+	err := facade.DB.Drive().AutoMigrate(&NotificationDigest{})
+	if err != nil {
+		facade.Log.Error(map[string]any{"error": err}, "NotificationDigest表迁移失败")
+		return
+	}
+}
+
+// GetNotifyDigest - 获取用户的摘要设置（未设置时为关闭）
+func GetNotifyDigest(uid int) NotificationDigest {
+	item := NotificationDigest{Uid: uid, Frequency: NotifyDigestOff}
+	facade.DB.Drive().Where("uid = ?", uid).Limit(1).Find(&item)
+	return item
+}
+
+// SetNotifyDigest - 设置用户的摘要周期与时区
+func SetNotifyDigest(uid int, frequency, timezone string) error {
+
+	if !utils.In.Array(frequency, []any{NotifyDigestOff, NotifyDigestDaily, NotifyDigestWeekly}) {
+		return errors.New("不支持的摘要周期")
+	}
+
+	if _, err := time.LoadLocation(timezone); err != nil {
+		return errors.New("时区格式不正确")
+	}
+
+	return facade.DB.Drive().Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "uid"}},
+		DoUpdates: clause.Assignments(map[string]any{"frequency": frequency, "timezone": timezone, "update_time": time.Now().Unix()}),
+	}).Create(&NotificationDigest{
+		Uid:       uid,
+		Frequency: frequency,
+		Timezone:  timezone,
+	}).Error
+}
+
+// Due - 当前时间是否应发送摘要（用户本地时间到达发送时刻，周摘要仅周一发送）
+func (this *NotificationDigest) Due(now time.Time) bool {
+
+	location, err := time.LoadLocation(this.Timezone)
+	if err != nil {
+		location = time.Local
+	}
+	local := now.In(location)
+
+	if local.Hour() != cast.ToInt(facade.AppToml.Get("notification.digest_hour", 8)) {
+		return false
+	}
+
+	// 定时任务每小时执行，间隔判断留出一小时余量，避免执行时刻漂移导致漏发
+	elapsed := now.Sub(time.Unix(this.LastTime, 0))
+
+	switch this.Frequency {
+	case NotifyDigestDaily:
+		return elapsed >= 23*time.Hour
+	case NotifyDigestWeekly:
+		return local.Weekday() == time.Monday && elapsed >= (6*24+23)*time.Hour
+	}
+
+	return false
+}
+
+// Send - 汇总上次发送以来的未读通知并发送摘要邮件，无未读通知时仅更新发送时间
+func (this *NotificationDigest) Send(now time.Time) error {
+
+	since := this.LastTime
+	if since == 0 {
+		since = now.AddDate(0, 0, utils.Ternary(this.Frequency == NotifyDigestWeekly, -7, -1)).Unix()
+	}
+
+	// 先更新发送时间，防止发送耗时较长时被下一轮重复领取
+	result := facade.DB.Drive().Model(&NotificationDigest{}).
+		Where("id = ? AND last_time = ?", this.Id, this.LastTime).
+		Update("last_time", now.Unix())
+	if result.Error != nil || result.RowsAffected != 1 {
+		return result.Error
+	}
+
+	user, _ := facade.DB.Model(&Users{}).Where("id", this.Uid).Find()
+	email := cast.ToString(user["email"])
+	if !utils.Is.Email(email) {
+		return nil
+	}
+
+	// 聚合通知有新事件时只更新原记录，按更新时间筛选才不会漏掉
+	condition := "uid = ? AND is_read = 0 AND update_time > ?"
+
+	var count int64
+	facade.DB.Drive().Model(&Notification{}).Where(condition, this.Uid, since).Count(&count)
+	if count == 0 {
+		return nil
+	}
+
+	var list []struct {
+		Title      string
+		Content    string
+		UpdateTime int64
+	}
+	facade.DB.Drive().Model(&Notification{}).Where(condition, this.Uid, since).
+		Select("title", "content", "update_time").Order("update_time desc").Limit(notifyDigestItems).Scan(&list)
+
+	location, err := time.LoadLocation(this.Timezone)
+	if err != nil {
+		location = time.Local
+	}
+
+	items := make([]map[string]any, 0, len(list))
+	for _, item := range list {
+		items = append(items, map[string]any{
+			"title":   item.Title,
+			"content": item.Content,
+			"time":    time.Unix(item.UpdateTime, 0).In(location).Format("2006-01-02 15:04"),
+		})
+	}
+
+	response := NewOutbox(facade.SMSModeEmail).SendMailTemplate(email, facade.MailTemplateDigest, cast.ToString(user["lang"]), map[string]any{
+		"period":      this.Frequency,
+		"count":       count,
+		"items":       items,
+		"more":        count - int64(len(items)),
+		"uid":         this.Uid,
+		"notify_type": NotifyDigestType,
+	}, "通知摘要")
+
+	return response.Error
+}
+
+// SendNotificationDigests - 发送到期的通知摘要
+func SendNotificationDigests(now time.Time) {
+
+	var list []NotificationDigest
+	facade.DB.Drive().Where("frequency IN ?", []string{NotifyDigestDaily, NotifyDigestWeekly}).Find(&list)
+
+	for _, item := range list {
+		if !item.Due(now) {
+			continue
+		}
+		if err := item.Send(now); err != nil {
+			facade.Log.Error(map[string]any{"error": err, "uid": item.Uid}, "通知摘要发送失败")
+		}
+	}
+}
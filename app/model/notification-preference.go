@@ -0,0 +1,133 @@
+package model
+
+import (
+	"inis/app/facade"
+	"time"
+
+	"gorm.io/gorm/clause"
+)
+
+// 通知类型
+const (
+	NotifyTypeComment = "comment"
+	NotifyTypeReply   = "reply"
+	NotifyTypeLike    = "like"
+	NotifyTypeCollect = "collect"
+	NotifyTypeFollow  = "follow"
+	NotifyTypeSystem  = "system"
+)
+
+// 通知渠道
+const (
+	NotifyChannelSite  = "site"
+	NotifyChannelEmail = "email"
+	NotifyChannelSMS   = "sms"
+	NotifyChannelPush  = "push"
+)
+
+// NotifyTypes - 可设置偏好的通知类型
+var NotifyTypes = map[string]string{
+	NotifyTypeComment: "评论",
+	NotifyTypeReply:   "回复",
+	NotifyTypeLike:    "点赞",
+	NotifyTypeCollect: "收藏",
+	NotifyTypeFollow:  "关注",
+	NotifyTypeSystem:  "系统消息",
+}
+
+// NotifyChannels - 可设置偏好的通知渠道
+var NotifyChannels = map[string]string{
+	NotifyChannelSite:  "站内通知",
+	NotifyChannelEmail: "邮件",
+	NotifyChannelSMS:   "短信",
+	NotifyChannelPush:  "实时推送",
+}
+
+// NotificationPreference - 用户通知偏好（无记录时视为开启）
+type NotificationPreference struct {
+	Id         int    `gorm:"type:int(32); comment:主键;" json:"id"`
+	Uid        int    `gorm:"type:int(32); uniqueIndex:uk_notification_preference,priority:1; comment:用户ID;" json:"uid"`
+	Type       string `gorm:"size:32; uniqueIndex:uk_notification_preference,priority:2; comment:通知类型;" json:"type"`
+	Channel    string `gorm:"size:16; uniqueIndex:uk_notification_preference,priority:3; comment:通知渠道;" json:"channel"`
+	Enabled    int    `gorm:"type:int(2); default:1; comment:是否接收 0否 1是;" json:"enabled"`
+	CreateTime int64  `gorm:"autoCreateTime; comment:创建时间;" json:"create_time"`
+	UpdateTime int64  `gorm:"autoUpdateTime; comment:更新时间;" json:"update_time"`
+}
+
+// InitNotificationPreference - 初始化NotificationPreference表
+func InitNotificationPreference() {
+	err := facade.DB.Drive().AutoMigrate(&NotificationPreference{})
+	if err != nil {
+		facade.Log.Error(map[string]any{"error": err}, "NotificationPreference表迁移失败")
+		return
+	}
+}
+
+// NotifyRequired - 不可关闭的通知（系统消息的站内通知，如账号登录提醒）
+func NotifyRequired(typ, channel string) bool {
+	return typ == NotifyTypeSystem && channel == NotifyChannelSite
+}
+
+// NotifyAllowed - 用户是否接收指定类型、渠道的通知
+func NotifyAllowed(uid int, typ, channel string) bool {
+
+	if uid <= 0 || NotifyRequired(typ, channel) {
+		return true
+	}
+
+	// 数据库未初始化（未安装）时不拦截
+	if facade.DB == nil {
+		return true
+	}
+
+	var item NotificationPreference
+	err := facade.DB.Drive().Where("uid = ? AND type = ? AND channel = ?", uid, typ, channel).Limit(1).Find(&item).Error
+	if err != nil || item.Id == 0 {
+		return true
+	}
+
+	return item.Enabled == 1
+}
+
+// NotifyPreferences - 获取用户的完整偏好矩阵 [类型][渠道]是否接收
+func NotifyPreferences(uid int) map[string]map[string]bool {
+
+	result := make(map[string]map[string]bool)
+	for typ := range NotifyTypes {
+		result[typ] = make(map[string]bool)
+		for channel := range NotifyChannels {
+			result[typ][channel] = true
+		}
+	}
+
+	var list []NotificationPreference
+	facade.DB.Drive().Where("uid = ?", uid).Find(&list)
+
+	for _, item := range list {
+		if _, ok := result[item.Type][item.Channel]; !ok || NotifyRequired(item.Type, item.Channel) {
+			continue
+		}
+		result[item.Type][item.Channel] = item.Enabled == 1
+	}
+
+	return result
+}
+
+// SetNotifyPreference - 设置用户的通知偏好
+func SetNotifyPreference(uid int, typ, channel string, enabled bool) error {
+
+	value := 0
+	if enabled {
+		value = 1
+	}
+
+	return facade.DB.Drive().Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "uid"}, {Name: "type"}, {Name: "channel"}},
+		DoUpdates: clause.Assignments(map[string]any{"enabled": value, "update_time": time.Now().Unix()}),
+	}).Create(&NotificationPreference{
+		Uid:     uid,
+		Type:    typ,
+		Channel: channel,
+		Enabled: value,
+	}).Error
+}
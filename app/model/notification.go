@@ -69,6 +69,35 @@ func InitNotificationRead() {
 	facade.DB.Drive().Exec("CREATE INDEX IF NOT EXISTS idx_notification_reads_uid ON inis_notification_read(uid)")
 }
 
+// 通知实时推送事件
+const (
+	NotifyEventCreated = "created"
+	NotifyEventUpdated = "updated"
+)
+
+// NotificationPublisher - 通知实时推送（由 socket 服务注入，未启用时为 nil）
+// notif 为 nil 时仅推送未读数变化；uid 为 0 表示广播通知，推送给全部在线用户
+var NotificationPublisher func(uid int, event string, notif *Notification)
+
+// publishNotification - 推送新建或更新的通知（在线连接与浏览器 Web Push）
+func publishNotification(event string, notif *Notification) {
+	if notif == nil {
+		return
+	}
+	webPushNotification(notif)
+	if NotificationPublisher != nil {
+		NotificationPublisher(notif.Uid, event, notif)
+	}
+}
+
+// PublishNotifyUnread - 推送用户未读数变化（已读、删除等操作后调用）
+func PublishNotifyUnread(uid int) {
+	if NotificationPublisher == nil || uid <= 0 {
+		return
+	}
+	NotificationPublisher(uid, "", nil)
+}
+
 func (this *Notification) AfterFind(tx *gorm.DB) (err error) {
 	this.Result = this.result()
 	this.Text = cast.ToString(this.Text)
@@ -99,8 +128,13 @@ func (this *Notification) fromUserSync(wg *sync.WaitGroup, result *any) {
 	}
 }
 
-// CreateNotification 创建通知并推送WebSocket
+// CreateNotification 创建通知并推送WebSocket（用户关闭了该类站内通知时跳过，返回 nil）
 func (this *Notification) CreateNotification(uid, fromUid int, typ, title, content, bindType string, bindId int) (*Notification, error) {
+
+	if !NotifyAllowed(uid, typ, NotifyChannelSite) {
+		return nil, nil
+	}
+
 	notif := &Notification{
 		Uid:      uid,
 		FromUid:  fromUid,
@@ -119,9 +153,143 @@ func (this *Notification) CreateNotification(uid, fromUid int, typ, title, conte
 		return nil, err
 	}
 
+	publishNotification(NotifyEventCreated, notif)
+
 	return notif, nil
 }
 
+// notificationAggregateLimit - 聚合通知中记录的触发用户数量上限（用于去重）
+const notificationAggregateLimit = 100
+
+// CreateAggregatedNotification 创建可聚合的通知（点赞、收藏、关注等）
+// 聚合窗口内同一接收者、类型与关联实体的未读通知合并为一条，如「A、B 等 14 人赞了你的文章」
+// action 为不含触发用户昵称的动作描述，如「赞了你的文章「标题」」
+func (this *Notification) CreateAggregatedNotification(uid, fromUid int, typ, title, action, bindType string, bindId int) (*Notification, error) {
+
+	if !NotifyAllowed(uid, typ, NotifyChannelSite) {
+		return nil, nil
+	}
+
+	window := cast.ToInt64(facade.AppToml.Get("notification.aggregate_window", 86400))
+	if window <= 0 {
+		return this.CreateNotification(uid, fromUid, typ, title, notificationActors([]int{fromUid}, 1)+action, bindType, bindId)
+	}
+
+	var result Notification
+	event := NotifyEventCreated
+	err := facade.DB.Drive().Transaction(func(tx *gorm.DB) error {
+
+		var item Notification
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("uid = ? AND type = ? AND bind_type = ? AND bind_id = ? AND is_read = 0 AND create_time >= ?",
+				uid, typ, bindType, bindId, time.Now().Unix()-window).
+			Order("id desc").Limit(1).Find(&item).Error
+		if err != nil {
+			return err
+		}
+
+		if item.Id == 0 {
+			result = Notification{
+				Uid:      uid,
+				FromUid:  fromUid,
+				Type:     typ,
+				Title:    title,
+				Content:  notificationActors([]int{fromUid}, 1) + action,
+				BindId:   bindId,
+				BindType: bindType,
+				Json:     utils.Json.Encode(map[string]any{"actors": []int{fromUid}, "count": 1}),
+			}
+			return tx.Create(&result).Error
+		}
+
+		meta := cast.ToStringMap(item.Json)
+		actors := cast.ToIntSlice(meta["actors"])
+		count := cast.ToInt(meta["count"])
+		if count == 0 {
+			actors, count = []int{item.FromUid}, 1
+		}
+
+		// 同一用户重复触发时只移到最前，不重复计数
+		exist := false
+		for i, id := range actors {
+			if id == fromUid {
+				actors = append(actors[:i], actors[i+1:]...)
+				exist = true
+				break
+			}
+		}
+		if !exist {
+			count++
+		}
+		actors = append([]int{fromUid}, actors...)
+		if len(actors) > notificationAggregateLimit {
+			actors = actors[:notificationAggregateLimit]
+		}
+
+		meta["actors"] = actors
+		meta["count"] = count
+
+		event = NotifyEventUpdated
+		result = item
+		result.FromUid = fromUid
+		result.Title = title
+		result.Content = notificationActors(actors, count) + action
+		result.Json = utils.Json.Encode(meta)
+
+		return tx.Model(&Notification{}).Where("id = ?", item.Id).Updates(map[string]any{
+			"from_uid":    result.FromUid,
+			"title":       result.Title,
+			"content":     result.Content,
+			"json":        result.Json,
+			"update_time": time.Now().Unix(),
+		}).Error
+	})
+
+	if err != nil {
+		facade.Log.Error(map[string]any{"error": err, "uid": uid, "type": typ}, "创建聚合通知失败")
+		return nil, err
+	}
+
+	publishNotification(event, &result)
+
+	return &result, nil
+}
+
+// notificationActors - 生成触发用户描述，如「A」「A、B」「A、B 等 14 人」
+func notificationActors(actors []int, count int) string {
+
+	if len(actors) > 2 {
+		actors = actors[:2]
+	}
+
+	var users []struct {
+		Id       int
+		Nickname string
+	}
+	facade.DB.Drive().Model(&Users{}).Select("id", "nickname").Where("id IN ?", actors).Scan(&users)
+
+	nicknames := make(map[int]string)
+	for _, user := range users {
+		nicknames[user.Id] = user.Nickname
+	}
+
+	names := make([]string, 0, len(actors))
+	for _, id := range actors {
+		if name := nicknames[id]; !utils.Is.Empty(name) {
+			names = append(names, name)
+		}
+	}
+	if len(names) == 0 {
+		names = append(names, "有人")
+	}
+
+	if count > len(names) {
+		return fmt.Sprintf("%s 等 %d 人", strings.Join(names, "、"), count)
+	}
+
+	return strings.Join(names, "、") + " "
+}
+
 // CreateBroadcastNotification 创建广播通知（uid=0 表示推送给全体用户，仅创建一条记录）
 func (this *Notification) CreateBroadcastNotification(fromUid int, typ, title, content, bindType string, bindId int) (*Notification, error) {
 	notif := &Notification{
@@ -142,6 +310,8 @@ func (this *Notification) CreateBroadcastNotification(fromUid int, typ, title, c
 		return nil, err
 	}
 
+	publishNotification(NotifyEventCreated, notif)
+
 	return notif, nil
 }
 
@@ -188,6 +358,56 @@ func (this *Notification) CreateLoginNotification(uid int, account, ip, ua strin
 		return nil, err
 	}
 
+	publishNotification(NotifyEventCreated, notif)
+
+	return notif, nil
+}
+
+// CreateLockNotification 创建“账号锁定通知”（系统消息）
+// 在账号因多次密码或验证码错误被临时锁定时调用，提醒用户账号可能正在被他人尝试登录。
+func (this *Notification) CreateLockNotification(uid int, account, ip, ua string, lock time.Duration) (*Notification, error) {
+	now := time.Now()
+	lockTime := now.Format("2006-01-02 15:04:05")
+	unlockTime := now.Add(lock).Format("2006-01-02 15:04:05")
+
+	ua = strings.ReplaceAll(ua, "\n", " ")
+	ua = strings.ReplaceAll(ua, "\r", " ")
+	device := parseDevice(ua)
+
+	content := "你的账号因多次验证失败已被临时锁定，锁定期间无法登录或找回密码。\n\n" +
+		"登录账号：" + account + "\n" +
+		"锁定时间：" + lockTime + "\n" +
+		"解锁时间：" + unlockTime + "\n" +
+		"尝试IP：" + ip + "\n" +
+		"尝试设备：" + device + "\n\n" +
+		"如非本人操作，请在解锁后尽快修改密码并开启两步验证。"
+
+	notif := &Notification{
+		Uid:      uid,
+		FromUid:  uid,
+		Type:     "system",
+		Title:    "账号锁定通知",
+		Content:  content,
+		BindType: "user",
+		BindId:   uid,
+		IsRead:   0,
+		Json: utils.Json.Encode(map[string]any{
+			"account":     account,
+			"lock_time":   lockTime,
+			"unlock_time": unlockTime,
+			"ip":          ip,
+			"device":      device,
+		}),
+	}
+
+	_, err := facade.DB.Model(&Notification{}).Create(notif)
+	if err != nil {
+		facade.Log.Error(map[string]any{"error": err, "uid": uid}, "创建账号锁定通知失败")
+		return nil, err
+	}
+
+	publishNotification(NotifyEventCreated, notif)
+
 	return notif, nil
 }
 
@@ -325,7 +545,11 @@ func (this *Notification) MarkRead(uid, id int) error {
 
 	// 广播通知：记录该用户的已读状态
 	if cast.ToInt(info["uid"]) == 0 {
-		return this.MarkBroadcastRead(id, uid)
+		if err := this.MarkBroadcastRead(id, uid); err != nil {
+			return err
+		}
+		PublishNotifyUnread(uid)
+		return nil
 	}
 
 	// 个人通知：仅能操作自己的通知
@@ -337,7 +561,12 @@ func (this *Notification) MarkRead(uid, id int) error {
 		Where("id", id).
 		Where("uid", uid).
 		Update(map[string]any{"is_read": 1})
-	return err
+	if err != nil {
+		return err
+	}
+
+	PublishNotifyUnread(uid)
+	return nil
 }
 
 // MarkAllRead 标记用户所有通知为已读（包含广播通知）
@@ -362,14 +591,18 @@ func (this *Notification) MarkAllRead(uid int) error {
 		})
 	}
 
-	if utils.Is.Empty(records) {
-		return nil
+	if !utils.Is.Empty(records) {
+		err := facade.DB.Drive().Clauses(clause.OnConflict{
+			Columns:   []clause.Column{{Name: "notification_id"}, {Name: "uid"}},
+			DoUpdates: clause.Assignments(map[string]any{"is_read": 1, "update_time": time.Now().Unix()}),
+		}).Create(&records).Error
+		if err != nil {
+			return err
+		}
 	}
 
-	return facade.DB.Drive().Clauses(clause.OnConflict{
-		Columns:   []clause.Column{{Name: "notification_id"}, {Name: "uid"}},
-		DoUpdates: clause.Assignments(map[string]any{"is_read": 1, "update_time": time.Now().Unix()}),
-	}).Create(&records).Error
+	PublishNotifyUnread(uid)
+	return nil
 }
 
 // GetNotifications 获取用户通知列表（包含广播通知）
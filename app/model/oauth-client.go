@@ -0,0 +1,193 @@
+package model
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"inis/app/facade"
+	"net/url"
+	"strings"
+
+	"github.com/spf13/cast"
+	"github.com/unti-io/go-utils/utils"
+	"gorm.io/plugin/soft_delete"
+)
+
+// 授权服务支持的权限范围
+const (
+	OAuthScopeOpenid  = "openid"
+	OAuthScopeProfile = "profile"
+	OAuthScopeEmail   = "email"
+	OAuthScopePhone   = "phone"
+	OAuthScopeOffline = "offline_access"
+)
+
+// OAuthScopes - 可申请的权限范围及说明（用于授权确认页展示）
+var OAuthScopes = map[string]string{
+	OAuthScopeOpenid:  "获取你的用户标识",
+	OAuthScopeProfile: "获取你的昵称、头像、性别与简介",
+	OAuthScopeEmail:   "获取你的邮箱地址",
+	OAuthScopePhone:   "获取你的手机号",
+	OAuthScopeOffline: "在你离开后继续访问以上信息",
+}
+
+var (
+	// ErrOAuthRedirectURI - 回调地址格式不正确
+	ErrOAuthRedirectURI = errors.New("回调地址必须为不含 # 的绝对地址，且 http 仅允许本机地址！")
+	// ErrOAuthScope - 申请了未开放的权限范围
+	ErrOAuthScope = errors.New("申请的权限范围不被允许！")
+)
+
+// OAuthClient - 接入 inis 账号登录的第三方应用（由管理员登记）
+type OAuthClient struct {
+	Id       int    `gorm:"type:int(32); comment:主键;" json:"id"`
+	ClientId string `gorm:"size:64; uniqueIndex; comment:客户端ID;" json:"client_id"`
+	// 客户端密钥的 SHA-256（不保存明文，仅创建和重置时返回一次）
+	SecretHash  string `gorm:"size:64; comment:客户端密钥哈希;" json:"-"`
+	Name        string `gorm:"size:64; comment:应用名称;" json:"name"`
+	Logo        string `gorm:"size:512; comment:应用图标; default:Null;" json:"logo"`
+	Homepage    string `gorm:"size:512; comment:应用主页; default:Null;" json:"homepage"`
+	Description string `gorm:"comment:应用描述; default:Null;" json:"description"`
+	// 回调地址（逗号分隔，授权时需完全匹配）
+	RedirectUris string `gorm:"type:text; comment:回调地址;" json:"redirect_uris"`
+	// 允许申请的权限范围（逗号分隔）
+	Scopes string `gorm:"size:255; default:'openid,profile,email'; comment:允许的权限范围;" json:"scopes"`
+	// 公开客户端（单页应用、移动端）无法保管密钥，必须使用 PKCE
+	Public int `gorm:"type:int(2); default:0; comment:公开客户端 0否 1是;" json:"public"`
+	// 受信任的应用（如自有站点）跳过授权确认
+	Trusted int    `gorm:"type:int(2); default:0; comment:受信任应用 0否 1是;" json:"trusted"`
+	Status  int    `gorm:"type:int(2); default:1; comment:状态 0停用 1启用;" json:"status"`
+	Remark  string `gorm:"comment:备注; default:Null;" json:"remark"`
+	// 以下为公共字段
+	Json       any                   `gorm:"type:longtext; comment:用于存储JSON数据;" json:"json"`
+	Text       any                   `gorm:"type:longtext; comment:用于存储文本数据;" json:"text"`
+	Result     any                   `gorm:"type:varchar(256); comment:不存储数据，用于封装返回结果;" json:"result"`
+	CreateTime int64                 `gorm:"autoCreateTime; comment:创建时间;" json:"create_time"`
+	UpdateTime int64                 `gorm:"autoUpdateTime; comment:更新时间;" json:"update_time"`
+	DeleteTime soft_delete.DeletedAt `gorm:"comment:删除时间; default:0;" json:"delete_time"`
+}
+
+// InitOAuthClient - 初始化OAuthClient表
+func InitOAuthClient() {
+	err := facade.DB.Drive().AutoMigrate(&OAuthClient{})
+	if err != nil {
+		facade.Log.Error(map[string]any{"error": err}, "OAuthClient表迁移失败")
+		return
+	}
+}
+
+// oauthHash - 客户端密钥与令牌的哈希
+func oauthHash(value string) string {
+	sum := sha256.Sum256([]byte(value))
+	return hex.EncodeToString(sum[:])
+}
+
+// oauthRandom - 生成 size 字节的随机串（base64url）
+func oauthRandom(size int) (string, error) {
+	buf := make([]byte, size)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(buf), nil
+}
+
+// GenerateClientId - 生成客户端ID
+func (this *OAuthClient) GenerateClientId() string {
+	buf := make([]byte, 12)
+	_, _ = rand.Read(buf)
+	this.ClientId = "inis_" + hex.EncodeToString(buf)
+	return this.ClientId
+}
+
+// GenerateSecret - 生成客户端密钥，返回明文（只保存哈希）
+func (this *OAuthClient) GenerateSecret() (string, error) {
+	secret, err := oauthRandom(32)
+	if err != nil {
+		return "", err
+	}
+	this.SecretHash = oauthHash(secret)
+	return secret, nil
+}
+
+// FindOAuthClient - 查询启用中的客户端（不存在或已停用时返回 nil）
+func FindOAuthClient(clientId string) *OAuthClient {
+	if utils.Is.Empty(clientId) {
+		return nil
+	}
+	item := &OAuthClient{}
+	err := facade.DB.Drive().Where("client_id = ? AND status = 1", clientId).Take(item).Error
+	if err != nil {
+		return nil
+	}
+	return item
+}
+
+// CheckSecret - 校验客户端密钥
+func (this *OAuthClient) CheckSecret(secret string) bool {
+	if utils.Is.Empty(secret) || utils.Is.Empty(this.SecretHash) {
+		return false
+	}
+	return subtle.ConstantTimeCompare([]byte(oauthHash(secret)), []byte(this.SecretHash)) == 1
+}
+
+// RedirectList - 已登记的回调地址
+func (this *OAuthClient) RedirectList() []string {
+	return OAuthSplit(this.RedirectUris)
+}
+
+// AllowRedirect - 回调地址是否已登记（完全匹配）
+func (this *OAuthClient) AllowRedirect(uri string) bool {
+	return !utils.Is.Empty(uri) && utils.InArray(uri, this.RedirectList())
+}
+
+// GrantScopes - 校验申请的权限范围（空格分隔），返回去重后的列表
+func (this *OAuthClient) GrantScopes(scope string) ([]string, error) {
+
+	allow := OAuthSplit(this.Scopes)
+	result := []string{}
+
+	for _, item := range strings.Fields(scope) {
+		if _, ok := OAuthScopes[item]; !ok || !utils.InArray(item, allow) {
+			return nil, ErrOAuthScope
+		}
+		if !utils.InArray(item, result) {
+			result = append(result, item)
+		}
+	}
+
+	return result, nil
+}
+
+// OAuthSplit - 拆分逗号或空白分隔的列表
+func OAuthSplit(value any) []string {
+	return strings.FieldsFunc(cast.ToString(value), func(r rune) bool {
+		return r == ',' || r == ' ' || r == '\n' || r == '\r' || r == '\t'
+	})
+}
+
+// OAuthCheckRedirect - 校验回调地址：绝对地址、不含片段，http 仅允许本机（原生应用可使用自定义协议）
+func OAuthCheckRedirect(uri string) error {
+
+	item, err := url.Parse(uri)
+	if err != nil || utils.Is.Empty(item.Scheme) || !utils.Is.Empty(item.Fragment) || strings.Contains(uri, "#") {
+		return ErrOAuthRedirectURI
+	}
+
+	switch strings.ToLower(item.Scheme) {
+	case "https":
+		if utils.Is.Empty(item.Host) {
+			return ErrOAuthRedirectURI
+		}
+	case "http":
+		if !utils.InArray(item.Hostname(), []string{"localhost", "127.0.0.1", "::1"}) {
+			return ErrOAuthRedirectURI
+		}
+	case "javascript", "data", "file", "vbscript":
+		return ErrOAuthRedirectURI
+	}
+
+	return nil
+}
@@ -0,0 +1,475 @@
+package model
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"inis/app/facade"
+	"strings"
+	"time"
+
+	"github.com/spf13/cast"
+	"github.com/unti-io/go-utils/utils"
+)
+
+const (
+	// cacheOAuthCode - 授权码缓存键（键为授权码的哈希）
+	cacheOAuthCode = "oauth-code[%v]"
+)
+
+var (
+	// ErrOAuthCode - 授权码无效、已过期或已使用
+	ErrOAuthCode = errors.New("授权码无效或已过期！")
+	// ErrOAuthPKCE - code_verifier 与授权时的 code_challenge 不匹配
+	ErrOAuthPKCE = errors.New("code_verifier 校验失败！")
+	// ErrOAuthToken - 令牌无效、已过期或已吊销
+	ErrOAuthToken = errors.New("令牌无效或已过期！")
+	// ErrOAuthUser - 账号不存在、已冻结或被禁止登录
+	ErrOAuthUser = errors.New("账号不可用！")
+)
+
+// OAuthCodeExpire - 授权码有效期（秒）
+func OAuthCodeExpire() int64 {
+	return max(cast.ToInt64(facade.AppToml.Get("oauth_server.code_expire", 60)), 10)
+}
+
+// OAuthAccessExpire - 访问令牌与身份令牌有效期（秒）
+func OAuthAccessExpire() int64 {
+	return max(cast.ToInt64(facade.AppToml.Get("oauth_server.access_expire", 3600)), 60)
+}
+
+// OAuthRefreshExpire - 刷新令牌有效期（秒）
+func OAuthRefreshExpire() int64 {
+	return max(cast.ToInt64(facade.AppToml.Get("oauth_server.refresh_expire", 30*24*3600)), OAuthAccessExpire())
+}
+
+// OAuthGrant - 用户同意授权后的授权信息（以授权码为键写入缓存，换取令牌时一次性取出）
+type OAuthGrant struct {
+	ClientId    string
+	RedirectURI string
+	Uid         int
+	Scopes      []string
+	// PKCE 的 S256 挑战，为空表示未使用 PKCE（仅机密客户端允许）
+	Challenge string
+	Nonce     string
+	// 用户完成登录的时间
+	AuthTime int64
+}
+
+// CreateOAuthCode - 生成授权码
+func CreateOAuthCode(grant *OAuthGrant) (string, error) {
+
+	code, err := oauthRandom(32)
+	if err != nil {
+		return "", err
+	}
+
+	facade.Cache.Set(fmt.Sprintf(cacheOAuthCode, oauthHash(code)), map[string]any{
+		"client_id":    grant.ClientId,
+		"redirect_uri": grant.RedirectURI,
+		"uid":          grant.Uid,
+		"scope":        strings.Join(grant.Scopes, " "),
+		"challenge":    grant.Challenge,
+		"nonce":        grant.Nonce,
+		"auth_time":    grant.AuthTime,
+	}, time.Duration(OAuthCodeExpire())*time.Second)
+
+	return code, nil
+}
+
+// ConsumeOAuthCode - 取出并销毁授权码（只能使用一次）
+func ConsumeOAuthCode(code string) (*OAuthGrant, error) {
+
+	if utils.Is.Empty(code) {
+		return nil, ErrOAuthCode
+	}
+
+	// 原子地取出并删除，并发兑换同一授权码时只有一个请求能取到
+	cached := cast.ToStringMap(facade.Cache.Take(fmt.Sprintf(cacheOAuthCode, oauthHash(code))))
+	if utils.Is.Empty(cached) {
+		return nil, ErrOAuthCode
+	}
+
+	return &OAuthGrant{
+		ClientId:    cast.ToString(cached["client_id"]),
+		RedirectURI: cast.ToString(cached["redirect_uri"]),
+		Uid:         cast.ToInt(cached["uid"]),
+		Scopes:      strings.Fields(cast.ToString(cached["scope"])),
+		Challenge:   cast.ToString(cached["challenge"]),
+		Nonce:       cast.ToString(cached["nonce"]),
+		AuthTime:    cast.ToInt64(cached["auth_time"]),
+	}, nil
+}
+
+// CheckVerifier - 校验 PKCE 的 code_verifier（RFC 7636，仅支持 S256）
+func (this *OAuthGrant) CheckVerifier(verifier string) error {
+
+	if utils.Is.Empty(this.Challenge) {
+		if !utils.Is.Empty(verifier) {
+			return ErrOAuthPKCE
+		}
+		return nil
+	}
+
+	if len(verifier) < 43 || len(verifier) > 128 {
+		return ErrOAuthPKCE
+	}
+
+	sum := sha256.Sum256([]byte(verifier))
+	if subtle.ConstantTimeCompare([]byte(base64.RawURLEncoding.EncodeToString(sum[:])), []byte(this.Challenge)) != 1 {
+		return ErrOAuthPKCE
+	}
+
+	return nil
+}
+
+// OAuthToken - 签发给第三方应用的令牌（访问令牌与刷新令牌只保存哈希）
+type OAuthToken struct {
+	Id       int    `gorm:"type:int(32); comment:主键;" json:"id"`
+	ClientId string `gorm:"size:64; index; comment:客户端ID;" json:"client_id"`
+	Uid      int    `gorm:"type:int(32); index; comment:用户ID;" json:"uid"`
+	Scope    string `gorm:"size:255; comment:权限范围（空格分隔）;" json:"scope"`
+	// 访问令牌与刷新令牌的 SHA-256，未申请 offline_access 时没有刷新令牌
+	AccessHash    string `gorm:"size:64; uniqueIndex; comment:访问令牌哈希;" json:"-"`
+	RefreshHash   string `gorm:"size:64; index; comment:刷新令牌哈希;" json:"-"`
+	AccessExpire  int64  `gorm:"default:0; comment:访问令牌过期时间;" json:"access_expire"`
+	RefreshExpire int64  `gorm:"default:0; index; comment:刷新令牌过期时间;" json:"refresh_expire"`
+	AuthTime      int64  `gorm:"default:0; comment:用户登录时间;" json:"auth_time"`
+	// 吊销时间，0 表示有效；刷新令牌轮换后旧记录同样标记为吊销
+	RevokeTime int64 `gorm:"default:0; index; comment:吊销时间;" json:"revoke_time"`
+	CreateTime int64 `gorm:"autoCreateTime; comment:创建时间;" json:"create_time"`
+	UpdateTime int64 `gorm:"autoUpdateTime; comment:更新时间;" json:"update_time"`
+}
+
+// InitOAuthToken - 初始化OAuthToken表
+func InitOAuthToken() {
+	err := facade.DB.Drive().AutoMigrate(&OAuthToken{})
+	if err != nil {
+		facade.Log.Error(map[string]any{"error": err}, "OAuthToken表迁移失败")
+		return
+	}
+}
+
+// Scopes - 权限范围列表
+func (this *OAuthToken) Scopes() []string {
+	return strings.Fields(this.Scope)
+}
+
+// IssueOAuthToken - 签发访问令牌（权限范围包含 offline_access 时同时签发刷新令牌）
+func IssueOAuthToken(clientId string, uid int, scopes []string, authTime int64) (*OAuthToken, string, string, error) {
+
+	access, err := oauthRandom(32)
+	if err != nil {
+		return nil, "", "", err
+	}
+
+	now := time.Now().Unix()
+	item := &OAuthToken{
+		ClientId:     clientId,
+		Uid:          uid,
+		Scope:        strings.Join(scopes, " "),
+		AccessHash:   oauthHash(access),
+		AccessExpire: now + OAuthAccessExpire(),
+		AuthTime:     authTime,
+	}
+
+	refresh := ""
+	if utils.InArray(OAuthScopeOffline, scopes) {
+		if refresh, err = oauthRandom(32); err != nil {
+			return nil, "", "", err
+		}
+		item.RefreshHash = oauthHash(refresh)
+		item.RefreshExpire = now + OAuthRefreshExpire()
+	}
+
+	if err := facade.DB.Drive().Create(item).Error; err != nil {
+		return nil, "", "", err
+	}
+
+	return item, access, refresh, nil
+}
+
+// RefreshOAuthToken - 使用刷新令牌换取新令牌，旧令牌立即失效（scopes 为空时沿用原权限，否则只能缩小）
+func RefreshOAuthToken(clientId, refresh string, scopes []string) (*OAuthToken, string, string, error) {
+
+	if utils.Is.Empty(refresh) {
+		return nil, "", "", ErrOAuthToken
+	}
+
+	now := time.Now().Unix()
+	item := &OAuthToken{}
+	err := facade.DB.Drive().Where("refresh_hash = ? AND client_id = ?", oauthHash(refresh), clientId).Take(item).Error
+	if err != nil {
+		return nil, "", "", ErrOAuthToken
+	}
+
+	// 已轮换或吊销的刷新令牌再次出现，视为泄露，吊销该应用的全部令牌
+	if item.RevokeTime > 0 {
+		_, _ = RevokeOAuthTokens(item.Uid, item.ClientId)
+		facade.Log.Warn(map[string]any{"uid": item.Uid, "client_id": item.ClientId}, "第三方应用的刷新令牌被重复使用，已吊销全部令牌")
+		return nil, "", "", ErrOAuthToken
+	}
+	if item.RefreshExpire <= now {
+		return nil, "", "", ErrOAuthToken
+	}
+
+	granted := item.Scopes()
+	if len(scopes) == 0 {
+		scopes = granted
+	}
+	for _, scope := range scopes {
+		if !utils.InArray(scope, granted) {
+			return nil, "", "", ErrOAuthScope
+		}
+	}
+	// 缩小权限时保留离线访问，否则刷新后将无法继续刷新
+	if !utils.InArray(OAuthScopeOffline, scopes) {
+		scopes = append(scopes, OAuthScopeOffline)
+	}
+
+	if _, err := OAuthUser(item.Uid); err != nil {
+		return nil, "", "", err
+	}
+
+	// 以吊销时间为条件更新，并发刷新时只有一个请求能成功
+	result := facade.DB.Drive().Model(&OAuthToken{}).Where("id = ? AND revoke_time = 0", item.Id).Update("revoke_time", now)
+	if result.Error != nil {
+		return nil, "", "", result.Error
+	}
+	if result.RowsAffected == 0 {
+		return nil, "", "", ErrOAuthToken
+	}
+
+	return IssueOAuthToken(item.ClientId, item.Uid, scopes, item.AuthTime)
+}
+
+// FindOAuthAccessToken - 查询有效的访问令牌
+func FindOAuthAccessToken(access string) (*OAuthToken, error) {
+
+	if utils.Is.Empty(access) {
+		return nil, ErrOAuthToken
+	}
+
+	item := &OAuthToken{}
+	err := facade.DB.Drive().Where("access_hash = ? AND revoke_time = 0", oauthHash(access)).Take(item).Error
+	if err != nil || item.AccessExpire <= time.Now().Unix() {
+		return nil, ErrOAuthToken
+	}
+
+	// 应用被停用或删除后令牌随之失效
+	if FindOAuthClient(item.ClientId) == nil {
+		return nil, ErrOAuthToken
+	}
+
+	return item, nil
+}
+
+// RevokeOAuthToken - 吊销应用自己的访问令牌或刷新令牌（RFC 7009，令牌不存在时不报错）
+func RevokeOAuthToken(clientId, token string) error {
+
+	if utils.Is.Empty(token) {
+		return nil
+	}
+
+	hash := oauthHash(token)
+	return facade.DB.Drive().Model(&OAuthToken{}).
+		Where("client_id = ? AND revoke_time = 0 AND (access_hash = ? OR refresh_hash = ?)", clientId, hash, hash).
+		Update("revoke_time", time.Now().Unix()).Error
+}
+
+// RevokeOAuthTokens - 吊销用户授权给应用的全部令牌（clientId 为空时为全部应用）
+func RevokeOAuthTokens(uid int, clientId string) (int64, error) {
+
+	if uid <= 0 || facade.DB == nil {
+		return 0, nil
+	}
+
+	query := facade.DB.Drive().Model(&OAuthToken{}).Where("uid = ? AND revoke_time = 0", uid)
+	if !utils.Is.Empty(clientId) {
+		query = query.Where("client_id = ?", clientId)
+	}
+
+	result := query.Update("revoke_time", time.Now().Unix())
+	return result.RowsAffected, result.Error
+}
+
+// CleanOAuthTokens - 清理过期或已吊销超过 days 天的令牌记录
+func CleanOAuthTokens(days int) (int64, error) {
+	now := time.Now().Unix()
+	before := time.Now().AddDate(0, 0, -days).Unix()
+	result := facade.DB.Drive().
+		Where("(revoke_time > 0 AND revoke_time < ?) OR (access_expire < ? AND refresh_expire < ?)", before, before, now).
+		Delete(&OAuthToken{})
+	return result.RowsAffected, result.Error
+}
+
+// OAuthConsent - 用户同意授权的记录（再次授权相同或更小的权限范围时跳过确认）
+type OAuthConsent struct {
+	Id         int    `gorm:"type:int(32); comment:主键;" json:"id"`
+	Uid        int    `gorm:"type:int(32); uniqueIndex:idx_uid_client; comment:用户ID;" json:"uid"`
+	ClientId   string `gorm:"size:64; uniqueIndex:idx_uid_client; comment:客户端ID;" json:"client_id"`
+	Scope      string `gorm:"size:255; comment:已同意的权限范围（空格分隔）;" json:"scope"`
+	CreateTime int64  `gorm:"autoCreateTime; comment:创建时间;" json:"create_time"`
+	UpdateTime int64  `gorm:"autoUpdateTime; comment:更新时间;" json:"update_time"`
+}
+
+// InitOAuthConsent - 初始化OAuthConsent表
+func InitOAuthConsent() {
+	err := facade.DB.Drive().AutoMigrate(&OAuthConsent{})
+	if err != nil {
+		facade.Log.Error(map[string]any{"error": err}, "OAuthConsent表迁移失败")
+		return
+	}
+}
+
+// findOAuthConsent - 查询授权记录（不存在时返回 nil）
+func findOAuthConsent(uid int, clientId string) *OAuthConsent {
+	item := &OAuthConsent{}
+	if err := facade.DB.Drive().Where("uid = ? AND client_id = ?", uid, clientId).Take(item).Error; err != nil {
+		return nil
+	}
+	return item
+}
+
+// OAuthConsented - 用户是否已同意过这些权限范围
+func OAuthConsented(uid int, clientId string, scopes []string) bool {
+
+	item := findOAuthConsent(uid, clientId)
+	if item == nil {
+		return false
+	}
+
+	granted := strings.Fields(item.Scope)
+	for _, scope := range scopes {
+		if !utils.InArray(scope, granted) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// SaveOAuthConsent - 记录用户同意的权限范围（与已同意的合并）
+func SaveOAuthConsent(uid int, clientId string, scopes []string) error {
+
+	item := findOAuthConsent(uid, clientId)
+	if item == nil {
+		item = &OAuthConsent{Uid: uid, ClientId: clientId, Scope: strings.Join(scopes, " ")}
+		if err := facade.DB.Drive().Create(item).Error; err != nil {
+			// 并发授权时由唯一索引兜底，改为合并
+			if item = findOAuthConsent(uid, clientId); item == nil {
+				return err
+			}
+		} else {
+			return nil
+		}
+	}
+
+	granted := strings.Fields(item.Scope)
+	for _, scope := range scopes {
+		if !utils.InArray(scope, granted) {
+			granted = append(granted, scope)
+		}
+	}
+
+	return facade.DB.Drive().Model(&OAuthConsent{}).Where("id = ?", item.Id).
+		Update("scope", strings.Join(granted, " ")).Error
+}
+
+// DeleteOAuthConsent - 取消对应用的授权，同时吊销该应用的全部令牌
+func DeleteOAuthConsent(uid int, clientId string) (int64, error) {
+
+	result := facade.DB.Drive().Where("uid = ? AND client_id = ?", uid, clientId).Delete(&OAuthConsent{})
+	if result.Error != nil {
+		return 0, result.Error
+	}
+
+	if _, err := RevokeOAuthTokens(uid, clientId); err != nil {
+		return 0, err
+	}
+
+	return result.RowsAffected, nil
+}
+
+// DeleteOAuthGrants - 删除用户对全部应用的授权与令牌
+func DeleteOAuthGrants(uid int) error {
+	if uid <= 0 {
+		return nil
+	}
+	if err := facade.DB.Drive().Where("uid = ?", uid).Delete(&OAuthConsent{}).Error; err != nil {
+		return err
+	}
+	return facade.DB.Drive().Where("uid = ?", uid).Delete(&OAuthToken{}).Error
+}
+
+// OAuthUser - 查询可授权的用户（不存在、已冻结或被禁止登录时返回错误）
+func OAuthUser(uid int) (*Users, error) {
+
+	item := &Users{}
+	if err := facade.DB.Drive().Where("id = ?", uid).Take(item).Error; err != nil {
+		return nil, ErrOAuthUser
+	}
+	if item.Status == UserStatusFrozen || item.Restrictions&BanTypeLogin != 0 {
+		return nil, ErrOAuthUser
+	}
+
+	return item, nil
+}
+
+// OAuthClaims - 按权限范围将用户资料映射为 OpenID Connect 标准声明
+func OAuthClaims(user *Users, scopes []string) map[string]any {
+
+	claims := map[string]any{
+		"sub": cast.ToString(user.Id),
+	}
+
+	if utils.InArray(OAuthScopeProfile, scopes) {
+		claims["name"] = user.Nickname
+		claims["nickname"] = user.Nickname
+		claims["preferred_username"] = user.Account
+		claims["picture"] = user.Avatar
+		claims["gender"] = user.Gender
+		claims["locale"] = user.Lang
+		claims["updated_at"] = user.UpdateTime
+		for key, value := range claims {
+			if value == "" {
+				delete(claims, key)
+			}
+		}
+	}
+
+	// 邮箱与手机号均需通过验证码绑定
+	if utils.InArray(OAuthScopeEmail, scopes) && !utils.Is.Empty(user.Email) {
+		claims["email"] = user.Email
+		claims["email_verified"] = true
+	}
+	if utils.InArray(OAuthScopePhone, scopes) && !utils.Is.Empty(user.Phone) {
+		claims["phone_number"] = user.Phone
+		claims["phone_number_verified"] = true
+	}
+
+	return claims
+}
+
+// OAuthIDToken - 签发身份令牌（RS256，公钥通过 JWKS 公开）
+func OAuthIDToken(issuer, clientId string, user *Users, scopes []string, nonce string, authTime int64) (string, error) {
+
+	now := time.Now().Unix()
+
+	claims := OAuthClaims(user, scopes)
+	claims["iss"] = issuer
+	claims["aud"] = clientId
+	claims["azp"] = clientId
+	claims["iat"] = now
+	claims["exp"] = now + OAuthAccessExpire()
+	if authTime > 0 {
+		claims["auth_time"] = authTime
+	}
+	if !utils.Is.Empty(nonce) {
+		claims["nonce"] = nonce
+	}
+
+	return facade.OIDCSign(claims)
+}
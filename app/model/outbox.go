@@ -0,0 +1,406 @@
+package model
+
+import (
+	"errors"
+	"inis/app/facade"
+	"strings"
+	"time"
+
+	"github.com/spf13/cast"
+	"github.com/unti-io/go-utils/utils"
+)
+
+// 发件箱消息类型
+const (
+	OutboxKindVerifyCode    = "verify-code"
+	OutboxKindCommentNotify = "comment-notify"
+	OutboxKindReplyNotify   = "reply-notify"
+	// OutboxKindMailTemplate - 按邮件模板发送（仅邮件驱动）
+	OutboxKindMailTemplate = "mail-template"
+)
+
+// OutboxKinds - 消息类型说明
+var OutboxKinds = map[string]string{
+	OutboxKindVerifyCode:    "验证码",
+	OutboxKindCommentNotify: "评论通知",
+	OutboxKindReplyNotify:   "回复通知",
+	OutboxKindMailTemplate:  "模板邮件",
+}
+
+// 发件箱消息状态
+const (
+	OutboxStatusPending = "pending"
+	OutboxStatusSuccess = "success"
+	// OutboxStatusDead - 死信：超过最大次数、已过期或不可重试的错误
+	OutboxStatusDead = "dead"
+)
+
+const (
+	// outboxBaseDelay - 首次重试间隔，之后按 2 的幂次递增
+	outboxBaseDelay = 30 * time.Second
+	// outboxMaxDelay - 最大重试间隔
+	outboxMaxDelay = time.Hour
+	// outboxClaimDelay - 发送中的消息临时推迟时间，防止被定时任务重复领取；进程意外退出时由定时任务接管
+	outboxClaimDelay = 5 * time.Minute
+)
+
+// outboxPermanentErrors - 重试也无法成功的错误特征
+var outboxPermanentErrors = []string{"格式错误", "check frequency failed", "FREQUENCY_FAIL", "BUSINESS_LIMIT_CONTROL"}
+
+// Outbox - 邮件/短信发件箱
+type Outbox struct {
+	Id         int    `gorm:"type:int(32); comment:主键;" json:"id"`
+	Drive      string `gorm:"size:32; index; comment:发送驱动;" json:"drive"`
+	Kind       string `gorm:"size:32; index; comment:消息类型;" json:"kind"`
+	Recipient  string `gorm:"size:128; index; comment:收件人（邮箱或手机号）;" json:"recipient"`
+	Payload    string `gorm:"type:longtext; comment:消息内容;" json:"payload"`
+	Status     string `gorm:"size:16; index; default:'pending'; comment:发送状态;" json:"status"`
+	Attempts   int    `gorm:"type:int(32); default:0; comment:已发送次数;" json:"attempts"`
+	NextTime   int64  `gorm:"index; default:0; comment:下次发送时间;" json:"next_time"`
+	ExpireTime int64  `gorm:"default:0; comment:过期时间 0表示不过期;" json:"expire_time"`
+	SendTime   int64  `gorm:"index; default:0; comment:发送成功时间;" json:"send_time"`
+	Error      string `gorm:"size:512; comment:错误信息;" json:"error"`
+	CreateTime int64  `gorm:"autoCreateTime; comment:创建时间;" json:"create_time"`
+	UpdateTime int64  `gorm:"autoUpdateTime; comment:更新时间;" json:"update_time"`
+}
+
+// InitOutbox - 初始化Outbox表
+func InitOutbox() {
+	err := facade.DB.Drive().AutoMigrate(&Outbox{})
+	if err != nil {
+		facade.Log.Error(map[string]any{"error": err}, "Outbox表迁移失败")
+		return
+	}
+}
+
+// OutboxSMS - 经由发件箱发送的短信驱动，失败后由定时任务重试
+type OutboxSMS struct {
+	Drive string
+	// Lang - 收件人语言（用于邮件模板）
+	Lang string
+	// Expire - 验证码有效期，为 0 时读取 verify_code.expire 配置
+	Expire time.Duration
+}
+
+// NewOutbox - 创建发件箱驱动
+/**
+ * @param drive 驱动模式，同 facade.NewSMS
+ * @example：
+ * 1. sms := model.NewOutbox("email")
+ * 2. sms := model.NewOutbox(facade.SMSModeEmail)
+ */
+func NewOutbox(drive any) *OutboxSMS {
+	return &OutboxSMS{Drive: strings.ToLower(cast.ToString(drive))}
+}
+
+// VerifyCode - 发送验证码，首次发送失败时进入队列重试（验证码过期后不再重试）
+func (this *OutboxSMS) VerifyCode(phone any, code ...any) (response *facade.SMSResponse) {
+
+	if len(code) == 0 {
+		code = append(code, utils.Rand.String(6, "0123456789"))
+	}
+
+	// 数据库未初始化（未安装）时直接发送
+	if facade.DB == nil {
+		return facade.NewSMS(this.Drive).VerifyCode(phone, code...)
+	}
+
+	response = &facade.SMSResponse{}
+
+	if OutboxLimited(cast.ToString(phone), 0) {
+		response.Error = errors.New("发送过于频繁，请稍后再试！")
+		return response
+	}
+
+	// 验证码消息有效期与验证码缓存时间一致，过期后不再重试
+	ttl := this.Expire
+	if ttl <= 0 {
+		ttl = time.Duration(cast.ToInt(facade.AppToml.Get("verify_code.expire", 300))) * time.Second
+	}
+	expire := int((ttl + time.Minute - 1) / time.Minute)
+
+	item, err := NewOutboxMessage(this.Drive, OutboxKindVerifyCode, cast.ToString(phone), map[string]any{
+		"code":   cast.ToString(code[0]),
+		"lang":   this.Lang,
+		"expire": expire,
+	}, ttl)
+	if err != nil {
+		response.Error = err
+		return response
+	}
+
+	item.Send()
+
+	switch item.Status {
+	case OutboxStatusDead:
+		response.Error = errors.New(item.Error)
+	case OutboxStatusPending:
+		response.Result = "已加入发送队列"
+		response.VerifyCode = cast.ToString(code[0])
+	default:
+		response.Result = "发送成功"
+		response.VerifyCode = cast.ToString(code[0])
+	}
+
+	return response
+}
+
+// SendCommentNotify - 异步发送评论通知
+func (this *OutboxSMS) SendCommentNotify(recipient string, commentInfo map[string]any) (response *facade.SMSResponse) {
+	return this.enqueue(OutboxKindCommentNotify, recipient, commentInfo)
+}
+
+// SendReplyNotify - 异步发送评论回复通知
+func (this *OutboxSMS) SendReplyNotify(recipient string, commentInfo map[string]any) (response *facade.SMSResponse) {
+	return this.enqueue(OutboxKindReplyNotify, recipient, commentInfo)
+}
+
+// SendMailTemplate - 异步按邮件模板发送（如通知摘要）
+func (this *OutboxSMS) SendMailTemplate(recipient, name, lang string, data map[string]any, label string) (response *facade.SMSResponse) {
+	return this.enqueue(OutboxKindMailTemplate, recipient, map[string]any{
+		"template":    name,
+		"lang":        lang,
+		"label":       label,
+		"data":        data,
+		"uid":         data["uid"],
+		"notify_type": data["notify_type"],
+	})
+}
+
+// enqueue - 写入发件箱并立即尝试发送一次
+func (this *OutboxSMS) enqueue(kind, recipient string, data map[string]any) (response *facade.SMSResponse) {
+
+	response = &facade.SMSResponse{}
+
+	// 按收件人的通知偏好过滤（data 中携带 uid 与 notify_type 时）
+	channel := NotifyChannelSMS
+	if this.Drive == facade.SMSModeEmail {
+		channel = NotifyChannelEmail
+	}
+	if !NotifyAllowed(cast.ToInt(data["uid"]), cast.ToString(data["notify_type"]), channel) {
+		response.Result = "收件人已关闭此类通知"
+		return response
+	}
+
+	if facade.DB == nil {
+		sms := facade.NewSMS(this.Drive)
+		switch kind {
+		case OutboxKindMailTemplate:
+			return facade.GoMail.SendTemplate(recipient, cast.ToString(data["template"]), data["lang"], cast.ToStringMap(data["data"]), cast.ToString(data["label"]))
+		case OutboxKindReplyNotify:
+			return sms.SendReplyNotify(recipient, data)
+		}
+		return sms.SendCommentNotify(recipient, data)
+	}
+
+	item, err := NewOutboxMessage(this.Drive, kind, recipient, data, 0)
+	if err != nil {
+		response.Error = err
+		return response
+	}
+
+	go item.Send()
+
+	response.Result = "已加入发送队列"
+	return response
+}
+
+// NewOutboxMessage - 创建发件箱消息，创建者负责首次发送
+func NewOutboxMessage(drive, kind, recipient string, data map[string]any, expire time.Duration) (*Outbox, error) {
+
+	item := &Outbox{
+		Drive:     drive,
+		Kind:      kind,
+		Recipient: recipient,
+		Payload:   utils.Json.Encode(data),
+		Status:    OutboxStatusPending,
+		// 预先推迟，避免首次发送期间被定时任务领取；进程退出导致未发送时由定时任务兜底
+		NextTime: time.Now().Add(outboxClaimDelay).Unix(),
+	}
+	if expire > 0 {
+		item.ExpireTime = time.Now().Add(expire).Unix()
+	}
+
+	if err := facade.DB.Drive().Create(item).Error; err != nil {
+		facade.Log.Error(map[string]any{"error": err, "kind": kind, "drive": drive}, "发件箱消息创建失败")
+		return nil, err
+	}
+
+	return item, nil
+}
+
+// OutboxLimited - 收件人是否超出发送频率限制（按成功发送的消息计数）
+func OutboxLimited(recipient string, exclude int) bool {
+
+	now := time.Now()
+	limits := map[time.Duration]int{
+		time.Minute: cast.ToInt(facade.AppToml.Get("outbox.recipient_per_minute", 3)),
+		time.Hour:   cast.ToInt(facade.AppToml.Get("outbox.recipient_per_hour", 20)),
+	}
+
+	for window, limit := range limits {
+		if limit <= 0 {
+			continue
+		}
+		var count int64
+		facade.DB.Drive().Model(&Outbox{}).
+			Where("recipient = ? AND status = ? AND send_time >= ? AND id <> ?", recipient, OutboxStatusSuccess, now.Add(-window).Unix(), exclude).
+			Count(&count)
+		if count >= int64(limit) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Claim - 领取待发送的消息，防止多个任务重复发送
+func (this *Outbox) Claim() bool {
+	result := facade.DB.Drive().Model(&Outbox{}).
+		Where("id = ? AND status = ? AND next_time = ?", this.Id, OutboxStatusPending, this.NextTime).
+		Update("next_time", time.Now().Add(outboxClaimDelay).Unix())
+	return result.Error == nil && result.RowsAffected == 1
+}
+
+// Send - 执行一次发送，并根据结果更新状态与下次重试时间
+func (this *Outbox) Send() {
+
+	defer func() {
+		if err := recover(); err != nil {
+			facade.Log.Error(map[string]any{"error": err, "id": this.Id}, "发件箱消息发送发生错误")
+		}
+	}()
+
+	if this.ExpireTime > 0 && time.Now().Unix() > this.ExpireTime {
+		this.finish(errors.New("消息已过期"), true)
+		return
+	}
+
+	// 超出收件人频率限制时延后发送，不计入发送次数
+	if this.Kind != OutboxKindVerifyCode && OutboxLimited(this.Recipient, this.Id) {
+		this.NextTime = time.Now().Add(time.Minute).Unix()
+		facade.DB.Drive().Model(&Outbox{}).Where("id = ?", this.Id).Update("next_time", this.NextTime)
+		return
+	}
+
+	data := cast.ToStringMap(utils.Json.Decode(this.Payload))
+	sms := facade.NewSMS(this.Drive)
+
+	var response *facade.SMSResponse
+	switch this.Kind {
+	case OutboxKindVerifyCode:
+		if this.Drive == facade.SMSModeEmail {
+			response = facade.GoMail.SendVerifyCode(this.Recipient, data["code"], data["lang"], cast.ToInt(data["expire"]))
+		} else {
+			response = sms.VerifyCode(this.Recipient, data["code"])
+		}
+	case OutboxKindCommentNotify:
+		response = sms.SendCommentNotify(this.Recipient, data)
+	case OutboxKindReplyNotify:
+		response = sms.SendReplyNotify(this.Recipient, data)
+	case OutboxKindMailTemplate:
+		response = facade.GoMail.SendTemplate(this.Recipient, cast.ToString(data["template"]), data["lang"], cast.ToStringMap(data["data"]), cast.ToString(data["label"]))
+	default:
+		this.finish(errors.New("未知的消息类型："+this.Kind), true)
+		return
+	}
+
+	if response.Error == nil {
+		this.finish(nil, false)
+		return
+	}
+
+	final := false
+	for _, item := range outboxPermanentErrors {
+		if strings.Contains(response.Error.Error(), item) {
+			final = true
+			break
+		}
+	}
+	this.finish(response.Error, final)
+}
+
+// finish - 记录发送结果
+func (this *Outbox) finish(err error, final bool) {
+
+	this.Attempts++
+	this.Error = ""
+	maxAttempts := cast.ToInt(facade.AppToml.Get("outbox.max_attempts", 6))
+
+	switch {
+	case err == nil:
+		this.Status = OutboxStatusSuccess
+		this.NextTime = 0
+		this.SendTime = time.Now().Unix()
+	case final || this.Attempts >= maxAttempts:
+		this.Status = OutboxStatusDead
+		this.NextTime = 0
+	default:
+		this.Status = OutboxStatusPending
+		this.NextTime = time.Now().Add(OutboxBackoff(this.Attempts)).Unix()
+	}
+
+	if err != nil {
+		this.Error = facade.Comm.Truncate(err.Error(), 512)
+	}
+
+	result := facade.DB.Drive().Model(&Outbox{}).Where("id = ?", this.Id).Updates(map[string]any{
+		"attempts":  this.Attempts,
+		"status":    this.Status,
+		"next_time": this.NextTime,
+		"send_time": this.SendTime,
+		"error":     this.Error,
+	})
+	if result.Error != nil {
+		facade.Log.Error(map[string]any{"error": result.Error, "id": this.Id}, "发件箱消息更新失败")
+	}
+	if this.Status == OutboxStatusDead {
+		facade.Log.Warn(map[string]any{"id": this.Id, "kind": this.Kind, "drive": this.Drive, "error": this.Error}, "发件箱消息进入死信")
+	}
+}
+
+// Requeue - 将死信或待发送的消息重新加入队列
+func (this *Outbox) Requeue() error {
+
+	if this.Status == OutboxStatusSuccess {
+		return errors.New("消息已发送成功")
+	}
+
+	if this.ExpireTime > 0 && time.Now().Unix() > this.ExpireTime {
+		return errors.New("消息已过期")
+	}
+
+	return facade.DB.Drive().Model(&Outbox{}).Where("id = ?", this.Id).Updates(map[string]any{
+		"status":    OutboxStatusPending,
+		"attempts":  0,
+		"next_time": time.Now().Unix(),
+		"error":     "",
+	}).Error
+}
+
+// RetryOutbox - 发送到期的待发送消息
+func RetryOutbox(limit int) {
+
+	var list []Outbox
+	facade.DB.Drive().
+		Where("status = ? AND next_time <= ?", OutboxStatusPending, time.Now().Unix()).
+		Order("next_time asc").Limit(limit).Find(&list)
+
+	for _, item := range list {
+		if item.Claim() {
+			item.Send()
+		}
+	}
+}
+
+// OutboxBackoff - 第 n 次失败后的重试间隔（指数退避）
+func OutboxBackoff(attempts int) time.Duration {
+	if attempts < 1 {
+		attempts = 1
+	}
+	delay := outboxBaseDelay << (attempts - 1)
+	if delay <= 0 || delay > outboxMaxDelay {
+		return outboxMaxDelay
+	}
+	return delay
+}
@@ -0,0 +1,206 @@
+package model
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"inis/app/facade"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/spf13/cast"
+	"github.com/unti-io/go-utils/utils"
+	"gorm.io/gorm/clause"
+)
+
+// pushSubscriptionLimit - 每个用户最多保留的订阅（设备）数量，超出时淘汰最久未使用的订阅
+const pushSubscriptionLimit = 20
+
+// PushSubscription - 浏览器 Web Push 订阅（每个浏览器/设备一条）
+type PushSubscription struct {
+	Id  int `gorm:"type:int(32); comment:主键;" json:"id"`
+	Uid int `gorm:"type:int(32); index; comment:用户ID;" json:"uid"`
+	// 推送服务地址的 SHA-256，用于唯一索引（地址过长不适合直接建索引）
+	Hash     string `gorm:"size:64; uniqueIndex; comment:推送地址哈希;" json:"-"`
+	Endpoint string `gorm:"size:1024; comment:推送服务地址;" json:"endpoint"`
+	P256dh   string `gorm:"size:128; comment:浏览器公钥;" json:"-"`
+	Auth     string `gorm:"size:64; comment:认证密钥;" json:"-"`
+	Device   string `gorm:"size:128; comment:设备描述;" json:"device"`
+	// 最近一次推送成功的时间
+	LastTime   int64  `gorm:"default:0; comment:最近推送成功时间;" json:"last_time"`
+	Error      string `gorm:"size:512; comment:最近一次推送错误;" json:"error"`
+	CreateTime int64  `gorm:"autoCreateTime; comment:创建时间;" json:"create_time"`
+	UpdateTime int64  `gorm:"autoUpdateTime; comment:更新时间;" json:"update_time"`
+}
+
+// InitPushSubscription - 初始化PushSubscription表
+func InitPushSubscription() {
+	err := facade.DB.Drive().AutoMigrate(&PushSubscription{})
+	if err != nil {
+		facade.Log.Error(map[string]any{"error": err}, "PushSubscription表迁移失败")
+		return
+	}
+}
+
+// pushEndpointHash - 推送地址哈希
+func pushEndpointHash(endpoint string) string {
+	sum := sha256.Sum256([]byte(endpoint))
+	return hex.EncodeToString(sum[:])
+}
+
+// SavePushSubscription - 保存订阅（同一推送地址重复订阅时更新密钥并归属到当前用户）
+func SavePushSubscription(uid int, subscription facade.WebPushSubscription, ua string) error {
+
+	endpoint := strings.TrimSpace(subscription.Endpoint)
+	target, err := url.Parse(endpoint)
+	if err != nil || target.Scheme != "https" || utils.Is.Empty(target.Host) {
+		return errors.New("推送地址必须为 https 地址")
+	}
+	if err := facade.Comm.IsSafeOutboundURL(endpoint); err != nil {
+		return err
+	}
+	if len(endpoint) > 1024 {
+		return errors.New("推送地址过长")
+	}
+
+	// 用空负载试加密一次，提前发现格式错误的密钥
+	if _, err := facade.WebPushEncrypt(subscription, nil); err != nil {
+		return err
+	}
+
+	item := PushSubscription{
+		Uid:      uid,
+		Hash:     pushEndpointHash(endpoint),
+		Endpoint: endpoint,
+		P256dh:   subscription.P256dh,
+		Auth:     subscription.Auth,
+		Device:   parseDevice(ua),
+	}
+
+	err = facade.DB.Drive().Clauses(clause.OnConflict{
+		Columns: []clause.Column{{Name: "hash"}},
+		DoUpdates: clause.Assignments(map[string]any{
+			"uid":         item.Uid,
+			"p256dh":      item.P256dh,
+			"auth":        item.Auth,
+			"device":      item.Device,
+			"error":       "",
+			"update_time": time.Now().Unix(),
+		}),
+	}).Create(&item).Error
+	if err != nil {
+		return err
+	}
+
+	// 淘汰超出数量上限的旧订阅
+	var ids []int
+	facade.DB.Drive().Model(&PushSubscription{}).Where("uid = ?", uid).
+		Order("update_time desc").Offset(pushSubscriptionLimit).Pluck("id", &ids)
+	if len(ids) > 0 {
+		facade.DB.Drive().Where("id IN ?", ids).Delete(&PushSubscription{})
+	}
+
+	return nil
+}
+
+// DeletePushSubscription - 取消订阅（endpoint 为空时取消该用户的全部订阅）
+func DeletePushSubscription(uid int, endpoint string) (int64, error) {
+
+	query := facade.DB.Drive().Where("uid = ?", uid)
+	if !utils.Is.Empty(endpoint) {
+		query = query.Where("hash = ?", pushEndpointHash(strings.TrimSpace(endpoint)))
+	}
+
+	result := query.Delete(&PushSubscription{})
+	return result.RowsAffected, result.Error
+}
+
+// Send - 推送一条消息，订阅失效时删除该订阅
+func (this *PushSubscription) Send(payload []byte, opts ...facade.WebPushOptions) error {
+
+	// SSRF 防护：每次推送前重新校验，防止 DNS 变更后指向内网
+	if err := facade.Comm.IsSafeOutboundURL(this.Endpoint); err != nil {
+		facade.DB.Drive().Delete(&PushSubscription{}, this.Id)
+		return err
+	}
+
+	err := facade.WebPushSend(facade.WebPushSubscription{
+		Endpoint: this.Endpoint,
+		P256dh:   this.P256dh,
+		Auth:     this.Auth,
+	}, payload, opts...)
+
+	switch {
+	case err == nil:
+		facade.DB.Drive().Model(&PushSubscription{}).Where("id = ?", this.Id).
+			Updates(map[string]any{"last_time": time.Now().Unix(), "error": ""})
+	case errors.Is(err, facade.ErrWebPushGone):
+		facade.DB.Drive().Delete(&PushSubscription{}, this.Id)
+	default:
+		facade.DB.Drive().Model(&PushSubscription{}).Where("id = ?", this.Id).
+			Update("error", facade.Comm.Truncate(err.Error(), 512))
+	}
+
+	return err
+}
+
+// SendWebPush - 推送到用户的全部订阅设备
+func SendWebPush(uid int, payload []byte, opts ...facade.WebPushOptions) {
+
+	var list []PushSubscription
+	facade.DB.Drive().Where("uid = ?", uid).Find(&list)
+
+	for _, item := range list {
+		if err := item.Send(payload, opts...); err != nil && !errors.Is(err, facade.ErrWebPushGone) {
+			facade.Log.Error(map[string]any{"error": err, "uid": uid, "id": item.Id}, "Web Push 推送失败")
+		}
+	}
+}
+
+// webPushNotification - 推送个人通知到浏览器（广播通知面向全体用户，不逐个推送）
+func webPushNotification(notif *Notification) {
+
+	if notif == nil || notif.Uid <= 0 || facade.DB == nil {
+		return
+	}
+	if utils.Is.Empty(facade.WebPushPublicKey()) || !NotifyAllowed(notif.Uid, notif.Type, NotifyChannelPush) {
+		return
+	}
+
+	go func() {
+		defer func() {
+			if r := recover(); r != nil {
+				facade.Log.Error(map[string]any{"error": r}, "Web Push 推送协程错误")
+			}
+		}()
+
+		var count int64
+		facade.DB.Drive().Model(&PushSubscription{}).Where("uid = ?", notif.Uid).Count(&count)
+		if count == 0 {
+			return
+		}
+
+		content := []rune(notif.Content)
+		if len(content) > 200 {
+			content = append(content[:200], []rune("…")...)
+		}
+
+		payload := utils.Json.Encode(map[string]any{
+			"id":          notif.Id,
+			"type":        notif.Type,
+			"title":       notif.Title,
+			"content":     string(content),
+			"bind_type":   notif.BindType,
+			"bind_id":     notif.BindId,
+			"create_time": notif.CreateTime,
+		})
+
+		// 聚合通知以同一 Topic 推送，未送达的旧消息会被替换
+		SendWebPush(notif.Uid, []byte(payload), facade.WebPushOptions{
+			TTL:     86400,
+			Urgency: "normal",
+			Topic:   "notification-" + cast.ToString(notif.Id),
+		})
+	}()
+}
@@ -0,0 +1,229 @@
+package model
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"inis/app/facade"
+	"time"
+
+	"github.com/spf13/cast"
+	"github.com/unti-io/go-utils/utils"
+)
+
+const (
+	// OAuthActionLogin - 第三方登录（未绑定时按配置自动注册）
+	OAuthActionLogin = "login"
+	// OAuthActionLink - 已登录用户绑定第三方账号
+	OAuthActionLink = "link"
+	// cacheOAuthState - 授权状态缓存键
+	cacheOAuthState = "oauth-state[%v]"
+	// oauthStateExpire - 授权状态有效期
+	oauthStateExpire = 10 * time.Minute
+)
+
+var (
+	// ErrIdentityLinked - 第三方账号已绑定其他用户
+	ErrIdentityLinked = errors.New("该第三方账号已绑定其他用户！")
+	// ErrIdentityExists - 用户已绑定该服务商的其他账号
+	ErrIdentityExists = errors.New("已绑定该平台的其他账号，请先解绑！")
+	// ErrIdentityLast - 解绑后将没有任何登录方式
+	ErrIdentityLast = errors.New("这是当前账号唯一的登录方式，请先设置密码后再解绑！")
+	// ErrOAuthState - 授权状态不存在、已过期或已使用
+	ErrOAuthState = errors.New("授权已过期，请重新发起！")
+)
+
+// UserIdentity - 用户绑定的第三方账号（服务商 + 服务商内唯一标识 对应一个 inis 用户）
+type UserIdentity struct {
+	Id       int    `gorm:"type:int(32); comment:主键;" json:"id"`
+	Uid      int    `gorm:"type:int(32); index; comment:用户ID;" json:"uid"`
+	Provider string `gorm:"size:32; uniqueIndex:idx_provider_subject; comment:服务商;" json:"provider"`
+	Subject  string `gorm:"size:191; uniqueIndex:idx_provider_subject; comment:服务商内的唯一标识;" json:"-"`
+	// 以下为绑定或最近登录时服务商返回的资料
+	Email      string `gorm:"size:128; comment:邮箱;" json:"email"`
+	Nickname   string `gorm:"size:64; comment:昵称;" json:"nickname"`
+	Avatar     string `gorm:"size:512; comment:头像;" json:"avatar"`
+	LastTime   int64  `gorm:"default:0; comment:最近登录时间;" json:"last_time"`
+	CreateTime int64  `gorm:"autoCreateTime; comment:创建时间;" json:"create_time"`
+	UpdateTime int64  `gorm:"autoUpdateTime; comment:更新时间;" json:"update_time"`
+}
+
+// InitUserIdentity - 初始化UserIdentity表
+func InitUserIdentity() {
+	err := facade.DB.Drive().AutoMigrate(&UserIdentity{})
+	if err != nil {
+		facade.Log.Error(map[string]any{"error": err}, "UserIdentity表迁移失败")
+		return
+	}
+}
+
+// FindUserIdentity - 查询第三方账号的绑定记录（未绑定时返回 nil）
+func FindUserIdentity(provider, subject string) *UserIdentity {
+	item := &UserIdentity{}
+	err := facade.DB.Drive().Where("provider = ? AND subject = ?", provider, subject).Take(item).Error
+	if err != nil {
+		return nil
+	}
+	return item
+}
+
+// LinkUserIdentity - 绑定第三方账号（每个服务商只能绑定一个账号）
+func LinkUserIdentity(uid int, provider string, identity *facade.OAuthIdentity) (*UserIdentity, error) {
+
+	if exist := FindUserIdentity(provider, identity.Subject); exist != nil {
+		if exist.Uid != uid {
+			return nil, ErrIdentityLinked
+		}
+		exist.Touch(identity)
+		return exist, nil
+	}
+
+	var count int64
+	facade.DB.Drive().Model(&UserIdentity{}).Where("uid = ? AND provider = ?", uid, provider).Count(&count)
+	if count > 0 {
+		return nil, ErrIdentityExists
+	}
+
+	item := &UserIdentity{
+		Uid:      uid,
+		Provider: provider,
+		Subject:  identity.Subject,
+		Email:    facade.Comm.Truncate(identity.Email, 128),
+		Nickname: facade.Comm.Truncate(identity.Nickname, 64),
+		Avatar:   facade.Comm.Truncate(identity.Avatar, 512),
+		LastTime: time.Now().Unix(),
+	}
+	if err := facade.DB.Drive().Create(item).Error; err != nil {
+		// 并发绑定时由唯一索引兜底
+		if FindUserIdentity(provider, identity.Subject) != nil {
+			return nil, ErrIdentityLinked
+		}
+		return nil, err
+	}
+
+	return item, nil
+}
+
+// Touch - 登录时更新服务商资料与最近登录时间
+func (this *UserIdentity) Touch(identity *facade.OAuthIdentity) {
+	this.Email = facade.Comm.Truncate(identity.Email, 128)
+	this.Nickname = facade.Comm.Truncate(identity.Nickname, 64)
+	this.Avatar = facade.Comm.Truncate(identity.Avatar, 512)
+	this.LastTime = time.Now().Unix()
+	facade.DB.Drive().Model(&UserIdentity{}).Where("id = ?", this.Id).Updates(map[string]any{
+		"email":     this.Email,
+		"nickname":  this.Nickname,
+		"avatar":    this.Avatar,
+		"last_time": this.LastTime,
+	})
+}
+
+// UnlinkUserIdentity - 解绑第三方账号（不允许解绑唯一的登录方式）
+func UnlinkUserIdentity(uid, id int) error {
+
+	item := &UserIdentity{}
+	if err := facade.DB.Drive().Where("id = ? AND uid = ?", id, uid).Take(item).Error; err != nil {
+		return errors.New("无数据！")
+	}
+
+	var count int64
+	facade.DB.Drive().Model(&UserIdentity{}).Where("uid = ?", uid).Count(&count)
+	if count <= 1 && !HasUserPasskey(uid) {
+		user := Users{}
+		facade.DB.Drive().Select("password").Where("id = ?", uid).Take(&user)
+		if utils.Is.Empty(user.Password) {
+			return ErrIdentityLast
+		}
+	}
+
+	return facade.DB.Drive().Where("id = ?", item.Id).Delete(&UserIdentity{}).Error
+}
+
+// DeleteUserIdentities - 删除用户绑定的全部第三方账号
+func DeleteUserIdentities(uid int) (int64, error) {
+	if uid <= 0 {
+		return 0, nil
+	}
+	result := facade.DB.Drive().Where("uid = ?", uid).Delete(&UserIdentity{})
+	return result.RowsAffected, result.Error
+}
+
+// OAuthState - 授权状态（发起授权时写入缓存，回调时一次性取出）
+type OAuthState struct {
+	State    string
+	Provider string
+	Action   string
+	// 绑定操作的用户ID
+	Uid int
+	// PKCE 校验码与 S256 挑战（挑战仅在发起授权时使用，不写入缓存）
+	Verifier  string
+	Challenge string
+	// OIDC 的 nonce
+	Nonce string
+	// 绑定浏览器的随机值（写入 HttpOnly cookie，防止登录 CSRF）
+	Browser string
+}
+
+// CreateOAuthState - 生成授权状态
+func CreateOAuthState(provider, action string, uid int) (*OAuthState, error) {
+
+	random := func() (string, error) {
+		buf := make([]byte, 24)
+		if _, err := rand.Read(buf); err != nil {
+			return "", err
+		}
+		return base64.RawURLEncoding.EncodeToString(buf), nil
+	}
+
+	item := &OAuthState{Provider: provider, Action: action, Uid: uid}
+
+	var err error
+	if item.State, err = random(); err != nil {
+		return nil, err
+	}
+	if item.Nonce, err = random(); err != nil {
+		return nil, err
+	}
+	if item.Browser, err = random(); err != nil {
+		return nil, err
+	}
+	if item.Verifier, item.Challenge, err = facade.OAuthPKCE(); err != nil {
+		return nil, err
+	}
+
+	facade.Cache.Set(fmt.Sprintf(cacheOAuthState, item.State), map[string]any{
+		"provider": item.Provider,
+		"action":   item.Action,
+		"uid":      item.Uid,
+		"verifier": item.Verifier,
+		"nonce":    item.Nonce,
+		"browser":  item.Browser,
+	}, oauthStateExpire)
+
+	return item, nil
+}
+
+// ConsumeOAuthState - 取出并销毁授权状态
+func ConsumeOAuthState(state string) (*OAuthState, error) {
+
+	if utils.Is.Empty(state) {
+		return nil, ErrOAuthState
+	}
+
+	// 原子地取出并删除，并发回调同一 state 时只有一个能通过
+	cached := cast.ToStringMap(facade.Cache.Take(fmt.Sprintf(cacheOAuthState, state)))
+	if utils.Is.Empty(cached) {
+		return nil, ErrOAuthState
+	}
+
+	return &OAuthState{
+		State:    state,
+		Provider: cast.ToString(cached["provider"]),
+		Action:   cast.ToString(cached["action"]),
+		Uid:      cast.ToInt(cached["uid"]),
+		Verifier: cast.ToString(cached["verifier"]),
+		Nonce:    cast.ToString(cached["nonce"]),
+		Browser:  cast.ToString(cached["browser"]),
+	}, nil
+}
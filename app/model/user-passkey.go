@@ -0,0 +1,462 @@
+package model
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"inis/app/facade"
+	"strings"
+	"time"
+
+	"github.com/spf13/cast"
+	"github.com/unti-io/go-utils/utils"
+)
+
+const (
+	// userPasskeyLimit - 每个用户最多绑定的通行密钥数量
+	userPasskeyLimit = 10
+	// cacheWebAuthnChallenge - WebAuthn 挑战缓存键
+	cacheWebAuthnChallenge = "webauthn[challenge][%v]"
+)
+
+// WebAuthn 仪式类型
+const (
+	webAuthnRegister = "register"
+	webAuthnLogin    = "login"
+)
+
+var (
+	// ErrPasskeyInvalid - 通行密钥验证失败
+	ErrPasskeyInvalid = errors.New("通行密钥验证失败！")
+	// ErrPasskeyChallenge - 挑战不存在、已过期或已使用
+	ErrPasskeyChallenge = errors.New("通行密钥验证已过期，请重试！")
+	// ErrPasskeyExists - 凭证已绑定
+	ErrPasskeyExists = errors.New("该通行密钥已绑定！")
+	// ErrPasskeyLimit - 超出数量上限
+	ErrPasskeyLimit = errors.New("通行密钥数量已达上限！")
+	// ErrPasskeyCloned - 签名计数未递增，认证器可能被复制
+	ErrPasskeyCloned = errors.New("通行密钥状态异常，请联系管理员！")
+	// ErrPasskeyConfig - 未配置依赖方ID或允许的来源
+	ErrPasskeyConfig = errors.New("通行密钥服务未配置，请联系管理员！")
+)
+
+// UserPasskey - 用户通行密钥（WebAuthn 凭证，一个用户可绑定多个认证器）
+type UserPasskey struct {
+	Id  int `gorm:"type:int(32); comment:主键;" json:"id"`
+	Uid int `gorm:"type:int(32); index; comment:用户ID;" json:"uid"`
+	// 用户自定义名称，如“工作电脑”
+	Name string `gorm:"size:64; comment:名称;" json:"name"`
+	// 凭证ID（base64url）
+	CredentialId string `gorm:"size:255; uniqueIndex; comment:凭证ID;" json:"credential_id"`
+	// COSE 编码的公钥（base64url）
+	PublicKey string `gorm:"type:text; comment:公钥;" json:"-"`
+	Alg       int    `gorm:"type:int(32); comment:签名算法;" json:"alg"`
+	// 签名计数，每次验证必须递增（均为 0 表示认证器不支持计数）
+	SignCount  int64  `gorm:"default:0; comment:签名计数;" json:"sign_count"`
+	Aaguid     string `gorm:"size:36; comment:认证器型号;" json:"aaguid"`
+	Transports string `gorm:"size:128; comment:传输方式;" json:"transports"`
+	LastTime   int64  `gorm:"default:0; comment:最近使用时间;" json:"last_time"`
+	CreateTime int64  `gorm:"autoCreateTime; comment:创建时间;" json:"create_time"`
+	UpdateTime int64  `gorm:"autoUpdateTime; comment:更新时间;" json:"update_time"`
+}
+
+// InitUserPasskey - 初始化UserPasskey表
+func InitUserPasskey() {
+	err := facade.DB.Drive().AutoMigrate(&UserPasskey{})
+	if err != nil {
+		facade.Log.Error(map[string]any{"error": err}, "UserPasskey表迁移失败")
+		return
+	}
+}
+
+// HasUserPasskey - 用户是否绑定了通行密钥
+func HasUserPasskey(uid int) bool {
+	var count int64
+	facade.DB.Drive().Model(&UserPasskey{}).Where("uid = ?", uid).Count(&count)
+	return count > 0
+}
+
+// DeleteUserPasskeys - 删除用户的全部通行密钥
+func DeleteUserPasskeys(uid int) (int64, error) {
+	if uid <= 0 {
+		return 0, nil
+	}
+	result := facade.DB.Drive().Where("uid = ?", uid).Delete(&UserPasskey{})
+	return result.RowsAffected, result.Error
+}
+
+// WebAuthnRP - WebAuthn 依赖方（Relying Party）
+type WebAuthnRP struct {
+	// 依赖方ID，通常为站点域名（不含协议与端口）
+	ID   string
+	Name string
+	// 允许的来源，clientDataJSON 中的 origin 必须与其中之一完全一致
+	Origins []string
+	// 仪式超时（毫秒）
+	Timeout int
+}
+
+// NewWebAuthnRP - 创建依赖方，配置读取自 app.toml 的 [webauthn]
+/**
+ * rp_id 与 origins 必须由管理员配置，不从请求的 Host 推断（Host 可由客户端伪造），未配置时拒绝全部通行密钥操作
+ */
+func NewWebAuthnRP() (*WebAuthnRP, error) {
+
+	id := strings.ToLower(strings.TrimSpace(cast.ToString(facade.AppToml.Get("webauthn.rp_id", ""))))
+	if utils.Is.Empty(id) {
+		return nil, ErrPasskeyConfig
+	}
+
+	var origins []string
+	for _, item := range strings.Split(cast.ToString(facade.AppToml.Get("webauthn.origins", "")), ",") {
+		if item = strings.TrimSpace(item); utils.Is.Empty(item) {
+			continue
+		}
+		origin, err := facade.WebAuthnOrigin(id, item)
+		if err != nil {
+			facade.Log.Error(map[string]any{"rp_id": id, "origin": item, "error": err}, "通行密钥来源配置错误")
+			return nil, ErrPasskeyConfig
+		}
+		origins = append(origins, origin)
+	}
+	if len(origins) == 0 {
+		return nil, ErrPasskeyConfig
+	}
+
+	return &WebAuthnRP{
+		ID:      id,
+		Name:    cast.ToString(facade.AppToml.Get("webauthn.rp_name", "inis")),
+		Origins: origins,
+		Timeout: cast.ToInt(facade.AppToml.Get("webauthn.timeout", 60000)),
+	}, nil
+}
+
+// RegisterOptions - 注册仪式参数（navigator.credentials.create 的 publicKey）
+func (this *WebAuthnRP) RegisterOptions(user Users) (map[string]any, error) {
+
+	challenge, err := webAuthnChallenge(webAuthnRegister, user.Id, this.Timeout)
+	if err != nil {
+		return nil, err
+	}
+
+	var params []map[string]any
+	for _, alg := range facade.WebAuthnAlgs {
+		params = append(params, map[string]any{"type": "public-key", "alg": alg})
+	}
+
+	// 排除已绑定的凭证，避免同一认证器重复绑定
+	exclude := []map[string]any{}
+	var items []UserPasskey
+	facade.DB.Drive().Where("uid = ?", user.Id).Find(&items)
+	for _, item := range items {
+		exclude = append(exclude, item.descriptor())
+	}
+
+	name := utils.Ternary(utils.Is.Empty(user.Email), user.Account, user.Email)
+	return map[string]any{
+		"challenge": challenge,
+		"rp":        map[string]any{"id": this.ID, "name": this.Name},
+		"user": map[string]any{
+			"id":          webAuthnUserHandle(user.Id),
+			"name":        name,
+			"displayName": utils.Ternary(utils.Is.Empty(user.Nickname), name, user.Nickname),
+		},
+		"pubKeyCredParams":   params,
+		"timeout":            this.Timeout,
+		"excludeCredentials": exclude,
+		"authenticatorSelection": map[string]any{
+			"residentKey":      "preferred",
+			"userVerification": "preferred",
+		},
+		"attestation": "none",
+	}, nil
+}
+
+// Register - 校验注册仪式结果并保存凭证
+/**
+ * @param credential 前端 PublicKeyCredential 的 JSON（id、type、response.clientDataJSON、response.attestationObject）
+ */
+func (this *WebAuthnRP) Register(uid int, name string, credential map[string]any) (*UserPasskey, error) {
+
+	response := cast.ToStringMap(credential["response"])
+	if cast.ToString(credential["type"]) != "public-key" || utils.Is.Empty(response) {
+		return nil, ErrPasskeyInvalid
+	}
+
+	clientData, err := facade.WebAuthnDecode(cast.ToString(response["clientDataJSON"]))
+	if err != nil {
+		return nil, ErrPasskeyInvalid
+	}
+	if err := this.checkClientData(clientData, "webauthn.create", webAuthnRegister, uid); err != nil {
+		return nil, err
+	}
+
+	attestation, err := facade.WebAuthnDecode(cast.ToString(response["attestationObject"]))
+	if err != nil {
+		return nil, ErrPasskeyInvalid
+	}
+	_, raw, err := facade.WebAuthnParseAttestation(attestation)
+	if err != nil {
+		return nil, err
+	}
+	auth, err := facade.WebAuthnParseAuthData(raw)
+	if err != nil {
+		return nil, err
+	}
+	if err := this.checkAuthData(auth, false); err != nil {
+		return nil, err
+	}
+	if len(auth.CredentialID) == 0 {
+		return nil, ErrPasskeyInvalid
+	}
+
+	alg, _, err := facade.WebAuthnPublicKey(auth.PublicKey)
+	if err != nil {
+		return nil, err
+	}
+
+	var count int64
+	facade.DB.Drive().Model(&UserPasskey{}).Where("uid = ?", uid).Count(&count)
+	if count >= userPasskeyLimit {
+		return nil, ErrPasskeyLimit
+	}
+
+	id := base64.RawURLEncoding.EncodeToString(auth.CredentialID)
+	var exist int64
+	facade.DB.Drive().Model(&UserPasskey{}).Where("credential_id = ?", id).Count(&exist)
+	if exist > 0 {
+		return nil, ErrPasskeyExists
+	}
+
+	var transports []string
+	for _, item := range cast.ToStringSlice(response["transports"]) {
+		if len(item) <= 16 {
+			transports = append(transports, item)
+		}
+	}
+
+	item := &UserPasskey{
+		Uid:          uid,
+		Name:         utils.Ternary(utils.Is.Empty(name), "通行密钥", facade.Comm.Truncate(name, 64)),
+		CredentialId: id,
+		PublicKey:    base64.RawURLEncoding.EncodeToString(auth.PublicKey),
+		Alg:          alg,
+		SignCount:    int64(auth.SignCount),
+		Aaguid:       webAuthnAAGUID(auth.AAGUID),
+		Transports:   facade.Comm.Truncate(strings.Join(transports, ","), 128),
+	}
+	if err := facade.DB.Drive().Create(item).Error; err != nil {
+		return nil, err
+	}
+
+	return item, nil
+}
+
+// LoginOptions - 验证仪式参数（navigator.credentials.get 的 publicKey）
+/**
+ * @param uid 大于 0 时作为第二步验证，仅允许该用户的凭证；等于 0 时为无密码登录，由认证器选择可发现凭证
+ */
+func (this *WebAuthnRP) LoginOptions(uid int) (map[string]any, error) {
+
+	challenge, err := webAuthnChallenge(webAuthnLogin, uid, this.Timeout)
+	if err != nil {
+		return nil, err
+	}
+
+	allow := []map[string]any{}
+	if uid > 0 {
+		var items []UserPasskey
+		facade.DB.Drive().Where("uid = ?", uid).Find(&items)
+		for _, item := range items {
+			allow = append(allow, item.descriptor())
+		}
+	}
+
+	return map[string]any{
+		"challenge":        challenge,
+		"rpId":             this.ID,
+		"timeout":          this.Timeout,
+		"allowCredentials": allow,
+		// 无密码登录必须验证用户身份（指纹/面容/PIN），作为第二步时只需用户在场
+		"userVerification": utils.Ternary(uid > 0, "preferred", "required"),
+	}, nil
+}
+
+// Login - 校验验证仪式结果，返回通过验证的凭证
+/**
+ * @param credential 前端 PublicKeyCredential 的 JSON（id、type、response.clientDataJSON、authenticatorData、signature、userHandle）
+ * @param uid 与 LoginOptions 的 uid 一致
+ */
+func (this *WebAuthnRP) Login(credential map[string]any, uid int) (*UserPasskey, error) {
+
+	response := cast.ToStringMap(credential["response"])
+	if cast.ToString(credential["type"]) != "public-key" || utils.Is.Empty(response) {
+		return nil, ErrPasskeyInvalid
+	}
+
+	clientData, err := facade.WebAuthnDecode(cast.ToString(response["clientDataJSON"]))
+	if err != nil {
+		return nil, ErrPasskeyInvalid
+	}
+	if err := this.checkClientData(clientData, "webauthn.get", webAuthnLogin, uid); err != nil {
+		return nil, err
+	}
+
+	// 统一凭证ID的编码后查询
+	rawId, err := facade.WebAuthnDecode(cast.ToString(credential["id"]))
+	if err != nil || len(rawId) == 0 {
+		return nil, ErrPasskeyInvalid
+	}
+	item := &UserPasskey{}
+	err = facade.DB.Drive().Where("credential_id = ?", base64.RawURLEncoding.EncodeToString(rawId)).Take(item).Error
+	if err != nil || (uid > 0 && item.Uid != uid) {
+		return nil, ErrPasskeyInvalid
+	}
+
+	// 可发现凭证会返回 userHandle，必须与凭证所属用户一致
+	if handle := cast.ToString(response["userHandle"]); !utils.Is.Empty(handle) {
+		value, err := facade.WebAuthnDecode(handle)
+		if err != nil || string(value) != cast.ToString(item.Uid) {
+			return nil, ErrPasskeyInvalid
+		}
+	}
+
+	raw, err := facade.WebAuthnDecode(cast.ToString(response["authenticatorData"]))
+	if err != nil {
+		return nil, ErrPasskeyInvalid
+	}
+	auth, err := facade.WebAuthnParseAuthData(raw)
+	if err != nil {
+		return nil, ErrPasskeyInvalid
+	}
+	if err := this.checkAuthData(auth, uid == 0); err != nil {
+		return nil, err
+	}
+
+	cose, err := base64.RawURLEncoding.DecodeString(item.PublicKey)
+	if err != nil {
+		return nil, ErrPasskeyInvalid
+	}
+	sig, err := facade.WebAuthnDecode(cast.ToString(response["signature"]))
+	if err != nil {
+		return nil, ErrPasskeyInvalid
+	}
+	hash := sha256.Sum256(clientData)
+	if err := facade.WebAuthnVerify(cose, append(append([]byte{}, raw...), hash[:]...), sig); err != nil {
+		return nil, ErrPasskeyInvalid
+	}
+
+	// 签名计数未递增：认证器可能被复制
+	count := int64(auth.SignCount)
+	if (count != 0 || item.SignCount != 0) && count <= item.SignCount {
+		facade.Log.Warn(map[string]any{
+			"uid": item.Uid, "id": item.Id, "stored": item.SignCount, "received": count,
+		}, "通行密钥签名计数未递增，疑似认证器被复制")
+		return nil, ErrPasskeyCloned
+	}
+
+	// 以旧计数为条件更新，并发重放时只有一个请求能成功
+	result := facade.DB.Drive().Model(&UserPasskey{}).
+		Where("id = ? AND sign_count = ?", item.Id, item.SignCount).
+		Updates(map[string]any{"sign_count": count, "last_time": time.Now().Unix()})
+	if result.Error != nil || result.RowsAffected == 0 {
+		return nil, ErrPasskeyInvalid
+	}
+	item.SignCount, item.LastTime = count, time.Now().Unix()
+
+	return item, nil
+}
+
+// checkOrigin - 来源是否在配置的允许列表中
+func (this *WebAuthnRP) checkOrigin(origin string) bool {
+	return utils.InArray(strings.TrimRight(origin, "/"), this.Origins)
+}
+
+// checkClientData - 校验 clientDataJSON 的类型、来源与挑战（挑战一次性使用）
+func (this *WebAuthnRP) checkClientData(raw []byte, kind, purpose string, uid int) error {
+
+	data, err := facade.WebAuthnParseClientData(raw)
+	if err != nil || data.Type != kind || data.CrossOrigin {
+		return ErrPasskeyInvalid
+	}
+	if !this.checkOrigin(data.Origin) {
+		return ErrPasskeyInvalid
+	}
+	if !webAuthnConsume(data.Challenge, purpose, uid) {
+		return ErrPasskeyChallenge
+	}
+
+	return nil
+}
+
+// checkAuthData - 校验依赖方ID哈希与用户在场/已验证标志
+func (this *WebAuthnRP) checkAuthData(auth *facade.WebAuthnAuthData, verified bool) error {
+	hash := sha256.Sum256([]byte(this.ID))
+	if subtle.ConstantTimeCompare(auth.RPIDHash, hash[:]) != 1 || !auth.UserPresent() {
+		return ErrPasskeyInvalid
+	}
+	if verified && !auth.UserVerified() {
+		return ErrPasskeyInvalid
+	}
+	return nil
+}
+
+// descriptor - 凭证描述（excludeCredentials / allowCredentials 的元素）
+func (this *UserPasskey) descriptor() map[string]any {
+	item := map[string]any{"type": "public-key", "id": this.CredentialId}
+	if !utils.Is.Empty(this.Transports) {
+		item["transports"] = strings.Split(this.Transports, ",")
+	}
+	return item
+}
+
+// webAuthnUserHandle - 用户句柄（base64url 编码的用户ID）
+func webAuthnUserHandle(uid int) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(cast.ToString(uid)))
+}
+
+// webAuthnAAGUID - 认证器型号（UUID 格式，全零表示未提供）
+func webAuthnAAGUID(value []byte) string {
+	if len(value) != 16 {
+		return ""
+	}
+	text := hex.EncodeToString(value)
+	return text[:8] + "-" + text[8:12] + "-" + text[12:16] + "-" + text[16:20] + "-" + text[20:]
+}
+
+// webAuthnChallenge - 签发挑战并记录用途与用户
+func webAuthnChallenge(purpose string, uid, timeout int) (string, error) {
+
+	challenge, err := facade.WebAuthnChallenge()
+	if err != nil {
+		return "", err
+	}
+
+	// 有效期为仪式超时再加 1 分钟的网络余量，且不少于 2 分钟
+	expire := max(time.Duration(timeout)*time.Millisecond+time.Minute, 2*time.Minute)
+	facade.Cache.Set(fmt.Sprintf(cacheWebAuthnChallenge, challenge), map[string]any{
+		"purpose": purpose,
+		"uid":     uid,
+	}, expire)
+
+	return challenge, nil
+}
+
+// webAuthnConsume - 校验并销毁挑战
+func webAuthnConsume(challenge, purpose string, uid int) bool {
+
+	if utils.Is.Empty(challenge) {
+		return false
+	}
+
+	// 原子地取出并删除，并发提交同一挑战时只有一个能通过
+	item := cast.ToStringMap(facade.Cache.Take(fmt.Sprintf(cacheWebAuthnChallenge, challenge)))
+	if utils.Is.Empty(item) {
+		return false
+	}
+
+	return cast.ToString(item["purpose"]) == purpose && cast.ToInt(item["uid"]) == uid
+}
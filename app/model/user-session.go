@@ -0,0 +1,308 @@
+package model
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"inis/app/facade"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/spf13/cast"
+	"github.com/unti-io/go-utils/utils"
+)
+
+const (
+	// userSessionReuseGrace - 刷新令牌轮换后的宽限期（秒），期间重复提交旧令牌视为并发刷新而非盗用
+	userSessionReuseGrace = 10
+	// userSessionTouchInterval - 最近活跃时间的更新间隔（秒）
+	userSessionTouchInterval = 60
+	// userSessionLimit - 每个用户最多保留的有效会话（设备）数量，超出时下线最久未活跃的会话
+	userSessionLimit = 50
+	// cacheUserSession - 会话状态缓存键
+	cacheUserSession = "user-session[%v]"
+)
+
+// 会话下线原因
+const (
+	SessionRevokeLogout   = "logout"   // 退出登录
+	SessionRevokeUser     = "user"     // 用户手动下线设备
+	SessionRevokeOthers   = "others"   // 退出其它设备
+	SessionRevokeAdmin    = "admin"    // 管理员强制下线
+	SessionRevokeBan      = "ban"      // 封禁或冻结
+	SessionRevokePassword = "password" // 修改或重置密码
+	SessionRevokeDestroy  = "destroy"  // 注销账户
+	SessionRevokeReuse    = "reuse"    // 刷新令牌被重复使用（疑似泄露）
+	SessionRevokeLimit    = "limit"    // 超出会话数量上限
+)
+
+var (
+	// ErrSessionInvalid - 会话不存在、已过期或已下线
+	ErrSessionInvalid = errors.New("登录已过期，请重新登录！")
+	// ErrSessionReused - 刷新令牌被重复使用，会话已强制下线
+	ErrSessionReused = errors.New("登录凭证已失效，请重新登录！")
+)
+
+// UserSession - 登录会话（每次登录一条，刷新令牌每次使用后轮换）
+type UserSession struct {
+	Id  int `gorm:"type:int(32); comment:主键;" json:"id"`
+	Uid int `gorm:"type:int(32); index; comment:用户ID;" json:"uid"`
+	// 会话ID，写入访问令牌的 sid 声明
+	Sid string `gorm:"size:36; uniqueIndex; comment:会话ID;" json:"sid"`
+	// 当前刷新令牌与上一个刷新令牌的 SHA-256（不保存明文）
+	RefreshHash string `gorm:"size:64; uniqueIndex; comment:刷新令牌哈希;" json:"-"`
+	PrevHash    string `gorm:"size:64; index; comment:上一个刷新令牌哈希;" json:"-"`
+	RotateTime  int64  `gorm:"default:0; comment:最近轮换时间;" json:"-"`
+	Device      string `gorm:"size:128; comment:设备描述;" json:"device"`
+	Ip          string `gorm:"size:64; comment:最近IP;" json:"ip"`
+	Ua          string `gorm:"size:512; comment:User-Agent;" json:"ua"`
+	LastTime    int64  `gorm:"default:0; comment:最近活跃时间;" json:"last_time"`
+	ExpireTime  int64  `gorm:"default:0; index; comment:过期时间;" json:"expire_time"`
+	// 下线时间，0 表示会话有效
+	RevokeTime   int64  `gorm:"default:0; index; comment:下线时间;" json:"revoke_time"`
+	RevokeReason string `gorm:"size:32; comment:下线原因;" json:"revoke_reason"`
+	CreateTime   int64  `gorm:"autoCreateTime; comment:创建时间;" json:"create_time"`
+	UpdateTime   int64  `gorm:"autoUpdateTime; comment:更新时间;" json:"update_time"`
+}
+
+// InitUserSession - 初始化UserSession表
+func InitUserSession() {
+	err := facade.DB.Drive().AutoMigrate(&UserSession{})
+	if err != nil {
+		facade.Log.Error(map[string]any{"error": err}, "UserSession表迁移失败")
+		return
+	}
+}
+
+// userSessionHash - 刷新令牌哈希
+func userSessionHash(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// userSessionToken - 生成刷新令牌（32 字节随机数）
+func userSessionToken() (string, error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(buf), nil
+}
+
+// CreateUserSession - 登录成功后创建会话，返回会话与刷新令牌
+func CreateUserSession(uid int, ip, ua string) (*UserSession, string, error) {
+
+	refresh, err := userSessionToken()
+	if err != nil {
+		return nil, "", err
+	}
+
+	now := time.Now().Unix()
+	item := &UserSession{
+		Uid:         uid,
+		Sid:         uuid.New().String(),
+		RefreshHash: userSessionHash(refresh),
+		RotateTime:  now,
+		Device:      parseDevice(ua),
+		Ip:          ip,
+		Ua:          facade.Comm.Truncate(ua, 512),
+		LastTime:    now,
+		ExpireTime:  now + facade.JwtSessionExpire(),
+	}
+	if err := facade.DB.Drive().Create(item).Error; err != nil {
+		return nil, "", err
+	}
+
+	// 下线超出数量上限的旧会话
+	var sids []string
+	facade.DB.Drive().Model(&UserSession{}).Where("uid = ? AND revoke_time = 0", uid).
+		Order("last_time desc").Offset(userSessionLimit).Pluck("sid", &sids)
+	if len(sids) > 0 {
+		revokeUserSessions(sids, SessionRevokeLimit)
+	}
+
+	return item, refresh, nil
+}
+
+// RotateUserSession - 使用刷新令牌续期会话，旧刷新令牌立即失效
+func RotateUserSession(refresh, ip, ua string) (*UserSession, string, error) {
+
+	if utils.Is.Empty(refresh) {
+		return nil, "", ErrSessionInvalid
+	}
+
+	now := time.Now().Unix()
+	hash := userSessionHash(refresh)
+
+	item := &UserSession{}
+	if err := facade.DB.Drive().Where("refresh_hash = ?", hash).Take(item).Error; err != nil {
+		// 已轮换过的旧令牌再次出现：宽限期外视为令牌泄露，下线整个会话
+		old := &UserSession{}
+		if facade.DB.Drive().Where("prev_hash = ? AND revoke_time = 0", hash).Take(old).Error == nil {
+			if now-old.RotateTime <= userSessionReuseGrace {
+				return nil, "", ErrSessionInvalid
+			}
+			revokeUserSessions([]string{old.Sid}, SessionRevokeReuse)
+			facade.Log.Warn(map[string]any{"uid": old.Uid, "sid": old.Sid, "ip": ip}, "刷新令牌被重复使用，已强制下线会话")
+			return nil, "", ErrSessionReused
+		}
+		return nil, "", ErrSessionInvalid
+	}
+
+	if item.RevokeTime > 0 || item.ExpireTime <= now {
+		return nil, "", ErrSessionInvalid
+	}
+
+	next, err := userSessionToken()
+	if err != nil {
+		return nil, "", err
+	}
+
+	// 以旧哈希为条件更新，并发刷新时只有一个请求能成功
+	result := facade.DB.Drive().Model(&UserSession{}).
+		Where("id = ? AND refresh_hash = ?", item.Id, hash).
+		Updates(map[string]any{
+			"refresh_hash": userSessionHash(next),
+			"prev_hash":    hash,
+			"rotate_time":  now,
+			"ip":           ip,
+			"ua":           facade.Comm.Truncate(ua, 512),
+			"device":       parseDevice(ua),
+			"last_time":    now,
+			"expire_time":  now + facade.JwtSessionExpire(),
+		})
+	if result.Error != nil {
+		return nil, "", result.Error
+	}
+	if result.RowsAffected == 0 {
+		return nil, "", ErrSessionInvalid
+	}
+
+	item.Ip, item.LastTime = ip, now
+	item.ExpireTime = now + facade.JwtSessionExpire()
+	facade.Cache.Del(fmt.Sprintf(cacheUserSession, item.Sid))
+
+	return item, next, nil
+}
+
+// FindUserSession - 通过刷新令牌查找有效会话
+func FindUserSession(refresh string) *UserSession {
+	if utils.Is.Empty(refresh) {
+		return nil
+	}
+	item := &UserSession{}
+	err := facade.DB.Drive().Where("refresh_hash = ? AND revoke_time = 0", userSessionHash(refresh)).Take(item).Error
+	if err != nil {
+		return nil
+	}
+	return item
+}
+
+// AccessToken - 签发访问令牌（password 为用户当前的密码哈希）
+func (this *UserSession) AccessToken(password string) facade.JwtResponse {
+	return facade.Jwt(facade.JwtRequest{Expire: facade.JwtAccessExpire()}).Create(facade.H{
+		"uid":  this.Uid,
+		"sid":  this.Sid,
+		"hash": utils.Hash.Sum32(password),
+	})
+}
+
+// UserSessionActive - 访问令牌所属会话是否有效（带缓存，下线时清除）
+func UserSessionActive(sid string, uid int) bool {
+
+	if utils.Is.Empty(sid) || uid <= 0 {
+		return false
+	}
+
+	cacheName := fmt.Sprintf(cacheUserSession, sid)
+	if cached := cast.ToStringMap(facade.Cache.Get(cacheName)); !utils.Is.Empty(cached) {
+		return cast.ToInt(cached["uid"]) == uid && cast.ToInt64(cached["expire"]) > time.Now().Unix()
+	}
+
+	item := &UserSession{}
+	if err := facade.DB.Drive().Where("sid = ? AND revoke_time = 0", sid).Take(item).Error; err != nil {
+		return false
+	}
+
+	facade.Cache.Set(cacheName, map[string]any{"uid": item.Uid, "expire": item.ExpireTime}, 10*time.Minute)
+	return item.Uid == uid && item.ExpireTime > time.Now().Unix()
+}
+
+// TouchUserSession - 记录会话最近活跃时间与IP（限频写库）
+func TouchUserSession(sid, ip string) {
+
+	if utils.Is.Empty(sid) || facade.DB == nil {
+		return
+	}
+
+	cacheName := fmt.Sprintf(cacheUserSession+"[touch]", sid)
+	if facade.Cache.Has(cacheName) {
+		return
+	}
+	facade.Cache.Set(cacheName, 1, userSessionTouchInterval*time.Second)
+
+	facade.DB.Drive().Model(&UserSession{}).Where("sid = ? AND revoke_time = 0", sid).
+		Updates(map[string]any{"last_time": time.Now().Unix(), "ip": ip})
+}
+
+// RevokeUserSession - 下线指定会话
+func RevokeUserSession(sid, reason string) error {
+	if utils.Is.Empty(sid) {
+		return nil
+	}
+	_, err := revokeUserSessions([]string{sid}, reason)
+	return err
+}
+
+// RevokeUserSessions - 下线用户的全部会话（except 为保留的会话ID，如“退出其它设备”时保留当前会话）
+func RevokeUserSessions(uid int, except, reason string) (int64, error) {
+
+	if uid <= 0 || facade.DB == nil {
+		return 0, nil
+	}
+
+	var sids []string
+	query := facade.DB.Drive().Model(&UserSession{}).Where("uid = ? AND revoke_time = 0", uid)
+	if !utils.Is.Empty(except) {
+		query = query.Where("sid <> ?", except)
+	}
+	if err := query.Pluck("sid", &sids).Error; err != nil {
+		return 0, err
+	}
+
+	// 修改密码、封禁、注销或管理员强制下线时，一并吊销授权给第三方应用的令牌
+	if utils.InArray(reason, []string{SessionRevokePassword, SessionRevokeBan, SessionRevokeDestroy, SessionRevokeAdmin}) {
+		_, _ = RevokeOAuthTokens(uid, "")
+	}
+
+	return revokeUserSessions(sids, reason)
+}
+
+// revokeUserSessions - 下线会话并清除状态缓存
+func revokeUserSessions(sids []string, reason string) (int64, error) {
+
+	if len(sids) == 0 {
+		return 0, nil
+	}
+
+	result := facade.DB.Drive().Model(&UserSession{}).Where("sid IN ? AND revoke_time = 0", sids).
+		Updates(map[string]any{"revoke_time": time.Now().Unix(), "revoke_reason": reason})
+
+	for _, sid := range sids {
+		facade.Cache.Del(fmt.Sprintf(cacheUserSession, sid))
+	}
+
+	return result.RowsAffected, result.Error
+}
+
+// CleanUserSessions - 清理过期或已下线超过 days 天的会话记录
+func CleanUserSessions(days int) (int64, error) {
+	before := time.Now().AddDate(0, 0, -days).Unix()
+	result := facade.DB.Drive().
+		Where("expire_time < ? OR (revoke_time > 0 AND revoke_time < ?)", before, before).
+		Delete(&UserSession{})
+	return result.RowsAffected, result.Error
+}
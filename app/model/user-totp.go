@@ -0,0 +1,260 @@
+package model
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"inis/app/facade"
+	"strings"
+	"time"
+
+	"github.com/spf13/cast"
+	"github.com/unti-io/go-utils/utils"
+	"gorm.io/gorm/clause"
+)
+
+// totpRecoveryCount - 每次生成的恢复码数量
+const totpRecoveryCount = 10
+
+var (
+	// ErrTotpCode - 两步验证码错误
+	ErrTotpCode = errors.New("两步验证码错误！")
+	// ErrTotpNotEnabled - 未开启两步验证
+	ErrTotpNotEnabled = errors.New("未开启两步验证！")
+	// ErrTotpPending - 未开始绑定或绑定已过期
+	ErrTotpPending = errors.New("请先获取两步验证密钥！")
+)
+
+// UserTotp - 用户两步验证（TOTP，RFC 6238）
+type UserTotp struct {
+	Id  int `gorm:"type:int(32); comment:主键;" json:"id"`
+	Uid int `gorm:"type:int(32); uniqueIndex; comment:用户ID;" json:"uid"`
+	// 已启用的密钥（base32）
+	Secret string `gorm:"size:64; comment:密钥;" json:"-"`
+	// 绑定中的密钥，确认验证码后替换 Secret
+	PendingSecret string `gorm:"size:64; comment:待确认密钥;" json:"-"`
+	PendingTime   int64  `gorm:"default:0; comment:待确认密钥生成时间;" json:"-"`
+	Enabled       int    `gorm:"type:int(32); default:0; comment:是否启用;" json:"enabled"`
+	// 最近一次通过校验的时间步，拒绝重放同一验证码
+	LastCounter int64 `gorm:"default:0; comment:最近使用的时间步;" json:"-"`
+	// 恢复码的 SHA-256（JSON 数组），使用后移除
+	RecoveryCodes string `gorm:"type:text; comment:恢复码哈希;" json:"-"`
+	EnableTime    int64  `gorm:"default:0; comment:启用时间;" json:"enable_time"`
+	CreateTime    int64  `gorm:"autoCreateTime; comment:创建时间;" json:"create_time"`
+	UpdateTime    int64  `gorm:"autoUpdateTime; comment:更新时间;" json:"update_time"`
+}
+
+// InitUserTotp - 初始化UserTotp表
+func InitUserTotp() {
+	err := facade.DB.Drive().AutoMigrate(&UserTotp{})
+	if err != nil {
+		facade.Log.Error(map[string]any{"error": err}, "UserTotp表迁移失败")
+		return
+	}
+}
+
+// totpSkew - 允许的时间步偏移
+func totpSkew() int {
+	skew := cast.ToInt(facade.AppToml.Get("totp.skew", 1))
+	return max(0, min(skew, 5))
+}
+
+// totpRecoveryHash - 恢复码哈希（忽略大小写与分隔符）
+func totpRecoveryHash(code string) string {
+	code = strings.ToLower(strings.NewReplacer("-", "", " ", "").Replace(strings.TrimSpace(code)))
+	sum := sha256.Sum256([]byte(code))
+	return hex.EncodeToString(sum[:])
+}
+
+// totpRecoveryGenerate - 生成恢复码（格式 xxxxx-xxxxx）
+func totpRecoveryGenerate() (codes []string, hashes []string, err error) {
+
+	// 32 个字符，去掉易混淆的 i、l、o、1
+	const charset = "abcdefghjkmnpqrstuvwxyz023456789"
+
+	for range totpRecoveryCount {
+		buf := make([]byte, 10)
+		if _, err := rand.Read(buf); err != nil {
+			return nil, nil, err
+		}
+		for i := range buf {
+			buf[i] = charset[buf[i]&31]
+		}
+		code := string(buf[:5]) + "-" + string(buf[5:])
+		codes = append(codes, code)
+		hashes = append(hashes, totpRecoveryHash(code))
+	}
+
+	return codes, hashes, nil
+}
+
+// GetUserTotp - 查询用户的两步验证记录（不存在时返回 nil）
+func GetUserTotp(uid int) *UserTotp {
+	item := &UserTotp{}
+	if err := facade.DB.Drive().Where("uid = ?", uid).Take(item).Error; err != nil {
+		return nil
+	}
+	return item
+}
+
+// TotpEnabled - 用户是否已开启两步验证
+func TotpEnabled(uid int) bool {
+	item := GetUserTotp(uid)
+	return item != nil && item.Enabled == 1
+}
+
+// TotpRequired - 用户是否必须开启两步验证（配置要求 root=1 权限组的成员开启）
+func TotpRequired(uid int) bool {
+	if uid <= 0 || !cast.ToBool(facade.AppToml.Get("totp.require_root", false)) {
+		return false
+	}
+	for _, item := range UserAuthGroups(uid) {
+		if item.Root {
+			return true
+		}
+	}
+	return false
+}
+
+// TotpEnroll - 生成待确认的密钥，返回密钥与身份验证器配置地址
+func TotpEnroll(uid int, account string) (secret, uri string, err error) {
+
+	secret, err = facade.TOTPGenerateSecret()
+	if err != nil {
+		return "", "", err
+	}
+
+	item := UserTotp{Uid: uid, PendingSecret: secret, PendingTime: time.Now().Unix()}
+	err = facade.DB.Drive().Clauses(clause.OnConflict{
+		Columns: []clause.Column{{Name: "uid"}},
+		DoUpdates: clause.Assignments(map[string]any{
+			"pending_secret": item.PendingSecret,
+			"pending_time":   item.PendingTime,
+			"update_time":    time.Now().Unix(),
+		}),
+	}).Create(&item).Error
+	if err != nil {
+		return "", "", err
+	}
+
+	issuer := cast.ToString(facade.AppToml.Get("totp.issuer", "inis"))
+	return secret, facade.TOTPURI(issuer, account, secret), nil
+}
+
+// TotpConfirm - 校验待确认密钥的验证码并启用两步验证，返回恢复码（仅此一次返回明文）
+func TotpConfirm(uid int, code string) ([]string, error) {
+
+	item := GetUserTotp(uid)
+	// 待确认密钥 10 分钟内有效
+	if item == nil || utils.Is.Empty(item.PendingSecret) || time.Now().Unix()-item.PendingTime > 600 {
+		return nil, ErrTotpPending
+	}
+
+	counter, ok := facade.TOTPVerify(item.PendingSecret, code, totpSkew())
+	if !ok {
+		return nil, ErrTotpCode
+	}
+
+	codes, hashes, err := totpRecoveryGenerate()
+	if err != nil {
+		return nil, err
+	}
+
+	err = facade.DB.Drive().Model(&UserTotp{}).Where("id = ?", item.Id).Updates(map[string]any{
+		"secret":         item.PendingSecret,
+		"pending_secret": "",
+		"pending_time":   0,
+		"enabled":        1,
+		"last_counter":   counter,
+		"recovery_codes": utils.Json.Encode(hashes),
+		"enable_time":    time.Now().Unix(),
+	}).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return codes, nil
+}
+
+// TotpVerify - 校验两步验证码或恢复码（恢复码使用后失效）
+func TotpVerify(uid int, code string) error {
+
+	item := GetUserTotp(uid)
+	if item == nil || item.Enabled != 1 {
+		return ErrTotpNotEnabled
+	}
+
+	code = strings.TrimSpace(code)
+	if utils.Is.Empty(code) {
+		return ErrTotpCode
+	}
+
+	// 6 位数字：TOTP 验证码
+	if counter, ok := facade.TOTPVerify(item.Secret, code, totpSkew()); ok {
+		// 以时间步为条件更新，同一验证码（或更早的验证码）只能使用一次
+		result := facade.DB.Drive().Model(&UserTotp{}).
+			Where("id = ? AND last_counter < ?", item.Id, counter).
+			Update("last_counter", counter)
+		if result.Error != nil || result.RowsAffected == 0 {
+			return ErrTotpCode
+		}
+		return nil
+	}
+
+	// 恢复码
+	hash := totpRecoveryHash(code)
+	hashes := cast.ToStringSlice(utils.Json.Decode(item.RecoveryCodes))
+	for i, value := range hashes {
+		if subtle.ConstantTimeCompare([]byte(value), []byte(hash)) != 1 {
+			continue
+		}
+		remain := append(append([]string{}, hashes[:i]...), hashes[i+1:]...)
+		result := facade.DB.Drive().Model(&UserTotp{}).
+			Where("id = ? AND recovery_codes = ?", item.Id, item.RecoveryCodes).
+			Update("recovery_codes", utils.Json.Encode(remain))
+		if result.Error != nil || result.RowsAffected == 0 {
+			return ErrTotpCode
+		}
+		facade.Log.Info(map[string]any{"uid": uid, "remain": len(remain)}, "用户使用两步验证恢复码")
+		return nil
+	}
+
+	return ErrTotpCode
+}
+
+// RecoveryRemain - 剩余可用的恢复码数量
+func (this *UserTotp) RecoveryRemain() int {
+	return len(cast.ToStringSlice(utils.Json.Decode(this.RecoveryCodes)))
+}
+
+// TotpRegenerateRecovery - 重新生成恢复码（旧恢复码全部失效）
+func TotpRegenerateRecovery(uid int) ([]string, error) {
+
+	if !TotpEnabled(uid) {
+		return nil, ErrTotpNotEnabled
+	}
+
+	codes, hashes, err := totpRecoveryGenerate()
+	if err != nil {
+		return nil, err
+	}
+
+	err = facade.DB.Drive().Model(&UserTotp{}).Where("uid = ?", uid).
+		Update("recovery_codes", utils.Json.Encode(hashes)).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return codes, nil
+}
+
+// TotpDisable - 关闭两步验证
+func TotpDisable(uid int) error {
+	if uid <= 0 {
+		return fmt.Errorf("uid 无效")
+	}
+	return facade.DB.Drive().Where("uid = ?", uid).Delete(&UserTotp{}).Error
+}
@@ -33,6 +33,7 @@ type Users struct {
 	Gender      string `gorm:"comment:性别; default:Null;" json:"gender"`
 	Exp         int    `gorm:"type:int(32); comment:经验值; default:0;" json:"exp"`
 	Source      string `gorm:"size:32; default:'default'; comment:注册来源;" json:"source"`
+	Lang        string `gorm:"size:16; comment:语言偏好（用于邮件等通知）; default:Null;" json:"lang"`
 	Remark      string `gorm:"comment:备注; default:Null;" json:"remark"`
 	// 封禁相关字段
 	BanCount     int   `gorm:"type:int(32); default:0; comment:累计封禁次数;" json:"ban_count"`
@@ -145,21 +146,14 @@ func (this *Users) Rules(uid any) (slice []any) {
 	// 生成规则列表
 	item := func(uid any) (slice []any) {
 
-		var table []AuthGroup
-
-		group, _ := facade.DB.Model(&table).Like("uids", "%|"+cast.ToString(uid)+"|%").Select()
-
 		var hashes []any
 
-		for _, item := range group {
-			// 判断字符串中是否包含all
-			if strings.Contains(cast.ToString(item["rules"]), "all") {
+		for _, item := range UserAuthGroups(uid) {
+			if item.All {
 				hashes = append(hashes, "all")
 				continue
 			}
-			// 逗号分隔数组
-			list := strings.Split(cast.ToString(item["rules"]), ",")
-			for _, val := range list {
+			for _, val := range item.Rules {
 				hashes = append(hashes, val)
 			}
 		}
@@ -193,7 +187,7 @@ func (this *Users) Rules(uid any) (slice []any) {
 	}
 
 	// 用户组缓存
-	cacheName := fmt.Sprintf("user[%v][rule-group]", uid)
+	cacheName := fmt.Sprintf("user[%v][rule-group][%v]", uid, authPolicyVersion())
 	// 缓存状态
 	cacheState := cast.ToBool(facade.CacheToml.Get("open"))
 
@@ -278,7 +272,10 @@ func (this *Users) auth(wg *sync.WaitGroup, result *any) {
 
 	defer wg.Done()
 
-	group, _ := facade.DB.Model(&AuthGroup{}).Like("uids", "%|"+cast.ToString(this.Id)+"|%").Column("id", "rules", "name", "root", "pages", "key")
+	var group any = []any{}
+	if gids := UserGroupIds(this.Id); len(gids) > 0 {
+		group, _ = facade.DB.Model(&AuthGroup{}).WhereIn("id", gids).Column("id", "rules", "name", "root", "pages", "key")
+	}
 
 	var ids []int
 	var rules []string
@@ -349,10 +346,7 @@ func (this *Users) level(wg *sync.WaitGroup, result *any) {
 // Destroy - 注销后，清空用户数据
 func (this *Users) Destroy(uid any) {
 
-	ids, _ := facade.DB.Model(&[]AuthGroup{}).WithTrashed().Like("uids", "|"+cast.ToString(uid)+"|").Column("id")
-	if !utils.Is.Empty(ids) {
-		go (&AuthGroup{}).Auth(uid, ids, true)
-	}
+	go RemoveUserGroups(cast.ToInt(uid))
 
 	// 表名
 	tables := []any{
@@ -0,0 +1,275 @@
+package model
+
+import (
+	"crypto/subtle"
+	"fmt"
+	"inis/app/facade"
+	"strings"
+	"time"
+
+	"github.com/spf13/cast"
+	"github.com/unti-io/go-utils/utils"
+)
+
+// 验证码用途 - 不同用途的验证码互不通用
+const (
+	VerifyCodeRegister      = "register"
+	VerifyCodeResetPassword = "reset-password"
+	VerifyCodeBindEmail     = "bind-email"
+	VerifyCodeBindPhone     = "bind-phone"
+	VerifyCodeDestroy       = "destroy"
+	VerifyCodeAppeal        = "appeal"
+)
+
+// VerifyCodeError - 验证码服务错误（Message 为语言包键，Args 为格式化参数）
+type VerifyCodeError struct {
+	Message string
+	Args    []any
+}
+
+func (this *VerifyCodeError) Error() string {
+	return fmt.Sprintf(this.Message, this.Args...)
+}
+
+var (
+	// ErrVerifyCodeDisabled - 对应的发送服务未开启
+	ErrVerifyCodeDisabled = &VerifyCodeError{Message: "管理员未开启%v服务，无法发送验证码！"}
+	// ErrVerifyCodeFrequent - 发送服务商频控或发件箱频控
+	ErrVerifyCodeFrequent = &VerifyCodeError{Message: "发送过于频繁，请稍后再试！"}
+	// ErrVerifyCodeDaily - 超出每日发送上限
+	ErrVerifyCodeDaily = &VerifyCodeError{Message: "今日发送验证码次数已达上限，请明日再试！"}
+	// ErrVerifyCodeInvalid - 验证码错误、已过期或已使用
+	ErrVerifyCodeInvalid = &VerifyCodeError{Message: "验证码错误！"}
+	// ErrVerifyCodeAttempts - 错误次数过多，验证码已失效
+	ErrVerifyCodeAttempts = &VerifyCodeError{Message: "验证码错误次数过多，请重新发送验证码！"}
+)
+
+// VerifyCode - 验证码服务（按 用途 + 收件人 签发与校验）
+type VerifyCode struct {
+	// 用途，如 VerifyCodeRegister
+	Purpose string
+	// 发送模式 email 或 sms
+	Mode string
+	// 收件人（邮箱或手机号）
+	Recipient string
+	// 验证码长度
+	Length int
+	// 有效期
+	Expire time.Duration
+	// 最大校验次数，达到后验证码失效
+	MaxAttempts int
+	// 同一用途与收件人的重发间隔
+	Cooldown time.Duration
+	// 每个收件人每天最多发送次数（0 表示不限制）
+	RecipientPerDay int
+	// 每个IP每小时最多发送次数（0 表示不限制）
+	IPPerHour int
+	// 每个IP每天最多发送次数（0 表示不限制）
+	IPPerDay int
+	// 收件人语言（用于邮件模板），收件人已注册且设置了语言偏好时以其偏好为准
+	Lang string
+}
+
+// NewVerifyCode - 创建验证码服务，限制参数读取自 app.toml 的 [verify_code] 配置
+/**
+ * @param purpose 用途
+ * @param mode 发送模式 email 或 sms
+ * @param recipient 收件人
+ * @example：
+ * 1. code := model.NewVerifyCode(model.VerifyCodeRegister, "email", "admin@inis.cn")
+ */
+func NewVerifyCode(purpose, mode, recipient string) *VerifyCode {
+	return &VerifyCode{
+		Purpose:         purpose,
+		Mode:            mode,
+		Recipient:       strings.TrimSpace(recipient),
+		Length:          cast.ToInt(facade.AppToml.Get("verify_code.length", 6)),
+		Expire:          time.Duration(cast.ToInt(facade.AppToml.Get("verify_code.expire", 300))) * time.Second,
+		MaxAttempts:     cast.ToInt(facade.AppToml.Get("verify_code.max_attempts", 5)),
+		Cooldown:        time.Duration(cast.ToInt(facade.AppToml.Get("verify_code.cooldown", 60))) * time.Second,
+		RecipientPerDay: cast.ToInt(facade.AppToml.Get("verify_code.recipient_per_day", 10)),
+		IPPerHour:       cast.ToInt(facade.AppToml.Get("verify_code.ip_per_hour", 10)),
+		IPPerDay:        cast.ToInt(facade.AppToml.Get("verify_code.ip_per_day", 20)),
+	}
+}
+
+// Drive - 发送驱动（sms.toml 中 [drive] 对应模式的配置，为空表示未开启）
+func (this *VerifyCode) Drive() string {
+	return cast.ToString(facade.SMSToml.Get("drive." + this.Mode))
+}
+
+// key - 验证码缓存键
+func (this *VerifyCode) key() string {
+	return fmt.Sprintf("verify-code[%v][%v]", this.Purpose, this.Recipient)
+}
+
+// lang - 收件人语言：已注册用户的语言偏好，其次为请求语言
+func (this *VerifyCode) lang() string {
+
+	field := utils.Ternary(this.Mode == facade.SMSModeEmail, "email", "phone")
+
+	var langs []string
+	facade.DB.Drive().Model(&Users{}).Where(field+" = ? AND lang IS NOT NULL AND lang <> ''", this.Recipient).Limit(1).Pluck("lang", &langs)
+	if len(langs) > 0 {
+		return langs[0]
+	}
+
+	return this.Lang
+}
+
+// Send - 发送验证码（同一用途与收件人重复发送时，旧验证码立即失效）
+func (this *VerifyCode) Send(ip string) error {
+
+	drive := this.Drive()
+	if utils.Is.Empty(drive) {
+		return &VerifyCodeError{
+			Message: ErrVerifyCodeDisabled.Message,
+			Args:    []any{utils.Ternary(this.Mode == facade.SMSModeEmail, "邮箱", "短信")},
+		}
+	}
+
+	cooldownKey := fmt.Sprintf("verify-code[cooldown][%v][%v]", this.Purpose, this.Recipient)
+	recipientKey := fmt.Sprintf("verify-code[recipient][%v]", this.Recipient)
+	ipHourKey := fmt.Sprintf("verify-code[ip-hour][%v]", ip)
+	ipDayKey := fmt.Sprintf("verify-code[ip-day][%v]", ip)
+
+	// 重发间隔
+	if last := cast.ToInt64(facade.Cache.Get(cooldownKey)); last > 0 && this.Cooldown > 0 {
+		if wait := last + int64(this.Cooldown.Seconds()) - time.Now().Unix(); wait > 0 {
+			return &VerifyCodeError{Message: "发送过于频繁，请%d秒后再试！", Args: []any{wait}}
+		}
+	}
+
+	// 收件人与IP的发送次数
+	if verifyCodeLimited(recipientKey, this.RecipientPerDay) {
+		return ErrVerifyCodeDaily
+	}
+	if !utils.Is.Empty(ip) {
+		if verifyCodeLimited(ipDayKey, this.IPPerDay) {
+			return ErrVerifyCodeDaily
+		}
+		if verifyCodeLimited(ipHourKey, this.IPPerHour) {
+			return ErrVerifyCodeFrequent
+		}
+	}
+
+	length := utils.Ternary(this.Length < 4 || this.Length > 10, 6, this.Length)
+	outbox := NewOutbox(drive)
+	outbox.Lang, outbox.Expire = this.lang(), this.Expire
+	sms := outbox.VerifyCode(this.Recipient, utils.Rand.String(length, "0123456789"))
+	if sms.Error != nil {
+		// 服务商频控（阿里云）
+		if strings.Contains(sms.Error.Error(), "check frequency failed") || strings.Contains(sms.Error.Error(), "FREQUENCY_FAIL") {
+			return ErrVerifyCodeFrequent
+		}
+		return sms.Error
+	}
+
+	facade.Cache.Set(this.key(), map[string]any{
+		"code":     sms.VerifyCode,
+		"attempts": 0,
+		"expire":   time.Now().Add(this.Expire).Unix(),
+	}, this.Expire)
+
+	if this.Cooldown > 0 {
+		facade.Cache.Set(cooldownKey, time.Now().Unix(), this.Cooldown)
+	}
+	verifyCodeIncr(recipientKey, 24*time.Hour)
+	if !utils.Is.Empty(ip) {
+		verifyCodeIncr(ipHourKey, time.Hour)
+		verifyCodeIncr(ipDayKey, 24*time.Hour)
+	}
+
+	return nil
+}
+
+// Check - 校验验证码，校验通过后验证码立即失效（一次性）；收件人与IP的失败次数计入防暴力破解统计
+func (this *VerifyCode) Check(code any, ip string) error {
+
+	guard := NewLoginGuard(LoginGuardVerifyCode, this.Recipient, ip)
+	if err := guard.Check(); err != nil {
+		return err
+	}
+
+	input := strings.TrimSpace(cast.ToString(code))
+	if utils.Is.Empty(input) {
+		guard.Fail()
+		return ErrVerifyCodeInvalid
+	}
+
+	// 比对、计数与删除在同一次原子更新中完成：并发提交同一验证码只有一个能通过，并发猜测也不会丢失计数
+	result := ErrVerifyCodeInvalid
+	ok := facade.Cache.Update(this.key(), func(value any) (any, time.Duration) {
+
+		result = ErrVerifyCodeInvalid
+		item := cast.ToStringMap(value)
+		if utils.Is.Empty(item) {
+			return nil, 0
+		}
+
+		expire := cast.ToInt64(item["expire"])
+		if expire <= time.Now().Unix() {
+			return nil, 0
+		}
+
+		// 恒定时间比较，避免通过响应耗时逐位猜测
+		if subtle.ConstantTimeCompare([]byte(input), []byte(cast.ToString(item["code"]))) == 1 {
+			result = nil
+			return nil, 0
+		}
+
+		attempts := cast.ToInt(item["attempts"]) + 1
+		if this.MaxAttempts > 0 && attempts >= this.MaxAttempts {
+			result = ErrVerifyCodeAttempts
+			return nil, 0
+		}
+
+		item["attempts"] = attempts
+		return item, time.Until(time.Unix(expire, 0))
+	})
+	if !ok && result == nil {
+		// 删除未能写回时不放行，避免同一验证码被重复使用
+		result = ErrVerifyCodeInvalid
+	}
+
+	if result != nil {
+		guard.Fail()
+		return result
+	}
+
+	guard.Success()
+	return nil
+}
+
+// Revoke - 使验证码失效
+func (this *VerifyCode) Revoke() {
+	facade.Cache.Del(this.key())
+}
+
+// verifyCodeLimited - 计数是否已达上限（limit 小于等于 0 表示不限制）
+func verifyCodeLimited(key string, limit int) bool {
+	if limit <= 0 {
+		return false
+	}
+	item := cast.ToStringMap(facade.Cache.Get(key))
+	return cast.ToInt64(item["reset"]) > time.Now().Unix() && cast.ToInt(item["count"]) >= limit
+}
+
+// verifyCodeIncr - 固定窗口计数（续写时不延长窗口）
+func verifyCodeIncr(key string, window time.Duration) {
+
+	now := time.Now().Unix()
+	item := cast.ToStringMap(facade.Cache.Get(key))
+
+	reset := cast.ToInt64(item["reset"])
+	count := cast.ToInt(item["count"])
+	if reset <= now {
+		reset = time.Now().Add(window).Unix()
+		count = 0
+	}
+
+	facade.Cache.Set(key, map[string]any{
+		"count": count + 1,
+		"reset": reset,
+	}, time.Duration(reset-now)*time.Second)
+}
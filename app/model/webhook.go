@@ -0,0 +1,380 @@
+package model
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"inis/app/facade"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/spf13/cast"
+	"github.com/unti-io/go-utils/utils"
+	"gorm.io/gorm"
+	"gorm.io/plugin/soft_delete"
+)
+
+// Webhook 事件类型
+const (
+	WebhookEventArticlePublished = "article.published"
+	WebhookEventCommentCreated   = "comment.created"
+	WebhookEventUserRegistered   = "user.registered"
+	WebhookEventUserBanned       = "user.banned"
+	WebhookEventQpsBlocked       = "qps.blocked"
+	WebhookEventPing             = "ping"
+)
+
+// WebhookEvents - 可订阅的事件列表
+var WebhookEvents = map[string]string{
+	WebhookEventArticlePublished: "文章发布",
+	WebhookEventCommentCreated:   "评论创建",
+	WebhookEventUserRegistered:   "用户注册",
+	WebhookEventUserBanned:       "用户封禁",
+	WebhookEventQpsBlocked:       "QPS自动封禁",
+}
+
+// Webhook 投递状态
+const (
+	WebhookDeliveryPending = "pending"
+	WebhookDeliverySuccess = "success"
+	WebhookDeliveryFailed  = "failed"
+)
+
+const (
+	// webhookMaxAttempts - 最大投递次数（含首次）
+	webhookMaxAttempts = 6
+	// webhookBaseDelay - 首次重试间隔，之后按 2 的幂次递增
+	webhookBaseDelay = 30 * time.Second
+	// webhookMaxDelay - 最大重试间隔
+	webhookMaxDelay = 6 * time.Hour
+	// webhookTimeout - 单次请求超时
+	webhookTimeout = 10 * time.Second
+	// webhookClaimDelay - 投递中的记录临时推迟时间，防止被定时任务重复领取
+	webhookClaimDelay = 5 * time.Minute
+)
+
+type Webhook struct {
+	Id     int    `gorm:"type:int(32); comment:主键;" json:"id"`
+	Name   string `gorm:"size:64; comment:名称;" json:"name"`
+	Url    string `gorm:"size:512; comment:推送地址;" json:"url"`
+	Secret string `gorm:"size:64; comment:签名密钥;" json:"secret"`
+	Events string `gorm:"size:512; comment:订阅事件（逗号分隔，*为全部）;" json:"events"`
+	Status int    `gorm:"type:int(2); default:1; comment:状态 0停用 1启用;" json:"status"`
+	Remark string `gorm:"comment:备注; default:Null;" json:"remark"`
+	// 以下为公共字段
+	Json       any                   `gorm:"type:longtext; comment:用于存储JSON数据;" json:"json"`
+	Text       any                   `gorm:"type:longtext; comment:用于存储文本数据;" json:"text"`
+	Result     any                   `gorm:"type:varchar(256); comment:不存储数据，用于封装返回结果;" json:"result"`
+	CreateTime int64                 `gorm:"autoCreateTime; comment:创建时间;" json:"create_time"`
+	UpdateTime int64                 `gorm:"autoUpdateTime; comment:更新时间;" json:"update_time"`
+	DeleteTime soft_delete.DeletedAt `gorm:"comment:删除时间; default:0;" json:"delete_time"`
+}
+
+// WebhookDelivery - Webhook 投递记录
+type WebhookDelivery struct {
+	Id           int    `gorm:"type:int(32); comment:主键;" json:"id"`
+	WebhookId    int    `gorm:"type:int(32); index; default:0; comment:端点ID 0表示未注册的临时地址;" json:"webhook_id"`
+	DeliveryId   string `gorm:"size:36; index; comment:投递标识;" json:"delivery_id"`
+	Event        string `gorm:"size:64; index; comment:事件类型;" json:"event"`
+	Url          string `gorm:"size:512; comment:推送地址;" json:"url"`
+	Payload      string `gorm:"type:longtext; comment:推送内容;" json:"payload"`
+	Status       string `gorm:"size:16; index; default:'pending'; comment:投递状态;" json:"status"`
+	Attempts     int    `gorm:"type:int(32); default:0; comment:已投递次数;" json:"attempts"`
+	NextTime     int64  `gorm:"index; default:0; comment:下次投递时间;" json:"next_time"`
+	ResponseCode int    `gorm:"type:int(32); default:0; comment:响应状态码;" json:"response_code"`
+	ResponseBody string `gorm:"size:1024; comment:响应内容（截断）;" json:"response_body"`
+	Error        string `gorm:"size:512; comment:错误信息;" json:"error"`
+	Duration     int64  `gorm:"default:0; comment:请求耗时（毫秒）;" json:"duration"`
+	Redelivery   int    `gorm:"type:int(2); default:0; comment:是否为手动重新投递;" json:"redelivery"`
+	CreateTime   int64  `gorm:"autoCreateTime; comment:创建时间;" json:"create_time"`
+	UpdateTime   int64  `gorm:"autoUpdateTime; comment:更新时间;" json:"update_time"`
+}
+
+// InitWebhook - 初始化Webhook表
+func InitWebhook() {
+	err := facade.DB.Drive().AutoMigrate(&Webhook{})
+	if err != nil {
+		facade.Log.Error(map[string]any{"error": err}, "Webhook表迁移失败")
+		return
+	}
+}
+
+// InitWebhookDelivery - 初始化WebhookDelivery表
+func InitWebhookDelivery() {
+	err := facade.DB.Drive().AutoMigrate(&WebhookDelivery{})
+	if err != nil {
+		facade.Log.Error(map[string]any{"error": err}, "WebhookDelivery表迁移失败")
+		return
+	}
+}
+
+// AfterFind - 查询Hook
+func (this *Webhook) AfterFind(tx *gorm.DB) (err error) {
+	this.Text = cast.ToString(this.Text)
+	this.Json = utils.Json.Decode(this.Json)
+	return
+}
+
+// Subscribed - 是否订阅了指定事件
+func (this *Webhook) Subscribed(event string) bool {
+	if event == WebhookEventPing {
+		return true
+	}
+	for _, item := range strings.Split(this.Events, ",") {
+		item = strings.TrimSpace(item)
+		if item == "*" || item == event {
+			return true
+		}
+	}
+	return false
+}
+
+// GenerateSecret - 生成签名密钥
+func (this *Webhook) GenerateSecret() string {
+	return utils.Rand.String(40, "0123456789abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ")
+}
+
+// TriggerWebhook - 触发事件，异步投递给所有订阅了该事件的端点
+func TriggerWebhook(event string, data any) {
+	go func() {
+		defer func() {
+			if err := recover(); err != nil {
+				facade.Log.Error(map[string]any{"error": err, "event": event}, "Webhook事件分发发生错误")
+			}
+		}()
+
+		// 数据库未初始化（未安装）时跳过
+		if facade.DB == nil {
+			return
+		}
+
+		var hooks []Webhook
+		facade.DB.Drive().Where("status = ?", 1).Find(&hooks)
+
+		for _, hook := range hooks {
+			if !hook.Subscribed(event) {
+				continue
+			}
+			delivery, err := NewWebhookDelivery(hook.Id, hook.Url, event, data)
+			if err != nil {
+				continue
+			}
+			delivery.Send()
+		}
+	}()
+}
+
+// TriggerWebhookURL - 向未注册的地址投递事件（不签名），用于兼容旧配置中的 webhook 地址
+func TriggerWebhookURL(url, event string, data any) {
+	go func() {
+		defer func() {
+			if err := recover(); err != nil {
+				facade.Log.Error(map[string]any{"error": err, "event": event}, "Webhook事件分发发生错误")
+			}
+		}()
+
+		delivery, err := NewWebhookDelivery(0, url, event, data)
+		if err != nil {
+			return
+		}
+		delivery.Send()
+	}()
+}
+
+// NewWebhookDelivery - 创建投递记录
+func NewWebhookDelivery(webhookId int, url, event string, data any) (*WebhookDelivery, error) {
+
+	deliveryId := uuid.New().String()
+	payload := utils.Json.Encode(map[string]any{
+		"id":    deliveryId,
+		"event": event,
+		"time":  time.Now().Unix(),
+		"data":  data,
+	})
+
+	delivery := &WebhookDelivery{
+		WebhookId:  webhookId,
+		DeliveryId: deliveryId,
+		Event:      event,
+		Url:        url,
+		Payload:    payload,
+		Status:     WebhookDeliveryPending,
+	}
+
+	if err := facade.DB.Drive().Create(delivery).Error; err != nil {
+		facade.Log.Error(map[string]any{"error": err, "event": event, "url": url}, "Webhook投递记录创建失败")
+		return nil, err
+	}
+
+	return delivery, nil
+}
+
+// Redeliver - 以原始内容重新投递（生成新的投递记录）
+func (this *WebhookDelivery) Redeliver() (*WebhookDelivery, error) {
+
+	delivery := &WebhookDelivery{
+		WebhookId:  this.WebhookId,
+		DeliveryId: this.DeliveryId,
+		Event:      this.Event,
+		Url:        this.Url,
+		Payload:    this.Payload,
+		Status:     WebhookDeliveryPending,
+		Redelivery: 1,
+	}
+
+	// 端点地址可能已修改，以最新配置为准
+	if this.WebhookId > 0 {
+		var hook Webhook
+		if err := facade.DB.Drive().Where("id = ?", this.WebhookId).First(&hook).Error; err != nil {
+			return nil, fmt.Errorf("端点不存在或已删除")
+		}
+		delivery.Url = hook.Url
+	}
+
+	if err := facade.DB.Drive().Create(delivery).Error; err != nil {
+		return nil, err
+	}
+
+	delivery.Send()
+
+	return delivery, nil
+}
+
+// Claim - 领取待重试的投递记录，防止多个任务重复投递
+func (this *WebhookDelivery) Claim() bool {
+	result := facade.DB.Drive().Model(&WebhookDelivery{}).
+		Where("id = ? AND status = ? AND next_time = ?", this.Id, WebhookDeliveryPending, this.NextTime).
+		Update("next_time", time.Now().Add(webhookClaimDelay).Unix())
+	return result.Error == nil && result.RowsAffected == 1
+}
+
+// Send - 执行一次投递，并根据结果更新状态与下次重试时间
+func (this *WebhookDelivery) Send() {
+
+	secret := ""
+	if this.WebhookId > 0 {
+		var hook Webhook
+		if err := facade.DB.Drive().Where("id = ?", this.WebhookId).First(&hook).Error; err != nil || hook.Status != 1 {
+			this.finish(0, "", "端点不存在、已删除或已停用", 0, true)
+			return
+		}
+		secret = hook.Secret
+	}
+
+	// SSRF 防护：投递前预检协议与地址，连接时还会校验实际解析出的 IP
+	if err := facade.Comm.IsSafeOutboundURL(this.Url); err != nil {
+		this.finish(0, "", err.Error(), 0, true)
+		return
+	}
+
+	request, err := http.NewRequest(http.MethodPost, this.Url, bytes.NewBufferString(this.Payload))
+	if err != nil {
+		this.finish(0, "", err.Error(), 0, true)
+		return
+	}
+
+	timestamp := cast.ToString(time.Now().Unix())
+	request.Header.Set("Content-Type", "application/json; charset=utf-8")
+	request.Header.Set("User-Agent", "inis-webhook/"+facade.Version)
+	request.Header.Set("X-Inis-Event", this.Event)
+	request.Header.Set("X-Inis-Delivery", this.DeliveryId)
+	request.Header.Set("X-Inis-Timestamp", timestamp)
+	if !utils.Is.Empty(secret) {
+		request.Header.Set("X-Inis-Signature", "sha256="+WebhookSign(secret, timestamp, this.Payload))
+	}
+
+	// 连接时校验实际解析出的地址，且不跟随重定向，避免被引导至内网
+	client := facade.Comm.SafeOutboundClient(webhookTimeout)
+
+	start := time.Now()
+	response, err := client.Do(request)
+	duration := time.Since(start).Milliseconds()
+	if err != nil {
+		this.finish(0, "", err.Error(), duration, false)
+		return
+	}
+	defer response.Body.Close()
+
+	body, _ := io.ReadAll(io.LimitReader(response.Body, 1024))
+
+	if response.StatusCode >= 200 && response.StatusCode < 300 {
+		this.finish(response.StatusCode, string(body), "", duration, false)
+		return
+	}
+
+	this.finish(response.StatusCode, string(body), fmt.Sprintf("HTTP %d", response.StatusCode), duration, false)
+}
+
+// finish - 记录投递结果
+func (this *WebhookDelivery) finish(code int, body, message string, duration int64, final bool) {
+
+	this.Attempts++
+	this.ResponseCode = code
+	this.ResponseBody = facade.Comm.Truncate(body, 1024)
+	this.Error = facade.Comm.Truncate(message, 512)
+	this.Duration = duration
+
+	switch {
+	case utils.Is.Empty(message):
+		this.Status = WebhookDeliverySuccess
+		this.NextTime = 0
+	case final || this.Attempts >= webhookMaxAttempts:
+		this.Status = WebhookDeliveryFailed
+		this.NextTime = 0
+	default:
+		this.Status = WebhookDeliveryPending
+		this.NextTime = time.Now().Add(WebhookBackoff(this.Attempts)).Unix()
+	}
+
+	err := facade.DB.Drive().Model(&WebhookDelivery{}).Where("id = ?", this.Id).Updates(map[string]any{
+		"attempts":      this.Attempts,
+		"status":        this.Status,
+		"next_time":     this.NextTime,
+		"response_code": this.ResponseCode,
+		"response_body": this.ResponseBody,
+		"error":         this.Error,
+		"duration":      this.Duration,
+	}).Error
+	if err != nil {
+		facade.Log.Error(map[string]any{"error": err, "id": this.Id}, "Webhook投递记录更新失败")
+	}
+}
+
+// RetryWebhookDeliveries - 投递到期的待重试记录
+func RetryWebhookDeliveries(limit int) {
+
+	var list []WebhookDelivery
+	facade.DB.Drive().
+		Where("status = ? AND attempts > 0 AND next_time <= ?", WebhookDeliveryPending, time.Now().Unix()).
+		Order("next_time asc").Limit(limit).Find(&list)
+
+	for _, item := range list {
+		if item.Claim() {
+			item.Send()
+		}
+	}
+}
+
+// WebhookSign - 计算签名 HMAC-SHA256(secret, timestamp + "." + payload)
+func WebhookSign(secret, timestamp, payload string) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp + "." + payload))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// WebhookBackoff - 第 n 次失败后的重试间隔（指数退避）
+func WebhookBackoff(attempts int) time.Duration {
+	if attempts < 1 {
+		attempts = 1
+	}
+	delay := webhookBaseDelay << (attempts - 1)
+	if delay <= 0 || delay > webhookMaxDelay {
+		return webhookMaxDelay
+	}
+	return delay
+}
@@ -111,6 +111,7 @@ func (this Index) Connect(ctx *gin.Context) {
 	if user, ok := ctx.Get("user"); ok {
 		if userMap, ok := user.(map[string]any); ok {
 			client.username = cast.ToString(userMap["nickname"])
+			client.uid.Store(cast.ToInt64(userMap["id"]))
 		}
 	}
 
@@ -193,6 +194,12 @@ func (this *client) read() {
 				continue
 			}
 
+			if msgType == "notification-read" {
+				// 标记站内通知已读（id 为空时全部已读），未读数变化由通知推送同步到该用户的所有连接
+				this.handleNotificationRead(cast.ToInt(item["id"]))
+				continue
+			}
+
 			if msgType == "auth" {
 				// 连接后鉴权，升级身份（跨域场景下 Cookie 无法携带，前端主动发 token）
 				this.handleAuth(cast.ToString(item["token"]))
@@ -268,6 +275,12 @@ func (this *client) handleAuth(token string) {
 		return
 	}
 
+	// 校验登录会话（已退出或被下线的会话不允许升级身份）
+	if !model.UserSessionActive(cast.ToString(jwt.Data["sid"]), uid) {
+		socketLog("auth token 会话已失效: %s", this.info.ID)
+		return
+	}
+
 	// 提交身份升级请求给 hub.run（统一在 hub goroutine 内更新，避免并发读写）
 	this.hub.upgrade <- &clientUpgrade{
 		client:   this,
@@ -397,10 +410,15 @@ func (hub *hub) run() {
 
 			hub.recordConnection()
 			client.send <- []byte(`{"type":"connect","content":"连接成功","id":"` + client.info.ID + `"}`)
+			if uid := int(client.uid.Load()); uid > 0 {
+				hub.bindUser(client, uid)
+				go hub.pushUnread(uid)
+			}
 			hub.broadcastStatus()
 			socketLog("当前在线人数: %d", len(hub.clients))
 		case client := <-hub.close:
 			socketLog("客户端断开连接: %s", client.info.ID)
+			hub.unbindUser(client)
 			if _, ok := hub.clients[client.info.ID]; ok {
 				delete(hub.clients, client.info.ID)
 				close(client.send)
@@ -456,8 +474,14 @@ func (hub *hub) run() {
 			// 以避免与 read goroutine 并发读写 info.ID 产生数据竞争。
 			up.client.username = up.username
 			up.client.isAdmin = up.isAdmin
+			hub.unbindUser(up.client)
+			up.client.uid.Store(int64(up.uid))
+			hub.bindUser(up.client, up.uid)
 			socketLog("客户端身份升级: %s (uid=%d, 管理员=%v)", up.client.info.ID, up.uid, up.isAdmin)
 			hub.broadcastStatus()
+			go hub.pushUnread(up.uid)
+		case msg := <-hub.deliver:
+			hub.usercast(msg)
 		}
 	}
 }
@@ -519,6 +543,7 @@ func (hub *hub) broadcast(message []byte) {
 		select {
 		case client.send <- message:
 		default:
+			hub.unbindUser(client)
 			close(client.send)
 			delete(hub.clients, client.info.ID)
 		}
@@ -534,6 +559,7 @@ func (hub *hub) broadcastToAdmin(message []byte) {
 		select {
 		case client.send <- message:
 		default:
+			hub.unbindUser(client)
 			close(client.send)
 			delete(hub.clients, client.info.ID)
 		}
@@ -556,6 +582,7 @@ func (hub *hub) singlecast(message []byte) {
 			socketLog("消息发送成功: %s -> %s", content["id"], to)
 		default:
 			socketLog("发送队列满，断开连接: %s", client.info.ID)
+			hub.unbindUser(client)
 			close(client.send)
 			delete(hub.clients, client.info.ID)
 		}
@@ -0,0 +1,173 @@
+package controller
+
+import (
+	"encoding/json"
+
+	"inis/app/facade"
+	"inis/app/model"
+)
+
+func init() {
+	// 注入通知推送：通知写入后实时推送到该用户的所有在线连接
+	model.NotificationPublisher = Hub.PublishNotification
+}
+
+// userMessage 推送给指定用户全部在线连接的消息
+type userMessage struct {
+	uid     int
+	message []byte
+}
+
+// bindUser 记录用户的在线连接（仅在 hub goroutine 内调用）
+func (hub *hub) bindUser(item *client, uid int) {
+	if uid <= 0 {
+		return
+	}
+
+	hub.usersLock.Lock()
+	defer hub.usersLock.Unlock()
+
+	if _, ok := hub.users[uid]; !ok {
+		hub.users[uid] = make(map[*client]bool)
+	}
+	hub.users[uid][item] = true
+}
+
+// unbindUser 移除用户的在线连接（仅在 hub goroutine 内调用，须在关闭 send 通道前执行）
+func (hub *hub) unbindUser(item *client) {
+	uid := int(item.uid.Load())
+	if uid <= 0 {
+		return
+	}
+
+	hub.usersLock.Lock()
+	defer hub.usersLock.Unlock()
+
+	if clients, ok := hub.users[uid]; ok {
+		delete(clients, item)
+		if len(clients) == 0 {
+			delete(hub.users, uid)
+		}
+	}
+}
+
+// onlineUids 当前在线的登录用户 ID
+func (hub *hub) onlineUids() []int {
+	hub.usersLock.RLock()
+	defer hub.usersLock.RUnlock()
+
+	uids := make([]int, 0, len(hub.users))
+	for uid := range hub.users {
+		uids = append(uids, uid)
+	}
+	return uids
+}
+
+// usercast 推送消息到用户的全部在线连接（仅在 hub goroutine 内调用）
+func (hub *hub) usercast(msg *userMessage) {
+	hub.usersLock.RLock()
+	clients := make([]*client, 0, len(hub.users[msg.uid]))
+	for client := range hub.users[msg.uid] {
+		clients = append(clients, client)
+	}
+	hub.usersLock.RUnlock()
+
+	for _, client := range clients {
+		select {
+		case client.send <- msg.message:
+		default:
+			socketLog("发送队列满，丢弃通知推送: %s", client.info.ID)
+		}
+	}
+	hub.recordMessage("single")
+}
+
+// PushUser 推送消息到用户的全部在线连接（对外公开方法，不可在 hub goroutine 内调用）
+func (hub *hub) PushUser(uid int, data []byte) {
+	if hub == nil || hub.deliver == nil || uid <= 0 {
+		return
+	}
+	hub.deliver <- &userMessage{uid: uid, message: data}
+}
+
+// PublishNotification 推送通知与未读数（notif 为 nil 时仅推送未读数，uid 为 0 时推送给全部在线用户）
+func (hub *hub) PublishNotification(uid int, event string, notif *model.Notification) {
+
+	uids := []int{uid}
+	if uid == 0 {
+		uids = hub.onlineUids()
+	}
+	if len(uids) == 0 {
+		return
+	}
+
+	// 查询偏好与未读数涉及数据库，异步执行避免阻塞通知写入
+	go func() {
+		defer func() {
+			if r := recover(); r != nil {
+				facade.Log.Error(map[string]any{"error": r}, "通知实时推送协程错误")
+			}
+		}()
+
+		var message []byte
+		if notif != nil {
+			message, _ = json.Marshal(map[string]any{
+				"type":    "notification",
+				"event":   event,
+				"content": notif,
+			})
+		}
+
+		for _, id := range uids {
+			if !hub.online(id) {
+				continue
+			}
+			if notif != nil && model.NotifyAllowed(id, notif.Type, model.NotifyChannelPush) {
+				hub.PushUser(id, message)
+			}
+			hub.pushUnread(id)
+		}
+	}()
+}
+
+// online 用户是否有在线连接
+func (hub *hub) online(uid int) bool {
+	hub.usersLock.RLock()
+	defer hub.usersLock.RUnlock()
+	return len(hub.users[uid]) > 0
+}
+
+// pushUnread 推送用户的未读通知数（查询数据库，不可在 hub goroutine 内调用）
+func (hub *hub) pushUnread(uid int) {
+	if uid <= 0 || facade.DB == nil {
+		return
+	}
+
+	message, _ := json.Marshal(map[string]any{
+		"type": "unread",
+		"content": map[string]any{
+			"count": (&model.Notification{}).GetUnreadCount(uid),
+		},
+	})
+	hub.PushUser(uid, message)
+}
+
+// handleNotificationRead 处理客户端的通知已读消息（id 为 0 时全部标记已读）
+func (this *client) handleNotificationRead(id int) {
+	uid := int(this.uid.Load())
+	if uid <= 0 {
+		socketLog("未登录连接无法标记通知已读: %s", this.info.ID)
+		return
+	}
+
+	var err error
+	if id > 0 {
+		err = (&model.Notification{}).MarkRead(uid, id)
+	} else {
+		err = (&model.Notification{}).MarkAllRead(uid)
+	}
+
+	if err != nil {
+		socketLog("标记通知已读失败: %s, 错误: %v", this.info.ID, err)
+	}
+}
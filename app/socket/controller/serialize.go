@@ -3,8 +3,10 @@ package controller
 import (
 	"encoding/json"
 	"fmt"
+	"inis/app/model"
 	"inis/config"
 	"net/http"
+	"sync"
 	"sync/atomic"
 	"time"
 
@@ -40,6 +42,8 @@ type client struct {
 	username string
 	// 最后活跃时间（Unix 秒，原子操作避免并发读写竞争）
 	lastActive atomic.Int64
+	// 登录用户 ID（访客为 0，鉴权升级时由 hub goroutine 写入）
+	uid atomic.Int64
 }
 
 // 客户端信息结构体
@@ -149,6 +153,7 @@ type hub struct {
 	close                chan *client
 	status               chan map[string]any
 	upgrade              chan *clientUpgrade
+	deliver              chan *userMessage
 	pendingMessages      map[string]*pendingMessage
 	ackTimeout           time.Duration
 	maxRetries           int
@@ -164,6 +169,9 @@ type hub struct {
 	security             *securityConfig
 	chatSessions         map[string]*chatSession
 	messageStatuses      map[string]*messageStatus
+	// 用户 ID 到在线连接的索引（同一用户可多端在线），写入仅在 hub goroutine 内
+	users     map[int]map[*client]bool
+	usersLock sync.RWMutex
 	// IP临时封禁相关
 	ipBanEnabled   bool
 	ipBanThreshold int
@@ -187,6 +195,7 @@ var Hub = func() *hub {
 		close:                make(chan *client),
 		status:               make(chan map[string]any),
 		upgrade:              make(chan *clientUpgrade),
+		deliver:              make(chan *userMessage, 256),
 		clients:              make(map[string]*client),
 		pendingMessages:      make(map[string]*pendingMessage),
 		clientStates:         make(map[string]*clientState),
@@ -194,6 +203,7 @@ var Hub = func() *hub {
 		rateLimits:           make(map[string]*rateLimit),
 		chatSessions:         make(map[string]*chatSession),
 		messageStatuses:      make(map[string]*messageStatus),
+		users:                make(map[int]map[*client]bool),
 		ipBanRecords:         make(map[string]*ipBanRecord),
 		ackTimeout:           time.Duration(cast.ToInt(getSocketConfig("ack_timeout", 10))) * time.Second,
 		maxRetries:           cast.ToInt(getSocketConfig("max_retries", 3)),
@@ -436,7 +446,7 @@ func (hub *hub) RemoveFromBlacklist(ip string) {
 }
 
 func (hub *hub) IsBlacklisted(ip string) bool {
-	return hub.security.blacklistedIPs[ip]
+	return hub.security.blacklistedIPs[ip] || model.MatchIpBlack(ip) != nil
 }
 
 func (hub *hub) AddAllowedOrigin(origin string) {
@@ -490,7 +500,7 @@ func (hub *hub) ValidateMessage(message []byte) (bool, string) {
 	}
 
 	msgType := cast.ToString(content["type"])
-	allowedTypes := []string{"broadcast", "single", "private", "ack", "status", "read", "ping", "pong", "notification", "auth"}
+	allowedTypes := []string{"broadcast", "single", "private", "ack", "status", "read", "ping", "pong", "notification", "notification-read", "auth"}
 	found := false
 	for _, t := range allowedTypes {
 		if t == msgType {
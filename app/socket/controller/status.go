@@ -233,7 +233,7 @@ func getSystemStatus() map[string]any {
 				bannersCount, _ := facade.DB.Model(&model.Banner{}).Count()
 				placardsCount, _ := facade.DB.Model(&model.Placard{}).Count()
 				tagsCount, _ := facade.DB.Model(&model.Tags{}).Count()
-				attachmentsCount, _ := facade.DB.Model(&model.Attachment{}).Count()
+				attachmentsCount, _ := model.AttachmentModel(&model.Attachment{}).Count()
 
 				counts = map[string]any{
 					"users": map[string]any{
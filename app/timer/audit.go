@@ -0,0 +1,34 @@
+package timer
+
+import (
+	"inis/app/facade"
+	"inis/app/model"
+
+	"github.com/spf13/cast"
+)
+
+type AuditStruct struct{}
+
+var Audit *AuditStruct
+
+func (this *AuditStruct) Run() {
+	// 每天凌晨 04:45:00 清理超过保留天数的审计日志
+	_ = Timer.Every(1).Day().At("04:45:00").Do(cleanAuditLogs)
+}
+
+// cleanAuditLogs 清理超过保留天数的审计日志（audit.retention_days 为 0 时永久保留）
+func cleanAuditLogs() {
+	// 数据库未初始化（未安装）时跳过
+	if facade.DB == nil {
+		return
+	}
+
+	days := cast.ToInt(facade.AppToml.Get("audit.retention_days", 180))
+	cleaned, err := model.CleanAuditLogs(days)
+	if err != nil {
+		facade.Log.Error(map[string]any{"error": err}, "审计日志清理失败")
+		return
+	}
+
+	facade.Log.Info(map[string]any{"cleaned": cleaned, "days": days}, "审计日志清理完成")
+}
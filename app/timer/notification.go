@@ -3,6 +3,7 @@ package timer
 import (
 	"inis/app/facade"
 	"inis/app/model"
+	"time"
 
 	"github.com/spf13/cast"
 )
@@ -14,6 +15,24 @@ var Notification *NotificationStruct
 func (this *NotificationStruct) Run() {
 	// 每天凌晨 04:00:00 执行一次通知过期清理
 	_ = Timer.Every(1).Day().At("04:00:00").Do(cleanExpiredNotifications)
+	// 每小时检查一次需要发送的通知摘要（按用户时区判断发送时刻）
+	_ = Timer.Every(1).Hour().Do(sendNotificationDigests)
+}
+
+// sendNotificationDigests 发送每日/每周未读通知摘要
+func sendNotificationDigests() {
+	// 数据库未初始化（未安装）时跳过
+	if facade.DB == nil {
+		return
+	}
+
+	defer func() {
+		if err := recover(); err != nil {
+			facade.Log.Error(map[string]any{"error": err}, "通知摘要任务发生错误")
+		}
+	}()
+
+	model.SendNotificationDigests(time.Now())
 }
 
 // cleanExpiredNotifications 清理过期的已读通知与广播通知
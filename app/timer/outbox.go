@@ -0,0 +1,31 @@
+package timer
+
+import (
+	"inis/app/facade"
+	"inis/app/model"
+)
+
+type OutboxStruct struct{}
+
+var Outbox *OutboxStruct
+
+func (this *OutboxStruct) Run() {
+	// 每分钟检查一次待发送的邮件/短信
+	_ = Timer.Every(1).Minute().Do(retryOutbox)
+}
+
+// retryOutbox 发送到期的发件箱消息
+func retryOutbox() {
+	// 数据库未初始化（未安装）时跳过
+	if facade.DB == nil {
+		return
+	}
+
+	defer func() {
+		if err := recover(); err != nil {
+			facade.Log.Error(map[string]any{"error": err}, "发件箱重试任务发生错误")
+		}
+	}()
+
+	model.RetryOutbox(50)
+}
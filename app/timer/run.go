@@ -16,6 +16,10 @@ func Run() {
 	Device.Run()
 	Ban.Run()
 	Notification.Run()
+	Webhook.Run()
+	Outbox.Run()
+	Session.Run()
+	Audit.Run()
 
 	go func() {
 		<- Timer.Start()
@@ -0,0 +1,40 @@
+package timer
+
+import (
+	"inis/app/facade"
+	"inis/app/model"
+)
+
+type SessionStruct struct{}
+
+var Session *SessionStruct
+
+func (this *SessionStruct) Run() {
+	// 每天凌晨 04:30:00 清理过期或已下线的登录会话与第三方应用令牌
+	_ = Timer.Every(1).Day().At("04:30:00").Do(cleanUserSessions)
+}
+
+// cleanUserSessions 清理过期或已下线超过 30 天的登录会话（保留近期记录供用户查看）
+func cleanUserSessions() {
+	// 数据库未初始化（未安装）时跳过
+	if facade.DB == nil {
+		return
+	}
+
+	cleaned, err := model.CleanUserSessions(30)
+	if err != nil {
+		facade.Log.Error(map[string]any{"error": err}, "登录会话清理失败")
+		return
+	}
+
+	facade.Log.Info(map[string]any{"cleaned": cleaned}, "登录会话清理完成")
+
+	// 第三方应用的令牌同样保留 30 天
+	tokens, err := model.CleanOAuthTokens(30)
+	if err != nil {
+		facade.Log.Error(map[string]any{"error": err}, "第三方应用令牌清理失败")
+		return
+	}
+
+	facade.Log.Info(map[string]any{"cleaned": tokens}, "第三方应用令牌清理完成")
+}
@@ -0,0 +1,31 @@
+package timer
+
+import (
+	"inis/app/facade"
+	"inis/app/model"
+)
+
+type WebhookStruct struct{}
+
+var Webhook *WebhookStruct
+
+func (this *WebhookStruct) Run() {
+	// 每分钟检查一次到期的 Webhook 重试
+	_ = Timer.Every(1).Minute().Do(retryWebhookDeliveries)
+}
+
+// retryWebhookDeliveries 投递到期的待重试 Webhook
+func retryWebhookDeliveries() {
+	// 数据库未初始化（未安装）时跳过
+	if facade.DB == nil {
+		return
+	}
+
+	defer func() {
+		if err := recover(); err != nil {
+			facade.Log.Error(map[string]any{"error": err}, "Webhook重试任务发生错误")
+		}
+	}()
+
+	model.RetryWebhookDeliveries(50)
+}
@@ -73,6 +73,10 @@ func NewValid(table any, params map[string]any) (err error) {
 		item = &UserBanRecords{}
 	case "notification":
 		item = &Notification{}
+	case "webhook":
+		item = &Webhook{}
+	case "oauth-clients":
+		item = &OAuthClients{}
 	default:
 		return errors.New("未知的验证器！")
 	}
@@ -0,0 +1,19 @@
+package validator
+
+type OAuthClients struct {
+	Name         string `json:"name" rule:"required"`
+	RedirectUris string `json:"redirect_uris" rule:"required"`
+}
+
+var OAuthClientsMessage = map[string]string{
+	"name.required":          "应用名称不能为空！",
+	"redirect_uris.required": "回调地址不能为空！",
+}
+
+func (this OAuthClients) Message() map[string]string {
+	return OAuthClientsMessage
+}
+
+func (this OAuthClients) Struct() any {
+	return this
+}
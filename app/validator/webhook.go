@@ -0,0 +1,20 @@
+package validator
+
+type Webhook struct {
+	Name string `json:"name" rule:"required"`
+	Url  string `json:"url" rule:"required,url"`
+}
+
+var WebhookMessage = map[string]string{
+	"name.required": "名称不能为空！",
+	"url.required":  "推送地址不能为空！",
+	"url.url":       "推送地址格式错误！",
+}
+
+func (this Webhook) Message() map[string]string {
+	return WebhookMessage
+}
+
+func (this Webhook) Struct() any {
+	return this
+}
@@ -1,13 +1,13 @@
 package config
 
 import (
-	"bytes"
 	"fmt"
 	"image"
 	"inis/app/facade"
 	"inis/app/middleware"
 	"io"
 	"net/http"
+	"os"
 	"regexp"
 	"strings"
 
@@ -201,35 +201,54 @@ func handleImageFile(ctx *gin.Context, path, ext string, writeImage func(string,
 		return
 	}
 
+	info, err := os.Stat("public" + path)
+	if err != nil {
+		writeErrorGif("error.gif")
+		return
+	}
+
 	reg := regexp.MustCompile(`^(\d+)\D+(\d+)$`)
 	match := reg.FindStringSubmatch(ctx.Query("size"))
 
 	if match == nil {
+		if _, etag := imageETag(path, info.ModTime().UnixNano(), info.Size()); writeImageCacheHeaders(ctx, etag) {
+			return
+		}
 		writeImage(path, ext)
 		return
 	}
 
 	width := cast.ToInt(match[1])
 	height := cast.ToInt(match[2])
-	mode := ctx.DefaultQuery("mode", utils.Ternary(width == height, "fill", ""))
 
-	src, err := imaging.Open("public" + path)
-	if err != nil {
-		writeErrorGif("error.gif")
+	if !imageSizeAllowed(width, height) {
+		ctx.Header("Content-Type", "application/json; charset=utf-8")
+		ctx.JSON(SuccessCode, gin.H{"code": ErrorCode, "msg": ImageSizeNotAllowed, "data": nil})
 		return
 	}
 
-	dstImage := processImage(src, width, height, mode)
-	format := getImageFormat(ext)
+	mode := ctx.DefaultQuery("mode", utils.Ternary(width == height, "fill", ""))
+	if !utils.In.Array(mode, []any{"fill", "resize", "fit"}) {
+		mode = "fit"
+	}
+
+	// 输出格式参与缓存键与 ETag，同一尺寸的 webp、avif 与原格式分别缓存
+	format := negotiateImageFormat(ctx.GetHeader("Accept"), imageFormats(), ext)
+	sum, etag := imageETag(path, info.ModTime().UnixNano(), info.Size(), width, height, mode, format)
+
+	ctx.Header("Vary", "Accept")
+	ctx.Header("Content-Type", imageMime(format)+"; charset=utf-8")
+	if writeImageCacheHeaders(ctx, etag) {
+		return
+	}
 
-	buffer := new(bytes.Buffer)
-	err = imaging.Encode(buffer, dstImage, format)
+	item, err := imageDerivative(path, sum, format, width, height, mode)
 	if err != nil {
 		writeErrorGif("error.gif")
 		return
 	}
 
-	_, err = ctx.Writer.Write(buffer.Bytes())
+	_, err = ctx.Writer.Write(item)
 	if err != nil {
 		writeErrorGif("error.gif")
 	}
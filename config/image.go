@@ -0,0 +1,237 @@
+package config
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"fmt"
+	"image"
+	"inis/app/facade"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/disintegration/imaging"
+	"github.com/gen2brain/avif"
+	"github.com/gen2brain/webp"
+	"github.com/gin-gonic/gin"
+	"github.com/spf13/cast"
+	"github.com/unti-io/go-utils/utils"
+)
+
+const (
+	ImageSizeNotAllowed = "图片尺寸不在允许范围内！"
+	// 默认允许的缩放尺寸
+	defaultImageSizes = "64x64,128x128,256x256,512x512,320x180,640x360,1280x720"
+	// 默认缩放结果缓存目录
+	defaultImageCachePath = "runtime/cache/image"
+	// 默认按 Accept 协商的输出格式（按优先级由高到低）
+	defaultImageFormats = "avif,webp"
+	// 默认 webp/avif 编码质量
+	defaultImageQuality = 75
+)
+
+// imageEncoder - 可按 Accept 协商的图片编码器
+type imageEncoder struct {
+	// MIME类型
+	Mime string
+	// 编码方法
+	Encode func(writer io.Writer, img image.Image, quality int) error
+}
+
+// imageEncoders - 可协商的图片编码器（webp、avif 均为纯 Go 实现，无需 cgo）
+var imageEncoders = map[string]imageEncoder{
+	"avif": {Mime: "image/avif", Encode: func(writer io.Writer, img image.Image, quality int) error {
+		return avif.Encode(writer, img, avif.Options{Quality: quality, QualityAlpha: quality, Speed: avif.DefaultSpeed})
+	}},
+	"webp": {Mime: "image/webp", Encode: func(writer io.Writer, img image.Image, quality int) error {
+		return webp.Encode(writer, img, webp.Options{Quality: quality, Method: webp.DefaultMethod})
+	}},
+}
+
+// imageWorkers - 限制同时进行的缩放任务数量
+var imageWorkers chan struct{}
+var imageWorkersOnce sync.Once
+
+// imageSizeAllowed - 检查缩放尺寸是否在允许范围内
+func imageSizeAllowed(width, height int) bool {
+
+	if width <= 0 || height <= 0 {
+		return false
+	}
+
+	maxWidth := cast.ToInt(AppToml.Get("image.max_width", 2048))
+	maxHeight := cast.ToInt(AppToml.Get("image.max_height", 2048))
+	if width > maxWidth || height > maxHeight {
+		return false
+	}
+
+	size := fmt.Sprintf("%dx%d", width, height)
+	for _, item := range strings.Split(cast.ToString(AppToml.Get("image.sizes", defaultImageSizes)), ",") {
+		item = strings.ToLower(strings.TrimSpace(item))
+		if item == "*" || item == size {
+			return true
+		}
+	}
+
+	return false
+}
+
+// negotiateImageFormat - 根据 Accept 请求头协商输出格式，客户端不支持任何可协商的格式时沿用原格式
+/**
+ * 只认可明确列出的 image/webp、image/avif（q=0 表示不接受），通配的 image 类型不参与协商
+ * @param accept Accept 请求头
+ * @param formats 允许协商的格式，按优先级由高到低
+ * @param ext 原图格式
+ */
+func negotiateImageFormat(accept string, formats []string, ext string) string {
+
+	accepted := map[string]bool{}
+	for _, item := range strings.Split(strings.ToLower(accept), ",") {
+		parts := strings.Split(item, ";")
+		mime := strings.TrimSpace(parts[0])
+		refused := false
+		for _, param := range parts[1:] {
+			if key, value, ok := strings.Cut(strings.TrimSpace(param), "="); ok && strings.TrimSpace(key) == "q" {
+				refused = cast.ToFloat64(strings.TrimSpace(value)) <= 0
+			}
+		}
+		if !refused {
+			accepted[mime] = true
+		}
+	}
+
+	for _, format := range formats {
+		format = strings.ToLower(strings.TrimSpace(format))
+		encoder, ok := imageEncoders[format]
+		if ok && accepted[encoder.Mime] {
+			return format
+		}
+	}
+
+	return ext
+}
+
+// imageFormats - 配置中允许协商的输出格式（为空时不协商）
+func imageFormats() []string {
+	return strings.Split(cast.ToString(AppToml.Get("image.formats", defaultImageFormats)), ",")
+}
+
+// imageMime - 获取输出格式的MIME类型
+func imageMime(format string) string {
+	if encoder, ok := imageEncoders[format]; ok {
+		return encoder.Mime
+	}
+	return utils.Mime.Type(format)
+}
+
+// encodeImage - 按指定格式编码图片
+func encodeImage(img image.Image, format string) ([]byte, error) {
+
+	buffer := new(bytes.Buffer)
+
+	if encoder, ok := imageEncoders[format]; ok {
+		quality := cast.ToInt(AppToml.Get("image.quality", defaultImageQuality))
+		if quality <= 0 || quality > 100 {
+			quality = defaultImageQuality
+		}
+		if err := encoder.Encode(buffer, img, quality); err != nil {
+			return nil, err
+		}
+		return buffer.Bytes(), nil
+	}
+
+	if err := imaging.Encode(buffer, img, getImageFormat(format)); err != nil {
+		return nil, err
+	}
+
+	return buffer.Bytes(), nil
+}
+
+// imageETag - 生成图片的 ETag（源文件变化后自动失效），各部分以 | 分隔，避免相邻字符串拼接后相同
+func imageETag(args ...any) (sum string, etag string) {
+	parts := make([]string, len(args))
+	for i, item := range args {
+		parts[i] = cast.ToString(item)
+	}
+	sum = fmt.Sprintf("%x", sha256.Sum256([]byte(strings.Join(parts, "|"))))
+	return sum, `"` + sum[:32] + `"`
+}
+
+// writeImageCacheHeaders - 写入缓存相关响应头，命中协商缓存时返回 true
+func writeImageCacheHeaders(ctx *gin.Context, etag string) bool {
+
+	ctx.Header("ETag", etag)
+	ctx.Header("Cache-Control", fmt.Sprintf("public, max-age=%d", cast.ToInt(AppToml.Get("image.max_age", 604800))))
+
+	for _, item := range strings.Split(ctx.GetHeader("If-None-Match"), ",") {
+		item = strings.TrimSpace(item)
+		if item == etag || item == "*" || strings.TrimPrefix(item, "W/") == etag {
+			ctx.Status(304)
+			return true
+		}
+	}
+
+	return false
+}
+
+// acquireImageWorker - 获取缩放任务槽位
+func acquireImageWorker() func() {
+
+	imageWorkersOnce.Do(func() {
+		size := cast.ToInt(AppToml.Get("image.concurrency", 4))
+		if size <= 0 {
+			size = 4
+		}
+		imageWorkers = make(chan struct{}, size)
+	})
+
+	imageWorkers <- struct{}{}
+	return func() { <-imageWorkers }
+}
+
+// imageDerivative - 获取缩放后的图片（优先读取磁盘缓存）
+func imageDerivative(path, sum, format string, width, height int, mode string) ([]byte, error) {
+
+	root := cast.ToString(AppToml.Get("image.cache_path", defaultImageCachePath))
+	cache := filepath.Join(root, sum[:2], sum+"."+format)
+
+	if item, err := os.ReadFile(cache); err == nil {
+		return item, nil
+	}
+
+	release := acquireImageWorker()
+	defer release()
+
+	// 排队期间可能已被其他请求生成
+	if item, err := os.ReadFile(cache); err == nil {
+		return item, nil
+	}
+
+	src, err := imaging.Open("public" + path)
+	if err != nil {
+		return nil, err
+	}
+
+	item, err := encodeImage(processImage(src, width, height, mode), format)
+	if err != nil {
+		return nil, err
+	}
+
+	// 先写临时文件再重命名，避免并发读取到不完整的缓存
+	if err = os.MkdirAll(filepath.Dir(cache), 0755); err == nil {
+		temp := fmt.Sprintf("%s.%s.tmp", cache, utils.Rand.String(8, "0123456789abcdef"))
+		if err = os.WriteFile(temp, item, 0644); err == nil {
+			err = os.Rename(temp, cache)
+		}
+		if err != nil {
+			_ = os.Remove(temp)
+		}
+	}
+	if err != nil {
+		facade.Log.Error(map[string]any{"error": err, "path": path}, "图片缩放缓存写入失败")
+	}
+
+	return item, nil
+}